@@ -0,0 +1,174 @@
+package alerting
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"orbitstream/models"
+)
+
+// Sink receives anomaly events; implementations must not block
+type Sink interface {
+	Alert(event models.AnomalyEvent)
+}
+
+// groupKey identifies repeats of the same anomaly (or of its recovery) for
+// the same satellite
+type groupKey struct {
+	satelliteID string
+	detector    string
+	metric      string
+	severity    models.AnomalySeverity
+	recovered   bool
+}
+
+// alertGroup tracks one anomaly type within its grouping window
+type alertGroup struct {
+	windowEnd time.Time
+	repeats   int
+	last      models.AnomalyEvent
+}
+
+// Grouper deduplicates and silences alerts before passing them on.
+//
+// The first event of a kind (satellite, detector, metric, severity) is
+// forwarded immediately and opens a window. Repeats inside the window are
+// held back; when the window closes, one alert carrying the latest values and
+// the total Count is sent. Events matching an active silence are dropped.
+type Grouper struct {
+	next     Sink
+	window   time.Duration
+	silences *Silences
+
+	mu     sync.Mutex
+	groups map[groupKey]*alertGroup
+
+	silenced   atomic.Int64
+	suppressed atomic.Int64
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewGrouper creates a grouper forwarding to next. A window <= 0 disables
+// grouping; silences may be nil.
+func NewGrouper(next Sink, window time.Duration, silences *Silences) *Grouper {
+	return &Grouper{
+		next:     next,
+		window:   window,
+		silences: silences,
+		groups:   make(map[groupKey]*alertGroup),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins closing expired windows in the background
+func (g *Grouper) Start() {
+	if g.window <= 0 {
+		return
+	}
+	interval := g.window / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				g.flushExpired(time.Now())
+			case <-g.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop sends the pending summary of every open window
+func (g *Grouper) Stop() {
+	close(g.stopCh)
+	g.wg.Wait()
+	// Every open window ends within one window length from now
+	g.flushExpired(time.Now().Add(g.window))
+}
+
+// Alert forwards, groups or drops an event (never blocks)
+func (g *Grouper) Alert(event models.AnomalyEvent) {
+	if g.silences != nil && g.silences.Silenced(event) {
+		g.silenced.Add(1)
+		return
+	}
+	if g.window <= 0 {
+		g.next.Alert(event)
+		return
+	}
+
+	key := groupKey{
+		satelliteID: event.SatelliteID,
+		detector:    event.Detector,
+		metric:      event.Metric,
+		severity:    event.Severity,
+		recovered:   event.Recovered,
+	}
+	now := time.Now()
+
+	g.mu.Lock()
+	group, ok := g.groups[key]
+	if ok && now.Before(group.windowEnd) {
+		group.repeats++
+		group.last = event
+		g.mu.Unlock()
+		g.suppressed.Add(1)
+		return
+	}
+	g.groups[key] = &alertGroup{windowEnd: now.Add(g.window)}
+	g.mu.Unlock()
+
+	// A window that closed without a sweep still owes its summary
+	if ok && group.repeats > 0 {
+		g.sendSummary(group)
+	}
+	g.next.Alert(event)
+}
+
+// Silenced returns the number of events dropped by a silence
+func (g *Grouper) Silenced() int64 {
+	return g.silenced.Load()
+}
+
+// Suppressed returns the number of repeats folded into grouped alerts
+func (g *Grouper) Suppressed() int64 {
+	return g.suppressed.Load()
+}
+
+// flushExpired closes windows ending before now, sending their summaries
+func (g *Grouper) flushExpired(now time.Time) {
+	var closed []*alertGroup
+	g.mu.Lock()
+	for key, group := range g.groups {
+		if now.Before(group.windowEnd) {
+			continue
+		}
+		delete(g.groups, key)
+		if group.repeats > 0 {
+			closed = append(closed, group)
+		}
+	}
+	g.mu.Unlock()
+
+	for _, group := range closed {
+		g.sendSummary(group)
+	}
+}
+
+// sendSummary forwards the latest repeat of a group with its total count
+// (the first occurrence plus repeats)
+func (g *Grouper) sendSummary(group *alertGroup) {
+	event := group.last
+	event.Count = group.repeats + 1
+	g.next.Alert(event)
+}
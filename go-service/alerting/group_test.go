@@ -0,0 +1,166 @@
+package alerting
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// recordingSink collects forwarded events
+type recordingSink struct {
+	mu     sync.Mutex
+	events []models.AnomalyEvent
+}
+
+func (s *recordingSink) Alert(event models.AnomalyEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+func (s *recordingSink) received() []models.AnomalyEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.AnomalyEvent(nil), s.events...)
+}
+
+// TestGrouperGroupsRepeats tests that repeats in a window produce one summary with a count
+func TestGrouperGroupsRepeats(t *testing.T) {
+	sink := &recordingSink{}
+	grouper := NewGrouper(sink, time.Hour, nil)
+
+	for i := 0; i < 4; i++ {
+		event := testEvent(models.SeverityCritical)
+		event.Value = -101 - float64(i)
+		grouper.Alert(event)
+	}
+
+	if got := sink.received(); len(got) != 1 {
+		t.Fatalf("expected first event forwarded immediately, got %d events", len(got))
+	}
+
+	grouper.flushExpired(time.Now().Add(2 * time.Hour))
+
+	got := sink.received()
+	if len(got) != 2 {
+		t.Fatalf("expected one summary after the window, got %d events", len(got))
+	}
+	if got[1].Count != 4 {
+		t.Errorf("expected summary count 4, got %d", got[1].Count)
+	}
+	if got[1].Value != -104 {
+		t.Errorf("expected summary to carry the latest value, got %.1f", got[1].Value)
+	}
+	if grouper.Suppressed() != 3 {
+		t.Errorf("expected 3 suppressed repeats, got %d", grouper.Suppressed())
+	}
+}
+
+// TestGrouperKeepsDistinctAnomaliesApart tests that other satellites and severities are not grouped
+func TestGrouperKeepsDistinctAnomaliesApart(t *testing.T) {
+	sink := &recordingSink{}
+	grouper := NewGrouper(sink, time.Hour, nil)
+
+	grouper.Alert(testEvent(models.SeverityWarning))
+	grouper.Alert(testEvent(models.SeverityCritical))
+	other := testEvent(models.SeverityWarning)
+	other.SatelliteID = "SAT-0043"
+	grouper.Alert(other)
+
+	if got := sink.received(); len(got) != 3 {
+		t.Errorf("expected 3 distinct alerts, got %d", len(got))
+	}
+}
+
+// TestGrouperStopFlushesOpenWindows tests that Stop sends pending summaries
+func TestGrouperStopFlushesOpenWindows(t *testing.T) {
+	sink := &recordingSink{}
+	grouper := NewGrouper(sink, time.Hour, nil)
+	grouper.Start()
+
+	grouper.Alert(testEvent(models.SeverityCritical))
+	grouper.Alert(testEvent(models.SeverityCritical))
+	grouper.Stop()
+
+	got := sink.received()
+	if len(got) != 2 || got[1].Count != 2 {
+		t.Errorf("expected first alert and a summary with count 2, got %+v", got)
+	}
+}
+
+// TestGrouperWithoutWindowPassesThrough tests that a zero window disables grouping
+func TestGrouperWithoutWindowPassesThrough(t *testing.T) {
+	sink := &recordingSink{}
+	grouper := NewGrouper(sink, 0, nil)
+
+	grouper.Alert(testEvent(models.SeverityCritical))
+	grouper.Alert(testEvent(models.SeverityCritical))
+
+	if got := sink.received(); len(got) != 2 {
+		t.Errorf("expected 2 alerts, got %d", len(got))
+	}
+}
+
+// TestGrouperSilences tests that silenced satellites and metrics are dropped
+func TestGrouperSilences(t *testing.T) {
+	sink := &recordingSink{}
+	silences := NewSilences()
+	grouper := NewGrouper(sink, 0, silences)
+
+	silence := silences.Add("SAT-0042", "signal", "antenna repointing", time.Hour)
+	grouper.Alert(testEvent(models.SeverityCritical))
+
+	battery := testEvent(models.SeverityCritical)
+	battery.Dimension = models.DimensionBattery
+	battery.Metric = "battery_charge_percent"
+	grouper.Alert(battery)
+
+	got := sink.received()
+	if len(got) != 1 || got[0].Metric != "battery_charge_percent" {
+		t.Errorf("expected only the battery alert, got %+v", got)
+	}
+	if grouper.Silenced() != 1 {
+		t.Errorf("expected 1 silenced alert, got %d", grouper.Silenced())
+	}
+
+	if !silences.Remove(silence.ID) {
+		t.Fatal("expected silence to be removed")
+	}
+	grouper.Alert(testEvent(models.SeverityCritical))
+	if got := sink.received(); len(got) != 2 {
+		t.Errorf("expected alert after lifting the silence, got %d", len(got))
+	}
+}
+
+// TestSilencesExpire tests that expired silences no longer match
+func TestSilencesExpire(t *testing.T) {
+	silences := NewSilences()
+	silences.Add("SAT-0042", "", "", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	if silences.Silenced(testEvent(models.SeverityCritical)) {
+		t.Error("expected expired silence not to match")
+	}
+	if len(silences.Active()) != 0 {
+		t.Error("expected expired silence to be pruned")
+	}
+}
+
+// TestGrouperKeepsRecoveriesApart tests that a recovery is not folded into
+// the anomaly's group
+func TestGrouperKeepsRecoveriesApart(t *testing.T) {
+	sink := &recordingSink{}
+	grouper := NewGrouper(sink, time.Hour, nil)
+
+	grouper.Alert(testEvent(models.SeverityCritical))
+	recovery := testEvent(models.SeverityCritical)
+	recovery.Recovered = true
+	grouper.Alert(recovery)
+
+	got := sink.received()
+	if len(got) != 2 || !got[1].Recovered {
+		t.Errorf("expected the recovery forwarded immediately, got %+v", got)
+	}
+}
@@ -0,0 +1,162 @@
+package alerting
+
+import (
+	"fmt"
+	"strings"
+
+	"orbitstream/models"
+)
+
+// runbookEntry attaches a runbook to an anomaly type, optionally limited to
+// one severity. Type matches a dimension (battery), metric
+// (battery_charge_percent) or detector (statistical); "*" matches any.
+type runbookEntry struct {
+	anomalyType string
+	severity    models.AnomalySeverity
+	runbook     models.Runbook
+}
+
+// Runbooks maps anomaly types and severities to remediation links, so the
+// responder paged for an alert gets the playbook in the alert itself
+type Runbooks struct {
+	entries []runbookEntry
+}
+
+// ParseRunbooks parses semicolon-separated runbook definitions of the form
+//
+//	type[:severity]=<url>[|action|action...]
+//
+// e.g. "battery:critical=https://wiki/runbooks/power|Enter safe mode|Shed payload load".
+// A nil result (empty spec) attaches nothing.
+func ParseRunbooks(spec string) (*Runbooks, error) {
+	var entries []runbookEntry
+	for _, def := range strings.Split(spec, ";") {
+		def = strings.TrimSpace(def)
+		if def == "" {
+			continue
+		}
+		head, body, ok := strings.Cut(def, "=")
+		if !ok {
+			return nil, fmt.Errorf("runbook %q: expected type=url", def)
+		}
+
+		anomalyType, severity, hasSeverity := strings.Cut(strings.TrimSpace(head), ":")
+		entry := runbookEntry{anomalyType: strings.TrimSpace(anomalyType)}
+		if entry.anomalyType == "" {
+			return nil, fmt.Errorf("runbook %q: anomaly type is required", def)
+		}
+		if hasSeverity {
+			entry.severity = models.AnomalySeverity(strings.TrimSpace(severity))
+			if !entry.severity.Valid() {
+				return nil, fmt.Errorf("runbook %s: unknown severity %q", entry.anomalyType, severity)
+			}
+		}
+
+		parts := strings.Split(body, "|")
+		entry.runbook.URL = strings.TrimSpace(parts[0])
+		for _, action := range parts[1:] {
+			if action = strings.TrimSpace(action); action != "" {
+				entry.runbook.Actions = append(entry.runbook.Actions, action)
+			}
+		}
+		if entry.runbook.URL == "" && len(entry.runbook.Actions) == 0 {
+			return nil, fmt.Errorf("runbook %s: url or actions required", entry.anomalyType)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	return &Runbooks{entries: entries}, nil
+}
+
+// Lookup returns the most specific runbook for an anomaly of any of the
+// given types at severity: a named type beats "*", and an exact severity
+// beats none. Earlier definitions win ties. Nil when nothing matches.
+func (r *Runbooks) Lookup(severity models.AnomalySeverity, types ...string) *models.Runbook {
+	if r == nil {
+		return nil
+	}
+	var best *runbookEntry
+	bestScore := 0
+	for i := range r.entries {
+		entry := &r.entries[i]
+		if entry.severity != "" && entry.severity != severity {
+			continue
+		}
+		score := 0
+		if entry.anomalyType != "*" {
+			if !containsType(types, entry.anomalyType) {
+				continue
+			}
+			score += 2
+		}
+		if entry.severity != "" {
+			score++
+		}
+		if best == nil || score > bestScore {
+			best, bestScore = entry, score
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	runbook := best.runbook
+	return &runbook
+}
+
+// ForEvent returns the runbook for an alert event
+func (r *Runbooks) ForEvent(event models.AnomalyEvent) *models.Runbook {
+	return r.Lookup(event.Severity, string(event.Dimension), event.Metric, event.Detector)
+}
+
+// ForPoints returns the runbooks for the distinct anomaly kinds among points
+func (r *Runbooks) ForPoints(points []models.TelemetryPoint) []models.AnomalyRunbook {
+	if r == nil {
+		return nil
+	}
+	type kind struct {
+		detector  string
+		dimension models.AnomalyDimension
+		severity  models.AnomalySeverity
+	}
+	var runbooks []models.AnomalyRunbook
+	seen := make(map[kind]bool)
+	for _, point := range points {
+		if !point.IsAnomaly || point.AnomalySeverity == nil {
+			continue
+		}
+		k := kind{severity: *point.AnomalySeverity}
+		if point.AnomalyDetector != nil {
+			k.detector = *point.AnomalyDetector
+		}
+		if point.AnomalyDimension != nil {
+			k.dimension = *point.AnomalyDimension
+		}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		// Stored rows list every detector that fired
+		types := append(strings.Split(k.detector, ","), string(k.dimension))
+		if runbook := r.Lookup(k.severity, types...); runbook != nil {
+			runbooks = append(runbooks, models.AnomalyRunbook{
+				Detector:  k.detector,
+				Dimension: k.dimension,
+				Severity:  k.severity,
+				Runbook:   *runbook,
+			})
+		}
+	}
+	return runbooks
+}
+
+func containsType(types []string, target string) bool {
+	for _, t := range types {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package alerting
+
+import (
+	"testing"
+
+	"orbitstream/models"
+)
+
+// TestRunbooksLookupPrefersSpecificMatch tests type and severity specificity
+func TestRunbooksLookupPrefersSpecificMatch(t *testing.T) {
+	runbooks, err := ParseRunbooks(
+		"*=https://wiki/general; battery=https://wiki/power; battery:critical=https://wiki/power-critical|Enter safe mode|Shed payload load; statistical=https://wiki/drift")
+	if err != nil {
+		t.Fatalf("failed to parse runbooks: %v", err)
+	}
+
+	tests := []struct {
+		severity models.AnomalySeverity
+		types    []string
+		want     string
+	}{
+		{models.SeverityCritical, []string{"battery"}, "https://wiki/power-critical"},
+		{models.SeverityWarning, []string{"battery"}, "https://wiki/power"},
+		{models.SeverityWarning, []string{"signal"}, "https://wiki/general"},
+		{models.SeverityWarning, []string{"statistical", "storage"}, "https://wiki/drift"},
+	}
+	for _, tt := range tests {
+		runbook := runbooks.Lookup(tt.severity, tt.types...)
+		if runbook == nil || runbook.URL != tt.want {
+			t.Errorf("%s %v: expected %s, got %+v", tt.severity, tt.types, tt.want, runbook)
+		}
+	}
+
+	critical := runbooks.Lookup(models.SeverityCritical, "battery")
+	if len(critical.Actions) != 2 || critical.Actions[0] != "Enter safe mode" {
+		t.Errorf("expected suggested actions, got %+v", critical.Actions)
+	}
+}
+
+// TestParseRunbooksErrors tests invalid definitions
+func TestParseRunbooksErrors(t *testing.T) {
+	for _, bad := range []string{"battery", "=https://wiki", "battery:urgent=https://wiki", "battery="} {
+		if _, err := ParseRunbooks(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+	if runbooks, err := ParseRunbooks(""); err != nil || runbooks != nil {
+		t.Errorf("expected no runbooks for empty spec, got %v (%v)", runbooks, err)
+	}
+	// A nil set attaches nothing
+	var none *Runbooks
+	if none.Lookup(models.SeverityCritical, "battery") != nil {
+		t.Error("expected nil runbooks to match nothing")
+	}
+}
+
+// TestRunbooksForPoints tests one runbook per distinct stored anomaly kind
+func TestRunbooksForPoints(t *testing.T) {
+	runbooks, err := ParseRunbooks("signal=https://wiki/signal")
+	if err != nil {
+		t.Fatalf("failed to parse runbooks: %v", err)
+	}
+
+	detector := "threshold,statistical"
+	severity := models.SeverityCritical
+	signal, battery := models.DimensionSignal, models.DimensionBattery
+	anomaly := func(dimension *models.AnomalyDimension) models.TelemetryPoint {
+		return models.TelemetryPoint{IsAnomaly: true, AnomalyDetector: &detector, AnomalySeverity: &severity, AnomalyDimension: dimension}
+	}
+	points := []models.TelemetryPoint{anomaly(&signal), anomaly(&signal), anomaly(&battery), {}}
+
+	result := runbooks.ForPoints(points)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 runbook, got %+v", result)
+	}
+	if result[0].Dimension != signal || result[0].Detector != detector || result[0].URL != "https://wiki/signal" {
+		t.Errorf("unexpected runbook %+v", result[0])
+	}
+}
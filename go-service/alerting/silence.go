@@ -0,0 +1,111 @@
+package alerting
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+)
+
+// Silence mutes alerts for a satellite until ExpiresAt, e.g. during a
+// planned maneuver. Metric may name a telemetry metric
+// (signal_strength_dbm) or a dimension (signal); empty mutes everything.
+type Silence struct {
+	ID          string    `json:"id"`
+	SatelliteID string    `json:"satellite_id"`
+	Metric      string    `json:"metric,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// matches reports whether the silence applies to the event at now
+func (s Silence) matches(event models.AnomalyEvent, now time.Time) bool {
+	if !now.Before(s.ExpiresAt) || s.SatelliteID != event.SatelliteID {
+		return false
+	}
+	return s.Metric == "" || s.Metric == event.Metric || s.Metric == string(event.Dimension)
+}
+
+// Silences is an in-memory set of alert silences. Expired silences are
+// pruned lazily; silences do not survive a restart.
+type Silences struct {
+	mu     sync.Mutex
+	items  map[string]Silence
+	nextID int
+}
+
+// NewSilences creates an empty silence set
+func NewSilences() *Silences {
+	return &Silences{items: make(map[string]Silence)}
+}
+
+// Add silences a satellite (and optionally one metric) for duration
+func (s *Silences) Add(satelliteID, metric, reason string, duration time.Duration) Silence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	now := time.Now().UTC()
+	silence := Silence{
+		ID:          strconv.Itoa(s.nextID),
+		SatelliteID: satelliteID,
+		Metric:      metric,
+		Reason:      reason,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(duration),
+	}
+	s.items[silence.ID] = silence
+	return silence
+}
+
+// Remove lifts a silence early, reporting whether it existed
+func (s *Silences) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.items[id]
+	delete(s.items, id)
+	return ok
+}
+
+// Active returns unexpired silences, soonest to expire first
+func (s *Silences) Active() []Silence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(time.Now())
+	active := make([]Silence, 0, len(s.items))
+	for _, silence := range s.items {
+		active = append(active, silence)
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].ExpiresAt.Before(active[j].ExpiresAt)
+	})
+	return active
+}
+
+// Silenced reports whether an active silence matches the event
+func (s *Silences) Silenced(event models.AnomalyEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pruneLocked(now)
+	for _, silence := range s.items {
+		if silence.matches(event, now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Silences) pruneLocked(now time.Time) {
+	for id, silence := range s.items {
+		if !now.Before(silence.ExpiresAt) {
+			delete(s.items, id)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"orbitstream/models"
+	"orbitstream/retry"
+)
+
+// WebhookPayload is the JSON body POSTed for every anomaly event.
+// Text is a one-line summary so chat webhooks (e.g. Slack incoming
+// webhooks) render something readable without a custom integration.
+type WebhookPayload struct {
+	Text string `json:"text"`
+	models.AnomalyEvent
+}
+
+// WebhookConfig configures a WebhookAlerter
+type WebhookConfig struct {
+	URLs        []string
+	Timeout     time.Duration
+	MaxRetries  int
+	RetryDelay  time.Duration
+	MinSeverity models.AnomalySeverity
+	QueueSize   int
+	// Runbooks attached to events that do not carry one yet (optional)
+	Runbooks *Runbooks
+}
+
+// WebhookAlerter POSTs anomaly events to webhook URLs.
+//
+// Alert only queues the event, so the ingest path is never slowed down by a
+// slow or unreachable webhook. A background worker delivers each event to
+// every URL with retry and exponential backoff. Events are dropped (and
+// counted) when the queue is full or all retries fail.
+type WebhookAlerter struct {
+	urls        []string
+	client      *http.Client
+	maxRetries  int
+	retryDelay  time.Duration
+	minSeverity models.AnomalySeverity
+	runbooks    *Runbooks
+	queue       chan models.AnomalyEvent
+	stopCh      chan struct{}
+	wg          sync.WaitGroup
+	sent        atomic.Int64
+	dropped     atomic.Int64
+}
+
+// NewWebhookAlerter creates an alerter; call Start to begin delivery
+func NewWebhookAlerter(cfg WebhookConfig) *WebhookAlerter {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 1
+	}
+	return &WebhookAlerter{
+		urls:        cfg.URLs,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		maxRetries:  cfg.MaxRetries,
+		retryDelay:  cfg.RetryDelay,
+		minSeverity: cfg.MinSeverity,
+		runbooks:    cfg.Runbooks,
+		queue:       make(chan models.AnomalyEvent, cfg.QueueSize),
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// Start begins the background delivery loop
+func (a *WebhookAlerter) Start() {
+	a.wg.Add(1)
+	go a.run()
+}
+
+// Stop delivers whatever is queued and stops the background loop
+func (a *WebhookAlerter) Stop() {
+	close(a.stopCh)
+	a.wg.Wait()
+}
+
+// Alert queues an event for delivery (never blocks)
+func (a *WebhookAlerter) Alert(event models.AnomalyEvent) {
+	if event.Severity.Rank() < a.minSeverity.Rank() {
+		return
+	}
+	select {
+	case a.queue <- event:
+	default:
+		a.dropped.Add(1)
+		log.Printf("WebhookAlerter: queue full, dropping alert for %s", event.SatelliteID)
+	}
+}
+
+// Sent returns the number of events delivered to every URL
+func (a *WebhookAlerter) Sent() int64 {
+	return a.sent.Load()
+}
+
+// Dropped returns the number of events not delivered to at least one URL
+func (a *WebhookAlerter) Dropped() int64 {
+	return a.dropped.Load()
+}
+
+func (a *WebhookAlerter) run() {
+	defer a.wg.Done()
+	for {
+		select {
+		case event := <-a.queue:
+			a.deliver(event)
+		case <-a.stopCh:
+			for {
+				select {
+				case event := <-a.queue:
+					a.deliver(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver sends one event to every configured URL
+func (a *WebhookAlerter) deliver(event models.AnomalyEvent) {
+	if event.Runbook == nil {
+		event.Runbook = a.runbooks.ForEvent(event)
+	}
+	body, err := json.Marshal(WebhookPayload{Text: summary(event), AnomalyEvent: event})
+	if err != nil {
+		log.Printf("WebhookAlerter: failed to marshal alert: %v", err)
+		a.dropped.Add(1)
+		return
+	}
+
+	delivered := true
+	for _, url := range a.urls {
+		if !a.post(url, body) {
+			delivered = false
+		}
+	}
+	if delivered {
+		a.sent.Add(1)
+	} else {
+		a.dropped.Add(1)
+	}
+}
+
+// post sends the body to one URL with retry and exponential backoff
+func (a *WebhookAlerter) post(url string, body []byte) bool {
+	policy := retry.Policy{MaxAttempts: a.maxRetries, BaseDelay: a.retryDelay}
+	err := retry.Do(context.Background(), policy, func(ctx context.Context, attempt int) error {
+		resp, err := a.client.Post(url, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("webhook returned %s", resp.Status)
+		}
+		log.Printf("WebhookAlerter: %s attempt %d failed: %v", url, attempt, err)
+		return err
+	})
+	return err == nil
+}
+
+// summary renders a one-line human readable description of the event
+func summary(event models.AnomalyEvent) string {
+	if event.Recovered {
+		text := fmt.Sprintf("[%s] %s %s anomaly recovered: %s = %.2f detected by %s",
+			event.Severity, event.SatelliteID, event.Dimension, event.Metric,
+			event.Value, event.Detector)
+		if event.Episode != nil {
+			text += fmt.Sprintf(" (lasted %v, %d points, peak %.2f)",
+				time.Duration(event.Episode.DurationSeconds*float64(time.Second)).Round(time.Second),
+				event.Episode.Points, event.Episode.PeakValue)
+		}
+		if event.Count > 1 {
+			text += fmt.Sprintf(" (repeated %d times)", event.Count)
+		}
+		return text
+	}
+	text := fmt.Sprintf("[%s] %s %s anomaly: %s = %.2f (threshold %.2f) detected by %s",
+		event.Severity, event.SatelliteID, event.Dimension, event.Metric,
+		event.Value, event.Threshold, event.Detector)
+	if event.Count > 1 {
+		text += fmt.Sprintf(" (repeated %d times)", event.Count)
+	}
+	if event.Runbook != nil && event.Runbook.URL != "" {
+		text += " - runbook: " + event.Runbook.URL
+	}
+	return text
+}
@@ -0,0 +1,188 @@
+package alerting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+func testEvent(severity models.AnomalySeverity) models.AnomalyEvent {
+	return models.AnomalyEvent{
+		SatelliteID: "SAT-0042",
+		Timestamp:   time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		AnomalyResult: models.AnomalyResult{
+			Detector:  "threshold",
+			Severity:  severity,
+			Dimension: models.DimensionSignal,
+			Metric:    "signal_strength_dbm",
+			Value:     -104.5,
+			Threshold: -100.0,
+		},
+	}
+}
+
+// TestWebhookAlerterDeliversPayload tests that events are posted with metric, value and threshold
+func TestWebhookAlerterDeliversPayload(t *testing.T) {
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		mu.Lock()
+		received = append(received, payload)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	alerter := NewWebhookAlerter(WebhookConfig{URLs: []string{server.URL}, Timeout: time.Second, MaxRetries: 3})
+	alerter.Start()
+	alerter.Alert(testEvent(models.SeverityCritical))
+	alerter.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 {
+		t.Fatalf("expected 1 webhook call, got %d", len(received))
+	}
+	payload := received[0]
+	if payload["satellite_id"] != "SAT-0042" || payload["metric"] != "signal_strength_dbm" {
+		t.Errorf("unexpected payload: %v", payload)
+	}
+	if payload["value"] != -104.5 || payload["threshold"] != -100.0 {
+		t.Errorf("expected value and threshold in payload, got %v", payload)
+	}
+	if payload["text"] == "" {
+		t.Error("expected text summary for chat webhooks")
+	}
+	if alerter.Sent() != 1 {
+		t.Errorf("expected 1 sent, got %d", alerter.Sent())
+	}
+}
+
+// TestWebhookAlerterRetries tests that failed deliveries are retried with backoff
+func TestWebhookAlerterRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	alerter := NewWebhookAlerter(WebhookConfig{
+		URLs:       []string{server.URL},
+		Timeout:    time.Second,
+		MaxRetries: 3,
+		RetryDelay: time.Millisecond,
+	})
+	alerter.Start()
+	alerter.Alert(testEvent(models.SeverityCritical))
+	alerter.Stop()
+
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls.Load())
+	}
+	if alerter.Sent() != 1 || alerter.Dropped() != 0 {
+		t.Errorf("expected delivery after retries, sent=%d dropped=%d", alerter.Sent(), alerter.Dropped())
+	}
+}
+
+// TestWebhookAlerterGivesUp tests that events are dropped after all retries fail
+func TestWebhookAlerterGivesUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	alerter := NewWebhookAlerter(WebhookConfig{
+		URLs:       []string{server.URL},
+		Timeout:    time.Second,
+		MaxRetries: 2,
+		RetryDelay: time.Millisecond,
+	})
+	alerter.Start()
+	alerter.Alert(testEvent(models.SeverityCritical))
+	alerter.Stop()
+
+	if alerter.Dropped() != 1 {
+		t.Errorf("expected 1 dropped alert, got %d", alerter.Dropped())
+	}
+}
+
+// TestWebhookAlerterMinSeverity tests that events below the minimum severity are ignored
+func TestWebhookAlerterMinSeverity(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	alerter := NewWebhookAlerter(WebhookConfig{
+		URLs:        []string{server.URL},
+		Timeout:     time.Second,
+		MinSeverity: models.SeverityWarning,
+	})
+	alerter.Start()
+	alerter.Alert(testEvent(models.SeverityInfo))
+	alerter.Alert(testEvent(models.SeverityWarning))
+	alerter.Stop()
+
+	if calls.Load() != 1 {
+		t.Errorf("expected only the warning to be sent, got %d calls", calls.Load())
+	}
+}
+
+// TestWebhookAlerterAttachesRunbook tests that the matching runbook is in the payload and summary
+func TestWebhookAlerterAttachesRunbook(t *testing.T) {
+	var mu sync.Mutex
+	var received []WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		mu.Lock()
+		received = append(received, payload)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	runbooks, err := ParseRunbooks("signal:critical=https://wiki.example/runbooks/signal|Check ground station lock")
+	if err != nil {
+		t.Fatalf("failed to parse runbooks: %v", err)
+	}
+	alerter := NewWebhookAlerter(WebhookConfig{URLs: []string{server.URL}, Timeout: time.Second, Runbooks: runbooks})
+	alerter.Start()
+	alerter.Alert(testEvent(models.SeverityCritical))
+	alerter.Alert(testEvent(models.SeverityWarning))
+	alerter.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("expected 2 webhook calls, got %d", len(received))
+	}
+	runbook := received[0].Runbook
+	if runbook == nil || runbook.URL != "https://wiki.example/runbooks/signal" || len(runbook.Actions) != 1 {
+		t.Errorf("expected signal runbook in payload, got %+v", runbook)
+	}
+	if !strings.Contains(received[0].Text, "runbook: https://wiki.example/runbooks/signal") {
+		t.Errorf("expected runbook link in summary, got %q", received[0].Text)
+	}
+	if received[1].Runbook != nil {
+		t.Errorf("expected no runbook for warning, got %+v", received[1].Runbook)
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -305,6 +306,7 @@ func TestGetEnvDuration(t *testing.T) {
 
 // Helper function to clear all environment variables used by config
 func unsetEnvVars() {
+	os.Unsetenv("ORBITSTREAM_PROFILE")
 	os.Unsetenv("PORT")
 	os.Unsetenv("DATABASE_URL")
 	os.Unsetenv("BATCH_SIZE")
@@ -314,3 +316,201 @@ func unsetEnvVars() {
 	os.Unsetenv("ANOMALY_THRESHOLD_STORAGE")
 	os.Unsetenv("ANOMALY_THRESHOLD_SIGNAL")
 }
+
+func TestGetEnvQueryLimitOverrides(t *testing.T) {
+	os.Setenv("TEST_QUERY_OVERRIDES", "analyst:raw:72h:50000, reporting:aggregate:17520h:100000,bad-entry,ops:raw:notaduration:10")
+	defer os.Unsetenv("TEST_QUERY_OVERRIDES")
+
+	overrides := getEnvQueryLimitOverrides("TEST_QUERY_OVERRIDES")
+
+	if len(overrides) != 2 {
+		t.Fatalf("expected 2 valid overrides, got %d", len(overrides))
+	}
+	if overrides[0].Principal != "analyst" || overrides[0].Class != "raw" ||
+		overrides[0].MaxRange != 72*time.Hour || overrides[0].MaxRows != 50000 {
+		t.Errorf("unexpected first override: %+v", overrides[0])
+	}
+	if overrides[1].Principal != "reporting" || overrides[1].MaxRows != 100000 {
+		t.Errorf("unexpected second override: %+v", overrides[1])
+	}
+
+	if got := getEnvQueryLimitOverrides("TEST_QUERY_OVERRIDES_UNSET"); got != nil {
+		t.Errorf("expected nil overrides when unset, got %v", got)
+	}
+}
+
+func TestGetEnvAPIKeys(t *testing.T) {
+	loadProblems = nil
+	t.Setenv("TEST_API_KEYS", "station-a:ingest:abc:def, dashboard::xyz,missing-key:ingest:,bad-entry")
+
+	keys := getEnvAPIKeys("TEST_API_KEYS")
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 valid keys, got %d", len(keys))
+	}
+	if keys[0] != (APIKey{ID: "station-a", Role: "ingest", Key: "abc:def"}) {
+		t.Errorf("unexpected first key: %+v", keys[0])
+	}
+	if keys[1] != (APIKey{ID: "dashboard", Key: "xyz"}) {
+		t.Errorf("unexpected second key: %+v", keys[1])
+	}
+	if len(loadProblems) != 2 || strings.Contains(strings.Join(loadProblems, " "), "abc") {
+		t.Errorf("expected 2 problems without key material, got %v", loadProblems)
+	}
+	loadProblems = nil
+}
+
+func TestGetEnvClientCerts(t *testing.T) {
+	loadProblems = nil
+	t.Setenv("TEST_CLIENT_CERTS", "mission-ops:admin:CN=mission-ops,O=Ground; station-a::CN=station-a,O=Ground")
+
+	certs := getEnvClientCerts("TEST_CLIENT_CERTS")
+	if len(certs) != 2 {
+		t.Fatalf("expected 2 valid certificates, got %d", len(certs))
+	}
+	if certs[0] != (ClientCert{ID: "mission-ops", Role: "admin", Subject: "CN=mission-ops,O=Ground"}) {
+		t.Errorf("unexpected first certificate: %+v", certs[0])
+	}
+	if certs[1] != (ClientCert{ID: "station-a", Subject: "CN=station-a,O=Ground"}) {
+		t.Errorf("unexpected second certificate: %+v", certs[1])
+	}
+	if len(loadProblems) != 0 {
+		t.Errorf("expected no problems, got %v", loadProblems)
+	}
+	t.Setenv("TEST_CLIENT_CERTS", "no-subject:admin:;bad-entry")
+	if certs := getEnvClientCerts("TEST_CLIENT_CERTS"); len(certs) != 0 || len(loadProblems) != 2 {
+		t.Errorf("expected 2 problems and no certificates, got %+v and %v", certs, loadProblems)
+	}
+	loadProblems = nil
+}
+
+func TestGetEnvKeySatellites(t *testing.T) {
+	loadProblems = nil
+	t.Setenv("TEST_KEY_SATELLITES", "leo-team=LEO-*, geo-team=GEO-*|SAT-00??,bad-entry")
+
+	satellites := getEnvKeySatellites("TEST_KEY_SATELLITES")
+	if len(satellites) != 2 || len(satellites["leo-team"]) != 1 || len(satellites["geo-team"]) != 2 {
+		t.Errorf("unexpected satellites: %v", satellites)
+	}
+	if len(loadProblems) != 1 {
+		t.Errorf("expected the malformed entry to be reported, got %v", loadProblems)
+	}
+	loadProblems = nil
+}
+
+func TestGetEnvBool(t *testing.T) {
+	tests := []struct {
+		name          string
+		envValue      string
+		defaultValue  bool
+		expectedValue bool
+	}{
+		{"true value", "true", false, true},
+		{"numeric true", "1", false, true},
+		{"false value", "false", true, false},
+		{"invalid uses default", "maybe", true, true},
+		{"empty uses default", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envValue != "" {
+				os.Setenv("TEST_BOOL_VAR", tt.envValue)
+			} else {
+				os.Unsetenv("TEST_BOOL_VAR")
+			}
+			result := getEnvBool("TEST_BOOL_VAR", tt.defaultValue)
+			if result != tt.expectedValue {
+				t.Errorf("expected %v, got %v", tt.expectedValue, result)
+			}
+			os.Unsetenv("TEST_BOOL_VAR")
+		})
+	}
+}
+
+func TestGetEnvStringSlice(t *testing.T) {
+	os.Setenv("TEST_SLICE_VAR", " 10.0.0.0/8, ,192.168.1.5 ")
+	defer os.Unsetenv("TEST_SLICE_VAR")
+
+	items := getEnvStringSlice("TEST_SLICE_VAR")
+	if len(items) != 2 || items[0] != "10.0.0.0/8" || items[1] != "192.168.1.5" {
+		t.Errorf("unexpected items: %v", items)
+	}
+	if got := getEnvStringSlice("TEST_SLICE_VAR_UNSET"); got != nil {
+		t.Errorf("expected nil for unset variable, got %v", got)
+	}
+}
+
+func TestLoadConfigProfileDefaults(t *testing.T) {
+	unsetEnvVars()
+	os.Setenv("ORBITSTREAM_PROFILE", ProfileEdgeLowMemory)
+	defer os.Unsetenv("ORBITSTREAM_PROFILE")
+
+	cfg := LoadConfig()
+
+	if cfg.Profile != ProfileEdgeLowMemory {
+		t.Errorf("expected Profile to be %q, got %q", ProfileEdgeLowMemory, cfg.Profile)
+	}
+	if cfg.BatchSize != 200 {
+		t.Errorf("expected BatchSize to be 200, got %d", cfg.BatchSize)
+	}
+	if cfg.MaxConnections != 5 {
+		t.Errorf("expected MaxConnections to be 5, got %d", cfg.MaxConnections)
+	}
+	if cfg.BatchTimeout != 2*time.Second {
+		t.Errorf("expected BatchTimeout to be 2s, got %v", cfg.BatchTimeout)
+	}
+	// Settings the profile does not cover keep their built-in defaults
+	if cfg.Port != "8080" {
+		t.Errorf("expected Port to be '8080', got '%s'", cfg.Port)
+	}
+}
+
+func TestLoadConfigProfileEnvOverrides(t *testing.T) {
+	unsetEnvVars()
+	os.Setenv("ORBITSTREAM_PROFILE", ProfileRegionalHighThroughput)
+	os.Setenv("BATCH_SIZE", "750")
+	defer os.Unsetenv("ORBITSTREAM_PROFILE")
+	defer os.Unsetenv("BATCH_SIZE")
+
+	cfg := LoadConfig()
+
+	if cfg.BatchSize != 750 {
+		t.Errorf("expected explicit BatchSize 750 to win over profile, got %d", cfg.BatchSize)
+	}
+	if cfg.MaxConnections != 100 {
+		t.Errorf("expected profile MaxConnections 100, got %d", cfg.MaxConnections)
+	}
+}
+
+func TestLoadConfigUnknownProfile(t *testing.T) {
+	unsetEnvVars()
+	os.Setenv("ORBITSTREAM_PROFILE", "does-not-exist")
+	defer os.Unsetenv("ORBITSTREAM_PROFILE")
+
+	cfg := LoadConfig()
+
+	if cfg.Profile != "" {
+		t.Errorf("expected unknown profile to be ignored, got %q", cfg.Profile)
+	}
+	if cfg.BatchSize != 1000 {
+		t.Errorf("expected default BatchSize 1000, got %d", cfg.BatchSize)
+	}
+}
+
+func TestLoadConfigAllProfiles(t *testing.T) {
+	unsetEnvVars()
+	for _, name := range ProfileNames() {
+		os.Setenv("ORBITSTREAM_PROFILE", name)
+		cfg := LoadConfig()
+		if cfg.Profile != name {
+			t.Errorf("profile %q not selected", name)
+		}
+		if cfg.BatchSize <= 0 || cfg.MaxConnections <= 0 || cfg.MaxBufferSize <= 0 {
+			t.Errorf("profile %q produced invalid sizes: %+v", name, cfg)
+		}
+		if cfg.ReservedConnections >= cfg.MaxConnections {
+			t.Errorf("profile %q reserves all %d connections", name, cfg.MaxConnections)
+		}
+	}
+	os.Unsetenv("ORBITSTREAM_PROFILE")
+}
@@ -0,0 +1,20 @@
+package config
+
+import "reflect"
+
+// ChangedSettings returns the names of the Config fields whose values differ
+// between old and updated, in declaration order
+func ChangedSettings(old, updated Config) []string {
+	oldValue, updatedValue := reflect.ValueOf(old), reflect.ValueOf(updated)
+	var changed []string
+	for i := 0; i < oldValue.NumField(); i++ {
+		field := oldValue.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), updatedValue.Field(i).Interface()) {
+			changed = append(changed, field.Name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestChangedSettings tests changed fields are named in declaration order
+func TestChangedSettings(t *testing.T) {
+	unsetEnvVars()
+	old := LoadConfig()
+	updated := old
+	updated.AnomalyThresholdBattery = old.AnomalyThresholdBattery + 5
+	updated.BatchSize = old.BatchSize * 2
+	updated.CORSAllowedOrigins = []string{"https://ops.example.com"}
+
+	changed := ChangedSettings(old, updated)
+	want := []string{"BatchSize", "AnomalyThresholdBattery", "CORSAllowedOrigins"}
+	if strings.Join(changed, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, changed)
+	}
+	if changed := ChangedSettings(old, old); len(changed) != 0 {
+		t.Errorf("expected no changes, got %v", changed)
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Settings collects KEY=value command line settings; it implements
+// flag.Value so -set can be repeated
+type Settings []string
+
+func (s *Settings) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set adds one KEY=value setting
+func (s *Settings) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("setting %q must be KEY=value", value)
+	}
+	*s = append(*s, value)
+	return nil
+}
+
+// The layers around the environment consulted by lookupEnv while
+// LoadConfigFromFile runs: command line settings win over the environment,
+// the file only over the profile and built-in defaults
+var (
+	activeSettings map[string]string
+	activeFile     map[string]string
+	// Keys looked up by LoadConfig, so unknown file keys can be reported
+	consultedKeys map[string]bool
+)
+
+// LoadConfigFromFile loads the configuration with the values of the YAML or
+// TOML file at path (by extension; "" = no file) and the KEY=value
+// settings. Precedence is settings > environment > file > profile >
+// built-in defaults. File keys are the environment variable names in any
+// case, and nested tables join their keys with "_", so
+//
+//	wal:
+//	  path: /data/wal/data.wal
+//
+// sets WAL_PATH; lists become comma-separated values. Keys no setting reads
+// are an error, so a typo can't silently keep the default.
+func LoadConfigFromFile(path string, settings Settings) (Config, error) {
+	file := map[string]string{}
+	if path != "" {
+		var err error
+		if file, err = readConfigFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	flags := make(map[string]string, len(settings))
+	for _, setting := range settings {
+		key, value, _ := strings.Cut(setting, "=")
+		flags[strings.ToUpper(strings.TrimSpace(key))] = value
+	}
+
+	activeSettings, activeFile, consultedKeys = flags, file, map[string]bool{}
+	defer func() { activeSettings, activeFile, consultedKeys = nil, nil, nil }()
+	cfg := LoadConfig()
+
+	var unknown []string
+	for key := range file {
+		if !consultedKeys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	for key := range flags {
+		if !consultedKeys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return Config{}, fmt.Errorf("unknown configuration keys: %s", strings.Join(unknown, ", "))
+	}
+	return cfg, nil
+}
+
+// readConfigFile parses a YAML or TOML file into flat settings
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var tree map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("config file %s: unknown format %q (want .yaml, .yml or .toml)", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	settings := make(map[string]string)
+	if err := flattenConfig("", tree, settings); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// flattenConfig adds the values of tree to settings under their upper-cased
+// keys, joining nested keys with "_"
+func flattenConfig(prefix string, tree map[string]interface{}, settings map[string]string) error {
+	for key, value := range tree {
+		key = strings.ToUpper(key)
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flattenConfig(key, v, settings); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				if _, nested := item.(map[string]interface{}); nested {
+					return fmt.Errorf("%s: lists of tables are not supported", key)
+				}
+				items[i] = fmt.Sprint(item)
+			}
+			settings[key] = strings.Join(items, ",")
+		case nil:
+			settings[key] = ""
+		default:
+			settings[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFromYAMLFile(t *testing.T) {
+	unsetEnvVars()
+	path := writeConfigFile(t, "orbitstream.yaml", `
+batch_size: 500
+BATCH_TIMEOUT: 3s
+wal:
+  path: /data/wal/data.wal
+  replay_rate: 2000
+trusted_proxies:
+  - 10.0.0.1
+  - 10.0.0.2
+`)
+
+	cfg, err := LoadConfigFromFile(path, nil)
+	if err != nil {
+		t.Fatalf("LoadConfigFromFile failed: %v", err)
+	}
+	if cfg.BatchSize != 500 || cfg.BatchTimeout != 3*time.Second {
+		t.Errorf("expected batch settings from the file, got %d %v", cfg.BatchSize, cfg.BatchTimeout)
+	}
+	if cfg.WALPath != "/data/wal/data.wal" || cfg.WALReplayRate != 2000 {
+		t.Errorf("expected nested WAL settings from the file, got %q %d", cfg.WALPath, cfg.WALReplayRate)
+	}
+	if len(cfg.TrustedProxies) != 2 || cfg.TrustedProxies[1] != "10.0.0.2" {
+		t.Errorf("expected the proxy list from the file, got %v", cfg.TrustedProxies)
+	}
+	// Settings the file doesn't cover keep their defaults
+	if cfg.Port != "8080" {
+		t.Errorf("expected default Port, got %q", cfg.Port)
+	}
+}
+
+func TestLoadConfigFromFilePrecedence(t *testing.T) {
+	unsetEnvVars()
+	path := writeConfigFile(t, "orbitstream.toml", `
+orbitstream_profile = "edge-low-memory"
+batch_size = 500
+max_connections = 8
+
+[circuit_breaker]
+threshold = 7
+`)
+	t.Setenv("BATCH_SIZE", "750")
+	t.Setenv("MAX_CONNECTIONS", "12")
+
+	cfg, err := LoadConfigFromFile(path, Settings{"MAX_CONNECTIONS=20"})
+	if err != nil {
+		t.Fatalf("LoadConfigFromFile failed: %v", err)
+	}
+	if cfg.MaxConnections != 20 {
+		t.Errorf("expected the -set value to win, got %d", cfg.MaxConnections)
+	}
+	if cfg.BatchSize != 750 {
+		t.Errorf("expected the environment to win over the file, got %d", cfg.BatchSize)
+	}
+	if cfg.CircuitBreakerThreshold != 7 {
+		t.Errorf("expected the file to win over the profile, got %d", cfg.CircuitBreakerThreshold)
+	}
+	if cfg.Profile != ProfileEdgeLowMemory || cfg.BatchTimeout != 2*time.Second {
+		t.Errorf("expected the profile from the file to supply the rest, got %q %v", cfg.Profile, cfg.BatchTimeout)
+	}
+
+	// The layers don't leak into later loads
+	os.Unsetenv("BATCH_SIZE")
+	if cfg := LoadConfig(); cfg.BatchSize != 1000 || cfg.Profile != "" {
+		t.Errorf("expected defaults after the file load, got %d %q", cfg.BatchSize, cfg.Profile)
+	}
+}
+
+func TestLoadConfigFromFileErrors(t *testing.T) {
+	unsetEnvVars()
+
+	typo := writeConfigFile(t, "orbitstream.yaml", "batch_sise: 500\nwal:\n  pth: /tmp\n")
+	_, err := LoadConfigFromFile(typo, nil)
+	if err == nil || !strings.Contains(err.Error(), "BATCH_SISE, WAL_PTH") {
+		t.Errorf("expected the unknown keys to be reported, got %v", err)
+	}
+	if _, err := LoadConfigFromFile("", Settings{"NOT_A_SETTING=1"}); err == nil {
+		t.Error("expected an unknown -set key to fail")
+	}
+
+	ini := writeConfigFile(t, "orbitstream.ini", "BATCH_SIZE=500\n")
+	if _, err := LoadConfigFromFile(ini, nil); err == nil {
+		t.Error("expected an unknown file format to fail")
+	}
+	var settings Settings
+	if err := settings.Set("BATCH_SIZE"); err == nil {
+		t.Error("expected a setting without = to be refused")
+	}
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"log"
+	"os"
+	"sort"
+)
+
+// Profile names selectable via ORBITSTREAM_PROFILE
+const (
+	ProfileEdgeLowMemory          = "edge-low-memory"
+	ProfileRegionalHighThroughput = "regional-high-throughput"
+	ProfileLab                    = "lab"
+)
+
+// profiles maps a profile name to environment defaults. A profile only
+// changes defaults: any variable set explicitly in the environment still wins.
+var profiles = map[string]map[string]string{
+	// Small ground-station boxes: little RAM, few DB connections, small WAL,
+	// patient retries over unreliable links
+	ProfileEdgeLowMemory: {
+		"BATCH_SIZE":                "200",
+		"BATCH_TIMEOUT":             "2s",
+		"MAX_BUFFER_SIZE":           "2000",
+		"FLUSH_WORKERS":             "1",
+		"MAX_CONNECTIONS":           "5",
+		"DB_RESERVED_CONNECTIONS":   "1",
+		"WAL_MAX_SIZE":              "16777216", // 16MB
+		"WAL_COMPRESSION":           "zstd",
+		"MAX_RETRIES":               "3",
+		"RETRY_DELAY":               "2s",
+		"CIRCUIT_BREAKER_THRESHOLD": "3",
+		"EXPORT_CHECKPOINT_ROWS":    "200",
+		"ALERT_WEBHOOK_TIMEOUT":     "10s",
+	},
+	// Regional aggregation nodes: large batches, deep buffers, big pool
+	ProfileRegionalHighThroughput: {
+		"BATCH_SIZE":                "5000",
+		"BATCH_TIMEOUT":             "500ms",
+		"MAX_BUFFER_SIZE":           "100000",
+		"FLUSH_WORKERS":             "8",
+		"MAX_CONNECTIONS":           "100",
+		"WAL_MAX_SIZE":              "1073741824", // 1GB
+		"MAX_RETRIES":               "5",
+		"RETRY_DELAY":               "500ms",
+		"CIRCUIT_BREAKER_THRESHOLD": "5",
+		"EXPORT_CHECKPOINT_ROWS":    "5000",
+	},
+	// Development and test benches: fast feedback, local WAL, relaxed
+	// query limits and statistical detection switched on
+	ProfileLab: {
+		"BATCH_SIZE":                  "100",
+		"BATCH_TIMEOUT":               "1s",
+		"MAX_BUFFER_SIZE":             "10000",
+		"MAX_CONNECTIONS":             "10",
+		"WAL_PATH":                    "./data/wal/data.wal",
+		"WAL_MAX_SIZE":                "52428800", // 50MB
+		"MAX_RETRIES":                 "1",
+		"RETRY_DELAY":                 "100ms",
+		"CIRCUIT_BREAKER_THRESHOLD":   "3",
+		"QUERY_MAX_RANGE_RAW":         "168h",
+		"ANOMALY_STATISTICAL_ENABLED": "true",
+	},
+}
+
+// activeProfile holds the defaults of the profile selected by LoadConfig
+var activeProfile map[string]string
+
+// ProfileNames returns the names of all built-in profiles
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectProfile activates the named profile, returning the name in effect
+// ("" when none or unknown)
+func selectProfile(name string) string {
+	activeProfile = nil
+	if name == "" {
+		return ""
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		log.Printf("WARNING: Unknown ORBITSTREAM_PROFILE %q (known: %v), using built-in defaults", name, ProfileNames())
+		return ""
+	}
+	activeProfile = profile
+	return name
+}
+
+// lookupEnv returns the value for key from the command line settings, the
+// environment, the config file or the active profile's defaults, in that
+// order (see LoadConfigFromFile)
+func lookupEnv(key string) string {
+	if consultedKeys != nil {
+		consultedKeys[key] = true
+	}
+	if value := activeSettings[key]; value != "" {
+		return value
+	}
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	if value := activeFile[key]; value != "" {
+		return value
+	}
+	return activeProfile[key]
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// loadProblems collects the values LoadConfig could not use
+var loadProblems []string
+
+// loadProblem records a value LoadConfig could not use for Validate
+func loadProblem(format string, args ...interface{}) {
+	loadProblems = append(loadProblems, fmt.Sprintf(format, args...))
+}
+
+// parseProblem records a value that isn't of the setting's kind; LoadConfig
+// falls back to the default
+func parseProblem(key, value, kind string) {
+	loadProblem("%s=%q is not %s", key, value, kind)
+}
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d configuration problem(s):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// Validate checks the configuration for values LoadConfig couldn't parse and
+// for nonsensical combinations, returning a *ValidationError listing all of
+// them so a deployment can be fixed in one go. Settings checked where they
+// are used (anomaly detectors, dedup modes, time zones) are not repeated.
+func (c Config) Validate() error {
+	problems := append([]string(nil), c.loadProblems...)
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	// Batching and buffering
+	check(c.BatchSize > 0, "BATCH_SIZE must be positive, got %d", c.BatchSize)
+	check(c.BatchTimeout > 0, "BATCH_TIMEOUT must be positive, got %v", c.BatchTimeout)
+	check(c.MaxBufferSize >= c.BatchSize, "MAX_BUFFER_SIZE (%d) must be at least BATCH_SIZE (%d)", c.MaxBufferSize, c.BatchSize)
+	check(c.BufferHighWaterPercent >= 0 && c.BufferHighWaterPercent <= 100,
+		"BUFFER_HIGH_WATER_PERCENT must be between 0 and 100, got %d", c.BufferHighWaterPercent)
+	check(c.ReadyBufferPercent >= 0 && c.ReadyBufferPercent <= 100,
+		"READY_BUFFER_PERCENT must be between 0 and 100, got %d", c.ReadyBufferPercent)
+	check(c.BufferShards >= 1 && c.BufferShards <= max(c.MaxBufferSize, 1),
+		"BUFFER_SHARDS must be between 1 and MAX_BUFFER_SIZE (%d), got %d", c.MaxBufferSize, c.BufferShards)
+	if c.BatchSizeAdaptive {
+		check(c.BatchSizeMin >= 1 && c.BatchSizeMax >= c.BatchSizeMin && c.BatchLatencyTarget > 0,
+			"adaptive batch size needs 1 <= BATCH_SIZE_MIN (%d) <= BATCH_SIZE_MAX (%d) and a positive BATCH_LATENCY_TARGET",
+			c.BatchSizeMin, c.BatchSizeMax)
+	}
+
+	// Connections
+	check(c.MaxConnections > 0, "MAX_CONNECTIONS must be positive, got %d", c.MaxConnections)
+	check(c.ReservedConnections >= 0 && c.ReservedConnections < c.MaxConnections,
+		"DB_RESERVED_CONNECTIONS must be between 0 and MAX_CONNECTIONS-1, got %d", c.ReservedConnections)
+	insertPool := c.MaxConnections - c.ReservedConnections
+	check(c.FlushWorkers >= 1 && c.FlushWorkers < insertPool,
+		"FLUSH_WORKERS must be between 1 and the insert pool size minus one (%d), got %d", insertPool-1, c.FlushWorkers)
+
+	// TLS
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check(c.TLSClientCAFile == "" || c.TLSCertFile != "", "TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	check(c.TLSMinVersion == "1.2" || c.TLSMinVersion == "1.3", "TLS_MIN_VERSION must be 1.2 or 1.3, got %q", c.TLSMinVersion)
+
+	// Authentication
+	keyIDs := make(map[string]bool, len(c.AuthAPIKeys))
+	for _, key := range c.AuthAPIKeys {
+		keyIDs[key.ID] = true
+	}
+	for _, cert := range c.AuthClientCerts {
+		check(!keyIDs[cert.ID], "AUTH_CLIENT_CERTS ID %q is already used", cert.ID)
+		keyIDs[cert.ID] = true
+	}
+	scopedIDs := make([]string, 0, len(c.AuthKeySatellites))
+	for id := range c.AuthKeySatellites {
+		scopedIDs = append(scopedIDs, id)
+	}
+	sort.Strings(scopedIDs)
+	for _, id := range scopedIDs {
+		check(keyIDs[id], "AUTH_KEY_SATELLITES names %q, which is not an AUTH_API_KEYS or AUTH_CLIENT_CERTS ID", id)
+		for _, pattern := range c.AuthKeySatellites[id] {
+			_, err := path.Match(pattern, "")
+			check(err == nil, "AUTH_KEY_SATELLITES pattern %q of %q is invalid", pattern, id)
+		}
+	}
+
+	check(c.PositionCheckMaxVelocityKMPH > 0, "POSITION_CHECK_MAX_VELOCITY_KMPH must be positive, got %g", c.PositionCheckMaxVelocityKMPH)
+	check(c.PositionCheckTolerance > 0, "POSITION_CHECK_TOLERANCE must be positive, got %g", c.PositionCheckTolerance)
+	check(c.PositionCheckSlackKM > 0, "POSITION_CHECK_SLACK_KM must be positive, got %g", c.PositionCheckSlackKM)
+
+	check(c.RateLimitRPS >= 0, "RATE_LIMIT_RPS must not be negative, got %g", c.RateLimitRPS)
+	if c.RateLimitRPS > 0 {
+		check(c.RateLimitBurst >= 1, "RATE_LIMIT_BURST must be at least 1, got %d", c.RateLimitBurst)
+	}
+	check(c.MaxBodyBytes >= 0, "MAX_BODY_BYTES must not be negative, got %d", c.MaxBodyBytes)
+	check(c.MaxBatchPoints >= 0, "MAX_BATCH_POINTS must not be negative, got %d", c.MaxBatchPoints)
+	check(c.AuditLogSink == "file" || c.AuditLogSink == "db" || c.AuditLogSink == "off",
+		"AUDIT_LOG_SINK must be file, db or off, got %q", c.AuditLogSink)
+	check(c.AuditLogSink != "file" || c.AuditLogPath != "", "AUDIT_LOG_SINK=file requires AUDIT_LOG_PATH")
+	check(c.IdempotencyTTL >= 0, "IDEMPOTENCY_TTL must not be negative, got %v", c.IdempotencyTTL)
+	if c.IdempotencyTTL > 0 {
+		check(c.IdempotencyMaxKeys >= 1, "IDEMPOTENCY_MAX_KEYS must be at least 1, got %d", c.IdempotencyMaxKeys)
+	}
+
+	// Payload validation (the mode and pattern are checked where they are used)
+	check(c.ValidationMinAltitudeKM < c.ValidationMaxAltitudeKM,
+		"VALIDATION_MIN_ALTITUDE_KM (%g) must be below VALIDATION_MAX_ALTITUDE_KM (%g)", c.ValidationMinAltitudeKM, c.ValidationMaxAltitudeKM)
+	check(c.ValidationMaxVelocityKMPH > 0, "VALIDATION_MAX_VELOCITY_KMPH must be positive, got %g", c.ValidationMaxVelocityKMPH)
+
+	// Warm standby
+	check(c.ReplicationMode == "" || c.ReplicationMode == "primary" || c.ReplicationMode == "standby",
+		"REPLICATION_MODE must be primary or standby, got %q", c.ReplicationMode)
+	check(c.ReplicationMode == "" || c.ReplicationToken != "", "REPLICATION_MODE=%s requires REPLICATION_TOKEN", c.ReplicationMode)
+
+	// Retries and replay
+	check(c.MaxRetries >= 0, "MAX_RETRIES must not be negative, got %d", c.MaxRetries)
+	check(c.WALReplayRate >= 0, "WAL_REPLAY_RATE must not be negative, got %d", c.WALReplayRate)
+	check(c.WALReplayBatchSize > 0, "WAL_REPLAY_BATCH_SIZE must be positive, got %d", c.WALReplayBatchSize)
+
+	// Anomaly thresholds
+	check(c.AnomalyThresholdBattery >= 0 && c.AnomalyThresholdBattery <= 100,
+		"ANOMALY_THRESHOLD_BATTERY is a percentage and must be between 0 and 100, got %g", c.AnomalyThresholdBattery)
+	check(c.AnomalyThresholdStorage >= 0, "ANOMALY_THRESHOLD_STORAGE must not be negative, got %g", c.AnomalyThresholdStorage)
+
+	if c.GapDetectionEnabled {
+		check(c.GapExpectedCadence > 0, "GAP_EXPECTED_CADENCE must be positive, got %v", c.GapExpectedCadence)
+		check(c.GapTolerance >= 1, "GAP_TOLERANCE must be at least 1, got %v", c.GapTolerance)
+	}
+
+	if c.BatteryForecastEnabled {
+		check(c.BatteryForecastWindow > 0, "BATTERY_FORECAST_WINDOW must be positive, got %v", c.BatteryForecastWindow)
+		check(c.BatteryForecastInterval > 0, "BATTERY_FORECAST_INTERVAL must be positive, got %v", c.BatteryForecastInterval)
+		check(c.BatteryForecastMinSamples >= 2, "BATTERY_FORECAST_MIN_SAMPLES must be at least 2, got %d", c.BatteryForecastMinSamples)
+		check(c.BatteryForecastMinR2 >= 0 && c.BatteryForecastMinR2 <= 1,
+			"BATTERY_FORECAST_MIN_R2 must be between 0 and 1, got %g", c.BatteryForecastMinR2)
+		check(c.BatteryForecastThreshold >= 0 && c.BatteryForecastThreshold <= 100,
+			"BATTERY_FORECAST_THRESHOLD is a percentage and must be between 0 and 100, got %g", c.BatteryForecastThreshold)
+		check(c.BatteryForecastCriticalHorizon >= 0 && c.BatteryForecastCriticalHorizon <= c.BatteryForecastWarnHorizon,
+			"BATTERY_FORECAST_CRITICAL_HORIZON (%v) must be between 0 and BATTERY_FORECAST_WARN_HORIZON (%v)",
+			c.BatteryForecastCriticalHorizon, c.BatteryForecastWarnHorizon)
+	}
+
+	if c.StorageForecastEnabled {
+		check(c.StorageForecastWindow > 0, "STORAGE_FORECAST_WINDOW must be positive, got %v", c.StorageForecastWindow)
+		check(c.StorageForecastInterval > 0, "STORAGE_FORECAST_INTERVAL must be positive, got %v", c.StorageForecastInterval)
+		check(c.StorageForecastMinSamples >= 2, "STORAGE_FORECAST_MIN_SAMPLES must be at least 2, got %d", c.StorageForecastMinSamples)
+		check(c.StorageForecastMinR2 >= 0 && c.StorageForecastMinR2 <= 1,
+			"STORAGE_FORECAST_MIN_R2 must be between 0 and 1, got %g", c.StorageForecastMinR2)
+		check(c.StorageForecastCapacityMB > 0, "STORAGE_FORECAST_CAPACITY_MB must be positive, got %g", c.StorageForecastCapacityMB)
+		check(c.StorageForecastCriticalHorizon >= 0 && c.StorageForecastCriticalHorizon <= c.StorageForecastWarnHorizon,
+			"STORAGE_FORECAST_CRITICAL_HORIZON (%v) must be between 0 and STORAGE_FORECAST_WARN_HORIZON (%v)",
+			c.StorageForecastCriticalHorizon, c.StorageForecastWarnHorizon)
+	}
+
+	if c.WatchdogEnabled {
+		check(c.WatchdogSilenceWindow > 0, "WATCHDOG_SILENCE_WINDOW must be positive, got %v", c.WatchdogSilenceWindow)
+		check(c.WatchdogInterval > 0, "WATCHDOG_INTERVAL must be positive, got %v", c.WatchdogInterval)
+		for _, window := range c.WatchdogSilenceWindows {
+			_, err := path.Match(window.Pattern, "")
+			check(err == nil, "WATCHDOG_SILENCE_WINDOWS pattern %q is invalid", window.Pattern)
+			check(window.Window > 0, "WATCHDOG_SILENCE_WINDOWS window of %q must be positive, got %v", window.Pattern, window.Window)
+		}
+	}
+
+	if c.PropagationEnabled {
+		check(c.PropagationTLERefresh > 0, "PROPAGATION_TLE_REFRESH must be positive, got %v", c.PropagationTLERefresh)
+		check(c.PropagationMaxTLEAge >= 0, "PROPAGATION_MAX_TLE_AGE must not be negative, got %v", c.PropagationMaxTLEAge)
+	}
+
+	if c.FleetReportEnabled {
+		check(c.FleetReportHour >= 0 && c.FleetReportHour <= 23, "FLEET_REPORT_HOUR must be between 0 and 23, got %d", c.FleetReportHour)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateDefaultsAndProfiles(t *testing.T) {
+	unsetEnvVars()
+	if err := LoadConfig().Validate(); err != nil {
+		t.Errorf("expected the defaults to be valid, got %v", err)
+	}
+	for _, name := range ProfileNames() {
+		os.Setenv("ORBITSTREAM_PROFILE", name)
+		if err := LoadConfig().Validate(); err != nil {
+			t.Errorf("expected profile %q to be valid, got %v", name, err)
+		}
+	}
+	os.Unsetenv("ORBITSTREAM_PROFILE")
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	unsetEnvVars()
+	t.Setenv("BATCH_SIZE", "2000")
+	t.Setenv("MAX_BUFFER_SIZE", "1000")
+	t.Setenv("BATCH_TIMEOUT", "soon")
+	t.Setenv("RETRY_DELAY", "-1s")
+	t.Setenv("ANOMALY_THRESHOLD_BATTERY", "150")
+
+	cfg := LoadConfig()
+	if cfg.BatchTimeout <= 0 {
+		t.Fatalf("expected the default batch timeout after a parse error, got %v", cfg.BatchTimeout)
+	}
+	err := cfg.Validate()
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	want := []string{
+		`BATCH_TIMEOUT="soon" is not a duration`,
+		"RETRY_DELAY=-1s must not be negative",
+		"MAX_BUFFER_SIZE (1000) must be at least BATCH_SIZE (2000)",
+		"ANOMALY_THRESHOLD_BATTERY is a percentage",
+	}
+	if len(validation.Problems) != len(want) {
+		t.Errorf("expected %d problems, got %v", len(want), validation.Problems)
+	}
+	for _, problem := range want {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected %q in %v", problem, err)
+		}
+	}
+
+	// Problems of one load don't carry over to the next
+	for _, key := range []string{"BATCH_SIZE", "MAX_BUFFER_SIZE", "BATCH_TIMEOUT", "RETRY_DELAY", "ANOMALY_THRESHOLD_BATTERY"} {
+		os.Unsetenv(key)
+	}
+	if err := LoadConfig().Validate(); err != nil {
+		t.Errorf("expected a clean load to be valid, got %v", err)
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	unsetEnvVars()
+	t.Setenv("TLS_KEY_FILE", "/etc/orbitstream/tls.key")
+	t.Setenv("TLS_CLIENT_CA_FILE", "/etc/orbitstream/ca.crt")
+	t.Setenv("TLS_MIN_VERSION", "1.0")
+
+	err := LoadConfig().Validate()
+	for _, problem := range []string{
+		"TLS_CERT_FILE and TLS_KEY_FILE must be set together",
+		"TLS_CLIENT_CA_FILE requires TLS_CERT_FILE",
+		`TLS_MIN_VERSION must be 1.2 or 1.3, got "1.0"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected %q in %v", problem, err)
+		}
+	}
+
+	t.Setenv("TLS_CERT_FILE", "/etc/orbitstream/tls.crt")
+	t.Setenv("TLS_MIN_VERSION", "1.3")
+	if err := LoadConfig().Validate(); err != nil {
+		t.Errorf("expected mTLS settings to be valid, got %v", err)
+	}
+}
+
+func TestValidateKeySatellites(t *testing.T) {
+	unsetEnvVars()
+	t.Setenv("AUTH_API_KEYS", "leo-team::secret")
+	t.Setenv("AUTH_KEY_SATELLITES", "leo-team=LEO-[,geo-team=GEO-*")
+
+	err := LoadConfig().Validate()
+	for _, problem := range []string{
+		`AUTH_KEY_SATELLITES names "geo-team"`,
+		`pattern "LEO-[" of "leo-team" is invalid`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected %q in %v", problem, err)
+		}
+	}
+}
+
+func TestValidateWatchdogSilenceWindows(t *testing.T) {
+	unsetEnvVars()
+	t.Setenv("WATCHDOG_SILENCE_WINDOWS", "GEO-*=10m, CUBE-*=3h")
+	cfg := LoadConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid windows, got %v", err)
+	}
+	if len(cfg.WatchdogSilenceWindows) != 2 || cfg.WatchdogSilenceWindows[1] != (SilenceWindow{Pattern: "CUBE-*", Window: 3 * time.Hour}) {
+		t.Errorf("unexpected windows %+v", cfg.WatchdogSilenceWindows)
+	}
+
+	t.Setenv("WATCHDOG_SILENCE_WINDOWS", "GEO-[=10m,CUBE-*=soon,LEO-*=-1m")
+	err := LoadConfig().Validate()
+	for _, problem := range []string{
+		`pattern "GEO-[" is invalid`,
+		`CUBE-*=soon`,
+		`window of "LEO-*" must be positive`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected %q in %v", problem, err)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+)
+
+// AnomalyDetector classifies a telemetry point.
+//
+// Detectors are chained on the BatchProcessor and every detector sees every
+// point, so stateful detectors (e.g. rolling statistics) stay up to date even
+// when an earlier detector already flagged the point. The names of all
+// detectors that fired are stored with the row in anomaly_detector, and the
+// most severe result decides anomaly_severity and anomaly_dimension.
+type AnomalyDetector interface {
+	// Name identifies the detector in stored rows and logs
+	Name() string
+	// Detect returns a result if the point is anomalous, nil otherwise
+	Detect(point models.TelemetryPoint) *models.AnomalyResult
+}
+
+// AnomalyAlertSink receives an event for every finding of the detector chain.
+// Implementations must not block; they are invoked while the buffer lock is held.
+type AnomalyAlertSink interface {
+	Alert(event models.AnomalyEvent)
+}
+
+// AnomalyDetectorFunc adapts a plain function into a named AnomalyDetector
+type AnomalyDetectorFunc struct {
+	DetectorName string
+	Fn           func(point models.TelemetryPoint) *models.AnomalyResult
+}
+
+// Name returns the detector name
+func (f AnomalyDetectorFunc) Name() string {
+	return f.DetectorName
+}
+
+// Detect calls the wrapped function
+func (f AnomalyDetectorFunc) Detect(point models.TelemetryPoint) *models.AnomalyResult {
+	return f.Fn(point)
+}
+
+// ThresholdDetector flags points that cross fixed battery, storage or signal limits.
+//
+// With hysteresis configured (AnomalyConfig.ConsecutivePoints or
+// MinBreachDuration), a satellite must stay past a threshold for that many
+// points or that long before it is flagged, and stays flagged until the metric
+// recovers past the clear threshold. This stops a signal hovering around
+// -100 dBm from flapping between normal and anomalous on every point.
+type ThresholdDetector struct {
+	config AnomalyConfig
+	mu     sync.Mutex
+	states map[string]*thresholdState
+}
+
+// thresholdState tracks hysteresis for one satellite
+type thresholdState struct {
+	battery breachState
+	storage breachState
+	signal  breachState
+}
+
+// breachState tracks hysteresis for one metric
+type breachState struct {
+	count       int
+	firstBreach time.Time
+	active      bool
+}
+
+// NewThresholdDetector creates a fixed-threshold detector
+func NewThresholdDetector(config AnomalyConfig) *ThresholdDetector {
+	return &ThresholdDetector{
+		config: config,
+		states: make(map[string]*thresholdState),
+	}
+}
+
+// Name returns "threshold"
+func (d *ThresholdDetector) Name() string {
+	return "threshold"
+}
+
+// Config returns the thresholds in use
+func (d *ThresholdDetector) Config() AnomalyConfig {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.config
+}
+
+// SetConfig replaces the thresholds while the detector runs. Hysteresis
+// state is kept, so flagged satellites stay flagged until they recover past
+// the new clear thresholds.
+func (d *ThresholdDetector) SetConfig(config AnomalyConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.config = config
+}
+
+// hysteresisEnabled reports whether flagging depends on previous points
+func (c AnomalyConfig) hysteresisEnabled() bool {
+	return c.ConsecutivePoints > 1 || c.MinBreachDuration > 0 ||
+		c.BatteryClearPercent != 0 || c.StorageClearMB != 0 || c.SignalClearDBM != 0
+}
+
+// Detect checks the point against the configured thresholds
+// Crossing a fixed threshold is always critical
+func (d *ThresholdDetector) Detect(point models.TelemetryPoint) *models.AnomalyResult {
+	config := d.Config()
+	batteryLow := point.BatteryChargePercent < config.BatteryMinPercent
+	storageHigh := point.StorageUsageMB > config.StorageMaxMB
+	signalWeak := point.SignalStrengthDBM < config.SignalMinDBM
+
+	if config.hysteresisEnabled() {
+		batteryLow, storageHigh, signalWeak = d.applyHysteresis(config, point, batteryLow, storageHigh, signalWeak)
+	}
+
+	if batteryLow {
+		log.Printf("ANOMALY: Satellite %s battery critically low: %.2f%%",
+			point.SatelliteID, point.BatteryChargePercent)
+		return &models.AnomalyResult{
+			Severity:  models.SeverityCritical,
+			Dimension: models.DimensionBattery,
+			Message:   fmt.Sprintf("battery %.2f%% below %.2f%%", point.BatteryChargePercent, config.BatteryMinPercent),
+			Metric:    "battery_charge_percent",
+			Value:     point.BatteryChargePercent,
+			Threshold: config.BatteryMinPercent,
+		}
+	}
+
+	if storageHigh {
+		log.Printf("ANOMALY: Satellite %s storage critically high: %.2f MB",
+			point.SatelliteID, point.StorageUsageMB)
+		return &models.AnomalyResult{
+			Severity:  models.SeverityCritical,
+			Dimension: models.DimensionStorage,
+			Message:   fmt.Sprintf("storage %.2f MB above %.2f MB", point.StorageUsageMB, config.StorageMaxMB),
+			Metric:    "storage_usage_mb",
+			Value:     point.StorageUsageMB,
+			Threshold: config.StorageMaxMB,
+		}
+	}
+
+	if signalWeak {
+		log.Printf("ANOMALY: Satellite %s signal critically weak: %.2f dBm",
+			point.SatelliteID, point.SignalStrengthDBM)
+		return &models.AnomalyResult{
+			Severity:  models.SeverityCritical,
+			Dimension: models.DimensionSignal,
+			Message:   fmt.Sprintf("signal %.2f dBm below %.2f dBm", point.SignalStrengthDBM, config.SignalMinDBM),
+			Metric:    "signal_strength_dbm",
+			Value:     point.SignalStrengthDBM,
+			Threshold: config.SignalMinDBM,
+		}
+	}
+
+	return nil
+}
+
+// applyHysteresis turns raw threshold breaches into debounced flags
+func (d *ThresholdDetector) applyHysteresis(config AnomalyConfig, point models.TelemetryPoint, batteryLow, storageHigh, signalWeak bool) (bool, bool, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, ok := d.states[point.SatelliteID]
+	if !ok {
+		state = &thresholdState{}
+		d.states[point.SatelliteID] = state
+	}
+
+	at := point.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	batteryCleared := point.BatteryChargePercent >= clearOr(config.BatteryClearPercent, config.BatteryMinPercent)
+	storageCleared := point.StorageUsageMB <= clearOr(config.StorageClearMB, config.StorageMaxMB)
+	signalCleared := point.SignalStrengthDBM >= clearOr(config.SignalClearDBM, config.SignalMinDBM)
+
+	return d.step(config, &state.battery, point.SatelliteID, "battery", batteryLow, batteryCleared, at),
+		d.step(config, &state.storage, point.SatelliteID, "storage", storageHigh, storageCleared, at),
+		d.step(config, &state.signal, point.SatelliteID, "signal", signalWeak, signalCleared, at)
+}
+
+// step advances one metric's hysteresis state and returns whether it is flagged
+func (d *ThresholdDetector) step(config AnomalyConfig, state *breachState, satelliteID, metric string, breached, cleared bool, at time.Time) bool {
+	if state.active {
+		if cleared {
+			state.active = false
+			state.count = 0
+			log.Printf("ANOMALY CLEARED: Satellite %s %s recovered past clear threshold", satelliteID, metric)
+			return false
+		}
+		return true
+	}
+
+	if !breached {
+		state.count = 0
+		return false
+	}
+
+	if state.count == 0 {
+		state.firstBreach = at
+	}
+	state.count++
+
+	byPoints := config.ConsecutivePoints > 1 && state.count >= config.ConsecutivePoints
+	byDuration := config.MinBreachDuration > 0 && at.Sub(state.firstBreach) >= config.MinBreachDuration
+	immediate := config.ConsecutivePoints <= 1 && config.MinBreachDuration == 0
+	if byPoints || byDuration || immediate {
+		state.active = true
+		return true
+	}
+	return false
+}
+
+// clearOr returns the clear threshold, or the flag threshold when unset
+func clearOr(clear, threshold float64) float64 {
+	if clear == 0 {
+		return threshold
+	}
+	return clear
+}
+
+// runAnomalyDetectors runs every detector in order and returns their findings
+func runAnomalyDetectors(detectors []AnomalyDetector, point models.TelemetryPoint) []models.AnomalyResult {
+	var results []models.AnomalyResult
+	for _, detector := range detectors {
+		if result := detector.Detect(point); result != nil {
+			if result.Detector == "" {
+				result.Detector = detector.Name()
+			}
+			results = append(results, *result)
+		}
+	}
+	return results
+}
+
+// applyAnomalyResults stores the chain's findings on the point: the names of
+// all detectors that fired, the severity/dimension of the worst finding and
+// whether the position detector fired
+func applyAnomalyResults(point *models.TelemetryPoint, results []models.AnomalyResult) {
+	point.IsAnomaly = len(results) > 0
+	point.AnomalyDetector = nil
+	point.AnomalySeverity = nil
+	point.AnomalyDimension = nil
+	point.PositionAnomaly = false
+	if len(results) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(results))
+	worst := results[0]
+	for _, result := range results {
+		names = append(names, result.Detector)
+		if result.Detector == PositionDetectorName {
+			point.PositionAnomaly = true
+		}
+		if result.Severity.Rank() > worst.Severity.Rank() {
+			worst = result
+		}
+	}
+
+	joined := strings.Join(names, ",")
+	point.AnomalyDetector = &joined
+	point.AnomalySeverity = &worst.Severity
+	point.AnomalyDimension = &worst.Dimension
+}
+
+var (
+	anomalyRegistryMu sync.RWMutex
+	anomalyRegistry   = make(map[string]func() AnomalyDetector)
+)
+
+// RegisterAnomalyDetector makes a custom detector selectable by name in
+// ANOMALY_DETECTORS. Call it from an init function before startup.
+func RegisterAnomalyDetector(name string, factory func() AnomalyDetector) {
+	anomalyRegistryMu.Lock()
+	defer anomalyRegistryMu.Unlock()
+	anomalyRegistry[name] = factory
+}
+
+// NewRegisteredAnomalyDetector creates a detector registered under name
+func NewRegisteredAnomalyDetector(name string) (AnomalyDetector, bool) {
+	anomalyRegistryMu.RLock()
+	factory, ok := anomalyRegistry[name]
+	anomalyRegistryMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+)
+
+// PositionDetectorName identifies position consistency findings; points it
+// flags are stored with position_anomaly = TRUE
+const PositionDetectorName = "position"
+
+// earthRadiusKM is the mean Earth radius used to place positions in space
+const earthRadiusKM = 6371.0
+
+// PositionCheckConfig bounds how far a satellite may plausibly move between
+// two points
+type PositionCheckConfig struct {
+	// MaxVelocityKMPH is assumed when neither point reports a velocity
+	MaxVelocityKMPH float64
+	// Tolerance is the fraction the displacement may exceed the reported
+	// velocity by, e.g. 0.5 allows 150% of velocity × Δt
+	Tolerance float64
+	// SlackKM is always allowed on top, for position measurement noise
+	SlackKM float64
+}
+
+// PositionDetector flags physically impossible jumps between consecutive
+// positions of a satellite: the straight-line distance between them is
+// compared with what its reported velocity (or MaxVelocityKMPH) allows over
+// the time between their timestamps, e.g. 5000 km in 10 s.
+//
+// A flagged position is not trusted as the next reference, so one bad fix
+// flags one point. When the next point agrees with the flagged one instead
+// of the last trusted one, the satellite really is there (e.g. a corrected
+// orbit solution) and the new position is adopted.
+type PositionDetector struct {
+	cfg    PositionCheckConfig
+	mu     sync.Mutex
+	states map[string]*positionState
+}
+
+// positionFix is a satellite's position in Earth-centered coordinates
+type positionFix struct {
+	at        time.Time
+	x, y, z   float64
+	speedKMPH *float64
+}
+
+// positionState is the last trusted position of a satellite and the last
+// flagged one after it, if any
+type positionState struct {
+	trusted positionFix
+	suspect *positionFix
+}
+
+// NewPositionDetector creates a position consistency detector; zero values
+// default to 40000 km/h, a tolerance of 0.5 and 10 km of slack
+func NewPositionDetector(cfg PositionCheckConfig) *PositionDetector {
+	if cfg.MaxVelocityKMPH <= 0 {
+		cfg.MaxVelocityKMPH = 40000
+	}
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = 0.5
+	}
+	if cfg.SlackKM <= 0 {
+		cfg.SlackKM = 10
+	}
+	return &PositionDetector{cfg: cfg, states: make(map[string]*positionState)}
+}
+
+// Name returns "position"
+func (d *PositionDetector) Name() string {
+	return PositionDetectorName
+}
+
+// Detect compares the point's position with the satellite's previous one.
+// Points without a full position or not newer than the reference are not
+// checked.
+func (d *PositionDetector) Detect(point models.TelemetryPoint) *models.AnomalyResult {
+	if point.Latitude == nil || point.Longitude == nil || point.AltitudeKM == nil || point.Timestamp.IsZero() {
+		return nil
+	}
+	fix := newPositionFix(point)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, ok := d.states[point.SatelliteID]
+	if !ok {
+		d.states[point.SatelliteID] = &positionState{trusted: fix}
+		return nil
+	}
+	if !fix.at.After(state.trusted.at) {
+		return nil
+	}
+
+	distance, allowed := d.displacement(state.trusted, fix)
+	if distance <= allowed {
+		*state = positionState{trusted: fix}
+		return nil
+	}
+	if state.suspect != nil && fix.at.After(state.suspect.at) {
+		if fromSuspect, allowedFromSuspect := d.displacement(*state.suspect, fix); fromSuspect <= allowedFromSuspect {
+			*state = positionState{trusted: fix}
+			return nil
+		}
+	}
+	state.suspect = &fix
+
+	severity := models.SeverityWarning
+	if distance > 2*allowed {
+		severity = models.SeverityCritical
+	}
+	elapsed := fix.at.Sub(state.trusted.at)
+	return &models.AnomalyResult{
+		Severity:  severity,
+		Dimension: models.DimensionPosition,
+		Message: fmt.Sprintf("position jumped %.0f km in %v, at most %.0f km is plausible",
+			distance, elapsed, allowed),
+		Metric:    "displacement_km",
+		Value:     distance,
+		Threshold: allowed,
+	}
+}
+
+// displacement returns the distance between two fixes and the most the
+// satellite could plausibly have moved between them
+func (d *PositionDetector) displacement(from, to positionFix) (float64, float64) {
+	distance := math.Sqrt((to.x-from.x)*(to.x-from.x) + (to.y-from.y)*(to.y-from.y) + (to.z-from.z)*(to.z-from.z))
+
+	speed := d.cfg.MaxVelocityKMPH
+	switch {
+	case from.speedKMPH != nil && to.speedKMPH != nil:
+		speed = math.Max(math.Abs(*from.speedKMPH), math.Abs(*to.speedKMPH))
+	case from.speedKMPH != nil:
+		speed = math.Abs(*from.speedKMPH)
+	case to.speedKMPH != nil:
+		speed = math.Abs(*to.speedKMPH)
+	}
+	hours := to.at.Sub(from.at).Hours()
+	return distance, speed*hours*(1+d.cfg.Tolerance) + d.cfg.SlackKM
+}
+
+func newPositionFix(point models.TelemetryPoint) positionFix {
+	lat := *point.Latitude * math.Pi / 180
+	lon := *point.Longitude * math.Pi / 180
+	r := earthRadiusKM + *point.AltitudeKM
+	return positionFix{
+		at:        point.Timestamp,
+		x:         r * math.Cos(lat) * math.Cos(lon),
+		y:         r * math.Cos(lat) * math.Sin(lon),
+		z:         r * math.Sin(lat),
+		speedKMPH: point.VelocityKMPH,
+	}
+}
+
+func (d *PositionDetector) forgetSatellite(satelliteID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.states, satelliteID)
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+func trackPoint(at time.Time, lat, lon float64, velocity *float64) models.TelemetryPoint {
+	point := positionedPoint("SAT-001", at, lat, lon, 420)
+	point.VelocityKMPH = velocity
+	return point
+}
+
+// TestPositionDetectorFlagsImpossibleJumps tests displacement against
+// velocity × Δt
+func TestPositionDetectorFlagsImpossibleJumps(t *testing.T) {
+	detector := NewPositionDetector(PositionCheckConfig{Tolerance: 0.5, SlackKM: 10})
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	leo := 27600.0
+
+	if result := detector.Detect(trackPoint(start, 0, 0, &leo)); result != nil {
+		t.Fatalf("expected the first position to be accepted, got %+v", result)
+	}
+	// ~7.7 km/s covers one degree of longitude (~118 km at 420 km) in 15 s
+	if result := detector.Detect(trackPoint(start.Add(15*time.Second), 0, 1, &leo)); result != nil {
+		t.Errorf("expected a plausible move, got %+v", result)
+	}
+
+	// ~5000 km in 10 s
+	result := detector.Detect(trackPoint(start.Add(25*time.Second), 0, 46, &leo))
+	if result == nil || result.Severity != models.SeverityCritical || result.Dimension != models.DimensionPosition {
+		t.Fatalf("expected a critical jump, got %+v", result)
+	}
+	if result.Value < 4900 || result.Value > 5300 {
+		t.Errorf("expected about 5000 km, got %.0f", result.Value)
+	}
+
+	// The glitch is not the new reference: back on track is fine
+	if result := detector.Detect(trackPoint(start.Add(30*time.Second), 0, 2, &leo)); result != nil {
+		t.Errorf("expected the track to continue from the trusted position, got %+v", result)
+	}
+}
+
+// TestPositionDetectorAdoptsConfirmedPositions tests that a jump confirmed by
+// the next point becomes the reference
+func TestPositionDetectorAdoptsConfirmedPositions(t *testing.T) {
+	detector := NewPositionDetector(PositionCheckConfig{})
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	detector.Detect(trackPoint(start, 0, 0, nil))
+	if result := detector.Detect(trackPoint(start.Add(10*time.Second), 0, 90, nil)); result == nil {
+		t.Fatal("expected a jump without velocity to be checked against the maximum")
+	}
+	if result := detector.Detect(trackPoint(start.Add(20*time.Second), 0, 90.5, nil)); result != nil {
+		t.Errorf("expected the confirmed position to be adopted, got %+v", result)
+	}
+	if result := detector.Detect(trackPoint(start.Add(30*time.Second), 0, 91, nil)); result != nil {
+		t.Errorf("expected the track to continue from the adopted position, got %+v", result)
+	}
+
+	// Out of order and position-less points are not checked
+	if result := detector.Detect(trackPoint(start, 0, 0, nil)); result != nil {
+		t.Errorf("expected an older point to be skipped, got %+v", result)
+	}
+	if result := detector.Detect(models.TelemetryPoint{SatelliteID: "SAT-001", Timestamp: start.Add(time.Minute)}); result != nil {
+		t.Errorf("expected a point without position to be skipped, got %+v", result)
+	}
+}
+
+func TestApplyAnomalyResultsFlagsPositionAnomalies(t *testing.T) {
+	point := models.TelemetryPoint{PositionAnomaly: true}
+	applyAnomalyResults(&point, []models.AnomalyResult{{Detector: "threshold", Severity: models.SeverityWarning}})
+	if point.PositionAnomaly {
+		t.Error("expected the flag to be cleared without a position finding")
+	}
+	applyAnomalyResults(&point, []models.AnomalyResult{{Detector: PositionDetectorName, Severity: models.SeverityCritical}})
+	if !point.PositionAnomaly || !point.IsAnomaly {
+		t.Errorf("expected a position anomaly, got %+v", point)
+	}
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+
+	"orbitstream/models"
+)
+
+// StatisticalDetector flags telemetry points that deviate from a satellite's
+// own recent behaviour rather than from a fixed threshold.
+//
+// For each satellite and metric it maintains an exponentially weighted moving
+// average (EWMA) of the mean and variance. A point is anomalous when any metric
+// is more than `sigma` standard deviations away from the rolling mean. This
+// catches gradual degradation (e.g. a battery that normally sits at 90% and
+// starts dropping to 60%) that never crosses the static thresholds.
+type StatisticalDetector struct {
+	mu     sync.Mutex
+	alpha  float64
+	sigma  float64
+	warmup int
+	states map[string]*satelliteRollingStats
+}
+
+// rollingStat is the EWMA mean/variance of a single metric
+type rollingStat struct {
+	mean     float64
+	variance float64
+	count    int
+}
+
+// satelliteRollingStats holds rolling statistics for every monitored metric
+type satelliteRollingStats struct {
+	battery rollingStat
+	storage rollingStat
+	signal  rollingStat
+}
+
+// NewStatisticalDetector creates a rolling z-score detector
+// alpha: EWMA smoothing factor in (0, 1]; smaller values remember longer
+// sigma: number of standard deviations that counts as anomalous
+// warmup: number of points per satellite observed before flagging starts
+func NewStatisticalDetector(alpha, sigma float64, warmup int) *StatisticalDetector {
+	return &StatisticalDetector{
+		alpha:  alpha,
+		sigma:  sigma,
+		warmup: warmup,
+		states: make(map[string]*satelliteRollingStats),
+	}
+}
+
+// Observe scores the point against the satellite's rolling statistics, then
+// folds it into them. Returns true if any metric deviates beyond sigma.
+func (d *StatisticalDetector) Observe(point models.TelemetryPoint) bool {
+	return d.observe(point) != nil
+}
+
+// observe scores and folds in the point, returning the most deviant metric
+// Deviations beyond twice sigma are critical, otherwise warning
+func (d *StatisticalDetector) observe(point models.TelemetryPoint) *models.AnomalyResult {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, ok := d.states[point.SatelliteID]
+	if !ok {
+		state = &satelliteRollingStats{}
+		d.states[point.SatelliteID] = state
+	}
+
+	var worst *models.AnomalyResult
+	worstZ := 0.0
+	consider := func(dimension models.AnomalyDimension, metric string, value float64, dev deviation, message string) {
+		log.Printf("ANOMALY: Satellite %s %s", point.SatelliteID, message)
+		if worst != nil && dev.z <= worstZ {
+			return
+		}
+		severity := models.SeverityWarning
+		if dev.z > 2*d.sigma {
+			severity = models.SeverityCritical
+		}
+		worst = &models.AnomalyResult{
+			Severity:  severity,
+			Dimension: dimension,
+			Message:   message,
+			Metric:    metric,
+			Value:     value,
+			Threshold: dev.bound,
+		}
+		worstZ = dev.z
+	}
+
+	if dev, ok := d.update(&state.battery, point.BatteryChargePercent); ok {
+		consider(models.DimensionBattery, "battery_charge_percent", point.BatteryChargePercent, dev,
+			fmt.Sprintf("battery %.2f%% deviates %.1f sigma from rolling mean", point.BatteryChargePercent, dev.z))
+	}
+	if dev, ok := d.update(&state.storage, point.StorageUsageMB); ok {
+		consider(models.DimensionStorage, "storage_usage_mb", point.StorageUsageMB, dev,
+			fmt.Sprintf("storage %.2f MB deviates %.1f sigma from rolling mean", point.StorageUsageMB, dev.z))
+	}
+	if dev, ok := d.update(&state.signal, point.SignalStrengthDBM); ok {
+		consider(models.DimensionSignal, "signal_strength_dbm", point.SignalStrengthDBM, dev,
+			fmt.Sprintf("signal %.2f dBm deviates %.1f sigma from rolling mean", point.SignalStrengthDBM, dev.z))
+	}
+	return worst
+}
+
+// deviation is a value's z-score and the sigma bound it was compared against
+type deviation struct {
+	z     float64
+	bound float64
+}
+
+// update returns the deviation of value from the current statistics and
+// whether it exceeds sigma, then updates the statistics with value
+func (d *StatisticalDetector) update(stat *rollingStat, value float64) (deviation, bool) {
+	if stat.count == 0 {
+		stat.mean = value
+		stat.count = 1
+		return deviation{}, false
+	}
+
+	var dev deviation
+	exceeded := false
+	if stddev := math.Sqrt(stat.variance); stddev > 0 {
+		dev.z = math.Abs(value-stat.mean) / stddev
+		dev.bound = stat.mean + d.sigma*stddev
+		if value < stat.mean {
+			dev.bound = stat.mean - d.sigma*stddev
+		}
+		exceeded = stat.count >= d.warmup && dev.z > d.sigma
+	}
+
+	diff := value - stat.mean
+	incr := d.alpha * diff
+	stat.mean += incr
+	stat.variance = (1 - d.alpha) * (stat.variance + diff*incr)
+	stat.count++
+
+	return dev, exceeded
+}
+
+// TrackedSatellites returns the number of satellites with rolling state
+func (d *StatisticalDetector) TrackedSatellites() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.states)
+}
+
+// Name returns "statistical"
+func (d *StatisticalDetector) Name() string {
+	return "statistical"
+}
+
+// Detect implements AnomalyDetector by observing the point
+func (d *StatisticalDetector) Detect(point models.TelemetryPoint) *models.AnomalyResult {
+	return d.observe(point)
+}
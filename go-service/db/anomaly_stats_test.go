@@ -0,0 +1,124 @@
+package db
+
+import (
+	"testing"
+
+	"orbitstream/models"
+)
+
+// TestStatisticalDetectorWarmup tests that nothing is flagged before warmup completes
+func TestStatisticalDetectorWarmup(t *testing.T) {
+	d := NewStatisticalDetector(0.1, 3.0, 10)
+
+	// Only a handful of points, then a huge outlier - still within warmup
+	for i := 0; i < 5; i++ {
+		d.Observe(TelemetryPointForTest(90.0+float64(i%2), 45000.0, -55.0))
+	}
+	if d.Observe(TelemetryPointForTest(10.0, 45000.0, -55.0)) {
+		t.Error("expected no anomaly during warmup")
+	}
+}
+
+// TestStatisticalDetectorFlagsDeviation tests that a large deviation is flagged after warmup
+func TestStatisticalDetectorFlagsDeviation(t *testing.T) {
+	d := NewStatisticalDetector(0.1, 3.0, 10)
+
+	for i := 0; i < 50; i++ {
+		// Small natural jitter around 90% / 45000 MB / -55 dBm
+		jitter := float64(i%3) - 1.0
+		if d.Observe(TelemetryPointForTest(90.0+jitter, 45000.0+jitter*10, -55.0+jitter)) {
+			t.Fatalf("unexpected anomaly on stable point %d", i)
+		}
+	}
+
+	// Battery drops to 60% - well above the static 10% threshold but far from normal
+	result := d.Detect(TelemetryPointForTest(60.0, 45000.0, -55.0))
+	if result == nil {
+		t.Fatal("expected sudden battery drop to be flagged")
+	}
+	if result.Dimension != models.DimensionBattery {
+		t.Errorf("expected battery dimension, got %s", result.Dimension)
+	}
+	if result.Severity != models.SeverityCritical {
+		t.Errorf("expected critical severity for a deviation far beyond 2x sigma, got %s", result.Severity)
+	}
+}
+
+// TestStatisticalDetectorPerSatellite tests that statistics are tracked per satellite
+func TestStatisticalDetectorPerSatellite(t *testing.T) {
+	d := NewStatisticalDetector(0.1, 3.0, 10)
+
+	for i := 0; i < 50; i++ {
+		jitter := float64(i%3) - 1.0
+		a := TelemetryPointForTest(90.0+jitter, 45000.0, -55.0)
+		a.SatelliteID = "SAT-A"
+		b := TelemetryPointForTest(40.0+jitter, 45000.0, -55.0)
+		b.SatelliteID = "SAT-B"
+		d.Observe(a)
+		d.Observe(b)
+	}
+
+	// 40% is normal for SAT-B but anomalous for SAT-A
+	normal := TelemetryPointForTest(40.0, 45000.0, -55.0)
+	normal.SatelliteID = "SAT-B"
+	if d.Observe(normal) {
+		t.Error("expected SAT-B's normal value not to be flagged")
+	}
+
+	outlier := TelemetryPointForTest(40.0, 45000.0, -55.0)
+	outlier.SatelliteID = "SAT-A"
+	if !d.Observe(outlier) {
+		t.Error("expected SAT-A deviation to be flagged")
+	}
+
+	if d.TrackedSatellites() != 2 {
+		t.Errorf("expected 2 tracked satellites, got %d", d.TrackedSatellites())
+	}
+}
+
+// TestStatisticalDetectorConstantSignal tests that zero variance never divides by zero
+func TestStatisticalDetectorConstantSignal(t *testing.T) {
+	d := NewStatisticalDetector(0.1, 3.0, 1)
+
+	for i := 0; i < 20; i++ {
+		if d.Observe(TelemetryPointForTest(90.0, 45000.0, -55.0)) {
+			t.Fatal("constant values must not be flagged")
+		}
+	}
+}
+
+// TestBatchProcessorAddWithStatisticalDetector tests the detector is applied on Add
+func TestBatchProcessorAddWithStatisticalDetector(t *testing.T) {
+	bp := &BatchProcessor{
+		buffer:        make([]models.TelemetryPoint, 0, 100),
+		batchSize:     100,
+		maxBufferSize: 1000,
+		anomalyConfig: AnomalyConfig{
+			BatteryMinPercent: 10.0,
+			StorageMaxMB:      95000.0,
+			SignalMinDBM:      -100.0,
+		},
+	}
+	bp.AddAnomalyDetector(NewStatisticalDetector(0.1, 3.0, 10))
+
+	for i := 0; i < 30; i++ {
+		jitter := float64(i%3) - 1.0
+		if err := bp.Add(TelemetryPointForTest(90.0+jitter, 45000.0, -55.0)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := bp.Add(TelemetryPointForTest(50.0, 45000.0, -55.0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	last := bp.buffer[len(bp.buffer)-1]
+	if !last.IsAnomaly {
+		t.Error("expected statistical detector to flag the last point")
+	}
+	if last.AnomalyDetector == nil || *last.AnomalyDetector != "statistical" {
+		t.Errorf("expected anomaly_detector statistical, got %v", last.AnomalyDetector)
+	}
+	if bp.buffer[0].IsAnomaly {
+		t.Error("expected first point not to be flagged")
+	}
+}
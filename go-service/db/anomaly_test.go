@@ -0,0 +1,273 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// TestAnomalyDetectorChainRecordsFiredDetectors tests that every detector runs and fired names are stored
+func TestAnomalyDetectorChainRecordsFiredDetectors(t *testing.T) {
+	calls := 0
+	custom := AnomalyDetectorFunc{
+		DetectorName: "custom",
+		Fn: func(point models.TelemetryPoint) *models.AnomalyResult {
+			calls++
+			if point.SatelliteID != "SAT-BAD" {
+				return nil
+			}
+			return &models.AnomalyResult{Severity: models.SeverityInfo, Dimension: models.DimensionPosition}
+		},
+	}
+
+	bp := &BatchProcessor{
+		buffer:        make([]models.TelemetryPoint, 0, 10),
+		batchSize:     10,
+		maxBufferSize: 100,
+		anomalyConfig: AnomalyConfig{BatteryMinPercent: 10.0, StorageMaxMB: 95000.0, SignalMinDBM: -100.0},
+	}
+	bp.AddAnomalyDetector(custom)
+
+	// Low battery and custom rule both fire
+	point := TelemetryPointForTest(5.0, 45000.0, -55.0)
+	point.SatelliteID = "SAT-BAD"
+	if err := bp.Add(point); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Nothing fires
+	if err := bp.Add(TelemetryPointForTest(80.0, 45000.0, -55.0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	flagged := bp.buffer[0]
+	if !flagged.IsAnomaly {
+		t.Error("expected first point to be flagged")
+	}
+	if flagged.AnomalyDetector == nil || *flagged.AnomalyDetector != "threshold,custom" {
+		t.Errorf("expected anomaly_detector threshold,custom, got %v", flagged.AnomalyDetector)
+	}
+	// The critical threshold finding outranks the custom info finding
+	if flagged.AnomalySeverity == nil || *flagged.AnomalySeverity != models.SeverityCritical {
+		t.Errorf("expected critical severity, got %v", flagged.AnomalySeverity)
+	}
+	if flagged.AnomalyDimension == nil || *flagged.AnomalyDimension != models.DimensionBattery {
+		t.Errorf("expected battery dimension, got %v", flagged.AnomalyDimension)
+	}
+
+	normal := bp.buffer[1]
+	if normal.IsAnomaly || normal.AnomalyDetector != nil {
+		t.Errorf("expected normal point to be unflagged, got %+v", normal)
+	}
+	if calls != 2 {
+		t.Errorf("expected custom detector to see every point, saw %d", calls)
+	}
+}
+
+// TestSetAnomalyDetectorsReplacesThresholds tests that an explicit chain replaces the default thresholds
+func TestSetAnomalyDetectorsReplacesThresholds(t *testing.T) {
+	bp := &BatchProcessor{
+		anomalyConfig: AnomalyConfig{BatteryMinPercent: 10.0, StorageMaxMB: 95000.0, SignalMinDBM: -100.0},
+	}
+
+	point := TelemetryPointForTest(5.0, 45000.0, -55.0)
+	if !bp.detectAnomaly(point) {
+		t.Error("expected default threshold detector to flag low battery")
+	}
+
+	bp.SetAnomalyDetectors(AnomalyDetectorFunc{
+		DetectorName: "never",
+		Fn:           func(models.TelemetryPoint) *models.AnomalyResult { return nil },
+	})
+	if bp.detectAnomaly(point) {
+		t.Error("expected custom chain to replace threshold detection")
+	}
+}
+
+// TestRegisteredAnomalyDetector tests creating custom detectors by name
+func TestRegisteredAnomalyDetector(t *testing.T) {
+	RegisterAnomalyDetector("test-always", func() AnomalyDetector {
+		return AnomalyDetectorFunc{
+			DetectorName: "test-always",
+			Fn: func(models.TelemetryPoint) *models.AnomalyResult {
+				return &models.AnomalyResult{Severity: models.SeverityWarning, Dimension: models.DimensionSignal}
+			},
+		}
+	})
+
+	detector, ok := NewRegisteredAnomalyDetector("test-always")
+	if !ok {
+		t.Fatal("expected registered detector to be found")
+	}
+	if detector.Name() != "test-always" || detector.Detect(TelemetryPointForTest(80.0, 45000.0, -55.0)) == nil {
+		t.Error("unexpected registered detector behaviour")
+	}
+
+	if _, ok := NewRegisteredAnomalyDetector("missing"); ok {
+		t.Error("expected unknown detector not to be found")
+	}
+}
+
+// TestThresholdDetectorClassification tests severity and dimension of threshold findings
+func TestThresholdDetectorClassification(t *testing.T) {
+	detector := NewThresholdDetector(AnomalyConfig{BatteryMinPercent: 10.0, StorageMaxMB: 95000.0, SignalMinDBM: -100.0})
+
+	tests := []struct {
+		name      string
+		point     models.TelemetryPoint
+		dimension models.AnomalyDimension
+	}{
+		{"battery", TelemetryPointForTest(5.0, 45000.0, -55.0), models.DimensionBattery},
+		{"storage", TelemetryPointForTest(80.0, 99000.0, -55.0), models.DimensionStorage},
+		{"signal", TelemetryPointForTest(80.0, 45000.0, -110.0), models.DimensionSignal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := detector.Detect(tt.point)
+			if result == nil {
+				t.Fatal("expected a finding")
+			}
+			if result.Severity != models.SeverityCritical {
+				t.Errorf("expected critical, got %s", result.Severity)
+			}
+			if result.Dimension != tt.dimension {
+				t.Errorf("expected dimension %s, got %s", tt.dimension, result.Dimension)
+			}
+		})
+	}
+
+	if detector.Detect(TelemetryPointForTest(80.0, 45000.0, -55.0)) != nil {
+		t.Error("expected no finding for a normal point")
+	}
+}
+
+// TestThresholdHysteresisConsecutivePoints tests that a breach must persist for N points
+func TestThresholdHysteresisConsecutivePoints(t *testing.T) {
+	detector := NewThresholdDetector(AnomalyConfig{
+		BatteryMinPercent: 10.0,
+		StorageMaxMB:      95000.0,
+		SignalMinDBM:      -100.0,
+		ConsecutivePoints: 3,
+	})
+
+	weak := TelemetryPointForTest(80.0, 45000.0, -101.0)
+	normal := TelemetryPointForTest(80.0, 45000.0, -99.0)
+
+	// Two breaches then a recovery resets the counter
+	for i, p := range []models.TelemetryPoint{weak, weak, normal, weak, weak} {
+		if detector.Detect(p) != nil {
+			t.Fatalf("point %d flagged before 3 consecutive breaches", i)
+		}
+	}
+	if result := detector.Detect(weak); result == nil || result.Dimension != models.DimensionSignal {
+		t.Errorf("expected third consecutive breach to be flagged, got %v", result)
+	}
+}
+
+// TestThresholdHysteresisClearThreshold tests that flapping around the threshold stays flagged
+func TestThresholdHysteresisClearThreshold(t *testing.T) {
+	detector := NewThresholdDetector(AnomalyConfig{
+		BatteryMinPercent: 10.0,
+		StorageMaxMB:      95000.0,
+		SignalMinDBM:      -100.0,
+		SignalClearDBM:    -95.0,
+	})
+
+	if detector.Detect(TelemetryPointForTest(80.0, 45000.0, -101.0)) == nil {
+		t.Fatal("expected initial breach to be flagged")
+	}
+	// Hovering just above -100 dBm is not a recovery
+	if detector.Detect(TelemetryPointForTest(80.0, 45000.0, -99.0)) == nil {
+		t.Error("expected point between threshold and clear threshold to stay flagged")
+	}
+	// Recovering past -95 dBm clears the anomaly
+	if detector.Detect(TelemetryPointForTest(80.0, 45000.0, -94.0)) != nil {
+		t.Error("expected recovery past clear threshold to un-flag")
+	}
+	if detector.Detect(TelemetryPointForTest(80.0, 45000.0, -99.0)) != nil {
+		t.Error("expected point above threshold not to re-flag after clearing")
+	}
+}
+
+// TestThresholdHysteresisDuration tests that a breach must persist for a minimum time
+func TestThresholdHysteresisDuration(t *testing.T) {
+	detector := NewThresholdDetector(AnomalyConfig{
+		BatteryMinPercent: 10.0,
+		StorageMaxMB:      95000.0,
+		SignalMinDBM:      -100.0,
+		MinBreachDuration: 30 * time.Second,
+	})
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) models.TelemetryPoint {
+		p := TelemetryPointForTest(5.0, 45000.0, -55.0)
+		p.Timestamp = base.Add(offset)
+		return p
+	}
+
+	if detector.Detect(at(0)) != nil || detector.Detect(at(20*time.Second)) != nil {
+		t.Fatal("expected breach shorter than 30s not to be flagged")
+	}
+	if detector.Detect(at(30*time.Second)) == nil {
+		t.Error("expected breach lasting 30s to be flagged")
+	}
+}
+
+// TestThresholdHysteresisPerSatellite tests that hysteresis state is tracked per satellite
+func TestThresholdHysteresisPerSatellite(t *testing.T) {
+	detector := NewThresholdDetector(AnomalyConfig{
+		BatteryMinPercent: 10.0,
+		StorageMaxMB:      95000.0,
+		SignalMinDBM:      -100.0,
+		ConsecutivePoints: 2,
+	})
+
+	a := TelemetryPointForTest(5.0, 45000.0, -55.0)
+	a.SatelliteID = "SAT-A"
+	b := a
+	b.SatelliteID = "SAT-B"
+
+	detector.Detect(a)
+	if detector.Detect(b) != nil {
+		t.Error("expected SAT-B's first breach not to count SAT-A's")
+	}
+	if detector.Detect(a) == nil {
+		t.Error("expected SAT-A's second breach to be flagged")
+	}
+}
+
+// recordingAlertSink collects events for assertions
+type recordingAlertSink struct {
+	events []models.AnomalyEvent
+}
+
+func (s *recordingAlertSink) Alert(event models.AnomalyEvent) {
+	s.events = append(s.events, event)
+}
+
+// TestBatchProcessorAlertSink tests that anomaly findings are forwarded to the alert sink
+func TestBatchProcessorAlertSink(t *testing.T) {
+	sink := &recordingAlertSink{}
+	bp := &BatchProcessor{
+		buffer:        make([]models.TelemetryPoint, 0, 10),
+		batchSize:     10,
+		maxBufferSize: 100,
+		anomalyConfig: AnomalyConfig{BatteryMinPercent: 10.0, StorageMaxMB: 95000.0, SignalMinDBM: -100.0},
+	}
+	bp.SetAlertSink(sink)
+
+	_ = bp.Add(TelemetryPointForTest(80.0, 45000.0, -55.0))
+	_ = bp.Add(TelemetryPointForTest(5.0, 45000.0, -55.0))
+
+	if len(sink.events) != 1 {
+		t.Fatalf("expected 1 alert event, got %d", len(sink.events))
+	}
+	event := sink.events[0]
+	if event.Metric != "battery_charge_percent" || event.Value != 5.0 || event.Threshold != 10.0 {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if event.Detector != "threshold" {
+		t.Errorf("expected threshold detector on event, got %+v", event)
+	}
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"orbitstream/models"
+)
+
+// AuditSink stores the audit log of admin operations
+type AuditSink interface {
+	Write(ctx context.Context, entry models.AuditEntry) error
+	// Read returns the newest limit entries, oldest first
+	Read(ctx context.Context, limit int) ([]models.AuditEntry, error)
+}
+
+// FileAuditSink appends audit entries to a JSON lines file
+type FileAuditSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileAuditSink creates a sink writing to path, creating its directory
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	return &FileAuditSink{path: path}, nil
+}
+
+// Write durably appends entry
+func (s *FileAuditSink) Write(ctx context.Context, entry models.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return file.Sync()
+}
+
+// Read returns the newest limit entries (all when limit <= 0), oldest first
+func (s *FileAuditSink) Read(ctx context.Context, limit int) ([]models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]models.AuditEntry, 0)
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry models.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // torn final line after a crash
+		}
+		entries = append(entries, entry)
+		if limit > 0 && len(entries) > limit {
+			entries = entries[1:]
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// TableAuditSink stores audit entries in the admin_audit_log table
+type TableAuditSink struct {
+	pool *pgxpool.Pool
+}
+
+// NewTableAuditSink creates a sink writing through pool
+func NewTableAuditSink(pool *pgxpool.Pool) *TableAuditSink {
+	return &TableAuditSink{pool: pool}
+}
+
+// Write inserts entry
+func (s *TableAuditSink) Write(ctx context.Context, entry models.AuditEntry) error {
+	caller, err := json.Marshal(entry.Caller)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit caller: %w", err)
+	}
+	params, err := json.Marshal(entry.Params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit params: %w", err)
+	}
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO admin_audit_log (time, method, route, path, caller, params, body, status, result, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		entry.Time, entry.Method, entry.Route, entry.Path, caller, params,
+		entry.Body, entry.Status, entry.Result, entry.DurationMS)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// Read returns the newest limit entries (all when limit <= 0), oldest first
+func (s *TableAuditSink) Read(ctx context.Context, limit int) ([]models.AuditEntry, error) {
+	query := `
+		SELECT time, method, route, path, caller, params, body, status, result, duration_ms
+		FROM admin_audit_log ORDER BY time DESC`
+	args := []interface{}{}
+	if limit > 0 {
+		query += ` LIMIT $1`
+		args = append(args, limit)
+	}
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var entry models.AuditEntry
+		var caller, params []byte
+		if err := rows.Scan(&entry.Time, &entry.Method, &entry.Route, &entry.Path, &caller, &params,
+			&entry.Body, &entry.Status, &entry.Result, &entry.DurationMS); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if err := json.Unmarshal(caller, &entry.Caller); err != nil {
+			return nil, fmt.Errorf("failed to decode audit caller: %w", err)
+		}
+		if err := json.Unmarshal(params, &entry.Params); err != nil {
+			return nil, fmt.Errorf("failed to decode audit params: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
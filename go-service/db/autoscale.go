@@ -0,0 +1,174 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+// AutoscaleSignals are ingestion pipeline pressure metrics for external
+// autoscalers (KEDA metrics-api, HPA external metrics adapters)
+type AutoscaleSignals struct {
+	// BufferOccupancyRatio is buffered points over the buffer limit; at 1
+	// new points are rejected
+	BufferOccupancyRatio float64 `json:"buffer_occupancy_ratio"`
+	// FlushBacklogSeconds estimates how long flushing every buffered and
+	// in-flight point takes at the recent flush rate (0 before the first
+	// successful flush)
+	FlushBacklogSeconds float64 `json:"flush_backlog_seconds"`
+	// WALGrowthBytesPerSecond is the WAL size change over the sample window;
+	// positive while the database falls behind, negative while replaying
+	WALGrowthBytesPerSecond float64   `json:"wal_growth_bytes_per_second"`
+	BufferedPoints          int       `json:"buffered_points"`
+	InFlightPoints          int       `json:"in_flight_points"`
+	MaxBufferSize           int       `json:"max_buffer_size"`
+	FlushRatePointsPerSec   float64   `json:"flush_rate_points_per_second"`
+	WALBytes                int64     `json:"wal_bytes"`
+	WindowSeconds           float64   `json:"window_seconds"`
+	Time                    time.Time `json:"time"`
+}
+
+// flushRateSmoothing weighs the newest flush in the flush rate moving average
+const flushRateSmoothing = 0.3
+
+// recordFlushRate folds a successful database flush into the flush rate
+func (bp *BatchProcessor) recordFlushRate(points int, duration time.Duration) {
+	if duration <= 0 {
+		return
+	}
+	rate := float64(points) / duration.Seconds()
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	if bp.flushRate == 0 {
+		bp.flushRate = rate
+		return
+	}
+	bp.flushRate += flushRateSmoothing * (rate - bp.flushRate)
+}
+
+// backlog returns the buffered and in-flight point counts, the buffer limit
+// and the recent flush rate in points per second
+func (bp *BatchProcessor) backlog() (buffered, inFlight, limit int, rate float64) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	for _, batch := range bp.inFlight {
+		inFlight += len(batch)
+	}
+	return bp.bufferedLocked(), inFlight, bp.maxBufferSize, bp.flushRate
+}
+
+// walSample is the WAL size at one point in time
+type walSample struct {
+	time  time.Time
+	bytes int64
+}
+
+// AutoscaleMonitor samples the WAL size over a sliding window, so WAL growth
+// can be reported as a rate, and derives autoscaling signals from the batch
+// processor on demand
+type AutoscaleMonitor struct {
+	bp       *BatchProcessor
+	window   time.Duration
+	interval time.Duration
+
+	mu      sync.Mutex
+	samples []walSample // oldest first, spanning at most window
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewAutoscaleMonitor creates a monitor measuring WAL growth over window
+func NewAutoscaleMonitor(bp *BatchProcessor, window time.Duration) *AutoscaleMonitor {
+	if window <= 0 {
+		window = time.Minute
+	}
+	interval := window / 12
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return &AutoscaleMonitor{
+		bp:       bp,
+		window:   window,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start samples the WAL size immediately and then every window/12 until Stop
+func (m *AutoscaleMonitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			m.sample(time.Now())
+
+			select {
+			case <-ticker.C:
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the sampling loop
+func (m *AutoscaleMonitor) Stop() {
+	close(m.stopCh)
+	m.wg.Wait()
+}
+
+// walBytes returns the current WAL size (0 without a WAL)
+func (m *AutoscaleMonitor) walBytes() int64 {
+	if wal := m.bp.GetWAL(); wal != nil {
+		return wal.Size()
+	}
+	return 0
+}
+
+// sample records the WAL size and drops samples older than the window
+func (m *AutoscaleMonitor) sample(now time.Time) {
+	bytes := m.walBytes()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.samples = append(m.samples, walSample{time: now, bytes: bytes})
+	cutoff := now.Add(-m.window)
+	drop := 0
+	for drop < len(m.samples)-1 && m.samples[drop].time.Before(cutoff) {
+		drop++
+	}
+	m.samples = m.samples[drop:]
+}
+
+// Signals returns the current autoscaling signals
+func (m *AutoscaleMonitor) Signals() AutoscaleSignals {
+	now := time.Now()
+	signals := AutoscaleSignals{
+		Time:     now.UTC(),
+		WALBytes: m.walBytes(),
+	}
+
+	buffered, inFlight, limit, rate := m.bp.backlog()
+	signals.BufferedPoints = buffered
+	signals.InFlightPoints = inFlight
+	signals.MaxBufferSize = limit
+	signals.FlushRatePointsPerSec = rate
+	if limit > 0 {
+		signals.BufferOccupancyRatio = float64(buffered) / float64(limit)
+	}
+	if rate > 0 {
+		signals.FlushBacklogSeconds = float64(buffered+inFlight) / rate
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.samples) > 0 {
+		oldest := m.samples[0]
+		if elapsed := now.Sub(oldest.time).Seconds(); elapsed > 0 {
+			signals.WALGrowthBytesPerSecond = float64(signals.WALBytes-oldest.bytes) / elapsed
+			signals.WindowSeconds = elapsed
+		}
+	}
+	return signals
+}
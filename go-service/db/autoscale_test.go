@@ -0,0 +1,74 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// TestAutoscaleSignalsBufferAndBacklog tests the buffer occupancy ratio and
+// the backlog estimate from the flush rate
+func TestAutoscaleSignalsBufferAndBacklog(t *testing.T) {
+	bp := &BatchProcessor{
+		buffer:        make([]models.TelemetryPoint, 0, 10),
+		batchSize:     100,
+		maxBufferSize: 10,
+	}
+	for i := 0; i < 4; i++ {
+		if err := bp.Add(TelemetryPointForTest(80, 1000, -60)); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+	}
+	bp.trackInFlight([]models.TelemetryPoint{TelemetryPointForTest(80, 1000, -60), TelemetryPointForTest(80, 1000, -60)})
+
+	monitor := NewAutoscaleMonitor(bp, time.Minute)
+	signals := monitor.Signals()
+	if signals.BufferOccupancyRatio != 0.4 || signals.BufferedPoints != 4 || signals.InFlightPoints != 2 {
+		t.Errorf("unexpected buffer signals %+v", signals)
+	}
+	if signals.FlushBacklogSeconds != 0 {
+		t.Errorf("expected no backlog estimate before a flush, got %v", signals.FlushBacklogSeconds)
+	}
+
+	bp.recordFlushRate(100, time.Second)
+	bp.recordFlushRate(200, time.Second)
+	signals = monitor.Signals()
+	if signals.FlushRatePointsPerSec != 130 {
+		t.Errorf("expected smoothed flush rate 130, got %v", signals.FlushRatePointsPerSec)
+	}
+	if want := 6.0 / 130; signals.FlushBacklogSeconds != want {
+		t.Errorf("expected backlog %v seconds, got %v", want, signals.FlushBacklogSeconds)
+	}
+}
+
+// TestAutoscaleSignalsWALGrowth tests the WAL growth rate over the window and
+// that samples older than the window are dropped
+func TestAutoscaleSignalsWALGrowth(t *testing.T) {
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	bp := &BatchProcessor{wal: wal}
+	monitor := NewAutoscaleMonitor(bp, time.Minute)
+
+	now := time.Now()
+	monitor.sample(now.Add(-2 * time.Minute)) // outside the window once newer samples exist
+	monitor.sample(now.Add(-10 * time.Second))
+	if len(monitor.samples) != 1 {
+		t.Fatalf("expected the stale sample dropped, got %d samples", len(monitor.samples))
+	}
+
+	if err := wal.Write(NewWALRecord(TelemetryPointForTest(80, 1000, -60))); err != nil {
+		t.Fatalf("WAL write failed: %v", err)
+	}
+	signals := monitor.Signals()
+	if signals.WALBytes <= 0 || signals.WALGrowthBytesPerSecond <= 0 {
+		t.Errorf("expected positive WAL growth, got %+v", signals)
+	}
+	if signals.WindowSeconds < 10 || signals.WindowSeconds > 11 {
+		t.Errorf("expected a ~10s window, got %v", signals.WindowSeconds)
+	}
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrBufferFull is returned by Add when the buffer (or the point's shard)
+// holds the maximum number of points
+var ErrBufferFull = errors.New("buffer at maximum capacity")
+
+// Bounds of the Retry-After hint given to clients under backpressure
+const (
+	minBackpressureRetryAfter = time.Second
+	maxBackpressureRetryAfter = time.Minute
+)
+
+// BackpressureState tells the HTTP layer whether to slow clients down
+type BackpressureState struct {
+	// Active is set while the buffer is at or above the high-water mark
+	Active        bool
+	Buffered      int
+	HighWaterMark int
+	// RetryAfter estimates how long flushing takes to bring the buffer back
+	// under the high-water mark at the recent flush rate, in whole seconds
+	RetryAfter time.Duration
+}
+
+// SetHighWaterMark sets the buffered point count at which Backpressure asks
+// clients to back off (0 disables it). Points are still accepted up to the
+// max buffer size, so clients that ignore it only lose data at the limit.
+func (bp *BatchProcessor) SetHighWaterMark(points int) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.highWaterMark = points
+}
+
+// Backpressure reports whether the buffer is above the high-water mark
+func (bp *BatchProcessor) Backpressure() BackpressureState {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+
+	state := BackpressureState{Buffered: bp.bufferedLocked(), HighWaterMark: bp.highWaterMark}
+	if bp.highWaterMark <= 0 || state.Buffered < bp.highWaterMark {
+		return state
+	}
+	state.Active = true
+
+	// Without a flush rate yet, one flush interval is the best guess
+	retryAfter := bp.batchTimeout
+	if bp.flushRate > 0 {
+		excess := state.Buffered - bp.highWaterMark + 1
+		retryAfter = time.Duration(float64(excess) / bp.flushRate * float64(time.Second))
+	}
+	retryAfter = time.Duration(math.Ceil(retryAfter.Seconds())) * time.Second
+	state.RetryAfter = min(max(retryAfter, minBackpressureRetryAfter), maxBackpressureRetryAfter)
+	return state
+}
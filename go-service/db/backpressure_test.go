@@ -0,0 +1,55 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBackpressureHighWaterMark tests that backpressure starts at the
+// high-water mark and is off without one
+func TestBackpressureHighWaterMark(t *testing.T) {
+	bp := &BatchProcessor{batchTimeout: 5 * time.Second}
+	for i := 0; i < 10; i++ {
+		bp.buffer = append(bp.buffer, TelemetryPointForTest(85.0, 45000.0, -55.0))
+	}
+	if bp.Backpressure().Active {
+		t.Error("expected no backpressure without a high-water mark")
+	}
+
+	bp.SetHighWaterMark(11)
+	if bp.Backpressure().Active {
+		t.Error("expected no backpressure below the high-water mark")
+	}
+
+	bp.SetHighWaterMark(10)
+	state := bp.Backpressure()
+	if !state.Active || state.Buffered != 10 {
+		t.Fatalf("expected backpressure at the high-water mark, got %+v", state)
+	}
+	if state.RetryAfter != 5*time.Second {
+		t.Errorf("expected one flush interval before the first flush, got %v", state.RetryAfter)
+	}
+}
+
+// TestBackpressureRetryAfter tests the Retry-After estimate from the flush rate
+func TestBackpressureRetryAfter(t *testing.T) {
+	bp := &BatchProcessor{highWaterMark: 100, flushRate: 50}
+	for i := 0; i < 349; i++ {
+		bp.buffer = append(bp.buffer, TelemetryPointForTest(85.0, 45000.0, -55.0))
+	}
+
+	// 250 points over the mark at 50 points/s
+	if got := bp.Backpressure().RetryAfter; got != 5*time.Second {
+		t.Errorf("expected 5s, got %v", got)
+	}
+
+	bp.flushRate = 1000
+	if got := bp.Backpressure().RetryAfter; got != time.Second {
+		t.Errorf("expected the 1s minimum, got %v", got)
+	}
+
+	bp.flushRate = 0.1
+	if got := bp.Backpressure().RetryAfter; got != time.Minute {
+		t.Errorf("expected the 1m maximum, got %v", got)
+	}
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -9,31 +10,120 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"orbitstream/models"
+	"orbitstream/retry"
 )
 
 type BatchProcessor struct {
 	pool            *pgxpool.Pool
 	batchSize       int
+	// Sizes batches from insert latency instead of batchSize when set
+	batchSizer      *BatchSizeController
 	batchTimeout    time.Duration
 	buffer          []models.TelemetryPoint
 	bufferMutex     sync.Mutex
+	// Per-satellite buffer shards replacing buffer when set (see
+	// SetBufferShards), and the shard the next batch is taken from
+	shards          []*bufferShard
+	nextShard       int
 	ticker          *time.Ticker
 	done            chan bool
+	stopOnce        sync.Once
+	// Closed by Start's loop once its final flush finished (nil = no loop)
+	stopped         chan struct{}
+	// Set by Drain: Add rejects points, and the final flush is bounded by
+	// the drain's context
+	draining        bool
+	drainCtx        context.Context
 	anomalyConfig   AnomalyConfig
 	wal             *WAL
 	circuitBreaker  *CircuitBreaker
 	maxRetries      int
 	retryDelay      time.Duration
+	// Total wall clock time one flush may spend retrying (0 = attempts only)
+	retryBudget     time.Duration
+	// Backoff randomization, see retry.ParseJitter ("" = proportional)
+	retryJitter     string
+	// Cancelled by Stop so running flushes stop retrying and go to the WAL
+	retryCtx        context.Context
+	cancelRetries   context.CancelFunc
 	maxBufferSize   int
+	// Buffered points above which clients are asked to back off (0 = never)
+	highWaterMark   int
+	detectors       []AnomalyDetector
+	alertSink       AnomalyAlertSink
+	// Batches swapped out of the buffer but not yet committed or written to WAL
+	inFlight        map[uint64][]models.TelemetryPoint
+	nextFlightID    uint64
+	lateData        *LateDataTracker
+	gaps            *GapRecorder
+	geofences       *GeofenceMonitor
+	propagator      *OrbitPropagator
+	watchdog        *ContactWatchdog
+	lastFlush       FlushStatus
+	// Called with the panic value when a background goroutine panics
+	crashHandler    func(reason any)
+	// Write-through journal and the journal position of buffer[0]
+	journal         *WriteThroughJournal
+	journalStart    uint64
+	// Journaled batches the WAL failed to take, written to it again on the
+	// next flush so their journal range can be committed
+	journalRetries  []flushJob
+	// Moving average of database flush throughput, points per second
+	flushRate       float64
+	// Pipeline totals and the latest committed flush, see Stats
+	counters        pipelineCounters
+	lastCommit      models.LastFlush
+	created         time.Time
+	deadLetters     *DeadLetterQueue
+	// Committed batches are written again to the table being burned in
+	shadow          *ShadowWriter
+	// Batches the database was too slow for wait here instead of the WAL
+	overflow        *OverflowQueue
+	episodes        *EpisodeTracker
+	cardinality     *CardinalityGuard
+	dedup           *Deduplicator
+	timestampGuard  *TimestampGuard
+	// Parallel flush workers fed batches through flushQueue by Start
+	// (0 = flush on the ticker goroutine)
+	flushWorkers    int
+	flushQueue      chan flushJob
+	// Signals Start's loop, the only flusher, that the buffer reached batchSize
+	flushWake       chan struct{}
+	workers         sync.WaitGroup
+	// Serializes whole-buffer flushes (shutdown, no workers, tests)
+	flushMu         sync.Mutex
+}
+
+// flushJob is a batch swapped out of the buffer for one flush
+type flushJob struct {
+	batch    []models.TelemetryPoint
+	flightID uint64
+	// Write-through journal range of the batch, committed once it is stored
+	journal      *WriteThroughJournal
+	journalStart uint64
+	// Shard the batch was taken from, released once it is stored
+	shard *bufferShard
+	// Overflow file the batch was taken back from, removed once it is stored
+	overflowPath string
 }
 
 type AnomalyConfig struct {
 	BatteryMinPercent float64
 	StorageMaxMB      float64
 	SignalMinDBM      float64
+	// Hysteresis: a breach must persist for ConsecutivePoints points or
+	// MinBreachDuration before flagging (zero values flag immediately)
+	ConsecutivePoints int
+	MinBreachDuration time.Duration
+	// Clear thresholds a flagged metric must recover past before un-flagging
+	// (zero uses the flag threshold)
+	BatteryClearPercent float64
+	StorageClearMB      float64
+	SignalClearDBM      float64
 }
 
 func NewBatchProcessor(pool *pgxpool.Pool, batchSize int, batchTimeout time.Duration, anomalyConfig AnomalyConfig) *BatchProcessor {
+	retryCtx, cancelRetries := context.WithCancel(context.Background())
 	return &BatchProcessor{
 		pool:           pool,
 		batchSize:      batchSize,
@@ -45,6 +135,12 @@ func NewBatchProcessor(pool *pgxpool.Pool, batchSize int, batchTimeout time.Dura
 		retryDelay:     1 * time.Second, // Default: 1 second initial delay
 		maxBufferSize:  10000,          // Default: 10K max buffer size
 		circuitBreaker: NewCircuitBreaker(3, 30*time.Second), // Open after 3 failures, 30s timeout
+		retryBudget:    30 * time.Second, // Default: give up retrying after 30s
+		flushWorkers:   1,                // Default: one flush at a time, off the ticker
+		flushWake:      make(chan struct{}, 1),
+		retryCtx:       retryCtx,
+		cancelRetries:  cancelRetries,
+		created:        time.Now().UTC(),
 	}
 }
 
@@ -55,6 +151,41 @@ func (bp *BatchProcessor) SetWAL(wal *WAL) {
 	bp.wal = wal
 }
 
+// SetWriteThrough enables write-through mode: every accepted point is
+// written to journal before it is buffered
+func (bp *BatchProcessor) SetWriteThrough(journal *WriteThroughJournal) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.journal = journal
+	// Journal positions are consecutive, so the buffer can't be sharded
+	bp.shards = nil
+}
+
+// SetDeadLetterQueue diverts records the database rejects permanently to
+// dlq instead of failing their whole batch
+func (bp *BatchProcessor) SetDeadLetterQueue(dlq *DeadLetterQueue) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.deadLetters = dlq
+}
+
+// SetOverflowQueue spills batches to queue when retries run out while the
+// database is still reachable (circuit breaker closed), keeping the WAL for
+// outages. Spilled batches are flushed again ahead of the buffer. Not used
+// in write-through mode, whose journal only commits durably stored batches.
+func (bp *BatchProcessor) SetOverflowQueue(queue *OverflowQueue) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.overflow = queue
+}
+
+// GetDeadLetterQueue returns the dead letter queue (nil if disabled)
+func (bp *BatchProcessor) GetDeadLetterQueue() *DeadLetterQueue {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return bp.deadLetters
+}
+
 // SetCircuitBreaker sets the circuit breaker for fault tolerance
 func (bp *BatchProcessor) SetCircuitBreaker(cb *CircuitBreaker) {
 	bp.bufferMutex.Lock()
@@ -70,6 +201,32 @@ func (bp *BatchProcessor) SetRetryConfig(maxRetries int, retryDelay time.Duratio
 	bp.retryDelay = retryDelay
 }
 
+// SetRetryBudget caps the wall clock time one flush spends retrying before
+// it writes the batch to the WAL (0 = bounded by attempts only). With a
+// budget, maxRetries <= 0 retries until the budget runs out.
+func (bp *BatchProcessor) SetRetryBudget(budget time.Duration) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.retryBudget = budget
+}
+
+// SetRetryJitter sets how backoff delays are randomized (see
+// retry.ParseJitter)
+func (bp *BatchProcessor) SetRetryJitter(strategy string) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.retryJitter = strategy
+}
+
+// SetFlushWorkers sets how many batches may be flushed in parallel; must be
+// called before Start. While every worker is busy, points stay buffered (up
+// to the max buffer size) instead of stalling the flush ticker.
+func (bp *BatchProcessor) SetFlushWorkers(workers int) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.flushWorkers = workers
+}
+
 // SetMaxBufferSize sets the maximum buffer size before rejecting new data
 func (bp *BatchProcessor) SetMaxBufferSize(size int) {
 	bp.bufferMutex.Lock()
@@ -77,117 +234,605 @@ func (bp *BatchProcessor) SetMaxBufferSize(size int) {
 	bp.maxBufferSize = size
 }
 
+// SetAnomalyDetectors replaces the anomaly detector chain
+// When no detectors are set, the fixed thresholds from AnomalyConfig are used
+func (bp *BatchProcessor) SetAnomalyDetectors(detectors ...AnomalyDetector) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.detectors = detectors
+}
+
+// SetAlertSink registers a sink notified of every anomaly finding
+func (bp *BatchProcessor) SetAlertSink(sink AnomalyAlertSink) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.alertSink = sink
+}
+
+// SetEpisodeTracker enables anomaly episode tracking; closed episodes are
+// sent to the alert sink as recovery events
+func (bp *BatchProcessor) SetEpisodeTracker(tracker *EpisodeTracker) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.episodes = tracker
+}
+
+// SetLateDataTracker enables late-arrival detection and policies
+func (bp *BatchProcessor) SetLateDataTracker(tracker *LateDataTracker) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.lateData = tracker
+}
+
+// SetCardinalityGuard caps the satellites detector and episode state is kept
+// for; the state of satellites the guard displaces is dropped
+func (bp *BatchProcessor) SetCardinalityGuard(guard *CardinalityGuard) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.cardinality = guard
+}
+
+// GetCardinalityGuard returns the cardinality guard (nil if unlimited)
+func (bp *BatchProcessor) GetCardinalityGuard() *CardinalityGuard {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return bp.cardinality
+}
+
+// GetLateDataTracker returns the late-arrival tracker (nil if disabled)
+func (bp *BatchProcessor) GetLateDataTracker() *LateDataTracker {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return bp.lateData
+}
+
+// AddAnomalyDetector appends a detector to the end of the chain
+func (bp *BatchProcessor) AddAnomalyDetector(detector AnomalyDetector) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	if bp.detectors == nil {
+		bp.detectors = []AnomalyDetector{NewThresholdDetector(bp.anomalyConfig)}
+	}
+	bp.detectors = append(bp.detectors, detector)
+}
+
 func (bp *BatchProcessor) Add(point models.TelemetryPoint) error {
 	bp.bufferMutex.Lock()
 	defer bp.bufferMutex.Unlock()
 
+	if bp.draining {
+		bp.counters.rejected.Add(1)
+		return ErrDraining
+	}
+
+	// Any point heard, even a refused one, proves the satellite is alive
+	bp.watchdog.observe(point.SatelliteID, time.Now())
+
+	// Keep bad satellite clocks out of historical buckets
+	if err := bp.timestampGuard.check(&point, time.Now().UTC()); err != nil {
+		bp.counters.rejected.Add(1)
+		return err
+	}
+
+	// Drop retransmissions of recently accepted points; the sender gets the
+	// same answer as for the original
+	key, duplicate := bp.dedup.seen(&point)
+	if duplicate {
+		bp.counters.duplicates.Add(1)
+		return nil
+	}
+
 	// Check buffer size limit to prevent unbounded growth
-	if len(bp.buffer) >= bp.maxBufferSize {
+	shard := bp.shardFor(point.SatelliteID)
+	if shard != nil {
+		if limit := bp.shardCapacity(); len(shard.points) >= limit {
+			log.Printf("WARNING: Buffer shard of %s full (%d records), rejecting new data", point.SatelliteID, len(shard.points))
+			bp.counters.rejected.Add(1)
+			return fmt.Errorf("%w: shard of %s holds %d points", ErrBufferFull, point.SatelliteID, limit)
+		}
+	} else if len(bp.buffer) >= bp.maxBufferSize {
 		log.Printf("WARNING: Buffer full (%d records), rejecting new data", len(bp.buffer))
-		return fmt.Errorf("buffer at maximum capacity (%d)", bp.maxBufferSize)
+		bp.counters.rejected.Add(1)
+		return fmt.Errorf("%w (%d)", ErrBufferFull, bp.maxBufferSize)
 	}
 
-	// Check for anomalies
-	point.IsAnomaly = bp.detectAnomaly(point)
+	// Flag telemetry arriving after its aggregate windows were refreshed
+	bp.lateData.observe(&point, time.Now())
+
+	// Compare the reported position with the registered TLE's prediction
+	bp.propagator.enrich(&point)
+
+	// Keep per-satellite state for the most active satellites only
+	if evicted, ok := bp.cardinality.observe(point.SatelliteID); ok {
+		bp.forgetSatellite(evicted)
+	}
+
+	// Run the anomaly detector chain and classify the point
+	results := bp.runDetectors(point)
+	applyAnomalyResults(&point, results)
+	recovered := bp.episodes.observe(point, results)
+	zoneEvents := bp.geofences.observe(&point)
+	if bp.alertSink != nil {
+		for _, result := range results {
+			bp.alertSink.Alert(models.AnomalyEvent{
+				SatelliteID:   point.SatelliteID,
+				Timestamp:     point.Timestamp,
+				AnomalyResult: result,
+			})
+		}
+		for _, episode := range recovered {
+			bp.alertSink.Alert(recoveryEvent(point, episode))
+		}
+		for _, event := range zoneEvents {
+			bp.alertSink.Alert(event)
+		}
+	}
+
+	// In write-through mode the point must be durable before it is accepted
+	if bp.journal != nil {
+		pos, err := bp.journal.Append(point)
+		if err != nil {
+			bp.counters.rejected.Add(1)
+			return fmt.Errorf("failed to write point to write-through journal: %w", err)
+		}
+		if len(bp.buffer) == 0 {
+			bp.journalStart = pos
+		}
+	}
 
-	bp.buffer = append(bp.buffer, point)
+	bp.dedup.remember(key)
+	bp.counters.accepted.Add(1)
+	if point.IsAnomaly {
+		bp.counters.anomalies.Add(1)
+	}
+	buffered := 0
+	if shard != nil {
+		shard.points = append(shard.points, point)
+		buffered = len(shard.points)
+	} else {
+		bp.buffer = append(bp.buffer, point)
+		buffered = len(bp.buffer)
+	}
 
-	// If buffer reaches batch size, trigger immediate flush
-	if len(bp.buffer) >= bp.batchSize {
-		go bp.flush()
+	// If buffer reaches batch size, wake the flush loop. Add never flushes
+	// itself: flushes started here would race the ticker's, each swapping
+	// the buffer and storing batches out of order.
+	if buffered >= bp.batchSizer.sizeOr(bp.batchSize) {
+		bp.wakeFlushLoop()
 	}
 
 	return nil
 }
 
+// wakeFlushLoop asks Start's loop to dispatch a flush
+func (bp *BatchProcessor) wakeFlushLoop() {
+	select {
+	case bp.flushWake <- struct{}{}:
+	default: // a dispatch is already pending (or Start isn't running yet)
+	}
+}
+
+// Start runs the flush loop until Stop. It is the only goroutine that takes
+// batches out of the buffer, on the ticker or when Add fills a batch, and
+// hands them to the flush workers (or flushes inline without workers).
 func (bp *BatchProcessor) Start() {
+	stopped := bp.loopStarted()
+	defer close(stopped)
+	bp.bufferMutex.Lock()
+	// Retune resets the ticker when the batch timeout changes
 	bp.ticker = time.NewTicker(bp.batchTimeout)
+	bp.bufferMutex.Unlock()
+	bp.startFlushWorkers()
 
 	for {
 		select {
 		case <-bp.ticker.C:
-			bp.flush()
+			bp.dispatchFlush()
+		case <-bp.flushWake:
+			bp.dispatchFlush()
 		case <-bp.done:
 			bp.ticker.Stop()
-			// Final flush on shutdown
-			bp.flush()
+			bp.stopFlushWorkers()
+			// Final flush on shutdown, bounded by the retry budget (and
+			// the drain deadline)
+			bp.flushWithContext(bp.finalFlushContext())
+			if bp.overflow != nil && bp.overflow.Len() > 0 {
+				log.Printf("%d spilled records stay in the overflow queue until the next start", bp.overflow.Len())
+			}
+			return
+		}
+	}
+}
+
+// startFlushWorkers starts the flush workers; the queue holds one batch per
+// worker so dispatching never blocks the ticker
+func (bp *BatchProcessor) startFlushWorkers() {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	if bp.flushWorkers <= 0 {
+		return
+	}
+	bp.flushQueue = make(chan flushJob, bp.flushWorkers)
+	for i := 0; i < bp.flushWorkers; i++ {
+		bp.workers.Add(1)
+		go bp.flushWorker()
+	}
+}
+
+// stopFlushWorkers lets the workers finish the queued batches and waits
+func (bp *BatchProcessor) stopFlushWorkers() {
+	if bp.flushQueue == nil {
+		return
+	}
+	close(bp.flushQueue)
+	bp.workers.Wait()
+}
+
+// flushWorker flushes queued batches. Each flush consults the insert
+// circuit breaker itself, so while it is open every worker diverts its
+// batch to the WAL, and half-open probes are shared between workers.
+func (bp *BatchProcessor) flushWorker() {
+	defer bp.workers.Done()
+	defer bp.recoverCrash()
+	for job := range bp.flushQueue {
+		bp.runFlush(bp.flushContext(), job)
+	}
+}
+
+// dispatchFlush hands batches of up to the batch size to idle workers,
+// leaving the rest buffered while all of them are busy. Only Start's
+// goroutine sends to the queue, so checking its length first never blocks.
+func (bp *BatchProcessor) dispatchFlush() {
+	bp.retryJournalBatches()
+	if bp.flushQueue == nil {
+		ctx := bp.flushContext()
+		if job, ok := bp.takeOverflowBatch(); ok {
+			bp.runFlush(ctx, job)
+		}
+		bp.flushWithContext(ctx)
+		return
+	}
+	for len(bp.flushQueue) < cap(bp.flushQueue) {
+		job, ok := bp.takeOverflowBatch()
+		if !ok {
+			job, ok = bp.takeBatch(bp.targetBatchSize())
+		}
+		if !ok {
 			return
 		}
+		bp.flushQueue <- job
+	}
+}
+
+// takeOverflowBatch takes the oldest spilled batch back from the overflow
+// queue and tracks it as in flight; its points are older than the buffer's
+func (bp *BatchProcessor) takeOverflowBatch() (flushJob, bool) {
+	if bp.overflow == nil {
+		return flushJob{}, false
 	}
+	batch, path, ok := bp.overflow.Pop()
+	if !ok {
+		return flushJob{}, false
+	}
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return flushJob{batch: batch, flightID: bp.trackInFlight(batch), overflowPath: path}, true
 }
 
+// Stop ends the flush loop after a final flush without waiting for it;
+// flushes already retrying stop backing off and write their batches to the
+// WAL. Drain waits for the buffer to be stored instead.
 func (bp *BatchProcessor) Stop() {
-	close(bp.done)
+	if bp.cancelRetries != nil {
+		bp.cancelRetries()
+	}
+	bp.stopLoop()
+}
+
+// stopLoop asks Start's loop to end; safe to call more than once
+func (bp *BatchProcessor) stopLoop() {
+	bp.stopOnce.Do(func() { close(bp.done) })
 }
 
 func (bp *BatchProcessor) flush() {
+	bp.flushWithContext(bp.flushContext())
+}
+
+// flushContext is the context a flush retries under: the retry context
+// until Stop or the drain deadline cancels it, then the final flush's. A
+// batch still queued after Stop gets one flush bounded by the retry budget,
+// like the buffer's, while one queued past the drain deadline goes straight
+// to the WAL.
+func (bp *BatchProcessor) flushContext() context.Context {
+	if bp.retryCtx != nil && bp.retryCtx.Err() == nil {
+		return bp.retryCtx
+	}
+	return bp.finalFlushContext()
+}
+
+// flushWithContext flushes the buffer, one batch per shard, retrying until
+// ctx is done. Callers wait for a flush already running, so batches are
+// stored in order.
+func (bp *BatchProcessor) flushWithContext(ctx context.Context) {
+	bp.flushMu.Lock()
+	defer bp.flushMu.Unlock()
+	bp.retryJournalBatches()
+	for i := bp.shardCount(); i > 0; i-- {
+		job, ok := bp.takeBatch(0)
+		if !ok {
+			return
+		}
+		bp.runFlush(ctx, job)
+	}
+}
+
+// takeBatch swaps up to max points (all when max <= 0) out of the buffer, or
+// out of the next shard when sharded, and tracks them as in flight
+func (bp *BatchProcessor) takeBatch(max int) (flushJob, bool) {
 	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	if len(bp.shards) > 0 {
+		return bp.takeShardBatch(max)
+	}
 	if len(bp.buffer) == 0 {
-		bp.bufferMutex.Unlock()
-		return
+		return flushJob{}, false
+	}
+	n := len(bp.buffer)
+	if max > 0 && n > max {
+		n = max
 	}
 
 	// Swap buffer to minimize lock time
-	batch := make([]models.TelemetryPoint, len(bp.buffer))
-	copy(batch, bp.buffer)
-	bp.buffer = make([]models.TelemetryPoint, 0, bp.batchSize)
-	bp.bufferMutex.Unlock()
+	batch := bp.swapOut(&bp.buffer, n)
+
+	job := flushJob{
+		batch:        batch,
+		flightID:     bp.trackInFlight(batch),
+		journal:      bp.journal,
+		journalStart: bp.journalStart,
+	}
+	// Journal positions are consecutive, so the rest starts n later
+	bp.journalStart += uint64(n)
+	return job, true
+}
+
+// runFlush flushes a batch with retry logic and WAL fallback
+func (bp *BatchProcessor) runFlush(ctx context.Context, job flushJob) {
+	// Nothing keeps the batch once it is committed, spilled or in the WAL
+	defer putPointBatch(job.batch)
+	defer bp.untrackInFlight(job.flightID)
+	if job.shard != nil {
+		defer bp.releaseShard(job.shard)
+	}
 
-	// Try to flush with retry logic and WAL fallback
-	if err := bp.flushWithRetry(batch); err != nil {
+	err := bp.flushWithRetry(ctx, job.batch)
+	if err != nil {
 		log.Printf("ERROR: Failed to flush batch after all retries: %v", err)
+		if tally := flushTally(ctx); tally != nil {
+			tally.Failed += int64(len(job.batch))
+		}
+		// Left uncommitted, the journal (or the overflow file it was
+		// taken back from) recovers the batch on restart; until then a
+		// journaled batch pins the watermark, so the WAL gets another go
+		if job.journal != nil && bp.wal != nil {
+			bp.bufferMutex.Lock()
+			bp.journalRetries = append(bp.journalRetries, flushJob{
+				batch:        append([]models.TelemetryPoint(nil), job.batch...),
+				journal:      job.journal,
+				journalStart: job.journalStart,
+			})
+			bp.bufferMutex.Unlock()
+		}
+	} else {
+		if job.journal != nil {
+			commitJournal(job)
+		}
+		if job.overflowPath != "" {
+			bp.overflow.Ack(job.overflowPath)
+		}
 	}
+	bp.recordFlush(len(job.batch), err)
 }
 
-// flushWithRetry attempts to flush the batch with retry logic and exponential backoff
-// If all retries fail, it falls back to writing to WAL
-func (bp *BatchProcessor) flushWithRetry(batch []models.TelemetryPoint) error {
-	for attempt := 0; attempt < bp.maxRetries; attempt++ {
-		// Check circuit breaker first
-		if bp.circuitBreaker != nil && !bp.circuitBreaker.Allow() {
-			log.Printf("Circuit breaker OPEN, writing %d records to WAL", len(batch))
-			return bp.flushToWAL(batch)
+// commitJournal marks a stored batch's range committed in its journal
+func commitJournal(job flushJob) {
+	if err := job.journal.Commit(job.journalStart, job.journalStart+uint64(len(job.batch))); err != nil {
+		log.Printf("WARNING: Failed to mark batch committed in write-through journal: %v", err)
+	}
+}
+
+// retryJournalBatches writes the journaled batches the WAL failed to take to
+// it again, oldest first, and commits each one it takes. The rest wait for
+// the next flush.
+func (bp *BatchProcessor) retryJournalBatches() {
+	bp.bufferMutex.Lock()
+	jobs := bp.journalRetries
+	bp.journalRetries = nil
+	bp.bufferMutex.Unlock()
+
+	for i, job := range jobs {
+		if err := bp.flushToWAL(job.batch); err != nil {
+			log.Printf("WARNING: WAL still failing, %d journaled batches stay uncommitted: %v", len(jobs)-i, err)
+			bp.bufferMutex.Lock()
+			bp.journalRetries = append(jobs[i:], bp.journalRetries...)
+			bp.bufferMutex.Unlock()
+			return
 		}
+		commitJournal(job)
+	}
+}
 
-		// Attempt to insert to database
-		startTime := time.Now()
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		rowsAffected, err := bp.insertBatch(ctx, batch)
-		cancel()
-		duration := time.Since(startTime)
+// recordFlush keeps the outcome of the latest flush for crash reports
+func (bp *BatchProcessor) recordFlush(records int, err error) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.lastFlush = FlushStatus{Time: time.Now().UTC(), Records: records}
+	if err != nil {
+		bp.lastFlush.Error = err.Error()
+	}
+}
 
+// trackInFlight registers a batch that left the buffer but is not yet durable
+// Caller must hold bufferMutex
+func (bp *BatchProcessor) trackInFlight(batch []models.TelemetryPoint) uint64 {
+	if bp.inFlight == nil {
+		bp.inFlight = make(map[uint64][]models.TelemetryPoint)
+	}
+	bp.nextFlightID++
+	bp.inFlight[bp.nextFlightID] = batch
+	return bp.nextFlightID
+}
+
+// untrackInFlight removes a batch once it reached the database or the WAL
+func (bp *BatchProcessor) untrackInFlight(id uint64) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	delete(bp.inFlight, id)
+}
+
+// flushWithRetry attempts to flush the batch with retry logic and exponential backoff
+// Retries stop after maxRetries attempts, once the retry budget is spent or
+// when ctx is done; the batch then falls back to the WAL. Only transient
+// errors are retried (see classifyInsertError): rows the database rejects go
+// to the dead letter queue, and client-side errors skip the circuit breaker.
+func (bp *BatchProcessor) flushWithRetry(ctx context.Context, batch []models.TelemetryPoint) error {
+	err := retry.Do(ctx, bp.retryPolicy(), func(ctx context.Context, attempt int) error {
+		return bp.flushAttempt(ctx, attempt, batch)
+	})
+	if err == nil {
+		return nil
+	}
+	if bp.spillToOverflow(ctx, err, batch) {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, errFlushDiverted):
+		// flushAttempt logged why
+	case errors.Is(err, retry.ErrBudgetExhausted):
+		log.Printf("Retry budget spent (%v), writing %d records to WAL", err, len(batch))
+	case errors.Is(err, retry.ErrAttemptsExhausted):
+		log.Printf("All retry attempts failed (%v), writing %d records to WAL", err, len(batch))
+	default:
+		log.Printf("Retries cancelled (%v), writing %d records to WAL", err, len(batch))
+	}
+	if err := bp.flushToWAL(batch); err != nil {
+		return err
+	}
+	if tally := flushTally(ctx); tally != nil {
+		tally.WALDiverted += int64(len(batch))
+	}
+	return nil
+}
+
+// spillToOverflow parks a batch in the overflow queue when retries ran out
+// but the database isn't down: the circuit breaker is still closed and the
+// processor isn't stopping. It reports whether the batch was spilled.
+func (bp *BatchProcessor) spillToOverflow(ctx context.Context, err error, batch []models.TelemetryPoint) bool {
+	if bp.overflow == nil || bp.journal != nil || ctx.Err() != nil {
+		return false
+	}
+	if bp.retryCtx != nil && bp.retryCtx.Err() != nil {
+		return false
+	}
+	if !errors.Is(err, retry.ErrAttemptsExhausted) && !errors.Is(err, retry.ErrBudgetExhausted) {
+		return false
+	}
+	if bp.circuitBreaker != nil && bp.circuitBreaker.State() != Closed {
+		return false
+	}
+	if spillErr := bp.overflow.Push(batch); spillErr != nil {
+		log.Printf("WARNING: Failed to spill %d records to the overflow queue: %v", len(batch), spillErr)
+		return false
+	}
+	log.Printf("Database slow (%v), spilled %d records to the overflow queue", err, len(batch))
+	bp.counters.overflowSpilled.Add(int64(len(batch)))
+	if tally := flushTally(ctx); tally != nil {
+		tally.OverflowSpilled += int64(len(batch))
+	}
+	return true
+}
+
+// errFlushDiverted ends retrying: the batch goes straight to the WAL
+var errFlushDiverted = errors.New("flush diverted to WAL")
+
+// flushAttempt makes one attempt to insert the batch. Errors not worth
+// retrying are returned as retry.Permanent(errFlushDiverted).
+func (bp *BatchProcessor) flushAttempt(ctx context.Context, attempt int, batch []models.TelemetryPoint) error {
+	// Check circuit breaker first
+	if bp.circuitBreaker != nil && !bp.circuitBreaker.Allow() {
+		log.Printf("Circuit breaker OPEN, writing %d records to WAL", len(batch))
+		return retry.Permanent(errFlushDiverted)
+	}
+
+	// Attempt to insert to database
+	startTime := time.Now()
+	attemptCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rowsAffected, err := bp.insertBatch(attemptCtx, batch)
+	committed := batch
+	if err != nil && bp.deadLetters != nil && classifyInsertError(err) == insertErrorRejected {
+		// One bad record must not fail the batch forever: insert the
+		// rest and divert the rejected records to the dead letter queue
+		log.Printf("Flush attempt %d rejected a record (%v), isolating bad records", attempt, err)
+		rowsAffected, committed, err = insertDivertingRejects(attemptCtx, bp.pool, batch, bp.lateData, bp.gaps, bp.deadLetters, DeadLetterSourceFlush)
 		if err == nil {
-			// Success!
-			pointsPerSecond := float64(rowsAffected) / duration.Seconds()
-			log.Printf("Flushed %d rows in %v (%.0f points/sec)",
-				rowsAffected, duration, pointsPerSecond)
-
-			// Record success with circuit breaker
-			if bp.circuitBreaker != nil {
-				bp.circuitBreaker.RecordSuccess()
+			bp.counters.deadLettered.Add(int64(len(batch) - len(committed)))
+			if tally := flushTally(ctx); tally != nil {
+				tally.DeadLettered += int64(len(batch) - len(committed))
 			}
-			return nil
+		}
+	}
+	duration := time.Since(startTime)
+
+	if err == nil {
+		// Success!
+		pointsPerSecond := float64(rowsAffected) / duration.Seconds()
+		log.Printf("Flushed %d rows in %v (%.0f points/sec)",
+			rowsAffected, duration, pointsPerSecond)
+		if skipped := int64(len(committed)) - rowsAffected; skipped > 0 {
+			log.Printf("Skipped %d duplicate rows already stored", skipped)
 		}
 
-		log.Printf("Flush attempt %d failed: %v", attempt+1, err)
-
-		// Record failure with circuit breaker
+		// Record success with circuit breaker
 		if bp.circuitBreaker != nil {
-			bp.circuitBreaker.RecordFailure()
+			bp.circuitBreaker.RecordSuccess()
 		}
+		bp.lateData.committed(committed)
+		bp.recordFlushRate(len(batch), duration)
+		bp.recordCommit(len(committed), duration)
+		if tally := flushTally(ctx); tally != nil {
+			tally.Written += int64(len(committed))
+		}
+		bp.batchSizer.observe(len(batch), duration, nil)
+		bp.shadow.enqueue(committed)
+		return nil
+	}
+
+	log.Printf("Flush attempt %d failed: %v", attempt, err)
 
-		// Exponential backoff with jitter (except on last attempt)
-		if attempt < bp.maxRetries-1 {
-			delay := bp.retryDelay * time.Duration(1<<uint(attempt))
-			// Add some jitter (±20%)
-			jitter := time.Duration(float64(delay) * 0.2 * (2.0*randFloat64() - 1.0))
-			time.Sleep(delay + jitter)
+	switch classifyInsertError(err) {
+	case insertErrorRejected:
+		// Without a dead letter queue the rows can't be isolated; the
+		// database answered, so this says nothing about its health
+		if bp.circuitBreaker != nil {
+			bp.circuitBreaker.RecordSuccess()
 		}
+		log.Printf("Database rejected the batch, writing %d records to WAL (set DEAD_LETTER_PATH to isolate bad records)", len(batch))
+		return retry.Permanent(errFlushDiverted)
+	case insertErrorClient:
+		// Retrying the same statement won't help, and it isn't the
+		// database failing; keep the breaker out of it
+		log.Printf("Flush failed with a non-retryable error, writing %d records to WAL", len(batch))
+		return retry.Permanent(errFlushDiverted)
 	}
 
-	// All retries failed, write to WAL
-	log.Printf("All %d retry attempts failed, writing %d records to WAL", bp.maxRetries, len(batch))
-	return bp.flushToWAL(batch)
+	// Record failure with circuit breaker
+	if bp.circuitBreaker != nil {
+		bp.circuitBreaker.RecordFailure()
+	}
+	bp.batchSizer.observe(len(batch), duration, err)
+	return err
 }
 
 // flushToWAL writes buffered records to the Write Ahead Log
@@ -198,96 +843,69 @@ func (bp *BatchProcessor) flushToWAL(batch []models.TelemetryPoint) error {
 	}
 
 	for _, point := range batch {
-		walRecord := WALRecord{
-			Timestamp:            point.Timestamp,
-			SatelliteID:          point.SatelliteID,
-			BatteryChargePercent: point.BatteryChargePercent,
-			StorageUsageMB:       point.StorageUsageMB,
-			SignalStrengthDBM:    point.SignalStrengthDBM,
-			IsAnomaly:            point.IsAnomaly,
-			// Position tracking fields
-			Latitude:             point.Latitude,
-			Longitude:            point.Longitude,
-			AltitudeKM:           point.AltitudeKM,
-			VelocityKMPH:         point.VelocityKMPH,
-		}
+		walRecord := NewWALRecord(point)
 		if err := bp.wal.Write(walRecord); err != nil {
 			return fmt.Errorf("failed to write to WAL: %w", err)
 		}
 	}
 
 	log.Printf("Wrote %d records to WAL", len(batch))
+	bp.counters.walDiverted.Add(int64(len(batch)))
 	return nil
 }
 
-// randFloat64 returns a random float64 between 0 and 1
-// Simple implementation without importing math/rand
-func randFloat64() float64 {
-	return float64(time.Now().UnixNano()%1000) / 1000.0
-}
-
 func (bp *BatchProcessor) insertBatch(ctx context.Context, batch []models.TelemetryPoint) (int64, error) {
-	// Use pgx's batch insert for maximum performance
+	// One transaction per batch; large batches are written with COPY
 	tx, err := bp.pool.Begin(ctx)
 	if err != nil {
 		return 0, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	stmt := `
-		INSERT INTO telemetry (
-			time, satellite_id, battery_charge_percent,
-			storage_usage_mb, signal_strength_dbm, is_anomaly,
-			latitude, longitude, altitude_km, velocity_kmph
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`
-
-	for _, point := range batch {
-		_, err := tx.Exec(ctx, stmt,
-			point.Timestamp,
-			point.SatelliteID,
-			point.BatteryChargePercent,
-			point.StorageUsageMB,
-			point.SignalStrengthDBM,
-			point.IsAnomaly,
-			point.Latitude,
-			point.Longitude,
-			point.AltitudeKM,
-			point.VelocityKMPH,
-		)
-		if err != nil {
-			return 0, err
-		}
+	// Column list and arguments come from the TelemetryPoint db tags
+	inserted, err := insertTelemetry(ctx, tx, batch)
+	if err != nil {
+		return 0, err
+	}
+	if err := bp.lateData.insertCorrections(ctx, tx, batch); err != nil {
+		return 0, err
+	}
+	if err := bp.gaps.recordGaps(ctx, tx, batch); err != nil {
+		return 0, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return 0, err
 	}
 
-	return int64(len(batch)), nil
+	return inserted, nil
 }
 
+// detectAnomaly reports whether any detector in the chain flags the point
 func (bp *BatchProcessor) detectAnomaly(point models.TelemetryPoint) bool {
-	// Simple threshold-based anomaly detection
-	if point.BatteryChargePercent < bp.anomalyConfig.BatteryMinPercent {
-		log.Printf("ANOMALY: Satellite %s battery critically low: %.2f%%",
-			point.SatelliteID, point.BatteryChargePercent)
-		return true
-	}
+	return len(bp.runDetectors(point)) > 0
+}
 
-	if point.StorageUsageMB > bp.anomalyConfig.StorageMaxMB {
-		log.Printf("ANOMALY: Satellite %s storage critically high: %.2f MB",
-			point.SatelliteID, point.StorageUsageMB)
-		return true
+// forgetSatellite drops the detector, episode and zone state of a satellite
+// Caller must hold bufferMutex
+func (bp *BatchProcessor) forgetSatellite(satelliteID string) {
+	for _, detector := range bp.detectors {
+		if holder, ok := detector.(satelliteStateHolder); ok {
+			holder.forgetSatellite(satelliteID)
+		}
 	}
+	bp.episodes.forgetSatellite(satelliteID)
+	bp.geofences.forgetSatellite(satelliteID)
+}
 
-	if point.SignalStrengthDBM < bp.anomalyConfig.SignalMinDBM {
-		log.Printf("ANOMALY: Satellite %s signal critically weak: %.2f dBm",
-			point.SatelliteID, point.SignalStrengthDBM)
-		return true
+// runDetectors runs the configured chain, defaulting to the fixed thresholds
+// Caller must hold bufferMutex when detectors may be reconfigured concurrently
+func (bp *BatchProcessor) runDetectors(point models.TelemetryPoint) []models.AnomalyResult {
+	if bp.detectors == nil {
+		// Kept on the processor so hysteresis state survives between points
+		bp.detectors = []AnomalyDetector{NewThresholdDetector(bp.anomalyConfig)}
 	}
-
-	return false
+	return runAnomalyDetectors(bp.detectors, point)
 }
 
 // GetWAL returns the Write Ahead Log instance
@@ -308,7 +926,7 @@ func (bp *BatchProcessor) GetCircuitBreaker() *CircuitBreaker {
 func (bp *BatchProcessor) GetBufferSize() int {
 	bp.bufferMutex.Lock()
 	defer bp.bufferMutex.Unlock()
-	return len(bp.buffer)
+	return bp.bufferedLocked()
 }
 
 // GetPool returns the database connection pool
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"sync"
+
+	"orbitstream/models"
+)
+
+// maxPooledBatch is the largest slice capacity kept for reuse; the rare
+// larger ones (whole-buffer flushes at shutdown) are left to the GC
+const maxPooledBatch = 1 << 16
+
+// Batch slices swapped out of the buffer and WAL record slices converted
+// for the WAL, overflow queue and replay are recycled instead of allocated
+// per flush. Pools hold pointers so Put doesn't allocate.
+var (
+	pointBatchPool sync.Pool // *[]models.TelemetryPoint
+	walRecordPool  sync.Pool // *[]WALRecord
+)
+
+// getPointBatch returns an empty slice with room for at least n points
+func getPointBatch(n int) []models.TelemetryPoint {
+	if p, ok := pointBatchPool.Get().(*[]models.TelemetryPoint); ok && cap(*p) >= n {
+		return (*p)[:0]
+	}
+	return make([]models.TelemetryPoint, 0, n)
+}
+
+// putPointBatch recycles a batch; the caller must not use it afterwards.
+// Points are cleared so the pool doesn't keep their strings alive.
+func putPointBatch(batch []models.TelemetryPoint) {
+	if cap(batch) == 0 || cap(batch) > maxPooledBatch {
+		return
+	}
+	batch = batch[:cap(batch)]
+	clear(batch)
+	batch = batch[:0]
+	pointBatchPool.Put(&batch)
+}
+
+// swapOut takes the first n points out of *buffer as a batch. Taking them
+// all swaps in a pooled slice, so the points aren't copied; otherwise they
+// are copied to a pooled batch and the rest moves to the front in place.
+// Caller must hold bufferMutex
+func (bp *BatchProcessor) swapOut(buffer *[]models.TelemetryPoint, n int) []models.TelemetryPoint {
+	points := *buffer
+	if n == len(points) {
+		*buffer = getPointBatch(bp.batchSize)
+		return points
+	}
+	batch := append(getPointBatch(n), points[:n]...)
+	rest := copy(points, points[n:])
+	clear(points[rest:])
+	*buffer = points[:rest]
+	return batch
+}
+
+// getWALRecords returns an empty slice with room for at least n records
+func getWALRecords(n int) []WALRecord {
+	if p, ok := walRecordPool.Get().(*[]WALRecord); ok && cap(*p) >= n {
+		return (*p)[:0]
+	}
+	return make([]WALRecord, 0, n)
+}
+
+// putWALRecords recycles a record slice; the caller must not use it
+// afterwards
+func putWALRecords(records []WALRecord) {
+	if cap(records) == 0 || cap(records) > maxPooledBatch {
+		return
+	}
+	records = records[:cap(records)]
+	clear(records)
+	records = records[:0]
+	walRecordPool.Put(&records)
+}
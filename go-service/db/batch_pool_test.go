@@ -0,0 +1,60 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// TestTakeBatchPartialDoesNotAliasBuffer tests that a batch taken from the
+// front of the buffer is a copy and the rest stays buffered in order
+func TestTakeBatchPartialDoesNotAliasBuffer(t *testing.T) {
+	bp := NewBatchProcessor(nil, 1000, time.Second, AnomalyConfig{})
+	for i := 0; i < 5; i++ {
+		point := TelemetryPointForTest(85.0, 45000.0, -55.0)
+		point.SatelliteID = fmt.Sprintf("SAT-%03d", i)
+		if err := bp.Add(point); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	job, ok := bp.takeBatch(2)
+	if !ok || len(job.batch) != 2 || job.batch[0].SatelliteID != "SAT-000" || job.batch[1].SatelliteID != "SAT-001" {
+		t.Fatalf("unexpected batch %+v", job.batch)
+	}
+	job.batch[0].SatelliteID = "CHANGED"
+
+	rest, ok := bp.takeBatch(0)
+	if !ok || len(rest.batch) != 3 {
+		t.Fatalf("expected the 3 remaining points, got %+v", rest.batch)
+	}
+	for i, point := range rest.batch {
+		if want := fmt.Sprintf("SAT-%03d", i+2); point.SatelliteID != want {
+			t.Errorf("expected %s at %d, got %s", want, i, point.SatelliteID)
+		}
+	}
+	if size := bp.GetBufferSize(); size != 0 {
+		t.Errorf("expected an empty buffer, got %d", size)
+	}
+}
+
+// TestPutPointBatchClearsPoints tests that recycled batches don't keep their
+// points alive
+func TestPutPointBatchClearsPoints(t *testing.T) {
+	batch := make([]models.TelemetryPoint, 2, 4)
+	batch[0].SatelliteID = "SAT-001"
+	batch[1].SatelliteID = "SAT-002"
+	backing := batch[:4]
+
+	putPointBatch(batch)
+	for i, point := range backing {
+		if point.SatelliteID != "" {
+			t.Errorf("expected point %d to be cleared, got %s", i, point.SatelliteID)
+		}
+	}
+	if got := getPointBatch(100); len(got) != 0 || cap(got) < 100 {
+		t.Errorf("expected an empty batch with room for 100, got len %d cap %d", len(got), cap(got))
+	}
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// batchLatencySmoothing weighs the newest flush in the per-point latency
+	// moving average
+	batchLatencySmoothing = 0.3
+	// batchErrorSmoothing weighs the newest flush in the error rate
+	batchErrorSmoothing = 0.2
+	// batchGrowthErrorRate is the error rate below which batches may grow
+	batchGrowthErrorRate = 0.05
+	// batchMaxGrowth caps how much one flush may grow the batch size
+	batchMaxGrowth = 1.25
+)
+
+// BatchSizeConfig bounds the adaptive batch size
+type BatchSizeConfig struct {
+	Min int
+	Max int
+	// TargetLatency is the insert latency batches are sized for
+	TargetLatency time.Duration
+}
+
+// BatchSizeStatus reports the adaptive batch size and the signals behind it
+type BatchSizeStatus struct {
+	Size          int    `json:"size"`
+	Min           int    `json:"min"`
+	Max           int    `json:"max"`
+	TargetLatency string `json:"target_latency"`
+	// LatencyPerPoint is the recent insert latency per point, microseconds
+	LatencyPerPoint float64 `json:"latency_per_point_us"`
+	ErrorRate       float64 `json:"error_rate"`
+	Flushes         int64   `json:"flushes"`
+}
+
+// BatchSizeController sizes flush batches from recent insert latency and
+// error rate, since the best size differs widely between deployments. After
+// each successful insert it moves the size halfway toward the size the
+// recent latency per point predicts to take TargetLatency, growing at most
+// 25% per flush and only while the error rate is low. A failed insert halves
+// it (multiplicative decrease), so a struggling database gets smaller
+// transactions quickly and larger ones back gradually.
+type BatchSizeController struct {
+	mu       sync.Mutex
+	cfg      BatchSizeConfig
+	size     int
+	perPoint float64 // seconds, moving average (0 = no sample yet)
+	errRate  float64
+	flushes  int64
+}
+
+// NewBatchSizeController creates a controller starting at initial, clamped
+// to the bounds
+func NewBatchSizeController(cfg BatchSizeConfig, initial int) *BatchSizeController {
+	if cfg.Min <= 0 {
+		cfg.Min = 1
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	if cfg.TargetLatency <= 0 {
+		cfg.TargetLatency = 250 * time.Millisecond
+	}
+	return &BatchSizeController{cfg: cfg, size: min(max(initial, cfg.Min), cfg.Max)}
+}
+
+// sizeOr returns the current batch size, or fixed without a controller
+func (c *BatchSizeController) sizeOr(fixed int) int {
+	if c == nil {
+		return fixed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// observe folds one insert of points into the controller; err is a
+// transient insert failure (rejected records say nothing about the size)
+func (c *BatchSizeController) observe(points int, latency time.Duration, err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushes++
+
+	if err != nil {
+		c.errRate += batchErrorSmoothing * (1 - c.errRate)
+		previous := c.size
+		c.size = max(c.size/2, c.cfg.Min)
+		if c.size != previous {
+			log.Printf("Batch size %d -> %d after a failed insert", previous, c.size)
+		}
+		return
+	}
+	c.errRate -= batchErrorSmoothing * c.errRate
+	if points <= 0 || latency <= 0 {
+		return
+	}
+
+	perPoint := latency.Seconds() / float64(points)
+	if c.perPoint == 0 {
+		c.perPoint = perPoint
+	} else {
+		c.perPoint += batchLatencySmoothing * (perPoint - c.perPoint)
+	}
+
+	ideal := c.cfg.TargetLatency.Seconds() / c.perPoint
+	next := float64(c.size) + (ideal-float64(c.size))/2
+	if next > float64(c.size) {
+		if c.errRate >= batchGrowthErrorRate {
+			return
+		}
+		next = min(next, float64(c.size)*batchMaxGrowth+1)
+	}
+	c.size = min(max(int(next), c.cfg.Min), c.cfg.Max)
+}
+
+// Status returns the current size and signals
+func (c *BatchSizeController) Status() BatchSizeStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return BatchSizeStatus{
+		Size:            c.size,
+		Min:             c.cfg.Min,
+		Max:             c.cfg.Max,
+		TargetLatency:   c.cfg.TargetLatency.String(),
+		LatencyPerPoint: c.perPoint * 1e6,
+		ErrorRate:       c.errRate,
+		Flushes:         c.flushes,
+	}
+}
+
+// SetBatchSizeController sizes batches adaptively instead of the fixed
+// batch size; must be called before Start
+func (bp *BatchProcessor) SetBatchSizeController(controller *BatchSizeController) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.batchSizer = controller
+}
+
+// GetBatchSizeController returns the adaptive batch size controller (nil
+// with a fixed batch size)
+func (bp *BatchProcessor) GetBatchSizeController() *BatchSizeController {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return bp.batchSizer
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestBatchSizeControllerGrowsWhenFast tests that fast inserts grow the
+// batch size gradually up to the maximum
+func TestBatchSizeControllerGrowsWhenFast(t *testing.T) {
+	c := NewBatchSizeController(BatchSizeConfig{Min: 100, Max: 5000, TargetLatency: 200 * time.Millisecond}, 1000)
+
+	// 1000 points in 20ms: the target latency fits 10000
+	c.observe(1000, 20*time.Millisecond, nil)
+	if size := c.sizeOr(0); size != 1251 {
+		t.Errorf("expected growth capped at 25%%, got %d", size)
+	}
+	for i := 0; i < 20; i++ {
+		c.observe(c.sizeOr(0), time.Duration(c.sizeOr(0))*20*time.Microsecond, nil)
+	}
+	if size := c.sizeOr(0); size != 5000 {
+		t.Errorf("expected the maximum of 5000, got %d", size)
+	}
+}
+
+// TestBatchSizeControllerShrinksWhenSlow tests that slow inserts shrink the
+// batch size toward the target latency, not below the minimum
+func TestBatchSizeControllerShrinksWhenSlow(t *testing.T) {
+	c := NewBatchSizeController(BatchSizeConfig{Min: 100, Max: 5000, TargetLatency: 200 * time.Millisecond}, 1000)
+
+	// 1000 points in 800ms: the target latency fits 250
+	c.observe(1000, 800*time.Millisecond, nil)
+	if size := c.sizeOr(0); size != 625 {
+		t.Errorf("expected the size halfway to 250, got %d", size)
+	}
+	for i := 0; i < 20; i++ {
+		c.observe(c.sizeOr(0), time.Duration(c.sizeOr(0))*10*time.Millisecond, nil)
+	}
+	if size := c.sizeOr(0); size != 100 {
+		t.Errorf("expected the minimum of 100, got %d", size)
+	}
+}
+
+// TestBatchSizeControllerHalvesOnError tests that a failed insert halves the
+// batch size and holds growth back until the error rate recovers
+func TestBatchSizeControllerHalvesOnError(t *testing.T) {
+	c := NewBatchSizeController(BatchSizeConfig{Min: 100, Max: 5000, TargetLatency: 200 * time.Millisecond}, 1000)
+
+	c.observe(1000, 10*time.Second, errors.New("timeout"))
+	if size := c.sizeOr(0); size != 500 {
+		t.Errorf("expected the size halved to 500, got %d", size)
+	}
+	c.observe(500, time.Millisecond, nil)
+	if size := c.sizeOr(0); size != 500 {
+		t.Errorf("expected no growth right after an error, got %d", size)
+	}
+	for i := 0; i < 10; i++ {
+		c.observe(c.sizeOr(0), time.Millisecond, nil)
+	}
+	if size := c.sizeOr(0); size <= 500 {
+		t.Errorf("expected growth once the error rate recovered, got %d", size)
+	}
+	if status := c.Status(); status.Flushes != 12 || status.ErrorRate >= batchGrowthErrorRate {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+// TestAddWakesAtAdaptiveBatchSize tests that the flush loop is woken at the
+// controller's batch size rather than the fixed one
+func TestAddWakesAtAdaptiveBatchSize(t *testing.T) {
+	bp := NewBatchProcessor(nil, 1000, time.Second, AnomalyConfig{})
+	bp.SetBatchSizeController(NewBatchSizeController(BatchSizeConfig{Min: 2, Max: 10}, 2))
+
+	for i := 0; i < 2; i++ {
+		if err := bp.Add(TelemetryPointForTest(85.0, 45000.0, -55.0)); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+	}
+	select {
+	case <-bp.flushWake:
+	default:
+		t.Error("expected the flush loop to be woken at 2 points")
+	}
+}
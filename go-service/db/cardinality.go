@@ -0,0 +1,176 @@
+package db
+
+import (
+	"log"
+	"sort"
+	"sync"
+)
+
+// CardinalityOther is the label satellites outside the tracked top-K are
+// reported under
+const CardinalityOther = "other"
+
+// SatellitePoints is a tracked satellite and its estimated point count
+type SatellitePoints struct {
+	SatelliteID string `json:"satellite_id"`
+	Points      int64  `json:"points"`
+}
+
+// CardinalityStats reports how many satellites have per-satellite state
+type CardinalityStats struct {
+	Limit   int   `json:"limit"`
+	WarnAt  int   `json:"warn_at"`
+	Tracked int   `json:"tracked"`
+	Evicted int64 `json:"evicted"`
+	// OtherPoints counts points of satellites that were evicted or displaced
+	OtherPoints int64             `json:"other_points"`
+	Top         []SatellitePoints `json:"top"`
+}
+
+// CardinalityGuard caps the satellites the pipeline keeps state for (detector
+// history, open episodes) to the limit most active ones, so a test harness
+// sending random satellite IDs can't grow memory without bound. It counts
+// points with the space-saving algorithm: a new satellite arriving when the
+// guard is full replaces the least active one, whose state is dropped and
+// whose points move to the "other" bucket. Established satellites outrank a
+// stream of one-off IDs, which only churn the bottom slot.
+type CardinalityGuard struct {
+	mu          sync.Mutex
+	limit       int
+	warnAt      int
+	counts      map[string]int64
+	evicted     int64
+	otherPoints int64
+	warned      bool
+	overflowed  bool
+}
+
+// NewCardinalityGuard creates a guard tracking at most limit satellites and
+// warning once when more than warnAt are tracked (0 disables the warning)
+func NewCardinalityGuard(limit, warnAt int) *CardinalityGuard {
+	if limit <= 0 {
+		limit = 10000
+	}
+	return &CardinalityGuard{
+		limit:  limit,
+		warnAt: warnAt,
+		counts: make(map[string]int64),
+	}
+}
+
+// observe counts a point of satelliteID and returns the satellite it
+// displaced, if any, whose state must be dropped. A nil guard tracks all.
+func (g *CardinalityGuard) observe(satelliteID string) (evicted string, ok bool) {
+	if g == nil {
+		return "", false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, tracked := g.counts[satelliteID]; tracked {
+		g.counts[satelliteID]++
+		return "", false
+	}
+	if len(g.counts) < g.limit {
+		g.counts[satelliteID] = 1
+		if g.warnAt > 0 && len(g.counts) > g.warnAt && !g.warned {
+			g.warned = true
+			log.Printf("WARNING: fleet size exceeds %d satellites (tracking up to %d)", g.warnAt, g.limit)
+		}
+		return "", false
+	}
+
+	// Full: replace the least active satellite, inheriting its count
+	minID, minCount := "", int64(-1)
+	for id, count := range g.counts {
+		if minCount < 0 || count < minCount || count == minCount && id < minID {
+			minID, minCount = id, count
+		}
+	}
+	delete(g.counts, minID)
+	g.counts[satelliteID] = minCount + 1
+	g.evicted++
+	g.otherPoints += minCount
+	if !g.overflowed {
+		g.overflowed = true
+		log.Printf("WARNING: more than %d distinct satellites, dropping state of the least active ones", g.limit)
+	}
+	return minID, true
+}
+
+// Label returns satelliteID if it is tracked, CardinalityOther otherwise
+func (g *CardinalityGuard) Label(satelliteID string) string {
+	if g == nil {
+		return satelliteID
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.counts[satelliteID]; ok {
+		return satelliteID
+	}
+	return CardinalityOther
+}
+
+// Stats reports the tracked satellites and the top most active (all when
+// top <= 0)
+func (g *CardinalityGuard) Stats(top int) CardinalityStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stats := CardinalityStats{
+		Limit:       g.limit,
+		WarnAt:      g.warnAt,
+		Tracked:     len(g.counts),
+		Evicted:     g.evicted,
+		OtherPoints: g.otherPoints,
+		Top:         make([]SatellitePoints, 0, len(g.counts)),
+	}
+	for id, count := range g.counts {
+		stats.Top = append(stats.Top, SatellitePoints{SatelliteID: id, Points: count})
+	}
+	sort.Slice(stats.Top, func(i, j int) bool {
+		if stats.Top[i].Points != stats.Top[j].Points {
+			return stats.Top[i].Points > stats.Top[j].Points
+		}
+		return stats.Top[i].SatelliteID < stats.Top[j].SatelliteID
+	})
+	if top > 0 && len(stats.Top) > top {
+		stats.Top = stats.Top[:top]
+	}
+	return stats
+}
+
+// satelliteStateHolder is implemented by detectors keeping per-satellite state
+type satelliteStateHolder interface {
+	forgetSatellite(satelliteID string)
+}
+
+func (d *ThresholdDetector) forgetSatellite(satelliteID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.states, satelliteID)
+}
+
+func (d *CompositeDetector) forgetSatellite(satelliteID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.states, satelliteID)
+}
+
+func (d *StatisticalDetector) forgetSatellite(satelliteID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.states, satelliteID)
+}
+
+// forgetSatellite drops the open episodes of a satellite without recovery
+// events (closed history is bounded separately)
+func (t *EpisodeTracker) forgetSatellite(satelliteID string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.open, satelliteID)
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestCardinalityGuardEvictsLeastActive tests that one-off satellites displace each other, not established ones
+func TestCardinalityGuardEvictsLeastActive(t *testing.T) {
+	guard := NewCardinalityGuard(3, 0)
+	for i := 0; i < 10; i++ {
+		guard.observe("SAT-A")
+		guard.observe("SAT-B")
+	}
+
+	var evicted []string
+	for i := 0; i < 5; i++ {
+		if id, ok := guard.observe(fmt.Sprintf("RANDOM-%d", i)); ok {
+			evicted = append(evicted, id)
+		}
+	}
+
+	if len(evicted) != 4 {
+		t.Fatalf("expected 4 evictions, got %v", evicted)
+	}
+	for _, id := range evicted {
+		if id == "SAT-A" || id == "SAT-B" {
+			t.Errorf("expected established satellites to stay tracked, evicted %s", id)
+		}
+	}
+	if got := guard.Label("SAT-A"); got != "SAT-A" {
+		t.Errorf("expected SAT-A label, got %s", got)
+	}
+	if got := guard.Label("RANDOM-0"); got != CardinalityOther {
+		t.Errorf("expected evicted satellite labeled %q, got %s", CardinalityOther, got)
+	}
+
+	stats := guard.Stats(2)
+	if stats.Tracked != 3 || stats.Evicted != 4 || stats.OtherPoints == 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if len(stats.Top) != 2 || stats.Top[0].SatelliteID != "SAT-A" || stats.Top[0].Points != 10 {
+		t.Errorf("expected SAT-A and SAT-B on top, got %+v", stats.Top)
+	}
+}
+
+// TestCardinalityGuardDropsDetectorState tests that displaced satellites lose their hysteresis state
+func TestCardinalityGuardDropsDetectorState(t *testing.T) {
+	detector := NewThresholdDetector(AnomalyConfig{
+		BatteryMinPercent: 10.0,
+		StorageMaxMB:      95000.0,
+		SignalMinDBM:      -100.0,
+		ConsecutivePoints: 3,
+	})
+	bp := NewBatchProcessor(nil, 1000, time.Second, AnomalyConfig{})
+	bp.SetAnomalyDetectors(detector)
+	bp.SetCardinalityGuard(NewCardinalityGuard(1, 0))
+
+	point := TelemetryPointForTest(5.0, 45000.0, -55.0)
+	point.SatelliteID = "SAT-001"
+	if err := bp.Add(point); err != nil {
+		t.Fatalf("failed to add point: %v", err)
+	}
+	if len(detector.states) != 1 {
+		t.Fatalf("expected state for SAT-001, got %d states", len(detector.states))
+	}
+
+	point.SatelliteID = "SAT-002"
+	if err := bp.Add(point); err != nil {
+		t.Fatalf("failed to add point: %v", err)
+	}
+	if _, ok := detector.states["SAT-001"]; ok {
+		t.Error("expected SAT-001 state to be dropped after eviction")
+	}
+	if len(detector.states) != 1 {
+		t.Errorf("expected state for one satellite, got %d", len(detector.states))
+	}
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"log"
+	"math"
 	"sync"
 	"time"
 )
@@ -34,12 +35,17 @@ func (s CircuitBreakerState) String() string {
 
 // CircuitBreaker implements the circuit breaker pattern
 // It prevents cascading failures by blocking requests to a failing service
-// after a threshold of consecutive failures is reached.
+// after a threshold of consecutive failures is reached, or, with a sliding
+// window policy, once the failure rate of the last N calls exceeds a limit.
 //
 // States:
 // - CLOSED: Requests pass through normally. Failures increment the counter.
 // - OPEN: Requests are blocked. After timeout, transitions to HALF_OPEN.
 // - HALF_OPEN: One request is allowed. Success closes circuit, failure reopens it.
+//
+// SetHalfOpenPolicy allows several probes in HALF_OPEN and requires several
+// successes before closing; SetRampUp then admits a growing share of
+// requests for a while after closing instead of the full load at once.
 type CircuitBreaker struct {
 	mu                sync.Mutex
 	state             CircuitBreakerState
@@ -48,8 +54,40 @@ type CircuitBreaker struct {
 	lastFailureTime   time.Time
 	timeout           time.Duration
 	halfOpenAttempts  int
+
+	// Sliding window policy (windowSize 0 = consecutive failure counting)
+	window         []bool // ring buffer of recent outcomes, true = failure
+	windowSize     int
+	windowNext     int
+	windowCalls    int
+	windowFailures int
+	failureRate    float64
+	minCalls       int
+
+	// Half-open probing (0 = one probe, one success closes)
+	halfOpenProbes    int
+	halfOpenSuccesses int
+	halfOpenSucceeded int
+	// Ramp-up after closing from HALF_OPEN (rampUp 0 = full load at once)
+	rampUp       time.Duration
+	rampStart    time.Time
+	rampCalls    int
+	rampAdmitted int
+
+	// State change hooks, and transitions made under mu not yet passed to them
+	hooks          []func(from, to CircuitBreakerState)
+	pending        []stateChange
+	stateChangedAt time.Time
 }
 
+// stateChange is one transition of the circuit breaker
+type stateChange struct {
+	from, to CircuitBreakerState
+}
+
+// rampUpFloor is the share of requests admitted when a ramp-up starts
+const rampUpFloor = 0.1
+
 // NewCircuitBreaker creates a new circuit breaker with the given threshold and timeout
 // threshold: number of consecutive failures before opening the circuit
 // timeout: how long to wait before transitioning from OPEN to HALF_OPEN
@@ -61,36 +99,183 @@ func NewCircuitBreaker(threshold int, timeout time.Duration) *CircuitBreaker {
 	}
 }
 
+// NewSlidingWindowCircuitBreaker creates a circuit breaker that opens when
+// more than failureRate (0-1) of the last windowSize calls failed, so isolated
+// timeouts under load don't trip it
+// minCalls: calls recorded before the rate is evaluated (capped at windowSize)
+// timeout: how long to wait before transitioning from OPEN to HALF_OPEN
+func NewSlidingWindowCircuitBreaker(windowSize int, failureRate float64, minCalls int, timeout time.Duration) *CircuitBreaker {
+	if windowSize <= 0 {
+		windowSize = 100
+	}
+	if minCalls <= 0 || minCalls > windowSize {
+		minCalls = windowSize
+	}
+	return &CircuitBreaker{
+		state:       Closed,
+		timeout:     timeout,
+		window:      make([]bool, windowSize),
+		windowSize:  windowSize,
+		failureRate: failureRate,
+		minCalls:    minCalls,
+	}
+}
+
+// SetHalfOpenPolicy lets probes requests through at a time in HALF_OPEN and
+// requires successes successful probes before the circuit closes
+func (cb *CircuitBreaker) SetHalfOpenPolicy(probes, successes int) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.halfOpenProbes = probes
+	cb.halfOpenSuccesses = successes
+}
+
+// SetRampUp makes a circuit closing from HALF_OPEN admit a share of requests
+// growing linearly from 10% to all of them over duration (0 disables); a
+// failure during the ramp-up reopens the circuit
+func (cb *CircuitBreaker) SetRampUp(duration time.Duration) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.rampUp = duration
+}
+
+// OnStateChange registers a hook called on every state transition, in
+// order, after the breaker's lock is released (hooks may query the breaker).
+// Hooks run on the goroutine that caused the transition and must not block.
+func (cb *CircuitBreaker) OnStateChange(hook func(from, to CircuitBreakerState)) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.hooks = append(cb.hooks, hook)
+}
+
+// setState transitions the breaker, queueing the change for the hooks
+// Caller must hold cb.mu
+func (cb *CircuitBreaker) setState(to CircuitBreakerState) {
+	if cb.state == to {
+		return
+	}
+	cb.stateChangedAt = time.Now()
+	if len(cb.hooks) > 0 {
+		cb.pending = append(cb.pending, stateChange{from: cb.state, to: to})
+	}
+	cb.state = to
+}
+
+// unlockAndNotify releases cb.mu and passes queued transitions to the hooks
+func (cb *CircuitBreaker) unlockAndNotify() {
+	changes := cb.pending
+	cb.pending = nil
+	hooks := cb.hooks
+	cb.mu.Unlock()
+
+	for _, change := range changes {
+		for _, hook := range hooks {
+			hook(change.from, change.to)
+		}
+	}
+}
+
+// rampShare returns the share of requests admitted while ramping up, 1 once
+// the ramp-up is over
+// Caller must hold cb.mu
+func (cb *CircuitBreaker) rampShare(now time.Time) float64 {
+	if cb.rampStart.IsZero() {
+		return 1
+	}
+	elapsed := now.Sub(cb.rampStart)
+	if elapsed >= cb.rampUp {
+		log.Printf("CircuitBreaker: ramp-up complete, admitting full load")
+		cb.rampStart = time.Time{}
+		return 1
+	}
+	return rampUpFloor + (1-rampUpFloor)*float64(elapsed)/float64(cb.rampUp)
+}
+
+// recordOutcome adds a call to the sliding window, evicting the oldest
+// Caller must hold cb.mu
+func (cb *CircuitBreaker) recordOutcome(failed bool) {
+	if cb.windowCalls == cb.windowSize {
+		if cb.window[cb.windowNext] {
+			cb.windowFailures--
+		}
+	} else {
+		cb.windowCalls++
+	}
+	cb.window[cb.windowNext] = failed
+	if failed {
+		cb.windowFailures++
+	}
+	cb.windowNext = (cb.windowNext + 1) % cb.windowSize
+}
+
+// resetWindow forgets every call in the sliding window
+// Caller must hold cb.mu
+func (cb *CircuitBreaker) resetWindow() {
+	for i := range cb.window {
+		cb.window[i] = false
+	}
+	cb.windowNext = 0
+	cb.windowCalls = 0
+	cb.windowFailures = 0
+}
+
+// shouldTrip reports whether a CLOSED circuit should open
+// Caller must hold cb.mu
+func (cb *CircuitBreaker) shouldTrip() bool {
+	if cb.windowSize == 0 {
+		return cb.failureCount >= cb.failureThreshold
+	}
+	return cb.windowCalls >= cb.minCalls &&
+		float64(cb.windowFailures) > cb.failureRate*float64(cb.windowCalls)
+}
+
 // Allow returns true if a request should be allowed through the circuit breaker
 // It handles state transitions and implements the circuit breaker logic:
 // - CLOSED: Always allow
 // - OPEN: Allow only if timeout has passed (transition to HALF_OPEN)
-// - HALF_OPEN: Allow one request to test recovery
+// - HALF_OPEN: Allow halfOpenProbes requests at a time to test recovery,
+//   the transition request being the first
 func (cb *CircuitBreaker) Allow() bool {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	defer cb.unlockAndNotify()
 
 	switch cb.state {
 	case Closed:
-		return true
+		share := cb.rampShare(time.Now())
+		if share >= 1 {
+			return true
+		}
+		// Admit requests evenly: keep the admitted count at share of all calls
+		cb.rampCalls++
+		if float64(cb.rampAdmitted) < share*float64(cb.rampCalls) {
+			cb.rampAdmitted++
+			return true
+		}
+		return false
 
 	case Open:
 		// Check if we should transition to HALF_OPEN
 		if time.Since(cb.lastFailureTime) >= cb.timeout {
 			log.Printf("CircuitBreaker: OPEN -> HALF_OPEN (timeout elapsed)")
-			cb.state = HalfOpen
-			cb.halfOpenAttempts = 0
+			cb.setState(HalfOpen)
+			// The transition request is the first probe
+			cb.halfOpenAttempts = 1
+			cb.halfOpenSucceeded = 0
 			return true
 		}
 		return false
 
 	case HalfOpen:
-		// Allow one request through to test if service has recovered
-		cb.halfOpenAttempts++
-		if cb.halfOpenAttempts > 1 {
-			// Only one request allowed in HALF_OPEN state
+		// Allow probe requests through to test if service has recovered
+		if cb.halfOpenAttempts >= max(cb.halfOpenProbes, 1) {
+			// Only halfOpenProbes requests allowed in HALF_OPEN state;
+			// rejected requests don't take a slot
 			return false
 		}
+		cb.halfOpenAttempts++
 		return true
 
 	default:
@@ -102,13 +287,35 @@ func (cb *CircuitBreaker) Allow() bool {
 // It closes the circuit if we're in HALF_OPEN state (service recovered)
 func (cb *CircuitBreaker) RecordSuccess() {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	defer cb.unlockAndNotify()
 
 	if cb.state == HalfOpen {
+		cb.halfOpenSucceeded++
+		if cb.halfOpenSucceeded < cb.halfOpenSuccesses {
+			// Free the probe slot for the next probe
+			log.Printf("CircuitBreaker: HALF_OPEN probe succeeded (%d/%d)",
+				cb.halfOpenSucceeded, cb.halfOpenSuccesses)
+			if cb.halfOpenAttempts > 0 {
+				cb.halfOpenAttempts--
+			}
+			return
+		}
 		log.Printf("CircuitBreaker: HALF_OPEN -> CLOSED (service recovered)")
-		cb.state = Closed
+		cb.setState(Closed)
 		cb.failureCount = 0
 		cb.halfOpenAttempts = 0
+		cb.halfOpenSucceeded = 0
+		if cb.windowSize > 0 {
+			cb.resetWindow()
+		}
+		if cb.rampUp > 0 {
+			log.Printf("CircuitBreaker: ramping up load over %v", cb.rampUp)
+			cb.rampStart = time.Now()
+			cb.rampCalls = 0
+			cb.rampAdmitted = 0
+		}
+	} else if cb.state == Closed && cb.windowSize > 0 {
+		cb.recordOutcome(false)
 	}
 }
 
@@ -116,24 +323,53 @@ func (cb *CircuitBreaker) RecordSuccess() {
 // It increments the failure counter and opens the circuit if threshold is reached
 func (cb *CircuitBreaker) RecordFailure() {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	defer cb.unlockAndNotify()
 
 	cb.failureCount++
 	cb.lastFailureTime = time.Now()
 
 	// Log the failure count
-	log.Printf("CircuitBreaker: Failure recorded (count: %d/%d, state: %s)",
-		cb.failureCount, cb.failureThreshold, cb.state)
+	if cb.windowSize > 0 {
+		if cb.state == Closed {
+			cb.recordOutcome(true)
+		}
+		log.Printf("CircuitBreaker: Failure recorded (window: %d/%d failed, state: %s)",
+			cb.windowFailures, cb.windowCalls, cb.state)
+	} else {
+		log.Printf("CircuitBreaker: Failure recorded (count: %d/%d, state: %s)",
+			cb.failureCount, cb.failureThreshold, cb.state)
+	}
+
+	// A failure while ramping up means the service has not recovered
+	if cb.state == Closed && !cb.rampStart.IsZero() && cb.rampShare(time.Now()) < 1 {
+		log.Printf("CircuitBreaker: CLOSED -> OPEN (failure during ramp-up)")
+		cb.setState(Open)
+		cb.rampStart = time.Time{}
+		return
+	}
 
 	// Open the circuit if we've reached the threshold
-	if cb.state == Closed && cb.failureCount >= cb.failureThreshold {
+	if cb.state == Closed && cb.shouldTrip() {
 		log.Printf("CircuitBreaker: CLOSED -> OPEN (threshold reached)")
-		cb.state = Open
+		cb.setState(Open)
 	} else if cb.state == HalfOpen {
 		// Service not recovered, go back to OPEN
 		log.Printf("CircuitBreaker: HALF_OPEN -> OPEN (service still failing)")
-		cb.state = Open
+		cb.setState(Open)
 		cb.halfOpenAttempts = 0
+		cb.halfOpenSucceeded = 0
+	}
+}
+
+// Release gives back the HALF_OPEN probe slot of an admitted request that
+// ended without telling anything about the service, e.g. one canceled by
+// its caller; no outcome is recorded
+func (cb *CircuitBreaker) Release() {
+	cb.mu.Lock()
+	defer cb.unlockAndNotify()
+
+	if cb.state == HalfOpen && cb.halfOpenAttempts > 0 {
+		cb.halfOpenAttempts--
 	}
 }
 
@@ -141,13 +377,18 @@ func (cb *CircuitBreaker) RecordFailure() {
 // This can be used for testing or manual intervention
 func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	defer cb.unlockAndNotify()
 
 	log.Printf("CircuitBreaker: Manually reset to CLOSED")
-	cb.state = Closed
+	cb.setState(Closed)
 	cb.failureCount = 0
 	cb.lastFailureTime = time.Time{}
 	cb.halfOpenAttempts = 0
+	cb.halfOpenSucceeded = 0
+	cb.rampStart = time.Time{}
+	if cb.windowSize > 0 {
+		cb.resetWindow()
+	}
 }
 
 // State returns the current state of the circuit breaker
@@ -158,6 +399,14 @@ func (cb *CircuitBreaker) State() CircuitBreakerState {
 	return cb.state
 }
 
+// StateChangedAt returns when the breaker last changed state (zero if never)
+func (cb *CircuitBreaker) StateChangedAt() time.Time {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.stateChangedAt
+}
+
 // FailureCount returns the current failure count
 func (cb *CircuitBreaker) FailureCount() int {
 	cb.mu.Lock()
@@ -166,6 +415,80 @@ func (cb *CircuitBreaker) FailureCount() int {
 	return cb.failureCount
 }
 
+// FailureRate returns the failure rate over the sliding window (0 for the
+// consecutive failure policy or before any call was recorded)
+func (cb *CircuitBreaker) FailureRate() float64 {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.windowCalls == 0 {
+		return 0
+	}
+	return float64(cb.windowFailures) / float64(cb.windowCalls)
+}
+
+// RampShare returns the share of requests currently admitted (below 1 while
+// ramping up after recovery)
+func (cb *CircuitBreaker) RampShare() float64 {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != Closed {
+		return 0
+	}
+	return cb.rampShare(time.Now())
+}
+
+// CircuitBreakerSnapshot reports a breaker's state for operators
+type CircuitBreakerSnapshot struct {
+	State        string     `json:"state"`
+	FailureCount int        `json:"failure_count"`
+	LastFailure  *time.Time `json:"last_failure,omitempty"`
+	StateSince   *time.Time `json:"state_since,omitempty"`
+	// HalfOpenIn is how long until an OPEN breaker lets a probe through
+	HalfOpenInSeconds *float64 `json:"half_open_in_seconds,omitempty"`
+	// FailureRate is only reported for the sliding window policy
+	FailureRate *float64 `json:"failure_rate,omitempty"`
+	RampShare   float64  `json:"ramp_share"`
+}
+
+// Snapshot returns the breaker's state, failures and, when OPEN, the time
+// until it turns HALF_OPEN
+func (cb *CircuitBreaker) Snapshot() CircuitBreakerSnapshot {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := time.Now()
+	snapshot := CircuitBreakerSnapshot{State: cb.state.String(), FailureCount: cb.failureCount}
+	if !cb.lastFailureTime.IsZero() {
+		last := cb.lastFailureTime.UTC()
+		snapshot.LastFailure = &last
+	}
+	if !cb.stateChangedAt.IsZero() {
+		since := cb.stateChangedAt.UTC()
+		snapshot.StateSince = &since
+	}
+	if cb.state == Open {
+		remaining := math.Max(0, (cb.timeout - now.Sub(cb.lastFailureTime)).Seconds())
+		snapshot.HalfOpenInSeconds = &remaining
+	}
+	if cb.windowSize > 0 {
+		rate := 0.0
+		if cb.windowCalls > 0 {
+			rate = float64(cb.windowFailures) / float64(cb.windowCalls)
+		}
+		snapshot.FailureRate = &rate
+	}
+	if cb.state == Closed {
+		// Peek without ending the ramp-up, which rampShare would log
+		snapshot.RampShare = 1
+		if !cb.rampStart.IsZero() && now.Sub(cb.rampStart) < cb.rampUp {
+			snapshot.RampShare = rampUpFloor + (1-rampUpFloor)*float64(now.Sub(cb.rampStart))/float64(cb.rampUp)
+		}
+	}
+	return snapshot
+}
+
 // IsOpen returns true if the circuit breaker is in OPEN state
 func (cb *CircuitBreaker) IsOpen() bool {
 	return cb.State() == Open
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"errors"
+	"sync"
+)
+
+// Circuit breaker operations
+const (
+	OperationInsert           = "insert"
+	OperationAggregateRefresh = "aggregate_refresh"
+	OperationQuery            = "query"
+)
+
+// ErrCircuitOpen is returned when an operation's circuit breaker rejects a call
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreakerRegistry holds one circuit breaker per operation, so a
+// failing aggregate refresh or slow queries don't block telemetry inserts.
+// Breakers are created on first use by the registry's factory.
+type CircuitBreakerRegistry struct {
+	mu       sync.Mutex
+	factory  func(operation string) *CircuitBreaker
+	breakers map[string]*CircuitBreaker
+}
+
+// NewCircuitBreakerRegistry creates a registry building breakers with factory
+func NewCircuitBreakerRegistry(factory func(operation string) *CircuitBreaker) *CircuitBreakerRegistry {
+	return &CircuitBreakerRegistry{
+		factory:  factory,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+// Get returns the breaker of an operation, creating it on first use
+// A nil registry returns nil (no circuit breaking)
+func (r *CircuitBreakerRegistry) Get(operation string) *CircuitBreaker {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cb, ok := r.breakers[operation]
+	if !ok {
+		cb = r.factory(operation)
+		r.breakers[operation] = cb
+	}
+	return cb
+}
+
+// Operations returns the operations with a breaker, in order
+func (r *CircuitBreakerRegistry) Operations() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return sortedKeys(r.breakers)
+}
+
+// States returns the state of every breaker by operation
+func (r *CircuitBreakerRegistry) States() map[string]CircuitBreakerState {
+	r.mu.Lock()
+	breakers := make(map[string]*CircuitBreaker, len(r.breakers))
+	for operation, cb := range r.breakers {
+		breakers[operation] = cb
+	}
+	r.mu.Unlock()
+
+	states := make(map[string]CircuitBreakerState, len(breakers))
+	for operation, cb := range breakers {
+		states[operation] = cb.State()
+	}
+	return states
+}
+
+// Snapshots returns the state of every breaker by operation, in detail
+func (r *CircuitBreakerRegistry) Snapshots() map[string]CircuitBreakerSnapshot {
+	snapshots := make(map[string]CircuitBreakerSnapshot)
+	for _, operation := range r.Operations() {
+		snapshots[operation] = r.Get(operation).Snapshot()
+	}
+	return snapshots
+}
+
+// Reset closes the breaker of operation, or of every operation when
+// operation is empty, and returns the operations reset; an unknown
+// operation resets nothing
+func (r *CircuitBreakerRegistry) Reset(operation string) []string {
+	operations := r.Operations()
+	if operation != "" {
+		operations = nil
+		r.mu.Lock()
+		if _, ok := r.breakers[operation]; ok {
+			operations = []string{operation}
+		}
+		r.mu.Unlock()
+	}
+	for _, op := range operations {
+		r.Get(op).Reset()
+	}
+	return operations
+}
+
+// guard runs fn if cb admits the call and records its outcome
+// A nil breaker always runs fn.
+func guard(cb *CircuitBreaker, fn func() error) error {
+	if cb == nil {
+		return fn()
+	}
+	if !cb.Allow() {
+		return ErrCircuitOpen
+	}
+	if err := fn(); err != nil {
+		cb.RecordFailure()
+		return err
+	}
+	cb.RecordSuccess()
+	return nil
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestCircuitBreakerRegistryIsolatesOperations tests that a tripped breaker only blocks its own operation
+func TestCircuitBreakerRegistryIsolatesOperations(t *testing.T) {
+	created := 0
+	registry := NewCircuitBreakerRegistry(func(operation string) *CircuitBreaker {
+		created++
+		return NewCircuitBreaker(2, time.Hour)
+	})
+
+	refresh := registry.Get(OperationAggregateRefresh)
+	if registry.Get(OperationAggregateRefresh) != refresh {
+		t.Fatal("expected the same breaker for repeated lookups")
+	}
+	refresh.RecordFailure()
+	refresh.RecordFailure()
+
+	if !refresh.IsOpen() {
+		t.Fatalf("expected aggregate refresh breaker to be OPEN, got %s", refresh.State())
+	}
+	if !registry.Get(OperationInsert).Allow() {
+		t.Error("expected insert breaker to allow calls while the refresh breaker is open")
+	}
+	if created != 2 {
+		t.Errorf("expected 2 breakers created, got %d", created)
+	}
+
+	states := registry.States()
+	if states[OperationAggregateRefresh] != Open || states[OperationInsert] != Closed {
+		t.Errorf("unexpected states: %v", states)
+	}
+	if ops := registry.Operations(); len(ops) != 2 || ops[0] != OperationAggregateRefresh || ops[1] != OperationInsert {
+		t.Errorf("expected sorted operations, got %v", ops)
+	}
+}
+
+// TestCircuitBreakerRegistryNil tests that a nil registry disables circuit breaking
+func TestCircuitBreakerRegistryNil(t *testing.T) {
+	var registry *CircuitBreakerRegistry
+	if cb := registry.Get(OperationQuery); cb != nil {
+		t.Errorf("expected nil breaker from nil registry, got %v", cb)
+	}
+}
+
+// TestCircuitBreakerRegistryReset tests that a reset closes the named breaker, or all of them
+func TestCircuitBreakerRegistryReset(t *testing.T) {
+	registry := NewCircuitBreakerRegistry(func(operation string) *CircuitBreaker {
+		return NewCircuitBreaker(1, time.Hour)
+	})
+	registry.Get(OperationInsert).RecordFailure()
+	registry.Get(OperationQuery).RecordFailure()
+
+	snapshot := registry.Snapshots()[OperationInsert]
+	if snapshot.State != "OPEN" || snapshot.FailureCount != 1 || snapshot.LastFailure == nil ||
+		snapshot.HalfOpenInSeconds == nil || *snapshot.HalfOpenInSeconds < 3500 {
+		t.Errorf("unexpected snapshot of an open breaker: %+v", snapshot)
+	}
+
+	if reset := registry.Reset("unknown"); len(reset) != 0 {
+		t.Errorf("expected an unknown operation to reset nothing, got %v", reset)
+	}
+	if reset := registry.Reset(OperationInsert); len(reset) != 1 || !registry.Get(OperationInsert).IsClosed() || !registry.Get(OperationQuery).IsOpen() {
+		t.Errorf("expected only the insert breaker to close, got %v %v", reset, registry.States())
+	}
+	if reset := registry.Reset(""); len(reset) != 2 || !registry.Get(OperationQuery).IsClosed() {
+		t.Errorf("expected every breaker to close, got %v %v", reset, registry.States())
+	}
+	if snapshot := registry.Get(OperationInsert).Snapshot(); snapshot.FailureCount != 0 || snapshot.HalfOpenInSeconds != nil || snapshot.RampShare != 1 {
+		t.Errorf("unexpected snapshot after reset: %+v", snapshot)
+	}
+}
+
+// TestGuard tests that guard records outcomes and fails fast once the circuit opens
+func TestGuard(t *testing.T) {
+	cb := NewCircuitBreaker(2, time.Hour)
+	failing := errors.New("refresh failed")
+
+	calls := 0
+	for i := 0; i < 2; i++ {
+		err := guard(cb, func() error {
+			calls++
+			return failing
+		})
+		if !errors.Is(err, failing) {
+			t.Fatalf("expected the call's error, got %v", err)
+		}
+	}
+
+	err := guard(cb, func() error {
+		calls++
+		return nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected the open circuit to skip the call, got %d calls", calls)
+	}
+
+	if err := guard(nil, func() error { return nil }); err != nil {
+		t.Errorf("expected nil breaker to run the call, got %v", err)
+	}
+}
@@ -149,18 +149,13 @@ func TestCircuitBreakerHalfOpenOnlyAllowsOneRequest(t *testing.T) {
 	cb.RecordFailure()
 	time.Sleep(timeout + 50*time.Millisecond)
 
-	// First Allow() should succeed and transition from OPEN to HALF_OPEN
+	// First Allow() should succeed and transition from OPEN to HALF_OPEN;
+	// it is the single probe
 	if !cb.Allow() {
 		t.Error("first Allow() should return true (transition from OPEN to HALF_OPEN)")
 	}
 
-	// The next call in HALF_OPEN state should also return true
-	// (first call in HALF_OPEN increments halfOpenAttempts to 1, which is not > 1)
-	if !cb.Allow() {
-		t.Error("second Allow() should return true in HALF_OPEN (first request)")
-	}
-
-	// Subsequent Allow() calls should be blocked (halfOpenAttempts now = 2, which is > 1)
+	// Subsequent Allow() calls should be blocked while the probe is in flight
 	for i := 0; i < 5; i++ {
 		if cb.Allow() {
 			t.Errorf("subsequent Allow() %d should return false in HALF_OPEN state", i+2)
@@ -416,3 +411,220 @@ func TestCircuitBreakerStateTransitionsSummary(t *testing.T) {
 		t.Error("state helpers incorrect for HALF_OPEN")
 	}
 }
+
+// TestSlidingWindowCircuitBreakerToleratesIsolatedFailures tests that failures
+// below the failure rate never open a sliding window circuit
+func TestSlidingWindowCircuitBreakerToleratesIsolatedFailures(t *testing.T) {
+	cb := NewSlidingWindowCircuitBreaker(10, 0.5, 4, 30*time.Second)
+
+	// Alternate success/failure: a consecutive breaker with threshold 3 would
+	// open after 3 failures, the 50% rate is never exceeded
+	for i := 0; i < 20; i++ {
+		cb.RecordSuccess()
+		cb.RecordFailure()
+	}
+	if !cb.IsClosed() {
+		t.Errorf("expected circuit to stay CLOSED at a 50%% failure rate, got %s", cb.State())
+	}
+	if rate := cb.FailureRate(); rate != 0.5 {
+		t.Errorf("expected failure rate 0.5, got %v", rate)
+	}
+}
+
+// TestSlidingWindowCircuitBreakerOpensOnFailureRate tests that the circuit
+// opens once the window holds enough calls and the rate is exceeded
+func TestSlidingWindowCircuitBreakerOpensOnFailureRate(t *testing.T) {
+	cb := NewSlidingWindowCircuitBreaker(10, 0.5, 4, 30*time.Second)
+
+	// Below minCalls the rate is not evaluated
+	for i := 0; i < 3; i++ {
+		cb.RecordFailure()
+	}
+	if !cb.IsClosed() {
+		t.Fatalf("expected circuit to stay CLOSED before minCalls, got %s", cb.State())
+	}
+
+	cb.RecordSuccess()
+	cb.RecordFailure() // 4 of 5 failed
+	if !cb.IsOpen() {
+		t.Errorf("expected circuit to open at an 80%% failure rate, got %s", cb.State())
+	}
+}
+
+// TestSlidingWindowCircuitBreakerEvictsOldCalls tests that only the last
+// windowSize calls count towards the failure rate
+func TestSlidingWindowCircuitBreakerEvictsOldCalls(t *testing.T) {
+	cb := NewSlidingWindowCircuitBreaker(4, 0.5, 4, 30*time.Second)
+
+	cb.RecordFailure()
+	cb.RecordFailure()
+	for i := 0; i < 4; i++ {
+		cb.RecordSuccess()
+	}
+	if rate := cb.FailureRate(); rate != 0 {
+		t.Errorf("expected old failures evicted, got rate %v", rate)
+	}
+
+	cb.RecordFailure()
+	cb.RecordFailure()
+	if !cb.IsClosed() {
+		t.Errorf("expected 2 of 4 failures to keep the circuit CLOSED, got %s", cb.State())
+	}
+	cb.RecordFailure()
+	if !cb.IsOpen() {
+		t.Errorf("expected 3 of 4 failures to open the circuit, got %s", cb.State())
+	}
+}
+
+// TestSlidingWindowCircuitBreakerRecovery tests that closing from HALF_OPEN
+// starts with an empty window
+func TestSlidingWindowCircuitBreakerRecovery(t *testing.T) {
+	cb := NewSlidingWindowCircuitBreaker(4, 0.5, 2, 10*time.Millisecond)
+
+	cb.RecordFailure()
+	cb.RecordFailure()
+	if !cb.IsOpen() {
+		t.Fatalf("expected circuit to open, got %s", cb.State())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !cb.Allow() || !cb.IsHalfOpen() {
+		t.Fatalf("expected HALF_OPEN after timeout, got %s", cb.State())
+	}
+	cb.RecordSuccess()
+	if !cb.IsClosed() {
+		t.Fatalf("expected circuit to close after a successful probe, got %s", cb.State())
+	}
+	if rate := cb.FailureRate(); rate != 0 {
+		t.Errorf("expected an empty window after recovery, got rate %v", rate)
+	}
+
+	cb.RecordFailure()
+	if !cb.IsClosed() {
+		t.Errorf("expected a single failure below minCalls to keep the circuit CLOSED, got %s", cb.State())
+	}
+}
+
+// TestCircuitBreakerHalfOpenPolicy tests that several probes are allowed and
+// several successes are required before closing
+func TestCircuitBreakerHalfOpenPolicy(t *testing.T) {
+	cb := NewCircuitBreaker(1, 10*time.Millisecond)
+	cb.SetHalfOpenPolicy(2, 3)
+
+	cb.RecordFailure()
+	time.Sleep(20 * time.Millisecond)
+
+	// Two probes at a time, the transition request being the first
+	for i := 0; i < 2; i++ {
+		if !cb.Allow() {
+			t.Fatalf("expected probe %d allowed", i+1)
+		}
+	}
+	if cb.Allow() {
+		t.Fatal("expected further requests blocked while probes are in flight")
+	}
+
+	cb.RecordSuccess()
+	cb.RecordSuccess()
+	if !cb.IsHalfOpen() {
+		t.Fatalf("expected HALF_OPEN after 2 of 3 successes, got %s", cb.State())
+	}
+	if !cb.Allow() {
+		t.Error("expected a successful probe to free a slot")
+	}
+	cb.RecordSuccess()
+	if !cb.IsClosed() {
+		t.Errorf("expected CLOSED after 3 successes, got %s", cb.State())
+	}
+}
+
+// TestCircuitBreakerReleaseFreesProbe tests that a probe ending without an
+// outcome gives its slot back without closing or reopening the circuit
+func TestCircuitBreakerReleaseFreesProbe(t *testing.T) {
+	cb := NewCircuitBreaker(1, 10*time.Millisecond)
+	cb.RecordFailure()
+	time.Sleep(20 * time.Millisecond)
+
+	if !cb.Allow() || cb.Allow() {
+		t.Fatal("expected exactly one probe admitted")
+	}
+	cb.Release()
+	if !cb.IsHalfOpen() {
+		t.Fatalf("expected HALF_OPEN after a release, got %s", cb.State())
+	}
+	if !cb.Allow() {
+		t.Error("expected the released slot to admit the next probe")
+	}
+}
+
+// TestCircuitBreakerRampUp tests that a recovered circuit admits a growing
+// share of requests and reopens on a failure during the ramp-up
+func TestCircuitBreakerRampUp(t *testing.T) {
+	cb := NewCircuitBreaker(1, 10*time.Millisecond)
+	cb.SetRampUp(time.Hour)
+
+	cb.RecordFailure()
+	time.Sleep(20 * time.Millisecond)
+	cb.Allow()
+	cb.RecordSuccess()
+	if !cb.IsClosed() {
+		t.Fatalf("expected CLOSED, got %s", cb.State())
+	}
+
+	admitted := 0
+	for i := 0; i < 100; i++ {
+		if cb.Allow() {
+			admitted++
+		}
+	}
+	if admitted < 10 || admitted > 11 {
+		t.Errorf("expected about 10%% of requests admitted at the start of the ramp-up, got %d", admitted)
+	}
+
+	// Pretend the ramp-up is half over
+	cb.mu.Lock()
+	cb.rampStart = time.Now().Add(-30 * time.Minute)
+	cb.mu.Unlock()
+	if share := cb.RampShare(); share < 0.54 || share > 0.56 {
+		t.Errorf("expected a 55%% share half way, got %v", share)
+	}
+
+	cb.RecordFailure()
+	if !cb.IsOpen() {
+		t.Errorf("expected a failure during ramp-up to reopen the circuit, got %s", cb.State())
+	}
+}
+
+// TestCircuitBreakerOnStateChange tests that hooks see every transition in
+// order and may query the breaker
+func TestCircuitBreakerOnStateChange(t *testing.T) {
+	cb := NewCircuitBreaker(1, 10*time.Millisecond)
+	var transitions []string
+	cb.OnStateChange(func(from, to CircuitBreakerState) {
+		// The lock is released before hooks run
+		if cb.State() != to {
+			t.Errorf("expected state %s inside the hook, got %s", to, cb.State())
+		}
+		transitions = append(transitions, from.String()+"->"+to.String())
+	})
+
+	cb.RecordFailure()
+	cb.RecordFailure() // already OPEN: no transition
+	time.Sleep(20 * time.Millisecond)
+	cb.Allow()
+	cb.RecordSuccess()
+	cb.Reset() // already CLOSED: no transition
+
+	want := []string{"CLOSED->OPEN", "OPEN->HALF_OPEN", "HALF_OPEN->CLOSED"}
+	if len(transitions) != len(want) {
+		t.Fatalf("expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transition %d: expected %s, got %s", i, want[i], transitions[i])
+		}
+	}
+	if cb.StateChangedAt().IsZero() {
+		t.Error("expected StateChangedAt to be set after transitions")
+	}
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"orbitstream/models"
+)
+
+// telemetryColumn maps one telemetry table column to a TelemetryPoint field
+type telemetryColumn struct {
+	name  string
+	index []int
+}
+
+// telemetryColumns is derived from the `db` tags on models.TelemetryPoint, so a
+// new model field with a db tag is written by every insert path automatically
+var telemetryColumns = buildTelemetryColumns()
+
+// telemetryInsertStmt is the INSERT statement for telemetryColumns. A point
+// already stored for the same (satellite_id, time) is skipped, so inserting a
+// batch again after a crash or a WAL replay cannot duplicate rows.
+var telemetryInsertStmt = buildTelemetryInsertStmt(telemetryColumns)
+
+// Batches of at least copyMinPoints are written with COPY into
+// telemetryStagingTable and moved into telemetry with one INSERT ... SELECT,
+// which keeps the duplicate skipping COPY itself can't do. Smaller batches
+// don't repay the extra round trips.
+const (
+	copyMinPoints         = 16
+	telemetryStagingTable = "telemetry_copy_staging"
+)
+
+// telemetryStagingStmt creates the per-connection staging table; its rows
+// are transactional and cleared when the transaction commits
+const telemetryStagingStmt = "CREATE TEMP TABLE IF NOT EXISTS " + telemetryStagingTable +
+	" (LIKE telemetry INCLUDING DEFAULTS) ON COMMIT DELETE ROWS"
+
+// telemetryCopyInsertStmt moves staged points into telemetry
+var telemetryCopyInsertStmt = buildTelemetryCopyInsertStmt(telemetryColumns)
+
+func buildTelemetryColumns() []telemetryColumn {
+	t := reflect.TypeOf(models.TelemetryPoint{})
+	columns := make([]telemetryColumn, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("db"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		columns = append(columns, telemetryColumn{name: name, index: field.Index})
+	}
+	return columns
+}
+
+func buildTelemetryInsertStmt(columns []telemetryColumn) string {
+	names := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return fmt.Sprintf("INSERT INTO telemetry (%s) VALUES (%s) ON CONFLICT (satellite_id, time) DO NOTHING",
+		strings.Join(names, ", "), strings.Join(placeholders, ", "))
+}
+
+func buildTelemetryCopyInsertStmt(columns []telemetryColumn) string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+	list := strings.Join(names, ", ")
+	return fmt.Sprintf("INSERT INTO telemetry (%s) SELECT %s FROM %s ON CONFLICT (satellite_id, time) DO NOTHING",
+		list, list, telemetryStagingTable)
+}
+
+// insertTelemetry inserts points within tx and returns how many were new;
+// the rest were already stored. Large batches use COPY; if that fails the
+// batch is inserted with pipelined INSERTs, which also report a bad row's
+// own error.
+func insertTelemetry(ctx context.Context, tx pgx.Tx, points []models.TelemetryPoint) (int64, error) {
+	if len(points) < copyMinPoints {
+		return insertTelemetryRows(ctx, tx, points)
+	}
+	inserted, err := copyTelemetry(ctx, tx, points)
+	if err == nil || ctx.Err() != nil {
+		return inserted, err
+	}
+	log.Printf("WARNING: COPY of %d points failed, falling back to INSERT: %v", len(points), err)
+	return insertTelemetryRows(ctx, tx, points)
+}
+
+// copyTelemetry stages points with COPY and inserts them in one statement,
+// within a savepoint so a failure leaves tx usable
+func copyTelemetry(ctx context.Context, tx pgx.Tx, points []models.TelemetryPoint) (int64, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = savepoint.Rollback(ctx) }()
+
+	if _, err := savepoint.Exec(ctx, telemetryStagingStmt); err != nil {
+		return 0, err
+	}
+	if _, err := savepoint.CopyFrom(ctx, pgx.Identifier{telemetryStagingTable}, TelemetryColumnNames(),
+		pgx.CopyFromSlice(len(points), func(i int) ([]interface{}, error) {
+			return telemetryInsertArgs(&points[i]), nil
+		})); err != nil {
+		return 0, err
+	}
+	tag, err := savepoint.Exec(ctx, telemetryCopyInsertStmt)
+	if err != nil {
+		return 0, err
+	}
+	// Empty the staging table for the next batch in this transaction
+	if _, err := savepoint.Exec(ctx, "TRUNCATE "+telemetryStagingTable); err != nil {
+		return 0, err
+	}
+	if err := savepoint.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// insertTelemetryRows inserts points with telemetryInsertStmt, pipelined in
+// one round trip; a failing row reports its own error
+func insertTelemetryRows(ctx context.Context, tx pgx.Tx, points []models.TelemetryPoint) (int64, error) {
+	batch := &pgx.Batch{}
+	for i := range points {
+		batch.Queue(telemetryInsertStmt, telemetryInsertArgs(&points[i])...)
+	}
+	return sendBatch(ctx, tx, batch)
+}
+
+// sendBatch sends the queued statements in one round trip and returns the
+// rows they affected, stopping at the first error
+func sendBatch(ctx context.Context, tx pgx.Tx, batch *pgx.Batch) (int64, error) {
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+	results := tx.SendBatch(ctx, batch)
+	affected := int64(0)
+	for i := 0; i < batch.Len(); i++ {
+		tag, err := results.Exec()
+		if err != nil {
+			_ = results.Close()
+			return affected, err
+		}
+		affected += tag.RowsAffected()
+	}
+	return affected, results.Close()
+}
+
+// telemetryInsertArgs returns the arguments for telemetryInsertStmt in column order
+func telemetryInsertArgs(point *models.TelemetryPoint) []interface{} {
+	v := reflect.ValueOf(point).Elem()
+	args := make([]interface{}, len(telemetryColumns))
+	for i, column := range telemetryColumns {
+		args[i] = v.FieldByIndex(column.index).Interface()
+	}
+	return args
+}
+
+// TelemetryColumnNames returns the telemetry columns written on insert, in order
+func TelemetryColumnNames() []string {
+	names := make([]string, len(telemetryColumns))
+	for i, column := range telemetryColumns {
+		names[i] = column.name
+	}
+	return names
+}
@@ -0,0 +1,265 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// TestTelemetryColumnsCoverModel tests that every db-tagged model field is inserted
+func TestTelemetryColumnsCoverModel(t *testing.T) {
+	names := TelemetryColumnNames()
+
+	typ := reflect.TypeOf(models.TelemetryPoint{})
+	expected := 0
+	for i := 0; i < typ.NumField(); i++ {
+		if tag := typ.Field(i).Tag.Get("db"); tag != "" && tag != "-" {
+			expected++
+		}
+	}
+	if len(names) != expected {
+		t.Errorf("expected %d columns, got %d", expected, len(names))
+	}
+
+	for _, required := range []string{"time", "satellite_id", "latitude", "longitude", "altitude_km", "velocity_kmph", "software_version", "anomaly_severity"} {
+		found := false
+		for _, name := range names {
+			if name == required {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected column %s in insert list", required)
+		}
+	}
+
+	if got := strings.Count(telemetryInsertStmt, "$"); got != len(names) {
+		t.Errorf("expected %d placeholders, got %d: %s", len(names), got, telemetryInsertStmt)
+	}
+	if !strings.HasSuffix(telemetryInsertStmt, "ON CONFLICT (satellite_id, time) DO NOTHING") {
+		t.Errorf("expected inserts to skip stored points: %s", telemetryInsertStmt)
+	}
+}
+
+// TestTelemetryInsertArgsOrder tests that arguments line up with the column list
+func TestTelemetryInsertArgsOrder(t *testing.T) {
+	point := fullTelemetryPointForTest()
+	args := telemetryInsertArgs(&point)
+	names := TelemetryColumnNames()
+
+	if len(args) != len(names) {
+		t.Fatalf("expected %d args, got %d", len(names), len(args))
+	}
+	for i, name := range names {
+		switch name {
+		case "satellite_id":
+			if args[i] != point.SatelliteID {
+				t.Errorf("satellite_id bound to %v", args[i])
+			}
+		case "time":
+			if args[i] != point.Timestamp {
+				t.Errorf("time bound to %v", args[i])
+			}
+		case "latitude":
+			if args[i] != point.Latitude {
+				t.Errorf("latitude bound to %v", args[i])
+			}
+		}
+	}
+}
+
+// TestTelemetryCopyInsertStmt tests that staged points are moved with the
+// insert column list and still skip stored points
+func TestTelemetryCopyInsertStmt(t *testing.T) {
+	list := strings.Join(TelemetryColumnNames(), ", ")
+	expected := "INSERT INTO telemetry (" + list + ") SELECT " + list + " FROM " + telemetryStagingTable +
+		" ON CONFLICT (satellite_id, time) DO NOTHING"
+	if telemetryCopyInsertStmt != expected {
+		t.Errorf("unexpected copy insert statement:\n%s\nwant:\n%s", telemetryCopyInsertStmt, expected)
+	}
+}
+
+// TestInsertTelemetryCopy tests that large batches are copied without
+// duplicating stored points and fall back to row inserts on a bad row
+func TestInsertTelemetryCopy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	points := make([]models.TelemetryPoint, 2*copyMinPoints)
+	for i := range points {
+		points[i] = fullTelemetryPointForTest()
+		points[i].SatelliteID = "SAT-COPY"
+		points[i].Timestamp = base.Add(time.Duration(i) * time.Second)
+	}
+	// A point repeated within the batch is stored once
+	points = append(points, points[0])
+
+	insert := func(points []models.TelemetryPoint) (int64, error) {
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatalf("failed to begin: %v", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		inserted, err := insertTelemetry(ctx, tx, points)
+		if err != nil {
+			return inserted, err
+		}
+		return inserted, tx.Commit(ctx)
+	}
+
+	inserted, err := insert(points)
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if inserted != 2*copyMinPoints {
+		t.Errorf("expected %d new points, got %d", 2*copyMinPoints, inserted)
+	}
+	if inserted, err := insert(points); err != nil || inserted != 0 {
+		t.Errorf("expected a repeated batch to insert nothing, got %d (%v)", inserted, err)
+	}
+
+	// A row COPY can't store is reported with its own error by the fallback
+	bad := make([]models.TelemetryPoint, copyMinPoints)
+	for i := range bad {
+		bad[i] = fullTelemetryPointForTest()
+		bad[i].SatelliteID = "SAT-COPY-BAD"
+		bad[i].Timestamp = base.Add(time.Duration(i) * time.Second)
+	}
+	bad[3].BatteryChargePercent = 1000 // overflows DECIMAL(5,2)
+	if _, err := insert(bad); !isPermanentInsertError(err) {
+		t.Errorf("expected a permanent insert error, got %v", err)
+	}
+}
+
+// TestInsertTelemetryRowsPipelined tests that small batches sent as one
+// pipeline skip stored points and report the failing row's error
+func TestInsertTelemetryRowsPipelined(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	points := make([]models.TelemetryPoint, copyMinPoints-1)
+	for i := range points {
+		points[i] = fullTelemetryPointForTest()
+		points[i].SatelliteID = "SAT-PIPELINE"
+		points[i].Timestamp = base.Add(time.Duration(i) * time.Second)
+	}
+
+	insert := func(points []models.TelemetryPoint) (int64, error) {
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatalf("failed to begin: %v", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		inserted, err := insertTelemetryRows(ctx, tx, points)
+		if err != nil {
+			return inserted, err
+		}
+		return inserted, tx.Commit(ctx)
+	}
+
+	if inserted, err := insert(points[:5]); err != nil || inserted != 5 {
+		t.Fatalf("expected 5 new points, got %d (%v)", inserted, err)
+	}
+	if inserted, err := insert(points); err != nil || inserted != int64(len(points)-5) {
+		t.Errorf("expected %d new points, got %d (%v)", len(points)-5, inserted, err)
+	}
+
+	bad := append([]models.TelemetryPoint(nil), points...)
+	bad[7].Timestamp = base.Add(time.Hour)
+	bad[7].BatteryChargePercent = 1000 // overflows DECIMAL(5,2)
+	if _, err := insert(bad); !isPermanentInsertError(err) {
+		t.Errorf("expected a permanent insert error, got %v", err)
+	}
+}
+
+// TestWALRecordMatchesTelemetryPoint tests that WAL records carry every model field
+func TestWALRecordMatchesTelemetryPoint(t *testing.T) {
+	pointType := reflect.TypeOf(models.TelemetryPoint{})
+	recordType := reflect.TypeOf(WALRecord{})
+
+	for i := 0; i < pointType.NumField(); i++ {
+		field := pointType.Field(i)
+		recordField, ok := recordType.FieldByName(field.Name)
+		if !ok {
+			t.Errorf("WALRecord is missing field %s; WAL replay would drop it", field.Name)
+			continue
+		}
+		if recordField.Type != field.Type {
+			t.Errorf("WALRecord.%s has type %v, TelemetryPoint has %v", field.Name, recordField.Type, field.Type)
+		}
+	}
+}
+
+// TestWALRecordRoundTrip tests point -> WAL record -> point conversion preserves all fields
+func TestWALRecordRoundTrip(t *testing.T) {
+	point := fullTelemetryPointForTest()
+
+	got := NewWALRecord(point).TelemetryPoint()
+	if !reflect.DeepEqual(got, point) {
+		t.Errorf("round trip mismatch:\nexpected %+v\ngot      %+v", point, got)
+	}
+
+	// Every field must be set so a forgotten conversion shows up above
+	v := reflect.ValueOf(point)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).IsZero() {
+			t.Errorf("test point leaves %s unset, extend fullTelemetryPointForTest", v.Type().Field(i).Name)
+		}
+	}
+}
+
+// fullTelemetryPointForTest returns a point with every field populated
+func fullTelemetryPointForTest() models.TelemetryPoint {
+	lat, lon, alt, vel := 12.5, -45.25, 550.0, 27000.0
+	version := "2.4.0"
+	detector := "threshold"
+	severity := models.SeverityCritical
+	dimension := models.DimensionBattery
+	issues := "latitude:out_of_range"
+	zones := "svalbard_gs"
+	residual := 12.5
+	return models.TelemetryPoint{
+		SatelliteID:          "SAT-0042",
+		BatteryChargePercent: 5.0,
+		StorageUsageMB:       45000.0,
+		SignalStrengthDBM:    -55.0,
+		Timestamp:            time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		IsAnomaly:            true,
+		Latitude:             &lat,
+		Longitude:            &lon,
+		AltitudeKM:           &alt,
+		VelocityKMPH:         &vel,
+		SoftwareVersion:      &version,
+		AnomalyDetector:      &detector,
+		AnomalySeverity:      &severity,
+		AnomalyDimension:     &dimension,
+		IsLate:               true,
+		TimestampClamped:     true,
+		ValidationIssues:     &issues,
+		PositionAnomaly:      true,
+		GeofenceZones:        &zones,
+		PositionResidualKM:   &residual,
+	}
+}
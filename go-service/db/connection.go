@@ -8,14 +8,35 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func NewConnectionPool(dbUrl string, maxConnections int) (*pgxpool.Pool, error) {
+func NewConnectionPool(creds Credentials, maxConnections int) (*pgxpool.Pool, error) {
+	return newPool(creds, maxConnections, 5, "Database connection pool")
+}
+
+// NewReservedPool creates a small pool kept apart from the insert pool, so
+// health checks and WAL replay still get a connection when a saturated
+// insert workload holds every connection of the main pool.
+// All its connections are kept open so a check never waits on a dial.
+func NewReservedPool(creds Credentials, connections int) (*pgxpool.Pool, error) {
+	return newPool(creds, connections, connections, "Reserved connection pool")
+}
+
+func newPool(creds Credentials, maxConnections, minConnections int, name string) (*pgxpool.Pool, error) {
+	dbUrl, err := creds.connString()
+	if err != nil {
+		return nil, err
+	}
 	config, err := pgxpool.ParseConfig(dbUrl)
 	if err != nil {
 		return nil, err
 	}
+	// Every new connection picks up rotated secret files
+	config.BeforeConnect = creds.beforeConnect
 
+	if minConnections > maxConnections {
+		minConnections = maxConnections
+	}
 	config.MaxConns = int32(maxConnections)
-	config.MinConns = 5
+	config.MinConns = int32(minConnections)
 	config.MaxConnLifetime = 1 * time.Hour
 	config.MaxConnIdleTime = 10 * time.Minute
 	config.HealthCheckPeriod = 1 * time.Minute
@@ -34,6 +55,6 @@ func NewConnectionPool(dbUrl string, maxConnections int) (*pgxpool.Pool, error)
 		return nil, err
 	}
 
-	log.Println("Database connection pool established successfully")
+	log.Printf("%s established successfully (max %d connections)", name, maxConnections)
 	return pool, nil
 }
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"orbitstream/models"
+)
+
+// FlushStatus describes the most recent buffer flush
+type FlushStatus struct {
+	Time    time.Time `json:"time"`
+	Records int       `json:"records"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// CrashReport captures ingestion state at the time of a panic
+type CrashReport struct {
+	Time           time.Time    `json:"time"`
+	Panic          string       `json:"panic"`
+	Stack          string       `json:"stack"`
+	BufferSize     int          `json:"buffer_size"`
+	InFlight       int          `json:"in_flight"`
+	LastFlush      *FlushStatus `json:"last_flush,omitempty"`
+	CircuitBreaker string       `json:"circuit_breaker,omitempty"`
+	WALBytes       int64        `json:"wal_bytes"`
+	// Where unflushed points were persisted, and how many
+	EmergencyWAL     string `json:"emergency_wal,omitempty"`
+	EmergencyRecords int    `json:"emergency_records"`
+	EmergencyError   string `json:"emergency_error,omitempty"`
+}
+
+// CrashSnapshot persists every point not yet in the database or WAL (the
+// buffer and in-flight batches) to an emergency WAL file at path and returns
+// a report of the processor state, for use from a panic handler.
+//
+// It must not block on a goroutine that died mid-panic, so locks are only
+// taken if free; otherwise the state is read as-is, on a best-effort basis.
+func (bp *BatchProcessor) CrashSnapshot(reason any, path string) CrashReport {
+	report := CrashReport{
+		Time:         time.Now().UTC(),
+		Panic:        fmt.Sprint(reason),
+		Stack:        string(debug.Stack()),
+		EmergencyWAL: path,
+	}
+
+	if bp.bufferMutex.TryLock() {
+		defer bp.bufferMutex.Unlock()
+	}
+	points := bp.bufferedPointsLocked()
+	report.BufferSize = len(points)
+	for _, batch := range bp.inFlight {
+		points = append(points, batch...)
+		report.InFlight += len(batch)
+	}
+	if !bp.lastFlush.Time.IsZero() {
+		lastFlush := bp.lastFlush
+		report.LastFlush = &lastFlush
+	}
+	if bp.circuitBreaker != nil {
+		report.CircuitBreaker = bp.circuitBreaker.State().String()
+	}
+	if bp.wal != nil && bp.wal.mu.TryLock() {
+		bp.wal.mu.Unlock()
+		report.WALBytes = bp.wal.Size()
+	}
+
+	if path == "" {
+		report.EmergencyError = "no emergency WAL path configured"
+		return report
+	}
+	written, err := writeEmergencyWAL(path, points)
+	report.EmergencyRecords = written
+	if err != nil {
+		report.EmergencyError = err.Error()
+	}
+	return report
+}
+
+// SetCrashHandler sets the function called with the panic value when a flush
+// worker or the health monitor panics, before the panic continues; main uses
+// it to take a CrashSnapshot like it does around Start
+func (bp *BatchProcessor) SetCrashHandler(handler func(reason any)) {
+	bp.crashHandler = handler
+}
+
+// recoverCrash hands a panic unwinding a background goroutine to the crash
+// handler, then re-panics so the process still exits with the original stack
+// trace. It must be deferred directly.
+func (bp *BatchProcessor) recoverCrash() {
+	if bp == nil || bp.crashHandler == nil {
+		return
+	}
+	r := recover()
+	if r == nil {
+		return
+	}
+	bp.crashHandler(r)
+	panic(r)
+}
+
+// writeEmergencyWAL appends points to a standalone WAL file (one JSON record
+// per line, like a WAL segment) and syncs it. It avoids the WAL itself, whose
+// lock may be held by the panicking goroutine.
+func writeEmergencyWAL(path string, points []models.TelemetryPoint) (int, error) {
+	if len(points) == 0 {
+		return 0, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create emergency WAL directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open emergency WAL: %w", err)
+	}
+	defer file.Close()
+
+	written := 0
+	for _, point := range points {
+		data, err := json.Marshal(NewWALRecord(point))
+		if err != nil {
+			return written, fmt.Errorf("failed to marshal emergency WAL record: %w", err)
+		}
+		if _, err := file.Write(append(data, '\n')); err != nil {
+			return written, fmt.Errorf("failed to write emergency WAL: %w", err)
+		}
+		written++
+	}
+	if err := file.Sync(); err != nil {
+		return written, fmt.Errorf("failed to sync emergency WAL: %w", err)
+	}
+	return written, nil
+}
+
+// RecoverEmergencyWAL moves records from an emergency WAL file left by a
+// crash into the WAL, where the health monitor replays them, and deletes
+// the file. Returns the number of records recovered. On error the file is
+// kept, so records written before the error are recovered again next time.
+func RecoverEmergencyWAL(path string, wal *WAL) (int, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	it := newWALIterator([]string{path})
+	defer it.Close()
+	recovered := 0
+	for it.Next() {
+		if err := wal.Write(it.Record()); err != nil {
+			return recovered, err
+		}
+		recovered++
+	}
+	if err := it.Err(); err != nil {
+		return recovered, err
+	}
+	it.Close()
+
+	if err := os.Remove(path); err != nil {
+		return recovered, fmt.Errorf("failed to delete emergency WAL: %w", err)
+	}
+	return recovered, nil
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"orbitstream/models"
+)
+
+// TestCrashSnapshotPersistsUnflushedPoints tests that buffered and in-flight
+// points reach the emergency WAL and are recovered into the WAL on restart
+func TestCrashSnapshotPersistsUnflushedPoints(t *testing.T) {
+	dir := t.TempDir()
+	emergencyPath := filepath.Join(dir, "emergency.wal")
+
+	bp := &BatchProcessor{
+		buffer:         []models.TelemetryPoint{TelemetryPointForTest(80, 1000, -60)},
+		circuitBreaker: NewCircuitBreaker(3, 0),
+	}
+	bp.trackInFlight([]models.TelemetryPoint{
+		TelemetryPointForTest(70, 1000, -60),
+		TelemetryPointForTest(60, 1000, -60),
+	})
+	bp.recordFlush(2, os.ErrDeadlineExceeded)
+
+	report := bp.CrashSnapshot("boom", emergencyPath)
+	if report.EmergencyError != "" || report.EmergencyRecords != 3 {
+		t.Fatalf("expected 3 records saved, got %d (%s)", report.EmergencyRecords, report.EmergencyError)
+	}
+	if report.Panic != "boom" || report.BufferSize != 1 || report.InFlight != 2 {
+		t.Errorf("unexpected crash report %+v", report)
+	}
+	if report.CircuitBreaker != "CLOSED" || report.LastFlush == nil || report.LastFlush.Error == "" {
+		t.Errorf("expected breaker state and failed last flush in report, got %+v", report)
+	}
+
+	wal, err := NewWAL(filepath.Join(dir, "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	recovered, err := RecoverEmergencyWAL(emergencyPath, wal)
+	if err != nil || recovered != 3 {
+		t.Fatalf("expected 3 records recovered, got %d (%v)", recovered, err)
+	}
+	if count, _ := wal.Count(); count != 3 {
+		t.Errorf("expected 3 records in WAL, got %d", count)
+	}
+	if _, err := os.Stat(emergencyPath); !os.IsNotExist(err) {
+		t.Error("expected emergency WAL to be deleted after recovery")
+	}
+}
+
+// TestCrashSnapshotWithHeldLock tests that a lock held by the panicking
+// goroutine does not block the snapshot
+func TestCrashSnapshotWithHeldLock(t *testing.T) {
+	emergencyPath := filepath.Join(t.TempDir(), "emergency.wal")
+	bp := &BatchProcessor{buffer: []models.TelemetryPoint{TelemetryPointForTest(80, 1000, -60)}}
+
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	report := bp.CrashSnapshot("boom", emergencyPath)
+
+	if report.EmergencyRecords != 1 {
+		t.Errorf("expected 1 record saved, got %d (%s)", report.EmergencyRecords, report.EmergencyError)
+	}
+}
+
+// TestRecoverEmergencyWALMissing tests that no emergency file is a no-op
+func TestRecoverEmergencyWALMissing(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := NewWAL(filepath.Join(dir, "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+
+	recovered, err := RecoverEmergencyWAL(filepath.Join(dir, "emergency.wal"), wal)
+	if err != nil || recovered != 0 {
+		t.Errorf("expected nothing recovered, got %d (%v)", recovered, err)
+	}
+}
+
+// TestRecoverCrashCallsHandler tests that a panic in a background goroutine
+// reaches the crash handler and still unwinds
+func TestRecoverCrashCallsHandler(t *testing.T) {
+	var handled any
+	bp := &BatchProcessor{}
+	bp.SetCrashHandler(func(reason any) { handled = reason })
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("expected the panic to continue, got %v", r)
+		}
+		if handled != "boom" {
+			t.Errorf("expected the handler to get the panic, got %v", handled)
+		}
+	}()
+	func() {
+		defer bp.recoverCrash()
+		panic("boom")
+	}()
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Credentials are the connection string and password of new connections.
+// Either can come from a mounted secret file (Kubernetes secret, Vault agent
+// template) instead of the environment. The files are re-read when they
+// change, so rotated credentials apply to every connection opened
+// afterwards without a restart; open connections keep their session until
+// the pool recycles them.
+type Credentials struct {
+	URL string
+	// Replaces URL when set
+	URLFile *SecretFile
+	// Replaces the password of the connection string when set
+	PasswordFile *SecretFile
+}
+
+// connString returns the connection string the pool is configured from
+func (c Credentials) connString() (string, error) {
+	if c.URLFile == nil {
+		return c.URL, nil
+	}
+	return c.URLFile.Read()
+}
+
+// beforeConnect applies the current credentials to a new connection
+func (c Credentials) beforeConnect(_ context.Context, conn *pgx.ConnConfig) error {
+	if c.URLFile != nil {
+		connString, err := c.URLFile.Read()
+		if err != nil {
+			return err
+		}
+		current, err := pgx.ParseConfig(connString)
+		if err != nil {
+			return fmt.Errorf("invalid connection string in %s: %w", c.URLFile.path, err)
+		}
+		conn.Host = current.Host
+		conn.Port = current.Port
+		conn.Database = current.Database
+		conn.User = current.User
+		conn.Password = current.Password
+		conn.TLSConfig = current.TLSConfig
+		conn.Fallbacks = current.Fallbacks
+	}
+	if c.PasswordFile != nil {
+		password, err := c.PasswordFile.Read()
+		if err != nil {
+			return err
+		}
+		conn.Password = password
+	}
+	return nil
+}
+
+// SecretFile is a secret mounted as a file. Its contents are cached until
+// the file's modification time or size changes; Kubernetes swaps a symlink
+// on rotation, which Stat follows.
+type SecretFile struct {
+	path string
+
+	mu        sync.Mutex
+	loaded    bool
+	modTime   time.Time
+	size      int64
+	value     string
+	rotations int64
+}
+
+// NewSecretFile creates a secret read from path on first use
+func NewSecretFile(path string) *SecretFile {
+	return &SecretFile{path: path}
+}
+
+// Read returns the file contents without surrounding whitespace (secret
+// files usually end in a newline), re-reading the file when it changed
+func (s *SecretFile) Read() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	if s.loaded && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return s.value, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("secret file %s is empty", s.path)
+	}
+	if s.loaded && value != s.value {
+		s.rotations++
+		log.Printf("EVENT: secret_rotated file=%s rotations=%d", s.path, s.rotations)
+	}
+	s.loaded, s.modTime, s.size, s.value = true, info.ModTime(), info.Size(), value
+	return value, nil
+}
+
+// Rotations returns how often the secret changed since it was first read
+func (s *SecretFile) Rotations() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rotations
+}
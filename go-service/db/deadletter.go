@@ -0,0 +1,173 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"orbitstream/models"
+)
+
+// Dead letter sources
+const (
+	DeadLetterSourceFlush  = "flush"
+	DeadLetterSourceReplay = "replay"
+)
+
+// DeadLetter is a record the database rejected permanently, with the error
+type DeadLetter struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Error  string    `json:"error"`
+	Record WALRecord `json:"record"`
+}
+
+// DeadLetterQueue stores rejected records in a JSON lines file, so one bad
+// record no longer fails its whole batch (or wedges WAL replay) forever
+type DeadLetterQueue struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewDeadLetterQueue creates a queue at path, creating its directory
+func NewDeadLetterQueue(path string) (*DeadLetterQueue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dead letter directory: %w", err)
+	}
+	return &DeadLetterQueue{path: path}, nil
+}
+
+// Write durably appends letters
+func (q *DeadLetterQueue) Write(letters []DeadLetter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := os.OpenFile(q.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	defer file.Close()
+	for _, letter := range letters {
+		data, err := json.Marshal(letter)
+		if err != nil {
+			return fmt.Errorf("failed to marshal dead letter: %w", err)
+		}
+		if _, err := file.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to write dead letter: %w", err)
+		}
+	}
+	return file.Sync()
+}
+
+// Read returns the newest limit letters (all when limit <= 0), oldest first,
+// and the total number stored
+func (q *DeadLetterQueue) Read(limit int) ([]DeadLetter, int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	letters := make([]DeadLetter, 0)
+	file, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return letters, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	defer file.Close()
+
+	total := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var letter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			continue // torn final line after a crash
+		}
+		total++
+		letters = append(letters, letter)
+		if limit > 0 && len(letters) > limit {
+			letters = letters[1:]
+		}
+	}
+	return letters, total, scanner.Err()
+}
+
+// isPermanentInsertError reports whether err rejects the data itself, so
+// retrying the same row can never succeed: data exceptions (SQLSTATE class
+// 22, e.g. numeric overflow) and integrity constraint violations (class 23)
+func isPermanentInsertError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || len(pgErr.Code) < 2 {
+		return false
+	}
+	class := pgErr.Code[:2]
+	return class == "22" || class == "23"
+}
+
+// insertDivertingRejects inserts points in one transaction with a savepoint
+// per row. Rows failing with a permanent error are rolled back and written
+// to dlq before the transaction commits (a failed commit may leave them
+// dead-lettered twice, never lost); any other error fails the whole
+// transaction. Returns the rows inserted and the points not rejected.
+func insertDivertingRejects(ctx context.Context, pool *pgxpool.Pool, points []models.TelemetryPoint, lateData *LateDataTracker, gaps *GapRecorder, dlq *DeadLetterQueue, source string) (int64, []models.TelemetryPoint, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	inserted := int64(0)
+	accepted := make([]models.TelemetryPoint, 0, len(points))
+	var rejected []DeadLetter
+	for _, point := range points {
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		n, err := insertTelemetry(ctx, savepoint, []models.TelemetryPoint{point})
+		if err != nil {
+			_ = savepoint.Rollback(ctx)
+			if !isPermanentInsertError(err) {
+				return 0, nil, err
+			}
+			rejected = append(rejected, DeadLetter{
+				Time:   time.Now().UTC(),
+				Source: source,
+				Error:  err.Error(),
+				Record: NewWALRecord(point),
+			})
+			continue
+		}
+		if err := savepoint.Commit(ctx); err != nil {
+			return 0, nil, err
+		}
+		inserted += n
+		accepted = append(accepted, point)
+	}
+	if err := lateData.insertCorrections(ctx, tx, accepted); err != nil {
+		return 0, nil, err
+	}
+	if err := gaps.recordGaps(ctx, tx, accepted); err != nil {
+		return 0, nil, err
+	}
+	if err := dlq.Write(rejected); err != nil {
+		return 0, nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, nil, err
+	}
+	log.Printf("Dead-lettered %d of %d records (%s)", len(rejected), len(points), source)
+	return inserted, accepted, nil
+}
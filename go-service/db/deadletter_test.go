@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	"orbitstream/models"
+)
+
+// TestDeadLetterQueueReadNewest tests that Read returns the newest records
+// oldest first with the total count
+func TestDeadLetterQueueReadNewest(t *testing.T) {
+	dlq, err := NewDeadLetterQueue(filepath.Join(t.TempDir(), "dlq", "dead-letter.jsonl"))
+	if err != nil {
+		t.Fatalf("failed to create dead letter queue: %v", err)
+	}
+
+	letters, total, err := dlq.Read(10)
+	if err != nil || total != 0 || len(letters) != 0 {
+		t.Fatalf("expected an empty queue, got %d/%d (%v)", len(letters), total, err)
+	}
+
+	for i := 0; i < 5; i++ {
+		letter := DeadLetter{Source: DeadLetterSourceFlush, Error: fmt.Sprintf("error %d", i)}
+		if err := dlq.Write([]DeadLetter{letter}); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	letters, total, err = dlq.Read(2)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if total != 5 || len(letters) != 2 || letters[0].Error != "error 3" || letters[1].Error != "error 4" {
+		t.Errorf("expected the newest 2 of 5 letters, got %d: %+v", total, letters)
+	}
+}
+
+// TestIsPermanentInsertError tests that only data and constraint errors are
+// treated as permanent
+func TestIsPermanentInsertError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: "22003"}, true},                           // numeric_value_out_of_range
+		{fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23502"}), true}, // not_null_violation
+		{&pgconn.PgError{Code: "08006"}, false},                          // connection_failure
+		{&pgconn.PgError{Code: "40001"}, false},                          // serialization_failure
+		{errors.New("connection refused"), false},
+		{context.DeadlineExceeded, false},
+	}
+	for _, tc := range cases {
+		if got := isPermanentInsertError(tc.err); got != tc.want {
+			t.Errorf("isPermanentInsertError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+// TestDeadLetterDivertsRejectedRecords tests that a record the database
+// rejects is dead-lettered on flush and on WAL replay while the rest of the
+// batch is stored, instead of failing the batch or wedging replay
+func TestDeadLetterDivertsRejectedRecords(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, InitTestSchema(pool))
+
+	dir := t.TempDir()
+	dlq, err := NewDeadLetterQueue(filepath.Join(dir, "dead-letter.jsonl"))
+	require.NoError(t, err)
+	wal, err := NewWAL(filepath.Join(dir, "data.wal"))
+	require.NoError(t, err)
+	defer wal.Close()
+
+	bp := NewBatchProcessor(pool, 100, time.Second, AnomalyConfig{})
+	bp.SetWAL(wal)
+	bp.SetDeadLetterQueue(dlq)
+	bp.SetRetryConfig(1, time.Millisecond)
+
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	batch := func(satelliteID string) []models.TelemetryPoint {
+		points := make([]models.TelemetryPoint, 3)
+		for i := range points {
+			points[i] = TelemetryPointForTest(80, 1000, -60)
+			points[i].SatelliteID = satelliteID
+			points[i].Timestamp = base.Add(time.Duration(i) * time.Second)
+		}
+		points[1].BatteryChargePercent = 1000 // overflows DECIMAL(5,2)
+		return points
+	}
+
+	// Flush path
+	require.NoError(t, bp.flushWithRetry(context.Background(), batch("SAT-FLUSH")))
+
+	// Replay path
+	require.NoError(t, bp.flushToWAL(batch("SAT-REPLAY")))
+	hm := NewHealthMonitor(pool, wal, bp)
+	replayed, err := hm.Replay()
+	require.NoError(t, err)
+	require.Equal(t, 3, replayed)
+
+	for _, satelliteID := range []string{"SAT-FLUSH", "SAT-REPLAY"} {
+		var count int
+		require.NoError(t, pool.QueryRow(ctx,
+			`SELECT COUNT(*) FROM telemetry WHERE satellite_id = $1`, satelliteID).Scan(&count))
+		require.Equal(t, 2, count, "valid records of %s should be stored", satelliteID)
+	}
+
+	letters, total, err := dlq.Read(0)
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	require.Equal(t, DeadLetterSourceFlush, letters[0].Source)
+	require.Equal(t, DeadLetterSourceReplay, letters[1].Source)
+	require.Equal(t, 1000.0, letters[1].Record.BatteryChargePercent)
+	require.Contains(t, letters[1].Error, "22003")
+}
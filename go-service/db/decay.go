@@ -0,0 +1,243 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+)
+
+// DecayDetectorName identifies orbital decay findings in alerts
+const DecayDetectorName = "orbital_decay"
+
+// AltitudeTrend is a least-squares fit of a satellite's hourly mean altitude
+type AltitudeTrend struct {
+	SatelliteID string    `json:"satellite_id"`
+	Samples     int       `json:"samples"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	// SlopeKMPerDay is the fitted altitude change (negative = decaying)
+	SlopeKMPerDay float64 `json:"slope_km_per_day"`
+	// R2 is the goodness of fit; low values mean the trend is mostly noise
+	R2               float64 `json:"r2"`
+	LatestAltitudeKM float64 `json:"latest_altitude_km"`
+}
+
+// AltitudeTrendQuerier fits altitude trends from stored telemetry
+// This allows for mocking in tests
+type AltitudeTrendQuerier interface {
+	AltitudeTrends(ctx context.Context, since time.Time, minSamples int) ([]AltitudeTrend, error)
+}
+
+// AltitudeTrends fits a linear altitude trend per satellite over the hourly
+// aggregates since the given time, skipping satellites with fewer samples
+func (qs *QueryService) AltitudeTrends(ctx context.Context, since time.Time, minSamples int) ([]AltitudeTrend, error) {
+	rows, err := qs.query(ctx, `
+		SELECT
+			satellite_id, COUNT(*), MIN(bucket), MAX(bucket),
+			regr_slope(avg_altitude_km::double precision, EXTRACT(EPOCH FROM bucket) / 86400.0),
+			COALESCE(regr_r2(avg_altitude_km::double precision, EXTRACT(EPOCH FROM bucket) / 86400.0), 0),
+			(array_agg(avg_altitude_km::double precision ORDER BY bucket DESC))[1]
+		FROM satellite_stats_hourly
+		WHERE bucket >= $1 AND avg_altitude_km IS NOT NULL
+		GROUP BY satellite_id
+		HAVING COUNT(*) >= $2
+		ORDER BY satellite_id
+	`, since, minSamples)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trends := make([]AltitudeTrend, 0)
+	for rows.Next() {
+		var t AltitudeTrend
+		var slope *float64
+		if err := rows.Scan(&t.SatelliteID, &t.Samples, &t.From, &t.To, &slope, &t.R2, &t.LatestAltitudeKM); err != nil {
+			return nil, err
+		}
+		if slope == nil {
+			continue // all samples in one bucket, no trend to fit
+		}
+		t.SlopeKMPerDay = *slope
+		trends = append(trends, t)
+	}
+	return trends, rows.Err()
+}
+
+// DecayConfig configures orbital decay monitoring
+type DecayConfig struct {
+	// Window is how far back altitude is fitted
+	Window time.Duration
+	// Interval between checks
+	Interval time.Duration
+	// MinSamples is the minimum number of hourly buckets needed for a fit
+	MinSamples int
+	// Altitude loss rates (km/day) beyond which a warning or critical alert fires
+	WarningKMPerDay  float64
+	CriticalKMPerDay float64
+	// MinR2 ignores fits that explain less of the variance than this
+	MinR2 float64
+}
+
+// EvaluateDecay checks a trend against the expected bounds, returning a
+// finding when altitude loss exceeds them
+func EvaluateDecay(trend AltitudeTrend, cfg DecayConfig) *models.AnomalyResult {
+	loss := -trend.SlopeKMPerDay
+	if loss <= 0 || trend.R2 < cfg.MinR2 {
+		return nil
+	}
+
+	var severity models.AnomalySeverity
+	var bound float64
+	switch {
+	case cfg.CriticalKMPerDay > 0 && loss >= cfg.CriticalKMPerDay:
+		severity, bound = models.SeverityCritical, cfg.CriticalKMPerDay
+	case cfg.WarningKMPerDay > 0 && loss >= cfg.WarningKMPerDay:
+		severity, bound = models.SeverityWarning, cfg.WarningKMPerDay
+	default:
+		return nil
+	}
+
+	return &models.AnomalyResult{
+		Detector:  DecayDetectorName,
+		Severity:  severity,
+		Dimension: models.DimensionPosition,
+		Message: fmt.Sprintf("altitude falling %.2f km/day over %d hourly samples (R²=%.2f), now %.1f km",
+			loss, trend.Samples, trend.R2, trend.LatestAltitudeKM),
+		Metric:    "altitude_decay_km_per_day",
+		Value:     loss,
+		Threshold: bound,
+	}
+}
+
+// DecayMonitor periodically fits altitude trends and alerts flight dynamics
+// when a satellite loses altitude faster than expected.
+//
+// A satellite is alerted when it first exceeds a bound and again only if
+// its severity escalates; it is re-armed once its trend is back in bounds.
+type DecayMonitor struct {
+	querier AltitudeTrendQuerier
+	sink    AnomalyAlertSink
+	cfg     DecayConfig
+
+	mu       sync.Mutex
+	alerted  map[string]models.AnomalySeverity
+	findings []models.AnomalyEvent
+	// Only the leader replica checks and alerts (nil = always)
+	leadership Leadership
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewDecayMonitor creates a monitor; sink may be nil (findings are only logged)
+func NewDecayMonitor(querier AltitudeTrendQuerier, sink AnomalyAlertSink, cfg DecayConfig) *DecayMonitor {
+	if cfg.Window <= 0 {
+		cfg.Window = 7 * 24 * time.Hour
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	return &DecayMonitor{
+		querier: querier,
+		sink:    sink,
+		cfg:     cfg,
+		alerted: make(map[string]models.AnomalySeverity),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// SetLeadership makes the monitor check only while this replica is the
+// leader, so replicas don't send the same alerts; must be called before Start
+func (m *DecayMonitor) SetLeadership(leadership Leadership) {
+	m.leadership = leadership
+}
+
+// Start runs a check immediately and then every interval until Stop
+func (m *DecayMonitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			if m.leadership == nil || m.leadership.IsLeader() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				if _, err := m.Check(ctx); err != nil {
+					log.Printf("DecayMonitor: check failed: %v", err)
+				}
+				cancel()
+			}
+
+			select {
+			case <-ticker.C:
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the check loop
+func (m *DecayMonitor) Stop() {
+	close(m.stopCh)
+	m.wg.Wait()
+}
+
+// Check fits current trends and returns every satellite outside bounds.
+// Alerts are sent only for new or escalated findings.
+func (m *DecayMonitor) Check(ctx context.Context) ([]models.AnomalyEvent, error) {
+	trends, err := m.querier.AltitudeTrends(ctx, time.Now().Add(-m.cfg.Window), m.cfg.MinSamples)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	findings := make([]models.AnomalyEvent, 0)
+	var toAlert []models.AnomalyEvent
+
+	m.mu.Lock()
+	inBounds := make(map[string]bool, len(m.alerted))
+	for id := range m.alerted {
+		inBounds[id] = true
+	}
+	for _, trend := range trends {
+		result := EvaluateDecay(trend, m.cfg)
+		if result == nil {
+			continue
+		}
+		delete(inBounds, trend.SatelliteID)
+
+		event := models.AnomalyEvent{SatelliteID: trend.SatelliteID, Timestamp: now, AnomalyResult: *result}
+		findings = append(findings, event)
+		if previous, ok := m.alerted[trend.SatelliteID]; !ok || result.Severity.Rank() > previous.Rank() {
+			m.alerted[trend.SatelliteID] = result.Severity
+			toAlert = append(toAlert, event)
+		}
+	}
+	// Satellites back within bounds (or without a fit) are re-armed
+	for id := range inBounds {
+		delete(m.alerted, id)
+	}
+	m.findings = findings
+	m.mu.Unlock()
+
+	for _, event := range toAlert {
+		log.Printf("DecayMonitor: [%s] %s %s", event.Severity, event.SatelliteID, event.Message)
+		if m.sink != nil {
+			m.sink.Alert(event)
+		}
+	}
+	return findings, nil
+}
+
+// Findings returns the satellites outside bounds at the last check
+func (m *DecayMonitor) Findings() []models.AnomalyEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.AnomalyEvent(nil), m.findings...)
+}
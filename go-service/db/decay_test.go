@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// fakeTrendQuerier returns canned altitude trends
+type fakeTrendQuerier struct {
+	trends []AltitudeTrend
+}
+
+func (f *fakeTrendQuerier) AltitudeTrends(ctx context.Context, since time.Time, minSamples int) ([]AltitudeTrend, error) {
+	return f.trends, nil
+}
+
+func testDecayConfig() DecayConfig {
+	return DecayConfig{MinSamples: 24, WarningKMPerDay: 0.5, CriticalKMPerDay: 2.0, MinR2: 0.5}
+}
+
+// TestEvaluateDecay tests severity classification of altitude loss rates
+func TestEvaluateDecay(t *testing.T) {
+	cfg := testDecayConfig()
+	tests := []struct {
+		name     string
+		trend    AltitudeTrend
+		severity models.AnomalySeverity
+	}{
+		{"stable orbit", AltitudeTrend{SlopeKMPerDay: -0.1, R2: 0.9}, ""},
+		{"orbit raise", AltitudeTrend{SlopeKMPerDay: 3.0, R2: 0.9}, ""},
+		{"warning decay", AltitudeTrend{SlopeKMPerDay: -0.8, R2: 0.9}, models.SeverityWarning},
+		{"critical decay", AltitudeTrend{SlopeKMPerDay: -2.5, R2: 0.9}, models.SeverityCritical},
+		{"noisy fit", AltitudeTrend{SlopeKMPerDay: -2.5, R2: 0.2}, ""},
+	}
+
+	for _, tt := range tests {
+		result := EvaluateDecay(tt.trend, cfg)
+		if tt.severity == "" {
+			if result != nil {
+				t.Errorf("%s: expected no finding, got %+v", tt.name, result)
+			}
+			continue
+		}
+		if result == nil || result.Severity != tt.severity {
+			t.Errorf("%s: expected %s finding, got %+v", tt.name, tt.severity, result)
+			continue
+		}
+		if result.Detector != DecayDetectorName || result.Dimension != models.DimensionPosition {
+			t.Errorf("%s: unexpected classification %+v", tt.name, result)
+		}
+		if result.Value != -tt.trend.SlopeKMPerDay {
+			t.Errorf("%s: expected value %.2f, got %.2f", tt.name, -tt.trend.SlopeKMPerDay, result.Value)
+		}
+	}
+}
+
+// TestDecayMonitorAlertsOnNewAndEscalatedFindings tests alert deduplication across checks
+func TestDecayMonitorAlertsOnNewAndEscalatedFindings(t *testing.T) {
+	querier := &fakeTrendQuerier{trends: []AltitudeTrend{
+		{SatelliteID: "SAT-001", SlopeKMPerDay: -0.8, R2: 0.9},
+		{SatelliteID: "SAT-002", SlopeKMPerDay: -0.05, R2: 0.9},
+	}}
+	sink := &recordingAlertSink{}
+	monitor := NewDecayMonitor(querier, sink, testDecayConfig())
+
+	findings, err := monitor.Check(context.Background())
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(findings) != 1 || len(sink.events) != 1 || sink.events[0].SatelliteID != "SAT-001" {
+		t.Fatalf("expected one SAT-001 alert, got findings %+v alerts %+v", findings, sink.events)
+	}
+
+	// Same severity again: still a finding, but no new alert
+	if _, err := monitor.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(sink.events) != 1 {
+		t.Errorf("expected no repeat alert, got %d alerts", len(sink.events))
+	}
+
+	// Escalation alerts again
+	querier.trends[0].SlopeKMPerDay = -3.0
+	if _, err := monitor.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(sink.events) != 2 || sink.events[1].Severity != models.SeverityCritical {
+		t.Errorf("expected critical escalation alert, got %+v", sink.events)
+	}
+
+	// Back in bounds re-arms the satellite
+	querier.trends[0].SlopeKMPerDay = -0.1
+	if _, err := monitor.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(monitor.Findings()) != 0 {
+		t.Errorf("expected no findings, got %+v", monitor.Findings())
+	}
+	querier.trends[0].SlopeKMPerDay = -0.8
+	if _, err := monitor.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(sink.events) != 3 {
+		t.Errorf("expected re-armed satellite to alert again, got %d alerts", len(sink.events))
+	}
+}
+
+// TestAltitudeTrends tests the regression over hourly aggregates
+func TestAltitudeTrends(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	ctx := context.Background()
+	start := time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Hour)
+	for h := 0; h < 48; h++ {
+		// Losing 1 km per day
+		altitude := 420.0 - float64(h)/24.0
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO telemetry (time, satellite_id, battery_charge_percent, storage_usage_mb, signal_strength_dbm, altitude_km)
+			VALUES ($1, 'SAT-DECAY', 80, 1000, -60, $2)`, start.Add(time.Duration(h)*time.Hour), altitude); err != nil {
+			t.Fatalf("failed to insert telemetry: %v", err)
+		}
+	}
+	if _, err := pool.Exec(ctx, `CALL refresh_continuous_aggregate('satellite_stats_hourly', NULL, NULL)`); err != nil {
+		t.Fatalf("failed to refresh aggregate: %v", err)
+	}
+
+	trends, err := NewQueryService(pool).AltitudeTrends(ctx, start.Add(-time.Hour), 24)
+	if err != nil {
+		t.Fatalf("failed to fit trends: %v", err)
+	}
+	if len(trends) != 1 {
+		t.Fatalf("expected 1 trend, got %d", len(trends))
+	}
+	if slope := trends[0].SlopeKMPerDay; slope > -0.99 || slope < -1.01 {
+		t.Errorf("expected slope of -1 km/day, got %.3f", slope)
+	}
+}
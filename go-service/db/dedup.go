@@ -0,0 +1,152 @@
+package db
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sync"
+
+	"orbitstream/models"
+)
+
+// Deduplication modes
+const (
+	// DedupByKey drops any point whose (satellite_id, timestamp) was seen
+	DedupByKey = "key"
+	// DedupByContent drops only exact copies: same key and same values
+	DedupByContent = "content"
+)
+
+// DedupStats reports the recent points tracked and the duplicates dropped
+type DedupStats struct {
+	Mode       string `json:"mode"`
+	Capacity   int    `json:"capacity"`
+	Tracked    int    `json:"tracked"`
+	Duplicates int64  `json:"duplicates"`
+}
+
+// dedupKey identifies a point; hash is only set in content mode
+type dedupKey struct {
+	satelliteID string
+	nanos       int64
+	hash        uint64
+}
+
+// Deduplicator drops retransmissions of recently accepted points before
+// they reach the detectors and the buffer, so flaky ground links resending
+// a window don't raise anomaly alerts twice or skew the pipeline counts.
+// It remembers the keys of the last capacity accepted points (least
+// recently seen first out); older duplicates are still skipped by the
+// insert's ON CONFLICT, just not before detection.
+type Deduplicator struct {
+	mu         sync.Mutex
+	mode       string
+	capacity   int
+	order      *list.List // of dedupKey, most recent first
+	keys       map[dedupKey]*list.Element
+	duplicates int64
+}
+
+// NewDeduplicator creates a deduplicator remembering capacity points
+func NewDeduplicator(capacity int, mode string) (*Deduplicator, error) {
+	if mode != DedupByKey && mode != DedupByContent {
+		return nil, fmt.Errorf("unknown dedup mode %q (want %s or %s)", mode, DedupByKey, DedupByContent)
+	}
+	if capacity <= 0 {
+		return nil, fmt.Errorf("dedup cache size must be positive, got %d", capacity)
+	}
+	return &Deduplicator{
+		mode:     mode,
+		capacity: capacity,
+		order:    list.New(),
+		keys:     make(map[dedupKey]*list.Element, capacity),
+	}, nil
+}
+
+// seen reports whether point repeats a remembered one, counting it as a
+// duplicate and refreshing its key if so, and returns the point's key for
+// remember. A nil deduplicator sees nothing.
+func (d *Deduplicator) seen(point *models.TelemetryPoint) (dedupKey, bool) {
+	if d == nil {
+		return dedupKey{}, false
+	}
+	key := dedupKey{satelliteID: point.SatelliteID, nanos: point.Timestamp.UnixNano()}
+	if d.mode == DedupByContent {
+		key.hash = contentHash(point)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if element, ok := d.keys[key]; ok {
+		d.order.MoveToFront(element)
+		d.duplicates++
+		return key, true
+	}
+	return key, false
+}
+
+// remember records the key of an accepted point, forgetting the least
+// recently seen one when full
+func (d *Deduplicator) remember(key dedupKey) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.keys[key]; ok {
+		return
+	}
+	d.keys[key] = d.order.PushFront(key)
+	if d.order.Len() > d.capacity {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.keys, oldest.Value.(dedupKey))
+	}
+}
+
+// Stats returns the mode, the keys tracked and the duplicates dropped
+func (d *Deduplicator) Stats() DedupStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return DedupStats{
+		Mode:       d.mode,
+		Capacity:   d.capacity,
+		Tracked:    d.order.Len(),
+		Duplicates: d.duplicates,
+	}
+}
+
+// contentHash hashes the stored columns of a point as received; pointer
+// columns hash their value, or nothing when nil
+func contentHash(point *models.TelemetryPoint) uint64 {
+	h := fnv.New64a()
+	v := reflect.ValueOf(point).Elem()
+	for _, column := range telemetryColumns {
+		field := v.FieldByIndex(column.index)
+		if field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				h.Write([]byte{0})
+				continue
+			}
+			field = field.Elem()
+		}
+		fmt.Fprintf(h, "%v\x00", field.Interface())
+	}
+	return h.Sum64()
+}
+
+// SetDeduplicator drops points repeating recently accepted ones; must be
+// called before Start
+func (bp *BatchProcessor) SetDeduplicator(dedup *Deduplicator) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.dedup = dedup
+}
+
+// GetDeduplicator returns the deduplicator (nil if disabled)
+func (bp *BatchProcessor) GetDeduplicator() *Deduplicator {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return bp.dedup
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDeduplicatorDropsRetransmissions tests that a repeated point is not
+// buffered or run through detection again
+func TestDeduplicatorDropsRetransmissions(t *testing.T) {
+	bp := NewBatchProcessor(nil, 1000, time.Second, AnomalyConfig{BatteryMinPercent: 10})
+	dedup, err := NewDeduplicator(100, DedupByKey)
+	if err != nil {
+		t.Fatalf("NewDeduplicator failed: %v", err)
+	}
+	bp.SetDeduplicator(dedup)
+
+	point := TelemetryPointForTest(5.0, 45000.0, -55.0)
+	point.SatelliteID = "SAT-001"
+	for i := 0; i < 3; i++ {
+		if err := bp.Add(point); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	later := point
+	later.Timestamp = point.Timestamp.Add(time.Second)
+	if err := bp.Add(later); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	stats := bp.Stats()
+	if stats.Accepted != 2 || stats.Duplicates != 2 || stats.Anomalies != 2 || stats.Buffered != 2 {
+		t.Errorf("unexpected pipeline stats %+v", stats)
+	}
+}
+
+// TestDeduplicatorRejectedPointIsNotRemembered tests that a retransmission of
+// a point the full buffer refused is accepted once there is room
+func TestDeduplicatorRejectedPointIsNotRemembered(t *testing.T) {
+	bp := NewBatchProcessor(nil, 1000, time.Second, AnomalyConfig{})
+	bp.SetMaxBufferSize(0)
+	dedup, _ := NewDeduplicator(100, DedupByKey)
+	bp.SetDeduplicator(dedup)
+
+	point := TelemetryPointForTest(85.0, 45000.0, -55.0)
+	if err := bp.Add(point); err == nil {
+		t.Fatal("expected the full buffer to refuse the point")
+	}
+	bp.SetMaxBufferSize(10)
+	if err := bp.Add(point); err != nil {
+		t.Fatalf("expected the retransmission to be accepted, got %v", err)
+	}
+	if size := bp.GetBufferSize(); size != 1 {
+		t.Errorf("expected 1 buffered point, got %d", size)
+	}
+}
+
+// TestDeduplicatorContentModeAndEviction tests that content mode only drops
+// identical points and that the least recently seen key is forgotten
+func TestDeduplicatorContentModeAndEviction(t *testing.T) {
+	dedup, err := NewDeduplicator(2, DedupByContent)
+	if err != nil {
+		t.Fatalf("NewDeduplicator failed: %v", err)
+	}
+	first := TelemetryPointForTest(85.0, 45000.0, -55.0)
+	first.SatelliteID = "SAT-001"
+	key, _ := dedup.seen(&first)
+	dedup.remember(key)
+
+	changed := first
+	changed.BatteryChargePercent = 84.0
+	if _, duplicate := dedup.seen(&changed); duplicate {
+		t.Error("expected a point with different values to pass in content mode")
+	}
+	if _, duplicate := dedup.seen(&first); !duplicate {
+		t.Error("expected an identical point to be dropped")
+	}
+
+	for i := 1; i <= 2; i++ {
+		other := first
+		other.Timestamp = first.Timestamp.Add(time.Duration(i) * time.Second)
+		key, _ := dedup.seen(&other)
+		dedup.remember(key)
+	}
+	if _, duplicate := dedup.seen(&first); duplicate {
+		t.Error("expected the oldest key to be evicted")
+	}
+	if stats := dedup.Stats(); stats.Tracked != 2 || stats.Duplicates != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if _, err := NewDeduplicator(10, "hash"); err == nil {
+		t.Error("expected an unknown mode to be rejected")
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrDraining is returned by Add once Drain has started
+var ErrDraining = errors.New("batch processor is draining, not accepting points")
+
+// Drain stops accepting points, ends the flush loop and waits until every
+// buffered and in-flight batch is stored in the database or, failing that,
+// the WAL. Flushes keep retrying while ctx allows; once it expires they stop
+// backing off and write their batches to the WAL, Drain waits for that and
+// returns ctx's error. Without a running flush loop Drain flushes the buffer
+// itself.
+func (bp *BatchProcessor) Drain(ctx context.Context) error {
+	bp.bufferMutex.Lock()
+	bp.draining = true
+	bp.drainCtx = ctx
+	stopped := bp.stopped
+	bp.bufferMutex.Unlock()
+
+	bp.stopLoop()
+	if stopped == nil {
+		bp.flushWithContext(ctx)
+		return drainErr(ctx)
+	}
+	select {
+	case <-stopped:
+		// The final flush gave up on the database if ctx expired meanwhile
+		return drainErr(ctx)
+	case <-ctx.Done():
+	}
+	// Out of time: running flushes give up retrying and go to the WAL
+	if bp.cancelRetries != nil {
+		bp.cancelRetries()
+	}
+	<-stopped
+	return drainErr(ctx)
+}
+
+// Draining reports whether Drain has started
+func (bp *BatchProcessor) Draining() bool {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return bp.draining
+}
+
+func drainErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("drain deadline passed, unflushed batches went to the WAL: %w", err)
+	}
+	return nil
+}
+
+// loopStarted records that Start's loop runs and returns the channel it
+// closes when it ends
+func (bp *BatchProcessor) loopStarted() chan struct{} {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.stopped = make(chan struct{})
+	return bp.stopped
+}
+
+// finalFlushContext bounds the flush loop's final flush: by the drain's
+// context when draining, otherwise only by the retry budget
+func (bp *BatchProcessor) finalFlushContext() context.Context {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	if bp.drainCtx != nil {
+		return bp.drainCtx
+	}
+	return context.Background()
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newDrainTestProcessor returns a running batch processor whose database is
+// unreachable, so flushes end in its WAL
+func newDrainTestProcessor(t *testing.T, retryBudget time.Duration) (*BatchProcessor, *WAL) {
+	t.Helper()
+	unreachable, wal := newUnreachableBatchProcessor(t)
+	bp := NewBatchProcessor(unreachable.pool, 100, time.Hour, AnomalyConfig{})
+	bp.SetWAL(wal)
+	bp.SetRetryConfig(0, 20*time.Millisecond)
+	bp.SetRetryBudget(retryBudget)
+	go bp.Start()
+	for i := 0; i < 3; i++ {
+		if err := bp.Add(TelemetryPointForTest(85.0, 45000.0, -55.0)); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	return bp, wal
+}
+
+// TestDrainStoresBufferAndRejectsPoints tests that Drain returns once the
+// buffer is stored and Add is refused from then on
+func TestDrainStoresBufferAndRejectsPoints(t *testing.T) {
+	bp, wal := newDrainTestProcessor(t, 100*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bp.Drain(ctx); err != nil {
+		t.Fatalf("expected a complete drain, got %v", err)
+	}
+	if size := bp.GetBufferSize(); size != 0 {
+		t.Errorf("expected an empty buffer, got %d", size)
+	}
+	if count, _ := wal.Count(); count != 3 {
+		t.Errorf("expected 3 WAL records, got %d", count)
+	}
+	if err := bp.Add(TelemetryPointForTest(85.0, 45000.0, -55.0)); !errors.Is(err, ErrDraining) {
+		t.Errorf("expected ErrDraining after Drain, got %v", err)
+	}
+	// Stop after Drain must not close the loop twice
+	bp.Stop()
+}
+
+// TestDrainDeadlineDivertsQueuedBatches tests that batches still queued for
+// the flush workers at the drain deadline go straight to the WAL instead of
+// retrying for the whole retry budget
+func TestDrainDeadlineDivertsQueuedBatches(t *testing.T) {
+	unreachable, wal := newUnreachableBatchProcessor(t)
+	bp := NewBatchProcessor(unreachable.pool, 1, time.Hour, AnomalyConfig{})
+	bp.SetWAL(wal)
+	bp.SetRetryConfig(0, 20*time.Millisecond)
+	bp.SetRetryBudget(30 * time.Second)
+	bp.SetCircuitBreaker(nil)
+	go bp.Start()
+	// One batch retrying in the worker, one queued and one buffered
+	for i := 0; i < 3; i++ {
+		if err := bp.Add(TelemetryPointForTest(85.0, 45000.0, -55.0)); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := bp.Drain(ctx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected drain error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected Drain to return soon after its deadline, took %v", elapsed)
+	}
+	if count, _ := wal.Count(); count != 3 {
+		t.Errorf("expected 3 WAL records, got %d", count)
+	}
+}
+
+// TestDrainDeadlineDivertsToWAL tests that flushes still retrying at the
+// drain deadline write their batches to the WAL before Drain returns
+func TestDrainDeadlineDivertsToWAL(t *testing.T) {
+	bp, wal := newDrainTestProcessor(t, time.Hour)
+	// Keep retrying instead of the breaker diverting to the WAL
+	bp.SetCircuitBreaker(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	// Retries stop at the deadline, or just before it when the next backoff
+	// wouldn't fit
+	if err := bp.Drain(ctx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected drain error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected Drain to return soon after its deadline, took %v", elapsed)
+	}
+	if count, _ := wal.Count(); count != 3 {
+		t.Errorf("expected 3 WAL records, got %d", count)
+	}
+}
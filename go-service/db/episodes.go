@@ -0,0 +1,227 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+)
+
+// episodeKey identifies the rule an episode tracks for one satellite
+type episodeKey struct {
+	detector string
+	metric   string
+}
+
+// EpisodeStats summarizes the episodes of a satellite (or the fleet)
+type EpisodeStats struct {
+	Open   int `json:"open"`
+	Closed int `json:"closed"`
+	// MTTRSeconds is the mean duration of the closed episodes in the history
+	MTTRSeconds float64 `json:"mttr_seconds"`
+	// LongestSeconds is the longest closed episode in the history
+	LongestSeconds float64 `json:"longest_seconds"`
+}
+
+// EpisodeTracker folds per-point anomaly flags into episodes per satellite
+// and detector/metric: an episode opens on the first flagged point, tracks
+// its peak value and highest severity, and closes on the first point the
+// rule no longer flags. Closed episodes are kept in a bounded in-memory
+// history for MTTR reporting; they do not survive a restart.
+type EpisodeTracker struct {
+	mu      sync.Mutex
+	open    map[string]map[episodeKey]*models.AnomalyEpisode
+	history []models.AnomalyEpisode // closed episodes, oldest first
+	limit   int
+}
+
+// NewEpisodeTracker creates a tracker keeping the last historyLimit closed
+// episodes
+func NewEpisodeTracker(historyLimit int) *EpisodeTracker {
+	if historyLimit <= 0 {
+		historyLimit = 1000
+	}
+	return &EpisodeTracker{
+		open:  make(map[string]map[episodeKey]*models.AnomalyEpisode),
+		limit: historyLimit,
+	}
+}
+
+// observe updates the episodes of the point's satellite with the findings of
+// the detector chain and returns the episodes the point closed
+func (t *EpisodeTracker) observe(point models.TelemetryPoint, results []models.AnomalyResult) []models.AnomalyEpisode {
+	if t == nil {
+		return nil
+	}
+	at := point.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	at = at.UTC()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	open := t.open[point.SatelliteID]
+	if open == nil && len(results) == 0 {
+		return nil
+	}
+	if open == nil {
+		open = make(map[episodeKey]*models.AnomalyEpisode)
+		t.open[point.SatelliteID] = open
+	}
+
+	flagged := make(map[episodeKey]bool, len(results))
+	for _, result := range results {
+		key := episodeKey{detector: result.Detector, metric: result.Metric}
+		flagged[key] = true
+		episode, ok := open[key]
+		if !ok {
+			open[key] = &models.AnomalyEpisode{
+				SatelliteID: point.SatelliteID,
+				Detector:    result.Detector,
+				Metric:      result.Metric,
+				Dimension:   result.Dimension,
+				Severity:    result.Severity,
+				StartedAt:   at,
+				LastSeenAt:  at,
+				PeakValue:   result.Value,
+				Threshold:   result.Threshold,
+				Points:      1,
+			}
+			continue
+		}
+		episode.Points++
+		if at.After(episode.LastSeenAt) {
+			episode.LastSeenAt = at
+		}
+		if result.Severity.Rank() > episode.Severity.Rank() {
+			episode.Severity = result.Severity
+		}
+		if math.Abs(result.Value-result.Threshold) > math.Abs(episode.PeakValue-episode.Threshold) {
+			episode.PeakValue = result.Value
+			episode.Threshold = result.Threshold
+		}
+		episode.DurationSeconds = episode.LastSeenAt.Sub(episode.StartedAt).Seconds()
+	}
+
+	var closed []models.AnomalyEpisode
+	for key, episode := range open {
+		if flagged[key] {
+			continue
+		}
+		delete(open, key)
+		ended := at
+		if ended.Before(episode.LastSeenAt) {
+			ended = episode.LastSeenAt // out-of-order point
+		}
+		episode.EndedAt = &ended
+		episode.DurationSeconds = ended.Sub(episode.StartedAt).Seconds()
+		closed = append(closed, *episode)
+	}
+	if len(open) == 0 {
+		delete(t.open, point.SatelliteID)
+	}
+
+	t.history = append(t.history, closed...)
+	if over := len(t.history) - t.limit; over > 0 {
+		t.history = append(t.history[:0], t.history[over:]...)
+	}
+	return closed
+}
+
+// Open returns the open episodes, of one satellite or all when satelliteID
+// is empty, oldest first
+func (t *EpisodeTracker) Open(satelliteID string) []models.AnomalyEpisode {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	episodes := make([]models.AnomalyEpisode, 0)
+	for id, open := range t.open {
+		if satelliteID != "" && id != satelliteID {
+			continue
+		}
+		for _, episode := range open {
+			episodes = append(episodes, *episode)
+		}
+	}
+	sort.Slice(episodes, func(i, j int) bool {
+		return episodes[i].StartedAt.Before(episodes[j].StartedAt)
+	})
+	return episodes
+}
+
+// History returns up to limit closed episodes (all when limit <= 0), of one
+// satellite or all when satelliteID is empty, most recently closed first
+func (t *EpisodeTracker) History(satelliteID string, limit int) []models.AnomalyEpisode {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	episodes := make([]models.AnomalyEpisode, 0)
+	for i := len(t.history) - 1; i >= 0; i-- {
+		if limit > 0 && len(episodes) == limit {
+			break
+		}
+		if satelliteID == "" || t.history[i].SatelliteID == satelliteID {
+			episodes = append(episodes, t.history[i])
+		}
+	}
+	return episodes
+}
+
+// Stats summarizes the open episodes and closed history, of one satellite or
+// all when satelliteID is empty
+func (t *EpisodeTracker) Stats(satelliteID string) EpisodeStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var stats EpisodeStats
+	for id, open := range t.open {
+		if satelliteID == "" || id == satelliteID {
+			stats.Open += len(open)
+		}
+	}
+	total := 0.0
+	for _, episode := range t.history {
+		if satelliteID != "" && episode.SatelliteID != satelliteID {
+			continue
+		}
+		stats.Closed++
+		total += episode.DurationSeconds
+		stats.LongestSeconds = math.Max(stats.LongestSeconds, episode.DurationSeconds)
+	}
+	if stats.Closed > 0 {
+		stats.MTTRSeconds = total / float64(stats.Closed)
+	}
+	return stats
+}
+
+// recoveryEvent builds the alert sent when point closes episode
+func recoveryEvent(point models.TelemetryPoint, episode models.AnomalyEpisode) models.AnomalyEvent {
+	duration := time.Duration(episode.DurationSeconds * float64(time.Second)).Round(time.Second)
+	event := models.AnomalyEvent{
+		SatelliteID: point.SatelliteID,
+		Timestamp:   point.Timestamp,
+		Recovered:   true,
+		Episode:     &episode,
+		AnomalyResult: models.AnomalyResult{
+			Detector:  episode.Detector,
+			Severity:  episode.Severity,
+			Dimension: episode.Dimension,
+			Message:   fmt.Sprintf("recovered after %v (%d points, peak %.2f)", duration, episode.Points, episode.PeakValue),
+			Metric:    episode.Metric,
+			Threshold: episode.Threshold,
+		},
+	}
+	name := episode.Metric
+	if alias, ok := ruleMetricAliases[name]; ok {
+		name = alias
+	}
+	if metric, ok := ruleMetrics[name]; ok {
+		event.Value, _ = metric.value(point)
+	}
+	return event
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+func episodePoint(satelliteID string, battery float64, at time.Time) models.TelemetryPoint {
+	point := TelemetryPointForTest(battery, 45000.0, -55.0)
+	point.SatelliteID = satelliteID
+	point.Timestamp = at
+	return point
+}
+
+// TestEpisodeTrackerOpensAndCloses tests that consecutive flags form one
+// episode with its peak, closed by the first normal point
+func TestEpisodeTrackerOpensAndCloses(t *testing.T) {
+	tracker := NewEpisodeTracker(10)
+	detector := NewThresholdDetector(AnomalyConfig{BatteryMinPercent: 10.0, StorageMaxMB: 95000.0, SignalMinDBM: -100.0})
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, battery := range []float64{8, 3, 6} {
+		point := episodePoint("SAT-0001", battery, start.Add(time.Duration(i)*time.Minute))
+		if closed := tracker.observe(point, runAnomalyDetectors([]AnomalyDetector{detector}, point)); len(closed) != 0 {
+			t.Fatalf("expected no closed episode while flagged, got %+v", closed)
+		}
+	}
+	open := tracker.Open("SAT-0001")
+	if len(open) != 1 || open[0].Points != 3 || open[0].PeakValue != 3 {
+		t.Fatalf("expected one open episode of 3 points peaking at 3, got %+v", open)
+	}
+
+	point := episodePoint("SAT-0001", 50, start.Add(5*time.Minute))
+	closed := tracker.observe(point, nil)
+	if len(closed) != 1 {
+		t.Fatalf("expected the episode closed, got %+v", closed)
+	}
+	episode := closed[0]
+	if episode.Metric != "battery_charge_percent" || episode.DurationSeconds != 300 ||
+		episode.EndedAt == nil || !episode.EndedAt.Equal(start.Add(5*time.Minute)) {
+		t.Errorf("unexpected closed episode: %+v", episode)
+	}
+	if len(tracker.Open("")) != 0 {
+		t.Errorf("expected no open episodes after recovery")
+	}
+}
+
+// TestEpisodeTrackerStats tests MTTR over the closed history and the history limit
+func TestEpisodeTrackerStats(t *testing.T) {
+	tracker := NewEpisodeTracker(2)
+	flag := []models.AnomalyResult{{Detector: "threshold", Metric: "battery_charge_percent", Value: 5, Threshold: 10}}
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Episodes of 1, 2 and 3 minutes; only the last two are kept
+	at := start
+	for minutes := 1; minutes <= 3; minutes++ {
+		tracker.observe(episodePoint("SAT-0001", 5, at), flag)
+		at = at.Add(time.Duration(minutes) * time.Minute)
+		tracker.observe(episodePoint("SAT-0001", 50, at), nil)
+		at = at.Add(time.Hour)
+	}
+	tracker.observe(episodePoint("SAT-0002", 5, at), flag)
+
+	stats := tracker.Stats("")
+	if stats.Open != 1 || stats.Closed != 2 || stats.MTTRSeconds != 150 || stats.LongestSeconds != 180 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	history := tracker.History("SAT-0001", 0)
+	if len(history) != 2 || history[0].DurationSeconds != 180 {
+		t.Errorf("expected the last two episodes, newest first, got %+v", history)
+	}
+	if got := tracker.Stats("SAT-0002"); got.Open != 1 || got.Closed != 0 {
+		t.Errorf("unexpected per-satellite stats: %+v", got)
+	}
+}
+
+// TestBatchProcessorRecoveryEvent tests that a closed episode is sent to the
+// alert sink as a recovery event
+func TestBatchProcessorRecoveryEvent(t *testing.T) {
+	sink := &recordingAlertSink{}
+	bp := &BatchProcessor{
+		buffer:        make([]models.TelemetryPoint, 0, 10),
+		batchSize:     10,
+		maxBufferSize: 100,
+		anomalyConfig: AnomalyConfig{BatteryMinPercent: 10.0, StorageMaxMB: 95000.0, SignalMinDBM: -100.0},
+	}
+	bp.SetAlertSink(sink)
+	bp.SetEpisodeTracker(NewEpisodeTracker(10))
+
+	_ = bp.Add(TelemetryPointForTest(5.0, 45000.0, -55.0))
+	_ = bp.Add(TelemetryPointForTest(4.0, 45000.0, -55.0))
+	_ = bp.Add(TelemetryPointForTest(80.0, 45000.0, -55.0))
+
+	if len(sink.events) != 3 {
+		t.Fatalf("expected 2 anomaly events and 1 recovery, got %d", len(sink.events))
+	}
+	event := sink.events[2]
+	if !event.Recovered || event.Episode == nil || event.Episode.Points != 2 || event.Episode.PeakValue != 4.0 {
+		t.Errorf("unexpected recovery event: %+v", event)
+	}
+	if event.Value != 80.0 || event.Severity != models.SeverityCritical {
+		t.Errorf("expected recovery to carry the normal value and episode severity, got %+v", event)
+	}
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"orbitstream/models"
+)
+
+// ExportCursor marks how far an export has progressed.
+// After is the timestamp of the last row delivered and Skip is how many rows
+// with exactly that timestamp were already delivered, so a resumed export
+// neither repeats nor loses rows that share a timestamp.
+type ExportCursor struct {
+	After time.Time
+	Skip  int
+}
+
+// Advance moves the cursor past a delivered point
+func (c ExportCursor) Advance(point models.TelemetryPoint) ExportCursor {
+	if point.Timestamp.Equal(c.After) {
+		return ExportCursor{After: c.After, Skip: c.Skip + 1}
+	}
+	return ExportCursor{After: point.Timestamp, Skip: 1}
+}
+
+// ExportTelemetry streams raw telemetry for a satellite in time order,
+// starting after the cursor, calling fn for every row. Rows are read from
+// the database as they are sent, so exports never load the range into memory.
+// q.Limit is ignored; exports are bounded by the time range only.
+func (qs *QueryService) ExportTelemetry(ctx context.Context, q TelemetryQuery, cursor ExportCursor, fn func(models.TelemetryPoint) error) error {
+	from := q.From
+	if !cursor.After.IsZero() && !cursor.After.Before(from) {
+		from = cursor.After
+	} else {
+		cursor = ExportCursor{}
+	}
+
+	rows, err := qs.query(ctx, `
+		SELECT`+telemetrySelectColumns+`
+		FROM telemetry
+		WHERE satellite_id = $1 AND time >= $2 AND time < $3
+		ORDER BY time ASC, received_at ASC
+	`, q.SatelliteID, from, q.To)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	skipped := 0
+	for rows.Next() {
+		p, err := scanTelemetryPoint(rows)
+		if err != nil {
+			return err
+		}
+		// Skip rows at the cursor timestamp that were already delivered
+		if skipped < cursor.Skip && p.Timestamp.Equal(cursor.After) {
+			skipped++
+			continue
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// TestExportCursorAdvance tests cursor handling of rows sharing a timestamp
+func TestExportCursorAdvance(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) models.TelemetryPoint {
+		return models.TelemetryPoint{Timestamp: base.Add(offset)}
+	}
+
+	var cursor ExportCursor
+	cursor = cursor.Advance(at(0))
+	cursor = cursor.Advance(at(time.Minute))
+	cursor = cursor.Advance(at(time.Minute))
+
+	if !cursor.After.Equal(base.Add(time.Minute)) || cursor.Skip != 2 {
+		t.Errorf("expected cursor at +1m skipping 2, got %+v", cursor)
+	}
+
+	cursor = cursor.Advance(at(2 * time.Minute))
+	if cursor.Skip != 1 {
+		t.Errorf("expected skip to reset on a new timestamp, got %d", cursor.Skip)
+	}
+}
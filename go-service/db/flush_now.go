@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// FlushResult reports what a forced flush did with the buffered points
+type FlushResult struct {
+	// Points taken from the buffer
+	Points int `json:"points"`
+	// Written counts the points committed to the database (duplicates of
+	// stored points included); the others were diverted or left in the
+	// write-through journal when even the WAL failed
+	Written         int64   `json:"written"`
+	WALDiverted     int64   `json:"wal_diverted"`
+	OverflowSpilled int64   `json:"overflow_spilled"`
+	DeadLettered    int64   `json:"dead_lettered"`
+	Failed          int64   `json:"failed"`
+	Batches         int     `json:"batches"`
+	DurationMS      float64 `json:"duration_ms"`
+}
+
+// flushTallyKey carries the *FlushResult of a forced flush in the flush
+// context, so the counters of that flush alone can be reported while the
+// flush loop keeps running
+type flushTallyKey struct{}
+
+// flushTally returns the forced flush tally of ctx, nil for regular flushes
+func flushTally(ctx context.Context) *FlushResult {
+	tally, _ := ctx.Value(flushTallyKey{}).(*FlushResult)
+	return tally
+}
+
+// FlushNow flushes every buffered point immediately, instead of waiting for
+// the batch size or timeout, and reports how many were written to the
+// database and how many were diverted. Flushes retry while ctx allows;
+// once it is done they go to the WAL. Points added meanwhile wait for the
+// next flush.
+func (bp *BatchProcessor) FlushNow(ctx context.Context) FlushResult {
+	start := time.Now()
+	result := &FlushResult{}
+	ctx = context.WithValue(ctx, flushTallyKey{}, result)
+
+	bp.flushMu.Lock()
+	defer bp.flushMu.Unlock()
+	for i := bp.shardCount(); i > 0; i-- {
+		job, ok := bp.takeBatch(0)
+		if !ok {
+			break
+		}
+		result.Points += len(job.batch)
+		result.Batches++
+		bp.runFlush(ctx, job)
+	}
+	result.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+	return *result
+}
@@ -0,0 +1,409 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+)
+
+// Forecast resources
+const (
+	ResourceBattery = "battery"
+	ResourceStorage = "storage"
+)
+
+// forecastResource is how a resource is read and when it runs out
+type forecastResource struct {
+	// column is the satellite_stats column fitted; limitColumn the registry
+	// column that overrides the configured limit per satellite
+	column      string
+	limitColumn string
+	// falling resources run out when they drop to the limit, others when
+	// they grow to it
+	falling   bool
+	dimension models.AnomalyDimension
+	unit      string
+}
+
+var forecastResources = map[string]forecastResource{
+	ResourceBattery: {
+		column:      "avg_battery",
+		limitColumn: "battery_min_percent",
+		falling:     true,
+		dimension:   models.DimensionBattery,
+		unit:        "percent",
+	},
+	ResourceStorage: {
+		column:      "avg_storage",
+		limitColumn: "storage_max_mb",
+		dimension:   models.DimensionStorage,
+		unit:        "MB",
+	},
+}
+
+// ResourceTrend is a least-squares fit of a resource over a satellite's
+// recent 5-minute buckets
+type ResourceTrend struct {
+	SatelliteID string    `json:"satellite_id"`
+	Samples     int       `json:"samples"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	// SlopePerHour is the fitted change per hour
+	SlopePerHour float64 `json:"slope_per_hour"`
+	// R2 is the goodness of fit; low values mean the trend is mostly noise
+	R2          float64 `json:"r2"`
+	LatestValue float64 `json:"latest_value"`
+	// FittedValue is the trend line at the newest bucket
+	FittedValue float64 `json:"fitted_value"`
+	// RegistryLimit is the limit in the satellite's current registry
+	// version, if any
+	RegistryLimit *float64 `json:"-"`
+}
+
+// ResourceTrendQuerier fits resource trends from stored telemetry
+// This allows for mocking in tests
+type ResourceTrendQuerier interface {
+	ResourceTrends(ctx context.Context, resource string, since time.Time, minSamples int, satelliteID string) ([]ResourceTrend, error)
+}
+
+// ResourceTrends fits a linear trend of the resource per satellite over the
+// 5-minute aggregate since the given time, skipping satellites with fewer
+// samples; satelliteID restricts the fit to one satellite (empty = fleet)
+func (qs *QueryService) ResourceTrends(ctx context.Context, resource string, since time.Time, minSamples int, satelliteID string) ([]ResourceTrend, error) {
+	def, ok := forecastResources[resource]
+	if !ok {
+		return nil, fmt.Errorf("unknown forecast resource %q", resource)
+	}
+	// Hours since the window start keep the intercept small
+	x := `EXTRACT(EPOCH FROM s.bucket - $1) / 3600.0`
+	y := `s.` + def.column + `::double precision`
+	rows, err := qs.query(ctx, `
+		SELECT
+			s.satellite_id, COUNT(*), MIN(s.bucket), MAX(s.bucket),
+			regr_slope(`+y+`, `+x+`),
+			regr_intercept(`+y+`, `+x+`),
+			COALESCE(regr_r2(`+y+`, `+x+`), 0),
+			(array_agg(`+y+` ORDER BY s.bucket DESC))[1],
+			MAX(r.`+def.limitColumn+`)::double precision
+		FROM `+aggregateStats5Min+` s
+		LEFT JOIN satellite_registry_history r
+			ON r.satellite_id = s.satellite_id AND r.valid_to IS NULL
+		WHERE s.bucket >= $1 AND s.`+def.column+` IS NOT NULL
+		  AND ($3 = '' OR s.satellite_id = $3)
+		GROUP BY s.satellite_id
+		HAVING COUNT(*) >= $2
+		ORDER BY s.satellite_id
+	`, since, minSamples, satelliteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trends := make([]ResourceTrend, 0)
+	for rows.Next() {
+		var t ResourceTrend
+		var slope, intercept *float64
+		if err := rows.Scan(&t.SatelliteID, &t.Samples, &t.From, &t.To, &slope, &intercept,
+			&t.R2, &t.LatestValue, &t.RegistryLimit); err != nil {
+			return nil, err
+		}
+		if slope == nil || intercept == nil {
+			continue // all samples in one bucket, no trend to fit
+		}
+		t.SlopePerHour = *slope
+		t.FittedValue = *intercept + *slope*t.To.Sub(since).Hours()
+		trends = append(trends, t)
+	}
+	return trends, rows.Err()
+}
+
+// ForecastConfig configures resource forecasting
+type ForecastConfig struct {
+	// Window is how far back the resource is fitted
+	Window time.Duration
+	// Interval between fleet checks
+	Interval time.Duration
+	// MinSamples is the minimum number of 5-minute buckets needed for a fit
+	MinSamples int
+	// MinR2 ignores fits that explain less of the variance than this
+	MinR2 float64
+	// Limit is where the resource runs out, unless the registry sets one
+	Limit float64
+	// Time to the limit below which a warning or critical alert fires
+	WarningHorizon  time.Duration
+	CriticalHorizon time.Duration
+}
+
+// ResourceForecast projects when a satellite's resource reaches its limit
+type ResourceForecast struct {
+	Resource string `json:"resource"`
+	Unit     string `json:"unit"`
+	ResourceTrend
+	Limit float64 `json:"limit"`
+	// HoursToLimit and LimitAt are nil when the trend moves away from the
+	// limit; HoursToLimit is 0 once it is reached
+	HoursToLimit *float64   `json:"hours_to_limit"`
+	LimitAt      *time.Time `json:"limit_at"`
+}
+
+// Forecaster periodically fits a resource trend per satellite and alerts
+// operations when a satellite is projected to run out within the horizon,
+// e.g. a battery draining towards its minimum charge or onboard storage
+// filling up before the next downlink.
+//
+// Like DecayMonitor, a satellite is alerted when it first comes within a
+// horizon and again only if its severity escalates; it is re-armed once its
+// projection is outside the horizons.
+type Forecaster struct {
+	resource string
+	def      forecastResource
+	querier  ResourceTrendQuerier
+	sink     AnomalyAlertSink
+	cfg      ForecastConfig
+
+	mu       sync.Mutex
+	alerted  map[string]models.AnomalySeverity
+	findings []models.AnomalyEvent
+	// Only the leader replica checks and alerts (nil = always)
+	leadership Leadership
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewBatteryForecaster creates a forecaster of battery charge reaching its
+// minimum; sink may be nil (findings are only logged)
+func NewBatteryForecaster(querier ResourceTrendQuerier, sink AnomalyAlertSink, cfg ForecastConfig) *Forecaster {
+	return newForecaster(ResourceBattery, querier, sink, cfg)
+}
+
+// NewStorageForecaster creates a forecaster of onboard storage filling up
+// to its capacity; sink may be nil (findings are only logged)
+func NewStorageForecaster(querier ResourceTrendQuerier, sink AnomalyAlertSink, cfg ForecastConfig) *Forecaster {
+	return newForecaster(ResourceStorage, querier, sink, cfg)
+}
+
+// newForecaster creates a forecaster; zero values default to a 6 hour
+// window, 5 minute interval and 12 samples
+func newForecaster(resource string, querier ResourceTrendQuerier, sink AnomalyAlertSink, cfg ForecastConfig) *Forecaster {
+	if cfg.Window <= 0 {
+		cfg.Window = 6 * time.Hour
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.MinSamples < 2 {
+		cfg.MinSamples = 12
+	}
+	return &Forecaster{
+		resource: resource,
+		def:      forecastResources[resource],
+		querier:  querier,
+		sink:     sink,
+		cfg:      cfg,
+		alerted:  make(map[string]models.AnomalySeverity),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// SetLeadership makes the forecaster check only while this replica is the
+// leader, so replicas don't send the same alerts; must be called before Start
+func (f *Forecaster) SetLeadership(leadership Leadership) {
+	f.leadership = leadership
+}
+
+// Start runs a check immediately and then every interval until Stop
+func (f *Forecaster) Start() {
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(f.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			if f.leadership == nil || f.leadership.IsLeader() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				if _, err := f.Check(ctx); err != nil {
+					log.Printf("Forecaster(%s): check failed: %v", f.resource, err)
+				}
+				cancel()
+			}
+
+			select {
+			case <-ticker.C:
+			case <-f.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the check loop
+func (f *Forecaster) Stop() {
+	close(f.stopCh)
+	f.wg.Wait()
+}
+
+// Forecast fits one satellite's recent trend; nil when it has too few
+// samples
+func (f *Forecaster) Forecast(ctx context.Context, satelliteID string) (*ResourceForecast, error) {
+	trends, err := f.querier.ResourceTrends(ctx, f.resource, time.Now().Add(-f.cfg.Window), f.cfg.MinSamples, satelliteID)
+	if err != nil {
+		return nil, err
+	}
+	for _, trend := range trends {
+		if trend.SatelliteID == satelliteID {
+			forecast := f.project(trend, time.Now().UTC())
+			return &forecast, nil
+		}
+	}
+	return nil, nil
+}
+
+// ForecastFleet forecasts every satellite with enough recent samples, the
+// soonest to reach its limit first and those moving away from it last
+func (f *Forecaster) ForecastFleet(ctx context.Context) ([]ResourceForecast, error) {
+	trends, err := f.querier.ResourceTrends(ctx, f.resource, time.Now().Add(-f.cfg.Window), f.cfg.MinSamples, "")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	forecasts := make([]ResourceForecast, len(trends))
+	for i, trend := range trends {
+		forecasts[i] = f.project(trend, now)
+	}
+	sort.SliceStable(forecasts, func(i, j int) bool {
+		a, b := forecasts[i].HoursToLimit, forecasts[j].HoursToLimit
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a < *b
+	})
+	return forecasts, nil
+}
+
+// project extends the trend line to the satellite's limit
+func (f *Forecaster) project(trend ResourceTrend, now time.Time) ResourceForecast {
+	forecast := ResourceForecast{
+		Resource:      f.resource,
+		Unit:          f.def.unit,
+		ResourceTrend: trend,
+		Limit:         f.cfg.Limit,
+	}
+	if trend.RegistryLimit != nil {
+		forecast.Limit = *trend.RegistryLimit
+	}
+
+	remaining, rate := forecast.Limit-trend.FittedValue, trend.SlopePerHour
+	if f.def.falling {
+		remaining, rate = -remaining, -rate
+	}
+	limitAt := trend.To
+	switch {
+	case remaining <= 0:
+		// Already at the limit
+	case rate <= 0:
+		return forecast
+	default:
+		// Extended from the newest bucket, which lags the present
+		limitAt = trend.To.Add(time.Duration(remaining / rate * float64(time.Hour)))
+	}
+	hours := max(limitAt.Sub(now).Hours(), 0)
+	forecast.HoursToLimit = &hours
+	forecast.LimitAt = &limitAt
+	return forecast
+}
+
+// evaluate returns a finding when the forecast reaches the limit within a
+// horizon
+func (f *Forecaster) evaluate(forecast ResourceForecast) *models.AnomalyResult {
+	if forecast.HoursToLimit == nil {
+		return nil
+	}
+	hours := *forecast.HoursToLimit
+	if hours > 0 && forecast.R2 < f.cfg.MinR2 {
+		return nil
+	}
+
+	var severity models.AnomalySeverity
+	var horizon time.Duration
+	switch {
+	case f.cfg.CriticalHorizon > 0 && hours <= f.cfg.CriticalHorizon.Hours():
+		severity, horizon = models.SeverityCritical, f.cfg.CriticalHorizon
+	case f.cfg.WarningHorizon > 0 && hours <= f.cfg.WarningHorizon.Hours():
+		severity, horizon = models.SeverityWarning, f.cfg.WarningHorizon
+	default:
+		return nil
+	}
+
+	return &models.AnomalyResult{
+		Detector:  f.resource + "_forecast",
+		Severity:  severity,
+		Dimension: f.def.dimension,
+		Message: fmt.Sprintf("%s projected to reach %.1f %s in %.1fh (%+.2f %s/h over %d samples, R²=%.2f), now %.1f %s",
+			f.resource, forecast.Limit, f.def.unit, hours, forecast.SlopePerHour, f.def.unit,
+			forecast.Samples, forecast.R2, forecast.LatestValue, f.def.unit),
+		Metric:    f.resource + "_hours_to_limit",
+		Value:     hours,
+		Threshold: horizon.Hours(),
+	}
+}
+
+// Check forecasts the fleet and returns every satellite within a horizon.
+// Alerts are sent only for new or escalated findings.
+func (f *Forecaster) Check(ctx context.Context) ([]models.AnomalyEvent, error) {
+	forecasts, err := f.ForecastFleet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	findings := make([]models.AnomalyEvent, 0)
+	var toAlert []models.AnomalyEvent
+
+	f.mu.Lock()
+	outside := make(map[string]bool, len(f.alerted))
+	for id := range f.alerted {
+		outside[id] = true
+	}
+	for _, forecast := range forecasts {
+		result := f.evaluate(forecast)
+		if result == nil {
+			continue
+		}
+		delete(outside, forecast.SatelliteID)
+
+		event := models.AnomalyEvent{SatelliteID: forecast.SatelliteID, Timestamp: now, AnomalyResult: *result}
+		findings = append(findings, event)
+		if previous, ok := f.alerted[forecast.SatelliteID]; !ok || result.Severity.Rank() > previous.Rank() {
+			f.alerted[forecast.SatelliteID] = result.Severity
+			toAlert = append(toAlert, event)
+		}
+	}
+	// Satellites outside the horizons (or without a fit) are re-armed
+	for id := range outside {
+		delete(f.alerted, id)
+	}
+	f.findings = findings
+	f.mu.Unlock()
+
+	for _, event := range toAlert {
+		log.Printf("Forecaster(%s): [%s] %s %s", f.resource, event.Severity, event.SatelliteID, event.Message)
+		if f.sink != nil {
+			f.sink.Alert(event)
+		}
+	}
+	return findings, nil
+}
+
+// Findings returns the satellites within a horizon at the last check
+func (f *Forecaster) Findings() []models.AnomalyEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]models.AnomalyEvent(nil), f.findings...)
+}
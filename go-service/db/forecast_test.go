@@ -0,0 +1,229 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// fakeResourceTrendQuerier returns canned resource trends and records the
+// satellite asked for
+type fakeResourceTrendQuerier struct {
+	trends      []ResourceTrend
+	satelliteID string
+}
+
+func (f *fakeResourceTrendQuerier) ResourceTrends(ctx context.Context, resource string, since time.Time, minSamples int, satelliteID string) ([]ResourceTrend, error) {
+	f.satelliteID = satelliteID
+	return f.trends, nil
+}
+
+func testForecastConfig() ForecastConfig {
+	return ForecastConfig{MinSamples: 12, MinR2: 0.3, Limit: 20, WarningHorizon: 12 * time.Hour, CriticalHorizon: 2 * time.Hour}
+}
+
+// TestForecastProjection tests the time to the limit and its severity
+func TestForecastProjection(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	registryLimit := 40.0
+	tests := []struct {
+		name     string
+		trend    ResourceTrend
+		hours    float64 // -1 = no projection
+		severity models.AnomalySeverity
+	}{
+		{"charging", ResourceTrend{SlopePerHour: 2, FittedValue: 60, R2: 0.9}, -1, ""},
+		{"slow drain", ResourceTrend{SlopePerHour: -1, FittedValue: 60, R2: 0.9}, 40, ""},
+		{"warning drain", ResourceTrend{SlopePerHour: -5, FittedValue: 60, R2: 0.9}, 8, models.SeverityWarning},
+		{"critical drain", ResourceTrend{SlopePerHour: -20, FittedValue: 50, R2: 0.9}, 1.5, models.SeverityCritical},
+		{"noisy drain", ResourceTrend{SlopePerHour: -20, FittedValue: 50, R2: 0.1}, 1.5, ""},
+		{"already empty", ResourceTrend{SlopePerHour: 1, FittedValue: 15, R2: 0.1}, 0, models.SeverityCritical},
+		{"registry limit", ResourceTrend{SlopePerHour: -5, FittedValue: 60, R2: 0.9, RegistryLimit: &registryLimit}, 4, models.SeverityWarning},
+	}
+
+	forecaster := NewBatteryForecaster(&fakeResourceTrendQuerier{}, nil, testForecastConfig())
+	for _, tt := range tests {
+		tt.trend.To = now
+		forecast := forecaster.project(tt.trend, now)
+		if tt.hours < 0 {
+			if forecast.HoursToLimit != nil || forecast.LimitAt != nil {
+				t.Errorf("%s: expected no projection, got %v at %v", tt.name, forecast.HoursToLimit, forecast.LimitAt)
+			}
+		} else if forecast.HoursToLimit == nil || math.Abs(*forecast.HoursToLimit-tt.hours) > 1e-6 {
+			t.Errorf("%s: expected %.1fh to the limit, got %v", tt.name, tt.hours, forecast.HoursToLimit)
+		} else if want := now.Add(time.Duration(tt.hours * float64(time.Hour))); !forecast.LimitAt.Equal(want) {
+			t.Errorf("%s: expected limit at %v, got %v", tt.name, want, forecast.LimitAt)
+		}
+
+		result := forecaster.evaluate(forecast)
+		if tt.severity == "" {
+			if result != nil {
+				t.Errorf("%s: expected no finding, got %+v", tt.name, result)
+			}
+			continue
+		}
+		if result == nil || result.Severity != tt.severity {
+			t.Errorf("%s: expected %s finding, got %+v", tt.name, tt.severity, result)
+			continue
+		}
+		if result.Detector != "battery_forecast" || result.Dimension != models.DimensionBattery {
+			t.Errorf("%s: unexpected classification %+v", tt.name, result)
+		}
+	}
+}
+
+// TestForecastProjectionFromStaleBucket tests that the time to the limit
+// counts from now rather than the newest bucket
+func TestForecastProjectionFromStaleBucket(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	forecaster := NewBatteryForecaster(&fakeResourceTrendQuerier{}, nil, testForecastConfig())
+
+	forecast := forecaster.project(ResourceTrend{SlopePerHour: -10, FittedValue: 40, To: now.Add(-30 * time.Minute)}, now)
+	if forecast.HoursToLimit == nil || *forecast.HoursToLimit != 1.5 {
+		t.Errorf("expected 1.5h to the limit, got %v", forecast.HoursToLimit)
+	}
+}
+
+// TestStorageForecast tests that storage runs out when it grows to its
+// capacity and that the fleet is ordered by time to full
+func TestStorageForecast(t *testing.T) {
+	to := time.Now().UTC()
+	capacity := 50000.0
+	querier := &fakeResourceTrendQuerier{trends: []ResourceTrend{
+		{SatelliteID: "SAT-001", To: to, SlopePerHour: -500, FittedValue: 80000, R2: 0.9},
+		{SatelliteID: "SAT-002", To: to, SlopePerHour: 1000, FittedValue: 80000, R2: 0.9},
+		{SatelliteID: "SAT-003", To: to, SlopePerHour: 1000, FittedValue: 40000, R2: 0.9, RegistryLimit: &capacity},
+		{SatelliteID: "SAT-004", To: to, SlopePerHour: 100, FittedValue: 10000, R2: 0.9},
+	}}
+	forecaster := NewStorageForecaster(querier, nil, ForecastConfig{Limit: 95000, WarningHorizon: 24 * time.Hour, CriticalHorizon: 4 * time.Hour})
+
+	forecasts, err := forecaster.ForecastFleet(context.Background())
+	if err != nil {
+		t.Fatalf("forecast failed: %v", err)
+	}
+	var order []string
+	for _, forecast := range forecasts {
+		order = append(order, forecast.SatelliteID)
+	}
+	if len(order) != 4 || order[0] != "SAT-003" || order[1] != "SAT-002" || order[2] != "SAT-004" || order[3] != "SAT-001" {
+		t.Fatalf("expected the soonest to fill first and the draining last, got %v", order)
+	}
+	if hours := forecasts[0].HoursToLimit; hours == nil || math.Abs(*hours-10) > 0.01 || forecasts[0].Unit != "MB" {
+		t.Errorf("expected SAT-003 full in 10h, got %+v", forecasts[0])
+	}
+
+	findings, err := forecaster.Check(context.Background())
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(findings) != 2 || findings[0].Detector != "storage_forecast" || findings[0].Dimension != models.DimensionStorage {
+		t.Errorf("expected SAT-003 and SAT-002 storage findings, got %+v", findings)
+	}
+}
+
+// TestForecasterAlertsOnNewAndEscalatedFindings tests alert deduplication
+// across checks
+func TestForecasterAlertsOnNewAndEscalatedFindings(t *testing.T) {
+	to := time.Now().UTC()
+	querier := &fakeResourceTrendQuerier{trends: []ResourceTrend{
+		{SatelliteID: "SAT-001", To: to, SlopePerHour: -5, FittedValue: 60, R2: 0.9},
+		{SatelliteID: "SAT-002", To: to, SlopePerHour: 1, FittedValue: 60, R2: 0.9},
+	}}
+	sink := &recordingAlertSink{}
+	forecaster := NewBatteryForecaster(querier, sink, testForecastConfig())
+
+	findings, err := forecaster.Check(context.Background())
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(findings) != 1 || len(sink.events) != 1 || sink.events[0].SatelliteID != "SAT-001" {
+		t.Fatalf("expected one SAT-001 alert, got findings %+v alerts %+v", findings, sink.events)
+	}
+	if querier.satelliteID != "" {
+		t.Errorf("expected a fleet check, got satellite %q", querier.satelliteID)
+	}
+
+	// Same severity again: still a finding, but no new alert
+	if _, err := forecaster.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(sink.events) != 1 {
+		t.Errorf("expected no repeat alert, got %d alerts", len(sink.events))
+	}
+
+	// Escalation alerts again
+	querier.trends[0].SlopePerHour = -30
+	if _, err := forecaster.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(sink.events) != 2 || sink.events[1].Severity != models.SeverityCritical {
+		t.Errorf("expected critical escalation alert, got %+v", sink.events)
+	}
+
+	// Recharging re-arms the satellite
+	querier.trends[0].SlopePerHour = 3
+	if _, err := forecaster.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(forecaster.Findings()) != 0 {
+		t.Errorf("expected no findings, got %+v", forecaster.Findings())
+	}
+	querier.trends[0].SlopePerHour = -5
+	if _, err := forecaster.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(sink.events) != 3 {
+		t.Errorf("expected re-armed satellite to alert again, got %d alerts", len(sink.events))
+	}
+}
+
+// TestResourceTrends tests the regression over 5-minute aggregates
+func TestResourceTrends(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	ctx := context.Background()
+	start := time.Now().UTC().Add(-3 * time.Hour).Truncate(5 * time.Minute)
+	for i := 0; i < 24; i++ {
+		// Draining 6 percent per hour
+		battery := 90.0 - float64(i)*0.5
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO telemetry (time, satellite_id, battery_charge_percent, storage_usage_mb, signal_strength_dbm)
+			VALUES ($1, 'SAT-DRAIN', $2, 1000, -60)`, start.Add(time.Duration(i)*5*time.Minute), battery); err != nil {
+			t.Fatalf("failed to insert telemetry: %v", err)
+		}
+	}
+	if _, err := pool.Exec(ctx, `CALL refresh_continuous_aggregate('satellite_stats', NULL, NULL)`); err != nil {
+		t.Fatalf("failed to refresh aggregate: %v", err)
+	}
+
+	qs := NewQueryService(pool)
+	trends, err := qs.ResourceTrends(ctx, ResourceBattery, start.Add(-time.Minute), 12, "SAT-DRAIN")
+	if err != nil {
+		t.Fatalf("failed to fit trends: %v", err)
+	}
+	if len(trends) != 1 {
+		t.Fatalf("expected 1 trend, got %d", len(trends))
+	}
+	trend := trends[0]
+	if math.Abs(trend.SlopePerHour+6) > 0.01 || math.Abs(trend.FittedValue-78.5) > 0.01 || trend.LatestValue != 78.5 {
+		t.Errorf("expected -6 %%/h ending at 78.5, got %+v", trend)
+	}
+	if trend.RegistryLimit != nil {
+		t.Errorf("expected no registry limit, got %v", *trend.RegistryLimit)
+	}
+
+	if trends, err := qs.ResourceTrends(ctx, ResourceBattery, start.Add(-time.Minute), 30, ""); err != nil || len(trends) != 0 {
+		t.Errorf("expected too few samples to fit, got %+v, %v", trends, err)
+	}
+}
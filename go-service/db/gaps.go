@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"orbitstream/models"
+)
+
+// gapLockClass namespaces the per-satellite advisory locks serializing gap
+// computation (two-key form, so it can't clash with the leader election lock)
+const gapLockClass = 0x67617073
+
+// GapConfig configures telemetry gap detection
+type GapConfig struct {
+	// ExpectedCadence is the interval satellites normally report at
+	ExpectedCadence time.Duration
+	// Tolerance is how many cadences may pass before the silence is a gap
+	Tolerance float64
+}
+
+// GapRecorder stores the gaps in each satellite's telemetry in
+// telemetry_gaps as points are committed, from live ingest and WAL replay
+// alike, so pass coverage can be analyzed after the fact.
+//
+// Gaps are recomputed from the stored points around every committed batch,
+// in its transaction: a late or replayed point filling a gap splits or
+// removes it rather than leaving a stale row. A per-satellite advisory lock
+// makes concurrent flushes of the same satellite see each other's points.
+type GapRecorder struct {
+	minGap time.Duration
+}
+
+// NewGapRecorder creates a recorder; zero values default to a 1 minute
+// cadence and a tolerance of 3
+func NewGapRecorder(cfg GapConfig) *GapRecorder {
+	if cfg.ExpectedCadence <= 0 {
+		cfg.ExpectedCadence = time.Minute
+	}
+	if cfg.Tolerance < 1 {
+		cfg.Tolerance = 3
+	}
+	return &GapRecorder{minGap: time.Duration(float64(cfg.ExpectedCadence) * cfg.Tolerance)}
+}
+
+// SetGapRecorder records telemetry gaps with every committed batch
+func (bp *BatchProcessor) SetGapRecorder(recorder *GapRecorder) {
+	bp.gaps = recorder
+}
+
+// GetGapRecorder returns the gap recorder, if any
+func (bp *BatchProcessor) GetGapRecorder() *GapRecorder {
+	return bp.gaps
+}
+
+// MinGap is the shortest silence stored as a gap
+func (r *GapRecorder) MinGap() time.Duration {
+	return r.minGap
+}
+
+// satelliteSpan is the time range a batch covers for one satellite
+type satelliteSpan struct {
+	satelliteID string
+	from, to    time.Time
+}
+
+// batchSpans returns the range of each satellite's points, ordered by
+// satellite ID so locks are always taken in the same order
+func batchSpans(points []models.TelemetryPoint) []satelliteSpan {
+	index := make(map[string]int)
+	var spans []satelliteSpan
+	for i := range points {
+		point := &points[i]
+		j, ok := index[point.SatelliteID]
+		if !ok {
+			index[point.SatelliteID] = len(spans)
+			spans = append(spans, satelliteSpan{satelliteID: point.SatelliteID, from: point.Timestamp, to: point.Timestamp})
+			continue
+		}
+		if point.Timestamp.Before(spans[j].from) {
+			spans[j].from = point.Timestamp
+		}
+		if point.Timestamp.After(spans[j].to) {
+			spans[j].to = point.Timestamp
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].satelliteID < spans[j].satelliteID })
+	return spans
+}
+
+// gapBounds widens a span to the stored points just before and after it,
+// the only points whose gaps the batch can change
+const gapBounds = `
+	WITH bounds AS (
+		SELECT
+			COALESCE((SELECT MAX(time) FROM telemetry WHERE satellite_id = $1 AND time < $2), $2) AS lo,
+			COALESCE((SELECT MIN(time) FROM telemetry WHERE satellite_id = $1 AND time > $3), $3) AS hi
+	)`
+
+// recordGaps recomputes the gaps around the committed points in the
+// transaction that stores them
+func (r *GapRecorder) recordGaps(ctx context.Context, tx pgx.Tx, points []models.TelemetryPoint) error {
+	if r == nil || len(points) == 0 {
+		return nil
+	}
+	spans := batchSpans(points)
+
+	batch := &pgx.Batch{}
+	for _, span := range spans {
+		batch.Queue(`SELECT pg_advisory_xact_lock($1, hashtext($2))`, gapLockClass, span.satelliteID)
+	}
+	for _, span := range spans {
+		batch.Queue(gapBounds+`
+			DELETE FROM telemetry_gaps g USING bounds b
+			WHERE g.satellite_id = $1 AND g.gap_start < b.hi AND g.gap_end > b.lo`,
+			span.satelliteID, span.from, span.to)
+		batch.Queue(gapBounds+`
+			INSERT INTO telemetry_gaps (satellite_id, gap_start, gap_end, duration_seconds)
+			SELECT $1, prev, time, EXTRACT(EPOCH FROM time - prev)
+			FROM (
+				SELECT t.time, LAG(t.time) OVER (ORDER BY t.time) AS prev
+				FROM telemetry t, bounds b
+				WHERE t.satellite_id = $1 AND t.time BETWEEN b.lo AND b.hi
+			) s
+			WHERE EXTRACT(EPOCH FROM time - prev) > $4`,
+			span.satelliteID, span.from, span.to, r.minGap.Seconds())
+	}
+
+	_, err := sendBatch(ctx, tx, batch)
+	return err
+}
+
+// GapQuery selects the gaps overlapping a time range
+type GapQuery struct {
+	// SatelliteID restricts the query to one satellite (empty = fleet)
+	SatelliteID string
+	From        time.Time
+	To          time.Time
+	// MinDuration skips shorter gaps
+	MinDuration time.Duration
+	Limit       int
+}
+
+// QueryGaps returns the stored gaps overlapping the range, oldest first
+func (qs *QueryService) QueryGaps(ctx context.Context, q GapQuery) ([]models.TelemetryGap, error) {
+	rows, err := qs.query(ctx, `
+		SELECT satellite_id, gap_start, gap_end, duration_seconds
+		FROM telemetry_gaps
+		WHERE ($1 = '' OR satellite_id = $1)
+		  AND gap_end > $2 AND gap_start < $3
+		  AND duration_seconds >= $4
+		ORDER BY gap_start, satellite_id
+		LIMIT $5
+	`, q.SatelliteID, q.From, q.To, q.MinDuration.Seconds(), q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gaps := make([]models.TelemetryGap, 0)
+	for rows.Next() {
+		var gap models.TelemetryGap
+		if err := rows.Scan(&gap.SatelliteID, &gap.Start, &gap.End, &gap.DurationSeconds); err != nil {
+			return nil, err
+		}
+		gaps = append(gaps, gap)
+	}
+	return gaps, rows.Err()
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+func TestBatchSpans(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	points := []models.TelemetryPoint{
+		{SatelliteID: "SAT-002", Timestamp: start.Add(time.Minute)},
+		{SatelliteID: "SAT-001", Timestamp: start.Add(5 * time.Minute)},
+		{SatelliteID: "SAT-002", Timestamp: start},
+		{SatelliteID: "SAT-001", Timestamp: start.Add(2 * time.Minute)},
+		{SatelliteID: "SAT-001", Timestamp: start.Add(9 * time.Minute)},
+	}
+
+	spans := batchSpans(points)
+	want := []satelliteSpan{
+		{satelliteID: "SAT-001", from: start.Add(2 * time.Minute), to: start.Add(9 * time.Minute)},
+		{satelliteID: "SAT-002", from: start, to: start.Add(time.Minute)},
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("expected %d spans, got %+v", len(want), spans)
+	}
+	for i := range want {
+		if spans[i] != want[i] {
+			t.Errorf("span %d: expected %+v, got %+v", i, want[i], spans[i])
+		}
+	}
+
+	if recorder := NewGapRecorder(GapConfig{ExpectedCadence: 10 * time.Second, Tolerance: 3}); recorder.MinGap() != 30*time.Second {
+		t.Errorf("expected a 30s minimum gap, got %v", recorder.MinGap())
+	}
+}
+
+// TestRecordGaps tests that committed points record gaps and that a late
+// point filling a gap splits it
+func TestRecordGaps(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	ctx := context.Background()
+	recorder := NewGapRecorder(GapConfig{ExpectedCadence: time.Minute, Tolerance: 3})
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	commit := func(offsets ...time.Duration) {
+		t.Helper()
+		points := make([]models.TelemetryPoint, len(offsets))
+		for i, offset := range offsets {
+			points[i] = models.TelemetryPoint{SatelliteID: "SAT-GAP", Timestamp: start.Add(offset), BatteryChargePercent: 80}
+		}
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatalf("begin failed: %v", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		if _, err := insertTelemetry(ctx, tx, points); err != nil {
+			t.Fatalf("insert failed: %v", err)
+		}
+		if err := recorder.recordGaps(ctx, tx, points); err != nil {
+			t.Fatalf("recording gaps failed: %v", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatalf("commit failed: %v", err)
+		}
+	}
+	gaps := func() []models.TelemetryGap {
+		t.Helper()
+		gaps, err := NewQueryService(pool).QueryGaps(ctx, GapQuery{From: start, To: start.Add(time.Hour), Limit: 100})
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		return gaps
+	}
+
+	// A 20 minute silence between two batches
+	commit(0, time.Minute, 2*time.Minute)
+	commit(22*time.Minute, 23*time.Minute)
+	got := gaps()
+	if len(got) != 1 || !got[0].Start.Equal(start.Add(2*time.Minute)) || got[0].DurationSeconds != 1200 {
+		t.Fatalf("expected one 20 minute gap, got %+v", got)
+	}
+
+	// A late point in the middle splits it
+	commit(12 * time.Minute)
+	got = gaps()
+	if len(got) != 2 || got[0].DurationSeconds != 600 || got[1].DurationSeconds != 600 {
+		t.Errorf("expected two 10 minute gaps, got %+v", got)
+	}
+}
@@ -0,0 +1,281 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+)
+
+// GeofenceDetectorName identifies zone entry and exit alerts
+const GeofenceDetectorName = "geofence"
+
+// GeofenceBox is a latitude/longitude bounding box; MinLon > MaxLon spans
+// the antimeridian
+type GeofenceBox struct {
+	MinLat float64 `json:"min_lat"`
+	MaxLat float64 `json:"max_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+// Geofence is a named zone: a bounding box or polygon over the ground
+// track, an altitude band, or both
+type Geofence struct {
+	Name string `json:"name"`
+	// BBox and Polygon ([lat, lon] vertices) restrict the ground track;
+	// polygons must not cross the antimeridian. Neither means anywhere.
+	BBox    *GeofenceBox `json:"bbox,omitempty"`
+	Polygon [][2]float64 `json:"polygon,omitempty"`
+	// MinAltitudeKM and MaxAltitudeKM bound the altitude (nil = unbounded)
+	MinAltitudeKM *float64 `json:"min_altitude_km,omitempty"`
+	MaxAltitudeKM *float64 `json:"max_altitude_km,omitempty"`
+	// Satellites restricts the zone to matching IDs (path.Match syntax)
+	Satellites string `json:"satellites,omitempty"`
+	// Severity of the entry alert (default info)
+	Severity models.AnomalySeverity `json:"severity,omitempty"`
+}
+
+// Contains reports whether a position lies inside the zone
+func (g Geofence) Contains(lat, lon, altitudeKM float64) bool {
+	if g.MinAltitudeKM != nil && altitudeKM < *g.MinAltitudeKM {
+		return false
+	}
+	if g.MaxAltitudeKM != nil && altitudeKM > *g.MaxAltitudeKM {
+		return false
+	}
+	if g.BBox != nil && !g.BBox.contains(lat, lon) {
+		return false
+	}
+	if len(g.Polygon) > 0 && !polygonContains(g.Polygon, lat, lon) {
+		return false
+	}
+	return true
+}
+
+func (b GeofenceBox) contains(lat, lon float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return lon >= b.MinLon && lon <= b.MaxLon
+	}
+	return lon >= b.MinLon || lon <= b.MaxLon
+}
+
+// polygonContains is an even-odd ray cast with longitude as x
+func polygonContains(polygon [][2]float64, lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		latI, lonI := polygon[i][0], polygon[i][1]
+		latJ, lonJ := polygon[j][0], polygon[j][1]
+		if (latI > lat) != (latJ > lat) && lon < (lonJ-lonI)*(lat-latI)/(latJ-latI)+lonI {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// validate checks the zone definition
+func (g Geofence) validate() error {
+	if g.Name == "" {
+		return fmt.Errorf("zone name is required")
+	}
+	if strings.Contains(g.Name, ",") {
+		return fmt.Errorf("zone %s: name must not contain commas", g.Name)
+	}
+	if g.BBox == nil && len(g.Polygon) == 0 && g.MinAltitudeKM == nil && g.MaxAltitudeKM == nil {
+		return fmt.Errorf("zone %s: needs a bbox, polygon or altitude band", g.Name)
+	}
+	if g.BBox != nil && (g.BBox.MinLat > g.BBox.MaxLat || g.BBox.MinLat < -90 || g.BBox.MaxLat > 90) {
+		return fmt.Errorf("zone %s: bbox latitudes must be ordered within [-90, 90]", g.Name)
+	}
+	if len(g.Polygon) > 0 && len(g.Polygon) < 3 {
+		return fmt.Errorf("zone %s: polygon needs at least 3 vertices", g.Name)
+	}
+	if g.MinAltitudeKM != nil && g.MaxAltitudeKM != nil && *g.MinAltitudeKM > *g.MaxAltitudeKM {
+		return fmt.Errorf("zone %s: min_altitude_km exceeds max_altitude_km", g.Name)
+	}
+	if _, err := path.Match(g.Satellites, ""); err != nil {
+		return fmt.Errorf("zone %s: invalid satellites pattern %q", g.Name, g.Satellites)
+	}
+	if g.Severity != "" && !g.Severity.Valid() {
+		return fmt.Errorf("zone %s: unknown severity %q", g.Name, g.Severity)
+	}
+	return nil
+}
+
+// LoadGeofences reads a JSON array of zones
+func LoadGeofences(file string) ([]Geofence, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var zones []Geofence
+	if err := decoder.Decode(&zones); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	seen := make(map[string]bool)
+	for _, zone := range zones {
+		if err := zone.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if seen[zone.Name] {
+			return nil, fmt.Errorf("%s: zone %s is defined twice", file, zone.Name)
+		}
+		seen[zone.Name] = true
+	}
+	return zones, nil
+}
+
+// GeofenceMonitor tracks which zones each satellite's reported position is
+// in. Points are tagged with their zones (geofence_zones) and an alert is
+// sent when a satellite enters a zone, with a recovery when it leaves, e.g.
+// to know when a satellite passes over a ground station or a restricted
+// region. Points without a full position leave a satellite's zones as they
+// were, and points older than the satellite's newest don't move it.
+type GeofenceMonitor struct {
+	zones []Geofence
+
+	mu         sync.Mutex
+	satellites map[string]*geofenceState
+}
+
+// geofenceState is the zones a satellite was in at its newest point
+type geofenceState struct {
+	at    time.Time
+	zones map[string]bool
+}
+
+// GeofenceOccupancy is a zone and the satellites currently in it
+type GeofenceOccupancy struct {
+	Geofence
+	Satellites []string `json:"satellites_inside"`
+}
+
+// NewGeofenceMonitor creates a monitor for the given zones
+func NewGeofenceMonitor(zones []Geofence) *GeofenceMonitor {
+	return &GeofenceMonitor{
+		zones:      zones,
+		satellites: make(map[string]*geofenceState),
+	}
+}
+
+// SetGeofenceMonitor tags points with their zones and alerts on entries and
+// exits
+func (bp *BatchProcessor) SetGeofenceMonitor(monitor *GeofenceMonitor) {
+	bp.geofences = monitor
+}
+
+// observe tags the point with its zones and returns the entry and exit
+// events it causes. Zones sent by the client are always discarded.
+func (m *GeofenceMonitor) observe(point *models.TelemetryPoint) []models.AnomalyEvent {
+	point.GeofenceZones = nil
+	if m == nil || point.Latitude == nil || point.Longitude == nil || point.AltitudeKM == nil {
+		return nil
+	}
+
+	inside := make(map[string]bool)
+	var names []string
+	for _, zone := range m.zones {
+		if zone.Satellites != "" {
+			if ok, _ := path.Match(zone.Satellites, point.SatelliteID); !ok {
+				continue
+			}
+		}
+		if zone.Contains(*point.Latitude, *point.Longitude, *point.AltitudeKM) {
+			inside[zone.Name] = true
+			names = append(names, zone.Name)
+		}
+	}
+	if len(names) > 0 {
+		tagged := strings.Join(names, ",")
+		point.GeofenceZones = &tagged
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.satellites[point.SatelliteID]
+	if !ok {
+		state = &geofenceState{zones: make(map[string]bool)}
+		m.satellites[point.SatelliteID] = state
+	} else if point.Timestamp.Before(state.at) {
+		return nil
+	}
+	state.at = point.Timestamp
+
+	var events []models.AnomalyEvent
+	for _, zone := range m.zones {
+		switch {
+		case inside[zone.Name] && !state.zones[zone.Name]:
+			state.zones[zone.Name] = true
+			events = append(events, geofenceEvent(point, zone, false))
+		case !inside[zone.Name] && state.zones[zone.Name]:
+			delete(state.zones, zone.Name)
+			events = append(events, geofenceEvent(point, zone, true))
+		}
+	}
+	return events
+}
+
+func geofenceEvent(point *models.TelemetryPoint, zone Geofence, exited bool) models.AnomalyEvent {
+	severity := zone.Severity
+	if severity == "" {
+		severity = models.SeverityInfo
+	}
+	verb := "entered"
+	if exited {
+		verb = "left"
+	}
+	return models.AnomalyEvent{
+		SatelliteID: point.SatelliteID,
+		Timestamp:   point.Timestamp,
+		Recovered:   exited,
+		AnomalyResult: models.AnomalyResult{
+			Detector:  GeofenceDetectorName,
+			Severity:  severity,
+			Dimension: models.DimensionPosition,
+			Message: fmt.Sprintf("%s zone %s at %.4f, %.4f, %.1f km",
+				verb, zone.Name, *point.Latitude, *point.Longitude, *point.AltitudeKM),
+			Metric: "zone:" + zone.Name,
+			Value:  *point.AltitudeKM,
+		},
+	}
+}
+
+// forgetSatellite drops the zones of a satellite
+func (m *GeofenceMonitor) forgetSatellite(satelliteID string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.satellites, satelliteID)
+}
+
+// Occupancy returns every zone with the satellites currently inside it
+func (m *GeofenceMonitor) Occupancy() []GeofenceOccupancy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	occupancy := make([]GeofenceOccupancy, len(m.zones))
+	for i, zone := range m.zones {
+		occupancy[i] = GeofenceOccupancy{Geofence: zone, Satellites: make([]string, 0)}
+		for satelliteID, state := range m.satellites {
+			if state.zones[zone.Name] {
+				occupancy[i].Satellites = append(occupancy[i].Satellites, satelliteID)
+			}
+		}
+		sort.Strings(occupancy[i].Satellites)
+	}
+	return occupancy
+}
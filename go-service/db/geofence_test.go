@@ -0,0 +1,124 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+func positionedPoint(satelliteID string, at time.Time, lat, lon, alt float64) models.TelemetryPoint {
+	return models.TelemetryPoint{SatelliteID: satelliteID, Timestamp: at, Latitude: &lat, Longitude: &lon, AltitudeKM: &alt}
+}
+
+func TestGeofenceContains(t *testing.T) {
+	low := 300.0
+	tests := []struct {
+		name          string
+		zone          Geofence
+		lat, lon, alt float64
+		want          bool
+	}{
+		{"inside bbox", Geofence{BBox: &GeofenceBox{MinLat: 70, MaxLat: 80, MinLon: 10, MaxLon: 20}}, 78, 15, 500, true},
+		{"outside bbox", Geofence{BBox: &GeofenceBox{MinLat: 70, MaxLat: 80, MinLon: 10, MaxLon: 20}}, 60, 15, 500, false},
+		{"bbox across the antimeridian", Geofence{BBox: &GeofenceBox{MinLat: -10, MaxLat: 10, MinLon: 170, MaxLon: -170}}, 0, -175, 500, true},
+		{"inside triangle", Geofence{Polygon: [][2]float64{{0, 0}, {10, 0}, {0, 10}}}, 2, 2, 500, true},
+		{"outside triangle", Geofence{Polygon: [][2]float64{{0, 0}, {10, 0}, {0, 10}}}, 8, 8, 500, false},
+		{"below altitude band", Geofence{MinAltitudeKM: &low}, 0, 0, 250, false},
+		{"above altitude band floor", Geofence{MinAltitudeKM: &low}, 0, 0, 350, true},
+	}
+	for _, tt := range tests {
+		if got := tt.zone.Contains(tt.lat, tt.lon, tt.alt); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestGeofenceMonitorEntryAndExit(t *testing.T) {
+	monitor := NewGeofenceMonitor([]Geofence{
+		{Name: "svalbard_gs", BBox: &GeofenceBox{MinLat: 70, MaxLat: 82, MinLon: 0, MaxLon: 35}},
+		{Name: "restricted", BBox: &GeofenceBox{MinLat: 75, MaxLat: 90, MinLon: -180, MaxLon: 180}, Satellites: "SAT-1*", Severity: models.SeverityCritical},
+	})
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	outside := positionedPoint("SAT-001", start, 10, 10, 500)
+	if events := monitor.observe(&outside); len(events) != 0 || outside.GeofenceZones != nil {
+		t.Fatalf("expected no zones, got %+v %v", events, outside.GeofenceZones)
+	}
+
+	// restricted only applies to SAT-1*
+	inside := positionedPoint("SAT-001", start.Add(time.Minute), 78, 15, 500)
+	events := monitor.observe(&inside)
+	if len(events) != 1 || events[0].Metric != "zone:svalbard_gs" || events[0].Severity != models.SeverityInfo || events[0].Recovered {
+		t.Fatalf("expected a svalbard_gs entry, got %+v", events)
+	}
+	if inside.GeofenceZones == nil || *inside.GeofenceZones != "svalbard_gs" {
+		t.Errorf("expected the point to be tagged, got %v", inside.GeofenceZones)
+	}
+
+	// Still inside: no repeat; an older point doesn't move the satellite
+	again := positionedPoint("SAT-001", start.Add(2*time.Minute), 79, 16, 500)
+	if events := monitor.observe(&again); len(events) != 0 {
+		t.Errorf("expected no repeat entry, got %+v", events)
+	}
+	stale := positionedPoint("SAT-001", start, 10, 10, 500)
+	if events := monitor.observe(&stale); len(events) != 0 {
+		t.Errorf("expected an out-of-order point to be ignored, got %+v", events)
+	}
+
+	left := positionedPoint("SAT-001", start.Add(3*time.Minute), 10, 10, 500)
+	if events := monitor.observe(&left); len(events) != 1 || !events[0].Recovered || events[0].Detector != GeofenceDetectorName {
+		t.Errorf("expected a svalbard_gs exit, got %+v", events)
+	}
+
+	critical := positionedPoint("SAT-100", start, 78, 15, 500)
+	events = monitor.observe(&critical)
+	if len(events) != 2 || events[1].Severity != models.SeverityCritical || *critical.GeofenceZones != "svalbard_gs,restricted" {
+		t.Errorf("expected entries into both zones, got %+v", events)
+	}
+	occupancy := monitor.Occupancy()
+	if len(occupancy) != 2 || len(occupancy[0].Satellites) != 1 || occupancy[0].Satellites[0] != "SAT-100" {
+		t.Errorf("unexpected occupancy %+v", occupancy)
+	}
+
+	// Clients can't forge zones, even without a position
+	zones := "restricted"
+	forged := models.TelemetryPoint{SatelliteID: "SAT-001", GeofenceZones: &zones}
+	var disabled *GeofenceMonitor
+	if disabled.observe(&forged); forged.GeofenceZones != nil {
+		t.Errorf("expected client zones to be discarded")
+	}
+}
+
+func TestLoadGeofences(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		file := filepath.Join(dir, "zones.json")
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	zones, err := LoadGeofences(write(`[
+		{"name": "svalbard_gs", "bbox": {"min_lat": 70, "max_lat": 82, "min_lon": 0, "max_lon": 35}},
+		{"name": "leo_band", "min_altitude_km": 160, "max_altitude_km": 2000, "severity": "warning"}
+	]`))
+	if err != nil || len(zones) != 2 || zones[1].Severity != models.SeverityWarning {
+		t.Fatalf("expected 2 zones, got %+v, %v", zones, err)
+	}
+
+	for content, problem := range map[string]string{
+		`[{"name": "nowhere"}]`:                                                      "needs a bbox, polygon or altitude band",
+		`[{"name": "line", "polygon": [[0, 0], [1, 1]]}]`:                            "at least 3 vertices",
+		`[{"name": "a", "min_altitude_km": 1}, {"name": "a", "min_altitude_km": 2}]`: "defined twice",
+		`[{"name": "a", "radius": 5}]`:                                               "unknown field",
+	} {
+		if _, err := LoadGeofences(write(content)); err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected %q for %s, got %v", problem, content, err)
+		}
+	}
+}
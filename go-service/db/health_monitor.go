@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -25,6 +27,27 @@ type HealthMonitor struct {
 	healthMutex     sync.RWMutex
 	lastCheckTime   time.Time
 	lastCheckResult error
+	cacheMaxAge     time.Duration
+	probeMutex      sync.Mutex
+	replayMutex     sync.Mutex
+	replay          ReplayStatus
+	// Replay pacing: records per second (0 = unpaced) in batches of
+	// replayBatchSize
+	replayRate      int
+	replayBatchSize int
+	// Connection pools sampled on every check, see WatchPool
+	poolMutex sync.Mutex
+	pools     []*watchedPool
+	// Subscribers to database state changes, guarded by healthMutex
+	healthyHooks   []func()
+	unhealthyHooks []func(err error)
+}
+
+// DatabaseHealth is the result of a database connectivity check
+type DatabaseHealth struct {
+	Healthy   bool
+	CheckedAt time.Time
+	Err       error
 }
 
 // NewHealthMonitor creates a new health monitor
@@ -33,12 +56,14 @@ type HealthMonitor struct {
 // batchProcessor: batch processor to use for replaying records
 func NewHealthMonitor(pool *pgxpool.Pool, wal *WAL, batchProcessor *BatchProcessor) *HealthMonitor {
 	return &HealthMonitor{
-		pool:           pool,
-		checkInterval:  5 * time.Second,
-		wal:            wal,
-		batchProcessor: batchProcessor,
-		stopCh:         make(chan struct{}),
-		isHealthy:      false, // Will be determined on first check
+		pool:            pool,
+		checkInterval:   5 * time.Second,
+		wal:             wal,
+		batchProcessor:  batchProcessor,
+		stopCh:          make(chan struct{}),
+		isHealthy:       false, // Will be determined on first check
+		cacheMaxAge:     10 * time.Second,
+		replayBatchSize: 1000,
 	}
 }
 
@@ -47,6 +72,42 @@ func (hm *HealthMonitor) SetCheckInterval(interval time.Duration) {
 	hm.checkInterval = interval
 }
 
+// SetReplayRate paces WAL replay to at most recordsPerSecond (0 =
+// unpaced), so catching up after a long outage leaves connections and
+// database capacity to live ingest; batchSize records are inserted per
+// transaction, capped at the rate so each batch takes at most a second.
+// Must be called before Start.
+func (hm *HealthMonitor) SetReplayRate(recordsPerSecond, batchSize int) {
+	hm.replayRate = max(recordsPerSecond, 0)
+	if batchSize > 0 {
+		hm.replayBatchSize = batchSize
+	}
+}
+
+// OnHealthy registers a hook called when the database becomes reachable,
+// including on the first successful check. Hooks run in order on the
+// checking goroutine after the monitor's lock is released and must not
+// block or call Database.
+func (hm *HealthMonitor) OnHealthy(hook func()) {
+	hm.healthMutex.Lock()
+	defer hm.healthMutex.Unlock()
+	hm.healthyHooks = append(hm.healthyHooks, hook)
+}
+
+// OnUnhealthy registers a hook called with the ping error when the database
+// becomes unreachable, including on a failed first check; like OnHealthy
+// hooks it must not block
+func (hm *HealthMonitor) OnUnhealthy(hook func(err error)) {
+	hm.healthMutex.Lock()
+	defer hm.healthMutex.Unlock()
+	hm.unhealthyHooks = append(hm.unhealthyHooks, hook)
+}
+
+// SetCacheMaxAge sets how old a cached check may be before Database probes again
+func (hm *HealthMonitor) SetCacheMaxAge(maxAge time.Duration) {
+	hm.cacheMaxAge = maxAge
+}
+
 // Start begins the health monitoring loop
 // It runs in a separate goroutine and periodically checks database connectivity
 func (hm *HealthMonitor) Start() {
@@ -68,6 +129,8 @@ func (hm *HealthMonitor) Stop() {
 // It runs health checks at regular intervals until stopped
 func (hm *HealthMonitor) monitorLoop() {
 	defer hm.wg.Done()
+	// A panic in a replay would otherwise lose the processor's buffer
+	defer hm.batchProcessor.recoverCrash()
 
 	ticker := time.NewTicker(hm.checkInterval)
 	defer ticker.Stop()
@@ -88,75 +151,155 @@ func (hm *HealthMonitor) checkHealth() {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
+	hm.samplePools()
+	// Serialized with on-demand probes from Database, so one state change
+	// fires its hooks once
+	hm.probeMutex.Lock()
+	wasHealthy, err := hm.probe(ctx)
+	hm.probeMutex.Unlock()
+	if err != nil {
+		return
+	}
+
+	// Database just recovered or still has WAL records left from an earlier
+	// attempt: replay once per check. A replay still running from a previous
+	// check (or started from /admin/wal/replay) makes this a no-op.
+	if !wasHealthy {
+		log.Println("HealthMonitor: Database recovered, replaying WAL")
+	}
+	hm.replayWAL()
+}
+
+// probe pings the database and records the result, returning whether the
+// database was healthy before and the ping error
+// Caller must hold probeMutex
+func (hm *HealthMonitor) probe(ctx context.Context) (bool, error) {
 	err := hm.pool.Ping(ctx)
 
 	hm.healthMutex.Lock()
+	firstCheck := hm.lastCheckTime.IsZero()
 	hm.lastCheckTime = time.Now()
 	hm.lastCheckResult = err
 	wasHealthy := hm.isHealthy
 	hm.isHealthy = (err == nil)
+	healthyHooks, unhealthyHooks := hm.healthyHooks, hm.unhealthyHooks
 	hm.healthMutex.Unlock()
 
-	// Log state changes
+	// Log state changes and notify subscribers
 	if err == nil && !wasHealthy {
 		log.Println("HealthMonitor: Database is now HEALTHY ✓")
-		// Database just recovered, replay WAL
-		hm.replayWAL()
-	} else if err != nil && wasHealthy {
+		for _, hook := range healthyHooks {
+			hook()
+		}
+	} else if err != nil && (wasHealthy || firstCheck) {
 		log.Printf("HealthMonitor: Database is now UNHEALTHY ✗ (error: %v)", err)
+		for _, hook := range unhealthyHooks {
+			hook(err)
+		}
+	}
+
+	return wasHealthy, err
+}
+
+// Database returns the last check result, pinging the database only when
+// that result is older than the cache max age. Concurrent callers share one
+// ping, so frequent load balancer probes do not turn into a ping storm.
+func (hm *HealthMonitor) Database(ctx context.Context) DatabaseHealth {
+	if health, fresh := hm.cachedDatabase(); fresh || hm.pool == nil {
+		return health
 	}
 
-	// If database is healthy and has WAL records, try replay
-	if err == nil {
-		hm.replayWAL()
+	hm.probeMutex.Lock()
+	defer hm.probeMutex.Unlock()
+
+	// Another caller may have refreshed the result while we waited
+	if health, fresh := hm.cachedDatabase(); fresh {
+		return health
 	}
+	hm.probe(ctx)
+	health, _ := hm.cachedDatabase()
+	return health
+}
+
+// cachedDatabase returns the last check result and whether it is still fresh
+func (hm *HealthMonitor) cachedDatabase() (DatabaseHealth, bool) {
+	hm.healthMutex.RLock()
+	defer hm.healthMutex.RUnlock()
+
+	health := DatabaseHealth{
+		Healthy:   hm.isHealthy,
+		CheckedAt: hm.lastCheckTime,
+		Err:       hm.lastCheckResult,
+	}
+	fresh := !hm.lastCheckTime.IsZero() && time.Since(hm.lastCheckTime) <= hm.cacheMaxAge
+	return health, fresh
 }
 
 // replayWAL replays all records from the WAL to the database
-// It replays records in batches for efficiency
-// If replay fails, it will be retried on the next health check
+// Sealed segments are streamed oldest first in batches and deleted once
+// committed, so memory stays bounded regardless of WAL size.
+// If replay fails, the next health check resumes after the last committed
+// batch (tracked by the WAL's replay cursor), so nothing is inserted twice
 func (hm *HealthMonitor) replayWAL() {
-	records, err := hm.wal.ReadAll()
-	if err != nil {
-		log.Printf("HealthMonitor: Failed to read WAL: %v", err)
+	replayed, err := hm.Replay()
+	if errors.Is(err, ErrReplayInProgress) {
 		return
 	}
-
-	if len(records) == 0 {
+	if err != nil {
+		// Uncommitted records are kept - will retry on next check
+		log.Printf("HealthMonitor: Failed to replay WAL after %d records: %v", replayed, err)
 		return
 	}
 
-	log.Printf("HealthMonitor: Replaying %d records from WAL", len(records))
-
-	// Replay in batches of 1000 to avoid overwhelming the database
-	batchSize := 1000
-	successCount := 0
+	if replayed > 0 {
+		log.Printf("HealthMonitor: Successfully replayed and deleted %d WAL records", replayed)
+	}
+}
 
-	for i := 0; i < len(records); i += batchSize {
-		end := i + batchSize
-		if end > len(records) {
-			end = len(records)
-		}
+// replaySegments streams sealed WAL segments to the database
+func (hm *HealthMonitor) replaySegments() (int, error) {
+	// Replay in batches to avoid overwhelming the database
+	batchSize := hm.replayBatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	if hm.replayRate > 0 {
+		batchSize = min(batchSize, hm.replayRate)
+	}
 
-		batch := records[i:end]
-		if err := hm.insertWALRecords(batch); err != nil {
-			log.Printf("HealthMonitor: Failed to replay WAL batch %d-%d: %v", i, end, err)
-			// Don't clear WAL - will retry on next check
-			return
+	batches := 0
+	replayed := 0
+	started := time.Now()
+	return hm.wal.ReplaySegments(batchSize, func(records []WALRecord) error {
+		batches++
+		if err := hm.insertWALRecords(records); err != nil {
+			return fmt.Errorf("batch %d (%d records): %w", batches, len(records), err)
 		}
+		replayed += len(records)
+		return hm.paceReplay(started, replayed)
+	})
+}
 
-		successCount += len(batch)
-		log.Printf("HealthMonitor: Replayed batch %d-%d (%d/%d records)",
-			i, end, successCount, len(records))
+// paceReplay waits until replaying replayed records since started keeps
+// within the replay rate; Stop cuts the wait short and ends the replay, which
+// resumes from its cursor on the next start
+func (hm *HealthMonitor) paceReplay(started time.Time, replayed int) error {
+	if hm.replayRate <= 0 {
+		return nil
 	}
-
-	// All records successfully replayed, clear WAL
-	if err := hm.wal.Clear(); err != nil {
-		log.Printf("HealthMonitor: Failed to clear WAL after replay: %v", err)
-		return
+	due := started.Add(time.Duration(float64(replayed) / float64(hm.replayRate) * float64(time.Second)))
+	wait := time.Until(due)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-hm.stopCh:
+		return errReplayStopped
 	}
-
-	log.Printf("HealthMonitor: Successfully replayed and cleared %d WAL records", successCount)
 }
 
 // insertWALRecords inserts a batch of WAL records into the database
@@ -170,39 +313,84 @@ func (hm *HealthMonitor) insertWALRecords(records []WALRecord) error {
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	stmt := `
-		INSERT INTO telemetry (
-			time, satellite_id, battery_charge_percent,
-			storage_usage_mb, signal_strength_dbm, is_anomaly,
-			latitude, longitude, altitude_km, velocity_kmph
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`
-
+	points := getPointBatch(len(records))
 	for _, record := range records {
-		_, err := tx.Exec(ctx, stmt,
-			record.Timestamp,
-			record.SatelliteID,
-			record.BatteryChargePercent,
-			record.StorageUsageMB,
-			record.SignalStrengthDBM,
-			record.IsAnomaly,
-			record.Latitude,
-			record.Longitude,
-			record.AltitudeKM,
-			record.VelocityKMPH,
-		)
+		points = append(points, record.TelemetryPoint())
+	}
+	defer putPointBatch(points)
+	// Records inserted before an interrupted replay are skipped, not duplicated
+	inserted, err := insertTelemetry(ctx, tx, points)
+	if err != nil {
+		dlq := hm.deadLetterQueue()
+		if dlq == nil || !isPermanentInsertError(err) {
+			return err
+		}
+		// A bad record would otherwise wedge replay: divert it and go on
+		_ = tx.Rollback(ctx)
+		log.Printf("HealthMonitor: WAL replay rejected a record (%v), isolating bad records", err)
+		inserted, committed, err := insertDivertingRejects(ctx, hm.pool, points, hm.lateDataTracker(), hm.gapRecorder(), dlq, DeadLetterSourceReplay)
 		if err != nil {
 			return err
 		}
+		if skipped := int64(len(committed)) - inserted; skipped > 0 {
+			log.Printf("HealthMonitor: skipped %d WAL records already in the database", skipped)
+		}
+		hm.lateDataTracker().committed(committed)
+		hm.shadowWriter().enqueue(committed)
+		return nil
+	}
+	lateData := hm.lateDataTracker()
+	if err := lateData.insertCorrections(ctx, tx, points); err != nil {
+		return err
+	}
+	if err := hm.gapRecorder().recordGaps(ctx, tx, points); err != nil {
+		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return err
 	}
 
+	if skipped := int64(len(points)) - inserted; skipped > 0 {
+		log.Printf("HealthMonitor: skipped %d WAL records already in the database", skipped)
+	}
+	lateData.committed(points)
+	hm.shadowWriter().enqueue(points)
 	return nil
 }
 
+// lateDataTracker returns the batch processor's late-arrival tracker, if any
+func (hm *HealthMonitor) lateDataTracker() *LateDataTracker {
+	if hm.batchProcessor == nil {
+		return nil
+	}
+	return hm.batchProcessor.GetLateDataTracker()
+}
+
+// gapRecorder returns the batch processor's gap recorder, if any
+func (hm *HealthMonitor) gapRecorder() *GapRecorder {
+	if hm.batchProcessor == nil {
+		return nil
+	}
+	return hm.batchProcessor.GetGapRecorder()
+}
+
+// deadLetterQueue returns the batch processor's dead letter queue, if any
+func (hm *HealthMonitor) deadLetterQueue() *DeadLetterQueue {
+	if hm.batchProcessor == nil {
+		return nil
+	}
+	return hm.batchProcessor.GetDeadLetterQueue()
+}
+
+// shadowWriter returns the batch processor's shadow writer, if any
+func (hm *HealthMonitor) shadowWriter() *ShadowWriter {
+	if hm.batchProcessor == nil {
+		return nil
+	}
+	return hm.batchProcessor.GetShadowWriter()
+}
+
 // IsHealthy returns the current health status of the database
 func (hm *HealthMonitor) IsHealthy() bool {
 	hm.healthMutex.RLock()
@@ -210,6 +398,15 @@ func (hm *HealthMonitor) IsHealthy() bool {
 	return hm.isHealthy
 }
 
+// Degraded reports whether the last check found the database unreachable:
+// ingest is then WAL-only, accepted points are journaled to disk and
+// replayed once the database is back. False before the first check.
+func (hm *HealthMonitor) Degraded() bool {
+	hm.healthMutex.RLock()
+	defer hm.healthMutex.RUnlock()
+	return !hm.lastCheckTime.IsZero() && !hm.isHealthy
+}
+
 // GetLastCheckTime returns the time of the last health check
 func (hm *HealthMonitor) GetLastCheckTime() time.Time {
 	hm.healthMutex.RLock()
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"sync"
@@ -372,3 +374,142 @@ func TestHealthMonitorThreadSafety(t *testing.T) {
 		}
 	}
 }
+
+// TestHealthMonitorDatabaseServesFreshCache tests that a fresh check is served without probing
+func TestHealthMonitorDatabaseServesFreshCache(t *testing.T) {
+	checkedAt := time.Now().Add(-2 * time.Second)
+	hm := &HealthMonitor{
+		isHealthy:     true,
+		lastCheckTime: checkedAt,
+		cacheMaxAge:   10 * time.Second,
+	}
+
+	health := hm.Database(context.Background())
+	if !health.Healthy || !health.CheckedAt.Equal(checkedAt) {
+		t.Errorf("expected cached healthy result from %v, got %+v", checkedAt, health)
+	}
+}
+
+// TestHealthMonitorDatabaseProbesWhenStale tests that a stale cache triggers one probe
+func TestHealthMonitorDatabaseProbesWhenStale(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+
+	stale := time.Now().Add(-time.Minute)
+	hm := &HealthMonitor{
+		pool:          pool,
+		lastCheckTime: stale,
+		cacheMaxAge:   10 * time.Second,
+	}
+
+	health := hm.Database(context.Background())
+	if !health.Healthy {
+		t.Errorf("expected probe to find database healthy, got %+v", health)
+	}
+	if !health.CheckedAt.After(stale) {
+		t.Errorf("expected a fresh check time, got %v", health.CheckedAt)
+	}
+
+	// The next call within max age is served from the refreshed cache
+	if again := hm.Database(context.Background()); !again.CheckedAt.Equal(health.CheckedAt) {
+		t.Errorf("expected cached result, got new check at %v", again.CheckedAt)
+	}
+}
+
+// TestHealthMonitorUnhealthyHooks tests that subscribers hear about a failed
+// first check once and that the monitor reports degraded mode
+func TestHealthMonitorUnhealthyHooks(t *testing.T) {
+	bp, wal := newUnreachableBatchProcessor(t)
+	hm := NewHealthMonitor(bp.pool, wal, bp)
+	if hm.Degraded() {
+		t.Error("expected no degraded mode before the first check")
+	}
+
+	var unhealthy, healthy int
+	var lastErr error
+	hm.OnUnhealthy(func(err error) {
+		unhealthy++
+		lastErr = err
+	})
+	hm.OnHealthy(func() { healthy++ })
+
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		hm.probe(ctx)
+		cancel()
+	}
+	if unhealthy != 1 || healthy != 0 || lastErr == nil {
+		t.Errorf("expected one unhealthy notification, got unhealthy=%d healthy=%d err=%v", unhealthy, healthy, lastErr)
+	}
+	if !hm.Degraded() {
+		t.Error("expected degraded mode with the database unreachable")
+	}
+}
+
+// TestHealthMonitorCheckWaitsForOnDemandProbe tests that a periodic check
+// waits for a probe started by Database instead of racing it
+func TestHealthMonitorCheckWaitsForOnDemandProbe(t *testing.T) {
+	bp, wal := newUnreachableBatchProcessor(t)
+	hm := NewHealthMonitor(bp.pool, wal, bp)
+	var unhealthy int
+	hm.OnUnhealthy(func(err error) { unhealthy++ })
+
+	hm.probeMutex.Lock() // an on-demand probe in flight
+	done := make(chan struct{})
+	go func() {
+		hm.checkHealth()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("expected the check to wait for the on-demand probe")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if !hm.GetLastCheckTime().IsZero() {
+		t.Error("expected no result recorded while the on-demand probe holds the lock")
+	}
+	hm.probeMutex.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("check did not finish after the on-demand probe")
+	}
+	if unhealthy != 1 {
+		t.Errorf("expected one unhealthy notification, got %d", unhealthy)
+	}
+}
+
+// TestHealthMonitorPacesReplay tests that replay waits out its rate and that
+// stopping the monitor ends the wait
+func TestHealthMonitorPacesReplay(t *testing.T) {
+	hm := NewHealthMonitor(nil, nil, nil)
+	if err := hm.paceReplay(time.Now(), 1000000); err != nil {
+		t.Fatalf("unpaced replay should not wait, got %v", err)
+	}
+
+	hm.SetReplayRate(100, 0)
+	start := time.Now()
+	if err := hm.paceReplay(start, 20); err != nil {
+		t.Fatalf("paceReplay failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("expected 20 records at 100/s to take about 200ms, took %v", elapsed)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		hm.Stop()
+	}()
+	start = time.Now()
+	if err := hm.paceReplay(start, 1000); !errors.Is(err, errReplayStopped) {
+		t.Errorf("expected errReplayStopped, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected Stop to end the wait, took %v", elapsed)
+	}
+}
@@ -0,0 +1,371 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SlowQuery is one normalized statement from pg_stat_statements
+type SlowQuery struct {
+	Query      string   `json:"query"`
+	Calls      int64    `json:"calls"`
+	TotalMS    float64  `json:"total_ms"`
+	MeanMS     float64  `json:"mean_ms"`
+	MaxMS      float64  `json:"max_ms"`
+	Rows       int64    `json:"rows"`
+	HitPercent *float64 `json:"hit_percent,omitempty"`
+}
+
+// TelemetryIndex is an index on the telemetry hypertable
+type TelemetryIndex struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// Index advisor recommendation kinds
+const (
+	// RecommendIndex suggests a new index on telemetry
+	RecommendIndex = "index"
+	// RecommendAggregate suggests reading a continuous aggregate instead
+	RecommendAggregate = "aggregate"
+)
+
+// IndexRecommendation is one suggestion derived from a slow query
+type IndexRecommendation struct {
+	Kind       string  `json:"kind"`
+	Query      string  `json:"query"`
+	Reason     string  `json:"reason"`
+	Suggestion string  `json:"suggestion"`
+	Calls      int64   `json:"calls"`
+	MeanMS     float64 `json:"mean_ms"`
+}
+
+// IndexAdvisorReport is the result of one analysis run
+type IndexAdvisorReport struct {
+	GeneratedAt     time.Time             `json:"generated_at"`
+	SlowQueries     []SlowQuery           `json:"slow_queries"`
+	Indexes         []TelemetryIndex      `json:"indexes"`
+	Recommendations []IndexRecommendation `json:"recommendations"`
+}
+
+// QueryStatsSource reads statement statistics and index definitions
+// This allows for mocking in tests
+type QueryStatsSource interface {
+	SlowTelemetryQueries(ctx context.Context, minMeanMS float64, limit int) ([]SlowQuery, error)
+	TelemetryIndexes(ctx context.Context) ([]TelemetryIndex, error)
+}
+
+// SlowTelemetryQueries returns the statements touching the telemetry
+// hypertable with a mean execution time of at least minMeanMS, by total time
+func (qs *QueryService) SlowTelemetryQueries(ctx context.Context, minMeanMS float64, limit int) ([]SlowQuery, error) {
+	rows, err := qs.query(ctx, `
+		SELECT query, calls, total_exec_time, mean_exec_time, max_exec_time, rows,
+			(100.0 * shared_blks_hit / NULLIF(shared_blks_hit + shared_blks_read, 0))::double precision
+		FROM pg_stat_statements
+		WHERE query ~* '\mtelemetry\M' AND mean_exec_time >= $1
+		ORDER BY total_exec_time DESC
+		LIMIT $2
+	`, minMeanMS, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	queries := make([]SlowQuery, 0)
+	for rows.Next() {
+		var q SlowQuery
+		if err := rows.Scan(&q.Query, &q.Calls, &q.TotalMS, &q.MeanMS, &q.MaxMS, &q.Rows, &q.HitPercent); err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+	return queries, rows.Err()
+}
+
+// indexColumnsPattern extracts the column list of an index definition
+var indexColumnsPattern = regexp.MustCompile(`\(([^)]*)\)`)
+
+// TelemetryIndexes returns the indexes on the telemetry hypertable
+func (qs *QueryService) TelemetryIndexes(ctx context.Context) ([]TelemetryIndex, error) {
+	rows, err := qs.query(ctx, `
+		SELECT indexname, indexdef FROM pg_indexes
+		WHERE tablename = 'telemetry'
+		ORDER BY indexname
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := make([]TelemetryIndex, 0)
+	for rows.Next() {
+		var name, def string
+		if err := rows.Scan(&name, &def); err != nil {
+			return nil, err
+		}
+		index := TelemetryIndex{Name: name}
+		if match := indexColumnsPattern.FindStringSubmatch(def); match != nil {
+			for _, column := range strings.Split(match[1], ",") {
+				// "time DESC" -> "time"
+				if fields := strings.Fields(column); len(fields) > 0 {
+					index.Columns = append(index.Columns, strings.Trim(fields[0], `"`))
+				}
+			}
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, rows.Err()
+}
+
+var (
+	// telemetryTablePattern matches statements reading the telemetry
+	// hypertable itself (not telemetry_corrections and the like)
+	telemetryTablePattern = regexp.MustCompile(`(?i)\bfrom\s+telemetry\b`)
+	// predicatePattern matches "column <op> $n" filters, optionally qualified
+	predicatePattern = regexp.MustCompile(`(?i)(?:\w+\.)?(\w+)\s*(=|>=|<=|<>|!=|<|>|\bin\b|\bbetween\b)\s*\(?\s*\$\d+`)
+	// aggregatePattern matches statements that aggregate raw rows
+	aggregatePattern = regexp.MustCompile(`(?i)\b(time_bucket|avg|count|sum|min|max)\s*\(`)
+)
+
+// predicateKeywords are SQL keywords the predicate pattern can mistake for a
+// column, e.g. "CASE ... END >= $5"
+var predicateKeywords = map[string]bool{"end": true, "and": true, "or": true, "not": true, "then": true, "else": true}
+
+// rangeOperators filter on a range rather than a single value
+var rangeOperators = map[string]bool{">=": true, "<=": true, "<": true, ">": true, "between": true}
+
+// AdviseIndexes derives recommendations from slow telemetry statements:
+// filters not served by an existing index get an index suggestion (equality
+// columns first, then range columns), and statements aggregating raw rows
+// are pointed at the continuous aggregates.
+func AdviseIndexes(queries []SlowQuery, indexes []TelemetryIndex) []IndexRecommendation {
+	recommendations := make([]IndexRecommendation, 0)
+	suggested := make(map[string]bool)
+
+	for _, q := range queries {
+		if !telemetryTablePattern.MatchString(q.Query) {
+			continue
+		}
+		excerpt := abbreviateQuery(q.Query)
+
+		equality, ranges := predicateColumns(q.Query)
+		if len(equality)+len(ranges) > 0 && !indexCovers(indexes, equality, ranges) {
+			columns := append(append([]string(nil), equality...), ranges...)
+			ddl := fmt.Sprintf("CREATE INDEX ON telemetry (%s)", strings.Join(columns, ", "))
+			if !suggested[ddl] {
+				suggested[ddl] = true
+				recommendations = append(recommendations, IndexRecommendation{
+					Kind:       RecommendIndex,
+					Query:      excerpt,
+					Reason:     fmt.Sprintf("filters on %s are not served by an existing index", strings.Join(columns, ", ")),
+					Suggestion: ddl,
+					Calls:      q.Calls,
+					MeanMS:     q.MeanMS,
+				})
+			}
+		}
+
+		if aggregatePattern.MatchString(q.Query) {
+			recommendations = append(recommendations, IndexRecommendation{
+				Kind:       RecommendAggregate,
+				Query:      excerpt,
+				Reason:     "aggregates raw telemetry rows",
+				Suggestion: "read satellite_stats (5m), satellite_stats_hourly or satellite_stats_daily instead of telemetry",
+				Calls:      q.Calls,
+				MeanMS:     q.MeanMS,
+			})
+		}
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].MeanMS*float64(recommendations[i].Calls) >
+			recommendations[j].MeanMS*float64(recommendations[j].Calls)
+	})
+	return recommendations
+}
+
+// predicateColumns returns the columns a statement filters on by value and
+// by range, in order of first appearance
+func predicateColumns(query string) (equality, ranges []string) {
+	seen := make(map[string]bool)
+	for _, match := range predicatePattern.FindAllStringSubmatch(query, -1) {
+		column := strings.ToLower(match[1])
+		if seen[column] || predicateKeywords[column] {
+			continue
+		}
+		seen[column] = true
+		if rangeOperators[strings.ToLower(match[2])] {
+			ranges = append(ranges, column)
+		} else {
+			equality = append(equality, column)
+		}
+	}
+	return equality, ranges
+}
+
+// indexCovers reports whether an index leads with the equality columns (in
+// any order) followed by a range column, or leads with the range column when
+// there are no equality filters
+func indexCovers(indexes []TelemetryIndex, equality, ranges []string) bool {
+	for _, index := range indexes {
+		if len(index.Columns) < len(equality) {
+			continue
+		}
+		leading := make(map[string]bool, len(equality))
+		for _, column := range index.Columns[:len(equality)] {
+			leading[column] = true
+		}
+		covered := true
+		for _, column := range equality {
+			if !leading[column] {
+				covered = false
+				break
+			}
+		}
+		if !covered {
+			continue
+		}
+		if len(ranges) == 0 {
+			return true
+		}
+		if len(index.Columns) > len(equality) {
+			next := index.Columns[len(equality)]
+			for _, column := range ranges {
+				if column == next {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// abbreviateQuery collapses whitespace and truncates long statements
+func abbreviateQuery(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	const maxLen = 200
+	if len(query) > maxLen {
+		return query[:maxLen] + "..."
+	}
+	return query
+}
+
+// IndexAdvisorConfig configures the periodic index advisor
+type IndexAdvisorConfig struct {
+	// Interval between analyses
+	Interval time.Duration
+	// MinMeanMS ignores statements faster than this on average
+	MinMeanMS float64
+	// TopN limits how many statements are analyzed, by total time
+	TopN int
+}
+
+// IndexAdvisor periodically analyzes pg_stat_statements for slow telemetry
+// queries and keeps the latest report for the admin endpoint
+type IndexAdvisor struct {
+	source QueryStatsSource
+	cfg    IndexAdvisorConfig
+
+	mu     sync.Mutex
+	report *IndexAdvisorReport
+	// Only the leader replica analyzes (nil = always)
+	leadership Leadership
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewIndexAdvisor creates an advisor reading from source
+func NewIndexAdvisor(source QueryStatsSource, cfg IndexAdvisorConfig) *IndexAdvisor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	if cfg.TopN <= 0 {
+		cfg.TopN = 20
+	}
+	return &IndexAdvisor{
+		source: source,
+		cfg:    cfg,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// SetLeadership makes the advisor analyze only while this replica is the
+// leader; followers serve no report. Must be called before Start.
+func (a *IndexAdvisor) SetLeadership(leadership Leadership) {
+	a.leadership = leadership
+}
+
+// Start runs an analysis immediately and then every interval until Stop
+func (a *IndexAdvisor) Start() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(a.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			if a.leadership == nil || a.leadership.IsLeader() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				if _, err := a.Analyze(ctx); err != nil {
+					log.Printf("IndexAdvisor: analysis failed: %v", err)
+				}
+				cancel()
+			}
+
+			select {
+			case <-ticker.C:
+			case <-a.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the analysis loop
+func (a *IndexAdvisor) Stop() {
+	close(a.stopCh)
+	a.wg.Wait()
+}
+
+// Analyze reads current statistics and replaces the latest report
+func (a *IndexAdvisor) Analyze(ctx context.Context) (IndexAdvisorReport, error) {
+	queries, err := a.source.SlowTelemetryQueries(ctx, a.cfg.MinMeanMS, a.cfg.TopN)
+	if err != nil {
+		return IndexAdvisorReport{}, err
+	}
+	indexes, err := a.source.TelemetryIndexes(ctx)
+	if err != nil {
+		return IndexAdvisorReport{}, err
+	}
+
+	report := IndexAdvisorReport{
+		GeneratedAt:     time.Now().UTC(),
+		SlowQueries:     queries,
+		Indexes:         indexes,
+		Recommendations: AdviseIndexes(queries, indexes),
+	}
+	a.mu.Lock()
+	a.report = &report
+	a.mu.Unlock()
+
+	if len(report.Recommendations) > 0 {
+		log.Printf("IndexAdvisor: %d recommendations from %d slow queries", len(report.Recommendations), len(queries))
+	}
+	return report, nil
+}
+
+// Report returns the latest report, or false before the first analysis
+func (a *IndexAdvisor) Report() (IndexAdvisorReport, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.report == nil {
+		return IndexAdvisorReport{}, false
+	}
+	return *a.report, true
+}
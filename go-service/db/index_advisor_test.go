@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+// fakeQueryStats returns canned statement statistics and indexes
+type fakeQueryStats struct {
+	queries []SlowQuery
+	indexes []TelemetryIndex
+}
+
+func (f *fakeQueryStats) SlowTelemetryQueries(ctx context.Context, minMeanMS float64, limit int) ([]SlowQuery, error) {
+	return f.queries, nil
+}
+
+func (f *fakeQueryStats) TelemetryIndexes(ctx context.Context) ([]TelemetryIndex, error) {
+	return f.indexes, nil
+}
+
+// schemaIndexes mirrors the telemetry indexes created by init.sql
+var schemaIndexes = []TelemetryIndex{
+	{Name: "idx_telemetry_satellite_time", Columns: []string{"satellite_id", "time"}},
+	{Name: "idx_telemetry_anomaly", Columns: []string{"is_anomaly", "time"}},
+	{Name: "telemetry_time_idx", Columns: []string{"time"}},
+}
+
+// TestAdviseIndexes tests recommendations for covered and uncovered filters
+func TestAdviseIndexes(t *testing.T) {
+	queries := []SlowQuery{
+		// Served by idx_telemetry_satellite_time; the severity CASE is not a column
+		{Query: `SELECT time, satellite_id FROM telemetry WHERE satellite_id = $1 AND time >= $2 AND time < $3
+			AND ($5 = 0 OR CASE anomaly_severity WHEN $6 THEN $7 ELSE $8 END >= $5) ORDER BY time DESC LIMIT $4`,
+			Calls: 100, MeanMS: 150},
+		// No index leads with software_version
+		{Query: `SELECT * FROM telemetry WHERE software_version = $1 AND time > $2`, Calls: 10, MeanMS: 900},
+		// Aggregating raw rows
+		{Query: `SELECT time_bucket($1, time), avg(battery_charge_percent) FROM telemetry t WHERE t.satellite_id = $2 GROUP BY 1`,
+			Calls: 5, MeanMS: 400},
+		// Not the telemetry hypertable
+		{Query: `SELECT * FROM telemetry_corrections WHERE status = $1`, Calls: 1000, MeanMS: 500},
+	}
+
+	recommendations := AdviseIndexes(queries, schemaIndexes)
+	if len(recommendations) != 2 {
+		t.Fatalf("expected 2 recommendations, got %+v", recommendations)
+	}
+
+	// Ordered by total time: 10*900 before 5*400
+	index := recommendations[0]
+	if index.Kind != RecommendIndex || index.Suggestion != "CREATE INDEX ON telemetry (software_version, time)" {
+		t.Errorf("unexpected index recommendation %+v", index)
+	}
+	if aggregate := recommendations[1]; aggregate.Kind != RecommendAggregate || aggregate.Calls != 5 {
+		t.Errorf("unexpected aggregate recommendation %+v", aggregate)
+	}
+}
+
+// TestIndexAdvisorAnalyze tests that the latest report is kept
+func TestIndexAdvisorAnalyze(t *testing.T) {
+	source := &fakeQueryStats{
+		queries: []SlowQuery{{Query: `SELECT * FROM telemetry WHERE anomaly_dimension = $1`, Calls: 3, MeanMS: 250}},
+		indexes: schemaIndexes,
+	}
+	advisor := NewIndexAdvisor(source, IndexAdvisorConfig{MinMeanMS: 100})
+
+	if _, ok := advisor.Report(); ok {
+		t.Error("expected no report before the first analysis")
+	}
+	if _, err := advisor.Analyze(context.Background()); err != nil {
+		t.Fatalf("analysis failed: %v", err)
+	}
+
+	report, ok := advisor.Report()
+	if !ok {
+		t.Fatal("expected a report after analysis")
+	}
+	if len(report.SlowQueries) != 1 || len(report.Recommendations) != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
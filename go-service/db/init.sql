@@ -23,7 +23,30 @@ CREATE TABLE IF NOT EXISTS telemetry (
     latitude DECIMAL(9,6),
     longitude DECIMAL(9,6),
     altitude_km DECIMAL(8,2),
-    velocity_kmph DECIMAL(9,2)
+    velocity_kmph DECIMAL(9,2),
+    -- Flight software version reported by the satellite (nullable)
+    software_version VARCHAR(50),
+    -- Anomaly detectors that flagged the point, e.g. 'threshold,statistical'
+    anomaly_detector VARCHAR(100),
+    -- Classification of the most severe finding: info/warning/critical and
+    -- battery/storage/signal/position
+    anomaly_severity VARCHAR(10),
+    anomaly_dimension VARCHAR(20),
+    -- Arrived after the aggregate refresh window for its timestamp had passed
+    is_late BOOLEAN DEFAULT FALSE,
+    -- Timestamp was out of range (clock skew) and clamped by the ingest guard
+    timestamp_clamped BOOLEAN DEFAULT FALSE,
+    -- Validation problems of a point accepted in lenient mode, e.g.
+    -- 'battery_charge_percent:out_of_range,latitude:missing'
+    validation_issues VARCHAR(500),
+    -- Position detector found a physically impossible jump from the
+    -- satellite's previous position
+    position_anomaly BOOLEAN DEFAULT FALSE,
+    -- Geofence zones the reported position was in, e.g. 'svalbard_gs'
+    geofence_zones VARCHAR(500),
+    -- Distance between the reported position and the SGP4 prediction from
+    -- the satellite's registered TLE, for drift analysis
+    position_residual_km DOUBLE PRECISION
 );
 
 -- Convert to hypertable with 1-hour chunks for optimal performance
@@ -32,10 +55,65 @@ SELECT create_hypertable('telemetry', 'time',
 );
 
 -- Create indexes for efficient querying
-CREATE INDEX idx_telemetry_satellite_time ON telemetry (satellite_id, time DESC);
+-- Unique so inserts can skip points already stored (ON CONFLICT DO NOTHING),
+-- which keeps WAL replays and retried batches idempotent
+CREATE UNIQUE INDEX idx_telemetry_satellite_time ON telemetry (satellite_id, time DESC);
 CREATE INDEX idx_telemetry_anomaly ON telemetry (is_anomaly, time DESC) WHERE is_anomaly = TRUE;
 -- Index for position-based queries (e.g., find satellites over a region)
 CREATE INDEX idx_telemetry_position ON telemetry (satellite_id, time DESC) INCLUDE (latitude, longitude, altitude_km);
+-- Index for radius searches around a ground point (/telemetry/near), which
+-- prefilter on the bounding box of the circle
+CREATE INDEX idx_telemetry_lat_lon ON telemetry (latitude, longitude, time DESC)
+    WHERE latitude IS NOT NULL AND longitude IS NOT NULL;
+
+-- Late arrivals queued for review when LATE_DATA_POLICY includes 'corrections'.
+-- The telemetry row is stored as usual; this queue lets a corrections
+-- workflow re-issue reports or derived products built before it arrived.
+CREATE TABLE IF NOT EXISTS telemetry_corrections (
+    id BIGSERIAL PRIMARY KEY,
+    satellite_id VARCHAR(50) NOT NULL,
+    time TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ DEFAULT NOW(),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+);
+CREATE INDEX idx_telemetry_corrections_status ON telemetry_corrections (status, time);
+
+-- Stretches without telemetry, between consecutive points of a satellite
+-- further apart than the expected cadence (GAP_DETECTION_ENABLED). Rows are
+-- recomputed as points commit, so late and replayed data close gaps.
+CREATE TABLE IF NOT EXISTS telemetry_gaps (
+    satellite_id VARCHAR(50) NOT NULL,
+    gap_start TIMESTAMPTZ NOT NULL,
+    gap_end TIMESTAMPTZ NOT NULL,
+    duration_seconds DOUBLE PRECISION NOT NULL,
+    detected_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (satellite_id, gap_start)
+);
+CREATE INDEX idx_telemetry_gaps_time ON telemetry_gaps (gap_start, gap_end);
+
+-- Versioned satellite registry: one row per configuration period, closed by
+-- valid_to when superseded (NULL = current). Telemetry reads can join the
+-- version in force at each point's timestamp (as-of join).
+CREATE TABLE IF NOT EXISTS satellite_registry_history (
+    satellite_id VARCHAR(50) NOT NULL,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ,
+    constellation VARCHAR(50),
+    software_version VARCHAR(50),
+    -- Operating thresholds in force for the period (nullable)
+    battery_min_percent DECIMAL(5,2),
+    storage_max_mb DECIMAL(10,2),
+    signal_min_dbm DECIMAL(6,2),
+    -- Timestamp format the satellite sends (rfc3339, epoch_s, epoch_ms,
+    -- boot_s, boot_ms; NULL = auto-detect) and, for boot-relative offsets,
+    -- the time of its last boot
+    timestamp_format VARCHAR(20),
+    boot_epoch TIMESTAMPTZ,
+    -- Two-line element set of the orbit, for position propagation
+    tle_line1 VARCHAR(69),
+    tle_line2 VARCHAR(69),
+    PRIMARY KEY (satellite_id, valid_from)
+);
 
 -- Configure compression settings (90% space savings)
 ALTER TABLE telemetry SET (
@@ -66,11 +144,21 @@ SELECT
     AVG(storage_usage_mb) AS avg_storage,
     AVG(signal_strength_dbm) AS avg_signal,
     COUNT(*) AS data_points,
-    -- Position tracking averages
+    -- Position tracking averages, altitude and velocity ranges
     AVG(latitude) AS avg_latitude,
     AVG(longitude) AS avg_longitude,
     AVG(altitude_km) AS avg_altitude_km,
-    AVG(velocity_kmph) AS avg_velocity_kmph
+    MIN(altitude_km) AS min_altitude_km,
+    MAX(altitude_km) AS max_altitude_km,
+    AVG(velocity_kmph) AS avg_velocity_kmph,
+    MIN(velocity_kmph) AS min_velocity_kmph,
+    MAX(velocity_kmph) AS max_velocity_kmph,
+    -- Vis-viva semi-major axis from each point's radius and speed (mean
+    -- Earth radius 6371 km, GM 398600.4418 km^3/s^2), for the apogee and
+    -- perigee estimate; NULL for points without velocity or not in orbit
+    AVG(1.0 / NULLIF(GREATEST(
+        2.0 / NULLIF(6371.0 + altitude_km::float8, 0) - POWER(velocity_kmph::float8 / 3600.0, 2) / 398600.4418,
+    0), 0)) AS avg_semi_major_axis_km
 FROM telemetry
 GROUP BY satellite_id, bucket;
 
@@ -105,13 +193,21 @@ SELECT
     MAX(signal_strength_dbm) AS max_signal,
     COUNT(*) AS data_points,
     SUM(CASE WHEN is_anomaly THEN 1 ELSE 0 END) AS anomaly_count,
-    -- Position tracking (with min/max for altitude)
+    -- Position tracking (with min/max for altitude and velocity)
     AVG(latitude) AS avg_latitude,
     AVG(longitude) AS avg_longitude,
     AVG(altitude_km) AS avg_altitude_km,
     MIN(altitude_km) AS min_altitude_km,
     MAX(altitude_km) AS max_altitude_km,
-    AVG(velocity_kmph) AS avg_velocity_kmph
+    AVG(velocity_kmph) AS avg_velocity_kmph,
+    MIN(velocity_kmph) AS min_velocity_kmph,
+    MAX(velocity_kmph) AS max_velocity_kmph,
+    -- Vis-viva semi-major axis from each point's radius and speed (mean
+    -- Earth radius 6371 km, GM 398600.4418 km^3/s^2), for the apogee and
+    -- perigee estimate; NULL for points without velocity or not in orbit
+    AVG(1.0 / NULLIF(GREATEST(
+        2.0 / NULLIF(6371.0 + altitude_km::float8, 0) - POWER(velocity_kmph::float8 / 3600.0, 2) / 398600.4418,
+    0), 0)) AS avg_semi_major_axis_km
 FROM telemetry
 GROUP BY satellite_id, bucket;
 
@@ -160,13 +256,21 @@ SELECT
     MAX(signal_strength_dbm) AS max_signal,
     COUNT(*) AS data_points,
     SUM(CASE WHEN is_anomaly THEN 1 ELSE 0 END) AS anomaly_count,
-    -- Position tracking (with min/max for altitude)
+    -- Position tracking (with min/max for altitude and velocity)
     AVG(latitude) AS avg_latitude,
     AVG(longitude) AS avg_longitude,
     AVG(altitude_km) AS avg_altitude_km,
     MIN(altitude_km) AS min_altitude_km,
     MAX(altitude_km) AS max_altitude_km,
-    AVG(velocity_kmph) AS avg_velocity_kmph
+    AVG(velocity_kmph) AS avg_velocity_kmph,
+    MIN(velocity_kmph) AS min_velocity_kmph,
+    MAX(velocity_kmph) AS max_velocity_kmph,
+    -- Vis-viva semi-major axis from each point's radius and speed (mean
+    -- Earth radius 6371 km, GM 398600.4418 km^3/s^2), for the apogee and
+    -- perigee estimate; NULL for points without velocity or not in orbit
+    AVG(1.0 / NULLIF(GREATEST(
+        2.0 / NULLIF(6371.0 + altitude_km::float8, 0) - POWER(velocity_kmph::float8 / 3600.0, 2) / 398600.4418,
+    0), 0)) AS avg_semi_major_axis_km
 FROM telemetry
 GROUP BY satellite_id, bucket;
 
@@ -196,6 +300,55 @@ SELECT add_retention_policy('satellite_stats_daily',
     INTERVAL '1 year'
 );
 
+-- =====================================================
+-- HOURLY PER-SOFTWARE-VERSION AGGREGATE
+-- =====================================================
+-- Splits health metrics by flight software version so regressions
+-- introduced by a software update show up as a version-split trend
+CREATE MATERIALIZED VIEW satellite_version_stats_hourly
+WITH (timescaledb.continuous) AS
+SELECT
+    satellite_id,
+    software_version,
+    time_bucket('1 hour', time) AS bucket,
+    AVG(battery_charge_percent) AS avg_battery,
+    AVG(storage_usage_mb) AS avg_storage,
+    AVG(signal_strength_dbm) AS avg_signal,
+    COUNT(*) AS data_points,
+    SUM(CASE WHEN is_anomaly THEN 1 ELSE 0 END) AS anomaly_count
+FROM telemetry
+GROUP BY satellite_id, software_version, bucket;
+
+CREATE INDEX idx_satellite_version_stats_hourly_lookup
+ON satellite_version_stats_hourly (satellite_id, bucket DESC);
+
+SELECT add_continuous_aggregate_policy('satellite_version_stats_hourly',
+    start_offset => INTERVAL '48 hours',
+    end_offset => INTERVAL '1 hour',
+    schedule_interval => INTERVAL '1 hour'
+);
+
+SELECT add_retention_policy('satellite_version_stats_hourly',
+    INTERVAL '6 months'
+);
+
+-- Audit log of admin operations (AUDIT_LOG_SINK=db): caller identity,
+-- parameters and result of each WAL clear, config reload, breaker reset...
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    time TIMESTAMPTZ NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    route VARCHAR(200) NOT NULL,
+    path TEXT NOT NULL,
+    caller JSONB NOT NULL,
+    params JSONB,
+    body TEXT,
+    status INTEGER NOT NULL,
+    result TEXT,
+    duration_ms DOUBLE PRECISION
+);
+CREATE INDEX idx_admin_audit_log_time ON admin_audit_log (time DESC);
+
 -- =====================================================
 -- QUERY STATISTICS VIEW (for database monitoring)
 -- =====================================================
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// insertErrorClass decides how a failed insert is handled
+type insertErrorClass int
+
+const (
+	// insertErrorTransient: connection loss, timeouts, lock conflicts and
+	// server overload; retried with backoff and counted by the circuit breaker
+	insertErrorTransient insertErrorClass = iota
+	// insertErrorRejected: the database rejected the data itself (data
+	// exceptions, constraint violations); retrying the same rows can never
+	// succeed, so they go to the dead letter queue
+	insertErrorRejected
+	// insertErrorClient: the request was wrong or cancelled on our side
+	// (invalid statement, missing privilege, shutdown); not retried and not
+	// held against the database by the circuit breaker
+	insertErrorClient
+)
+
+// classifyInsertError classifies an insert error by SQLSTATE class, see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html. Errors
+// without a SQLSTATE (network, pool) are transient unless the context was
+// cancelled.
+func classifyInsertError(err error) insertErrorClass {
+	if isPermanentInsertError(err) {
+		return insertErrorRejected
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && len(pgErr.Code) >= 2 {
+		switch pgErr.Code[:2] {
+		case "42", // syntax error or access rule violation (undefined column, permission)
+			"0A", // feature not supported
+			"25", // invalid transaction state
+			"28": // invalid authorization
+			return insertErrorClient
+		}
+		// 08 connection, 40 serialization failure/deadlock, 53 insufficient
+		// resources, 57 operator intervention (query_canceled on timeout,
+		// admin_shutdown) and anything unknown are worth another attempt
+		return insertErrorTransient
+	}
+	if errors.Is(err, context.Canceled) {
+		return insertErrorClient
+	}
+	return insertErrorTransient
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// TestClassifyInsertError tests which errors are retried, dead-lettered or
+// kept away from the circuit breaker
+func TestClassifyInsertError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want insertErrorClass
+	}{
+		{&pgconn.PgError{Code: "22003"}, insertErrorRejected},                           // numeric_value_out_of_range
+		{fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505"}), insertErrorRejected}, // unique_violation
+		{&pgconn.PgError{Code: "42703"}, insertErrorClient},                             // undefined_column
+		{&pgconn.PgError{Code: "42501"}, insertErrorClient},                             // insufficient_privilege
+		{&pgconn.PgError{Code: "08006"}, insertErrorTransient},                          // connection_failure
+		{&pgconn.PgError{Code: "40001"}, insertErrorTransient},                          // serialization_failure
+		{&pgconn.PgError{Code: "40P01"}, insertErrorTransient},                          // deadlock_detected
+		{&pgconn.PgError{Code: "57014"}, insertErrorTransient},                          // query_canceled (statement timeout)
+		{&pgconn.PgError{Code: "53300"}, insertErrorTransient},                          // too_many_connections
+		{errors.New("connection refused"), insertErrorTransient},
+		{context.DeadlineExceeded, insertErrorTransient},
+		{fmt.Errorf("begin: %w", context.Canceled), insertErrorClient},
+	}
+	for _, tc := range cases {
+		if got := classifyInsertError(tc.err); got != tc.want {
+			t.Errorf("classifyInsertError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+)
+
+// WriteThroughJournal is a WAL every accepted point is written to before it
+// is buffered in memory, so points survive a process crash and not only a
+// database outage. Records are marked committed once their batch reached the
+// database or the outage WAL; sealed segments holding only committed records
+// are deleted and a cursor file records how far the oldest one is committed.
+//
+// Batches may finish out of order, so only the committed prefix (the
+// watermark) is persisted. Records after it are recovered again after a
+// crash even if their batch was stored; the unique (satellite_id, time)
+// index makes the second insert a no-op.
+type WriteThroughJournal struct {
+	wal *WAL
+
+	mu        sync.Mutex
+	next      uint64            // position of the next appended record
+	watermark uint64            // every position below is committed
+	committed map[uint64]uint64 // committed ranges above the watermark, first -> end
+	segments  []journalSegment  // segments holding records from the watermark on
+}
+
+// journalSegment is the range of journal positions written to one segment
+type journalSegment struct {
+	seq        int
+	first, end uint64
+}
+
+// OpenWriteThroughJournal opens the journal at path and moves records a
+// crash left uncommitted into wal, where the health monitor replays them.
+// Returns the journal and the number of records recovered.
+func OpenWriteThroughJournal(path string, wal *WAL) (*WriteThroughJournal, int, error) {
+	journal, err := NewWAL(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	recovered, err := journal.ReplaySegments(1000, func(records []WALRecord) error {
+		for _, record := range records {
+			if err := wal.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		journal.Close()
+		return nil, recovered, fmt.Errorf("failed to recover write-through journal: %w", err)
+	}
+	return &WriteThroughJournal{
+		wal:       journal,
+		committed: make(map[uint64]uint64),
+	}, recovered, nil
+}
+
+// SetRotation sets when journal segments are sealed, see WAL.SetRotation
+// Only sealed segments can be deleted once committed.
+func (j *WriteThroughJournal) SetRotation(maxSize int64, maxAge time.Duration) {
+	j.wal.SetRotation(maxSize, maxAge)
+}
+
+// Append durably writes point and returns its journal position
+// Positions are consecutive, so callers serializing appends can derive the
+// range of a batch from its first position and length.
+func (j *WriteThroughJournal) Append(point models.TelemetryPoint) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.wal.Write(NewWALRecord(point)); err != nil {
+		return 0, err
+	}
+	j.wal.mu.Lock()
+	seq := j.wal.activeSeq
+	j.wal.mu.Unlock()
+
+	pos := j.next
+	j.next++
+	if n := len(j.segments); n > 0 && j.segments[n-1].seq == seq {
+		j.segments[n-1].end = j.next
+	} else {
+		j.segments = append(j.segments, journalSegment{seq: seq, first: pos, end: j.next})
+	}
+	return pos, nil
+}
+
+// Commit marks the records at positions [first, end) as stored
+func (j *WriteThroughJournal) Commit(first, end uint64) error {
+	if end <= first {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.committed[first] = end
+	advanced := false
+	for {
+		end, ok := j.committed[j.watermark]
+		if !ok {
+			break
+		}
+		delete(j.committed, j.watermark)
+		j.watermark = end
+		advanced = true
+	}
+	if !advanced {
+		return nil
+	}
+
+	j.wal.mu.Lock()
+	defer j.wal.mu.Unlock()
+	for len(j.segments) > 0 {
+		seg := j.segments[0]
+		if seg.end > j.watermark || seg.seq == j.wal.activeSeq {
+			break
+		}
+		if err := j.wal.removeSegment(seg.seq); err != nil {
+			return fmt.Errorf("failed to delete committed journal segment: %w", err)
+		}
+		j.segments = j.segments[1:]
+	}
+	if len(j.segments) == 0 || j.watermark <= j.segments[0].first {
+		return j.wal.removeReplayCursor()
+	}
+	oldest := j.segments[0]
+	return j.wal.saveReplayCursor(replayCursor{Segment: oldest.seq, Records: int(j.watermark - oldest.first)})
+}
+
+// Uncommitted returns the number of records written after the watermark
+func (j *WriteThroughJournal) Uncommitted() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.next - j.watermark
+}
+
+// Close closes the active journal segment
+func (j *WriteThroughJournal) Close() error {
+	return j.wal.Close()
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+func openTestJournal(t *testing.T, dir string) (*WriteThroughJournal, *WAL, int) {
+	t.Helper()
+	wal, err := NewWAL(filepath.Join(dir, "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	journal, recovered, err := OpenWriteThroughJournal(filepath.Join(dir, "journal.wal"), wal)
+	if err != nil {
+		t.Fatalf("failed to open journal: %v", err)
+	}
+	return journal, wal, recovered
+}
+
+// TestWriteThroughJournalRecoversUncommitted tests that records after the
+// committed prefix are moved into the WAL when the journal is reopened
+func TestWriteThroughJournalRecoversUncommitted(t *testing.T) {
+	dir := t.TempDir()
+	journal, wal, _ := openTestJournal(t, dir)
+
+	for i := 0; i < 5; i++ {
+		point := TelemetryPointForTest(float64(50+i), 1000, -60)
+		if pos, err := journal.Append(point); err != nil || pos != uint64(i) {
+			t.Fatalf("append %d: position %d, err %v", i, pos, err)
+		}
+	}
+	if err := journal.Commit(0, 3); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if got := journal.Uncommitted(); got != 2 {
+		t.Errorf("expected 2 uncommitted records, got %d", got)
+	}
+
+	// Simulate a crash: reopen without committing the rest
+	journal.Close()
+	wal.Close()
+	journal, wal, recovered := openTestJournal(t, dir)
+	defer journal.Close()
+	defer wal.Close()
+	if recovered != 2 {
+		t.Fatalf("expected 2 records recovered, got %d", recovered)
+	}
+	records, err := wal.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read WAL: %v", err)
+	}
+	if len(records) != 2 || records[0].BatteryChargePercent != 53 || records[1].BatteryChargePercent != 54 {
+		t.Errorf("expected the last two points in the WAL, got %+v", records)
+	}
+	if journal.Uncommitted() != 0 {
+		t.Errorf("expected an empty journal after recovery")
+	}
+}
+
+// TestWriteThroughJournalOutOfOrderCommit tests that a batch committed ahead
+// of an earlier one only advances the watermark once the gap is committed
+func TestWriteThroughJournalOutOfOrderCommit(t *testing.T) {
+	journal, wal, _ := openTestJournal(t, t.TempDir())
+	defer journal.Close()
+	defer wal.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err := journal.Append(TelemetryPointForTest(80, 1000, -60)); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+	if err := journal.Commit(2, 4); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if got := journal.Uncommitted(); got != 4 {
+		t.Errorf("expected watermark to wait for the first batch, %d uncommitted", got)
+	}
+	if err := journal.Commit(0, 2); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if got := journal.Uncommitted(); got != 0 {
+		t.Errorf("expected everything committed, %d uncommitted", got)
+	}
+}
+
+// TestWriteThroughJournalDeletesCommittedSegments tests that sealed segments
+// are deleted once all their records are committed
+func TestWriteThroughJournalDeletesCommittedSegments(t *testing.T) {
+	journal, wal, _ := openTestJournal(t, t.TempDir())
+	defer journal.Close()
+	defer wal.Close()
+	journal.SetRotation(1, 0) // one record per segment
+
+	for i := 0; i < 4; i++ {
+		if _, err := journal.Append(TelemetryPointForTest(80, 1000, -60)); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+	if got := journal.wal.SegmentCount(); got != 4 {
+		t.Fatalf("expected 4 segments, got %d", got)
+	}
+	if err := journal.Commit(0, 4); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if got := journal.wal.SegmentCount(); got != 1 {
+		t.Errorf("expected only the active segment left, got %d", got)
+	}
+}
+
+// TestBatchProcessorWriteThrough tests that points are journaled on Add and
+// committed once their batch reached the WAL
+func TestBatchProcessorWriteThrough(t *testing.T) {
+	journal, wal, _ := openTestJournal(t, t.TempDir())
+	defer journal.Close()
+	defer wal.Close()
+
+	cb := NewCircuitBreaker(1, time.Hour)
+	cb.RecordFailure() // open: flushes go straight to the WAL
+	bp := &BatchProcessor{
+		buffer:         make([]models.TelemetryPoint, 0, 10),
+		batchSize:      10,
+		maxBufferSize:  100,
+		maxRetries:     1,
+		wal:            wal,
+		circuitBreaker: cb,
+	}
+	bp.SetWriteThrough(journal)
+
+	for i := 0; i < 3; i++ {
+		if err := bp.Add(TelemetryPointForTest(80, 1000, -60)); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+	}
+	if got := journal.Uncommitted(); got != 3 {
+		t.Fatalf("expected 3 journaled points before the flush, got %d", got)
+	}
+
+	bp.flush()
+	if got := journal.Uncommitted(); got != 0 {
+		t.Errorf("expected the flushed batch committed, %d uncommitted", got)
+	}
+	if count, _ := wal.Count(); count != 3 {
+		t.Errorf("expected 3 points in the WAL, got %d", count)
+	}
+}
+
+// TestBatchProcessorWriteThroughRetriesWAL tests that a batch the WAL failed
+// to take is written to it on the next flush, so its journal range is
+// committed and the segments behind it deleted
+func TestBatchProcessorWriteThroughRetriesWAL(t *testing.T) {
+	dir := t.TempDir()
+	journal, wal, _ := openTestJournal(t, dir)
+	defer journal.Close()
+	defer wal.Close()
+	journal.SetRotation(1, 0) // one record per segment
+
+	broken, err := NewWAL(filepath.Join(dir, "broken.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	broken.Close() // writes fail from now on
+
+	cb := NewCircuitBreaker(1, time.Hour)
+	cb.RecordFailure() // open: flushes go straight to the WAL
+	bp := &BatchProcessor{
+		buffer:         make([]models.TelemetryPoint, 0, 10),
+		batchSize:      10,
+		maxBufferSize:  100,
+		maxRetries:     1,
+		wal:            broken,
+		circuitBreaker: cb,
+	}
+	bp.SetWriteThrough(journal)
+
+	add := func(n int) {
+		for i := 0; i < n; i++ {
+			if err := bp.Add(TelemetryPointForTest(80, 1000, -60)); err != nil {
+				t.Fatalf("add failed: %v", err)
+			}
+		}
+	}
+	add(3)
+	bp.flush()
+	if got := journal.Uncommitted(); got != 3 {
+		t.Fatalf("expected the failed batch uncommitted, %d uncommitted", got)
+	}
+
+	// The WAL recovers: the failed batch goes first, then the new one
+	bp.wal = wal
+	add(2)
+	bp.flush()
+	if got := journal.Uncommitted(); got != 0 {
+		t.Errorf("expected both batches committed, %d uncommitted", got)
+	}
+	if got := journal.wal.SegmentCount(); got != 1 {
+		t.Errorf("expected only the active journal segment left, got %d", got)
+	}
+	if count, _ := wal.Count(); count != 5 {
+		t.Errorf("expected 5 points in the WAL, got %d", count)
+	}
+}
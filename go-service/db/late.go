@@ -0,0 +1,328 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"orbitstream/models"
+)
+
+// LateDataPolicy is an action taken for telemetry that arrives late
+type LateDataPolicy string
+
+const (
+	// LatePolicyTag stores late points with is_late = TRUE
+	LatePolicyTag LateDataPolicy = "tag"
+	// LatePolicyRefresh re-materializes the aggregate buckets a late point
+	// falls into once it is committed
+	LatePolicyRefresh LateDataPolicy = "refresh"
+	// LatePolicyCorrections queues late points in telemetry_corrections
+	LatePolicyCorrections LateDataPolicy = "corrections"
+)
+
+// LateDataConfig configures late-arrival handling
+type LateDataConfig struct {
+	// Threshold is how old a point may be on arrival before it is late.
+	// The default matches the shortest continuous aggregate start_offset.
+	Threshold time.Duration
+	// Policies to apply; refresh and corrections imply tag, since the flag
+	// is what carries lateness through the WAL
+	Policies []LateDataPolicy
+}
+
+// aggregateRefreshWindow mirrors a continuous aggregate policy in init.sql.
+// Points older than startOffset on commit are outside the scheduled refresh.
+type aggregateRefreshWindow struct {
+	view        string
+	bucket      time.Duration
+	startOffset time.Duration
+}
+
+var aggregateRefreshWindows = []aggregateRefreshWindow{
+	{view: "satellite_stats", bucket: 5 * time.Minute, startOffset: 30 * time.Minute},
+	{view: "satellite_stats_hourly", bucket: time.Hour, startOffset: 48 * time.Hour},
+	{view: "satellite_stats_daily", bucket: 24 * time.Hour, startOffset: 7 * 24 * time.Hour},
+	{view: "satellite_version_stats_hourly", bucket: time.Hour, startOffset: 48 * time.Hour},
+}
+
+// latenessBuckets are the counter buckets, by upper bound (0 = unbounded)
+var latenessBuckets = []struct {
+	label string
+	max   time.Duration
+}{
+	{label: "<=2h", max: 2 * time.Hour},
+	{label: "<=48h", max: 48 * time.Hour},
+	{label: "<=7d", max: 7 * 24 * time.Hour},
+	{label: ">7d"},
+}
+
+// LateDataStats reports late arrivals since startup
+type LateDataStats struct {
+	Threshold        string           `json:"threshold"`
+	Policies         []LateDataPolicy `json:"policies"`
+	Late             int64            `json:"late"`
+	ByLateness       map[string]int64 `json:"by_lateness"`
+	PendingRefreshes int              `json:"pending_refreshes"`
+	Refreshes        int64            `json:"refreshes"`
+	Corrections      int64            `json:"corrections"`
+}
+
+// LateDataTracker detects late arrivals, counts them by lateness and applies
+// the configured policies
+type LateDataTracker struct {
+	threshold   time.Duration
+	policies    []LateDataPolicy
+	tag         bool
+	refresh     bool
+	corrections bool
+
+	late       atomic.Int64
+	byLateness []atomic.Int64
+	refreshes  atomic.Int64
+	corrected  atomic.Int64
+
+	// Aggregate bucket starts awaiting a targeted refresh, per view
+	mu      sync.Mutex
+	pending map[string]map[time.Time]struct{}
+
+	// Refreshes fail fast while the aggregate refresh breaker is open
+	breaker *CircuitBreaker
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewLateDataTracker creates a tracker; a zero threshold uses 30 minutes
+func NewLateDataTracker(cfg LateDataConfig) *LateDataTracker {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 30 * time.Minute
+	}
+	t := &LateDataTracker{
+		threshold:  cfg.Threshold,
+		policies:   cfg.Policies,
+		byLateness: make([]atomic.Int64, len(latenessBuckets)),
+		pending:    make(map[string]map[time.Time]struct{}),
+		stopCh:     make(chan struct{}),
+	}
+	for _, policy := range cfg.Policies {
+		switch policy {
+		case LatePolicyTag:
+			t.tag = true
+		case LatePolicyRefresh:
+			t.tag, t.refresh = true, true
+		case LatePolicyCorrections:
+			t.tag, t.corrections = true, true
+		}
+	}
+	return t
+}
+
+// ParseLateDataPolicies validates policy names
+func ParseLateDataPolicies(names []string) ([]LateDataPolicy, error) {
+	policies := make([]LateDataPolicy, 0, len(names))
+	for _, name := range names {
+		switch policy := LateDataPolicy(name); policy {
+		case LatePolicyTag, LatePolicyRefresh, LatePolicyCorrections:
+			policies = append(policies, policy)
+		default:
+			return nil, fmt.Errorf("unknown late data policy %q", name)
+		}
+	}
+	return policies, nil
+}
+
+// observe classifies a point on arrival, counting and tagging it when late
+func (t *LateDataTracker) observe(point *models.TelemetryPoint, now time.Time) {
+	if t == nil {
+		return
+	}
+	lateness := now.Sub(point.Timestamp)
+	if lateness <= t.threshold {
+		return
+	}
+
+	t.late.Add(1)
+	for i, bucket := range latenessBuckets {
+		if bucket.max == 0 || lateness <= bucket.max {
+			t.byLateness[i].Add(1)
+			break
+		}
+	}
+	if t.tag {
+		point.IsLate = true
+	}
+}
+
+// committed schedules aggregate refreshes for late points now in the database
+func (t *LateDataTracker) committed(points []models.TelemetryPoint) {
+	if t == nil || !t.refresh {
+		return
+	}
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, point := range points {
+		if !point.IsLate {
+			continue
+		}
+		for _, window := range aggregateRefreshWindows {
+			if now.Sub(point.Timestamp) <= window.startOffset {
+				continue // the scheduled policy still covers it
+			}
+			if t.pending[window.view] == nil {
+				t.pending[window.view] = make(map[time.Time]struct{})
+			}
+			t.pending[window.view][point.Timestamp.UTC().Truncate(window.bucket)] = struct{}{}
+		}
+	}
+}
+
+// insertCorrections queues late points for the corrections workflow in the
+// same transaction that stores them
+func (t *LateDataTracker) insertCorrections(ctx context.Context, tx pgx.Tx, points []models.TelemetryPoint) error {
+	if t == nil || !t.corrections {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for i := range points {
+		if !points[i].IsLate {
+			continue
+		}
+		batch.Queue(`INSERT INTO telemetry_corrections (satellite_id, time) VALUES ($1, $2)`,
+			points[i].SatelliteID, points[i].Timestamp)
+	}
+	if _, err := sendBatch(ctx, tx, batch); err != nil {
+		return err
+	}
+	t.corrected.Add(int64(batch.Len()))
+	return nil
+}
+
+// SetCircuitBreaker guards aggregate refreshes with cb; buckets whose
+// refresh it rejects stay pending for the next tick
+func (t *LateDataTracker) SetCircuitBreaker(cb *CircuitBreaker) {
+	t.breaker = cb
+}
+
+// Start refreshes pending aggregate buckets every interval until Stop
+func (t *LateDataTracker) Start(pool *pgxpool.Pool, interval time.Duration) {
+	if !t.refresh {
+		return
+	}
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+				if err := t.RefreshPending(ctx, pool); err != nil {
+					log.Printf("LateData: aggregate refresh failed: %v", err)
+				}
+				cancel()
+			case <-t.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the refresh loop; refreshes still pending are dropped
+func (t *LateDataTracker) Stop() {
+	close(t.stopCh)
+	t.wg.Wait()
+}
+
+// RefreshPending re-materializes every pending aggregate range.
+// Adjacent buckets are merged so a late downlink triggers one refresh per view.
+func (t *LateDataTracker) RefreshPending(ctx context.Context, pool *pgxpool.Pool) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[string]map[time.Time]struct{})
+	t.mu.Unlock()
+
+	for i, window := range aggregateRefreshWindows {
+		for _, r := range mergeBuckets(pending[window.view], window.bucket) {
+			// refresh_continuous_aggregate cannot run in a transaction block,
+			// so use the simple protocol (no implicit extended-protocol tx)
+			err := guard(t.breaker, func() error {
+				_, err := pool.Exec(ctx, `CALL refresh_continuous_aggregate($1, $2, $3)`,
+					pgx.QueryExecModeSimpleProtocol, window.view, r[0], r[1])
+				return err
+			})
+			if err != nil {
+				// Retry this view and the ones not reached on the next tick
+				t.requeue(pending, aggregateRefreshWindows[i:])
+				return fmt.Errorf("refresh %s [%s, %s): %w",
+					window.view, r[0].Format(time.RFC3339), r[1].Format(time.RFC3339), err)
+			}
+			t.refreshes.Add(1)
+		}
+	}
+	return nil
+}
+
+// requeue puts the bucket starts of the given views back after a failed refresh
+func (t *LateDataTracker) requeue(pending map[string]map[time.Time]struct{}, windows []aggregateRefreshWindow) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, window := range windows {
+		for start := range pending[window.view] {
+			if t.pending[window.view] == nil {
+				t.pending[window.view] = make(map[time.Time]struct{})
+			}
+			t.pending[window.view][start] = struct{}{}
+		}
+	}
+}
+
+// mergeBuckets turns bucket starts into sorted [from, to) ranges, joining
+// adjacent buckets
+func mergeBuckets(starts map[time.Time]struct{}, bucket time.Duration) [][2]time.Time {
+	sorted := make([]time.Time, 0, len(starts))
+	for start := range starts {
+		sorted = append(sorted, start)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	var ranges [][2]time.Time
+	for _, start := range sorted {
+		if n := len(ranges); n > 0 && ranges[n-1][1].Equal(start) {
+			ranges[n-1][1] = start.Add(bucket)
+			continue
+		}
+		ranges = append(ranges, [2]time.Time{start, start.Add(bucket)})
+	}
+	return ranges
+}
+
+// Stats returns late-arrival counters
+func (t *LateDataTracker) Stats() LateDataStats {
+	stats := LateDataStats{
+		Threshold:   t.threshold.String(),
+		Policies:    t.policies,
+		Late:        t.late.Load(),
+		ByLateness:  make(map[string]int64, len(latenessBuckets)),
+		Refreshes:   t.refreshes.Load(),
+		Corrections: t.corrected.Load(),
+	}
+	for i, bucket := range latenessBuckets {
+		stats.ByLateness[bucket.label] = t.byLateness[i].Load()
+	}
+	t.mu.Lock()
+	for _, starts := range t.pending {
+		stats.PendingRefreshes += len(starts)
+	}
+	t.mu.Unlock()
+	return stats
+}
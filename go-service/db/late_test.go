@@ -0,0 +1,133 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// TestLateDataTrackerObserve tests lateness detection, bucketing and tagging
+func TestLateDataTrackerObserve(t *testing.T) {
+	tracker := NewLateDataTracker(LateDataConfig{Policies: []LateDataPolicy{LatePolicyTag}})
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	onTime := TelemetryPointForTest(80, 1000, -60)
+	onTime.Timestamp = now.Add(-10 * time.Minute)
+	tracker.observe(&onTime, now)
+	if onTime.IsLate {
+		t.Error("expected point within threshold not to be late")
+	}
+
+	for _, age := range []time.Duration{time.Hour, 3 * time.Hour, 72 * time.Hour, 30 * 24 * time.Hour} {
+		point := TelemetryPointForTest(80, 1000, -60)
+		point.Timestamp = now.Add(-age)
+		tracker.observe(&point, now)
+		if !point.IsLate {
+			t.Errorf("expected point %v old to be tagged late", age)
+		}
+	}
+
+	stats := tracker.Stats()
+	if stats.Late != 4 {
+		t.Errorf("expected 4 late points, got %d", stats.Late)
+	}
+	for _, label := range []string{"<=2h", "<=48h", "<=7d", ">7d"} {
+		if stats.ByLateness[label] != 1 {
+			t.Errorf("expected 1 point in bucket %s, got %d", label, stats.ByLateness[label])
+		}
+	}
+}
+
+// TestLateDataTrackerCountsWithoutTagging tests that no policy only counts late points
+func TestLateDataTrackerCountsWithoutTagging(t *testing.T) {
+	tracker := NewLateDataTracker(LateDataConfig{})
+	now := time.Now()
+
+	point := TelemetryPointForTest(80, 1000, -60)
+	point.Timestamp = now.Add(-2 * time.Hour)
+	tracker.observe(&point, now)
+
+	if point.IsLate {
+		t.Error("expected point not to be tagged without a policy")
+	}
+	if tracker.Stats().Late != 1 {
+		t.Errorf("expected late point to be counted, got %d", tracker.Stats().Late)
+	}
+}
+
+// TestLateDataTrackerSchedulesRefreshes tests that only views past their refresh window are scheduled
+func TestLateDataTrackerSchedulesRefreshes(t *testing.T) {
+	tracker := NewLateDataTracker(LateDataConfig{Policies: []LateDataPolicy{LatePolicyRefresh}})
+
+	// Three hours late: outside satellite_stats (30m) only
+	point := TelemetryPointForTest(80, 1000, -60)
+	point.Timestamp = time.Now().Add(-3 * time.Hour)
+	point.IsLate = true
+	// A second point in the same 5-minute bucket shares one refresh
+	sameBucket := point
+	sameBucket.Timestamp = point.Timestamp.Truncate(5 * time.Minute).Add(time.Second)
+	onTime := TelemetryPointForTest(80, 1000, -60)
+	onTime.Timestamp = time.Now()
+
+	tracker.committed([]models.TelemetryPoint{point, sameBucket, onTime})
+
+	if len(tracker.pending) != 1 || len(tracker.pending["satellite_stats"]) != 1 {
+		t.Errorf("expected one satellite_stats bucket pending, got %v", tracker.pending)
+	}
+	if tracker.Stats().PendingRefreshes != 1 {
+		t.Errorf("expected 1 pending refresh, got %d", tracker.Stats().PendingRefreshes)
+	}
+}
+
+// TestMergeBuckets tests that adjacent buckets are merged into one range
+func TestMergeBuckets(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	starts := map[time.Time]struct{}{
+		base.Add(time.Hour):     {},
+		base:                    {},
+		base.Add(5 * time.Hour): {},
+	}
+
+	ranges := mergeBuckets(starts, time.Hour)
+	if len(ranges) != 2 {
+		t.Fatalf("expected 2 ranges, got %d: %v", len(ranges), ranges)
+	}
+	if !ranges[0][0].Equal(base) || !ranges[0][1].Equal(base.Add(2*time.Hour)) {
+		t.Errorf("unexpected first range %v", ranges[0])
+	}
+	if !ranges[1][0].Equal(base.Add(5*time.Hour)) || !ranges[1][1].Equal(base.Add(6*time.Hour)) {
+		t.Errorf("unexpected second range %v", ranges[1])
+	}
+}
+
+// TestParseLateDataPolicies tests policy name validation
+func TestParseLateDataPolicies(t *testing.T) {
+	policies, err := ParseLateDataPolicies([]string{"tag", "refresh", "corrections"})
+	if err != nil || len(policies) != 3 {
+		t.Errorf("expected 3 valid policies, got %v (%v)", policies, err)
+	}
+	if _, err := ParseLateDataPolicies([]string{"drop"}); err == nil {
+		t.Error("expected unknown policy to be rejected")
+	}
+}
+
+// TestBatchProcessorTagsLateData tests that Add runs late-arrival detection
+func TestBatchProcessorTagsLateData(t *testing.T) {
+	bp := &BatchProcessor{
+		batchSize:     100,
+		maxBufferSize: 100,
+		anomalyConfig: AnomalyConfig{BatteryMinPercent: 10, StorageMaxMB: 95000, SignalMinDBM: -100},
+	}
+	bp.SetLateDataTracker(NewLateDataTracker(LateDataConfig{Policies: []LateDataPolicy{LatePolicyTag}}))
+
+	point := TelemetryPointForTest(80, 1000, -60)
+	point.Timestamp = time.Now().Add(-6 * time.Hour)
+	if err := bp.Add(point); err != nil {
+		t.Fatalf("failed to add point: %v", err)
+	}
+
+	if !bp.buffer[0].IsLate {
+		t.Error("expected buffered point to be tagged late")
+	}
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultLeaderLockKey is the advisory lock key replicas elect a leader with
+const DefaultLeaderLockKey int64 = 0x6f726269 // "orbi"
+
+// Leadership tells a background job whether this replica runs it
+// This allows for mocking in tests
+type Leadership interface {
+	IsLeader() bool
+}
+
+// LeaderStatus reports this replica's part in leader election
+type LeaderStatus struct {
+	Identity string     `json:"identity"`
+	Leader   bool       `json:"leader"`
+	Since    *time.Time `json:"since,omitempty"`
+	LockKey  int64      `json:"lock_key"`
+	// Transitions counts leadership gained and lost since start
+	Transitions int64 `json:"transitions"`
+}
+
+// LeaderElector elects the one replica that runs cluster-wide background
+// jobs (decay alerts, index advice, fleet reports) with a Postgres session
+// advisory lock held on a dedicated connection. Replicas try to take the
+// lock every interval; the leader pings its connection instead and steps
+// down when the ping fails, closing the session so the lock is released.
+// Postgres also releases the lock when a partitioned leader's session dies,
+// and jobs re-check IsLeader before every run, so ownership moves to the
+// next replica within about one interval plus the server's connection
+// timeout. Per-replica work (WAL replay, flushes) never depends on it.
+type LeaderElector struct {
+	pool     *pgxpool.Pool
+	key      int64
+	identity string
+	interval time.Duration
+	// Leader session holding the lock (nil = follower)
+	conn *pgxpool.Conn
+
+	mu          sync.Mutex
+	leader      bool
+	since       time.Time
+	transitions int64
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewLeaderElector creates an elector; identity names this replica in logs
+// and status (e.g. the hostname)
+func NewLeaderElector(pool *pgxpool.Pool, key int64, identity string, interval time.Duration) *LeaderElector {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &LeaderElector{
+		pool:     pool,
+		key:      key,
+		identity: identity,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start tries to take leadership right away, so jobs started next see the
+// outcome, and then every interval until Stop
+func (e *LeaderElector) Start() {
+	e.elect()
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.elect()
+			case <-e.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the election loop and hands leadership over by releasing the lock
+func (e *LeaderElector) Stop() {
+	close(e.stopCh)
+	e.wg.Wait()
+
+	if e.conn == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.interval)
+	defer cancel()
+	if _, err := e.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", e.key); err != nil {
+		log.Printf("LeaderElector: failed to release leadership: %v", err)
+	}
+	e.stepDown("shutting down")
+}
+
+// IsLeader reports whether this replica holds the leader lock
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Status returns this replica's election state
+func (e *LeaderElector) Status() LeaderStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	status := LeaderStatus{
+		Identity:    e.identity,
+		Leader:      e.leader,
+		LockKey:     e.key,
+		Transitions: e.transitions,
+	}
+	if e.leader {
+		since := e.since
+		status.Since = &since
+	}
+	return status
+}
+
+// elect keeps leadership alive or tries to take it. Only the election loop
+// (and Stop once it ended) touches conn, so the database round trips run
+// without holding mu.
+func (e *LeaderElector) elect() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.interval)
+	defer cancel()
+
+	if e.conn != nil {
+		if _, err := e.conn.Exec(ctx, "SELECT 1"); err != nil {
+			e.stepDown(err.Error())
+		}
+		return
+	}
+
+	conn, err := e.pool.Acquire(ctx)
+	if err != nil {
+		return
+	}
+	var acquired bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&acquired); err != nil || !acquired {
+		conn.Release()
+		return
+	}
+	e.conn = conn
+	e.setLeader(true)
+	log.Printf("EVENT: leadership_change identity=%s leader=true", e.identity)
+}
+
+// stepDown closes the leader session, which releases the lock even when it
+// couldn't be unlocked, and drops the connection from the pool
+func (e *LeaderElector) stepDown(reason string) {
+	e.setLeader(false)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_ = e.conn.Conn().Close(ctx)
+	e.conn.Release()
+	e.conn = nil
+	log.Printf("EVENT: leadership_change identity=%s leader=false reason=%q", e.identity, reason)
+}
+
+// setLeader records a leadership transition
+func (e *LeaderElector) setLeader(leader bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leader = leader
+	e.since = time.Now().UTC()
+	e.transitions++
+}
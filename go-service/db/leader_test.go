@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeLeadership reports a fixed leadership
+type fakeLeadership bool
+
+func (f fakeLeadership) IsLeader() bool { return bool(f) }
+
+// TestDecayMonitorRunsOnLeaderOnly tests that a follower replica neither
+// checks trends nor sends alerts
+func TestDecayMonitorRunsOnLeaderOnly(t *testing.T) {
+	querier := &fakeTrendQuerier{trends: []AltitudeTrend{
+		{SatelliteID: "SAT-001", SlopeKMPerDay: -0.8, R2: 0.9},
+	}}
+
+	sink := &recordingAlertSink{}
+	follower := NewDecayMonitor(querier, sink, testDecayConfig())
+	follower.SetLeadership(fakeLeadership(false))
+	follower.Start()
+	follower.Stop()
+	if len(sink.events) != 0 || len(follower.Findings()) != 0 {
+		t.Errorf("expected no check on a follower, got %d alerts", len(sink.events))
+	}
+
+	leader := NewDecayMonitor(querier, sink, testDecayConfig())
+	leader.SetLeadership(fakeLeadership(true))
+	leader.Start()
+	leader.Stop()
+	if len(sink.events) != 1 {
+		t.Errorf("expected the leader to alert once, got %d alerts", len(sink.events))
+	}
+}
+
+// TestLeaderElectorHandsOver tests that one of two replicas leads and the
+// other takes over once it stops
+func TestLeaderElectorHandsOver(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+
+	first := NewLeaderElector(pool, DefaultLeaderLockKey, "replica-1", time.Hour)
+	first.Start()
+	second := NewLeaderElector(pool, DefaultLeaderLockKey, "replica-2", time.Hour)
+	second.Start()
+	defer second.Stop()
+
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatalf("expected replica-1 to lead, got %v and %v", first.IsLeader(), second.IsLeader())
+	}
+
+	first.Stop()
+	if first.IsLeader() {
+		t.Error("expected replica-1 to step down on Stop")
+	}
+	second.elect()
+	status := second.Status()
+	if !status.Leader || status.Since == nil || status.Transitions != 1 {
+		t.Errorf("expected replica-2 to take over, got %+v", status)
+	}
+
+	// A lost session releases the lock: the leader steps down at its next ping
+	_ = second.conn.Conn().Close(context.Background())
+	second.elect()
+	if second.IsLeader() {
+		t.Error("expected replica-2 to step down after losing its session")
+	}
+}
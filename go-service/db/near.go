@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"orbitstream/models"
+)
+
+// NearQuery selects the points whose ground track position lies within a
+// radius of a ground point
+type NearQuery struct {
+	Latitude  float64
+	Longitude float64
+	RadiusKM  float64
+	// SatelliteID restricts the query to one satellite (empty = fleet)
+	SatelliteID string
+	From        time.Time
+	To          time.Time
+	Limit       int
+}
+
+// nearBounds returns the latitude/longitude box enclosing the circle, for
+// the index prefilter; the longitude range is unrestricted when the circle
+// reaches a pole
+func nearBounds(lat, lon, radiusKM float64) GeofenceBox {
+	angular := radiusKM / earthRadiusKM
+	dLat := angular * 180 / math.Pi
+	box := GeofenceBox{MinLat: lat - dLat, MaxLat: lat + dLat, MinLon: -180, MaxLon: 180}
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		box.MinLat = math.Max(box.MinLat, -90)
+		box.MaxLat = math.Min(box.MaxLat, 90)
+		return box
+	}
+	dLon := math.Asin(math.Min(1, math.Sin(angular)/math.Cos(lat*math.Pi/180))) * 180 / math.Pi
+	if dLon >= 90 {
+		return box
+	}
+	box.MinLon, box.MaxLon = lon-dLon, lon+dLon
+	if box.MinLon < -180 {
+		box.MinLon += 360
+	}
+	if box.MaxLon > 180 {
+		box.MaxLon -= 360
+	}
+	return box
+}
+
+// QueryNear returns the points within the radius, newest first, with their
+// great-circle distance over a spherical Earth
+func (qs *QueryService) QueryNear(ctx context.Context, q NearQuery) ([]models.NearbyPoint, error) {
+	box := nearBounds(q.Latitude, q.Longitude, q.RadiusKM)
+	lonFilter := `longitude BETWEEN $8 AND $9`
+	if box.MinLon > box.MaxLon {
+		lonFilter = `(longitude >= $8 OR longitude <= $9)`
+	}
+	rows, err := qs.query(ctx, `
+		SELECT * FROM (
+			SELECT`+telemetrySelectColumns+`,
+				2 * $10::float8 * ASIN(LEAST(1, SQRT(
+					POWER(SIN(RADIANS(latitude - $1) / 2), 2) +
+					COS(RADIANS($1)) * COS(RADIANS(latitude)) *
+					POWER(SIN(RADIANS(longitude - $2) / 2), 2)
+				))) AS distance_km
+			FROM telemetry
+			WHERE time >= $3 AND time < $4
+			  AND ($5 = '' OR satellite_id = $5)
+			  AND latitude BETWEEN $6 AND $7 AND `+lonFilter+`
+		) s
+		WHERE distance_km <= $11
+		ORDER BY time DESC, satellite_id
+		LIMIT $12
+	`, q.Latitude, q.Longitude, q.From, q.To, q.SatelliteID,
+		box.MinLat, box.MaxLat, box.MinLon, box.MaxLon,
+		earthRadiusKM, q.RadiusKM, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make([]models.NearbyPoint, 0)
+	for rows.Next() {
+		var p models.NearbyPoint
+		p.TelemetryPoint, err = scanTelemetryPoint(rows, &p.DistanceKM)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
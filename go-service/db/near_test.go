@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// greatCircleKM is the haversine distance the query computes in SQL
+func greatCircleKM(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	a := math.Pow(math.Sin((lat2-lat1)*rad/2), 2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Pow(math.Sin((lon2-lon1)*rad/2), 2)
+	return 2 * earthRadiusKM * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func TestNearBounds(t *testing.T) {
+	cases := []struct {
+		name          string
+		lat, lon, rkm float64
+		wrapped, full bool
+	}{
+		{name: "mid latitude", lat: 48.1, lon: 11.6, rkm: 500},
+		{name: "across the antimeridian", lat: -17.7, lon: 178.1, rkm: 800, wrapped: true},
+		{name: "over the pole", lat: 78.2, lon: 15.4, rkm: 2000, full: true},
+	}
+	for _, tc := range cases {
+		box := nearBounds(tc.lat, tc.lon, tc.rkm)
+		if wrapped := box.MinLon > box.MaxLon; wrapped != tc.wrapped {
+			t.Errorf("%s: wrapped = %v, box %+v", tc.name, wrapped, box)
+		}
+		if full := box.MinLon == -180 && box.MaxLon == 180; full != tc.full {
+			t.Errorf("%s: full longitude range = %v, box %+v", tc.name, full, box)
+		}
+		// Every position inside the circle must pass the prefilter
+		for lat := -90.0; lat <= 90; lat += 0.5 {
+			for lon := -180.0; lon <= 180; lon += 0.5 {
+				if greatCircleKM(tc.lat, tc.lon, lat, lon) <= tc.rkm && !box.contains(lat, lon) {
+					t.Fatalf("%s: %v, %v is within %v km but outside %+v", tc.name, lat, lon, tc.rkm, box)
+				}
+			}
+		}
+	}
+}
+
+func TestQueryNear(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	ctx := context.Background()
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	// Passes near Fiji on both sides of the antimeridian, and one far away
+	points := []models.TelemetryPoint{
+		positionedPoint("SAT-A", start, -17.5, 179.5, 500),
+		positionedPoint("SAT-B", start.Add(time.Minute), -18.0, -179.5, 500),
+		positionedPoint("SAT-C", start.Add(2*time.Minute), 40.0, -100.0, 500),
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("begin failed: %v", err)
+	}
+	if _, err := insertTelemetry(ctx, tx, points); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+
+	near, err := NewQueryService(pool).QueryNear(ctx, NearQuery{
+		Latitude: -17.7, Longitude: 178.1, RadiusKM: 500,
+		From: start, To: start.Add(time.Hour), Limit: 100,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(near) != 2 || near[0].SatelliteID != "SAT-B" || near[1].SatelliteID != "SAT-A" {
+		t.Fatalf("expected SAT-B then SAT-A, got %+v", near)
+	}
+	if want := greatCircleKM(-17.7, 178.1, -17.5, 179.5); math.Abs(near[1].DistanceKM-want) > 0.01 {
+		t.Errorf("distance = %v, want %v", near[1].DistanceKM, want)
+	}
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+)
+
+// ErrOverflowFull is returned by Push when a batch would exceed the limit
+var ErrOverflowFull = errors.New("overflow queue full")
+
+// overflowFile is one spilled batch on disk
+type overflowFile struct {
+	path   string
+	points int
+	// satellites summarises the batch per satellite for /admin/pending
+	satellites map[string]overflowSpan
+}
+
+// overflowSpan is how many points of a satellite a batch holds and their
+// time range
+type overflowSpan struct {
+	count          int
+	oldest, newest time.Time
+}
+
+// summarizeOverflow builds the per-satellite spans of a batch
+func summarizeOverflow(points int, satelliteAt func(i int) (string, time.Time)) map[string]overflowSpan {
+	spans := make(map[string]overflowSpan)
+	for i := 0; i < points; i++ {
+		id, ts := satelliteAt(i)
+		span, ok := spans[id]
+		if !ok || ts.Before(span.oldest) {
+			span.oldest = ts
+		}
+		if !ok || ts.After(span.newest) {
+			span.newest = ts
+		}
+		span.count++
+		spans[id] = span
+	}
+	return spans
+}
+
+// OverflowQueue holds batches on disk while the database is healthy but
+// too slow to keep up, so they are retried later instead of going to the
+// WAL, which stays reserved for outages. It is flow control, not
+// durability: files are not fsynced. A batch taken back keeps its file until
+// Ack, once it is stored, so a crash in between picks it up again at the
+// next start.
+type OverflowQueue struct {
+	dir       string
+	maxPoints int
+	mu        sync.Mutex
+	files     []overflowFile // oldest first
+	points    int
+	nextSeq   uint64
+}
+
+// NewOverflowQueue opens the queue in dir, picking up batches left by a
+// previous run. maxPoints bounds the spilled points (0 = unbounded).
+func NewOverflowQueue(dir string, maxPoints int) (*OverflowQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create overflow directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overflow directory: %w", err)
+	}
+
+	// Entries come sorted by name, and names sort by sequence
+	q := &OverflowQueue{dir: dir, maxPoints: maxPoints}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			// Interrupted while spilling; the batch went to the WAL instead
+			_ = os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		var seq uint64
+		var points int
+		if _, err := fmt.Sscanf(name, "overflow-%d-%d.json", &seq, &points); err != nil {
+			continue
+		}
+		file := overflowFile{path: filepath.Join(dir, name), points: points}
+		// Unreadable files are skipped when popped; until then their
+		// points count towards the queue but not towards any satellite
+		var records []WALRecord
+		if data, err := os.ReadFile(file.path); err == nil && json.Unmarshal(data, &records) == nil {
+			file.satellites = summarizeOverflow(len(records), func(i int) (string, time.Time) {
+				return records[i].SatelliteID, records[i].Timestamp
+			})
+		}
+		q.files = append(q.files, file)
+		q.points += points
+		if seq >= q.nextSeq {
+			q.nextSeq = seq + 1
+		}
+	}
+	return q, nil
+}
+
+// Push spills a batch; it returns ErrOverflowFull instead of going over the
+// limit
+func (q *OverflowQueue) Push(batch []models.TelemetryPoint) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	records := getWALRecords(len(batch))
+	for _, point := range batch {
+		records = append(records, NewWALRecord(point))
+	}
+	data, err := json.Marshal(records)
+	putWALRecords(records)
+	if err != nil {
+		return fmt.Errorf("failed to encode overflow batch: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.maxPoints > 0 && q.points+len(batch) > q.maxPoints {
+		return ErrOverflowFull
+	}
+
+	// Written under a temporary name so a half-written batch is never read
+	path := filepath.Join(q.dir, fmt.Sprintf("overflow-%020d-%d.json", q.nextSeq, len(batch)))
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return fmt.Errorf("failed to write overflow batch: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return fmt.Errorf("failed to write overflow batch: %w", err)
+	}
+	q.nextSeq++
+	q.files = append(q.files, overflowFile{path: path, points: len(batch),
+		satellites: summarizeOverflow(len(batch), func(i int) (string, time.Time) {
+			return batch[i].SatelliteID, batch[i].Timestamp
+		})})
+	q.points += len(batch)
+	return nil
+}
+
+// Pop takes the oldest batch back along with the path of its file, which
+// stays on disk until the batch is acknowledged with Ack. Unreadable files
+// (cut short by a crash) are logged, removed and skipped.
+func (q *OverflowQueue) Pop() ([]models.TelemetryPoint, string, bool) {
+	for {
+		file, ok := q.take()
+		if !ok {
+			return nil, "", false
+		}
+
+		data, err := os.ReadFile(file.path)
+		var records []WALRecord
+		if err == nil {
+			err = json.Unmarshal(data, &records)
+		}
+		if err != nil {
+			log.Printf("WARNING: Dropping unreadable overflow batch %s (%d points): %v", file.path, file.points, err)
+			q.Ack(file.path)
+			continue
+		}
+
+		batch := getPointBatch(len(records))
+		for _, record := range records {
+			batch = append(batch, record.TelemetryPoint())
+		}
+		return batch, file.path, true
+	}
+}
+
+// take removes the oldest file from the queue
+func (q *OverflowQueue) take() (overflowFile, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.files) == 0 {
+		return overflowFile{}, false
+	}
+	file := q.files[0]
+	q.files = q.files[1:]
+	q.points -= file.points
+	return file, true
+}
+
+// Ack removes the file of a batch taken back with Pop once the batch is
+// stored in the database or the WAL
+func (q *OverflowQueue) Ack(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("WARNING: Failed to remove overflow batch %s: %v", path, err)
+	}
+}
+
+// pendingFor counts the spilled points of a satellite and records their
+// time range in the summary
+func (q *OverflowQueue) pendingFor(satelliteID string, summary *PendingWrites) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	count := 0
+	for _, file := range q.files {
+		if span, ok := file.satellites[satelliteID]; ok {
+			count += span.count
+			summary.observe(span.oldest)
+			summary.observe(span.newest)
+		}
+	}
+	return count
+}
+
+// Len returns the number of spilled points
+func (q *OverflowQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.points
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+func overflowBatchForTest(n int, start time.Time) []models.TelemetryPoint {
+	batch := make([]models.TelemetryPoint, n)
+	for i := range batch {
+		batch[i] = TelemetryPointForTest(85.0, 45000.0, -55.0)
+		batch[i].Timestamp = start.Add(time.Duration(i) * time.Second)
+	}
+	return batch
+}
+
+// TestOverflowQueuePushPop tests that batches come back oldest first and
+// their files are removed once acknowledged
+func TestOverflowQueuePushPop(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewOverflowQueue(dir, 0)
+	if err != nil {
+		t.Fatalf("failed to create overflow queue: %v", err)
+	}
+	first := time.Now().UTC().Truncate(time.Second)
+	second := first.Add(time.Hour)
+	if err := q.Push(overflowBatchForTest(3, first)); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if err := q.Push(overflowBatchForTest(2, second)); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if q.Len() != 5 {
+		t.Errorf("expected 5 spilled points, got %d", q.Len())
+	}
+
+	batch, firstPath, ok := q.Pop()
+	if !ok || len(batch) != 3 || !batch[0].Timestamp.Equal(first) {
+		t.Fatalf("expected the first batch back, got %d points (%v)", len(batch), ok)
+	}
+	batch, secondPath, ok := q.Pop()
+	if !ok || len(batch) != 2 || !batch[0].Timestamp.Equal(second) {
+		t.Fatalf("expected the second batch back, got %d points (%v)", len(batch), ok)
+	}
+	if _, _, ok := q.Pop(); ok {
+		t.Error("expected an empty queue")
+	}
+	if q.Len() != 0 {
+		t.Errorf("expected no spilled points queued, got %d", q.Len())
+	}
+
+	// Until they are stored the batches are picked up again after a crash
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("expected both files kept until acknowledged, got %d", len(entries))
+	}
+	q.Ack(firstPath)
+	q.Ack(secondPath)
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected no files left, got %d", len(entries))
+	}
+}
+
+// TestOverflowQueueReopen tests that batches left by a previous run are
+// picked up in order and interrupted spills are removed
+func TestOverflowQueueReopen(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewOverflowQueue(dir, 0)
+	if err != nil {
+		t.Fatalf("failed to create overflow queue: %v", err)
+	}
+	start := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 12; i++ {
+		if err := q.Push(overflowBatchForTest(1, start.Add(time.Duration(i)*time.Minute))); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "overflow-99-1.json.tmp"), []byte("[{"), 0644); err != nil {
+		t.Fatalf("failed to write partial file: %v", err)
+	}
+
+	reopened, err := NewOverflowQueue(dir, 0)
+	if err != nil {
+		t.Fatalf("failed to reopen overflow queue: %v", err)
+	}
+	if reopened.Len() != 12 {
+		t.Fatalf("expected 12 spilled points, got %d", reopened.Len())
+	}
+	for i := 0; i < 12; i++ {
+		batch, _, ok := reopened.Pop()
+		if want := start.Add(time.Duration(i) * time.Minute); !ok || !batch[0].Timestamp.Equal(want) {
+			t.Fatalf("batch %d: expected %v, got %v", i, want, batch)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "overflow-99-1.json.tmp")); !os.IsNotExist(err) {
+		t.Error("expected the interrupted spill to be removed")
+	}
+}
+
+// TestOverflowQueueFull tests that pushes beyond the limit are refused
+func TestOverflowQueueFull(t *testing.T) {
+	q, err := NewOverflowQueue(t.TempDir(), 4)
+	if err != nil {
+		t.Fatalf("failed to create overflow queue: %v", err)
+	}
+	if err := q.Push(overflowBatchForTest(3, time.Now())); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if err := q.Push(overflowBatchForTest(2, time.Now())); !errors.Is(err, ErrOverflowFull) {
+		t.Errorf("expected ErrOverflowFull, got %v", err)
+	}
+}
+
+// TestOverflowQueueSkipsUnreadable tests that a truncated batch is dropped
+// and the next one is returned
+func TestOverflowQueueSkipsUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewOverflowQueue(dir, 0)
+	if err != nil {
+		t.Fatalf("failed to create overflow queue: %v", err)
+	}
+	if err := q.Push(overflowBatchForTest(2, time.Now())); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if err := q.Push(overflowBatchForTest(1, time.Now())); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if err := os.WriteFile(q.files[0].path, []byte("[{"), 0644); err != nil {
+		t.Fatalf("failed to truncate batch: %v", err)
+	}
+
+	batch, _, ok := q.Pop()
+	if !ok || len(batch) != 1 {
+		t.Errorf("expected the readable batch, got %d points (%v)", len(batch), ok)
+	}
+	if q.Len() != 0 {
+		t.Errorf("expected an empty queue, got %d", q.Len())
+	}
+}
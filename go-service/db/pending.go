@@ -0,0 +1,94 @@
+package db
+
+import (
+	"time"
+
+	"orbitstream/models"
+)
+
+// PendingWrites summarises data for one satellite that has been accepted
+// but is not yet committed to the database
+type PendingWrites struct {
+	SatelliteID   string     `json:"satellite_id"`
+	BufferCount   int        `json:"buffer_count"`
+	InFlightCount int        `json:"in_flight_count"`
+	WALCount      int        `json:"wal_count"`
+	OverflowCount int        `json:"overflow_count"`
+	TotalPending  int        `json:"total_pending"`
+	OldestPending *time.Time `json:"oldest_pending,omitempty"`
+	NewestPending *time.Time `json:"newest_pending,omitempty"`
+}
+
+// observe folds one pending timestamp into the summary
+func (p *PendingWrites) observe(ts time.Time) {
+	if p.OldestPending == nil || ts.Before(*p.OldestPending) {
+		t := ts
+		p.OldestPending = &t
+	}
+	if p.NewestPending == nil || ts.After(*p.NewestPending) {
+		t := ts
+		p.NewestPending = &t
+	}
+}
+
+// PendingForSatellite reports how many points for a satellite are still in
+// the memory buffer, in a batch currently being flushed, in the WAL or in
+// the overflow queue.
+// It answers "did this satellite's last pass actually reach the database?"
+func (bp *BatchProcessor) PendingForSatellite(satelliteID string) (PendingWrites, error) {
+	summary := PendingWrites{SatelliteID: satelliteID}
+
+	bp.bufferMutex.Lock()
+	buffered := bp.buffer
+	if shard := bp.shardFor(satelliteID); shard != nil {
+		buffered = shard.points
+	}
+	for _, point := range buffered {
+		if point.SatelliteID == satelliteID {
+			summary.BufferCount++
+			summary.observe(point.Timestamp)
+		}
+	}
+	for _, batch := range bp.inFlight {
+		summary.InFlightCount += countPending(batch, satelliteID, &summary)
+	}
+	wal := bp.wal
+	overflow := bp.overflow
+	bp.bufferMutex.Unlock()
+
+	if overflow != nil {
+		summary.OverflowCount = overflow.pendingFor(satelliteID, &summary)
+	}
+
+	if wal != nil {
+		it, err := wal.Iter()
+		if err != nil {
+			return summary, err
+		}
+		defer it.Close()
+		for it.Next() {
+			if record := it.Record(); record.SatelliteID == satelliteID {
+				summary.WALCount++
+				summary.observe(record.Timestamp)
+			}
+		}
+		if err := it.Err(); err != nil {
+			return summary, err
+		}
+	}
+
+	summary.TotalPending = summary.BufferCount + summary.InFlightCount + summary.WALCount + summary.OverflowCount
+	return summary, nil
+}
+
+// countPending counts points for a satellite in a batch and records their timestamps
+func countPending(batch []models.TelemetryPoint, satelliteID string, summary *PendingWrites) int {
+	count := 0
+	for _, point := range batch {
+		if point.SatelliteID == satelliteID {
+			count++
+			summary.observe(point.Timestamp)
+		}
+	}
+	return count
+}
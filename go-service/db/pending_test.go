@@ -0,0 +1,140 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// TestPendingForSatelliteBufferAndWAL tests counting pending points across buffer, in-flight and WAL
+func TestPendingForSatelliteBufferAndWAL(t *testing.T) {
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "test.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	bp := &BatchProcessor{
+		buffer:        make([]models.TelemetryPoint, 0, 100),
+		batchSize:     100,
+		maxBufferSize: 1000,
+		wal:           wal,
+	}
+
+	// Two points in buffer for SAT-042, one for another satellite
+	for i, id := range []string{"SAT-042", "SAT-001", "SAT-042"} {
+		p := TelemetryPointForTest(80.0, 45000.0, -55.0)
+		p.SatelliteID = id
+		p.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		bp.buffer = append(bp.buffer, p)
+	}
+
+	// One batch in flight
+	inflight := TelemetryPointForTest(80.0, 45000.0, -55.0)
+	inflight.SatelliteID = "SAT-042"
+	inflight.Timestamp = base.Add(-time.Hour)
+	bp.bufferMutex.Lock()
+	id := bp.trackInFlight([]models.TelemetryPoint{inflight})
+	bp.bufferMutex.Unlock()
+
+	// Oldest record is in the WAL
+	oldest := base.Add(-2 * time.Hour)
+	_ = wal.Write(WALRecord{SatelliteID: "SAT-042", Timestamp: oldest})
+	_ = wal.Write(WALRecord{SatelliteID: "SAT-001", Timestamp: oldest})
+
+	pending, err := bp.PendingForSatellite("SAT-042")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if pending.BufferCount != 2 {
+		t.Errorf("expected 2 buffered points, got %d", pending.BufferCount)
+	}
+	if pending.InFlightCount != 1 {
+		t.Errorf("expected 1 in-flight point, got %d", pending.InFlightCount)
+	}
+	if pending.WALCount != 1 {
+		t.Errorf("expected 1 WAL record, got %d", pending.WALCount)
+	}
+	if pending.TotalPending != 4 {
+		t.Errorf("expected 4 total pending, got %d", pending.TotalPending)
+	}
+	if pending.OldestPending == nil || !pending.OldestPending.Equal(oldest) {
+		t.Errorf("expected oldest pending %v, got %v", oldest, pending.OldestPending)
+	}
+	if pending.NewestPending == nil || !pending.NewestPending.Equal(base.Add(2*time.Minute)) {
+		t.Errorf("unexpected newest pending %v", pending.NewestPending)
+	}
+
+	// Once the in-flight batch completes it no longer counts
+	bp.untrackInFlight(id)
+	pending, _ = bp.PendingForSatellite("SAT-042")
+	if pending.InFlightCount != 0 {
+		t.Errorf("expected 0 in-flight after untrack, got %d", pending.InFlightCount)
+	}
+}
+
+// TestPendingForSatelliteNothingPending tests a satellite with everything committed
+func TestPendingForSatelliteNothingPending(t *testing.T) {
+	bp := &BatchProcessor{}
+
+	pending, err := bp.PendingForSatellite("SAT-042")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pending.TotalPending != 0 || pending.OldestPending != nil {
+		t.Errorf("expected nothing pending, got %+v", pending)
+	}
+}
+
+// TestPendingForSatelliteOverflow tests that spilled batches count as
+// pending until they are taken back
+func TestPendingForSatelliteOverflow(t *testing.T) {
+	overflow, err := NewOverflowQueue(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("failed to create overflow queue: %v", err)
+	}
+	bp := &BatchProcessor{overflow: overflow}
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	batch := overflowBatchForTest(3, base)
+	batch[1].SatelliteID = "SAT-042"
+	batch[2].SatelliteID = "SAT-042"
+	if err := overflow.Push(batch); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+
+	pending, err := bp.PendingForSatellite("SAT-042")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pending.OverflowCount != 2 || pending.TotalPending != 2 {
+		t.Errorf("expected 2 spilled points pending, got %+v", pending)
+	}
+	if pending.OldestPending == nil || !pending.OldestPending.Equal(base.Add(time.Second)) ||
+		pending.NewestPending == nil || !pending.NewestPending.Equal(base.Add(2*time.Second)) {
+		t.Errorf("unexpected pending range %v - %v", pending.OldestPending, pending.NewestPending)
+	}
+	if stats := bp.Stats(); stats.OverflowQueued != 3 {
+		t.Errorf("expected 3 points queued in the overflow, got %d", stats.OverflowQueued)
+	}
+
+	// Batches left by a previous run are summarised when reopened
+	reopened, err := NewOverflowQueue(overflow.dir, 0)
+	if err != nil {
+		t.Fatalf("failed to reopen overflow queue: %v", err)
+	}
+	bp.overflow = reopened
+	if pending, _ := bp.PendingForSatellite("SAT-042"); pending.OverflowCount != 2 {
+		t.Errorf("expected 2 spilled points after reopening, got %+v", pending)
+	}
+
+	reopened.Pop()
+	// Taken back but not stored yet: in flight rather than spilled
+	if pending, _ := bp.PendingForSatellite("SAT-042"); pending.OverflowCount != 0 {
+		t.Errorf("expected nothing pending once the batch is taken back, got %+v", pending)
+	}
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"sync/atomic"
+	"time"
+
+	"orbitstream/models"
+)
+
+// pipelineCounters counts points through the pipeline; the zero value is
+// ready to use
+type pipelineCounters struct {
+	accepted        atomic.Int64
+	rejected        atomic.Int64
+	duplicates      atomic.Int64
+	anomalies       atomic.Int64
+	flushed         atomic.Int64
+	flushes         atomic.Int64
+	walDiverted     atomic.Int64
+	overflowSpilled atomic.Int64
+	deadLettered    atomic.Int64
+}
+
+// recordCommit counts a flush that committed points and keeps its timing
+func (bp *BatchProcessor) recordCommit(points int, duration time.Duration) {
+	bp.counters.flushed.Add(int64(points))
+	bp.counters.flushes.Add(1)
+
+	last := models.LastFlush{
+		Time:       time.Now().UTC(),
+		Points:     points,
+		DurationMS: float64(duration.Microseconds()) / 1000,
+	}
+	if duration > 0 {
+		last.Throughput = float64(points) / duration.Seconds()
+	}
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.lastCommit = last
+}
+
+// Stats returns the pipeline totals since the processor was created, the
+// buffered points and recent insert throughput
+func (bp *BatchProcessor) Stats() models.PipelineStats {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	stats := models.PipelineStats{
+		Since:           bp.created,
+		Accepted:        bp.counters.accepted.Load(),
+		Rejected:        bp.counters.rejected.Load(),
+		Duplicates:      bp.counters.duplicates.Load(),
+		Anomalies:       bp.counters.anomalies.Load(),
+		Flushed:         bp.counters.flushed.Load(),
+		Flushes:         bp.counters.flushes.Load(),
+		WALDiverted:     bp.counters.walDiverted.Load(),
+		OverflowSpilled: bp.counters.overflowSpilled.Load(),
+		DeadLettered:    bp.counters.deadLettered.Load(),
+		Buffered:        bp.bufferedLocked(),
+		Throughput:      bp.flushRate,
+	}
+	if bp.overflow != nil {
+		stats.OverflowQueued = bp.overflow.Len()
+	}
+	if !bp.lastCommit.Time.IsZero() {
+		last := bp.lastCommit
+		stats.LastFlush = &last
+	}
+	return stats
+}
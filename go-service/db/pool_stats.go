@@ -0,0 +1,111 @@
+package db
+
+import (
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"orbitstream/models"
+)
+
+// watchedPool is a connection pool the health monitor samples, with the
+// cumulative counters of the previous sample and the rates derived from them
+type watchedPool struct {
+	name string
+	pool *pgxpool.Pool
+
+	emptyAcquires int64
+	emptyWait     time.Duration
+	sampled       bool
+
+	recentEmpty int64
+	recentWait  time.Duration // mean wait of recentEmpty acquires
+	saturated   bool
+}
+
+// WatchPool samples pool on every health check and reports its connection
+// usage and acquire waits as name; must be called before Start. Pool
+// exhaustion otherwise only shows as slow flushes and timeouts.
+func (hm *HealthMonitor) WatchPool(name string, pool *pgxpool.Pool) {
+	if pool == nil {
+		return
+	}
+	hm.poolMutex.Lock()
+	defer hm.poolMutex.Unlock()
+	hm.pools = append(hm.pools, &watchedPool{name: name, pool: pool})
+}
+
+// samplePools records the acquire waits since the previous sample of every
+// watched pool and logs saturation changes
+func (hm *HealthMonitor) samplePools() {
+	hm.poolMutex.Lock()
+	defer hm.poolMutex.Unlock()
+	for _, watched := range hm.pools {
+		stat := watched.pool.Stat()
+		empty, wait := stat.EmptyAcquireCount(), stat.EmptyAcquireWaitTime()
+		if watched.sampled {
+			watched.recentEmpty, watched.recentWait = meanWaitSince(watched.emptyAcquires, watched.emptyWait, empty, wait)
+		}
+		watched.emptyAcquires, watched.emptyWait, watched.sampled = empty, wait, true
+
+		saturated := poolSaturated(stat.AcquiredConns(), stat.MaxConns())
+		if saturated != watched.saturated {
+			log.Printf("EVENT: pool_saturation pool=%s saturated=%t acquired=%d max=%d waited=%d",
+				watched.name, saturated, stat.AcquiredConns(), stat.MaxConns(), watched.recentEmpty)
+		}
+		watched.saturated = saturated
+	}
+}
+
+// meanWaitSince returns the acquires that waited between two samples of the
+// cumulative counters and their mean wait
+func meanWaitSince(prevCount int64, prevWait time.Duration, count int64, wait time.Duration) (int64, time.Duration) {
+	waited := count - prevCount
+	if waited <= 0 {
+		return 0, 0
+	}
+	return waited, (wait - prevWait) / time.Duration(waited)
+}
+
+func poolSaturated(acquired, max int32) bool {
+	return max > 0 && acquired >= max
+}
+
+// PoolStats returns the current state of every watched pool, with the
+// acquire waits over the last health check interval
+func (hm *HealthMonitor) PoolStats() []models.PoolStats {
+	hm.poolMutex.Lock()
+	defer hm.poolMutex.Unlock()
+
+	stats := make([]models.PoolStats, 0, len(hm.pools))
+	for _, watched := range hm.pools {
+		stat := watched.pool.Stat()
+		s := models.PoolStats{
+			Name:                 watched.name,
+			AcquiredConns:        stat.AcquiredConns(),
+			IdleConns:            stat.IdleConns(),
+			ConstructingConns:    stat.ConstructingConns(),
+			TotalConns:           stat.TotalConns(),
+			MaxConns:             stat.MaxConns(),
+			Saturated:            poolSaturated(stat.AcquiredConns(), stat.MaxConns()),
+			AcquireCount:         stat.AcquireCount(),
+			EmptyAcquireCount:    stat.EmptyAcquireCount(),
+			CanceledAcquireCount: stat.CanceledAcquireCount(),
+			EmptyAcquireWaitMS:   milliseconds(stat.EmptyAcquireWaitTime()),
+			RecentEmptyAcquires:  watched.recentEmpty,
+			RecentAcquireWaitMS:  milliseconds(watched.recentWait),
+		}
+		if s.MaxConns > 0 {
+			s.Utilization = float64(s.AcquiredConns) / float64(s.MaxConns)
+		}
+		if s.AcquireCount > 0 {
+			s.AcquireWaitMS = milliseconds(stat.AcquireDuration() / time.Duration(s.AcquireCount))
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMeanWaitSince tests the acquire waits derived from two samples of the
+// cumulative pool counters
+func TestMeanWaitSince(t *testing.T) {
+	waited, mean := meanWaitSince(10, 100*time.Millisecond, 14, 500*time.Millisecond)
+	if waited != 4 || mean != 100*time.Millisecond {
+		t.Errorf("expected 4 acquires waiting 100ms on average, got %d %v", waited, mean)
+	}
+	if waited, mean := meanWaitSince(14, 500*time.Millisecond, 14, 500*time.Millisecond); waited != 0 || mean != 0 {
+		t.Errorf("expected no waits without new empty acquires, got %d %v", waited, mean)
+	}
+	if !poolSaturated(10, 10) || poolSaturated(9, 10) || poolSaturated(0, 0) {
+		t.Error("unexpected saturation")
+	}
+}
+
+// TestHealthMonitorWatchPool tests that watched pools are reported by name
+// with their limits
+func TestHealthMonitorWatchPool(t *testing.T) {
+	bp, wal := newUnreachableBatchProcessor(t)
+	hm := NewHealthMonitor(bp.pool, wal, bp)
+	hm.WatchPool("ingest", bp.pool)
+	hm.WatchPool("missing", nil)
+	hm.samplePools()
+
+	stats := hm.PoolStats()
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 watched pool, got %d", len(stats))
+	}
+	if stats[0].Name != "ingest" || stats[0].MaxConns <= 0 || stats[0].AcquiredConns != 0 || stats[0].Saturated {
+		t.Errorf("unexpected pool stats %+v", stats[0])
+	}
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+	"orbitstream/orbit"
+)
+
+// SatelliteTLE is the two-line element set of a satellite's current
+// registry version
+type SatelliteTLE struct {
+	Line1 string
+	Line2 string
+}
+
+// TLESource loads the TLE of every satellite that has one
+// This allows for mocking in tests
+type TLESource interface {
+	SatelliteTLEs(ctx context.Context) (map[string]SatelliteTLE, error)
+}
+
+// SatelliteTLEs returns the TLEs of the current registry versions
+func (qs *QueryService) SatelliteTLEs(ctx context.Context) (map[string]SatelliteTLE, error) {
+	rows, err := qs.query(ctx, `
+		SELECT satellite_id, tle_line1, tle_line2
+		FROM satellite_registry_history
+		WHERE valid_to IS NULL AND tle_line1 IS NOT NULL AND tle_line2 IS NOT NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tles := make(map[string]SatelliteTLE)
+	for rows.Next() {
+		var satelliteID string
+		var tle SatelliteTLE
+		if err := rows.Scan(&satelliteID, &tle.Line1, &tle.Line2); err != nil {
+			return nil, err
+		}
+		tles[satelliteID] = tle
+	}
+	return tles, rows.Err()
+}
+
+// PropagationConfig configures TLE-based position enrichment
+type PropagationConfig struct {
+	// RefreshInterval is how often TLEs are reloaded from the registry
+	RefreshInterval time.Duration
+	// MaxTLEAge skips points further than this from their TLE's epoch,
+	// where SGP4 errors grow past useful (0 = no limit)
+	MaxTLEAge time.Duration
+}
+
+// OrbitPropagator computes where each satellite's registered TLE predicts
+// it to be at every telemetry timestamp, and stores the distance to the
+// reported position as position_residual_km. A residual growing over time
+// shows a drifting orbit or a stale TLE; a sudden one, a bad position fix.
+//
+// Only near-earth orbits are propagated; satellites with deep-space or
+// invalid TLEs are logged at each refresh and left without a residual.
+type OrbitPropagator struct {
+	source TLESource
+	cfg    PropagationConfig
+
+	mu          sync.RWMutex
+	propagators map[string]*orbit.Propagator
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewOrbitPropagator creates a propagator loading TLEs from source every
+// refresh interval (default 15 minutes) once started
+func NewOrbitPropagator(source TLESource, cfg PropagationConfig) *OrbitPropagator {
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 15 * time.Minute
+	}
+	return &OrbitPropagator{
+		source:      source,
+		cfg:         cfg,
+		propagators: make(map[string]*orbit.Propagator),
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// SetOrbitPropagator stores the residual to the TLE-predicted position with
+// every point
+func (bp *BatchProcessor) SetOrbitPropagator(propagator *OrbitPropagator) {
+	bp.propagator = propagator
+}
+
+// Start loads TLEs immediately and then every refresh interval until Stop
+func (p *OrbitPropagator) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.cfg.RefreshInterval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := p.Refresh(ctx); err != nil {
+				log.Printf("OrbitPropagator: failed to load TLEs: %v", err)
+			}
+			cancel()
+
+			select {
+			case <-ticker.C:
+			case <-p.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the refresh loop
+func (p *OrbitPropagator) Stop() {
+	close(p.stopCh)
+	p.wg.Wait()
+}
+
+// Refresh replaces the TLEs from the source
+func (p *OrbitPropagator) Refresh(ctx context.Context) error {
+	tles, err := p.source.SatelliteTLEs(ctx)
+	if err != nil {
+		return err
+	}
+	propagators := make(map[string]*orbit.Propagator, len(tles))
+	for satelliteID, lines := range tles {
+		tle, err := orbit.ParseTLE(lines.Line1, lines.Line2)
+		if err != nil {
+			log.Printf("OrbitPropagator: invalid TLE for %s: %v", satelliteID, err)
+			continue
+		}
+		propagator, err := orbit.NewPropagator(tle)
+		if err != nil {
+			log.Printf("OrbitPropagator: cannot propagate %s: %v", satelliteID, err)
+			continue
+		}
+		propagators[satelliteID] = propagator
+	}
+	p.mu.Lock()
+	p.propagators = propagators
+	p.mu.Unlock()
+	return nil
+}
+
+// enrich sets the point's position residual. Residuals sent by the client
+// are always discarded.
+func (p *OrbitPropagator) enrich(point *models.TelemetryPoint) {
+	point.PositionResidualKM = nil
+	if p == nil || point.Latitude == nil || point.Longitude == nil || point.AltitudeKM == nil {
+		return
+	}
+	p.mu.RLock()
+	propagator, ok := p.propagators[point.SatelliteID]
+	p.mu.RUnlock()
+	if !ok {
+		return
+	}
+	if p.cfg.MaxTLEAge > 0 && math.Abs(float64(point.Timestamp.Sub(propagator.TLE().Epoch))) > float64(p.cfg.MaxTLEAge) {
+		return
+	}
+	predicted, err := propagator.Position(point.Timestamp)
+	if err != nil {
+		return
+	}
+	residual := orbit.Distance(orbit.Geodetic{
+		Latitude:   *point.Latitude,
+		Longitude:  *point.Longitude,
+		AltitudeKM: *point.AltitudeKM,
+	}, predicted)
+	point.PositionResidualKM = &residual
+}
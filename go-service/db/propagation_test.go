@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+	"orbitstream/orbit"
+)
+
+type mockTLESource struct {
+	tles map[string]SatelliteTLE
+}
+
+func (m *mockTLESource) SatelliteTLEs(ctx context.Context) (map[string]SatelliteTLE, error) {
+	return m.tles, nil
+}
+
+const (
+	propagationTestLine1 = "1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753"
+	propagationTestLine2 = "2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667"
+)
+
+func TestOrbitPropagatorResidual(t *testing.T) {
+	propagator := NewOrbitPropagator(&mockTLESource{tles: map[string]SatelliteTLE{
+		"SAT-001": {Line1: propagationTestLine1, Line2: propagationTestLine2},
+		"SAT-002": {Line1: propagationTestLine1, Line2: "garbage"},
+	}}, PropagationConfig{MaxTLEAge: 7 * 24 * time.Hour})
+	if err := propagator.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	tle, _ := orbit.ParseTLE(propagationTestLine1, propagationTestLine2)
+	at := tle.Epoch.Add(90 * time.Minute)
+	reference, _ := orbit.NewPropagator(tle)
+	predicted, err := reference.Position(at)
+	if err != nil {
+		t.Fatalf("Position: %v", err)
+	}
+
+	// Reported 5 km above the predicted position
+	point := positionedPoint("SAT-001", at, predicted.Latitude, predicted.Longitude, predicted.AltitudeKM+5)
+	propagator.enrich(&point)
+	if point.PositionResidualKM == nil || math.Abs(*point.PositionResidualKM-5) > 1e-6 {
+		t.Fatalf("expected a 5 km residual, got %v", point.PositionResidualKM)
+	}
+
+	// No residual without a usable TLE, beyond the TLE age limit or without
+	// a full position; forged residuals are discarded
+	forged := 1.0
+	for name, point := range map[string]models.TelemetryPoint{
+		"unknown satellite": positionedPoint("SAT-999", at, 0, 0, 500),
+		"invalid TLE":       positionedPoint("SAT-002", at, 0, 0, 500),
+		"stale TLE":         positionedPoint("SAT-001", tle.Epoch.Add(8*24*time.Hour), 0, 0, 500),
+		"no position":       {SatelliteID: "SAT-001", Timestamp: at},
+	} {
+		point.PositionResidualKM = &forged
+		propagator.enrich(&point)
+		if point.PositionResidualKM != nil {
+			t.Errorf("%s: expected no residual, got %v", name, *point.PositionResidualKM)
+		}
+	}
+
+	var disabled *OrbitPropagator
+	point.PositionResidualKM = &forged
+	if disabled.enrich(&point); point.PositionResidualKM != nil {
+		t.Error("a disabled propagator should discard client residuals")
+	}
+}
@@ -0,0 +1,467 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"orbitstream/models"
+)
+
+// Stats resolutions map to the continuous aggregates defined in init.sql
+const (
+	Resolution5Min   = "5m"
+	ResolutionHourly = "1h"
+	ResolutionDaily  = "1d"
+)
+
+// TelemetryQuery describes a bounded read against telemetry or its aggregates
+// Callers are expected to have validated the window and limit already
+type TelemetryQuery struct {
+	SatelliteID string
+	From        time.Time
+	To          time.Time
+	Limit       int
+	// MinSeverity restricts raw reads to anomalies at or above this severity
+	MinSeverity models.AnomalySeverity
+	// Location aligns daily stats buckets to local midnight and reports
+	// bucket times in it; nil means UTC
+	Location *time.Location
+}
+
+// QueryService runs read queries against the telemetry hypertable and
+// its continuous aggregates
+type QueryService struct {
+	pool    *pgxpool.Pool
+	breaker *CircuitBreaker
+}
+
+// NewQueryService creates a query service backed by the given pool
+func NewQueryService(pool *pgxpool.Pool) *QueryService {
+	return &QueryService{pool: pool}
+}
+
+// SetCircuitBreaker makes queries fail fast with ErrCircuitOpen while cb is open
+func (qs *QueryService) SetCircuitBreaker(cb *CircuitBreaker) {
+	qs.breaker = cb
+}
+
+// query runs a read query through the query circuit breaker. pgx returns
+// only send and acquire errors from Query; execution errors and statement
+// timeouts surface in rows.Err(), so the outcome is recorded once the rows
+// are closed.
+func (qs *QueryService) query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if qs.breaker == nil {
+		return qs.pool.Query(ctx, sql, args...)
+	}
+	if !qs.breaker.Allow() {
+		return nil, ErrCircuitOpen
+	}
+	rows, err := qs.pool.Query(ctx, sql, args...)
+	if err != nil {
+		recordQueryOutcome(qs.breaker, err)
+		return nil, err
+	}
+	return &breakerRows{Rows: rows, breaker: qs.breaker}, nil
+}
+
+// recordQueryOutcome records a query's result in the breaker; queries
+// canceled by their caller (e.g. a client disconnect) say nothing about the
+// database and don't count either way
+func recordQueryOutcome(cb *CircuitBreaker, err error) {
+	switch {
+	case err == nil:
+		cb.RecordSuccess()
+	case errors.Is(err, context.Canceled):
+		cb.Release()
+	default:
+		cb.RecordFailure()
+	}
+}
+
+// breakerRows records the query's outcome in the breaker when closed
+type breakerRows struct {
+	pgx.Rows
+	breaker  *CircuitBreaker
+	recorded bool
+}
+
+// Close closes the rows and records rows.Err() once
+func (r *breakerRows) Close() {
+	r.Rows.Close()
+	if !r.recorded {
+		r.recorded = true
+		recordQueryOutcome(r.breaker, r.Rows.Err())
+	}
+}
+
+// ResolutionForRange picks the coarsest aggregate that still gives useful
+// detail for the requested window (5m up to a day, hourly up to a week,
+// daily beyond that)
+func ResolutionForRange(window time.Duration) string {
+	switch {
+	case window <= 24*time.Hour:
+		return Resolution5Min
+	case window <= 7*24*time.Hour:
+		return ResolutionHourly
+	default:
+		return ResolutionDaily
+	}
+}
+
+// telemetrySelectColumns is the column list scanned by scanTelemetryPoint
+const telemetrySelectColumns = `
+			time, satellite_id, battery_charge_percent,
+			storage_usage_mb, signal_strength_dbm, is_anomaly,
+			latitude, longitude, altitude_km, velocity_kmph,
+			software_version, anomaly_detector,
+			anomaly_severity, anomaly_dimension, is_late, timestamp_clamped,
+			validation_issues, position_anomaly, geofence_zones, position_residual_km`
+
+// scanTelemetryPoint scans a row selected with telemetrySelectColumns,
+// followed by any extra columns into extra
+func scanTelemetryPoint(rows pgx.Rows, extra ...any) (models.TelemetryPoint, error) {
+	var p models.TelemetryPoint
+	err := rows.Scan(append([]any{
+		&p.Timestamp, &p.SatelliteID, &p.BatteryChargePercent,
+		&p.StorageUsageMB, &p.SignalStrengthDBM, &p.IsAnomaly,
+		&p.Latitude, &p.Longitude, &p.AltitudeKM, &p.VelocityKMPH,
+		&p.SoftwareVersion, &p.AnomalyDetector,
+		&p.AnomalySeverity, &p.AnomalyDimension, &p.IsLate, &p.TimestampClamped,
+		&p.ValidationIssues, &p.PositionAnomaly, &p.GeofenceZones, &p.PositionResidualKM,
+	}, extra...)...)
+	return p, err
+}
+
+// QueryTelemetry returns raw telemetry points for a satellite, newest first
+func (qs *QueryService) QueryTelemetry(ctx context.Context, q TelemetryQuery) ([]models.TelemetryPoint, error) {
+	rows, err := qs.query(ctx, `
+		SELECT`+telemetrySelectColumns+`
+		FROM telemetry
+		WHERE`+telemetryQueryFilter, telemetryQueryArgs(q)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make([]models.TelemetryPoint, 0)
+	for rows.Next() {
+		p, err := scanTelemetryPoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// registrySelectColumns is the as-of registry column list read by
+// QueryTelemetryAsOf
+const registrySelectColumns = `,
+			reg.valid_from, reg.valid_to, reg.constellation, reg.registry_software_version,
+			reg.battery_min_percent, reg.storage_max_mb, reg.signal_min_dbm`
+
+// registryAsOfJoin joins each telemetry row with the registry version valid
+// at its timestamp. software_version is aliased so it doesn't clash with the
+// telemetry column selected unqualified.
+const registryAsOfJoin = `
+		LEFT JOIN LATERAL (
+			SELECT
+				r.valid_from, r.valid_to, r.constellation,
+				r.software_version AS registry_software_version,
+				r.battery_min_percent, r.storage_max_mb, r.signal_min_dbm
+			FROM satellite_registry_history r
+			WHERE r.satellite_id = telemetry.satellite_id
+			  AND r.valid_from <= telemetry.time
+			  AND (r.valid_to IS NULL OR telemetry.time < r.valid_to)
+			ORDER BY r.valid_from DESC
+			LIMIT 1
+		) reg ON TRUE`
+
+// QueryTelemetryAsOf is QueryTelemetry with each point joined to the
+// registry version in force at its timestamp. Registry is nil for points
+// no registry version covers.
+func (qs *QueryService) QueryTelemetryAsOf(ctx context.Context, q TelemetryQuery) ([]models.TelemetryPointAsOf, error) {
+	rows, err := qs.query(ctx, `
+		SELECT`+telemetrySelectColumns+registrySelectColumns+`
+		FROM telemetry`+registryAsOfJoin+`
+		WHERE`+telemetryQueryFilter, telemetryQueryArgs(q)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make([]models.TelemetryPointAsOf, 0)
+	for rows.Next() {
+		var p models.TelemetryPointAsOf
+		var validFrom *time.Time
+		var reg models.RegistryAttributes
+		if err := rows.Scan(
+			&p.Timestamp, &p.SatelliteID, &p.BatteryChargePercent,
+			&p.StorageUsageMB, &p.SignalStrengthDBM, &p.IsAnomaly,
+			&p.Latitude, &p.Longitude, &p.AltitudeKM, &p.VelocityKMPH,
+			&p.SoftwareVersion, &p.AnomalyDetector,
+			&p.AnomalySeverity, &p.AnomalyDimension, &p.IsLate, &p.TimestampClamped,
+			&p.ValidationIssues, &p.PositionAnomaly, &p.GeofenceZones, &p.PositionResidualKM,
+			&validFrom, &reg.ValidTo, &reg.Constellation, &reg.SoftwareVersion,
+			&reg.BatteryMinPercent, &reg.StorageMaxMB, &reg.SignalMinDBM,
+		); err != nil {
+			return nil, err
+		}
+		if validFrom != nil {
+			reg.ValidFrom = *validFrom
+			p.Registry = &reg
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// telemetryQueryFilter is the WHERE clause shared by the raw telemetry reads,
+// bound by telemetryQueryArgs
+const telemetryQueryFilter = `
+			satellite_id = $1 AND time >= $2 AND time < $3
+		  AND ($5 = 0 OR CASE anomaly_severity
+				WHEN 'info' THEN 1 WHEN 'warning' THEN 2 WHEN 'critical' THEN 3
+				ELSE 0 END >= $5)
+		ORDER BY time DESC
+		LIMIT $4
+	`
+
+func telemetryQueryArgs(q TelemetryQuery) []interface{} {
+	return []interface{}{q.SatelliteID, q.From, q.To, q.Limit, q.MinSeverity.Rank()}
+}
+
+// QueryStats returns aggregate buckets for a satellite at the given resolution
+func (qs *QueryService) QueryStats(ctx context.Context, q TelemetryQuery, resolution string) ([]models.StatsBucket, error) {
+	var stmt string
+	switch resolution {
+	case Resolution5Min:
+		stmt = `
+			SELECT
+				satellite_id, bucket, avg_battery, NULL::numeric, NULL::numeric,
+				avg_storage, avg_signal, data_points, NULL::bigint,
+				avg_altitude_km, min_altitude_km, max_altitude_km,
+				avg_velocity_kmph, min_velocity_kmph, max_velocity_kmph,
+				avg_semi_major_axis_km
+			FROM ` + aggregateStats5Min
+	case ResolutionHourly, ResolutionDaily:
+		if resolution == ResolutionDaily && !isUTC(q.Location) {
+			return qs.queryLocalDailyStats(ctx, q)
+		}
+		view := aggregateStatsHourly
+		if resolution == ResolutionDaily {
+			view = aggregateStatsDaily
+		}
+		stmt = `
+			SELECT
+				satellite_id, bucket, avg_battery, min_battery, max_battery,
+				avg_storage, avg_signal, data_points, anomaly_count,
+				avg_altitude_km, min_altitude_km, max_altitude_km,
+				avg_velocity_kmph, min_velocity_kmph, max_velocity_kmph,
+				avg_semi_major_axis_km
+			FROM ` + view
+	default:
+		return nil, fmt.Errorf("unknown stats resolution %q", resolution)
+	}
+	stmt += `
+		WHERE satellite_id = $1 AND bucket >= $2 AND bucket < $3
+		ORDER BY bucket DESC
+		LIMIT $4`
+
+	return qs.scanStats(ctx, q.Location, stmt, q.SatelliteID, q.From, q.To, q.Limit)
+}
+
+// queryLocalDailyStats rolls the hourly aggregate up into days starting at
+// midnight in q.Location, since the daily aggregate is bucketed on UTC
+// midnight. Averages are weighted by data points. The hourly aggregate is
+// kept for 6 months, so older days come back empty, and zones with a
+// non-whole-hour offset split days at the nearest hour.
+func (qs *QueryService) queryLocalDailyStats(ctx context.Context, q TelemetryQuery) ([]models.StatsBucket, error) {
+	stmt := `
+		SELECT
+			satellite_id, time_bucket('1 day', bucket, $5) AS day,
+			SUM(avg_battery * data_points) / SUM(data_points),
+			MIN(min_battery), MAX(max_battery),
+			SUM(avg_storage * data_points) / SUM(data_points),
+			SUM(avg_signal * data_points) / SUM(data_points),
+			SUM(data_points)::bigint, SUM(anomaly_count)::bigint,
+			SUM(avg_altitude_km * data_points) / NULLIF(SUM(data_points) FILTER (WHERE avg_altitude_km IS NOT NULL), 0),
+			MIN(min_altitude_km), MAX(max_altitude_km),
+			SUM(avg_velocity_kmph * data_points) / NULLIF(SUM(data_points) FILTER (WHERE avg_velocity_kmph IS NOT NULL), 0),
+			MIN(min_velocity_kmph), MAX(max_velocity_kmph),
+			SUM(avg_semi_major_axis_km * data_points) / NULLIF(SUM(data_points) FILTER (WHERE avg_semi_major_axis_km IS NOT NULL), 0)
+		FROM ` + aggregateStatsHourly + `
+		WHERE satellite_id = $1 AND bucket >= $2 AND bucket < $3
+		GROUP BY satellite_id, day
+		ORDER BY day DESC
+		LIMIT $4`
+
+	return qs.scanStats(ctx, q.Location, stmt, q.SatelliteID, q.From, q.To, q.Limit, q.Location.String())
+}
+
+// Continuous aggregates read by the stats endpoints
+const (
+	aggregateStats5Min          = "satellite_stats"
+	aggregateStatsHourly        = "satellite_stats_hourly"
+	aggregateStatsDaily         = "satellite_stats_daily"
+	aggregateVersionStatsHourly = "satellite_version_stats_hourly"
+)
+
+// aggregateBucketWidths is the bucket width of each stats aggregate
+var aggregateBucketWidths = map[string]time.Duration{
+	aggregateStats5Min:          5 * time.Minute,
+	aggregateStatsHourly:        time.Hour,
+	aggregateStatsDaily:         24 * time.Hour,
+	aggregateVersionStatsHourly: time.Hour,
+}
+
+// StatsDataThrough returns how far the aggregate behind QueryStats has been
+// refreshed, see dataThrough. Local daily stats are rolled up from the
+// hourly aggregate, so they are as fresh as it is.
+func (qs *QueryService) StatsDataThrough(ctx context.Context, resolution string, loc *time.Location) (*time.Time, error) {
+	switch resolution {
+	case Resolution5Min:
+		return qs.dataThrough(ctx, aggregateStats5Min)
+	case ResolutionHourly:
+		return qs.dataThrough(ctx, aggregateStatsHourly)
+	case ResolutionDaily:
+		if !isUTC(loc) {
+			return qs.dataThrough(ctx, aggregateStatsHourly)
+		}
+		return qs.dataThrough(ctx, aggregateStatsDaily)
+	}
+	return nil, fmt.Errorf("unknown stats resolution %q", resolution)
+}
+
+// VersionStatsDataThrough returns how far the aggregate behind
+// QueryVersionStats has been refreshed, see dataThrough
+func (qs *QueryService) VersionStatsDataThrough(ctx context.Context) (*time.Time, error) {
+	return qs.dataThrough(ctx, aggregateVersionStatsHourly)
+}
+
+// dataThrough returns the end of the newest bucket in a continuous aggregate
+// across the fleet, or nil when it is empty. Aggregates only materialize
+// complete buckets (see the refresh policies' end offsets), so anything
+// after it hasn't been aggregated yet: an empty bucket before it means no
+// data, after it means the aggregate hasn't caught up.
+func (qs *QueryService) dataThrough(ctx context.Context, view string) (*time.Time, error) {
+	width, ok := aggregateBucketWidths[view]
+	if !ok {
+		return nil, fmt.Errorf("unknown stats aggregate %q", view)
+	}
+	rows, err := qs.query(ctx, `SELECT max(bucket) FROM `+view)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var latest *time.Time
+	if rows.Next() {
+		if err := rows.Scan(&latest); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	through := latest.Add(width).UTC()
+	if now := time.Now().UTC(); through.After(now) {
+		through = now
+	}
+	return &through, nil
+}
+
+// isUTC reports whether loc is nil or UTC
+func isUTC(loc *time.Location) bool {
+	return loc == nil || loc == time.UTC || loc.String() == "UTC"
+}
+
+// scanStats runs a stats query and reports bucket times in loc (nil keeps UTC)
+func (qs *QueryService) scanStats(ctx context.Context, loc *time.Location, stmt string, args ...any) ([]models.StatsBucket, error) {
+	rows, err := qs.query(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make([]models.StatsBucket, 0)
+	for rows.Next() {
+		var b models.StatsBucket
+		var semiMajorAxis *float64
+		if err := rows.Scan(
+			&b.SatelliteID, &b.Bucket, &b.AvgBattery, &b.MinBattery, &b.MaxBattery,
+			&b.AvgStorage, &b.AvgSignal, &b.DataPoints, &b.AnomalyCount,
+			&b.AvgAltitudeKM, &b.MinAltitudeKM, &b.MaxAltitudeKM,
+			&b.AvgVelocityKMPH, &b.MinVelocityKMPH, &b.MaxVelocityKMPH,
+			&semiMajorAxis,
+		); err != nil {
+			return nil, err
+		}
+		b.ApogeeKM, b.PerigeeKM = estimateApsides(b.MinAltitudeKM, b.MaxAltitudeKM, semiMajorAxis)
+		if loc != nil {
+			b.Bucket = b.Bucket.In(loc)
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// estimateApsides estimates a bucket's apogee and perigee altitudes. The
+// lowest and highest altitudes reported bound them from inside; with the
+// vis-viva semi-major axis a, the apsides also satisfy r_a + r_p = 2a, which
+// widens the range to cover the part of the orbit the bucket didn't sample.
+// Without velocity the observed range is the estimate.
+func estimateApsides(minAltitudeKM, maxAltitudeKM, semiMajorAxisKM *float64) (apogee, perigee *float64) {
+	if minAltitudeKM == nil || maxAltitudeKM == nil {
+		return nil, nil
+	}
+	low, high := *minAltitudeKM, *maxAltitudeKM
+	if semiMajorAxisKM != nil {
+		// Sum of the apogee and perigee altitudes
+		sum := 2 * (*semiMajorAxisKM - earthRadiusKM)
+		low = math.Min(*minAltitudeKM, sum-*maxAltitudeKM)
+		high = math.Max(*maxAltitudeKM, sum-*minAltitudeKM)
+	}
+	return &high, &low
+}
+
+// UnknownSoftwareVersion labels telemetry that did not report a version
+const UnknownSoftwareVersion = "unknown"
+
+// QueryVersionStats returns hourly buckets split by software version
+func (qs *QueryService) QueryVersionStats(ctx context.Context, q TelemetryQuery) ([]models.VersionStatsBucket, error) {
+	rows, err := qs.query(ctx, `
+		SELECT
+			satellite_id, COALESCE(software_version, $5), bucket,
+			avg_battery, avg_storage, avg_signal, data_points, anomaly_count
+		FROM `+aggregateVersionStatsHourly+`
+		WHERE satellite_id = $1 AND bucket >= $2 AND bucket < $3
+		ORDER BY bucket DESC, software_version
+		LIMIT $4
+	`, q.SatelliteID, q.From, q.To, q.Limit, UnknownSoftwareVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make([]models.VersionStatsBucket, 0)
+	for rows.Next() {
+		var b models.VersionStatsBucket
+		if err := rows.Scan(
+			&b.SatelliteID, &b.SoftwareVersion, &b.Bucket,
+			&b.AvgBattery, &b.AvgStorage, &b.AvgSignal, &b.DataPoints, &b.AnomalyCount,
+		); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
@@ -0,0 +1,287 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TestQueryTelemetryRegistryAsOf tests that each point is joined with the
+// registry version in force at its timestamp
+func TestQueryTelemetryRegistryAsOf(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	ctx := context.Background()
+	start := time.Now().UTC().Add(-3 * time.Hour).Truncate(time.Hour)
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO satellite_registry_history (satellite_id, valid_from, valid_to, constellation, software_version, battery_min_percent)
+		VALUES ('SAT-REG', $1, $2, 'polar-a', '1.0.0', 20),
+		       ('SAT-REG', $2, NULL, 'polar-b', '1.1.0', 25)`,
+		start.Add(time.Hour), start.Add(2*time.Hour)); err != nil {
+		t.Fatalf("failed to insert registry history: %v", err)
+	}
+	// One point before any registry version, one in each version
+	for h := 0; h < 3; h++ {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO telemetry (time, satellite_id, battery_charge_percent, storage_usage_mb, signal_strength_dbm)
+			VALUES ($1, 'SAT-REG', 80, 1000, -60)`, start.Add(time.Duration(h)*time.Hour+30*time.Minute)); err != nil {
+			t.Fatalf("failed to insert telemetry: %v", err)
+		}
+	}
+
+	points, err := NewQueryService(pool).QueryTelemetryAsOf(ctx, TelemetryQuery{
+		SatelliteID: "SAT-REG",
+		From:        start,
+		To:          start.Add(3 * time.Hour),
+		Limit:       10,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(points) != 3 {
+		t.Fatalf("expected 3 points, got %d", len(points))
+	}
+
+	// Newest first
+	if reg := points[0].Registry; reg == nil || *reg.Constellation != "polar-b" || *reg.SoftwareVersion != "1.1.0" || reg.ValidTo != nil {
+		t.Errorf("expected current polar-b version, got %+v", reg)
+	}
+	if reg := points[1].Registry; reg == nil || *reg.Constellation != "polar-a" || *reg.BatteryMinPercent != 20 {
+		t.Errorf("expected superseded polar-a version, got %+v", reg)
+	}
+	if points[2].Registry != nil {
+		t.Errorf("expected no registry version before the first, got %+v", points[2].Registry)
+	}
+}
+
+// TestQueryStatsLocalDaily tests that daily buckets start at midnight in the
+// requested time zone instead of UTC midnight
+func TestQueryStatsLocalDaily(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+
+	// 02:00 and 08:00 UTC fall on the same UTC day but on two Chicago days
+	ctx := context.Background()
+	day := time.Now().UTC().AddDate(0, 0, -2).Truncate(24 * time.Hour)
+	for _, at := range []time.Time{day.Add(2 * time.Hour), day.Add(8 * time.Hour)} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO telemetry (time, satellite_id, battery_charge_percent, storage_usage_mb, signal_strength_dbm)
+			VALUES ($1, 'SAT-TZ', 80, 1000, -60)`, at); err != nil {
+			t.Fatalf("failed to insert telemetry: %v", err)
+		}
+	}
+	if _, err := pool.Exec(ctx, `CALL refresh_continuous_aggregate('satellite_stats_hourly', NULL, NULL)`); err != nil {
+		t.Fatalf("failed to refresh aggregate: %v", err)
+	}
+
+	buckets, err := NewQueryService(pool).QueryStats(ctx, TelemetryQuery{
+		SatelliteID: "SAT-TZ",
+		From:        day.AddDate(0, 0, -1),
+		To:          day.AddDate(0, 0, 1),
+		Limit:       10,
+		Location:    chicago,
+	}, ResolutionDaily)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("expected 2 local days, got %d", len(buckets))
+	}
+	for _, b := range buckets {
+		if h, m, _ := b.Bucket.Clock(); h != 0 || m != 0 || b.Bucket.Location() != chicago {
+			t.Errorf("expected bucket at Chicago midnight, got %v", b.Bucket)
+		}
+		if b.DataPoints != 1 {
+			t.Errorf("expected 1 data point per day, got %d", b.DataPoints)
+		}
+	}
+}
+
+// TestStatsDataThrough tests that freshness is the end of the newest
+// refreshed bucket and empty aggregates report nil
+func TestStatsDataThrough(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	ctx := context.Background()
+	qs := NewQueryService(pool)
+	through, err := qs.StatsDataThrough(ctx, ResolutionHourly, nil)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if through != nil {
+		t.Errorf("expected nil for an empty aggregate, got %v", through)
+	}
+
+	at := time.Now().UTC().Add(-3 * time.Hour).Truncate(time.Hour).Add(10 * time.Minute)
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO telemetry (time, satellite_id, battery_charge_percent, storage_usage_mb, signal_strength_dbm)
+		VALUES ($1, 'SAT-FRESH', 80, 1000, -60)`, at); err != nil {
+		t.Fatalf("failed to insert telemetry: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `CALL refresh_continuous_aggregate('satellite_stats_hourly', NULL, NULL)`); err != nil {
+		t.Fatalf("failed to refresh aggregate: %v", err)
+	}
+
+	through, err = qs.StatsDataThrough(ctx, ResolutionHourly, nil)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if want := at.Truncate(time.Hour).Add(time.Hour); through == nil || !through.Equal(want) {
+		t.Errorf("expected data through %v, got %v", want, through)
+	}
+}
+
+func TestEstimateApsides(t *testing.T) {
+	km := func(v float64) *float64 { return &v }
+	cases := []struct {
+		name                    string
+		minAlt, maxAlt, sma     *float64
+		wantApogee, wantPerigee float64
+	}{
+		// Without velocity the observed range is the estimate
+		{"no velocity", km(480), km(520), nil, 520, 480},
+		// Circular at 500 km: a = 6871 km confirms the observed range
+		{"circular", km(500), km(500), km(earthRadiusKM + 500), 500, 500},
+		// Perigee sampled at 400 km, a 700 km above the surface: the
+		// apogee the bucket missed is at 1000 km
+		{"unsampled apogee", km(400), km(600), km(earthRadiusKM + 700), 1000, 400},
+	}
+	for _, tc := range cases {
+		apogee, perigee := estimateApsides(tc.minAlt, tc.maxAlt, tc.sma)
+		if apogee == nil || perigee == nil ||
+			math.Abs(*apogee-tc.wantApogee) > 1e-9 || math.Abs(*perigee-tc.wantPerigee) > 1e-9 {
+			t.Errorf("%s: got apogee %v perigee %v, want %v and %v", tc.name, apogee, perigee, tc.wantApogee, tc.wantPerigee)
+		}
+	}
+	if apogee, perigee := estimateApsides(nil, nil, nil); apogee != nil || perigee != nil {
+		t.Error("expected no estimate without altitudes")
+	}
+}
+
+// TestQueryStatsOrbitalMetrics tests that the aggregates summarize altitude
+// and velocity and the stats API estimates the apsides from them
+func TestQueryStatsOrbitalMetrics(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	// Circular orbit speed at 500 km, reported at 490 and 510 km
+	ctx := context.Background()
+	hour := time.Now().UTC().Add(-3 * time.Hour).Truncate(time.Hour)
+	speed := math.Sqrt(398600.4418/(earthRadiusKM+500)) * 3600
+	for i, alt := range []float64{490, 510} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO telemetry (time, satellite_id, battery_charge_percent, storage_usage_mb, signal_strength_dbm, altitude_km, velocity_kmph)
+			VALUES ($1, 'SAT-ORB', 80, 1000, -60, $2, $3)`, hour.Add(time.Duration(i)*10*time.Minute), alt, speed+float64(i)); err != nil {
+			t.Fatalf("failed to insert telemetry: %v", err)
+		}
+	}
+	if _, err := pool.Exec(ctx, `CALL refresh_continuous_aggregate('satellite_stats_hourly', NULL, NULL)`); err != nil {
+		t.Fatalf("failed to refresh aggregate: %v", err)
+	}
+
+	buckets, err := NewQueryService(pool).QueryStats(ctx, TelemetryQuery{
+		SatelliteID: "SAT-ORB",
+		From:        hour,
+		To:          hour.Add(time.Hour),
+		Limit:       10,
+	}, ResolutionHourly)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(buckets) != 1 {
+		t.Fatalf("expected 1 bucket, got %d", len(buckets))
+	}
+	b := buckets[0]
+	if b.MinAltitudeKM == nil || *b.MinAltitudeKM != 490 || b.MaxAltitudeKM == nil || *b.MaxAltitudeKM != 510 {
+		t.Errorf("unexpected altitude range %v-%v", b.MinAltitudeKM, b.MaxAltitudeKM)
+	}
+	if b.MinVelocityKMPH == nil || b.MaxVelocityKMPH == nil || *b.MaxVelocityKMPH-*b.MinVelocityKMPH < 0.5 {
+		t.Errorf("unexpected velocity range %v-%v", b.MinVelocityKMPH, b.MaxVelocityKMPH)
+	}
+	if b.ApogeeKM == nil || b.PerigeeKM == nil || *b.ApogeeKM < 510 || *b.PerigeeKM > 490 || *b.ApogeeKM-*b.PerigeeKM > 100 {
+		t.Errorf("unexpected apsides %v/%v", b.ApogeeKM, b.PerigeeKM)
+	}
+}
+
+// fakeRows reports err from Err once closed, like pgx rows whose statement
+// failed or timed out during execution
+type fakeRows struct {
+	pgx.Rows
+	err    error
+	closes int
+}
+
+func (r *fakeRows) Close()     { r.closes++ }
+func (r *fakeRows) Err() error { return r.err }
+
+// TestBreakerRowsRecordOutcomeOnClose tests that query outcomes reach the
+// breaker only once the rows are closed, and that cancellations don't count
+func TestBreakerRowsRecordOutcomeOnClose(t *testing.T) {
+	cb := NewCircuitBreaker(2, time.Hour)
+	timeout := fmt.Errorf("query failed: %w", errors.New("canceling statement due to statement timeout"))
+
+	for i := 0; i < 2; i++ {
+		if !cb.Allow() {
+			t.Fatalf("expected the breaker to admit query %d", i)
+		}
+		rows := &breakerRows{Rows: &fakeRows{err: timeout}, breaker: cb}
+		if cb.FailureCount() != i {
+			t.Fatalf("expected no outcome before the rows are closed, got %d failures", cb.FailureCount())
+		}
+		rows.Close()
+		rows.Close()
+	}
+	if !cb.IsOpen() || cb.FailureCount() != 2 {
+		t.Fatalf("expected execution errors to open the breaker, got %s with %d failures", cb.State(), cb.FailureCount())
+	}
+
+	cb.Reset()
+	for i := 0; i < 3; i++ {
+		cb.Allow()
+		canceled := &breakerRows{Rows: &fakeRows{err: fmt.Errorf("read: %w", context.Canceled)}, breaker: cb}
+		canceled.Close()
+	}
+	if !cb.IsClosed() || cb.FailureCount() != 0 {
+		t.Errorf("expected canceled queries not to count, got %s with %d failures", cb.State(), cb.FailureCount())
+	}
+}
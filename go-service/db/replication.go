@@ -0,0 +1,306 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"orbitstream/retry"
+)
+
+// WALReplicator receives every mutation of a WAL so that a warm standby can
+// hold a copy of buffered-but-uncommitted data. Implementations must not
+// block the caller; they are invoked while the WAL lock is held.
+type WALReplicator interface {
+	Append(record WALRecord)
+	Truncate()
+}
+
+// walShipment is one queued replication event: a record or a truncate marker
+type walShipment struct {
+	record   WALRecord
+	truncate bool
+}
+
+// HTTPWALShipper streams WAL records from a primary to a standby over HTTP.
+//
+// Records are queued in memory and posted in small batches to
+// {standbyURL}/replication/wal, authenticated with the shared replication
+// token in the ReplicationTokenHeader. Truncates (after a successful replay on the
+// primary) are posted to {standbyURL}/replication/wal/truncate in order with
+// the records, so the standby never keeps data the primary already committed.
+// Shipping is best-effort: if the queue is full or the standby is unreachable
+// records are dropped and counted, the primary's own WAL stays authoritative.
+type HTTPWALShipper struct {
+	standbyURL    string
+	token         string
+	client        *http.Client
+	queue         chan walShipment
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	stopCh        chan struct{}
+	wg            sync.WaitGroup
+	shipped       atomic.Int64
+	dropped       atomic.Int64
+}
+
+// ReplicationTokenHeader carries the shared secret the standby checks
+// before accepting shipped records, truncates or promotion
+const ReplicationTokenHeader = "X-Replication-Token"
+
+// NewHTTPWALShipper creates a shipper posting to the given standby base URL
+// with the shared replication token
+func NewHTTPWALShipper(standbyURL, token string, timeout time.Duration) *HTTPWALShipper {
+	return &HTTPWALShipper{
+		standbyURL:    strings.TrimRight(standbyURL, "/"),
+		token:         token,
+		client:        &http.Client{Timeout: timeout},
+		queue:         make(chan walShipment, 10000),
+		batchSize:     500,
+		flushInterval: 200 * time.Millisecond,
+		maxRetries:    3,
+		stopCh:        make(chan struct{}),
+	}
+}
+
+// Start begins the background shipping loop
+func (s *HTTPWALShipper) Start() {
+	s.wg.Add(1)
+	go s.run()
+}
+
+// Stop ships whatever is queued and stops the background loop
+func (s *HTTPWALShipper) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+// Append queues a record for shipping (never blocks)
+func (s *HTTPWALShipper) Append(record WALRecord) {
+	select {
+	case s.queue <- walShipment{record: record}:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Truncate queues a truncate marker for shipping (never blocks)
+func (s *HTTPWALShipper) Truncate() {
+	select {
+	case s.queue <- walShipment{truncate: true}:
+	default:
+		log.Printf("WALShipper: queue full, standby truncate dropped")
+	}
+}
+
+// Shipped returns the number of records acknowledged by the standby
+func (s *HTTPWALShipper) Shipped() int64 {
+	return s.shipped.Load()
+}
+
+// Dropped returns the number of records that could not be shipped
+func (s *HTTPWALShipper) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+func (s *HTTPWALShipper) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	pending := make([]WALRecord, 0, s.batchSize)
+	for {
+		select {
+		case item := <-s.queue:
+			if item.truncate {
+				s.shipRecords(pending)
+				pending = pending[:0]
+				s.post("/replication/wal/truncate", nil)
+				continue
+			}
+			pending = append(pending, item.record)
+			if len(pending) >= s.batchSize {
+				s.shipRecords(pending)
+				pending = pending[:0]
+			}
+		case <-ticker.C:
+			s.shipRecords(pending)
+			pending = pending[:0]
+		case <-s.stopCh:
+			// Drain what is already queued before exiting
+			for {
+				select {
+				case item := <-s.queue:
+					if item.truncate {
+						s.shipRecords(pending)
+						pending = pending[:0]
+						s.post("/replication/wal/truncate", nil)
+					} else {
+						pending = append(pending, item.record)
+					}
+				default:
+					s.shipRecords(pending)
+					return
+				}
+			}
+		}
+	}
+}
+
+// shipRecords posts a batch of records to the standby
+func (s *HTTPWALShipper) shipRecords(records []WALRecord) {
+	if len(records) == 0 {
+		return
+	}
+	if s.post("/replication/wal", records) {
+		s.shipped.Add(int64(len(records)))
+	} else {
+		s.dropped.Add(int64(len(records)))
+	}
+}
+
+// post sends a JSON payload to the standby with retry and exponential backoff
+func (s *HTTPWALShipper) post(path string, payload interface{}) bool {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("WALShipper: failed to marshal payload: %v", err)
+		return false
+	}
+
+	policy := retry.Policy{MaxAttempts: s.maxRetries, BaseDelay: 100 * time.Millisecond}
+	err = retry.Do(context.Background(), policy, func(ctx context.Context, attempt int) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.standbyURL+path, bytes.NewReader(body))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(ReplicationTokenHeader, s.token)
+		resp, err := s.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("standby returned %s", resp.Status)
+		}
+		log.Printf("WALShipper: %s attempt %d failed: %v", path, attempt, err)
+		return err
+	})
+	return err == nil
+}
+
+// StandbyReceiver holds WAL records shipped from a primary until promotion.
+//
+// Shipped records are kept in a separate standby WAL file so that they are
+// not replayed while the primary is alive. Promote moves them into the local
+// WAL, where the HealthMonitor replays them like any other buffered data.
+type StandbyReceiver struct {
+	mu           sync.Mutex
+	standbyWAL   *WAL
+	localWAL     *WAL
+	received     int64
+	lastReceived time.Time
+	promoted     bool
+	promotedAt   time.Time
+}
+
+// StandbyStatus describes the state of a standby receiver
+type StandbyStatus struct {
+	Promoted       bool      `json:"promoted"`
+	PromotedAt     time.Time `json:"promoted_at,omitempty"`
+	PendingRecords int       `json:"pending_records"`
+	PendingBytes   int64     `json:"pending_bytes"`
+	TotalReceived  int64     `json:"total_received"`
+	LastReceived   time.Time `json:"last_received,omitempty"`
+}
+
+// NewStandbyReceiver creates a receiver storing shipped records in standbyWAL
+// and moving them into localWAL on promotion
+func NewStandbyReceiver(standbyWAL, localWAL *WAL) *StandbyReceiver {
+	return &StandbyReceiver{
+		standbyWAL: standbyWAL,
+		localWAL:   localWAL,
+	}
+}
+
+// Receive appends shipped records to the standby WAL
+func (r *StandbyReceiver) Receive(records []WALRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.promoted {
+		return fmt.Errorf("standby already promoted, refusing shipped records")
+	}
+	for _, record := range records {
+		if err := r.standbyWAL.Write(record); err != nil {
+			return err
+		}
+	}
+	r.received += int64(len(records))
+	r.lastReceived = time.Now()
+	return nil
+}
+
+// Truncate drops all held records (the primary committed them)
+func (r *StandbyReceiver) Truncate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.standbyWAL.Clear()
+}
+
+// Promote moves all held records into the local WAL for replay and stops
+// accepting further shipments. Returns the number of records taken over.
+func (r *StandbyReceiver) Promote() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.promoted {
+		return 0, nil
+	}
+	if r.localWAL == nil {
+		return 0, fmt.Errorf("local WAL not configured, cannot take over shipped records")
+	}
+
+	records, err := r.standbyWAL.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read standby WAL: %w", err)
+	}
+	for _, record := range records {
+		if err := r.localWAL.Write(record); err != nil {
+			return 0, fmt.Errorf("failed to move record to local WAL: %w", err)
+		}
+	}
+	if err := r.standbyWAL.Clear(); err != nil {
+		return len(records), fmt.Errorf("records moved but standby WAL not cleared: %w", err)
+	}
+
+	r.promoted = true
+	r.promotedAt = time.Now()
+	log.Printf("StandbyReceiver: promoted, took over %d records from primary", len(records))
+	return len(records), nil
+}
+
+// Status returns the current standby state
+func (r *StandbyReceiver) Status() StandbyStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending, _ := r.standbyWAL.Count()
+	return StandbyStatus{
+		Promoted:       r.promoted,
+		PromotedAt:     r.promotedAt,
+		PendingRecords: pending,
+		PendingBytes:   r.standbyWAL.Size(),
+		TotalReceived:  r.received,
+		LastReceived:   r.lastReceived,
+	}
+}
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStandby records what a primary ships to it, rejecting requests
+// without its token
+type fakeStandby struct {
+	token     string
+	mu        sync.Mutex
+	records   []WALRecord
+	truncates int
+	events    []string
+}
+
+func (f *fakeStandby) handler() http.Handler {
+	mux := http.NewServeMux()
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get(ReplicationTokenHeader) != f.token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+	mux.HandleFunc("/replication/wal", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		var records []WALRecord
+		if err := json.NewDecoder(r.Body).Decode(&records); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.records = append(f.records, records...)
+		f.events = append(f.events, "records")
+		f.mu.Unlock()
+	})
+	mux.HandleFunc("/replication/wal/truncate", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		f.mu.Lock()
+		f.truncates++
+		f.events = append(f.events, "truncate")
+		f.mu.Unlock()
+	})
+	return mux
+}
+
+func (f *fakeStandby) snapshot() ([]WALRecord, int, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]WALRecord{}, f.records...), f.truncates, append([]string{}, f.events...)
+}
+
+// TestHTTPWALShipperShipsWritesAndClears tests that WAL writes and clears reach the standby in order
+func TestHTTPWALShipperShipsWritesAndClears(t *testing.T) {
+	standby := &fakeStandby{token: "secret"}
+	server := httptest.NewServer(standby.handler())
+	defer server.Close()
+
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "primary.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+
+	shipper := NewHTTPWALShipper(server.URL, "secret", time.Second)
+	shipper.flushInterval = 10 * time.Millisecond
+	shipper.Start()
+	wal.SetReplicator(shipper)
+
+	for i := 0; i < 3; i++ {
+		if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: "SAT-001"}); err != nil {
+			t.Fatalf("failed to write WAL: %v", err)
+		}
+	}
+	if err := wal.Clear(); err != nil {
+		t.Fatalf("failed to clear WAL: %v", err)
+	}
+	shipper.Stop()
+
+	records, truncates, events := standby.snapshot()
+	if len(records) != 3 {
+		t.Errorf("expected 3 shipped records, got %d", len(records))
+	}
+	if truncates != 1 {
+		t.Errorf("expected 1 truncate, got %d", truncates)
+	}
+	if len(events) == 0 || events[len(events)-1] != "truncate" {
+		t.Errorf("expected truncate to be shipped after records, got %v", events)
+	}
+	if shipper.Shipped() != 3 {
+		t.Errorf("expected Shipped() 3, got %d", shipper.Shipped())
+	}
+}
+
+// TestHTTPWALShipperUnreachableStandby tests that failures are counted, not fatal
+func TestHTTPWALShipperUnreachableStandby(t *testing.T) {
+	shipper := NewHTTPWALShipper("http://127.0.0.1:1", "secret", 100*time.Millisecond)
+	shipper.maxRetries = 1
+	shipper.Start()
+
+	shipper.Append(WALRecord{SatelliteID: "SAT-001"})
+	shipper.Append(WALRecord{SatelliteID: "SAT-002"})
+	shipper.Stop()
+
+	if shipper.Dropped() != 2 {
+		t.Errorf("expected 2 dropped records, got %d", shipper.Dropped())
+	}
+}
+
+// TestStandbyReceiverPromote tests that promotion moves held records into the local WAL
+func TestStandbyReceiverPromote(t *testing.T) {
+	tmpDir := t.TempDir()
+	standbyWAL, err := NewWAL(filepath.Join(tmpDir, "standby.wal"))
+	if err != nil {
+		t.Fatalf("failed to create standby WAL: %v", err)
+	}
+	defer standbyWAL.Close()
+	localWAL, err := NewWAL(filepath.Join(tmpDir, "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create local WAL: %v", err)
+	}
+	defer localWAL.Close()
+
+	receiver := NewStandbyReceiver(standbyWAL, localWAL)
+	if err := receiver.Receive([]WALRecord{{SatelliteID: "SAT-001"}, {SatelliteID: "SAT-002"}}); err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	status := receiver.Status()
+	if status.PendingRecords != 2 || status.TotalReceived != 2 {
+		t.Errorf("unexpected status before promote: %+v", status)
+	}
+
+	// Local WAL must stay empty until promotion so nothing is replayed twice
+	if count, _ := localWAL.Count(); count != 0 {
+		t.Errorf("expected empty local WAL before promotion, got %d", count)
+	}
+
+	taken, err := receiver.Promote()
+	if err != nil {
+		t.Fatalf("failed to promote: %v", err)
+	}
+	if taken != 2 {
+		t.Errorf("expected 2 records taken over, got %d", taken)
+	}
+	if count, _ := localWAL.Count(); count != 2 {
+		t.Errorf("expected 2 records in local WAL after promotion, got %d", count)
+	}
+	if count, _ := standbyWAL.Count(); count != 0 {
+		t.Errorf("expected standby WAL to be empty after promotion, got %d", count)
+	}
+
+	if err := receiver.Receive([]WALRecord{{SatelliteID: "SAT-003"}}); err == nil {
+		t.Error("expected promoted standby to refuse new shipments")
+	}
+}
+
+// TestStandbyReceiverTruncate tests that primary truncates clear held records
+func TestStandbyReceiverTruncate(t *testing.T) {
+	standbyWAL, err := NewWAL(filepath.Join(t.TempDir(), "standby.wal"))
+	if err != nil {
+		t.Fatalf("failed to create standby WAL: %v", err)
+	}
+	defer standbyWAL.Close()
+
+	receiver := NewStandbyReceiver(standbyWAL, nil)
+	_ = receiver.Receive([]WALRecord{{SatelliteID: "SAT-001"}})
+
+	if err := receiver.Truncate(); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if receiver.Status().PendingRecords != 0 {
+		t.Error("expected no pending records after truncate")
+	}
+
+	if _, err := receiver.Promote(); err == nil {
+		t.Error("expected promote to fail without a local WAL")
+	}
+}
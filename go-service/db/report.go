@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"orbitstream/models"
+)
+
+// FleetReport summarizes ingest volume and anomalies across the fleet from
+// from to to, with days starting at midnight in loc (nil = UTC). Volumes
+// come from the hourly aggregate, top issues from the flagged telemetry
+// rows; the ground station scorecards are left to the caller.
+func (qs *QueryService) FleetReport(ctx context.Context, from, to time.Time, loc *time.Location, topN int) (models.FleetReport, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	report := models.FleetReport{PeriodStart: from, PeriodEnd: to}
+
+	rows, err := qs.query(ctx, `
+		SELECT time_bucket('1 day', bucket, $3) AS day,
+			SUM(data_points)::bigint, SUM(anomaly_count)::bigint, COUNT(DISTINCT satellite_id)
+		FROM `+aggregateStatsHourly+`
+		WHERE bucket >= $1 AND bucket < $2
+		GROUP BY day
+		ORDER BY day
+	`, from, to, loc.String())
+	if err != nil {
+		return report, err
+	}
+	report.Days = make([]models.FleetReportDay, 0)
+	for rows.Next() {
+		var day models.FleetReportDay
+		if err := rows.Scan(&day.Day, &day.DataPoints, &day.Anomalies, &day.Satellites); err != nil {
+			rows.Close()
+			return report, err
+		}
+		day.Day = day.Day.In(loc)
+		report.DataPoints += day.DataPoints
+		report.Anomalies += day.Anomalies
+		report.Days = append(report.Days, day)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	// Active satellites over the period, and the totals of the period before
+	rows, err = qs.query(ctx, `
+		SELECT
+			COUNT(DISTINCT satellite_id) FILTER (WHERE bucket >= $2),
+			COALESCE(SUM(data_points) FILTER (WHERE bucket < $2), 0)::bigint,
+			COALESCE(SUM(anomaly_count) FILTER (WHERE bucket < $2), 0)::bigint
+		FROM `+aggregateStatsHourly+`
+		WHERE bucket >= $1 AND bucket < $3
+	`, from.Add(-to.Sub(from)), from, to)
+	if err != nil {
+		return report, err
+	}
+	for rows.Next() {
+		if err := rows.Scan(&report.Satellites, &report.PreviousDataPoints, &report.PreviousAnomalies); err != nil {
+			rows.Close()
+			return report, err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	if report.TopSatellites, err = qs.fleetTopSatellites(ctx, from, to, topN); err != nil {
+		return report, err
+	}
+	if report.TopIssues, err = qs.fleetTopIssues(ctx, from, to, topN); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// fleetTopSatellites ranks the satellites with anomalies in the period
+func (qs *QueryService) fleetTopSatellites(ctx context.Context, from, to time.Time, limit int) ([]models.FleetReportSatellite, error) {
+	rows, err := qs.query(ctx, `
+		SELECT satellite_id, SUM(anomaly_count)::bigint AS anomalies,
+			SUM(data_points)::bigint, MIN(min_battery)::double precision
+		FROM `+aggregateStatsHourly+`
+		WHERE bucket >= $1 AND bucket < $2
+		GROUP BY satellite_id
+		HAVING SUM(anomaly_count) > 0
+		ORDER BY anomalies DESC, satellite_id
+		LIMIT $3
+	`, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	satellites := make([]models.FleetReportSatellite, 0)
+	for rows.Next() {
+		var s models.FleetReportSatellite
+		if err := rows.Scan(&s.SatelliteID, &s.Anomalies, &s.DataPoints, &s.MinBattery); err != nil {
+			return nil, err
+		}
+		satellites = append(satellites, s)
+	}
+	return satellites, rows.Err()
+}
+
+// fleetTopIssues ranks anomaly dimensions and severities by occurrences,
+// reading the flagged rows through the partial anomaly index
+func (qs *QueryService) fleetTopIssues(ctx context.Context, from, to time.Time, limit int) ([]models.FleetReportIssue, error) {
+	rows, err := qs.query(ctx, `
+		SELECT COALESCE(anomaly_dimension, 'unknown'), COALESCE(anomaly_severity, 'unknown'),
+			COUNT(*) AS occurrences, COUNT(DISTINCT satellite_id)
+		FROM telemetry
+		WHERE is_anomaly = TRUE AND time >= $1 AND time < $2
+		GROUP BY 1, 2
+		ORDER BY occurrences DESC, 1, 2
+		LIMIT $3
+	`, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issues := make([]models.FleetReportIssue, 0)
+	for rows.Next() {
+		var issue models.FleetReportIssue
+		if err := rows.Scan(&issue.Dimension, &issue.Severity, &issue.Count, &issue.Satellites); err != nil {
+			return nil, err
+		}
+		issues = append(issues, issue)
+	}
+	return issues, rows.Err()
+}
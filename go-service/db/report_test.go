@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestFleetReport tests the daily volumes, week over week totals and
+// top issues of the fleet report
+func TestFleetReport(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	ctx := context.Background()
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -7)
+	rows := []struct {
+		at        time.Time
+		satellite string
+		anomaly   bool
+	}{
+		{from.Add(2 * time.Hour), "SAT-A", true},
+		{from.Add(3 * time.Hour), "SAT-A", false},
+		{from.Add(26 * time.Hour), "SAT-B", true},
+		{from.Add(27 * time.Hour), "SAT-A", true},
+		{from.Add(-24 * time.Hour), "SAT-A", false},
+	}
+	for _, row := range rows {
+		var dimension, severity any
+		if row.anomaly {
+			dimension, severity = "battery", "critical"
+		}
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO telemetry (time, satellite_id, battery_charge_percent, storage_usage_mb, signal_strength_dbm,
+				is_anomaly, anomaly_dimension, anomaly_severity)
+			VALUES ($1, $2, 5, 1000, -60, $3, $4, $5)`,
+			row.at, row.satellite, row.anomaly, dimension, severity); err != nil {
+			t.Fatalf("failed to insert telemetry: %v", err)
+		}
+	}
+	if _, err := pool.Exec(ctx, `CALL refresh_continuous_aggregate('satellite_stats_hourly', NULL, NULL)`); err != nil {
+		t.Fatalf("failed to refresh aggregate: %v", err)
+	}
+
+	report, err := NewQueryService(pool).FleetReport(ctx, from, to, nil, 10)
+	if err != nil {
+		t.Fatalf("FleetReport failed: %v", err)
+	}
+	if report.DataPoints != 4 || report.Anomalies != 3 || report.Satellites != 2 {
+		t.Errorf("unexpected totals: %d points, %d anomalies, %d satellites", report.DataPoints, report.Anomalies, report.Satellites)
+	}
+	if report.PreviousDataPoints != 1 || report.PreviousAnomalies != 0 {
+		t.Errorf("unexpected previous week: %d points, %d anomalies", report.PreviousDataPoints, report.PreviousAnomalies)
+	}
+	if len(report.Days) != 2 || report.Days[0].DataPoints != 2 || report.Days[1].Satellites != 2 {
+		t.Errorf("unexpected days: %+v", report.Days)
+	}
+	if len(report.TopSatellites) != 2 || report.TopSatellites[0].SatelliteID != "SAT-A" || report.TopSatellites[0].Anomalies != 2 {
+		t.Errorf("unexpected top satellites: %+v", report.TopSatellites)
+	}
+	if len(report.TopIssues) != 1 || report.TopIssues[0].Count != 3 || report.TopIssues[0].Satellites != 2 {
+		t.Errorf("unexpected top issues: %+v", report.TopIssues)
+	}
+}
@@ -0,0 +1,426 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"orbitstream/models"
+)
+
+// CompositeDetectorName identifies composite rule findings in stored rows
+const CompositeDetectorName = "composite"
+
+// ruleMetric is a telemetry field a rule condition can test
+type ruleMetric struct {
+	dimension models.AnomalyDimension
+	value     func(point models.TelemetryPoint) (float64, bool)
+}
+
+// ruleMetrics are the metric names usable in rule expressions
+var ruleMetrics = map[string]ruleMetric{
+	"battery":  {models.DimensionBattery, func(p models.TelemetryPoint) (float64, bool) { return p.BatteryChargePercent, true }},
+	"storage":  {models.DimensionStorage, func(p models.TelemetryPoint) (float64, bool) { return p.StorageUsageMB, true }},
+	"signal":   {models.DimensionSignal, func(p models.TelemetryPoint) (float64, bool) { return p.SignalStrengthDBM, true }},
+	"altitude": {models.DimensionPosition, optionalMetric(func(p models.TelemetryPoint) *float64 { return p.AltitudeKM })},
+	"velocity": {models.DimensionPosition, optionalMetric(func(p models.TelemetryPoint) *float64 { return p.VelocityKMPH })},
+}
+
+// ruleMetricAliases accepts column names for the rule metrics
+var ruleMetricAliases = map[string]string{
+	"battery_charge_percent": "battery",
+	"storage_usage_mb":       "storage",
+	"signal_strength_dbm":    "signal",
+	"altitude_km":            "altitude",
+	"velocity_kmph":          "velocity",
+}
+
+// optionalMetric reads a nullable field; a missing value never matches
+func optionalMetric(field func(models.TelemetryPoint) *float64) func(models.TelemetryPoint) (float64, bool) {
+	return func(p models.TelemetryPoint) (float64, bool) {
+		if v := field(p); v != nil {
+			return *v, true
+		}
+		return 0, false
+	}
+}
+
+// RuleCondition compares one metric against a constant, e.g. battery < 20
+type RuleCondition struct {
+	Metric string
+	Op     string // <, <=, > or >=
+	Value  float64
+}
+
+// RuleExpr is a boolean composition of conditions. Exactly one of
+// Condition, All (AND) or Any (OR) is set.
+type RuleExpr struct {
+	Condition *RuleCondition
+	All       []RuleExpr
+	Any       []RuleExpr
+}
+
+// Eval reports whether the point satisfies the expression
+func (e RuleExpr) Eval(point models.TelemetryPoint) bool {
+	switch {
+	case e.Condition != nil:
+		return e.Condition.eval(point)
+	case len(e.All) > 0:
+		for _, sub := range e.All {
+			if !sub.Eval(point) {
+				return false
+			}
+		}
+		return true
+	case len(e.Any) > 0:
+		for _, sub := range e.Any {
+			if sub.Eval(point) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String renders the expression in the syntax ParseRuleExpr accepts
+func (e RuleExpr) String() string {
+	switch {
+	case e.Condition != nil:
+		return fmt.Sprintf("%s %s %g", e.Condition.Metric, e.Condition.Op, e.Condition.Value)
+	case len(e.All) > 0:
+		return joinRuleExprs(e.All, " AND ")
+	case len(e.Any) > 0:
+		return joinRuleExprs(e.Any, " OR ")
+	}
+	return ""
+}
+
+func joinRuleExprs(exprs []RuleExpr, sep string) string {
+	parts := make([]string, len(exprs))
+	for i, sub := range exprs {
+		parts[i] = sub.String()
+		if sub.Condition == nil {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// firstCondition returns the leftmost condition, which classifies findings
+func (e RuleExpr) firstCondition() *RuleCondition {
+	switch {
+	case e.Condition != nil:
+		return e.Condition
+	case len(e.All) > 0:
+		return e.All[0].firstCondition()
+	case len(e.Any) > 0:
+		return e.Any[0].firstCondition()
+	}
+	return nil
+}
+
+func (c *RuleCondition) eval(point models.TelemetryPoint) bool {
+	value, ok := ruleMetrics[c.Metric].value(point)
+	if !ok {
+		return false
+	}
+	switch c.Op {
+	case "<":
+		return value < c.Value
+	case "<=":
+		return value <= c.Value
+	case ">":
+		return value > c.Value
+	case ">=":
+		return value >= c.Value
+	}
+	return false
+}
+
+// CompositeRule flags a satellite while Expr holds, once it has held for
+// Sustained (measured by point timestamps; zero fires immediately)
+type CompositeRule struct {
+	Name      string
+	Expr      RuleExpr
+	Sustained time.Duration
+	Severity  models.AnomalySeverity
+}
+
+// CompositeDetector evaluates composite rules, so noisy single-metric
+// thresholds can be replaced by conditions like
+// "battery < 20 AND signal < -90 FOR 5m".
+// When several rules fire, the most severe one is reported.
+type CompositeDetector struct {
+	rules  []CompositeRule
+	mu     sync.Mutex
+	states map[string][]ruleState // per satellite, indexed like rules
+}
+
+// ruleState tracks how long a rule has held for one satellite
+type ruleState struct {
+	since  time.Time
+	active bool
+}
+
+// NewCompositeDetector creates a detector for the given rules
+func NewCompositeDetector(rules []CompositeRule) *CompositeDetector {
+	return &CompositeDetector{
+		rules:  rules,
+		states: make(map[string][]ruleState),
+	}
+}
+
+// Name returns "composite"
+func (d *CompositeDetector) Name() string {
+	return CompositeDetectorName
+}
+
+// Detect evaluates every rule for the point's satellite
+func (d *CompositeDetector) Detect(point models.TelemetryPoint) *models.AnomalyResult {
+	at := point.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	states, ok := d.states[point.SatelliteID]
+	if !ok {
+		states = make([]ruleState, len(d.rules))
+		d.states[point.SatelliteID] = states
+	}
+
+	var worst *models.AnomalyResult
+	for i, rule := range d.rules {
+		state := &states[i]
+		if !rule.Expr.Eval(point) {
+			*state = ruleState{}
+			continue
+		}
+		if !state.active {
+			if state.since.IsZero() {
+				state.since = at
+			}
+			if at.Sub(state.since) < rule.Sustained {
+				continue
+			}
+			state.active = true
+		}
+
+		if worst == nil || rule.Severity.Rank() > worst.Severity.Rank() {
+			worst = rule.result(point, at.Sub(state.since))
+		}
+	}
+	return worst
+}
+
+// result builds the finding for a firing rule, classified by its first condition
+func (r CompositeRule) result(point models.TelemetryPoint, held time.Duration) *models.AnomalyResult {
+	result := &models.AnomalyResult{
+		Severity: r.Severity,
+		Message:  fmt.Sprintf("rule %s: %s held for %v", r.Name, r.Expr, held.Round(time.Second)),
+		Metric:   r.Name,
+	}
+	if c := r.Expr.firstCondition(); c != nil {
+		metric := ruleMetrics[c.Metric]
+		result.Dimension = metric.dimension
+		result.Value, _ = metric.value(point)
+		result.Threshold = c.Value
+	}
+	return result
+}
+
+// ParseCompositeRules parses semicolon-separated rule definitions of the form
+//
+//	name[:severity]=<expression> [FOR <duration>]
+//
+// e.g. "low_power_link:critical=battery < 20 AND signal < -90 FOR 5m".
+// Severity defaults to warning.
+func ParseCompositeRules(spec string) ([]CompositeRule, error) {
+	var rules []CompositeRule
+	for _, def := range strings.Split(spec, ";") {
+		def = strings.TrimSpace(def)
+		if def == "" {
+			continue
+		}
+		head, body, ok := strings.Cut(def, "=")
+		if !ok {
+			return nil, fmt.Errorf("rule %q: expected name=expression", def)
+		}
+
+		rule := CompositeRule{Severity: models.SeverityWarning}
+		name, severity, hasSeverity := strings.Cut(strings.TrimSpace(head), ":")
+		rule.Name = strings.TrimSpace(name)
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %q: name is required", def)
+		}
+		if hasSeverity {
+			rule.Severity = models.AnomalySeverity(strings.TrimSpace(severity))
+			if !rule.Severity.Valid() {
+				return nil, fmt.Errorf("rule %s: unknown severity %q", rule.Name, severity)
+			}
+		}
+
+		expr, sustained, err := parseRuleBody(body)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		rule.Expr, rule.Sustained = expr, sustained
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseRuleBody splits off an optional trailing "FOR <duration>"
+func parseRuleBody(body string) (RuleExpr, time.Duration, error) {
+	tokens := tokenizeRule(body)
+	var sustained time.Duration
+	if n := len(tokens); n >= 2 && strings.EqualFold(tokens[n-2], "FOR") {
+		d, err := time.ParseDuration(tokens[n-1])
+		if err != nil || d < 0 {
+			return RuleExpr{}, 0, fmt.Errorf("invalid duration %q", tokens[n-1])
+		}
+		sustained = d
+		tokens = tokens[:n-2]
+	}
+	expr, err := parseRuleTokens(tokens)
+	return expr, sustained, err
+}
+
+// ParseRuleExpr parses a boolean expression of metric comparisons joined by
+// AND / OR (AND binds tighter) with optional parentheses
+func ParseRuleExpr(text string) (RuleExpr, error) {
+	return parseRuleTokens(tokenizeRule(text))
+}
+
+func parseRuleTokens(tokens []string) (RuleExpr, error) {
+	p := &ruleParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return RuleExpr{}, err
+	}
+	if p.pos < len(p.tokens) {
+		return RuleExpr{}, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+// tokenizeRule splits an expression into names, numbers, operators and parentheses
+func tokenizeRule(text string) []string {
+	var tokens []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, string(r))
+			i++
+		case r == '<' || r == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, string(runes[i:i+2]))
+				i += 2
+			} else {
+				tokens = append(tokens, string(r))
+				i++
+			}
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()<>", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		}
+	}
+	return tokens
+}
+
+// ruleParser is a recursive-descent parser over rule tokens
+type ruleParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *ruleParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *ruleParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *ruleParser) parseOr() (RuleExpr, error) {
+	return p.parseJoined("OR", p.parseAnd, func(exprs []RuleExpr) RuleExpr { return RuleExpr{Any: exprs} })
+}
+
+func (p *ruleParser) parseAnd() (RuleExpr, error) {
+	return p.parseJoined("AND", p.parsePrimary, func(exprs []RuleExpr) RuleExpr { return RuleExpr{All: exprs} })
+}
+
+// parseJoined parses operands separated by keyword, collapsing a single operand
+func (p *ruleParser) parseJoined(keyword string, operand func() (RuleExpr, error), combine func([]RuleExpr) RuleExpr) (RuleExpr, error) {
+	first, err := operand()
+	if err != nil {
+		return RuleExpr{}, err
+	}
+	exprs := []RuleExpr{first}
+	for strings.EqualFold(p.peek(), keyword) {
+		p.next()
+		expr, err := operand()
+		if err != nil {
+			return RuleExpr{}, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return combine(exprs), nil
+}
+
+func (p *ruleParser) parsePrimary() (RuleExpr, error) {
+	token := p.next()
+	if token == "(" {
+		expr, err := p.parseOr()
+		if err != nil {
+			return RuleExpr{}, err
+		}
+		if p.next() != ")" {
+			return RuleExpr{}, fmt.Errorf("missing closing parenthesis")
+		}
+		return expr, nil
+	}
+
+	if token == "" {
+		return RuleExpr{}, fmt.Errorf("unexpected end of expression")
+	}
+	metric := strings.ToLower(token)
+	if alias, ok := ruleMetricAliases[metric]; ok {
+		metric = alias
+	}
+	if _, ok := ruleMetrics[metric]; !ok {
+		return RuleExpr{}, fmt.Errorf("unknown metric %q", token)
+	}
+	op := p.next()
+	switch op {
+	case "<", "<=", ">", ">=":
+	default:
+		return RuleExpr{}, fmt.Errorf("expected comparison after %s, got %q", token, op)
+	}
+	raw := p.next()
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return RuleExpr{}, fmt.Errorf("invalid number %q", raw)
+	}
+	return RuleExpr{Condition: &RuleCondition{Metric: metric, Op: op, Value: value}}, nil
+}
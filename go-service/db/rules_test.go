@@ -0,0 +1,120 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// TestParseRuleExpr tests operator precedence, parentheses and metric aliases
+func TestParseRuleExpr(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"battery < 20", "battery < 20"},
+		{"battery<20 AND signal_strength_dbm <= -90", "battery < 20 AND signal <= -90"},
+		{"battery < 20 or signal < -90 and storage > 90000", "battery < 20 OR (signal < -90 AND storage > 90000)"},
+		{"(battery < 20 OR signal < -90) AND storage > 90000", "(battery < 20 OR signal < -90) AND storage > 90000"},
+	}
+	for _, tt := range tests {
+		expr, err := ParseRuleExpr(tt.text)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.text, err)
+			continue
+		}
+		if got := expr.String(); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.text, tt.want, got)
+		}
+	}
+
+	for _, bad := range []string{"", "battery", "battery = 20", "temperature < 5", "battery < low", "(battery < 20", "battery < 20 signal < 5"} {
+		if _, err := ParseRuleExpr(bad); err == nil {
+			t.Errorf("%q: expected parse error", bad)
+		}
+	}
+}
+
+// TestParseCompositeRules tests rule definitions with severity and duration
+func TestParseCompositeRules(t *testing.T) {
+	rules, err := ParseCompositeRules("low_power_link:critical=battery < 20 AND signal < -90 FOR 5m; storage_full=storage >= 95000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(rules))
+	}
+	if rules[0].Name != "low_power_link" || rules[0].Severity != models.SeverityCritical || rules[0].Sustained != 5*time.Minute {
+		t.Errorf("unexpected first rule %+v", rules[0])
+	}
+	if rules[1].Severity != models.SeverityWarning || rules[1].Sustained != 0 {
+		t.Errorf("expected warning rule without duration, got %+v", rules[1])
+	}
+
+	for _, bad := range []string{"battery < 20", "=battery < 20", "r:urgent=battery < 20", "r=battery < 20 FOR soon"} {
+		if _, err := ParseCompositeRules(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+// TestCompositeDetectorSustained tests that a rule fires only after holding
+// for its duration and resets when the condition breaks
+func TestCompositeDetectorSustained(t *testing.T) {
+	rules, err := ParseCompositeRules("low_power_link:critical=battery < 20 AND signal < -90 FOR 5m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	detector := NewCompositeDetector(rules)
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	point := func(offset time.Duration, battery, signal float64) models.TelemetryPoint {
+		p := TelemetryPointForTest(battery, 1000, signal)
+		p.SatelliteID = "SAT-001"
+		p.Timestamp = start.Add(offset)
+		return p
+	}
+
+	// Only one metric out of range: not a finding
+	if result := detector.Detect(point(0, 15, -60)); result != nil {
+		t.Errorf("expected no finding with only battery low, got %+v", result)
+	}
+	if result := detector.Detect(point(time.Minute, 15, -95)); result != nil {
+		t.Errorf("expected no finding before 5 minutes, got %+v", result)
+	}
+	// Condition breaks, so the clock restarts
+	detector.Detect(point(3*time.Minute, 50, -95))
+	if result := detector.Detect(point(6*time.Minute, 15, -95)); result != nil {
+		t.Errorf("expected no finding after the condition was interrupted, got %+v", result)
+	}
+
+	result := detector.Detect(point(11*time.Minute, 15, -95))
+	if result == nil {
+		t.Fatal("expected finding after 5 minutes sustained")
+	}
+	if result.Severity != models.SeverityCritical || result.Metric != "low_power_link" || result.Dimension != models.DimensionBattery {
+		t.Errorf("unexpected finding %+v", result)
+	}
+	if result := detector.Detect(point(12*time.Minute, 15, -95)); result == nil {
+		t.Error("expected rule to keep firing while the condition holds")
+	}
+}
+
+// TestCompositeDetectorMostSevere tests that the most severe firing rule is reported
+func TestCompositeDetectorMostSevere(t *testing.T) {
+	rules, err := ParseCompositeRules("weak=signal < -80; dead:critical=signal < -90 OR altitude < 200")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	detector := NewCompositeDetector(rules)
+
+	result := detector.Detect(TelemetryPointForTest(80, 1000, -95))
+	if result == nil || result.Metric != "dead" {
+		t.Errorf("expected critical rule to win, got %+v", result)
+	}
+	// No altitude reported: that condition never matches
+	if result := detector.Detect(TelemetryPointForTest(80, 1000, -85)); result == nil || result.Metric != "weak" {
+		t.Errorf("expected only the warning rule, got %+v", result)
+	}
+}
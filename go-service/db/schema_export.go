@@ -0,0 +1,513 @@
+package db
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// initSQL is the schema the repository ships, the baseline drift is
+// measured against
+//
+//go:embed init.sql
+var initSQL string
+
+// Schema policy kinds
+const (
+	PolicyRefresh     = "refresh"
+	PolicyCompression = "compression"
+	PolicyRetention   = "retention"
+)
+
+// SchemaInterval is an interval setting as PostgreSQL prints it, with its
+// length for comparison
+type SchemaInterval struct {
+	Text    string  `json:"text"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Hypertable is a hypertable and its time partitioning
+type Hypertable struct {
+	Name          string         `json:"name"`
+	TimeColumn    string         `json:"time_column"`
+	ChunkInterval SchemaInterval `json:"chunk_interval"`
+}
+
+// ContinuousAggregate is a continuous aggregate view and its definition
+type ContinuousAggregate struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition,omitempty"`
+}
+
+// CompressionSettings are the segment-by and order-by columns of a
+// compressed hypertable or continuous aggregate
+type CompressionSettings struct {
+	Relation  string   `json:"relation"`
+	SegmentBy []string `json:"segment_by,omitempty"`
+	OrderBy   []string `json:"order_by,omitempty"`
+}
+
+// SchemaPolicy is a refresh, compression or retention policy. Settings holds
+// its intervals: start_offset, end_offset and schedule_interval for refresh
+// policies, compress_after or drop_after for the others.
+type SchemaPolicy struct {
+	Kind     string                    `json:"kind"`
+	Relation string                    `json:"relation"`
+	Settings map[string]SchemaInterval `json:"settings"`
+}
+
+// SchemaSnapshot is the TimescaleDB schema of a database: hypertables,
+// continuous aggregates, compression settings and policies
+type SchemaSnapshot struct {
+	GeneratedAt          time.Time             `json:"generated_at"`
+	Hypertables          []Hypertable          `json:"hypertables"`
+	ContinuousAggregates []ContinuousAggregate `json:"continuous_aggregates"`
+	Compression          []CompressionSettings `json:"compression"`
+	Policies             []SchemaPolicy        `json:"policies"`
+}
+
+// Schema drift kinds
+const (
+	// DriftMissing is defined in the repository but absent from the database
+	DriftMissing = "missing"
+	// DriftUnexpected exists in the database but not in the repository
+	DriftUnexpected = "unexpected"
+	// DriftChanged exists in both with different settings
+	DriftChanged = "changed"
+)
+
+// SchemaDrift is one difference between the database and the repository
+type SchemaDrift struct {
+	Kind     string `json:"kind"`
+	Object   string `json:"object"`
+	Expected string `json:"expected,omitempty"`
+	Live     string `json:"live,omitempty"`
+}
+
+// SchemaSource reads the live TimescaleDB schema
+// This allows for mocking in tests
+type SchemaSource interface {
+	SchemaSnapshot(ctx context.Context) (SchemaSnapshot, error)
+}
+
+// SchemaSnapshot reads hypertables, continuous aggregates, compression
+// settings and policies from the TimescaleDB catalog
+func (qs *QueryService) SchemaSnapshot(ctx context.Context) (SchemaSnapshot, error) {
+	snapshot := SchemaSnapshot{GeneratedAt: time.Now().UTC()}
+
+	rows, err := qs.query(ctx, `
+		SELECT d.hypertable_name, d.column_name, d.time_interval::text,
+			EXTRACT(EPOCH FROM d.time_interval)::double precision
+		FROM timescaledb_information.dimensions d
+		JOIN timescaledb_information.hypertables h
+			ON h.hypertable_schema = d.hypertable_schema AND h.hypertable_name = d.hypertable_name
+		WHERE d.hypertable_schema = 'public' AND d.dimension_type = 'Time'
+		ORDER BY d.hypertable_name
+	`)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to read hypertables: %w", err)
+	}
+	for rows.Next() {
+		var h Hypertable
+		if err := rows.Scan(&h.Name, &h.TimeColumn, &h.ChunkInterval.Text, &h.ChunkInterval.Seconds); err != nil {
+			rows.Close()
+			return snapshot, err
+		}
+		snapshot.Hypertables = append(snapshot.Hypertables, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return snapshot, err
+	}
+
+	rows, err = qs.query(ctx, `
+		SELECT view_name, view_definition
+		FROM timescaledb_information.continuous_aggregates
+		WHERE view_schema = 'public'
+		ORDER BY view_name
+	`)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to read continuous aggregates: %w", err)
+	}
+	for rows.Next() {
+		var agg ContinuousAggregate
+		if err := rows.Scan(&agg.Name, &agg.Definition); err != nil {
+			rows.Close()
+			return snapshot, err
+		}
+		agg.Definition = strings.TrimSuffix(strings.TrimSpace(agg.Definition), ";")
+		snapshot.ContinuousAggregates = append(snapshot.ContinuousAggregates, agg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return snapshot, err
+	}
+
+	// Continuous aggregate settings and jobs are recorded against their
+	// materialization hypertable; report them under the view name
+	rows, err = qs.query(ctx, `
+		SELECT COALESCE(ca.view_name, s.hypertable_name), s.attname,
+			s.segmentby_column_index, s.orderby_column_index, s.orderby_asc
+		FROM timescaledb_information.compression_settings s
+		LEFT JOIN timescaledb_information.continuous_aggregates ca
+			ON ca.materialization_hypertable_schema = s.hypertable_schema
+			AND ca.materialization_hypertable_name = s.hypertable_name
+		WHERE s.segmentby_column_index IS NOT NULL OR s.orderby_column_index IS NOT NULL
+		ORDER BY 1, s.segmentby_column_index, s.orderby_column_index
+	`)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to read compression settings: %w", err)
+	}
+	compression := make(map[string]*CompressionSettings)
+	var relations []string
+	for rows.Next() {
+		var relation, column string
+		var segmentIndex, orderIndex *int
+		var asc *bool
+		if err := rows.Scan(&relation, &column, &segmentIndex, &orderIndex, &asc); err != nil {
+			rows.Close()
+			return snapshot, err
+		}
+		settings, ok := compression[relation]
+		if !ok {
+			settings = &CompressionSettings{Relation: relation}
+			compression[relation] = settings
+			relations = append(relations, relation)
+		}
+		if segmentIndex != nil {
+			settings.SegmentBy = append(settings.SegmentBy, column)
+		}
+		if orderIndex != nil {
+			if asc != nil && !*asc {
+				column += " DESC"
+			}
+			settings.OrderBy = append(settings.OrderBy, column)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return snapshot, err
+	}
+	for _, relation := range relations {
+		snapshot.Compression = append(snapshot.Compression, *compression[relation])
+	}
+
+	rows, err = qs.query(ctx, `
+		SELECT j.proc_name, COALESCE(ca.view_name, j.hypertable_name), s.key,
+			s.value::interval::text, EXTRACT(EPOCH FROM s.value::interval)::double precision
+		FROM timescaledb_information.jobs j
+		LEFT JOIN timescaledb_information.continuous_aggregates ca
+			ON ca.materialization_hypertable_schema = j.hypertable_schema
+			AND ca.materialization_hypertable_name = j.hypertable_name
+		CROSS JOIN LATERAL (
+			SELECT key, value FROM jsonb_each_text(j.config)
+			WHERE key IN ('start_offset', 'end_offset', 'compress_after', 'drop_after')
+				AND value IS NOT NULL
+			UNION ALL
+			SELECT 'schedule_interval', j.schedule_interval::text
+			WHERE j.proc_name = 'policy_refresh_continuous_aggregate'
+		) s
+		WHERE j.proc_name IN ('policy_refresh_continuous_aggregate', 'policy_compression', 'policy_retention')
+		ORDER BY 2, 1, 3
+	`)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to read policies: %w", err)
+	}
+	policies := make(map[string]*SchemaPolicy)
+	var keys []string
+	for rows.Next() {
+		var proc, relation, setting string
+		var interval SchemaInterval
+		if err := rows.Scan(&proc, &relation, &setting, &interval.Text, &interval.Seconds); err != nil {
+			rows.Close()
+			return snapshot, err
+		}
+		kind := policyKinds[proc]
+		key := kind + " " + relation
+		policy, ok := policies[key]
+		if !ok {
+			policy = &SchemaPolicy{Kind: kind, Relation: relation, Settings: make(map[string]SchemaInterval)}
+			policies[key] = policy
+			keys = append(keys, key)
+		}
+		policy.Settings[setting] = interval
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return snapshot, err
+	}
+	for _, key := range keys {
+		snapshot.Policies = append(snapshot.Policies, *policies[key])
+	}
+	return snapshot, nil
+}
+
+// policyKinds maps TimescaleDB job procedures to policy kinds
+var policyKinds = map[string]string{
+	"policy_refresh_continuous_aggregate": PolicyRefresh,
+	"policy_compression":                  PolicyCompression,
+	"policy_retention":                    PolicyRetention,
+}
+
+// Migration renders the snapshot as an idempotent SQL migration that brings
+// a database to the same hypertables, aggregates and policies
+func (s SchemaSnapshot) Migration() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Schema export generated %s from the live database\n", s.GeneratedAt.Format(time.RFC3339))
+	b.WriteString("-- Hypertables, continuous aggregates, compression and policies (idempotent)\n")
+
+	aggregates := make(map[string]bool)
+	for _, agg := range s.ContinuousAggregates {
+		aggregates[agg.Name] = true
+	}
+
+	if len(s.Hypertables) > 0 {
+		b.WriteString("\n-- Hypertables\n")
+	}
+	for _, h := range s.Hypertables {
+		fmt.Fprintf(&b, "SELECT create_hypertable('%s', '%s', chunk_time_interval => INTERVAL '%s', if_not_exists => TRUE);\n",
+			h.Name, h.TimeColumn, h.ChunkInterval.Text)
+		fmt.Fprintf(&b, "SELECT set_chunk_time_interval('%s', INTERVAL '%s');\n", h.Name, h.ChunkInterval.Text)
+	}
+
+	for _, agg := range s.ContinuousAggregates {
+		fmt.Fprintf(&b, "\n-- Continuous aggregate %s\n", agg.Name)
+		fmt.Fprintf(&b, "CREATE MATERIALIZED VIEW IF NOT EXISTS %s\nWITH (timescaledb.continuous) AS\n%s\nWITH NO DATA;\n",
+			agg.Name, agg.Definition)
+	}
+
+	if len(s.Compression) > 0 {
+		b.WriteString("\n-- Compression\n")
+	}
+	for _, c := range s.Compression {
+		relation := "TABLE"
+		if aggregates[c.Relation] {
+			relation = "MATERIALIZED VIEW"
+		}
+		options := []string{"timescaledb.compress"}
+		if len(c.SegmentBy) > 0 {
+			options = append(options, fmt.Sprintf("timescaledb.compress_segmentby = '%s'", strings.Join(c.SegmentBy, ", ")))
+		}
+		if len(c.OrderBy) > 0 {
+			options = append(options, fmt.Sprintf("timescaledb.compress_orderby = '%s'", strings.Join(c.OrderBy, ", ")))
+		}
+		fmt.Fprintf(&b, "ALTER %s %s SET (\n    %s\n);\n", relation, c.Relation, strings.Join(options, ",\n    "))
+	}
+
+	if len(s.Policies) > 0 {
+		b.WriteString("\n-- Policies (replaced so intervals match)\n")
+	}
+	for _, p := range s.Policies {
+		switch p.Kind {
+		case PolicyRefresh:
+			fmt.Fprintf(&b, "SELECT remove_continuous_aggregate_policy('%s', if_not_exists => TRUE);\n", p.Relation)
+			fmt.Fprintf(&b, "SELECT add_continuous_aggregate_policy('%s',\n    start_offset => %s,\n    end_offset => %s,\n    schedule_interval => %s\n);\n",
+				p.Relation, intervalLiteral(p.Settings, "start_offset"), intervalLiteral(p.Settings, "end_offset"),
+				intervalLiteral(p.Settings, "schedule_interval"))
+		case PolicyCompression:
+			fmt.Fprintf(&b, "SELECT remove_compression_policy('%s', if_exists => TRUE);\n", p.Relation)
+			fmt.Fprintf(&b, "SELECT add_compression_policy('%s', %s);\n", p.Relation, intervalLiteral(p.Settings, "compress_after"))
+		case PolicyRetention:
+			fmt.Fprintf(&b, "SELECT remove_retention_policy('%s', if_exists => TRUE);\n", p.Relation)
+			fmt.Fprintf(&b, "SELECT add_retention_policy('%s', %s);\n", p.Relation, intervalLiteral(p.Settings, "drop_after"))
+		}
+	}
+	return b.String()
+}
+
+// intervalLiteral renders a policy setting as SQL (NULL when unbounded)
+func intervalLiteral(settings map[string]SchemaInterval, name string) string {
+	interval, ok := settings[name]
+	if !ok {
+		return "NULL"
+	}
+	return fmt.Sprintf("INTERVAL '%s'", interval.Text)
+}
+
+var (
+	initHypertablePattern = regexp.MustCompile(`create_hypertable\('(\w+)',\s*'(\w+)',\s*chunk_time_interval\s*=>\s*INTERVAL\s*'([^']+)'`)
+	initAggregatePattern  = regexp.MustCompile(`CREATE MATERIALIZED VIEW\s+(?:IF NOT EXISTS\s+)?(\w+)\s+WITH\s*\(timescaledb\.continuous\)`)
+	initRefreshPattern    = regexp.MustCompile(`add_continuous_aggregate_policy\('(\w+)',\s*start_offset\s*=>\s*INTERVAL\s*'([^']+)',\s*end_offset\s*=>\s*INTERVAL\s*'([^']+)',\s*schedule_interval\s*=>\s*INTERVAL\s*'([^']+)'`)
+	initPolicyPattern     = regexp.MustCompile(`add_(compression|retention)_policy\('(\w+)',\s*INTERVAL\s*'([^']+)'`)
+)
+
+// ExpectedSchema parses the hypertables, continuous aggregates and policies
+// init.sql defines (aggregate definitions and compression settings are not
+// compared, PostgreSQL rewrites view definitions)
+func ExpectedSchema() (SchemaSnapshot, error) {
+	return parseSchemaSQL(initSQL)
+}
+
+// parseSchemaSQL extracts the schema objects of a migration script
+func parseSchemaSQL(sql string) (SchemaSnapshot, error) {
+	var snapshot SchemaSnapshot
+	parse := func(text string) (SchemaInterval, error) {
+		seconds, err := intervalSeconds(text)
+		return SchemaInterval{Text: text, Seconds: seconds}, err
+	}
+
+	for _, m := range initHypertablePattern.FindAllStringSubmatch(sql, -1) {
+		chunk, err := parse(m[3])
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.Hypertables = append(snapshot.Hypertables, Hypertable{Name: m[1], TimeColumn: m[2], ChunkInterval: chunk})
+	}
+	for _, m := range initAggregatePattern.FindAllStringSubmatch(sql, -1) {
+		snapshot.ContinuousAggregates = append(snapshot.ContinuousAggregates, ContinuousAggregate{Name: m[1]})
+	}
+	for _, m := range initRefreshPattern.FindAllStringSubmatch(sql, -1) {
+		policy := SchemaPolicy{Kind: PolicyRefresh, Relation: m[1], Settings: make(map[string]SchemaInterval)}
+		for i, name := range []string{"start_offset", "end_offset", "schedule_interval"} {
+			interval, err := parse(m[i+2])
+			if err != nil {
+				return snapshot, err
+			}
+			policy.Settings[name] = interval
+		}
+		snapshot.Policies = append(snapshot.Policies, policy)
+	}
+	for _, m := range initPolicyPattern.FindAllStringSubmatch(sql, -1) {
+		interval, err := parse(m[3])
+		if err != nil {
+			return snapshot, err
+		}
+		policy := SchemaPolicy{Kind: m[1], Relation: m[2], Settings: make(map[string]SchemaInterval)}
+		if m[1] == PolicyCompression {
+			policy.Settings["compress_after"] = interval
+		} else {
+			policy.Settings["drop_after"] = interval
+		}
+		snapshot.Policies = append(snapshot.Policies, policy)
+	}
+	return snapshot, nil
+}
+
+// intervalUnits are interval unit lengths in seconds, with months and years
+// as PostgreSQL's EXTRACT(EPOCH) counts them
+var intervalUnits = map[string]float64{
+	"second": 1, "sec": 1, "minute": 60, "min": 60, "hour": 3600,
+	"day": 86400, "week": 7 * 86400, "month": 2629800, "mon": 2629800, "year": 31557600,
+}
+
+// intervalSeconds parses intervals like "7 days", "6 months", "1 day 02:00:00"
+func intervalSeconds(text string) (float64, error) {
+	fields := strings.Fields(strings.ToLower(text))
+	total := 0.0
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if strings.Contains(field, ":") {
+			parts := strings.Split(field, ":")
+			seconds := 0.0
+			for _, part := range parts {
+				value, err := strconv.ParseFloat(part, 64)
+				if err != nil {
+					return 0, fmt.Errorf("invalid interval %q", text)
+				}
+				seconds = seconds*60 + value
+			}
+			for j := len(parts); j < 3; j++ {
+				seconds *= 60 // hh:mm
+			}
+			total += seconds
+			continue
+		}
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil || i+1 == len(fields) {
+			return 0, fmt.Errorf("invalid interval %q", text)
+		}
+		i++
+		unit, ok := intervalUnits[strings.TrimSuffix(fields[i], "s")]
+		if !ok {
+			return 0, fmt.Errorf("invalid interval unit %q in %q", fields[i], text)
+		}
+		total += value * unit
+	}
+	return total, nil
+}
+
+// DetectDrift compares a live snapshot with the expected schema
+func DetectDrift(expected, live SchemaSnapshot) []SchemaDrift {
+	drift := make([]SchemaDrift, 0)
+
+	liveHypertables := make(map[string]Hypertable)
+	for _, h := range live.Hypertables {
+		liveHypertables[h.Name] = h
+	}
+	for _, want := range expected.Hypertables {
+		object := "hypertable " + want.Name
+		got, ok := liveHypertables[want.Name]
+		if !ok {
+			drift = append(drift, SchemaDrift{Kind: DriftMissing, Object: object, Expected: want.ChunkInterval.Text})
+			continue
+		}
+		delete(liveHypertables, want.Name)
+		if !sameInterval(want.ChunkInterval, got.ChunkInterval) {
+			drift = append(drift, SchemaDrift{Kind: DriftChanged, Object: object + " chunk_time_interval",
+				Expected: want.ChunkInterval.Text, Live: got.ChunkInterval.Text})
+		}
+	}
+	for _, name := range sortedKeys(liveHypertables) {
+		drift = append(drift, SchemaDrift{Kind: DriftUnexpected, Object: "hypertable " + name})
+	}
+
+	liveAggregates := make(map[string]bool)
+	for _, agg := range live.ContinuousAggregates {
+		liveAggregates[agg.Name] = true
+	}
+	for _, want := range expected.ContinuousAggregates {
+		if !liveAggregates[want.Name] {
+			drift = append(drift, SchemaDrift{Kind: DriftMissing, Object: "continuous aggregate " + want.Name})
+		}
+		delete(liveAggregates, want.Name)
+	}
+	for _, name := range sortedKeys(liveAggregates) {
+		drift = append(drift, SchemaDrift{Kind: DriftUnexpected, Object: "continuous aggregate " + name})
+	}
+
+	livePolicies := make(map[string]SchemaPolicy)
+	for _, p := range live.Policies {
+		livePolicies[p.Kind+" policy "+p.Relation] = p
+	}
+	for _, want := range expected.Policies {
+		object := want.Kind + " policy " + want.Relation
+		got, ok := livePolicies[object]
+		if !ok {
+			drift = append(drift, SchemaDrift{Kind: DriftMissing, Object: object})
+			continue
+		}
+		delete(livePolicies, object)
+		for _, name := range sortedKeys(want.Settings) {
+			setting, found := got.Settings[name]
+			if !found || !sameInterval(want.Settings[name], setting) {
+				drift = append(drift, SchemaDrift{Kind: DriftChanged, Object: object + " " + name,
+					Expected: want.Settings[name].Text, Live: setting.Text})
+			}
+		}
+	}
+	for _, object := range sortedKeys(livePolicies) {
+		drift = append(drift, SchemaDrift{Kind: DriftUnexpected, Object: object})
+	}
+	return drift
+}
+
+// sameInterval compares intervals by length, ignoring how they are written
+func sameInterval(a, b SchemaInterval) bool {
+	return math.Abs(a.Seconds-b.Seconds) < 1
+}
+
+// sortedKeys returns the keys of a string-keyed map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
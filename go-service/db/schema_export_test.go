@@ -0,0 +1,145 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestExpectedSchemaParsesInitSQL tests that the objects init.sql defines are
+// extracted with their intervals
+func TestExpectedSchemaParsesInitSQL(t *testing.T) {
+	expected, err := ExpectedSchema()
+	if err != nil {
+		t.Fatalf("failed to parse init.sql: %v", err)
+	}
+
+	if len(expected.Hypertables) != 1 || expected.Hypertables[0].Name != "telemetry" ||
+		expected.Hypertables[0].ChunkInterval.Seconds != 3600 {
+		t.Errorf("unexpected hypertables: %+v", expected.Hypertables)
+	}
+	if len(expected.ContinuousAggregates) != 4 {
+		t.Errorf("expected 4 continuous aggregates, got %+v", expected.ContinuousAggregates)
+	}
+
+	kinds := make(map[string]int)
+	for _, p := range expected.Policies {
+		kinds[p.Kind]++
+	}
+	if kinds[PolicyRefresh] != 4 || kinds[PolicyCompression] != 3 || kinds[PolicyRetention] != 4 {
+		t.Errorf("unexpected policy counts: %v", kinds)
+	}
+}
+
+// TestIntervalSeconds tests interval parsing in init.sql and PostgreSQL output forms
+func TestIntervalSeconds(t *testing.T) {
+	cases := map[string]float64{
+		"7 days":          7 * 86400,
+		"1 day":           86400,
+		"30 minutes":      1800,
+		"6 months":        6 * 2629800,
+		"6 mons":          6 * 2629800,
+		"1 year":          31557600,
+		"01:00:00":        3600,
+		"1 day 02:30:00":  86400 + 9000,
+		"2 weeks":         14 * 86400,
+		"00:05:00":        300,
+		"48 hours":        48 * 3600,
+		"1 year 6 months": 31557600 + 6*2629800,
+	}
+	for text, want := range cases {
+		got, err := intervalSeconds(text)
+		if err != nil || got != want {
+			t.Errorf("intervalSeconds(%q) = %v, %v; want %v", text, got, err, want)
+		}
+	}
+	for _, text := range []string{"soon", "5", "3 fortnights"} {
+		if _, err := intervalSeconds(text); err == nil {
+			t.Errorf("expected intervalSeconds(%q) to fail", text)
+		}
+	}
+}
+
+// TestDetectDrift tests missing, unexpected and changed objects, comparing
+// intervals by length rather than spelling
+func TestDetectDrift(t *testing.T) {
+	expected, err := ExpectedSchema()
+	if err != nil {
+		t.Fatalf("failed to parse init.sql: %v", err)
+	}
+
+	// A live database matching init.sql, spelled the way PostgreSQL prints it
+	live := SchemaSnapshot{
+		Hypertables:          []Hypertable{{Name: "telemetry", TimeColumn: "time", ChunkInterval: SchemaInterval{Text: "01:00:00", Seconds: 3600}}},
+		ContinuousAggregates: expected.ContinuousAggregates,
+	}
+	for _, p := range expected.Policies {
+		copied := SchemaPolicy{Kind: p.Kind, Relation: p.Relation, Settings: make(map[string]SchemaInterval)}
+		for name, interval := range p.Settings {
+			copied.Settings[name] = SchemaInterval{Text: "pg:" + interval.Text, Seconds: interval.Seconds}
+		}
+		live.Policies = append(live.Policies, copied)
+	}
+	if drift := DetectDrift(expected, live); len(drift) != 0 {
+		t.Fatalf("expected no drift, got %+v", drift)
+	}
+
+	// Retention on raw telemetry raised to 30 days, one aggregate dropped, an
+	// extra aggregate created by hand
+	for i, p := range live.Policies {
+		if p.Kind == PolicyRetention && p.Relation == "telemetry" {
+			live.Policies[i].Settings["drop_after"] = SchemaInterval{Text: "30 days", Seconds: 30 * 86400}
+		}
+	}
+	live.ContinuousAggregates = append(live.ContinuousAggregates[1:], ContinuousAggregate{Name: "adhoc_stats"})
+
+	drift := DetectDrift(expected, live)
+	found := make(map[string]SchemaDrift)
+	for _, d := range drift {
+		found[d.Kind+" "+d.Object] = d
+	}
+	if d, ok := found["changed retention policy telemetry drop_after"]; !ok || d.Expected != "7 days" || d.Live != "30 days" {
+		t.Errorf("expected changed retention drift, got %+v", drift)
+	}
+	if _, ok := found["missing continuous aggregate "+expected.ContinuousAggregates[0].Name]; !ok {
+		t.Errorf("expected missing aggregate drift, got %+v", drift)
+	}
+	if _, ok := found["unexpected continuous aggregate adhoc_stats"]; !ok {
+		t.Errorf("expected unexpected aggregate drift, got %+v", drift)
+	}
+	if len(drift) != 3 {
+		t.Errorf("expected 3 drift entries, got %+v", drift)
+	}
+}
+
+// TestSchemaMigrationRoundTrip tests that an exported migration parses back
+// to the same schema
+func TestSchemaMigrationRoundTrip(t *testing.T) {
+	expected, err := ExpectedSchema()
+	if err != nil {
+		t.Fatalf("failed to parse init.sql: %v", err)
+	}
+	for i := range expected.ContinuousAggregates {
+		expected.ContinuousAggregates[i].Definition = " SELECT 1"
+	}
+	expected.Compression = []CompressionSettings{{Relation: "telemetry", SegmentBy: []string{"satellite_id"}, OrderBy: []string{"time DESC"}}}
+
+	migration := expected.Migration()
+	for _, want := range []string{
+		"ALTER TABLE telemetry SET (",
+		"timescaledb.compress_orderby = 'time DESC'",
+		"SELECT remove_retention_policy('telemetry', if_exists => TRUE);",
+		"CREATE MATERIALIZED VIEW IF NOT EXISTS satellite_stats\n",
+	} {
+		if !strings.Contains(migration, want) {
+			t.Errorf("expected migration to contain %q:\n%s", want, migration)
+		}
+	}
+
+	parsed, err := parseSchemaSQL(migration)
+	if err != nil {
+		t.Fatalf("failed to parse exported migration: %v", err)
+	}
+	if drift := DetectDrift(expected, parsed); len(drift) != 0 {
+		t.Errorf("expected the exported migration to match, got %+v", drift)
+	}
+}
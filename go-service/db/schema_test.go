@@ -77,6 +77,7 @@ func TestContinuousAggregatesExist(t *testing.T) {
 		"satellite_stats",
 		"satellite_stats_daily",
 		"satellite_stats_hourly",
+		"satellite_version_stats_hourly",
 	}
 
 	assert.ElementsMatch(t, expectedAggregates, aggregates, "All continuous aggregates should exist")
@@ -218,7 +219,7 @@ func TestRefreshPolicies(t *testing.T) {
 		SELECT COUNT(*) FROM timescaledb_information.continuous_aggregates
 	`).Scan(&count)
 	require.NoError(t, err, "Should be able to query continuous aggregates")
-	assert.Equal(t, 3, count, "Should have 3 continuous aggregates")
+	assert.Equal(t, 4, count, "Should have 4 continuous aggregates")
 }
 
 // TestAggregateColumnsExist verifies all expected columns exist in aggregates
@@ -245,6 +246,11 @@ func TestAggregateColumnsExist(t *testing.T) {
 			"avg_storage",
 			"avg_signal",
 			"data_points",
+			"min_altitude_km",
+			"max_altitude_km",
+			"min_velocity_kmph",
+			"max_velocity_kmph",
+			"avg_semi_major_axis_km",
 		},
 		"satellite_stats_hourly": {
 			"satellite_id",
@@ -260,6 +266,9 @@ func TestAggregateColumnsExist(t *testing.T) {
 			"max_signal",
 			"data_points",
 			"anomaly_count",
+			"min_velocity_kmph",
+			"max_velocity_kmph",
+			"avg_semi_major_axis_km",
 		},
 		"satellite_stats_daily": {
 			"satellite_id",
@@ -275,6 +284,9 @@ func TestAggregateColumnsExist(t *testing.T) {
 			"max_signal",
 			"data_points",
 			"anomaly_count",
+			"min_velocity_kmph",
+			"max_velocity_kmph",
+			"avg_semi_major_axis_km",
 		},
 	}
 
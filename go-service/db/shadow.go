@@ -0,0 +1,336 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"orbitstream/models"
+)
+
+// Kinds of shadow differences
+const (
+	ShadowMissing    = "missing"    // stored in telemetry, not in the shadow table
+	ShadowExtra      = "extra"      // stored in the shadow table only
+	ShadowMismatched = "mismatched" // stored in both with different values
+)
+
+// ShadowWriteStats counts the shadow writes since start
+type ShadowWriteStats struct {
+	Table   string    `json:"table"`
+	Columns []string  `json:"columns"`
+	Started time.Time `json:"started"`
+	Batches int64     `json:"batches"`
+	Points  int64     `json:"points"`
+	// Failed counts points of batches the shadow table rejected, Dropped
+	// points that found the queue full; both are missing from the shadow
+	Failed      int64      `json:"failed"`
+	Dropped     int64      `json:"dropped"`
+	Queued      int        `json:"queued"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// ShadowDifference is one row that differs between telemetry and the shadow
+type ShadowDifference struct {
+	SatelliteID string    `json:"satellite_id"`
+	Time        time.Time `json:"time"`
+	Kind        string    `json:"kind"`
+}
+
+// ShadowComparison compares telemetry with the shadow table over a window,
+// on the columns both have
+type ShadowComparison struct {
+	From            time.Time          `json:"from"`
+	To              time.Time          `json:"to"`
+	PrimaryRows     int64              `json:"primary_rows"`
+	ShadowRows      int64              `json:"shadow_rows"`
+	MissingInShadow int64              `json:"missing_in_shadow"`
+	ExtraInShadow   int64              `json:"extra_in_shadow"`
+	Mismatched      int64              `json:"mismatched"`
+	Match           bool               `json:"match"`
+	Samples         []ShadowDifference `json:"samples"`
+}
+
+// ShadowWriter burns in a new telemetry table layout: every batch committed
+// to telemetry is written again to the shadow table, on the columns both
+// tables have, and Compare checks the two agree. Shadow writes run on their
+// own goroutine after the telemetry commit, so a failing or slow shadow
+// table never holds up or fails ingest; what it misses is counted instead.
+type ShadowWriter struct {
+	pool       *pgxpool.Pool
+	table      string // as configured, for reports
+	ident      string // quoted for SQL
+	columns    []telemetryColumn
+	insertStmt string
+	queue      chan []models.TelemetryPoint
+
+	mu     sync.Mutex
+	stats  ShadowWriteStats
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// NewShadowWriter creates a writer for table ("name" or "schema.name"),
+// which must exist and have satellite_id and time columns. queueSize bounds
+// the batches waiting to be written.
+func NewShadowWriter(ctx context.Context, pool *pgxpool.Pool, table string, queueSize int) (*ShadowWriter, error) {
+	ident := pgx.Identifier(strings.Split(table, ".")).Sanitize()
+	rows, err := pool.Query(ctx, `
+		SELECT attname FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped
+	`, ident)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shadow table %s: %w", table, err)
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !existing["satellite_id"] || !existing["time"] {
+		return nil, fmt.Errorf("shadow table %s needs satellite_id and time columns", table)
+	}
+
+	columns := shadowColumns(existing)
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+	w := &ShadowWriter{
+		pool:       pool,
+		table:      table,
+		ident:      ident,
+		columns:    columns,
+		insertStmt: buildShadowInsertStmt(ident, columns),
+		queue:      make(chan []models.TelemetryPoint, queueSize),
+	}
+	w.stats = ShadowWriteStats{Table: table, Columns: columnNames(columns), Started: time.Now().UTC()}
+	return w, nil
+}
+
+// shadowColumns returns the telemetry columns the shadow table also has
+func shadowColumns(existing map[string]bool) []telemetryColumn {
+	columns := make([]telemetryColumn, 0, len(telemetryColumns))
+	for _, column := range telemetryColumns {
+		if existing[column.name] {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func columnNames(columns []telemetryColumn) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+	return names
+}
+
+// buildShadowInsertStmt skips rows the shadow already has under any of its
+// unique constraints, so replays don't fail the burn-in
+func buildShadowInsertStmt(ident string, columns []telemetryColumn) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT DO NOTHING",
+		ident, strings.Join(columnNames(columns), ", "), strings.Join(placeholders, ", "))
+}
+
+// Start writes queued batches until Stop
+func (w *ShadowWriter) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for batch := range w.queue {
+			w.write(batch)
+		}
+	}()
+}
+
+// Stop writes the batches still queued and waits; batches committed later
+// are counted as dropped
+func (w *ShadowWriter) Stop() {
+	w.mu.Lock()
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+	w.wg.Wait()
+}
+
+// enqueue hands a copy of a committed batch to the writer without blocking
+// (flushed batches are recycled); a full queue drops it
+func (w *ShadowWriter) enqueue(batch []models.TelemetryPoint) {
+	if w == nil || len(batch) == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		select {
+		case w.queue <- append([]models.TelemetryPoint(nil), batch...):
+			return
+		default:
+		}
+	}
+	w.stats.Dropped += int64(len(batch))
+}
+
+// write inserts one batch into the shadow table
+func (w *ShadowWriter) write(batch []models.TelemetryPoint) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := w.insert(ctx, batch)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		now := time.Now().UTC()
+		w.stats.Failed += int64(len(batch))
+		w.stats.LastError = err.Error()
+		w.stats.LastErrorAt = &now
+		log.Printf("ShadowWrite: failed to write %d records to %s: %v", len(batch), w.table, err)
+		return
+	}
+	w.stats.Batches++
+	w.stats.Points += int64(len(batch))
+}
+
+func (w *ShadowWriter) insert(ctx context.Context, batch []models.TelemetryPoint) error {
+	tx, err := w.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	queued := &pgx.Batch{}
+	for i := range batch {
+		v := reflect.ValueOf(&batch[i]).Elem()
+		args := make([]interface{}, len(w.columns))
+		for j, column := range w.columns {
+			args[j] = v.FieldByIndex(column.index).Interface()
+		}
+		queued.Queue(w.insertStmt, args...)
+	}
+	if _, err := sendBatch(ctx, tx, queued); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Stats returns the shadow write counters
+func (w *ShadowWriter) Stats() ShadowWriteStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := w.stats
+	stats.Queued = len(w.queue)
+	return stats
+}
+
+// Started returns when shadow writing began; telemetry older than that was
+// never shadowed
+func (w *ShadowWriter) Started() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats.Started
+}
+
+// Compare joins telemetry and the shadow table on (satellite_id, time) over
+// [from, to) and counts rows missing from either side or with different
+// values in the shared columns, returning up to samples differences
+func (w *ShadowWriter) Compare(ctx context.Context, from, to time.Time, samples int) (ShadowComparison, error) {
+	result := ShadowComparison{From: from, To: to, Samples: []ShadowDifference{}}
+	join := w.comparisonJoin()
+
+	err := w.pool.QueryRow(ctx, `
+		SELECT
+			COUNT(p.satellite_id),
+			COUNT(s.satellite_id),
+			COUNT(*) FILTER (WHERE s.satellite_id IS NULL),
+			COUNT(*) FILTER (WHERE p.satellite_id IS NULL),
+			COUNT(*) FILTER (WHERE `+w.mismatchCondition()+`)
+		`+join, from, to).Scan(&result.PrimaryRows, &result.ShadowRows,
+		&result.MissingInShadow, &result.ExtraInShadow, &result.Mismatched)
+	if err != nil {
+		return result, err
+	}
+	result.Match = result.MissingInShadow == 0 && result.ExtraInShadow == 0 && result.Mismatched == 0
+	if result.Match || samples <= 0 {
+		return result, nil
+	}
+
+	rows, err := w.pool.Query(ctx, `
+		SELECT COALESCE(p.satellite_id, s.satellite_id), COALESCE(p.time, s.time),
+			CASE WHEN s.satellite_id IS NULL THEN '`+ShadowMissing+`'
+				WHEN p.satellite_id IS NULL THEN '`+ShadowExtra+`'
+				ELSE '`+ShadowMismatched+`' END
+		`+join+`
+		WHERE s.satellite_id IS NULL OR p.satellite_id IS NULL OR `+w.mismatchCondition()+`
+		ORDER BY 2, 1
+		LIMIT $3
+	`, from, to, samples)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var diff ShadowDifference
+		if err := rows.Scan(&diff.SatelliteID, &diff.Time, &diff.Kind); err != nil {
+			return result, err
+		}
+		result.Samples = append(result.Samples, diff)
+	}
+	return result, rows.Err()
+}
+
+// comparisonJoin is the FROM clause pairing the rows of both tables in the
+// window ($1, $2)
+func (w *ShadowWriter) comparisonJoin() string {
+	list := strings.Join(columnNames(w.columns), ", ")
+	return fmt.Sprintf(`FROM (SELECT %s FROM telemetry WHERE time >= $1 AND time < $2) p
+		FULL OUTER JOIN (SELECT %s FROM %s WHERE time >= $1 AND time < $2) s
+		ON p.satellite_id = s.satellite_id AND p.time = s.time`, list, list, w.ident)
+}
+
+// mismatchCondition is true for paired rows whose shared columns differ
+func (w *ShadowWriter) mismatchCondition() string {
+	primary := make([]string, len(w.columns))
+	shadow := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		primary[i] = "p." + column.name
+		shadow[i] = "s." + column.name
+	}
+	return fmt.Sprintf("p.satellite_id IS NOT NULL AND s.satellite_id IS NOT NULL AND (%s) IS DISTINCT FROM (%s)",
+		strings.Join(primary, ", "), strings.Join(shadow, ", "))
+}
+
+// SetShadowWriter writes every committed batch to writer as well; must be
+// called before Start
+func (bp *BatchProcessor) SetShadowWriter(writer *ShadowWriter) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.shadow = writer
+}
+
+// GetShadowWriter returns the shadow writer (nil unless burning in a table)
+func (bp *BatchProcessor) GetShadowWriter() *ShadowWriter {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return bp.shadow
+}
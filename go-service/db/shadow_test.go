@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+// TestShadowInsertStmt tests that shadow writes insert only the columns the
+// shadow table shares with telemetry
+func TestShadowInsertStmt(t *testing.T) {
+	columns := shadowColumns(map[string]bool{"time": true, "satellite_id": true, "battery_charge_percent": true, "renamed_column": true})
+	names := columnNames(columns)
+	if len(names) != 3 {
+		t.Fatalf("expected 3 shared columns, got %v", names)
+	}
+
+	stmt := buildShadowInsertStmt(`"telemetry_v2"`, columns)
+	if !strings.HasPrefix(stmt, `INSERT INTO "telemetry_v2" (`+strings.Join(names, ", ")+")") {
+		t.Errorf("unexpected column list: %s", stmt)
+	}
+	if got := strings.Count(stmt, "$"); got != 3 {
+		t.Errorf("expected 3 placeholders, got %d: %s", got, stmt)
+	}
+	if !strings.HasSuffix(stmt, "ON CONFLICT DO NOTHING") {
+		t.Errorf("expected shadow writes to skip stored rows: %s", stmt)
+	}
+}
+
+// TestShadowWriterCompare tests that shadowed batches compare equal and that
+// missing and changed shadow rows are reported
+func TestShadowWriterCompare(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pool, cleanup := SetupTestDB(t)
+	defer cleanup()
+	if err := InitTestSchema(pool); err != nil {
+		t.Fatalf("Failed to initialize schema: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := pool.Exec(ctx, `
+		CREATE TABLE telemetry_v2 (LIKE telemetry INCLUDING DEFAULTS);
+		CREATE UNIQUE INDEX ON telemetry_v2 (satellite_id, time);
+	`); err != nil {
+		t.Fatalf("Failed to create shadow table: %v", err)
+	}
+
+	writer, err := NewShadowWriter(ctx, pool, "telemetry_v2", 10)
+	if err != nil {
+		t.Fatalf("NewShadowWriter failed: %v", err)
+	}
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-10 * time.Minute)
+	batch := make([]models.TelemetryPoint, 3)
+	for i := range batch {
+		batch[i] = TelemetryPointForTest(80, 100, -60)
+		batch[i].SatelliteID = "SAT-0001"
+		batch[i].Timestamp = base.Add(time.Duration(i) * time.Second)
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	if _, err := insertTelemetry(ctx, tx, batch); err != nil {
+		t.Fatalf("Failed to insert telemetry: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	writer.Start()
+	writer.enqueue(batch)
+	writer.Stop()
+	if stats := writer.Stats(); stats.Points != 3 || stats.Failed != 0 || stats.Dropped != 0 {
+		t.Fatalf("unexpected shadow stats %+v", stats)
+	}
+
+	from, to := base.Add(-time.Minute), base.Add(time.Minute)
+	comparison, err := writer.Compare(ctx, from, to, 10)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if !comparison.Match || comparison.PrimaryRows != 3 || comparison.ShadowRows != 3 {
+		t.Errorf("expected matching tables, got %+v", comparison)
+	}
+
+	if _, err := pool.Exec(ctx, `DELETE FROM telemetry_v2 WHERE time = $1`, batch[0].Timestamp); err != nil {
+		t.Fatalf("Failed to delete shadow row: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `UPDATE telemetry_v2 SET battery_charge_percent = 10 WHERE time = $1`, batch[1].Timestamp); err != nil {
+		t.Fatalf("Failed to change shadow row: %v", err)
+	}
+	comparison, err = writer.Compare(ctx, from, to, 10)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if comparison.Match || comparison.MissingInShadow != 1 || comparison.Mismatched != 1 || comparison.ExtraInShadow != 0 {
+		t.Errorf("expected one missing and one mismatched row, got %+v", comparison)
+	}
+	if len(comparison.Samples) != 2 || comparison.Samples[0].Kind != ShadowMissing || comparison.Samples[1].Kind != ShadowMismatched {
+		t.Errorf("unexpected samples %+v", comparison.Samples)
+	}
+}
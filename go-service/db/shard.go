@@ -0,0 +1,114 @@
+package db
+
+import (
+	"hash/fnv"
+
+	"orbitstream/models"
+)
+
+// bufferShard holds the buffered points of the satellites hashing to it
+type bufferShard struct {
+	points []models.TelemetryPoint
+	// A batch of the shard is being flushed; the next one waits for it so
+	// each satellite's points are stored in order
+	flushing bool
+}
+
+// SetBufferShards splits the buffer into shards by satellite ID hash; must
+// be called before Start. Each shard holds at most its share of the max
+// buffer size, so one chatty satellite fills its own shard instead of the
+// whole buffer, and the flush workers flush different shards in parallel
+// (one batch per shard at a time). shards <= 1 keeps a single buffer. Not
+// used in write-through mode, whose journal commits consecutive positions.
+func (bp *BatchProcessor) SetBufferShards(shards int) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	if shards <= 1 || bp.journal != nil {
+		bp.shards = nil
+		return
+	}
+	bp.shards = make([]*bufferShard, shards)
+	for i := range bp.shards {
+		bp.shards[i] = &bufferShard{}
+	}
+}
+
+// shardFor returns the shard of a satellite (nil when unsharded)
+// Caller must hold bufferMutex
+func (bp *BatchProcessor) shardFor(satelliteID string) *bufferShard {
+	if len(bp.shards) == 0 {
+		return nil
+	}
+	h := fnv.New32a()
+	h.Write([]byte(satelliteID))
+	return bp.shards[h.Sum32()%uint32(len(bp.shards))]
+}
+
+// shardCapacity is the points one shard may hold, its share of the max
+// buffer size (at least one)
+// Caller must hold bufferMutex
+func (bp *BatchProcessor) shardCapacity() int {
+	return max(bp.maxBufferSize/len(bp.shards), 1)
+}
+
+// shardCount is the number of batches a whole-buffer flush takes
+func (bp *BatchProcessor) shardCount() int {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return max(len(bp.shards), 1)
+}
+
+// bufferedLocked returns the points buffered across all shards
+// Caller must hold bufferMutex
+func (bp *BatchProcessor) bufferedLocked() int {
+	n := len(bp.buffer)
+	for _, shard := range bp.shards {
+		n += len(shard.points)
+	}
+	return n
+}
+
+// bufferedPointsLocked returns a copy of every buffered point
+// Caller must hold bufferMutex
+func (bp *BatchProcessor) bufferedPointsLocked() []models.TelemetryPoint {
+	points := append([]models.TelemetryPoint(nil), bp.buffer...)
+	for _, shard := range bp.shards {
+		points = append(points, shard.points...)
+	}
+	return points
+}
+
+// takeShardBatch swaps up to max points (all when max <= 0) out of the next
+// shard, round robin, that has points and no batch being flushed
+// Caller must hold bufferMutex
+func (bp *BatchProcessor) takeShardBatch(max int) (flushJob, bool) {
+	for i := range bp.shards {
+		index := (bp.nextShard + i) % len(bp.shards)
+		shard := bp.shards[index]
+		if shard.flushing || len(shard.points) == 0 {
+			continue
+		}
+		n := len(shard.points)
+		if max > 0 && n > max {
+			n = max
+		}
+
+		batch := bp.swapOut(&shard.points, n)
+		shard.flushing = true
+		bp.nextShard = index + 1
+		return flushJob{batch: batch, flightID: bp.trackInFlight(batch), shard: shard}, true
+	}
+	return flushJob{}, false
+}
+
+// releaseShard lets the next batch of a shard be flushed and wakes the flush
+// loop to dispatch it
+func (bp *BatchProcessor) releaseShard(shard *bufferShard) {
+	bp.bufferMutex.Lock()
+	shard.flushing = false
+	pending := len(shard.points) > 0
+	bp.bufferMutex.Unlock()
+	if pending {
+		bp.wakeFlushLoop()
+	}
+}
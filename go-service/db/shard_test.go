@@ -0,0 +1,138 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// otherShardSatellite returns a satellite ID hashing to a different shard
+// than satelliteID
+func otherShardSatellite(t *testing.T, bp *BatchProcessor, satelliteID string) string {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		candidate := fmt.Sprintf("SAT-%03d", i)
+		if bp.shardFor(candidate) != bp.shardFor(satelliteID) {
+			return candidate
+		}
+	}
+	t.Fatal("no satellite hashes to another shard")
+	return ""
+}
+
+// TestShardedBufferIsolatesChattySatellite tests that a satellite filling
+// its shard is rejected while satellites of other shards are still accepted
+func TestShardedBufferIsolatesChattySatellite(t *testing.T) {
+	bp := NewBatchProcessor(nil, 1000, time.Second, AnomalyConfig{})
+	bp.SetMaxBufferSize(40)
+	bp.SetBufferShards(4)
+
+	accepted := 0
+	for i := 0; i < 40; i++ {
+		point := TelemetryPointForTest(85.0, 45000.0, -55.0)
+		point.SatelliteID = "SAT-CHATTY"
+		if err := bp.Add(point); err != nil {
+			break
+		}
+		accepted++
+	}
+	if accepted != 10 {
+		t.Errorf("expected the chatty satellite to fill its shard of 10, got %d", accepted)
+	}
+
+	point := TelemetryPointForTest(85.0, 45000.0, -55.0)
+	point.SatelliteID = otherShardSatellite(t, bp, "SAT-CHATTY")
+	if err := bp.Add(point); err != nil {
+		t.Errorf("expected a satellite of another shard to be accepted, got %v", err)
+	}
+	if size := bp.GetBufferSize(); size != 11 {
+		t.Errorf("expected 11 buffered points, got %d", size)
+	}
+}
+
+// TestTakeShardBatchOnePerShard tests that batches come from different
+// shards and a shard's next batch waits until its flush finished
+func TestTakeShardBatchOnePerShard(t *testing.T) {
+	bp := NewBatchProcessor(nil, 2, time.Second, AnomalyConfig{})
+	bp.SetBufferShards(4)
+	other := otherShardSatellite(t, bp, "SAT-A")
+	for _, satelliteID := range []string{"SAT-A", "SAT-A", "SAT-A", other} {
+		point := TelemetryPointForTest(85.0, 45000.0, -55.0)
+		point.SatelliteID = satelliteID
+		if err := bp.Add(point); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+	}
+
+	first, ok := bp.takeBatch(2)
+	if !ok {
+		t.Fatal("expected a batch")
+	}
+	second, ok := bp.takeBatch(2)
+	if !ok || second.shard == first.shard {
+		t.Fatalf("expected a batch from the other shard, got %v", ok)
+	}
+	if _, ok := bp.takeBatch(2); ok {
+		t.Error("expected no batch while both shards are being flushed")
+	}
+
+	sharded := first
+	if first.batch[0].SatelliteID != "SAT-A" {
+		sharded = second
+	}
+	if len(sharded.batch) != 2 {
+		t.Errorf("expected a batch of 2 SAT-A points, got %d", len(sharded.batch))
+	}
+	bp.releaseShard(sharded.shard)
+	job, ok := bp.takeBatch(2)
+	if !ok || len(job.batch) != 1 || job.batch[0].SatelliteID != "SAT-A" {
+		t.Errorf("expected the last SAT-A point once its shard was released, got %d points", len(job.batch))
+	}
+}
+
+// TestShardedFlushKeepsSatelliteOrder tests that parallel workers flushing
+// different shards store every point, each satellite's in order
+func TestShardedFlushKeepsSatelliteOrder(t *testing.T) {
+	unreachable, wal := newUnreachableBatchProcessor(t)
+	bp := NewBatchProcessor(unreachable.pool, 5, 5*time.Millisecond, AnomalyConfig{})
+	bp.SetWAL(wal)
+	bp.SetRetryConfig(1, time.Millisecond)
+	bp.SetFlushWorkers(3)
+	bp.SetBufferShards(4)
+
+	stopped := make(chan struct{})
+	go func() {
+		bp.Start()
+		close(stopped)
+	}()
+	start := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 200; i++ {
+		point := TelemetryPointForTest(85.0, 45000.0, -55.0)
+		point.SatelliteID = fmt.Sprintf("SAT-%d", i%8)
+		point.Timestamp = start.Add(time.Duration(i) * time.Second)
+		if err := bp.Add(point); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+	}
+	bp.Stop()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Start did not return after Stop")
+	}
+
+	records, err := wal.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read WAL: %v", err)
+	}
+	if len(records) != 200 {
+		t.Fatalf("expected 200 WAL records, got %d", len(records))
+	}
+	last := make(map[string]time.Time)
+	for _, record := range records {
+		if previous, ok := last[record.SatelliteID]; ok && !record.Timestamp.After(previous) {
+			t.Fatalf("%s: %v stored after %v", record.SatelliteID, record.Timestamp, previous)
+		}
+		last[record.SatelliteID] = record.Timestamp
+	}
+}
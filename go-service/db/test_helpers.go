@@ -13,6 +13,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
+	"orbitstream/models"
 )
 
 // SetupTestDB creates a TimescaleDB container for testing and returns a connection pool
@@ -201,23 +202,16 @@ type TestTelemetryPoint struct {
 func InsertTestTelemetry(pool *pgxpool.Pool, points []TestTelemetryPoint) error {
 	ctx := context.Background()
 
-	stmt := `
-		INSERT INTO telemetry (
-			time, satellite_id, battery_charge_percent,
-			storage_usage_mb, signal_strength_dbm, is_anomaly
-		) VALUES ($1, $2, $3, $4, $5, $6)
-	`
-
-	for i, point := range points {
-		_, err := pool.Exec(ctx, stmt,
-			point.Timestamp,
-			point.SatelliteID,
-			point.BatteryChargePercent,
-			point.StorageUsageMB,
-			point.SignalStrengthDBM,
-			point.IsAnomaly,
-		)
-		if err != nil {
+	for i, p := range points {
+		point := models.TelemetryPoint{
+			Timestamp:            p.Timestamp,
+			SatelliteID:          p.SatelliteID,
+			BatteryChargePercent: p.BatteryChargePercent,
+			StorageUsageMB:       p.StorageUsageMB,
+			SignalStrengthDBM:    p.SignalStrengthDBM,
+			IsAnomaly:            p.IsAnomaly,
+		}
+		if _, err := pool.Exec(ctx, telemetryInsertStmt, telemetryInsertArgs(&point)...); err != nil {
 			return fmt.Errorf("failed to insert test data at row %d: %w", i, err)
 		}
 	}
@@ -246,6 +240,7 @@ func RefreshAllAggregates(pool *pgxpool.Pool) error {
 		"satellite_stats",
 		"satellite_stats_hourly",
 		"satellite_stats_daily",
+		"satellite_version_stats_hourly",
 	}
 
 	for _, agg := range aggregates {
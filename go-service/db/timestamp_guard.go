@@ -0,0 +1,127 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"orbitstream/models"
+)
+
+// Timestamp guard actions
+const (
+	// TimestampReject refuses points with out-of-range timestamps
+	TimestampReject = "reject"
+	// TimestampClamp moves them to the nearest allowed time and tags them
+	// timestamp_clamped
+	TimestampClamp = "clamp"
+)
+
+// ErrTimestampOutOfRange is returned by Add for points the timestamp guard
+// rejects
+var ErrTimestampOutOfRange = errors.New("timestamp out of range")
+
+// TimestampGuardConfig bounds the timestamps ingest accepts
+type TimestampGuardConfig struct {
+	Action string
+	// MaxFuture is how far ahead of the server clock a timestamp may be
+	// (0 = unchecked)
+	MaxFuture time.Duration
+	// MaxAge is how far behind it may be, normally the raw retention
+	// window, since older points land in chunks already dropped (0 =
+	// unchecked)
+	MaxAge time.Duration
+}
+
+// TimestampGuardStats counts the out-of-range timestamps by case and what
+// was done with them
+type TimestampGuardStats struct {
+	Action    string `json:"action"`
+	MaxFuture string `json:"max_future"`
+	MaxAge    string `json:"max_age"`
+	Future    int64  `json:"future"`
+	TooOld    int64  `json:"too_old"`
+	Rejected  int64  `json:"rejected"`
+	Clamped   int64  `json:"clamped"`
+}
+
+// TimestampGuard keeps points with bad satellite clocks out of historical
+// buckets: a timestamp too far in the future or older than the retention
+// window is rejected, or clamped to the allowed range and tagged so queries
+// and aggregates can tell it apart.
+type TimestampGuard struct {
+	cfg      TimestampGuardConfig
+	future   atomic.Int64
+	tooOld   atomic.Int64
+	rejected atomic.Int64
+	clamped  atomic.Int64
+}
+
+// NewTimestampGuard creates a guard
+func NewTimestampGuard(cfg TimestampGuardConfig) (*TimestampGuard, error) {
+	if cfg.Action != TimestampReject && cfg.Action != TimestampClamp {
+		return nil, fmt.Errorf("unknown timestamp guard action %q (want %s or %s)", cfg.Action, TimestampReject, TimestampClamp)
+	}
+	if cfg.MaxFuture < 0 || cfg.MaxAge < 0 {
+		return nil, fmt.Errorf("timestamp bounds must not be negative")
+	}
+	return &TimestampGuard{cfg: cfg}, nil
+}
+
+// check applies the guard to point at now; a nil guard accepts everything
+func (g *TimestampGuard) check(point *models.TelemetryPoint, now time.Time) error {
+	if g == nil {
+		return nil
+	}
+
+	var bound time.Time
+	switch {
+	case g.cfg.MaxFuture > 0 && point.Timestamp.After(now.Add(g.cfg.MaxFuture)):
+		g.future.Add(1)
+		bound = now.Add(g.cfg.MaxFuture)
+	case g.cfg.MaxAge > 0 && point.Timestamp.Before(now.Add(-g.cfg.MaxAge)):
+		g.tooOld.Add(1)
+		bound = now.Add(-g.cfg.MaxAge)
+	default:
+		return nil
+	}
+
+	if g.cfg.Action == TimestampReject {
+		g.rejected.Add(1)
+		return fmt.Errorf("%w: %s is more than %s from the server clock", ErrTimestampOutOfRange,
+			point.Timestamp.Format(time.RFC3339), now.Sub(point.Timestamp).Abs().Round(time.Second))
+	}
+	g.clamped.Add(1)
+	point.Timestamp = bound.UTC()
+	point.TimestampClamped = true
+	return nil
+}
+
+// Stats returns the bounds and the counts of each case
+func (g *TimestampGuard) Stats() TimestampGuardStats {
+	return TimestampGuardStats{
+		Action:    g.cfg.Action,
+		MaxFuture: g.cfg.MaxFuture.String(),
+		MaxAge:    g.cfg.MaxAge.String(),
+		Future:    g.future.Load(),
+		TooOld:    g.tooOld.Load(),
+		Rejected:  g.rejected.Load(),
+		Clamped:   g.clamped.Load(),
+	}
+}
+
+// SetTimestampGuard rejects or clamps points with out-of-range timestamps;
+// must be called before Start
+func (bp *BatchProcessor) SetTimestampGuard(guard *TimestampGuard) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.timestampGuard = guard
+}
+
+// GetTimestampGuard returns the timestamp guard (nil if disabled)
+func (bp *BatchProcessor) GetTimestampGuard() *TimestampGuard {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return bp.timestampGuard
+}
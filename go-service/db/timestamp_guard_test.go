@@ -0,0 +1,88 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestTimestampGuardRejects tests that points too far ahead or behind the
+// server clock are refused and counted by case
+func TestTimestampGuardRejects(t *testing.T) {
+	bp := NewBatchProcessor(nil, 1000, time.Second, AnomalyConfig{})
+	guard, err := NewTimestampGuard(TimestampGuardConfig{
+		Action:    TimestampReject,
+		MaxFuture: 5 * time.Minute,
+		MaxAge:    24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewTimestampGuard failed: %v", err)
+	}
+	bp.SetTimestampGuard(guard)
+
+	now := time.Now().UTC()
+	for _, offset := range []time.Duration{time.Hour, -48 * time.Hour, -49 * time.Hour} {
+		point := TelemetryPointForTest(85.0, 45000.0, -55.0)
+		point.Timestamp = now.Add(offset)
+		if err := bp.Add(point); !errors.Is(err, ErrTimestampOutOfRange) {
+			t.Errorf("offset %v: expected ErrTimestampOutOfRange, got %v", offset, err)
+		}
+	}
+	point := TelemetryPointForTest(85.0, 45000.0, -55.0)
+	point.Timestamp = now.Add(-time.Hour)
+	if err := bp.Add(point); err != nil {
+		t.Fatalf("expected an in-range point to be accepted, got %v", err)
+	}
+
+	stats := guard.Stats()
+	if stats.Future != 1 || stats.TooOld != 2 || stats.Rejected != 3 || stats.Clamped != 0 {
+		t.Errorf("unexpected guard stats %+v", stats)
+	}
+	if pipeline := bp.Stats(); pipeline.Rejected != 3 || pipeline.Accepted != 1 {
+		t.Errorf("unexpected pipeline stats %+v", pipeline)
+	}
+}
+
+// TestTimestampGuardClamps tests that out-of-range timestamps are moved to
+// the nearest bound and tagged
+func TestTimestampGuardClamps(t *testing.T) {
+	guard, err := NewTimestampGuard(TimestampGuardConfig{
+		Action:    TimestampClamp,
+		MaxFuture: 5 * time.Minute,
+		MaxAge:    24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewTimestampGuard failed: %v", err)
+	}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	future := TelemetryPointForTest(85.0, 45000.0, -55.0)
+	future.Timestamp = now.Add(time.Hour)
+	if err := guard.check(&future, now); err != nil {
+		t.Fatalf("clamp should not fail: %v", err)
+	}
+	if !future.Timestamp.Equal(now.Add(5*time.Minute)) || !future.TimestampClamped {
+		t.Errorf("expected the future point clamped to now+5m, got %v (clamped=%v)", future.Timestamp, future.TimestampClamped)
+	}
+
+	old := TelemetryPointForTest(85.0, 45000.0, -55.0)
+	old.Timestamp = now.Add(-72 * time.Hour)
+	_ = guard.check(&old, now)
+	if !old.Timestamp.Equal(now.Add(-24*time.Hour)) || !old.TimestampClamped {
+		t.Errorf("expected the old point clamped to now-24h, got %v (clamped=%v)", old.Timestamp, old.TimestampClamped)
+	}
+
+	fresh := TelemetryPointForTest(85.0, 45000.0, -55.0)
+	fresh.Timestamp = now.Add(-time.Minute)
+	_ = guard.check(&fresh, now)
+	if fresh.TimestampClamped {
+		t.Error("expected an in-range point to be left alone")
+	}
+
+	if stats := guard.Stats(); stats.Clamped != 2 || stats.Future != 1 || stats.TooOld != 1 {
+		t.Errorf("unexpected guard stats %+v", stats)
+	}
+	if _, err := NewTimestampGuard(TimestampGuardConfig{Action: "drop"}); err == nil {
+		t.Error("expected an unknown action to be refused")
+	}
+}
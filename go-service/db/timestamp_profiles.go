@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// TimestampProfile is how a satellite encodes point timestamps, from the
+// current registry version
+type TimestampProfile struct {
+	// Format is empty for auto-detection
+	Format string
+	// BootEpoch anchors boot-relative offsets (nil when unknown)
+	BootEpoch *time.Time
+}
+
+// TimestampProfileSource loads the timestamp profile of every satellite
+// This allows for mocking in tests
+type TimestampProfileSource interface {
+	TimestampProfiles(ctx context.Context) (map[string]TimestampProfile, error)
+}
+
+// TimestampProfiles returns the timestamp format and boot epoch of every
+// satellite whose current registry version declares either
+func (qs *QueryService) TimestampProfiles(ctx context.Context) (map[string]TimestampProfile, error) {
+	rows, err := qs.query(ctx, `
+		SELECT satellite_id, COALESCE(timestamp_format, ''), boot_epoch
+		FROM satellite_registry_history
+		WHERE valid_to IS NULL AND (timestamp_format IS NOT NULL OR boot_epoch IS NOT NULL)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := make(map[string]TimestampProfile)
+	for rows.Next() {
+		var satelliteID string
+		var profile TimestampProfile
+		if err := rows.Scan(&satelliteID, &profile.Format, &profile.BootEpoch); err != nil {
+			return nil, err
+		}
+		profiles[satelliteID] = profile
+	}
+	return profiles, rows.Err()
+}
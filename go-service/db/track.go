@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+
+	"orbitstream/models"
+)
+
+// QueryTrack returns the positions a satellite reported in the range,
+// oldest first; points without a latitude and longitude are skipped
+func (qs *QueryService) QueryTrack(ctx context.Context, q TelemetryQuery) ([]models.TrackPoint, error) {
+	rows, err := qs.query(ctx, `
+		SELECT time, latitude, longitude, altitude_km
+		FROM telemetry
+		WHERE satellite_id = $1 AND time >= $2 AND time < $3
+		  AND latitude IS NOT NULL AND longitude IS NOT NULL
+		ORDER BY time
+		LIMIT $4
+	`, q.SatelliteID, q.From, q.To, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	track := make([]models.TrackPoint, 0)
+	for rows.Next() {
+		var p models.TrackPoint
+		if err := rows.Scan(&p.Timestamp, &p.Latitude, &p.Longitude, &p.AltitudeKM); err != nil {
+			return nil, err
+		}
+		track = append(track, p)
+	}
+	return track, rows.Err()
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"time"
+
+	"orbitstream/retry"
+)
+
+// Tuning is the part of the processor's configuration that can change while
+// it runs: batching, retries and the fixed anomaly thresholds
+type Tuning struct {
+	BatchSize    int
+	BatchTimeout time.Duration
+	MaxRetries   int
+	RetryDelay   time.Duration
+	Anomaly      AnomalyConfig
+}
+
+// Tuning returns the tuning in use
+func (bp *BatchProcessor) Tuning() Tuning {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return Tuning{
+		BatchSize:    bp.batchSize,
+		BatchTimeout: bp.batchTimeout,
+		MaxRetries:   bp.maxRetries,
+		RetryDelay:   bp.retryDelay,
+		Anomaly:      bp.anomalyConfig,
+	}
+}
+
+// Retune applies tuning to the running processor at once, keeping the
+// buffer, in-flight batches and detector state. The new batch size applies
+// to the next batch taken (an adaptive batch size controller keeps sizing
+// between its own bounds), the timeout restarts the flush ticker, retry
+// settings apply to the next flush and thresholds to the next point.
+func (bp *BatchProcessor) Retune(tuning Tuning) {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	bp.batchSize = tuning.BatchSize
+	bp.maxRetries = tuning.MaxRetries
+	bp.retryDelay = tuning.RetryDelay
+	bp.anomalyConfig = tuning.Anomaly
+	for _, detector := range bp.detectors {
+		if threshold, ok := detector.(*ThresholdDetector); ok {
+			threshold.SetConfig(tuning.Anomaly)
+		}
+	}
+	if tuning.BatchTimeout != bp.batchTimeout {
+		bp.batchTimeout = tuning.BatchTimeout
+		if bp.ticker != nil {
+			bp.ticker.Reset(tuning.BatchTimeout)
+		}
+	}
+}
+
+// targetBatchSize is the size of the batches the flush loop hands to workers
+func (bp *BatchProcessor) targetBatchSize() int {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return bp.batchSizer.sizeOr(bp.batchSize)
+}
+
+// retryPolicy is the retry policy of the next flush
+func (bp *BatchProcessor) retryPolicy() retry.Policy {
+	bp.bufferMutex.Lock()
+	defer bp.bufferMutex.Unlock()
+	return retry.Policy{
+		MaxAttempts: bp.maxRetries,
+		BaseDelay:   bp.retryDelay,
+		Jitter:      bp.retryJitter,
+		Budget:      bp.retryBudget,
+	}
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBatchProcessorRetune tests tuning applies to the next points and
+// flushes while the buffer is kept
+func TestBatchProcessorRetune(t *testing.T) {
+	anomaly := AnomalyConfig{BatteryMinPercent: 10.0, StorageMaxMB: 95000.0, SignalMinDBM: -100.0}
+	bp := NewBatchProcessor(nil, 100, time.Second, anomaly)
+	bp.SetAnomalyDetectors(NewThresholdDetector(anomaly))
+
+	if err := bp.Add(TelemetryPointForTest(15.0, 45000.0, -55.0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bp.buffer[0].IsAnomaly {
+		t.Fatal("expected 15% battery not to be flagged below 10%")
+	}
+
+	anomaly.BatteryMinPercent = 20.0
+	tuning := Tuning{
+		BatchSize:    50,
+		BatchTimeout: 2 * time.Second,
+		MaxRetries:   2,
+		RetryDelay:   10 * time.Millisecond,
+		Anomaly:      anomaly,
+	}
+	bp.Retune(tuning)
+	if err := bp.Add(TelemetryPointForTest(15.0, 45000.0, -55.0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := bp.GetBufferSize(); got != 2 {
+		t.Fatalf("expected the buffer to be kept, got %d points", got)
+	}
+	if !bp.buffer[1].IsAnomaly {
+		t.Error("expected 15% battery to be flagged below the retuned 20%")
+	}
+	if got := bp.Tuning(); got != tuning {
+		t.Errorf("expected tuning %+v, got %+v", tuning, got)
+	}
+	if size := bp.targetBatchSize(); size != 50 {
+		t.Errorf("expected batch size 50, got %d", size)
+	}
+	if policy := bp.retryPolicy(); policy.MaxAttempts != 2 || policy.BaseDelay != 10*time.Millisecond {
+		t.Errorf("expected the retuned retry policy, got %+v", policy)
+	}
+}
@@ -1,23 +1,59 @@
 package db
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"orbitstream/models"
 )
 
 // WAL represents a Write Ahead Log for persistent buffering
 // When the database is unavailable, telemetry data is written to the WAL
 // and replayed when the database becomes available again.
+//
+// The log is a sequence of segment files next to the configured path
+// (data.wal is stored as data-000001.wal, data-000002.wal, ...). Writes go
+// to the newest segment, which is sealed and a new one started once it
+// reaches the maximum segment size or age, so replay can work through a long
+// outage one segment at a time and delete each one as it is committed.
+// Sealed segments can be compressed (data-000001.wal.gz) to stretch the disk
+// of edge nodes over long outages.
 type WAL struct {
-	filePath string
-	file     *os.File
-	mu       sync.Mutex
+	dir            string
+	prefix         string // segment file name prefix, e.g. "data-"
+	ext            string // segment file extension, e.g. ".wal"
+	legacyPath     string // pre-segment single WAL file, migrated on open
+	file           *os.File
+	activeSeq      int
+	activeSize     int64
+	activeCreated  time.Time
+	maxSegmentSize int64
+	maxSegmentAge  time.Duration
+	compression    string // codec for sealed segments, see SetCompression
+	mu             sync.Mutex
+	replicator     WALReplicator
+	replaying      bool // a ReplaySegments call is streaming sealed segments
+	// Sealed segments are compressed in the background, outside mu;
+	// segmentMu orders swapping in a compressed copy with deleting segments
+	compressing sync.WaitGroup
+	segmentMu   sync.Mutex
 }
 
+// Default segment limits, see SetRotation
+const (
+	defaultWALSegmentSize = 100 * 1024 * 1024 // 100MB
+	defaultWALSegmentAge  = time.Hour
+)
+
 // WALRecord represents a single telemetry record in the WAL
 // This is stored as JSON in the WAL file for easy inspection and debugging
 type WALRecord struct {
@@ -32,11 +68,75 @@ type WALRecord struct {
 	Longitude            *float64  `json:"longitude,omitempty"`
 	AltitudeKM           *float64  `json:"altitude_km,omitempty"`
 	VelocityKMPH         *float64  `json:"velocity_kmph,omitempty"`
+	SoftwareVersion      *string   `json:"software_version,omitempty"`
+	AnomalyDetector      *string   `json:"anomaly_detector,omitempty"`
+	AnomalySeverity      *models.AnomalySeverity  `json:"anomaly_severity,omitempty"`
+	AnomalyDimension     *models.AnomalyDimension `json:"anomaly_dimension,omitempty"`
+	IsLate               bool                     `json:"is_late,omitempty"`
+	TimestampClamped     bool                     `json:"timestamp_clamped,omitempty"`
+	ValidationIssues     *string                  `json:"validation_issues,omitempty"`
+	PositionAnomaly      bool                     `json:"position_anomaly,omitempty"`
+	GeofenceZones        *string                  `json:"geofence_zones,omitempty"`
+	PositionResidualKM   *float64                 `json:"position_residual_km,omitempty"`
+}
+
+// NewWALRecord converts a telemetry point into its WAL representation
+func NewWALRecord(point models.TelemetryPoint) WALRecord {
+	return WALRecord{
+		Timestamp:            point.Timestamp,
+		SatelliteID:          point.SatelliteID,
+		BatteryChargePercent: point.BatteryChargePercent,
+		StorageUsageMB:       point.StorageUsageMB,
+		SignalStrengthDBM:    point.SignalStrengthDBM,
+		IsAnomaly:            point.IsAnomaly,
+		Latitude:             point.Latitude,
+		Longitude:            point.Longitude,
+		AltitudeKM:           point.AltitudeKM,
+		VelocityKMPH:         point.VelocityKMPH,
+		SoftwareVersion:      point.SoftwareVersion,
+		AnomalyDetector:      point.AnomalyDetector,
+		AnomalySeverity:      point.AnomalySeverity,
+		AnomalyDimension:     point.AnomalyDimension,
+		IsLate:               point.IsLate,
+		TimestampClamped:     point.TimestampClamped,
+		ValidationIssues:     point.ValidationIssues,
+		PositionAnomaly:      point.PositionAnomaly,
+		GeofenceZones:        point.GeofenceZones,
+		PositionResidualKM:   point.PositionResidualKM,
+	}
+}
+
+// TelemetryPoint converts the record back into a telemetry point for replay
+func (r WALRecord) TelemetryPoint() models.TelemetryPoint {
+	return models.TelemetryPoint{
+		Timestamp:            r.Timestamp,
+		SatelliteID:          r.SatelliteID,
+		BatteryChargePercent: r.BatteryChargePercent,
+		StorageUsageMB:       r.StorageUsageMB,
+		SignalStrengthDBM:    r.SignalStrengthDBM,
+		IsAnomaly:            r.IsAnomaly,
+		Latitude:             r.Latitude,
+		Longitude:            r.Longitude,
+		AltitudeKM:           r.AltitudeKM,
+		VelocityKMPH:         r.VelocityKMPH,
+		SoftwareVersion:      r.SoftwareVersion,
+		AnomalyDetector:      r.AnomalyDetector,
+		AnomalySeverity:      r.AnomalySeverity,
+		AnomalyDimension:     r.AnomalyDimension,
+		IsLate:               r.IsLate,
+		TimestampClamped:     r.TimestampClamped,
+		ValidationIssues:     r.ValidationIssues,
+		PositionAnomaly:      r.PositionAnomaly,
+		GeofenceZones:        r.GeofenceZones,
+		PositionResidualKM:   r.PositionResidualKM,
+	}
 }
 
 // NewWAL creates a new WAL instance
-// It creates the directory for the WAL file if it doesn't exist
-// If the WAL file already exists, it will be opened and existing records can be read
+// It creates the directory for the WAL segments if it doesn't exist
+// If segments already exist, new records are appended to the newest one and
+// existing records can be read. A single-file WAL left by an older version
+// at walPath is kept as the oldest segment.
 func NewWAL(walPath string) (*WAL, error) {
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(walPath)
@@ -44,16 +144,52 @@ func NewWAL(walPath string) (*WAL, error) {
 		return nil, fmt.Errorf("failed to create WAL directory: %w", err)
 	}
 
-	// Open file in append mode, create if doesn't exist
-	file, err := os.OpenFile(walPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	ext := filepath.Ext(walPath)
+	w := &WAL{
+		dir:            dir,
+		prefix:         strings.TrimSuffix(filepath.Base(walPath), ext) + "-",
+		ext:            ext,
+		legacyPath:     walPath,
+		maxSegmentSize: defaultWALSegmentSize,
+		maxSegmentAge:  defaultWALSegmentAge,
+	}
+	if err := w.migrateLegacyFile(); err != nil {
+		return nil, err
+	}
+	w.removeCompressionLeftovers()
+
+	seqs, err := w.segmentSeqs()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open WAL file: %w", err)
+		return nil, err
+	}
+	seq := 1
+	if len(seqs) > 0 {
+		seq = seqs[len(seqs)-1]
+		if w.segmentFile(seq) != w.segmentPath(seq) {
+			seq++ // already sealed and compressed
+		}
+	}
+	if err := w.openSegment(seq); err != nil {
+		return nil, err
 	}
+	return w, nil
+}
 
-	return &WAL{
-		filePath: walPath,
-		file:     file,
-	}, nil
+// SetRotation sets when the active segment is sealed: once it holds maxSize
+// bytes or is older than maxAge. Zero disables the respective limit.
+func (w *WAL) SetRotation(maxSize int64, maxAge time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.maxSegmentSize = maxSize
+	w.maxSegmentAge = maxAge
+}
+
+// SetReplicator registers a replicator that receives every appended record
+// and every clear, used to stream the WAL to a warm standby
+func (w *WAL) SetReplicator(replicator WALReplicator) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.replicator = replicator
 }
 
 // Write appends a record to the WAL in JSON format
@@ -69,9 +205,17 @@ func (w *WAL) Write(record WALRecord) error {
 		return fmt.Errorf("failed to marshal WAL record: %w", err)
 	}
 
-	// Append newline and write to file
+	if w.shouldRotate() {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	// Append newline and write to the active segment
 	data = append(data, '\n')
-	if _, err := w.file.Write(data); err != nil {
+	n, err := w.file.Write(data)
+	w.activeSize += int64(n)
+	if err != nil {
 		return fmt.Errorf("failed to write WAL record: %w", err)
 	}
 
@@ -80,109 +224,352 @@ func (w *WAL) Write(record WALRecord) error {
 		return fmt.Errorf("failed to sync WAL file: %w", err)
 	}
 
+	if w.replicator != nil {
+		w.replicator.Append(record)
+	}
+
 	return nil
 }
 
-// ReadAll reads all records from the WAL
-// This opens the file in read-only mode and parses each line as JSON
+// ReadAll reads all records from every segment, oldest first
+// Prefer Iter or ReadBatch for large WALs, which do not load every record
 // Thread-safe: uses mutex to prevent concurrent reads
 func (w *WAL) ReadAll() ([]WALRecord, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	// Close existing file and reopen in read mode
-	if w.file != nil {
-		w.file.Close()
+	paths, err := w.segmentPaths()
+	if err != nil {
+		return nil, err
 	}
 
-	data, err := os.ReadFile(w.filePath)
+	records := []WALRecord{}
+	it := newWALIterator(paths)
+	defer it.Close()
+	for it.Next() {
+		records = append(records, it.Record())
+	}
+	return records, it.Err()
+}
+
+// Iter returns an iterator over the records of every current segment,
+// oldest first, reading one record at a time. It does not hold the WAL lock,
+// so segments deleted by a concurrent replay are skipped and records written
+// while iterating may or may not be seen. The caller must Close it.
+func (w *WAL) Iter() (*WALIterator, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	paths, err := w.segmentPaths()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []WALRecord{}, nil // No WAL file means no records
+		return nil, err
+	}
+	return newWALIterator(paths), nil
+}
+
+// ReadBatch returns up to n records starting at record offset, counted from
+// the oldest segment, streaming past the skipped ones. Offsets shift when a
+// replay deletes segments, so page through a WAL that is not being replayed.
+func (w *WAL) ReadBatch(offset, n int) ([]WALRecord, error) {
+	it, err := w.Iter()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	records := []WALRecord{}
+	for i := 0; len(records) < n && it.Next(); i++ {
+		if i >= offset {
+			records = append(records, it.Record())
 		}
-		return nil, fmt.Errorf("failed to read WAL file: %w", err)
+	}
+	return records, it.Err()
+}
+
+// ReplaySegments hands the records of each sealed segment to fn in batches
+// of at most batchSize, oldest first, deleting every segment whose batches
+// fn all accept. Segments are streamed, so memory stays bounded by the batch
+// size however large the WAL grew. The active segment is sealed first, so
+// records written during the replay land in a new segment and are left for
+// the next replay.
+//
+// Every accepted batch advances a replay cursor persisted next to the WAL,
+// so when fn fails midway the next replay resumes after the last accepted
+// batch instead of handing the segment's earlier records over again. Replay
+// stops at the first error, keeping the failed segment and later ones.
+// The records slice is reused for the next batch, so fn must not keep it.
+// Records written meanwhile go to the new active segment and wait for the
+// next replay; a second replay or a Clear fails with ErrReplayInProgress
+// until this one returns. Returns the number of records accepted.
+func (w *WAL) ReplaySegments(batchSize int, fn func(records []WALRecord) error) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 1000
 	}
 
-	// Reopen file in append mode for future writes
-	w.file, err = os.OpenFile(w.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	w.mu.Lock()
+	if w.replaying {
+		w.mu.Unlock()
+		return 0, ErrReplayInProgress
+	}
+	if w.activeSize > 0 {
+		if err := w.rotate(); err != nil {
+			w.mu.Unlock()
+			return 0, err
+		}
+	}
+	seqs, err := w.segmentSeqs()
+	active := w.activeSeq
+	w.replaying = err == nil
+	w.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		w.mu.Lock()
+		w.replaying = false
+		w.mu.Unlock()
+	}()
+	cursor, err := w.loadReplayCursor()
 	if err != nil {
-		return nil, fmt.Errorf("failed to reopen WAL file: %w", err)
+		return 0, err
 	}
 
-	// Parse each line as a JSON record
-	var records []WALRecord
-	lines := splitLines(data)
-	for _, line := range lines {
-		if len(line) == 0 {
-			continue
+	replayed := 0
+	for _, seq := range seqs {
+		if seq >= active {
+			break
+		}
+		skip := 0
+		if cursor.Segment == seq {
+			skip = cursor.Records
+		}
+		count, err := replaySegment(w.segmentFile(seq), batchSize, skip, fn, func(done int) error {
+			return w.saveReplayCursor(replayCursor{Segment: seq, Records: done})
+		})
+		replayed += count
+		if err != nil {
+			return replayed, err
 		}
+		if err := w.removeSegment(seq); err != nil {
+			return replayed, fmt.Errorf("failed to delete replayed WAL segment: %w", err)
+		}
+		if err := w.removeReplayCursor(); err != nil {
+			return replayed, err
+		}
+	}
 
-		var record WALRecord
-		if err := json.Unmarshal(line, &record); err != nil {
-			// Log error but continue parsing other records
-			fmt.Printf("Warning: failed to parse WAL record: %v\n", err)
+	// Once nothing is left to replay the standby's copy is obsolete
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.replicator != nil && w.activeSize == 0 {
+		w.replicator.Truncate()
+	}
+	return replayed, nil
+}
+
+// replaySegment streams one segment to fn in batches, skipping the first
+// skip records, and calls ack with the number of records of the segment
+// accepted so far after every batch. Returns the number of records replayed.
+func replaySegment(path string, batchSize, skip int, fn func(records []WALRecord) error, ack func(done int) error) (int, error) {
+	it := newWALIterator([]string{path})
+	defer it.Close()
+
+	count := 0
+	batch := getWALRecords(batchSize)
+	defer func() { putWALRecords(batch) }()
+	deliver := func() error {
+		if err := fn(batch); err != nil {
+			return err
+		}
+		count += len(batch)
+		clear(batch)
+		batch = batch[:0]
+		if err := ack(skip + count); err != nil {
+			return fmt.Errorf("failed to save WAL replay cursor: %w", err)
+		}
+		return nil
+	}
+
+	for seen := 0; it.Next(); seen++ {
+		if seen < skip {
+			continue // accepted by an earlier replay
+		}
+		batch = append(batch, it.Record())
+		if len(batch) < batchSize {
 			continue
 		}
-		records = append(records, record)
+		if err := deliver(); err != nil {
+			return count, err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return count, err
 	}
+	if len(batch) > 0 {
+		if err := deliver(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
 
-	return records, nil
+// replayCursor records how many records of a sealed segment were accepted
+// by a replay that did not finish the segment
+type replayCursor struct {
+	Segment int `json:"segment"`
+	Records int `json:"records"`
 }
 
-// Clear removes all records from the WAL by truncating the file
+// cursorPath returns the replay cursor file, next to the configured WAL path
+func (w *WAL) cursorPath() string {
+	return w.legacyPath + ".cursor"
+}
+
+// loadReplayCursor reads the replay cursor; a missing file means no
+// partially replayed segment (Segment -1)
+func (w *WAL) loadReplayCursor() (replayCursor, error) {
+	cursor := replayCursor{Segment: -1}
+	data, err := os.ReadFile(w.cursorPath())
+	if os.IsNotExist(err) {
+		return cursor, nil
+	}
+	if err != nil {
+		return cursor, fmt.Errorf("failed to read WAL replay cursor: %w", err)
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return replayCursor{Segment: -1}, fmt.Errorf("failed to parse WAL replay cursor: %w", err)
+	}
+	return cursor, nil
+}
+
+// saveReplayCursor durably replaces the replay cursor
+func (w *WAL) saveReplayCursor(cursor replayCursor) error {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+	tmp := w.cursorPath() + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.cursorPath())
+}
+
+// removeReplayCursor drops the cursor once its segment is deleted
+func (w *WAL) removeReplayCursor() error {
+	if err := os.Remove(w.cursorPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete WAL replay cursor: %w", err)
+	}
+	return nil
+}
+
+// Clear removes all records from the WAL by deleting every segment
 // This should be called after successfully replaying all records to the database
-// Thread-safe: uses mutex to prevent concurrent operations
+// Thread-safe: uses mutex to prevent concurrent operations, and fails with
+// ErrReplayInProgress while ReplaySegments runs
 func (w *WAL) Clear() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	if w.replaying {
+		return ErrReplayInProgress
+	}
 
-	// Close existing file
+	// Close the active segment
 	if w.file != nil {
 		w.file.Close()
 	}
 
-	// Truncate file
-	if err := os.Truncate(w.filePath, 0); err != nil {
-		return fmt.Errorf("failed to truncate WAL file: %w", err)
+	seqs, err := w.segmentSeqs()
+	if err != nil {
+		return err
+	}
+	for _, seq := range seqs {
+		if err := w.removeSegment(seq); err != nil {
+			return fmt.Errorf("failed to delete WAL segment: %w", err)
+		}
 	}
 
-	// Reopen file in append mode
-	file, err := os.OpenFile(w.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
+	if err := w.removeReplayCursor(); err != nil {
+		return err
+	}
+
+	// Start over with a fresh segment
+	if err := w.openSegment(w.activeSeq + 1); err != nil {
 		return fmt.Errorf("failed to reopen WAL file after clear: %w", err)
 	}
 
-	w.file = file
+	if w.replicator != nil {
+		w.replicator.Truncate()
+	}
 	return nil
 }
 
-// Size returns the current WAL file size in bytes
-// This can be used to monitor WAL growth and trigger rotation if needed
+// Size returns the total size of all WAL segments in bytes, as stored on
+// disk (compressed segments count with their compressed size)
 func (w *WAL) Size() int64 {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	info, err := os.Stat(w.filePath)
+	seqs, err := w.segmentSeqs()
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, seq := range seqs {
+		for _, path := range w.segmentVariants(seq) {
+			if info, err := os.Stat(path); err == nil {
+				total += info.Size()
+			}
+		}
+	}
+	return total
+}
+
+// SegmentCount returns the number of segment files, including the active one
+func (w *WAL) SegmentCount() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	seqs, err := w.segmentSeqs()
 	if err != nil {
 		return 0
 	}
-	return info.Size()
+	return len(seqs)
 }
 
 // Count returns the number of records in the WAL
-// This is a convenience method that calls ReadAll and counts the records
-// For better performance with large WALs, consider maintaining an in-memory counter
+// Records are streamed rather than loaded, but every segment is still read;
+// for frequent calls on large WALs, consider maintaining an in-memory counter
 func (w *WAL) Count() (int, error) {
-	records, err := w.ReadAll()
+	it, err := w.Iter()
 	if err != nil {
 		return 0, err
 	}
-	return len(records), nil
+	defer it.Close()
+
+	count := 0
+	for it.Next() {
+		count++
+	}
+	return count, it.Err()
 }
 
-// Close closes the WAL file
+// Close closes the active WAL segment once sealed segments are compressed
 // This should be called when shutting down the service
 func (w *WAL) Close() error {
+	w.compressing.Wait()
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -192,23 +579,258 @@ func (w *WAL) Close() error {
 	return nil
 }
 
-// splitLines splits byte data into lines
-// This is a helper function for ReadAll
-func splitLines(data []byte) [][]byte {
-	var lines [][]byte
-	start := 0
+// segmentPath returns the file path of segment seq
+func (w *WAL) segmentPath(seq int) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%s%06d%s", w.prefix, seq, w.ext))
+}
+
+// segmentPaths lists existing segment files, oldest first
+// Caller must hold mu
+func (w *WAL) segmentPaths() ([]string, error) {
+	seqs, err := w.segmentSeqs()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(seqs))
+	for i, seq := range seqs {
+		paths[i] = w.segmentFile(seq)
+	}
+	return paths, nil
+}
 
-	for i, b := range data {
-		if b == '\n' {
-			lines = append(lines, data[start:i])
-			start = i + 1
+// segmentSeqs lists existing segment sequence numbers in ascending order
+func (w *WAL) segmentSeqs() ([]int, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list WAL segments: %w", err)
+	}
+	var seqs []int
+	seen := make(map[int]bool)
+	for _, entry := range entries {
+		name := trimCompressedExt(entry.Name())
+		if entry.IsDir() || !strings.HasPrefix(name, w.prefix) || !strings.HasSuffix(name, w.ext) {
+			continue
 		}
+		seq, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, w.prefix), w.ext))
+		if err != nil || seq < 0 || seen[seq] {
+			continue
+		}
+		seen[seq] = true
+		seqs = append(seqs, seq)
 	}
+	sort.Ints(seqs)
+	return seqs, nil
+}
 
-	// Add last line if it doesn't end with newline
-	if start < len(data) {
-		lines = append(lines, data[start:])
+// openSegment opens (or creates) segment seq for appending and makes it active
+// Caller must hold mu (or own w exclusively)
+func (w *WAL) openSegment(seq int) error {
+	path := w.segmentPath(seq)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open WAL file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat WAL file: %w", err)
 	}
 
-	return lines
+	w.file = file
+	w.activeSeq = seq
+	w.activeSize = info.Size()
+	w.activeCreated = time.Now()
+	if info.Size() > 0 {
+		// Reopened segment: age it from its last write
+		w.activeCreated = info.ModTime()
+	}
+	return nil
+}
+
+// shouldRotate reports whether the active segment is due to be sealed
+// Caller must hold mu
+func (w *WAL) shouldRotate() bool {
+	if w.activeSize == 0 {
+		return false
+	}
+	if w.maxSegmentSize > 0 && w.activeSize >= w.maxSegmentSize {
+		return true
+	}
+	return w.maxSegmentAge > 0 && time.Since(w.activeCreated) >= w.maxSegmentAge
+}
+
+// rotate seals the active segment, compressing it if configured, and starts
+// the next one
+// Caller must hold mu
+func (w *WAL) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("failed to seal WAL segment: %w", err)
+		}
+	}
+	sealed := w.activeSeq
+	if err := w.openSegment(sealed + 1); err != nil {
+		return err
+	}
+	if w.compression == WALCompressionNone {
+		return nil
+	}
+	// Compressing a full segment takes a while; appends must not wait
+	w.compressing.Add(1)
+	go func(codec string) {
+		defer w.compressing.Done()
+		if err := w.compressSegment(sealed, codec); err != nil {
+			// The plain segment is kept and replayed as is
+			log.Printf("WAL: failed to compress segment %d: %v", sealed, err)
+		}
+	}(w.compression)
+	return nil
+}
+
+// migrateLegacyFile keeps records of a single-file WAL written by an older
+// version by renaming it to segment 0, the first one replayed
+func (w *WAL) migrateLegacyFile() error {
+	info, err := os.Stat(w.legacyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat WAL file: %w", err)
+	}
+	if info.Size() == 0 {
+		return os.Remove(w.legacyPath)
+	}
+	if _, err := os.Stat(w.segmentPath(0)); err == nil {
+		return fmt.Errorf("cannot migrate WAL file %s: segment %s already exists", w.legacyPath, w.segmentPath(0))
+	}
+	if err := os.Rename(w.legacyPath, w.segmentPath(0)); err != nil {
+		return fmt.Errorf("failed to migrate WAL file to segments: %w", err)
+	}
+	return nil
+}
+
+// maxWALRecordSize bounds a single WAL line; records are far smaller
+const maxWALRecordSize = 1024 * 1024
+
+// WALIterator streams WAL records from a list of segment files, oldest
+// first, holding one line in memory at a time
+//
+//	it, err := wal.Iter()
+//	...
+//	defer it.Close()
+//	for it.Next() {
+//		record := it.Record()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type WALIterator struct {
+	paths   []string
+	file        *os.File
+	closeReader func() // releases the decompressor, if any
+	scanner     *bufio.Scanner
+	record      WALRecord
+	err         error
+}
+
+// newWALIterator creates an iterator over the given segment files
+func newWALIterator(paths []string) *WALIterator {
+	return &WALIterator{paths: paths}
+}
+
+// Next advances to the next record, returning false at the end of the last
+// segment or on a read error. Lines that fail to parse are skipped.
+func (it *WALIterator) Next() bool {
+	for it.err == nil {
+		if it.scanner == nil {
+			if len(it.paths) == 0 {
+				return false
+			}
+			it.open(it.paths[0])
+			it.paths = it.paths[1:]
+			continue
+		}
+
+		if !it.scanner.Scan() {
+			if err := it.scanner.Err(); err != nil {
+				it.err = fmt.Errorf("failed to read WAL file %s: %w", it.file.Name(), err)
+			}
+			it.closeFile()
+			continue
+		}
+		line := it.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var record WALRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			// Log error but continue parsing other records
+			fmt.Printf("Warning: failed to parse WAL record: %v\n", err)
+			continue
+		}
+		it.record = record
+		return true
+	}
+	return false
+}
+
+// Record returns the record Next advanced to
+func (it *WALIterator) Record() WALRecord {
+	return it.record
+}
+
+// Err returns the first read error, if any
+func (it *WALIterator) Err() error {
+	return it.err
+}
+
+// Close releases the open segment file; it is safe to call more than once
+func (it *WALIterator) Close() error {
+	it.paths = nil
+	return it.closeFile()
+}
+
+// open starts scanning a segment, decompressing it if needed; a plain
+// segment compressed concurrently is read from its compressed copy, and a
+// segment deleted concurrently is skipped
+func (it *WALIterator) open(path string) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) && trimCompressedExt(path) == path {
+		for _, ext := range []string{walCompressedExts[WALCompressionGzip], walCompressedExts[WALCompressionZstd]} {
+			if file, err = os.Open(path + ext); !os.IsNotExist(err) {
+				path += ext
+				break
+			}
+		}
+	}
+	if err != nil {
+		if !os.IsNotExist(err) {
+			it.err = fmt.Errorf("failed to read WAL file: %w", err)
+		}
+		return
+	}
+	reader, closeReader, err := decompressingReader(path, file)
+	if err != nil {
+		file.Close()
+		it.err = fmt.Errorf("failed to read WAL file %s: %w", path, err)
+		return
+	}
+	it.file = file
+	it.closeReader = closeReader
+	it.scanner = bufio.NewScanner(reader)
+	it.scanner.Buffer(make([]byte, 0, 64*1024), maxWALRecordSize)
+}
+
+// closeFile closes the current segment so the next one is opened
+func (it *WALIterator) closeFile() error {
+	it.scanner = nil
+	if it.closeReader != nil {
+		it.closeReader()
+		it.closeReader = nil
+	}
+	if it.file == nil {
+		return nil
+	}
+	err := it.file.Close()
+	it.file = nil
+	return err
 }
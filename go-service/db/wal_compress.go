@@ -0,0 +1,181 @@
+package db
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// WAL segment compression codecs, see SetCompression
+const (
+	WALCompressionNone = ""
+	WALCompressionGzip = "gzip"
+	WALCompressionZstd = "zstd"
+)
+
+// walCompressedExts maps each codec to the suffix added to sealed segments
+var walCompressedExts = map[string]string{
+	WALCompressionGzip: ".gz",
+	WALCompressionZstd: ".zst",
+}
+
+// walCompressionTmpExt marks a compressed segment still being written
+const walCompressionTmpExt = ".tmp"
+
+// SetCompression compresses segments with codec (gzip or zstd) as they are
+// sealed, in the background; the active segment is always plain. Segments
+// sealed earlier keep their format, and every format is read back. An empty
+// codec disables it.
+func (w *WAL) SetCompression(codec string) error {
+	if _, ok := walCompressedExts[codec]; codec != WALCompressionNone && !ok {
+		return fmt.Errorf("unknown WAL compression %q (use gzip or zstd)", codec)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.compression = codec
+	return nil
+}
+
+// compressSegment replaces sealed segment seq by a copy compressed with
+// codec. The copy is written to a temporary file and renamed, so a crash
+// leaves either the plain or the complete compressed segment. It runs
+// without mu; readers opening the plain segment meanwhile fall back to the
+// compressed copy, and a segment deleted meanwhile is not brought back.
+func (w *WAL) compressSegment(seq int, codec string) error {
+	ext, ok := walCompressedExts[codec]
+	if !ok {
+		return nil
+	}
+	src := w.segmentPath(seq)
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	dst := src + ext
+	tmp := dst + walCompressionTmpExt
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp) // no-op once renamed
+
+	var encoder io.WriteCloser
+	switch codec {
+	case WALCompressionGzip:
+		encoder, err = gzip.NewWriterLevel(out, gzip.BestSpeed)
+	case WALCompressionZstd:
+		encoder, err = zstd.NewWriter(out, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	}
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	written, err := io.Copy(encoder, in)
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	w.segmentMu.Lock()
+	defer w.segmentMu.Unlock()
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		// Replayed or cleared while compressing
+		return nil
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return err
+	}
+	if err := os.Remove(src); err != nil {
+		return err
+	}
+	if info, err := os.Stat(dst); err == nil {
+		log.Printf("WAL: compressed segment %d with %s (%d -> %d bytes)", seq, codec, written, info.Size())
+	}
+	return nil
+}
+
+// segmentVariants returns every path segment seq may be stored under
+func (w *WAL) segmentVariants(seq int) []string {
+	path := w.segmentPath(seq)
+	return []string{path, path + walCompressedExts[WALCompressionGzip], path + walCompressedExts[WALCompressionZstd]}
+}
+
+// segmentFile returns the existing file of segment seq, preferring the plain
+// file (a compressed copy may be incomplete until the plain one is removed)
+func (w *WAL) segmentFile(seq int) string {
+	variants := w.segmentVariants(seq)
+	for _, path := range variants {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return variants[0]
+}
+
+// removeSegment deletes segment seq in every format
+func (w *WAL) removeSegment(seq int) error {
+	w.segmentMu.Lock()
+	defer w.segmentMu.Unlock()
+	for _, path := range w.segmentVariants(seq) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeCompressionLeftovers deletes partial compressed segments left by a
+// crash; their plain segments are still in place
+func (w *WAL) removeCompressionLeftovers() {
+	matches, _ := filepath.Glob(filepath.Join(w.dir, w.prefix+"*"+walCompressionTmpExt))
+	for _, path := range matches {
+		os.Remove(path)
+	}
+}
+
+// trimCompressedExt strips a compressed segment suffix from a file name
+func trimCompressedExt(name string) string {
+	for _, ext := range walCompressedExts {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
+// decompressingReader wraps a segment file in a decompressor matching its
+// suffix. The returned func releases the decompressor.
+func decompressingReader(path string, file *os.File) (io.Reader, func(), error) {
+	switch {
+	case strings.HasSuffix(path, walCompressedExts[WALCompressionGzip]):
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		return reader, func() { reader.Close() }, nil
+	case strings.HasSuffix(path, walCompressedExts[WALCompressionZstd]):
+		reader, err := zstd.NewReader(file, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, nil, err
+		}
+		return reader, reader.Close, nil
+	}
+	return file, nil, nil
+}
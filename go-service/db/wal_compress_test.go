@@ -0,0 +1,160 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWALCompressesSealedSegments tests that sealed segments are compressed
+// with each codec and still read and replayed
+func TestWALCompressesSealedSegments(t *testing.T) {
+	for codec, ext := range walCompressedExts {
+		t.Run(codec, func(t *testing.T) {
+			dir := t.TempDir()
+			wal, err := NewWAL(filepath.Join(dir, "data.wal"))
+			if err != nil {
+				t.Fatalf("failed to create WAL: %v", err)
+			}
+			defer wal.Close()
+			if err := wal.SetCompression(codec); err != nil {
+				t.Fatalf("failed to set compression: %v", err)
+			}
+			wal.SetRotation(1, 0)
+
+			for _, id := range []string{"SAT-001", "SAT-002", "SAT-003"} {
+				if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: id}); err != nil {
+					t.Fatalf("failed to write record: %v", err)
+				}
+			}
+
+			// Segments 1 and 2 are sealed, 3 is active
+			wal.compressing.Wait()
+			for seq, want := range map[int]string{1: ext, 2: ext, 3: ""} {
+				path := filepath.Join(dir, fmt.Sprintf("data-%06d.wal%s", seq, want))
+				if _, err := os.Stat(path); err != nil {
+					t.Errorf("expected segment %d at %s: %v", seq, path, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, "data-000001.wal")); !os.IsNotExist(err) {
+				t.Error("expected plain sealed segment to be removed")
+			}
+
+			records, err := wal.ReadAll()
+			if err != nil {
+				t.Fatalf("failed to read WAL: %v", err)
+			}
+			if len(records) != 3 || records[0].SatelliteID != "SAT-001" || records[2].SatelliteID != "SAT-003" {
+				t.Errorf("expected 3 records in order, got %+v", records)
+			}
+
+			replayed, err := wal.ReplaySegments(1000, func(records []WALRecord) error { return nil })
+			if err != nil || replayed != 3 {
+				t.Fatalf("expected 3 records replayed, got %d (%v)", replayed, err)
+			}
+			if got := wal.SegmentCount(); got != 1 {
+				t.Errorf("expected only the new active segment after replay, got %d", got)
+			}
+		})
+	}
+}
+
+// TestWALReopensAfterCompressedSegment tests that a compressed newest segment
+// is not reused as the active one
+func TestWALReopensAfterCompressedSegment(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "data.wal")
+	wal, err := NewWAL(walPath)
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	if err := wal.SetCompression(WALCompressionGzip); err != nil {
+		t.Fatalf("failed to set compression: %v", err)
+	}
+	if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: "SAT-001"}); err != nil {
+		t.Fatalf("failed to write record: %v", err)
+	}
+	// Seal and compress segment 1, then simulate a crash before segment 2 is written
+	wal.mu.Lock()
+	if err := wal.rotate(); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	wal.mu.Unlock()
+	wal.Close()
+	os.Remove(wal.segmentPath(2))
+
+	reopened, err := NewWAL(walPath)
+	if err != nil {
+		t.Fatalf("failed to reopen WAL: %v", err)
+	}
+	defer reopened.Close()
+	if err := reopened.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: "SAT-002"}); err != nil {
+		t.Fatalf("failed to write record: %v", err)
+	}
+
+	records, err := reopened.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read WAL: %v", err)
+	}
+	if len(records) != 2 || records[0].SatelliteID != "SAT-001" || records[1].SatelliteID != "SAT-002" {
+		t.Errorf("expected both records in order, got %+v", records)
+	}
+}
+
+// TestWALIteratorReadsSegmentCompressedMeanwhile tests that a reader listing
+// a plain segment still reads it after the background compression replaced it
+func TestWALIteratorReadsSegmentCompressedMeanwhile(t *testing.T) {
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	for _, id := range []string{"SAT-001", "SAT-002"} {
+		if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: id}); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+	wal.mu.Lock()
+	if err := wal.rotate(); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	wal.mu.Unlock()
+
+	listed := wal.segmentPath(1)
+	if err := wal.compressSegment(1, WALCompressionZstd); err != nil {
+		t.Fatalf("failed to compress segment: %v", err)
+	}
+	it := newWALIterator([]string{listed})
+	defer it.Close()
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if it.Err() != nil || count != 2 {
+		t.Errorf("expected 2 records from the compressed copy, got %d (%v)", count, it.Err())
+	}
+
+	// A segment deleted while compressing is not brought back
+	if err := wal.removeSegment(1); err != nil {
+		t.Fatalf("failed to remove segment: %v", err)
+	}
+	if err := wal.compressSegment(1, WALCompressionGzip); err == nil {
+		t.Error("expected compressing a deleted segment to fail")
+	}
+	if got := wal.SegmentCount(); got != 1 {
+		t.Errorf("expected only the active segment, got %d", got)
+	}
+}
+
+// TestWALSetCompressionRejectsUnknownCodec tests codec validation
+func TestWALSetCompressionRejectsUnknownCodec(t *testing.T) {
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	if err := wal.SetCompression("lz4"); err == nil {
+		t.Error("expected unknown codec to be rejected")
+	}
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrReplayInProgress is returned when a WAL replay or clear is requested
+// while a replay is running
+var ErrReplayInProgress = errors.New("WAL replay already in progress")
+
+// errReplayStopped ends a paced replay when the health monitor stops
+var errReplayStopped = errors.New("health monitor stopped")
+
+// ReplayStatus describes WAL replays since startup
+type ReplayStatus struct {
+	Running       bool       `json:"running"`
+	LastStarted   *time.Time `json:"last_started,omitempty"`
+	LastFinished  *time.Time `json:"last_finished,omitempty"`
+	LastReplayed  int        `json:"last_replayed"`
+	LastError     string     `json:"last_error,omitempty"`
+	TotalReplayed int64      `json:"total_replayed"`
+}
+
+// WALStatus describes the WAL contents and replay state
+type WALStatus struct {
+	SizeBytes int64 `json:"size_bytes"`
+	Segments  int   `json:"segments"`
+	Records   int   `json:"records"`
+	// Timestamp of the oldest record waiting for replay and its age
+	OldestRecord           *time.Time   `json:"oldest_record,omitempty"`
+	OldestRecordAgeSeconds float64      `json:"oldest_record_age_seconds"`
+	DatabaseHealthy        bool         `json:"database_healthy"`
+	Replay                 ReplayStatus `json:"replay"`
+}
+
+// Oldest returns the first record of the oldest segment, or nil when the WAL
+// is empty
+func (w *WAL) Oldest() (*WALRecord, error) {
+	it, err := w.Iter()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	if !it.Next() {
+		return nil, it.Err()
+	}
+	record := it.Record()
+	return &record, nil
+}
+
+// WALStatus reports the size, record count and oldest record of the WAL and
+// the replay state
+func (hm *HealthMonitor) WALStatus() (WALStatus, error) {
+	status := WALStatus{
+		SizeBytes:       hm.wal.Size(),
+		Segments:        hm.wal.SegmentCount(),
+		DatabaseHealthy: hm.IsHealthy(),
+		Replay:          hm.ReplayStatus(),
+	}
+	count, err := hm.wal.Count()
+	if err != nil {
+		return status, err
+	}
+	status.Records = count
+
+	oldest, err := hm.wal.Oldest()
+	if err != nil {
+		return status, err
+	}
+	if oldest != nil {
+		ts := oldest.Timestamp
+		status.OldestRecord = &ts
+		status.OldestRecordAgeSeconds = time.Since(ts).Seconds()
+	}
+	return status, nil
+}
+
+// ReplayStatus returns the state of the current or last WAL replay
+func (hm *HealthMonitor) ReplayStatus() ReplayStatus {
+	hm.healthMutex.RLock()
+	defer hm.healthMutex.RUnlock()
+	return hm.replay
+}
+
+// Replay replays the WAL to the database now, returning the number of
+// records replayed. Only one replay runs at a time; a concurrent call
+// returns ErrReplayInProgress.
+func (hm *HealthMonitor) Replay() (int, error) {
+	if !hm.replayMutex.TryLock() {
+		return 0, ErrReplayInProgress
+	}
+	defer hm.replayMutex.Unlock()
+
+	started := time.Now().UTC()
+	hm.healthMutex.Lock()
+	hm.replay.Running = true
+	hm.replay.LastStarted = &started
+	hm.healthMutex.Unlock()
+
+	replayed, err := hm.replaySegments()
+
+	finished := time.Now().UTC()
+	hm.healthMutex.Lock()
+	hm.replay.Running = false
+	hm.replay.LastFinished = &finished
+	hm.replay.LastReplayed = replayed
+	hm.replay.TotalReplayed += int64(replayed)
+	hm.replay.LastError = ""
+	if err != nil {
+		hm.replay.LastError = err.Error()
+	}
+	hm.healthMutex.Unlock()
+	return replayed, err
+}
+
+// ClearWAL deletes every WAL record without replaying it, returning the
+// number of records discarded. Fails with ErrReplayInProgress during a replay.
+func (hm *HealthMonitor) ClearWAL() (int, error) {
+	if !hm.replayMutex.TryLock() {
+		return 0, ErrReplayInProgress
+	}
+	defer hm.replayMutex.Unlock()
+
+	count, err := hm.wal.Count()
+	if err != nil {
+		return 0, err
+	}
+	if err := hm.wal.Clear(); err != nil {
+		return 0, fmt.Errorf("failed to clear WAL: %w", err)
+	}
+	return count, nil
+}
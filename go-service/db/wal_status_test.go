@@ -0,0 +1,145 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newStatusTestMonitor(t *testing.T) (*HealthMonitor, *WAL) {
+	t.Helper()
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	t.Cleanup(func() { wal.Close() })
+	return &HealthMonitor{wal: wal}, wal
+}
+
+// TestWALStatusReportsOldestRecord tests the record count and the age of
+// the oldest record
+func TestWALStatusReportsOldestRecord(t *testing.T) {
+	hm, wal := newStatusTestMonitor(t)
+
+	status, err := hm.WALStatus()
+	if err != nil {
+		t.Fatalf("WALStatus failed: %v", err)
+	}
+	if status.Records != 0 || status.OldestRecord != nil {
+		t.Errorf("expected an empty WAL, got %+v", status)
+	}
+
+	oldest := TelemetryPointForTest(80, 1000, -60)
+	oldest.Timestamp = time.Now().Add(-time.Hour)
+	if err := wal.Write(NewWALRecord(oldest)); err != nil {
+		t.Fatalf("WAL write failed: %v", err)
+	}
+	if err := wal.Write(NewWALRecord(TelemetryPointForTest(70, 1000, -60))); err != nil {
+		t.Fatalf("WAL write failed: %v", err)
+	}
+
+	status, err = hm.WALStatus()
+	if err != nil {
+		t.Fatalf("WALStatus failed: %v", err)
+	}
+	if status.Records != 2 || status.SizeBytes == 0 || status.OldestRecord == nil {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if status.OldestRecordAgeSeconds < 3599 || status.OldestRecordAgeSeconds > 3700 {
+		t.Errorf("expected the oldest record about an hour old, got %vs", status.OldestRecordAgeSeconds)
+	}
+}
+
+// TestReplayAndClearExcludeConcurrentReplay tests that a manual replay or
+// clear is refused while a replay is running
+func TestReplayAndClearExcludeConcurrentReplay(t *testing.T) {
+	hm, wal := newStatusTestMonitor(t)
+	if err := wal.Write(NewWALRecord(TelemetryPointForTest(80, 1000, -60))); err != nil {
+		t.Fatalf("WAL write failed: %v", err)
+	}
+
+	hm.replayMutex.Lock()
+	if _, err := hm.Replay(); !errors.Is(err, ErrReplayInProgress) {
+		t.Errorf("expected ErrReplayInProgress from Replay, got %v", err)
+	}
+	if _, err := hm.ClearWAL(); !errors.Is(err, ErrReplayInProgress) {
+		t.Errorf("expected ErrReplayInProgress from ClearWAL, got %v", err)
+	}
+	hm.replayMutex.Unlock()
+
+	cleared, err := hm.ClearWAL()
+	if err != nil || cleared != 1 {
+		t.Fatalf("expected 1 record cleared, got %d (%v)", cleared, err)
+	}
+	if count, _ := wal.Count(); count != 0 {
+		t.Errorf("expected an empty WAL after clear, got %d records", count)
+	}
+
+	// Nothing sealed to replay: the run is still recorded
+	if replayed, err := hm.Replay(); err != nil || replayed != 0 {
+		t.Fatalf("expected an empty replay, got %d (%v)", replayed, err)
+	}
+	status := hm.ReplayStatus()
+	if status.Running || status.LastStarted == nil || status.LastFinished == nil {
+		t.Errorf("unexpected replay status %+v", status)
+	}
+}
+
+// TestWALReplayExcludesConcurrentReplayAndClear tests that while one replay
+// streams segments a second replay and a clear are refused, and that points
+// written concurrently by ingest survive the replay
+func TestWALReplayExcludesConcurrentReplayAndClear(t *testing.T) {
+	_, wal := newStatusTestMonitor(t)
+	for i := 0; i < 3; i++ {
+		if err := wal.Write(NewWALRecord(TelemetryPointForTest(80, 1000, -60))); err != nil {
+			t.Fatalf("WAL write failed: %v", err)
+		}
+	}
+
+	inReplay := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := wal.ReplaySegments(1000, func(records []WALRecord) error {
+			close(inReplay)
+			<-release
+			return nil
+		})
+		done <- err
+	}()
+	<-inReplay
+
+	if _, err := wal.ReplaySegments(1000, func([]WALRecord) error { return nil }); !errors.Is(err, ErrReplayInProgress) {
+		t.Errorf("expected ErrReplayInProgress from a second replay, got %v", err)
+	}
+	if err := wal.Clear(); !errors.Is(err, ErrReplayInProgress) {
+		t.Errorf("expected ErrReplayInProgress from Clear, got %v", err)
+	}
+
+	var writers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for j := 0; j < 25; j++ {
+				if err := wal.Write(NewWALRecord(TelemetryPointForTest(80, 1000, -60))); err != nil {
+					t.Errorf("WAL write during replay failed: %v", err)
+				}
+			}
+		}()
+	}
+	writers.Wait()
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+
+	if count, _ := wal.Count(); count != 100 {
+		t.Errorf("expected the 100 records written during replay to remain, got %d", count)
+	}
+	if err := wal.Clear(); err != nil {
+		t.Errorf("expected Clear to succeed after the replay, got %v", err)
+	}
+}
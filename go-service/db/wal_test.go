@@ -1,6 +1,7 @@
 package db
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -345,9 +346,9 @@ func TestWALNewDirectory(t *testing.T) {
 		t.Error("expected directory to be created")
 	}
 
-	// File should exist
-	if _, err := os.Stat(walPath); os.IsNotExist(err) {
-		t.Error("expected WAL file to be created")
+	// First segment should exist
+	if _, err := os.Stat(filepath.Join(filepath.Dir(walPath), "test-000001.wal")); os.IsNotExist(err) {
+		t.Error("expected WAL segment file to be created")
 	}
 }
 
@@ -1012,3 +1013,354 @@ func TestWALMultipleRecordsWithPositionFields(t *testing.T) {
 		t.Error("third record: expected nil altitude")
 	}
 }
+
+// TestWALRecordSoftwareVersionPersistence tests that the software version survives a WAL round trip
+func TestWALRecordSoftwareVersionPersistence(t *testing.T) {
+	tmpDir := t.TempDir()
+	walPath := filepath.Join(tmpDir, "test.wal")
+
+	wal, err := NewWAL(walPath)
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+
+	version := "2.4.1"
+	records := []WALRecord{
+		{Timestamp: time.Now().UTC(), SatelliteID: "SAT-001", SoftwareVersion: &version},
+		{Timestamp: time.Now().UTC(), SatelliteID: "SAT-002"},
+	}
+	for _, r := range records {
+		if err := wal.Write(r); err != nil {
+			t.Fatalf("failed to write WAL record: %v", err)
+		}
+	}
+
+	read, err := wal.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read WAL: %v", err)
+	}
+	if len(read) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(read))
+	}
+	if read[0].SoftwareVersion == nil || *read[0].SoftwareVersion != version {
+		t.Errorf("expected software version %s, got %v", version, read[0].SoftwareVersion)
+	}
+	if read[1].SoftwareVersion != nil {
+		t.Errorf("expected nil software version, got %v", *read[1].SoftwareVersion)
+	}
+}
+
+// TestWALRotatesBySize tests that a full segment is sealed and a new one started
+func TestWALRotatesBySize(t *testing.T) {
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	wal.SetRotation(1, 0) // every record fills a segment
+
+	for i := 0; i < 3; i++ {
+		if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: "SAT-001"}); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+
+	if got := wal.SegmentCount(); got != 3 {
+		t.Errorf("expected 3 segments, got %d", got)
+	}
+	if count, _ := wal.Count(); count != 3 {
+		t.Errorf("expected 3 records across segments, got %d", count)
+	}
+}
+
+// TestWALRotatesByAge tests that an old segment is sealed on the next write
+func TestWALRotatesByAge(t *testing.T) {
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	wal.SetRotation(0, time.Minute)
+
+	record := WALRecord{Timestamp: time.Now().UTC(), SatelliteID: "SAT-001"}
+	if err := wal.Write(record); err != nil {
+		t.Fatalf("failed to write record: %v", err)
+	}
+	wal.mu.Lock()
+	wal.activeCreated = time.Now().Add(-2 * time.Minute)
+	wal.mu.Unlock()
+	if err := wal.Write(record); err != nil {
+		t.Fatalf("failed to write record: %v", err)
+	}
+
+	if got := wal.SegmentCount(); got != 2 {
+		t.Errorf("expected 2 segments after age rotation, got %d", got)
+	}
+}
+
+// TestWALReplaySegments tests in-order replay, deletion of replayed segments
+// and that records written during replay are kept
+func TestWALReplaySegments(t *testing.T) {
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	wal.SetRotation(1, 0)
+
+	for _, id := range []string{"SAT-001", "SAT-002", "SAT-003"} {
+		if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: id}); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+
+	var order []string
+	replayed, err := wal.ReplaySegments(1000, func(records []WALRecord) error {
+		for _, record := range records {
+			order = append(order, record.SatelliteID)
+		}
+		// A write during replay must survive it
+		return wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: "SAT-LATE"})
+	})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+
+	if replayed != 3 || len(order) != 3 || order[0] != "SAT-001" || order[2] != "SAT-003" {
+		t.Errorf("expected 3 records replayed in order, got %d %v", replayed, order)
+	}
+	records, _ := wal.ReadAll()
+	if len(records) != 3 {
+		t.Fatalf("expected the 3 records written during replay to remain, got %d", len(records))
+	}
+	for _, record := range records {
+		if record.SatelliteID != "SAT-LATE" {
+			t.Errorf("unexpected record left after replay: %s", record.SatelliteID)
+		}
+	}
+}
+
+// TestWALReplaySegmentsStopsOnError tests that failed and later segments are kept
+func TestWALReplaySegmentsStopsOnError(t *testing.T) {
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	wal.SetRotation(1, 0)
+
+	for _, id := range []string{"SAT-001", "SAT-002", "SAT-003"} {
+		if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: id}); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+
+	calls := 0
+	replayed, err := wal.ReplaySegments(1000, func(records []WALRecord) error {
+		calls++
+		if calls == 2 {
+			return os.ErrDeadlineExceeded
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected replay error")
+	}
+	if replayed != 1 {
+		t.Errorf("expected 1 record replayed before the error, got %d", replayed)
+	}
+	if count, _ := wal.Count(); count != 2 {
+		t.Errorf("expected 2 records kept for retry, got %d", count)
+	}
+}
+
+// TestWALMigratesLegacyFile tests that a single-file WAL is kept as the oldest segment
+func TestWALMigratesLegacyFile(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "data.wal")
+	legacy := `{"timestamp":"2024-03-01T12:00:00Z","satellite_id":"SAT-OLD"}` + "\n"
+	if err := os.WriteFile(walPath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("failed to write legacy WAL: %v", err)
+	}
+
+	wal, err := NewWAL(walPath)
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: "SAT-NEW"}); err != nil {
+		t.Fatalf("failed to write record: %v", err)
+	}
+
+	records, err := wal.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read WAL: %v", err)
+	}
+	if len(records) != 2 || records[0].SatelliteID != "SAT-OLD" || records[1].SatelliteID != "SAT-NEW" {
+		t.Errorf("expected legacy record first, got %+v", records)
+	}
+	if _, err := os.Stat(walPath); !os.IsNotExist(err) {
+		t.Error("expected legacy WAL file to be migrated")
+	}
+}
+
+// TestWALIter tests streaming records across segments in order
+func TestWALIter(t *testing.T) {
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	wal.SetRotation(200, 0)
+
+	for i := 0; i < 10; i++ {
+		if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: fmt.Sprintf("SAT-%03d", i)}); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+	if wal.SegmentCount() < 2 {
+		t.Fatalf("expected records spread over several segments, got %d", wal.SegmentCount())
+	}
+
+	it, err := wal.Iter()
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	defer it.Close()
+	i := 0
+	for it.Next() {
+		if want := fmt.Sprintf("SAT-%03d", i); it.Record().SatelliteID != want {
+			t.Errorf("record %d: expected %s, got %s", i, want, it.Record().SatelliteID)
+		}
+		i++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if i != 10 {
+		t.Errorf("expected 10 records, got %d", i)
+	}
+}
+
+// TestWALReadBatch tests paging through records by offset
+func TestWALReadBatch(t *testing.T) {
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	wal.SetRotation(200, 0)
+
+	for i := 0; i < 7; i++ {
+		if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: fmt.Sprintf("SAT-%03d", i)}); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+
+	var pages [][]WALRecord
+	for offset := 0; ; offset += 3 {
+		page, err := wal.ReadBatch(offset, 3)
+		if err != nil {
+			t.Fatalf("failed to read batch at %d: %v", offset, err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		pages = append(pages, page)
+	}
+
+	if len(pages) != 3 || len(pages[2]) != 1 {
+		t.Fatalf("expected pages of 3, 3 and 1 records, got %d pages", len(pages))
+	}
+	if pages[1][0].SatelliteID != "SAT-003" || pages[2][0].SatelliteID != "SAT-006" {
+		t.Errorf("unexpected page contents: %+v", pages)
+	}
+}
+
+// TestWALReplaySegmentsBatches tests that a segment is handed over in bounded batches
+func TestWALReplaySegmentsBatches(t *testing.T) {
+	wal, err := NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: "SAT-001"}); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+
+	var sizes []int
+	replayed, err := wal.ReplaySegments(2, func(records []WALRecord) error {
+		sizes = append(sizes, len(records))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if replayed != 5 || len(sizes) != 3 || sizes[0] != 2 || sizes[2] != 1 {
+		t.Errorf("expected batches of 2, 2 and 1, got %v (%d replayed)", sizes, replayed)
+	}
+	if count, _ := wal.Count(); count != 0 {
+		t.Errorf("expected WAL to be empty after replay, got %d", count)
+	}
+}
+
+// TestWALReplayResumesAfterAcceptedBatches tests that a replay failing midway
+// does not hand accepted batches over again, even after a restart
+func TestWALReplayResumesAfterAcceptedBatches(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "data.wal")
+	wal, err := NewWAL(walPath)
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := wal.Write(WALRecord{Timestamp: time.Now().UTC(), SatelliteID: fmt.Sprintf("SAT-%03d", i)}); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+
+	var inserted []string
+	failOn := 2
+	calls := 0
+	insert := func(records []WALRecord) error {
+		calls++
+		if calls == failOn {
+			return os.ErrDeadlineExceeded
+		}
+		for _, record := range records {
+			inserted = append(inserted, record.SatelliteID)
+		}
+		return nil
+	}
+
+	replayed, err := wal.ReplaySegments(2, insert)
+	if err == nil || replayed != 2 {
+		t.Fatalf("expected failure after 2 records, got %d (%v)", replayed, err)
+	}
+	if _, err := os.Stat(walPath + ".cursor"); err != nil {
+		t.Fatalf("expected replay cursor to be persisted: %v", err)
+	}
+	wal.Close()
+
+	// Resume in a new process
+	reopened, err := NewWAL(walPath)
+	if err != nil {
+		t.Fatalf("failed to reopen WAL: %v", err)
+	}
+	defer reopened.Close()
+	failOn = 0
+	replayed, err = reopened.ReplaySegments(2, insert)
+	if err != nil || replayed != 3 {
+		t.Fatalf("expected the remaining 3 records replayed, got %d (%v)", replayed, err)
+	}
+
+	want := []string{"SAT-000", "SAT-001", "SAT-002", "SAT-003", "SAT-004"}
+	if fmt.Sprint(inserted) != fmt.Sprint(want) {
+		t.Errorf("expected each record inserted once in order, got %v", inserted)
+	}
+	if _, err := os.Stat(walPath + ".cursor"); !os.IsNotExist(err) {
+		t.Error("expected replay cursor to be removed with its segment")
+	}
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxVerifyMissing bounds the missing records listed in a verification
+const maxVerifyMissing = 20
+
+// WALVerification reports how many sampled WAL records already have a
+// committed row in the database, i.e. how much of the WAL is redundant
+type WALVerification struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Records   int       `json:"records"`
+	Sampled   int       `json:"sampled"`
+	Committed int       `json:"committed"`
+	Missing   int       `json:"missing"`
+	// RedundantRatio is Committed / Sampled; EstimatedRedundant extrapolates
+	// it to every WAL record
+	RedundantRatio     float64 `json:"redundant_ratio"`
+	EstimatedRedundant int     `json:"estimated_redundant"`
+	// MissingRecords lists sampled records without a row (up to 20); clearing
+	// the WAL would lose them
+	MissingRecords []WALRecord `json:"missing_records"`
+}
+
+// walRecordKey identifies the row a WAL record inserts, matching the
+// (satellite_id, time) unique index at the database's microsecond precision
+type walRecordKey struct {
+	satelliteID string
+	micros      int64
+}
+
+func keyOf(r WALRecord) walRecordKey {
+	return walRecordKey{satelliteID: r.SatelliteID, micros: r.Timestamp.UnixMicro()}
+}
+
+// committedLookup returns the keys of records that have a committed row
+type committedLookup func(ctx context.Context, records []WALRecord) (map[walRecordKey]bool, error)
+
+// VerifyWAL samples up to sampleSize WAL records uniformly and checks which
+// already have a row (by satellite and timestamp), so operators can tell
+// whether a stuck WAL is safe to clear. Fails with ErrReplayInProgress while
+// a replay changes the WAL.
+func (hm *HealthMonitor) VerifyWAL(ctx context.Context, sampleSize int) (WALVerification, error) {
+	return hm.verifyWAL(ctx, sampleSize, func(ctx context.Context, records []WALRecord) (map[walRecordKey]bool, error) {
+		return committedRecords(ctx, hm.pool, records)
+	})
+}
+
+func (hm *HealthMonitor) verifyWAL(ctx context.Context, sampleSize int, lookup committedLookup) (WALVerification, error) {
+	if !hm.replayMutex.TryLock() {
+		return WALVerification{}, ErrReplayInProgress
+	}
+	defer hm.replayMutex.Unlock()
+
+	verification := WALVerification{CheckedAt: time.Now().UTC(), MissingRecords: make([]WALRecord, 0)}
+	sample, total, err := sampleWAL(hm.wal, sampleSize, rand.New(rand.NewSource(time.Now().UnixNano())))
+	if err != nil {
+		return verification, err
+	}
+	verification.Records = total
+	verification.Sampled = len(sample)
+	if len(sample) == 0 {
+		return verification, nil
+	}
+
+	committed, err := lookup(ctx, sample)
+	if err != nil {
+		return verification, fmt.Errorf("failed to look up WAL records: %w", err)
+	}
+	for _, record := range sample {
+		if committed[keyOf(record)] {
+			verification.Committed++
+			continue
+		}
+		verification.Missing++
+		if len(verification.MissingRecords) < maxVerifyMissing {
+			verification.MissingRecords = append(verification.MissingRecords, record)
+		}
+	}
+	verification.RedundantRatio = float64(verification.Committed) / float64(verification.Sampled)
+	verification.EstimatedRedundant = int(verification.RedundantRatio*float64(total) + 0.5)
+	return verification, nil
+}
+
+// sampleWAL draws up to n records uniformly from the WAL in one pass
+// (reservoir sampling) and returns them with the total record count
+func sampleWAL(w *WAL, n int, rng *rand.Rand) ([]WALRecord, int, error) {
+	it, err := w.Iter()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer it.Close()
+
+	sample := make([]WALRecord, 0, n)
+	total := 0
+	for it.Next() {
+		total++
+		if len(sample) < n {
+			sample = append(sample, it.Record())
+		} else if j := rng.Intn(total); j < n {
+			sample[j] = it.Record()
+		}
+	}
+	return sample, total, it.Err()
+}
+
+// committedRecords returns the keys of records with a telemetry row
+func committedRecords(ctx context.Context, pool *pgxpool.Pool, records []WALRecord) (map[walRecordKey]bool, error) {
+	ids := make([]string, len(records))
+	times := make([]time.Time, len(records))
+	for i, record := range records {
+		ids[i] = record.SatelliteID
+		times[i] = record.Timestamp
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT t.satellite_id, t.time
+		FROM telemetry t
+		JOIN unnest($1::text[], $2::timestamptz[]) AS w(satellite_id, time)
+			ON t.satellite_id = w.satellite_id AND t.time = w.time
+	`, ids, times)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	committed := make(map[walRecordKey]bool, len(records))
+	for rows.Next() {
+		var record WALRecord
+		if err := rows.Scan(&record.SatelliteID, &record.Timestamp); err != nil {
+			return nil, err
+		}
+		committed[keyOf(record)] = true
+	}
+	return committed, rows.Err()
+}
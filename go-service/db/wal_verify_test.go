@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// writeVerifyRecords writes n records for SAT-<i> one second apart
+func writeVerifyRecords(t *testing.T, wal *WAL, n int) []WALRecord {
+	t.Helper()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := make([]WALRecord, n)
+	for i := range records {
+		point := TelemetryPointForTest(80, 1000, -60)
+		point.SatelliteID = fmt.Sprintf("SAT-%03d", i)
+		point.Timestamp = base.Add(time.Duration(i) * time.Second)
+		records[i] = NewWALRecord(point)
+		if err := wal.Write(records[i]); err != nil {
+			t.Fatalf("WAL write failed: %v", err)
+		}
+	}
+	return records
+}
+
+// TestSampleWAL tests that reservoir sampling returns distinct records and the total
+func TestSampleWAL(t *testing.T) {
+	_, wal := newStatusTestMonitor(t)
+	writeVerifyRecords(t, wal, 50)
+
+	sample, total, err := sampleWAL(wal, 10, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("sampleWAL failed: %v", err)
+	}
+	if total != 50 || len(sample) != 10 {
+		t.Fatalf("expected 10 of 50 records, got %d of %d", len(sample), total)
+	}
+	seen := make(map[walRecordKey]bool)
+	for _, record := range sample {
+		if seen[keyOf(record)] {
+			t.Errorf("record %s sampled twice", record.SatelliteID)
+		}
+		seen[keyOf(record)] = true
+	}
+
+	sample, total, err = sampleWAL(wal, 100, rand.New(rand.NewSource(1)))
+	if err != nil || total != 50 || len(sample) != 50 {
+		t.Errorf("expected every record when the sample exceeds the WAL, got %d of %d (%v)", len(sample), total, err)
+	}
+}
+
+// TestVerifyWALReportsRedundantRecords tests the committed/missing counts and the estimate
+func TestVerifyWALReportsRedundantRecords(t *testing.T) {
+	hm, wal := newStatusTestMonitor(t)
+	records := writeVerifyRecords(t, wal, 4)
+
+	// The first three records reached the database before the WAL got stuck
+	lookup := func(ctx context.Context, sample []WALRecord) (map[walRecordKey]bool, error) {
+		return map[walRecordKey]bool{
+			keyOf(records[0]): true,
+			keyOf(records[1]): true,
+			keyOf(records[2]): true,
+		}, nil
+	}
+	verification, err := hm.verifyWAL(context.Background(), 10, lookup)
+	if err != nil {
+		t.Fatalf("verifyWAL failed: %v", err)
+	}
+	if verification.Records != 4 || verification.Sampled != 4 || verification.Committed != 3 || verification.Missing != 1 {
+		t.Errorf("unexpected verification: %+v", verification)
+	}
+	if verification.RedundantRatio != 0.75 || verification.EstimatedRedundant != 3 {
+		t.Errorf("expected 75%% redundant (3 records), got %v (%d)", verification.RedundantRatio, verification.EstimatedRedundant)
+	}
+	if len(verification.MissingRecords) != 1 || verification.MissingRecords[0].SatelliteID != "SAT-003" {
+		t.Errorf("expected SAT-003 missing, got %+v", verification.MissingRecords)
+	}
+}
+
+// TestVerifyWALDuringReplay tests that verification is refused while a replay runs
+func TestVerifyWALDuringReplay(t *testing.T) {
+	hm, _ := newStatusTestMonitor(t)
+	hm.replayMutex.Lock()
+	defer hm.replayMutex.Unlock()
+
+	_, err := hm.verifyWAL(context.Background(), 10, nil)
+	if !errors.Is(err, ErrReplayInProgress) {
+		t.Errorf("expected ErrReplayInProgress, got %v", err)
+	}
+}
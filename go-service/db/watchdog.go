@@ -0,0 +1,328 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+)
+
+// LossOfSignalDetectorName identifies missing-telemetry findings in alerts
+const LossOfSignalDetectorName = "loss_of_signal"
+
+// watchdogSeedLookback is how far back the first check looks for satellites,
+// so a restart doesn't forget the ones already silent
+const watchdogSeedLookback = 7 * 24 * time.Hour
+
+// LastContactQuerier returns the latest receipt time of stored telemetry per
+// satellite, for points timestamped since the given time
+// This allows for mocking in tests
+type LastContactQuerier interface {
+	LastContacts(ctx context.Context, since time.Time) (map[string]time.Time, error)
+}
+
+// LastContacts returns when each satellite's latest point since the given
+// time was received
+func (qs *QueryService) LastContacts(ctx context.Context, since time.Time) (map[string]time.Time, error) {
+	rows, err := qs.query(ctx, `
+		SELECT satellite_id, COALESCE(MAX(received_at), MAX(time))
+		FROM telemetry
+		WHERE time >= $1
+		GROUP BY satellite_id
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := make(map[string]time.Time)
+	for rows.Next() {
+		var satelliteID string
+		var last time.Time
+		if err := rows.Scan(&satelliteID, &last); err != nil {
+			return nil, err
+		}
+		contacts[satelliteID] = last
+	}
+	return contacts, rows.Err()
+}
+
+// SilenceWindow is the longest a group of satellites may go without
+// telemetry before it is reported, e.g. longer for satellites only heard
+// during ground station passes
+type SilenceWindow struct {
+	// Pattern matches satellite IDs (path.Match syntax)
+	Pattern string
+	Window  time.Duration
+}
+
+// WatchdogConfig configures last-contact monitoring
+type WatchdogConfig struct {
+	// DefaultWindow applies to satellites no pattern of Windows matches
+	DefaultWindow time.Duration
+	// Windows are checked in order; the first matching pattern wins
+	Windows []SilenceWindow
+	// Interval between checks
+	Interval time.Duration
+}
+
+// SatelliteContact is a satellite's last contact and how long it has been
+// silent
+type SatelliteContact struct {
+	SatelliteID    string    `json:"satellite_id"`
+	LastContact    time.Time `json:"last_contact"`
+	SilenceSeconds float64   `json:"silence_seconds"`
+	WindowSeconds  float64   `json:"window_seconds"`
+	Alerted        bool      `json:"alerted"`
+}
+
+// ContactWatchdog tracks when each satellite was last heard from and alerts
+// when one stays silent longer than its expected window: loss of signal
+// shows up as an absence of data, which no per-point detector can see.
+//
+// Contacts come from the points ingested by this replica and, at every
+// check, from the database, so points ingested by other replicas count
+// and a restart doesn't forget satellites that already went silent. A
+// satellite is alerted once per silence and a recovery is sent when its
+// telemetry resumes.
+type ContactWatchdog struct {
+	querier LastContactQuerier
+	sink    AnomalyAlertSink
+	cfg     WatchdogConfig
+	now     func() time.Time
+
+	mu        sync.Mutex
+	contacts  map[string]time.Time
+	alerted   map[string]bool
+	lastCheck time.Time
+	// Only the leader replica checks and alerts (nil = always)
+	leadership Leadership
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewContactWatchdog creates a watchdog; querier and sink may be nil (only
+// ingested points are tracked, findings are only logged)
+func NewContactWatchdog(querier LastContactQuerier, sink AnomalyAlertSink, cfg WatchdogConfig) *ContactWatchdog {
+	if cfg.DefaultWindow <= 0 {
+		cfg.DefaultWindow = 30 * time.Minute
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	return &ContactWatchdog{
+		querier:  querier,
+		sink:     sink,
+		cfg:      cfg,
+		now:      time.Now,
+		contacts: make(map[string]time.Time),
+		alerted:  make(map[string]bool),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// SetLeadership makes the watchdog check only while this replica is the
+// leader, so replicas don't send the same alerts; must be called before Start
+func (w *ContactWatchdog) SetLeadership(leadership Leadership) {
+	w.leadership = leadership
+}
+
+// SetContactWatchdog records the receipt of every accepted point
+func (bp *BatchProcessor) SetContactWatchdog(watchdog *ContactWatchdog) {
+	bp.watchdog = watchdog
+}
+
+// observe records a contact with the point's satellite
+func (w *ContactWatchdog) observe(satelliteID string, received time.Time) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if received.After(w.contacts[satelliteID]) {
+		w.contacts[satelliteID] = received
+	}
+}
+
+// Start runs a check immediately and then every interval until Stop
+func (w *ContactWatchdog) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			if w.leadership == nil || w.leadership.IsLeader() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				if _, err := w.Check(ctx); err != nil {
+					log.Printf("ContactWatchdog: check failed: %v", err)
+				}
+				cancel()
+			}
+
+			select {
+			case <-ticker.C:
+			case <-w.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the check loop
+func (w *ContactWatchdog) Stop() {
+	close(w.stopCh)
+	w.wg.Wait()
+}
+
+// Window returns the silence window of a satellite
+func (w *ContactWatchdog) Window(satelliteID string) time.Duration {
+	for _, window := range w.cfg.Windows {
+		if ok, _ := path.Match(window.Pattern, satelliteID); ok {
+			return window.Window
+		}
+	}
+	return w.cfg.DefaultWindow
+}
+
+// longestWindow is the longest silence window of any satellite
+func (w *ContactWatchdog) longestWindow() time.Duration {
+	longest := w.cfg.DefaultWindow
+	for _, window := range w.cfg.Windows {
+		if window.Window > longest {
+			longest = window.Window
+		}
+	}
+	return longest
+}
+
+// Check merges the contacts stored in the database, alerts for satellites
+// newly past their silence window and sends recoveries for alerted ones
+// heard from again. It returns the satellites past their window.
+func (w *ContactWatchdog) Check(ctx context.Context) ([]SatelliteContact, error) {
+	now := w.now()
+	if w.querier != nil {
+		// After the first check only recent points can move a contact
+		since := now.Add(-watchdogSeedLookback)
+		w.mu.Lock()
+		if !w.lastCheck.IsZero() {
+			since = w.lastCheck.Add(-w.longestWindow() - w.cfg.Interval)
+		}
+		w.mu.Unlock()
+
+		stored, err := w.querier.LastContacts(ctx, since)
+		if err != nil {
+			return nil, err
+		}
+		for satelliteID, last := range stored {
+			w.observe(satelliteID, last)
+		}
+	}
+
+	var stale []SatelliteContact
+	var toAlert []models.AnomalyEvent
+	w.mu.Lock()
+	w.lastCheck = now
+	for satelliteID, last := range w.contacts {
+		window := w.Window(satelliteID)
+		silence := now.Sub(last)
+		switch {
+		case silence > window && !w.alerted[satelliteID]:
+			w.alerted[satelliteID] = true
+			toAlert = append(toAlert, lossOfSignalEvent(satelliteID, now, last, silence, window, false))
+		case silence <= window && w.alerted[satelliteID]:
+			delete(w.alerted, satelliteID)
+			toAlert = append(toAlert, lossOfSignalEvent(satelliteID, now, last, silence, window, true))
+		}
+		if silence > window {
+			stale = append(stale, w.contact(satelliteID, last, now))
+		}
+	}
+	w.mu.Unlock()
+
+	for _, event := range toAlert {
+		log.Printf("ContactWatchdog: [%s] %s %s", event.Severity, event.SatelliteID, event.Message)
+		if w.sink != nil {
+			w.sink.Alert(event)
+		}
+	}
+	sortContacts(stale)
+	return stale, nil
+}
+
+func lossOfSignalEvent(satelliteID string, now, last time.Time, silence, window time.Duration, recovered bool) models.AnomalyEvent {
+	message := fmt.Sprintf("no telemetry for %v (expected within %v), last contact %s",
+		silence.Round(time.Second), window, last.UTC().Format(time.RFC3339))
+	if recovered {
+		message = fmt.Sprintf("telemetry resumed, last contact %s", last.UTC().Format(time.RFC3339))
+	}
+	return models.AnomalyEvent{
+		SatelliteID: satelliteID,
+		Timestamp:   now.UTC(),
+		Recovered:   recovered,
+		AnomalyResult: models.AnomalyResult{
+			Detector:  LossOfSignalDetectorName,
+			Severity:  models.SeverityCritical,
+			Dimension: models.DimensionSignal,
+			Message:   message,
+			Metric:    "silence_seconds",
+			Value:     silence.Seconds(),
+			Threshold: window.Seconds(),
+		},
+	}
+}
+
+// contact describes a satellite's silence
+// Caller must hold w.mu
+func (w *ContactWatchdog) contact(satelliteID string, last, now time.Time) SatelliteContact {
+	return SatelliteContact{
+		SatelliteID:    satelliteID,
+		LastContact:    last.UTC(),
+		SilenceSeconds: now.Sub(last).Seconds(),
+		WindowSeconds:  w.Window(satelliteID).Seconds(),
+		Alerted:        w.alerted[satelliteID],
+	}
+}
+
+// Stale returns the satellites silent for longer than threshold, or than
+// their own silence window when threshold is 0, longest silence first
+func (w *ContactWatchdog) Stale(threshold time.Duration) []SatelliteContact {
+	now := w.now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stale := make([]SatelliteContact, 0)
+	for satelliteID, last := range w.contacts {
+		limit := threshold
+		if limit <= 0 {
+			limit = w.Window(satelliteID)
+		}
+		if now.Sub(last) > limit {
+			stale = append(stale, w.contact(satelliteID, last, now))
+		}
+	}
+	sortContacts(stale)
+	return stale
+}
+
+// Tracked returns the number of satellites with a known last contact
+func (w *ContactWatchdog) Tracked() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.contacts)
+}
+
+func sortContacts(contacts []SatelliteContact) {
+	sort.Slice(contacts, func(i, j int) bool {
+		if contacts[i].SilenceSeconds != contacts[j].SilenceSeconds {
+			return contacts[i].SilenceSeconds > contacts[j].SilenceSeconds
+		}
+		return contacts[i].SatelliteID < contacts[j].SatelliteID
+	})
+}
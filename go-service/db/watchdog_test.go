@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeContactQuerier returns canned last contacts
+type fakeContactQuerier struct {
+	contacts map[string]time.Time
+	since    time.Time
+}
+
+func (f *fakeContactQuerier) LastContacts(ctx context.Context, since time.Time) (map[string]time.Time, error) {
+	f.since = since
+	return f.contacts, nil
+}
+
+// TestContactWatchdogAlertsOncePerSilence tests loss-of-signal alerts and recoveries
+func TestContactWatchdogAlertsOncePerSilence(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	querier := &fakeContactQuerier{contacts: map[string]time.Time{
+		"SAT-001": now.Add(-time.Hour),
+		"SAT-002": now.Add(-time.Minute),
+	}}
+	sink := &recordingAlertSink{}
+	watchdog := NewContactWatchdog(querier, sink, WatchdogConfig{DefaultWindow: 30 * time.Minute})
+	watchdog.now = func() time.Time { return now }
+
+	stale, err := watchdog.Check(context.Background())
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(stale) != 1 || stale[0].SatelliteID != "SAT-001" || len(sink.events) != 1 {
+		t.Fatalf("expected one SAT-001 alert, got stale %+v alerts %+v", stale, sink.events)
+	}
+	if event := sink.events[0]; event.Detector != LossOfSignalDetectorName || event.Recovered {
+		t.Errorf("unexpected alert %+v", event)
+	}
+	if !querier.since.Equal(now.Add(-watchdogSeedLookback)) {
+		t.Errorf("expected the first check to look back %v, got %v", watchdogSeedLookback, now.Sub(querier.since))
+	}
+
+	// Still silent: no repeat alert
+	now = now.Add(time.Minute)
+	if _, err := watchdog.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(sink.events) != 1 {
+		t.Errorf("expected no repeat alert, got %d alerts", len(sink.events))
+	}
+
+	// A point ingested by this replica recovers the satellite
+	watchdog.observe("SAT-001", now)
+	if _, err := watchdog.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if len(sink.events) != 2 || !sink.events[1].Recovered || sink.events[1].SatelliteID != "SAT-001" {
+		t.Errorf("expected a SAT-001 recovery, got %+v", sink.events)
+	}
+}
+
+func TestContactWatchdogWindows(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	watchdog := NewContactWatchdog(nil, nil, WatchdogConfig{
+		DefaultWindow: 30 * time.Minute,
+		Windows:       []SilenceWindow{{Pattern: "CUBE-*", Window: 3 * time.Hour}},
+	})
+	watchdog.now = func() time.Time { return now }
+	watchdog.observe("SAT-001", now.Add(-time.Hour))
+	watchdog.observe("CUBE-001", now.Add(-time.Hour))
+	watchdog.observe("SAT-002", now.Add(-2*time.Hour))
+
+	if window := watchdog.Window("CUBE-001"); window != 3*time.Hour {
+		t.Errorf("expected the CUBE-* window, got %v", window)
+	}
+
+	stale := watchdog.Stale(0)
+	if len(stale) != 2 || stale[0].SatelliteID != "SAT-002" || stale[1].SatelliteID != "SAT-001" {
+		t.Errorf("expected SAT-002 and SAT-001 past their windows, got %+v", stale)
+	}
+	if stale := watchdog.Stale(90 * time.Minute); len(stale) != 1 || stale[0].SatelliteID != "SAT-002" {
+		t.Errorf("expected only SAT-002 past 90m, got %+v", stale)
+	}
+	if watchdog.Tracked() != 3 {
+		t.Errorf("expected 3 tracked satellites, got %d", watchdog.Tracked())
+	}
+}
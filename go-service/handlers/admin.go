@@ -0,0 +1,525 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/alerting"
+	"orbitstream/db"
+	"orbitstream/models"
+	"orbitstream/reporting"
+)
+
+// AdminHandler serves operator endpoints under /admin
+type AdminHandler struct {
+	batchProcessor *db.BatchProcessor
+	decayMonitor   *db.DecayMonitor
+	indexAdvisor   *db.IndexAdvisor
+	healthMonitor  *db.HealthMonitor
+	runbooks       *alerting.Runbooks
+	schema         db.SchemaSource
+	fleetReports   *reporting.Reporter
+	leader         *db.LeaderElector
+	reloader       ConfigReloader
+	breakers       *db.CircuitBreakerRegistry
+}
+
+// ConfigReloader re-reads the configuration and applies the settings that
+// can change without a restart
+// This allows for mocking in tests
+type ConfigReloader interface {
+	Reload() (models.ConfigReload, error)
+}
+
+// NewAdminHandler creates an admin handler for the given batch processor
+func NewAdminHandler(bp *db.BatchProcessor) *AdminHandler {
+	return &AdminHandler{
+		batchProcessor: bp,
+	}
+}
+
+// SetDecayMonitor enables GET /admin/decay
+func (h *AdminHandler) SetDecayMonitor(monitor *db.DecayMonitor) {
+	h.decayMonitor = monitor
+}
+
+// SetFleetReporter enables GET /admin/reports/fleet
+func (h *AdminHandler) SetFleetReporter(reporter *reporting.Reporter) {
+	h.fleetReports = reporter
+}
+
+// SetLeaderElector enables GET /admin/leader
+func (h *AdminHandler) SetLeaderElector(leader *db.LeaderElector) {
+	h.leader = leader
+}
+
+// SetIndexAdvisor enables GET /admin/index-advisor
+func (h *AdminHandler) SetIndexAdvisor(advisor *db.IndexAdvisor) {
+	h.indexAdvisor = advisor
+}
+
+// SetHealthMonitor enables the /admin/wal endpoints
+func (h *AdminHandler) SetHealthMonitor(hm *db.HealthMonitor) {
+	h.healthMonitor = hm
+}
+
+// SetCircuitBreakers enables the /admin/circuit-breaker endpoints
+func (h *AdminHandler) SetCircuitBreakers(breakers *db.CircuitBreakerRegistry) {
+	h.breakers = breakers
+}
+
+// SetConfigReloader enables POST /admin/config/reload
+func (h *AdminHandler) SetConfigReloader(reloader ConfigReloader) {
+	h.reloader = reloader
+}
+
+// SetSchemaSource enables the /admin/schema endpoints
+func (h *AdminHandler) SetSchemaSource(source db.SchemaSource) {
+	h.schema = source
+}
+
+// SetRunbooks attaches remediation runbooks to reported anomalies
+func (h *AdminHandler) SetRunbooks(runbooks *alerting.Runbooks) {
+	h.runbooks = runbooks
+}
+
+// HandlePending reports points for a satellite that are not yet committed
+// GET /admin/pending?satellite_id=
+func (h *AdminHandler) HandlePending(c *gin.Context) {
+	satelliteID := c.Query("satellite_id")
+	if satelliteID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "satellite_id is required"})
+		return
+	}
+
+	pending, err := h.batchProcessor.PendingForSatellite(satelliteID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pending)
+}
+
+// HandleStats reports the ingest pipeline totals and insert throughput
+// GET /admin/stats
+func (h *AdminHandler) HandleStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.batchProcessor.Stats())
+}
+
+// HandleLateData reports late arrivals by lateness bucket and policy activity
+// GET /admin/late
+func (h *AdminHandler) HandleLateData(c *gin.Context) {
+	tracker := h.batchProcessor.GetLateDataTracker()
+	if tracker == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "late data tracking is disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, tracker.Stats())
+}
+
+// HandleDedup reports the recent points remembered for deduplication and
+// the retransmissions dropped
+// GET /admin/dedup
+func (h *AdminHandler) HandleDedup(c *gin.Context) {
+	dedup := h.batchProcessor.GetDeduplicator()
+	if dedup == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "deduplication is disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, dedup.Stats())
+}
+
+// HandleTimestampGuard reports the timestamp bounds and the points outside
+// them, by case
+// GET /admin/timestamp-guard
+func (h *AdminHandler) HandleTimestampGuard(c *gin.Context) {
+	guard := h.batchProcessor.GetTimestampGuard()
+	if guard == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "the timestamp guard is disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, guard.Stats())
+}
+
+// HandleBatchSize reports the adaptive batch size and the insert latency
+// and error rate driving it
+// GET /admin/batch-size
+func (h *AdminHandler) HandleBatchSize(c *gin.Context) {
+	controller := h.batchProcessor.GetBatchSizeController()
+	if controller == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "adaptive batch sizing is disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, controller.Status())
+}
+
+// HandleShadow reports the shadow writes and compares telemetry with the
+// shadow table over the window (default: the hour up to a minute ago, not
+// before shadow writing began)
+// GET /admin/shadow?from=&to=&samples=
+func (h *AdminHandler) HandleShadow(c *gin.Context) {
+	writer := h.batchProcessor.GetShadowWriter()
+	if writer == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "shadow writes are disabled"})
+		return
+	}
+
+	// Batches of the last minute may still be queued for the shadow
+	to := time.Now().UTC().Add(-time.Minute)
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'to' timestamp: " + err.Error()})
+			return
+		}
+		to = parsed
+	}
+	from := to.Add(-time.Hour)
+	if started := writer.Started(); from.Before(started) {
+		from = started
+	}
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'from' timestamp: " + err.Error()})
+			return
+		}
+		from = parsed
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' must be before 'to'"})
+		return
+	}
+	samples, err := strconv.Atoi(c.DefaultQuery("samples", "20"))
+	if err != nil || samples < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "samples must be a non-negative integer"})
+		return
+	}
+
+	comparison, err := writer.Compare(c.Request.Context(), from, to, samples)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"writes":     writer.Stats(),
+		"comparison": comparison,
+	})
+}
+
+// HandleLeader reports whether this replica is the leader running the
+// cluster-wide background jobs
+// GET /admin/leader
+func (h *AdminHandler) HandleLeader(c *gin.Context) {
+	if h.leader == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leader election is disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, h.leader.Status())
+}
+
+// HandleCardinality reports how many satellites have per-satellite state and
+// the most active ones
+// GET /admin/cardinality?top=
+func (h *AdminHandler) HandleCardinality(c *gin.Context) {
+	guard := h.batchProcessor.GetCardinalityGuard()
+	if guard == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "satellite cardinality is not limited"})
+		return
+	}
+	top := 20
+	if value := c.Query("top"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "top must be a positive integer"})
+			return
+		}
+		top = parsed
+	}
+	c.JSON(http.StatusOK, guard.Stats(top))
+}
+
+// HandleDecay reports satellites losing altitude faster than expected at the
+// last orbital decay check
+// GET /admin/decay
+func (h *AdminHandler) HandleDecay(c *gin.Context) {
+	if h.decayMonitor == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "orbital decay monitoring is disabled"})
+		return
+	}
+	findings := h.decayMonitor.Findings()
+	for i := range findings {
+		findings[i].Runbook = h.runbooks.ForEvent(findings[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"findings": findings, "count": len(findings)})
+}
+
+// HandleIndexAdvisor reports slow telemetry queries and index or aggregate
+// recommendations from the latest pg_stat_statements analysis
+// GET /admin/index-advisor?refresh=true
+// refresh=true analyzes now instead of returning the periodic report
+func (h *AdminHandler) HandleIndexAdvisor(c *gin.Context) {
+	if h.indexAdvisor == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "index advisor is disabled"})
+		return
+	}
+
+	if c.Query("refresh") == "true" {
+		report, err := h.indexAdvisor.Analyze(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	report, ok := h.indexAdvisor.Report()
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no analysis has completed yet"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleWAL reports WAL size, record count, oldest record and replay status
+// GET /admin/wal
+func (h *AdminHandler) HandleWAL(c *gin.Context) {
+	if h.healthMonitor == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "WAL is not configured"})
+		return
+	}
+	status, err := h.healthMonitor.WALStatus()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// HandleWALVerify samples WAL records and reports how many already have a
+// committed row, to check whether a stuck WAL is safe to clear
+// GET /admin/wal/verify?sample=1000
+func (h *AdminHandler) HandleWALVerify(c *gin.Context) {
+	if h.healthMonitor == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "WAL is not configured"})
+		return
+	}
+	sample := 1000
+	if value := c.Query("sample"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 10000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sample must be between 1 and 10000"})
+			return
+		}
+		sample = parsed
+	}
+	verification, err := h.healthMonitor.VerifyWAL(c.Request.Context(), sample)
+	if errors.Is(err, db.ErrReplayInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, verification)
+}
+
+// HandleWALReplay replays the WAL to the database now
+// POST /admin/wal/replay
+func (h *AdminHandler) HandleWALReplay(c *gin.Context) {
+	if h.healthMonitor == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "WAL is not configured"})
+		return
+	}
+	replayed, err := h.healthMonitor.Replay()
+	auditAdmin(c, "wal.replay", gin.H{}, replayed, err)
+	if errors.Is(err, db.ErrReplayInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "replayed": replayed})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed})
+}
+
+// HandleWALClear discards every WAL record without replaying it
+// POST /admin/wal/clear?confirm=true
+// The records are lost, so confirm=true is required.
+func (h *AdminHandler) HandleWALClear(c *gin.Context) {
+	if h.healthMonitor == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "WAL is not configured"})
+		return
+	}
+	if c.Query("confirm") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "clearing discards unreplayed telemetry, pass confirm=true"})
+		return
+	}
+	cleared, err := h.healthMonitor.ClearWAL()
+	auditAdmin(c, "wal.clear", gin.H{"confirm": true}, cleared, err)
+	if errors.Is(err, db.ErrReplayInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"cleared": cleared})
+}
+
+// HandleFlush flushes the buffer immediately, e.g. before a planned
+// maintenance window or in integration tests
+// POST /admin/flush
+// The response counts the points written and those diverted to the WAL,
+// the overflow queue or the dead letter queue.
+func (h *AdminHandler) HandleFlush(c *gin.Context) {
+	result := h.batchProcessor.FlushNow(c.Request.Context())
+	var err error
+	if result.Failed > 0 {
+		err = fmt.Errorf("%d points failed to flush and stay in the write-through journal", result.Failed)
+	}
+	auditAdmin(c, "buffer.flush", gin.H{}, result.Points, err)
+	c.JSON(http.StatusOK, result)
+}
+
+// HandleCircuitBreaker reports each operation's breaker: state, failure
+// count, last failure and the time until an open breaker turns half-open
+// GET /admin/circuit-breaker
+func (h *AdminHandler) HandleCircuitBreaker(c *gin.Context) {
+	if h.breakers == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "circuit breakers are not configured"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"breakers": h.breakers.Snapshots()})
+}
+
+// HandleCircuitBreakerReset closes a tripped breaker so on-call can recover
+// without restarting the process
+// POST /admin/circuit-breaker/reset?operation=
+// Without operation every breaker is reset.
+func (h *AdminHandler) HandleCircuitBreakerReset(c *gin.Context) {
+	if h.breakers == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "circuit breakers are not configured"})
+		return
+	}
+	operation := c.Query("operation")
+	reset := h.breakers.Reset(operation)
+	if operation != "" && len(reset) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":      fmt.Sprintf("no circuit breaker for operation %q", operation),
+			"operations": h.breakers.Operations(),
+		})
+		return
+	}
+	auditAdmin(c, "circuit_breaker.reset", gin.H{"operation": operation}, len(reset), nil)
+	c.JSON(http.StatusOK, gin.H{"reset": reset, "breakers": h.breakers.Snapshots()})
+}
+
+// HandleConfigReload re-reads the configuration and applies the batching,
+// retry and anomaly threshold settings without dropping the buffer
+// POST /admin/config/reload
+// An invalid configuration is rejected and the running one kept.
+func (h *AdminHandler) HandleConfigReload(c *gin.Context) {
+	if h.reloader == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "config reload is not configured"})
+		return
+	}
+	result, err := h.reloader.Reload()
+	auditAdmin(c, "config.reload", gin.H{}, len(result.Applied), err)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// HandleDeadLetters lists records the database rejected permanently
+// GET /admin/dead-letters?limit=100
+// The newest limit records are returned, with the total stored.
+func (h *AdminHandler) HandleDeadLetters(c *gin.Context) {
+	dlq := h.batchProcessor.GetDeadLetterQueue()
+	if dlq == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dead letter queue is disabled"})
+		return
+	}
+	limit := 100
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+	letters, total, err := dlq.Read(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dead_letters": letters, "count": len(letters), "total": total})
+}
+
+// HandleFleetReport renders the weekly fleet health report on demand
+// GET /admin/reports/fleet?end=&format=
+// end (YYYY-MM-DD) picks the week ending that day (default: the latest
+// complete week); format is html (default) or json
+func (h *AdminHandler) HandleFleetReport(c *gin.Context) {
+	if h.fleetReports == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "fleet reports are disabled"})
+		return
+	}
+	format := c.DefaultQuery("format", "html")
+	if format != "html" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be html or json"})
+		return
+	}
+
+	from, to := h.fleetReports.LastPeriod(time.Now())
+	if end := c.Query("end"); end != "" {
+		var err error
+		if from, to, err = h.fleetReports.PeriodEnding(end); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	report, err := h.fleetReports.Generate(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	html, err := reporting.RenderHTML(report)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// The page carries its own inline styles and nothing else
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", html)
+}
+
+// auditAdmin logs an operator action with the caller identity, parameters
+// and outcome (records affected or the error)
+func auditAdmin(c *gin.Context, action string, params gin.H, records int, err error) {
+	identity := ClientIdentityFrom(c)
+	result := "ok"
+	if err != nil {
+		result = "error: " + err.Error()
+	}
+	log.Printf("AUDIT: action=%s client_ip=%s cert_subject=%q params=%v records=%d result=%q",
+		action, identity.IP, identity.CertSubject, params, records, result)
+}
@@ -0,0 +1,732 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+	"orbitstream/reporting"
+	"orbitstream/test"
+)
+
+func setupAdminRouter(handler *AdminHandler) *gin.Engine {
+	router := gin.New()
+	admin := router.Group("/admin")
+	admin.GET("/pending", handler.HandlePending)
+	admin.GET("/late", handler.HandleLateData)
+	admin.GET("/cardinality", handler.HandleCardinality)
+	admin.GET("/batch-size", handler.HandleBatchSize)
+	admin.GET("/leader", handler.HandleLeader)
+	admin.GET("/stats", handler.HandleStats)
+	admin.GET("/dedup", handler.HandleDedup)
+	admin.GET("/timestamp-guard", handler.HandleTimestampGuard)
+	admin.GET("/shadow", handler.HandleShadow)
+	admin.GET("/decay", handler.HandleDecay)
+	admin.GET("/index-advisor", handler.HandleIndexAdvisor)
+	admin.GET("/wal", handler.HandleWAL)
+	admin.GET("/wal/verify", handler.HandleWALVerify)
+	admin.POST("/wal/replay", handler.HandleWALReplay)
+	admin.POST("/wal/clear", handler.HandleWALClear)
+	admin.GET("/dead-letters", handler.HandleDeadLetters)
+	admin.GET("/schema", handler.HandleSchema)
+	admin.GET("/schema/export", handler.HandleSchemaExport)
+	admin.GET("/reports/fleet", handler.HandleFleetReport)
+	admin.POST("/config/reload", handler.HandleConfigReload)
+	admin.POST("/flush", handler.HandleFlush)
+	admin.GET("/circuit-breaker", handler.HandleCircuitBreaker)
+	admin.POST("/circuit-breaker/reset", handler.HandleCircuitBreakerReset)
+	return router
+}
+
+func newTestBatchProcessor() *db.BatchProcessor {
+	return db.NewBatchProcessor(nil, 1000, time.Second, db.AnomalyConfig{
+		BatteryMinPercent: 10.0,
+		StorageMaxMB:      95000.0,
+		SignalMinDBM:      -100.0,
+	})
+}
+
+func TestHandlePending(t *testing.T) {
+	bp := newTestBatchProcessor()
+	router := setupAdminRouter(NewAdminHandler(bp))
+
+	for i := 0; i < 3; i++ {
+		point := test.NewTestTelemetryPointWithSatelliteID("SAT-042")
+		if err := bp.Add(point); err != nil {
+			t.Fatalf("failed to add point: %v", err)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/admin/pending?satellite_id=SAT-042", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var pending db.PendingWrites
+	if err := json.Unmarshal(w.Body.Bytes(), &pending); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if pending.BufferCount != 3 || pending.TotalPending != 3 {
+		t.Errorf("expected 3 pending points, got %+v", pending)
+	}
+	if pending.OldestPending == nil {
+		t.Error("expected oldest_pending to be set")
+	}
+}
+
+func TestHandlePendingMissingSatellite(t *testing.T) {
+	router := setupAdminRouter(NewAdminHandler(newTestBatchProcessor()))
+
+	req, _ := http.NewRequest("GET", "/admin/pending", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHandleLateData(t *testing.T) {
+	bp := newTestBatchProcessor()
+	bp.SetLateDataTracker(db.NewLateDataTracker(db.LateDataConfig{
+		Policies: []db.LateDataPolicy{db.LatePolicyTag},
+	}))
+	router := setupAdminRouter(NewAdminHandler(bp))
+
+	point := test.NewTestTelemetryPointWithSatelliteID("SAT-042")
+	point.Timestamp = time.Now().Add(-3 * time.Hour)
+	if err := bp.Add(point); err != nil {
+		t.Fatalf("failed to add point: %v", err)
+	}
+
+	w := doGet(router, "/admin/late")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var stats db.LateDataStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if stats.Late != 1 || stats.ByLateness["<=48h"] != 1 {
+		t.Errorf("expected one late point in <=48h, got %+v", stats)
+	}
+}
+
+func TestHandleLateDataDisabled(t *testing.T) {
+	router := setupAdminRouter(NewAdminHandler(newTestBatchProcessor()))
+
+	w := doGet(router, "/admin/late")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestHandleCardinality(t *testing.T) {
+	bp := newTestBatchProcessor()
+	bp.SetCardinalityGuard(db.NewCardinalityGuard(2, 0))
+	router := setupAdminRouter(NewAdminHandler(bp))
+
+	for _, id := range []string{"SAT-001", "SAT-001", "SAT-002", "SAT-003"} {
+		if err := bp.Add(test.NewTestTelemetryPointWithSatelliteID(id)); err != nil {
+			t.Fatalf("failed to add point: %v", err)
+		}
+	}
+
+	w := doGet(router, "/admin/cardinality?top=1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var stats db.CardinalityStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if stats.Limit != 2 || stats.Tracked != 2 || stats.Evicted != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if len(stats.Top) != 1 || stats.Top[0].SatelliteID != "SAT-001" {
+		t.Errorf("expected SAT-001 on top, got %+v", stats.Top)
+	}
+
+	if w := doGet(router, "/admin/cardinality?top=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for top=0, got %d", w.Code)
+	}
+}
+
+func TestHandleCardinalityUnlimited(t *testing.T) {
+	router := setupAdminRouter(NewAdminHandler(newTestBatchProcessor()))
+
+	if w := doGet(router, "/admin/cardinality"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+type fakeTrendQuerier []db.AltitudeTrend
+
+func (f fakeTrendQuerier) AltitudeTrends(ctx context.Context, since time.Time, minSamples int) ([]db.AltitudeTrend, error) {
+	return f, nil
+}
+
+func TestHandleDecay(t *testing.T) {
+	monitor := db.NewDecayMonitor(fakeTrendQuerier{
+		{SatelliteID: "SAT-042", Samples: 48, SlopeKMPerDay: -3.0, R2: 0.95, LatestAltitudeKM: 390},
+	}, nil, db.DecayConfig{WarningKMPerDay: 0.5, CriticalKMPerDay: 2.0, MinR2: 0.5})
+	if _, err := monitor.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	handler := NewAdminHandler(newTestBatchProcessor())
+	handler.SetDecayMonitor(monitor)
+	router := setupAdminRouter(handler)
+
+	w := doGet(router, "/admin/decay")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Findings []models.AnomalyEvent `json:"findings"`
+		Count    int                   `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response.Count != 1 || response.Findings[0].SatelliteID != "SAT-042" ||
+		response.Findings[0].Severity != models.SeverityCritical {
+		t.Errorf("expected critical SAT-042 finding, got %+v", response)
+	}
+}
+
+func TestHandleDecayDisabled(t *testing.T) {
+	router := setupAdminRouter(NewAdminHandler(newTestBatchProcessor()))
+
+	w := doGet(router, "/admin/decay")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+type fakeQueryStats struct{}
+
+func (fakeQueryStats) SlowTelemetryQueries(ctx context.Context, minMeanMS float64, limit int) ([]db.SlowQuery, error) {
+	return []db.SlowQuery{{Query: "SELECT * FROM telemetry WHERE software_version = $1", Calls: 4, MeanMS: 300}}, nil
+}
+
+func (fakeQueryStats) TelemetryIndexes(ctx context.Context) ([]db.TelemetryIndex, error) {
+	return nil, nil
+}
+
+func TestHandleIndexAdvisor(t *testing.T) {
+	handler := NewAdminHandler(newTestBatchProcessor())
+	handler.SetIndexAdvisor(db.NewIndexAdvisor(fakeQueryStats{}, db.IndexAdvisorConfig{}))
+	router := setupAdminRouter(handler)
+
+	if w := doGet(router, "/admin/index-advisor"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 before the first analysis, got %d", w.Code)
+	}
+
+	w := doGet(router, "/admin/index-advisor?refresh=true")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var report db.IndexAdvisorReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(report.Recommendations) != 1 || report.Recommendations[0].Kind != db.RecommendIndex {
+		t.Errorf("expected one index recommendation, got %+v", report.Recommendations)
+	}
+
+	if w := doGet(router, "/admin/index-advisor"); w.Code != http.StatusOK {
+		t.Errorf("expected the refreshed report to be served, got %d", w.Code)
+	}
+}
+
+func TestHandleIndexAdvisorDisabled(t *testing.T) {
+	router := setupAdminRouter(NewAdminHandler(newTestBatchProcessor()))
+
+	if w := doGet(router, "/admin/index-advisor"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func doPost(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleWAL(t *testing.T) {
+	wal, err := db.NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	for i := 0; i < 3; i++ {
+		if err := wal.Write(db.NewWALRecord(test.NewTestTelemetryPoint())); err != nil {
+			t.Fatalf("WAL write failed: %v", err)
+		}
+	}
+	bp := newTestBatchProcessor()
+	handler := NewAdminHandler(bp)
+	handler.SetHealthMonitor(db.NewHealthMonitor(nil, wal, bp))
+	router := setupAdminRouter(handler)
+
+	w := doGet(router, "/admin/wal")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var status db.WALStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if status.Records != 3 || status.OldestRecord == nil || status.Replay.Running {
+		t.Errorf("unexpected WAL status %+v", status)
+	}
+
+	if w := doPost(router, "/admin/wal/clear"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without confirm, got %d", w.Code)
+	}
+	w = doPost(router, "/admin/wal/clear?confirm=true")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"cleared":3`) {
+		t.Errorf("expected 3 records cleared, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doPost(router, "/admin/wal/replay")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"replayed":0`) {
+		t.Errorf("expected an empty replay, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleWALNotConfigured(t *testing.T) {
+	router := setupAdminRouter(NewAdminHandler(newTestBatchProcessor()))
+
+	if w := doGet(router, "/admin/wal"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	if w := doPost(router, "/admin/wal/replay"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	if w := doGet(router, "/admin/wal/verify"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestHandleWALVerifyInvalidSample(t *testing.T) {
+	wal, err := db.NewWAL(filepath.Join(t.TempDir(), "data.wal"))
+	if err != nil {
+		t.Fatalf("failed to create WAL: %v", err)
+	}
+	defer wal.Close()
+	bp := newTestBatchProcessor()
+	handler := NewAdminHandler(bp)
+	handler.SetHealthMonitor(db.NewHealthMonitor(nil, wal, bp))
+	router := setupAdminRouter(handler)
+
+	for _, sample := range []string{"0", "abc", "10001"} {
+		if w := doGet(router, "/admin/wal/verify?sample="+sample); w.Code != http.StatusBadRequest {
+			t.Errorf("sample=%s: expected status 400, got %d", sample, w.Code)
+		}
+	}
+
+	// An empty WAL is verified without touching the database
+	w := doGet(router, "/admin/wal/verify")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"sampled":0`) {
+		t.Errorf("expected an empty verification, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleDeadLetters(t *testing.T) {
+	dlq, err := db.NewDeadLetterQueue(filepath.Join(t.TempDir(), "dead-letter.jsonl"))
+	if err != nil {
+		t.Fatalf("failed to create dead letter queue: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		letter := db.DeadLetter{Source: db.DeadLetterSourceReplay, Error: "numeric field overflow (SQLSTATE 22003)"}
+		if err := dlq.Write([]db.DeadLetter{letter}); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	bp := newTestBatchProcessor()
+	router := setupAdminRouter(NewAdminHandler(bp))
+
+	if w := doGet(router, "/admin/dead-letters"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 when disabled, got %d", w.Code)
+	}
+
+	bp.SetDeadLetterQueue(dlq)
+	w := doGet(router, "/admin/dead-letters?limit=2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		DeadLetters []db.DeadLetter `json:"dead_letters"`
+		Count       int             `json:"count"`
+		Total       int             `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response.Count != 2 || response.Total != 3 || len(response.DeadLetters) != 2 {
+		t.Errorf("expected 2 of 3 dead letters, got %+v", response)
+	}
+
+	if w := doGet(router, "/admin/dead-letters?limit=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for limit=0, got %d", w.Code)
+	}
+}
+
+type fakeSchemaSource struct {
+	snapshot db.SchemaSnapshot
+}
+
+func (f fakeSchemaSource) SchemaSnapshot(ctx context.Context) (db.SchemaSnapshot, error) {
+	return f.snapshot, nil
+}
+
+func TestHandleSchema(t *testing.T) {
+	handler := NewAdminHandler(newTestBatchProcessor())
+	router := setupAdminRouter(handler)
+
+	if w := doGet(router, "/admin/schema"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without a schema source, got %d", w.Code)
+	}
+
+	expected, err := db.ExpectedSchema()
+	if err != nil {
+		t.Fatalf("failed to parse init.sql: %v", err)
+	}
+	live := expected
+	live.GeneratedAt = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	live.Policies = expected.Policies[1:]
+	handler.SetSchemaSource(fakeSchemaSource{snapshot: live})
+
+	w := doGet(router, "/admin/schema")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Drift   []db.SchemaDrift `json:"drift"`
+		Drifted bool             `json:"drifted"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if !response.Drifted || len(response.Drift) != 1 || response.Drift[0].Kind != db.DriftMissing {
+		t.Errorf("expected the removed policy reported missing, got %s", w.Body.String())
+	}
+
+	w = doGet(router, "/admin/schema/export")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, "20261001120000_schema_export.sql") {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+	if !strings.Contains(w.Body.String(), "SELECT create_hypertable('telemetry', 'time'") {
+		t.Errorf("expected a SQL migration, got %s", w.Body.String())
+	}
+}
+
+type fakeFleetSource struct {
+	from, to time.Time
+}
+
+func (s *fakeFleetSource) FleetReport(ctx context.Context, from, to time.Time, loc *time.Location, topN int) (models.FleetReport, error) {
+	s.from, s.to = from, to
+	return models.FleetReport{Satellites: 2, DataPoints: 400, Anomalies: 3}, nil
+}
+
+func TestHandleFleetReport(t *testing.T) {
+	handler := NewAdminHandler(newTestBatchProcessor())
+	router := setupAdminRouter(handler)
+
+	if w := doGet(router, "/admin/reports/fleet"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without a fleet reporter, got %d", w.Code)
+	}
+
+	source := &fakeFleetSource{}
+	handler.SetFleetReporter(reporting.NewReporter(source, nil, reporting.Config{Weekday: time.Monday}))
+
+	w := doGet(router, "/admin/reports/fleet?end=2026-03-09&format=json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var report models.FleetReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if report.DataPoints != 400 || !report.PeriodEnd.Equal(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if !source.from.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the week from 2026-03-02, got %v", source.from)
+	}
+
+	w = doGet(router, "/admin/reports/fleet")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("expected an HTML page, got %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "Fleet health report") {
+		t.Errorf("expected the rendered report, got %s", w.Body.String())
+	}
+
+	for _, path := range []string{"/admin/reports/fleet?format=pdf", "/admin/reports/fleet?end=last-week"} {
+		if w := doGet(router, path); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestHandleBatchSize(t *testing.T) {
+	bp := newTestBatchProcessor()
+	router := setupAdminRouter(NewAdminHandler(bp))
+
+	if w := doGet(router, "/admin/batch-size"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 with a fixed batch size, got %d", w.Code)
+	}
+
+	bp.SetBatchSizeController(db.NewBatchSizeController(db.BatchSizeConfig{Min: 100, Max: 5000, TargetLatency: time.Second}, 1000))
+	w := doGet(router, "/admin/batch-size")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var status db.BatchSizeStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if status.Size != 1000 || status.Min != 100 || status.Max != 5000 || status.TargetLatency != "1s" {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestHandleLeaderDisabled(t *testing.T) {
+	router := setupAdminRouter(NewAdminHandler(newTestBatchProcessor()))
+	if w := doGet(router, "/admin/leader"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without leader election, got %d", w.Code)
+	}
+}
+
+func TestHandleShadowDisabled(t *testing.T) {
+	router := setupAdminRouter(NewAdminHandler(newTestBatchProcessor()))
+	if w := doGet(router, "/admin/shadow"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without shadow writes, got %d", w.Code)
+	}
+}
+
+func TestHandleStats(t *testing.T) {
+	bp := newTestBatchProcessor()
+	bp.SetMaxBufferSize(3)
+	router := setupAdminRouter(NewAdminHandler(bp))
+
+	for i := 0; i < 4; i++ {
+		point := test.NewTestTelemetryPointWithSatelliteID("SAT-042")
+		if i == 0 {
+			point.BatteryChargePercent = 5.0
+		}
+		_ = bp.Add(point)
+	}
+
+	w := doGet(router, "/admin/stats")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var stats models.PipelineStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if stats.Accepted != 3 || stats.Rejected != 1 || stats.Anomalies != 1 || stats.Buffered != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.Flushed != 0 || stats.LastFlush != nil || stats.Since.IsZero() {
+		t.Errorf("expected no flushes yet, got %+v", stats)
+	}
+}
+
+func TestHandleDedup(t *testing.T) {
+	bp := newTestBatchProcessor()
+	router := setupAdminRouter(NewAdminHandler(bp))
+	if w := doGet(router, "/admin/dedup"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without deduplication, got %d", w.Code)
+	}
+
+	dedup, err := db.NewDeduplicator(100, db.DedupByKey)
+	if err != nil {
+		t.Fatalf("NewDeduplicator failed: %v", err)
+	}
+	bp.SetDeduplicator(dedup)
+	point := test.NewTestTelemetryPointWithSatelliteID("SAT-042")
+	for i := 0; i < 2; i++ {
+		if err := bp.Add(point); err != nil {
+			t.Fatalf("failed to add point: %v", err)
+		}
+	}
+
+	w := doGet(router, "/admin/dedup")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var stats db.DedupStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if stats.Mode != db.DedupByKey || stats.Tracked != 1 || stats.Duplicates != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestHandleTimestampGuard(t *testing.T) {
+	bp := newTestBatchProcessor()
+	router := setupAdminRouter(NewAdminHandler(bp))
+	if w := doGet(router, "/admin/timestamp-guard"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without the timestamp guard, got %d", w.Code)
+	}
+
+	guard, err := db.NewTimestampGuard(db.TimestampGuardConfig{
+		Action:    db.TimestampReject,
+		MaxFuture: 5 * time.Minute,
+		MaxAge:    24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewTimestampGuard failed: %v", err)
+	}
+	bp.SetTimestampGuard(guard)
+
+	point := test.NewTestTelemetryPointWithSatelliteID("SAT-042")
+	point.Timestamp = time.Now().UTC().Add(time.Hour)
+	body, _ := json.Marshal(point)
+	req, _ := http.NewRequest("POST", "/telemetry", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	setupTestRouter(NewTelemetryHandler(bp)).ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for a future timestamp, got %d", w.Code)
+	}
+
+	w = doGet(router, "/admin/timestamp-guard")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var stats db.TimestampGuardStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if stats.Future != 1 || stats.Rejected != 1 || stats.TooOld != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+type fakeConfigReloader struct {
+	result models.ConfigReload
+	err    error
+}
+
+func (r fakeConfigReloader) Reload() (models.ConfigReload, error) {
+	return r.result, r.err
+}
+
+func TestHandleConfigReload(t *testing.T) {
+	handler := NewAdminHandler(newTestBatchProcessor())
+	router := setupAdminRouter(handler)
+	if w := postJSON(router, "/admin/config/reload", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without a reloader, got %d", w.Code)
+	}
+
+	handler.SetConfigReloader(fakeConfigReloader{result: models.ConfigReload{
+		Applied:         []string{"BatchSize"},
+		RestartRequired: []string{"Port"},
+	}})
+	w := postJSON(router, "/admin/config/reload", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var result models.ConfigReload
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(result.Applied) != 1 || result.Applied[0] != "BatchSize" || len(result.RestartRequired) != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	handler.SetConfigReloader(fakeConfigReloader{err: errors.New("1 configuration problem")})
+	if w := postJSON(router, "/admin/config/reload", nil); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for an invalid configuration, got %d", w.Code)
+	}
+}
+
+func TestHandleCircuitBreakerReset(t *testing.T) {
+	handler := NewAdminHandler(newTestBatchProcessor())
+	breakers := db.NewCircuitBreakerRegistry(func(operation string) *db.CircuitBreaker {
+		return db.NewCircuitBreaker(1, time.Hour)
+	})
+	breakers.Get(db.OperationInsert).RecordFailure()
+	handler.SetCircuitBreakers(breakers)
+	router := setupAdminRouter(handler)
+
+	req, _ := http.NewRequest("GET", "/admin/circuit-breaker", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response struct {
+		Breakers map[string]db.CircuitBreakerSnapshot `json:"breakers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if insert := response.Breakers[db.OperationInsert]; insert.State != "OPEN" || insert.HalfOpenInSeconds == nil {
+		t.Fatalf("expected an open insert breaker, got %+v", response.Breakers)
+	}
+
+	req, _ = http.NewRequest("POST", "/admin/circuit-breaker/reset?operation=flush", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown operation, got %d", w.Code)
+	}
+
+	req, _ = http.NewRequest("POST", "/admin/circuit-breaker/reset?operation=insert", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !breakers.Get(db.OperationInsert).IsClosed() {
+		t.Errorf("expected the insert breaker to be reset, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleCircuitBreakerNotConfigured(t *testing.T) {
+	router := setupAdminRouter(NewAdminHandler(newTestBatchProcessor()))
+	req, _ := http.NewRequest("POST", "/admin/circuit-breaker/reset", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without breakers, got %d", w.Code)
+	}
+}
+
+func TestHandleFlushEmptyBuffer(t *testing.T) {
+	router := setupAdminRouter(NewAdminHandler(newTestBatchProcessor()))
+	req, _ := http.NewRequest("POST", "/admin/flush", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var result db.FlushResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if w.Code != http.StatusOK || result.Points != 0 || result.Written != 0 {
+		t.Errorf("expected an empty flush, got %d %+v", w.Code, result)
+	}
+}
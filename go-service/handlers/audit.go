@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+const (
+	// maxAuditBodyBytes truncates the request and response bodies recorded
+	maxAuditBodyBytes = 4096
+	// auditWriteTimeout bounds writing one entry, apart from the request
+	// context so a timed out operation is still recorded
+	auditWriteTimeout = 5 * time.Second
+)
+
+// AuditLogger records every admin operation that changes state (WAL
+// replay and clear, config reload, breaker reset, silences...) with the
+// caller's identity, parameters and result to a dedicated sink. Reads are
+// not recorded.
+type AuditLogger struct {
+	sink db.AuditSink
+	now  func() time.Time
+
+	mu       sync.Mutex
+	recorded int64
+	failed   int64
+}
+
+// NewAuditLogger records to sink; a nil sink disables the audit log
+func NewAuditLogger(sink db.AuditSink) *AuditLogger {
+	return &AuditLogger{sink: sink, now: time.Now}
+}
+
+// Enabled reports whether admin operations are recorded
+func (a *AuditLogger) Enabled() bool {
+	return a != nil && a.sink != nil
+}
+
+// Middleware records the admin operations of a route group. A failure to
+// record is logged but does not fail the operation, which already ran.
+func (a *AuditLogger) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if !a.Enabled() {
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		start := a.now()
+
+		c.Next()
+
+		identity := ClientIdentityFrom(c)
+		entry := models.AuditEntry{
+			Time:   start.UTC(),
+			Method: c.Request.Method,
+			Route:  c.FullPath(),
+			Path:   c.Request.URL.RequestURI(),
+			Caller: models.AuditCaller{
+				IP:          identity.IP,
+				CertSubject: identity.CertSubject,
+				APIKeyID:    c.GetString(ContextKeyAPIKeyID),
+				Role:        c.GetString(ContextKeyRole),
+				UserAgent:   c.Request.UserAgent(),
+			},
+			Params:     auditParams(c),
+			Body:       truncateAudit(body),
+			Status:     recorder.Status(),
+			Result:     truncateAudit(recorder.body.Bytes()),
+			DurationMS: float64(a.now().Sub(start).Microseconds()) / 1000,
+		}
+		a.record(entry)
+	}
+}
+
+func (a *AuditLogger) record(entry models.AuditEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+	defer cancel()
+	err := a.sink.Write(ctx, entry)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil {
+		a.failed++
+		log.Printf("WARNING: Failed to record audit entry for %s %s from %s: %v", entry.Method, entry.Path, entry.Caller.IP, err)
+		return
+	}
+	a.recorded++
+}
+
+// auditParams collects the path and query parameters of the request
+func auditParams(c *gin.Context) map[string]string {
+	params := make(map[string]string)
+	for _, param := range c.Params {
+		params[param.Key] = param.Value
+	}
+	for key, values := range c.Request.URL.Query() {
+		if len(values) > 0 {
+			params[key] = values[len(values)-1]
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+func truncateAudit(data []byte) string {
+	if len(data) > maxAuditBodyBytes {
+		return string(data[:maxAuditBodyBytes]) + "...(truncated)"
+	}
+	return string(data)
+}
+
+// HandleList returns the newest audit entries, oldest first
+// GET /admin/audit?limit=100
+func (a *AuditLogger) HandleList(c *gin.Context) {
+	if !a.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "audit log is disabled"})
+		return
+	}
+	limit := 100
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+	entries, err := a.sink.Read(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	a.mu.Lock()
+	recorded, failed := a.recorded, a.failed
+	a.mu.Unlock()
+	c.JSON(http.StatusOK, gin.H{
+		"entries":  entries,
+		"count":    len(entries),
+		"recorded": recorded,
+		"failed":   failed,
+	})
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+func setupAuditRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	sink, err := db.NewFileAuditSink(filepath.Join(t.TempDir(), "audit", "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("failed to create audit sink: %v", err)
+	}
+	audit := NewAuditLogger(sink)
+	router := gin.New()
+	admin := router.Group("/admin", audit.Middleware())
+	admin.GET("/audit", audit.HandleList)
+	admin.GET("/wal", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"records": 3}) })
+	admin.POST("/wal/clear", func(c *gin.Context) {
+		c.Set(ContextKeyAPIKeyID, "oncall")
+		c.JSON(http.StatusOK, gin.H{"cleared": 3})
+	})
+	admin.DELETE("/silences/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	return router
+}
+
+func auditRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.RemoteAddr = "10.9.9.9:4000"
+	req.Header.Set("User-Agent", "ops-cli/1.0")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuditLoggerRecordsAdminOperations(t *testing.T) {
+	router := setupAuditRouter(t)
+
+	auditRequest(router, "GET", "/admin/wal", "")
+	if w := auditRequest(router, "POST", "/admin/wal/clear?force=true", `{"reason":"disk full"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected the operation to run, got %d", w.Code)
+	}
+	auditRequest(router, "DELETE", "/admin/silences/42", "")
+
+	w := auditRequest(router, "GET", "/admin/audit?limit=10", "")
+	var response struct {
+		Entries  []models.AuditEntry `json:"entries"`
+		Recorded int64               `json:"recorded"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	// Reads are not audited
+	if len(response.Entries) != 2 || response.Recorded != 2 {
+		t.Fatalf("expected 2 audited operations, got %+v", response)
+	}
+
+	clear := response.Entries[0]
+	if clear.Route != "/admin/wal/clear" || clear.Params["force"] != "true" || clear.Body != `{"reason":"disk full"}` ||
+		clear.Status != http.StatusOK || clear.Result != `{"cleared":3}` {
+		t.Errorf("unexpected entry: %+v", clear)
+	}
+	if clear.Caller.IP != "10.9.9.9" || clear.Caller.APIKeyID != "oncall" || clear.Caller.UserAgent != "ops-cli/1.0" {
+		t.Errorf("unexpected caller: %+v", clear.Caller)
+	}
+	if deleted := response.Entries[1]; deleted.Params["id"] != "42" || deleted.Status != http.StatusNotFound {
+		t.Errorf("unexpected entry: %+v", deleted)
+	}
+}
+
+func TestAuditLoggerDisabled(t *testing.T) {
+	audit := NewAuditLogger(nil)
+	router := gin.New()
+	router.GET("/admin/audit", audit.HandleList)
+	router.POST("/admin/wal/clear", audit.Middleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	if w := auditRequest(router, "POST", "/admin/wal/clear", ""); w.Code != http.StatusOK {
+		t.Errorf("expected the operation to run unaudited, got %d", w.Code)
+	}
+	if w := auditRequest(router, "GET", "/admin/audit", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when disabled, got %d", w.Code)
+	}
+}
+
+// TestAuditLoggerRecordsAuthenticatedCaller tests that admin routes
+// authenticate before auditing, so entries carry the caller's API key
+func TestAuditLoggerRecordsAuthenticatedCaller(t *testing.T) {
+	sink, err := db.NewFileAuditSink(filepath.Join(t.TempDir(), "audit", "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("failed to create audit sink: %v", err)
+	}
+	auth, err := NewAuthenticator(AuthConfig{APIKeys: []APIKey{{ID: "oncall", Role: "operator", Key: "oncall-secret"}}})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	audit := NewAuditLogger(sink)
+	router := gin.New()
+	admin := router.Group("/admin", auth.Middleware(), audit.Middleware())
+	admin.GET("/audit", audit.HandleList)
+	admin.POST("/flush", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"flushed": 1}) })
+
+	if w := auditRequest(router, "POST", "/admin/flush", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without credentials, got %d", w.Code)
+	}
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-API-Key", "oncall-secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := request("POST", "/admin/flush"); w.Code != http.StatusOK {
+		t.Fatalf("expected the operation to run, got %d", w.Code)
+	}
+
+	var response struct {
+		Entries []models.AuditEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(request("GET", "/admin/audit").Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(response.Entries) != 1 {
+		t.Fatalf("expected only the authenticated operation audited, got %+v", response.Entries)
+	}
+	if caller := response.Entries[0].Caller; caller.APIKeyID != "oncall" || caller.Role != "operator" {
+		t.Errorf("expected the API key ID and role in the entry, got %+v", caller)
+	}
+}
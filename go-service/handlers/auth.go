@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKey is a static key a client presents in X-API-Key or as a bearer token
+type APIKey struct {
+	// ID identifies the key in logs, metrics and query limit overrides; the
+	// key itself is never logged
+	ID   string
+	Role string
+	Key  string
+	// Satellites are the satellite ID patterns the key may write telemetry
+	// for; none allows every satellite
+	Satellites []string
+}
+
+// ClientCert authenticates callers whose verified mTLS client certificate
+// (see ClientIdentity.CertSubject) has Subject, as a key would
+type ClientCert struct {
+	ID      string
+	Role    string
+	Subject string
+	// Satellites are the satellite ID patterns the caller may write
+	// telemetry for; none allows every satellite
+	Satellites []string
+}
+
+// AuthConfig configures authentication of the telemetry endpoints
+type AuthConfig struct {
+	APIKeys []APIKey
+	// ClientCerts are accepted when a request carries no API key or token
+	ClientCerts []ClientCert
+	// JWTSecret verifies HS256 bearer tokens; the "sub" claim identifies the
+	// caller, "role" its role and "satellites" the satellite ID patterns it
+	// may write. Empty disables JWT authentication.
+	JWTSecret string
+	// JWTIssuer and JWTAudience, when set, must match the "iss" and "aud" claims
+	JWTIssuer   string
+	JWTAudience string
+	// AdminRoles are the roles allowed on the admin routes
+	AdminRoles []string
+}
+
+// Authenticator checks API keys, JWT bearer tokens and verified client
+// certificates on the routes it guards and stores the caller's key ID and role in the gin context, where
+// query guardrails and later authorization pick them up. Without keys or a
+// JWT secret it lets every request through, so existing deployments keep
+// working until credentials are configured.
+type Authenticator struct {
+	keys       map[[sha256.Size]byte]authCaller // by key hash, so lookups don't leak key prefixes
+	certs      map[string]authCaller            // by verified certificate subject
+	jwtSecret  []byte
+	issuer     string
+	audience   string
+	adminRoles map[string]bool
+	now        func() time.Time
+
+	mu       sync.Mutex
+	callers  map[string]*AuthCallerStats // by key ID or JWT subject
+	failures map[string]int64            // by reason
+}
+
+// AuthCallerStats counts the requests of one API key or JWT subject
+type AuthCallerStats struct {
+	Method   string    `json:"method"`
+	Requests int64     `json:"requests"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// AuthStats reports authenticated callers and rejected requests since startup
+type AuthStats struct {
+	Enabled  bool                       `json:"enabled"`
+	Callers  map[string]AuthCallerStats `json:"callers"`
+	Failures map[string]int64           `json:"failures"`
+	Keys     []string                   `json:"keys"`
+}
+
+// authCaller is an authenticated API key or JWT subject
+type authCaller struct {
+	id     string
+	role   string
+	method string
+	scope  *SatelliteScope
+}
+
+// Reasons a request is rejected, used as failure metric labels
+const (
+	authFailureMissing = "missing_credentials"
+	authFailureKey     = "invalid_api_key"
+	authFailureToken   = "invalid_token"
+	authFailureExpired = "expired_token"
+	authFailureRole    = "insufficient_role"
+)
+
+// NewAuthenticator creates an authenticator; duplicate key IDs or keys are
+// rejected so metrics and overrides can't be attributed to the wrong caller
+func NewAuthenticator(cfg AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		keys:       make(map[[sha256.Size]byte]authCaller, len(cfg.APIKeys)),
+		certs:      make(map[string]authCaller, len(cfg.ClientCerts)),
+		issuer:     cfg.JWTIssuer,
+		audience:   cfg.JWTAudience,
+		adminRoles: make(map[string]bool, len(cfg.AdminRoles)),
+		now:        time.Now,
+		callers:    make(map[string]*AuthCallerStats),
+		failures:   make(map[string]int64),
+	}
+	for _, role := range cfg.AdminRoles {
+		a.adminRoles[role] = true
+	}
+	if cfg.JWTSecret != "" {
+		a.jwtSecret = []byte(cfg.JWTSecret)
+	}
+	ids := make(map[string]bool, len(cfg.APIKeys))
+	for _, key := range cfg.APIKeys {
+		if key.ID == "" || key.Key == "" {
+			return nil, errors.New("API keys need an ID and a key")
+		}
+		if ids[key.ID] {
+			return nil, fmt.Errorf("duplicate API key ID %q", key.ID)
+		}
+		hash := sha256.Sum256([]byte(key.Key))
+		if _, ok := a.keys[hash]; ok {
+			return nil, fmt.Errorf("API key %q reuses the key of another ID", key.ID)
+		}
+		scope, err := NewSatelliteScope("API key "+key.ID, key.Satellites)
+		if err != nil {
+			return nil, err
+		}
+		ids[key.ID] = true
+		a.keys[hash] = authCaller{id: key.ID, role: key.Role, method: "api_key", scope: scope}
+	}
+	for _, cert := range cfg.ClientCerts {
+		if cert.ID == "" || cert.Subject == "" {
+			return nil, errors.New("client certificates need an ID and a subject")
+		}
+		if ids[cert.ID] {
+			return nil, fmt.Errorf("duplicate API key ID %q", cert.ID)
+		}
+		if _, ok := a.certs[cert.Subject]; ok {
+			return nil, fmt.Errorf("client certificate %q reuses the subject of another ID", cert.ID)
+		}
+		scope, err := NewSatelliteScope("client certificate "+cert.ID, cert.Satellites)
+		if err != nil {
+			return nil, err
+		}
+		ids[cert.ID] = true
+		a.certs[cert.Subject] = authCaller{id: cert.ID, role: cert.Role, method: "client_cert", scope: scope}
+	}
+	return a, nil
+}
+
+// Enabled reports whether any credentials are configured
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (len(a.keys) > 0 || len(a.certs) > 0 || a.jwtSecret != nil)
+}
+
+// Middleware rejects requests without valid credentials with 401
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.Enabled() {
+			c.Next()
+			return
+		}
+
+		caller, reason := a.authenticate(c)
+		if reason != "" {
+			a.recordFailure(reason)
+			identity := ClientIdentityFrom(c)
+			log.Printf("AUTH: rejected %s %s from %s: %s", c.Request.Method, c.FullPath(), identity.IP, reason)
+			c.Header("WWW-Authenticate", `Bearer realm="orbitstream"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required", "reason": reason})
+			return
+		}
+
+		if a.recordRequest(caller.id, caller.method) {
+			log.Printf("AUTH: first request from %s %s (role %q, satellites %v) since startup",
+				caller.method, caller.id, caller.role, caller.scope.Patterns())
+		}
+		c.Set(ContextKeyAPIKeyID, caller.id)
+		if caller.role != "" {
+			c.Set(ContextKeyRole, caller.role)
+		}
+		if caller.scope != nil {
+			c.Set(ContextKeySatelliteScope, caller.scope)
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin rejects authenticated callers without an admin role with
+// 403. It must run after Middleware; without credentials configured it lets
+// every request through like Middleware does.
+func (a *Authenticator) RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.Enabled() {
+			c.Next()
+			return
+		}
+
+		role := c.GetString(ContextKeyRole)
+		if !a.adminRoles[role] {
+			a.recordFailure(authFailureRole)
+			log.Printf("AUTH: rejected %s %s from %s: role %q is not an admin role",
+				c.Request.Method, c.FullPath(), c.GetString(ContextKeyAPIKeyID), role)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin role required", "reason": authFailureRole})
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticate resolves the caller from X-API-Key or the Authorization
+// bearer token, which is either an API key or a JWT, or without either from
+// the verified client certificate subject. On failure it returns the reason
+// instead.
+func (a *Authenticator) authenticate(c *gin.Context) (authCaller, string) {
+	credential := c.GetHeader("X-API-Key")
+	if credential == "" {
+		if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			credential = strings.TrimSpace(bearer)
+		}
+	}
+	if credential == "" {
+		// Only set when the certificate was verified, by the server or a
+		// trusted proxy
+		if caller, ok := a.certs[ClientIdentityFrom(c).CertSubject]; ok {
+			return caller, ""
+		}
+		return authCaller{}, authFailureMissing
+	}
+
+	if caller, ok := a.keys[sha256.Sum256([]byte(credential))]; ok {
+		return caller, ""
+	}
+	if a.jwtSecret != nil && strings.Count(credential, ".") == 2 {
+		claims, err := a.verifyJWT(credential)
+		if errors.Is(err, errTokenExpired) {
+			return authCaller{}, authFailureExpired
+		}
+		if err != nil {
+			return authCaller{}, authFailureToken
+		}
+		scope, err := NewSatelliteScope("token subject "+claims.Subject, claims.Satellites)
+		if err != nil {
+			return authCaller{}, authFailureToken
+		}
+		return authCaller{id: claims.Subject, role: claims.Role, method: "jwt", scope: scope}, ""
+	}
+	return authCaller{}, authFailureKey
+}
+
+// jwtClaims are the registered and custom claims the authenticator reads
+type jwtClaims struct {
+	Subject string `json:"sub"`
+	Role    string `json:"role"`
+	// Satellite ID patterns the subject may write; none allows every satellite
+	Satellites []string        `json:"satellites"`
+	Issuer     string          `json:"iss"`
+	Audience   json.RawMessage `json:"aud"`
+	ExpiresAt  *float64        `json:"exp"`
+	NotBefore  *float64        `json:"nbf"`
+}
+
+var errTokenExpired = errors.New("token expired")
+
+// verifyJWT checks an HS256 token's signature and its time, issuer and
+// audience claims
+func (a *Authenticator) verifyJWT(token string) (jwtClaims, error) {
+	var claims jwtClaims
+	parts := strings.Split(token, ".")
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return claims, fmt.Errorf("invalid token header: %w", err)
+	}
+	var head struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(header, &head); err != nil || head.Alg != "HS256" {
+		return claims, fmt.Errorf("unsupported token algorithm %q", head.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, fmt.Errorf("invalid token signature: %w", err)
+	}
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return claims, errors.New("token signature mismatch")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, fmt.Errorf("invalid token payload: %w", err)
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, fmt.Errorf("invalid token claims: %w", err)
+	}
+	if claims.Subject == "" {
+		return claims, errors.New("token has no subject")
+	}
+
+	now := float64(a.now().Unix())
+	if claims.ExpiresAt != nil && now >= *claims.ExpiresAt {
+		return claims, errTokenExpired
+	}
+	if claims.NotBefore != nil && now < *claims.NotBefore {
+		return claims, errors.New("token not valid yet")
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return claims, fmt.Errorf("unexpected token issuer %q", claims.Issuer)
+	}
+	if a.audience != "" && !audienceContains(claims.Audience, a.audience) {
+		return claims, errors.New("token not issued for this audience")
+	}
+	return claims, nil
+}
+
+// audienceContains reports whether the "aud" claim, a string or a list of
+// strings, names audience
+func audienceContains(raw json.RawMessage, audience string) bool {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return single == audience
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		for _, entry := range list {
+			if entry == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recordRequest counts a request of caller id and reports whether it is the
+// caller's first since startup
+func (a *Authenticator) recordRequest(id, method string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stats, ok := a.callers[id]
+	if !ok {
+		stats = &AuthCallerStats{Method: method}
+		a.callers[id] = stats
+	}
+	stats.Requests++
+	stats.LastSeen = a.now().UTC()
+	return !ok
+}
+
+func (a *Authenticator) recordFailure(reason string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failures[reason]++
+}
+
+// Stats returns request counts per caller and rejections per reason
+func (a *Authenticator) Stats() AuthStats {
+	stats := AuthStats{
+		Enabled:  a.Enabled(),
+		Callers:  make(map[string]AuthCallerStats),
+		Failures: make(map[string]int64),
+		Keys:     []string{},
+	}
+	if a == nil {
+		return stats
+	}
+	for _, key := range a.keys {
+		stats.Keys = append(stats.Keys, key.id)
+	}
+	for _, cert := range a.certs {
+		stats.Keys = append(stats.Keys, cert.id)
+	}
+	sort.Strings(stats.Keys)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, caller := range a.callers {
+		stats.Callers[id] = *caller
+	}
+	for reason, count := range a.failures {
+		stats.Failures[reason] = count
+	}
+	return stats
+}
+
+// HandleStats returns authentication metrics per caller
+// GET /metrics/auth
+func (a *Authenticator) HandleStats(c *gin.Context) {
+	c.JSON(http.StatusOK, a.Stats())
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// signTestJWT creates an HS256 token with claims
+func signTestJWT(t *testing.T, secret string, claims map[string]interface{}) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("failed to marshal claims: %v", err)
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func setupAuthRouter(t *testing.T, cfg AuthConfig) (*gin.Engine, *Authenticator) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	auth, err := NewAuthenticator(cfg)
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	router := gin.New()
+	router.POST("/telemetry", auth.Middleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"key_id": c.GetString(ContextKeyAPIKeyID), "role": c.GetString(ContextKeyRole)})
+	})
+	return router, auth
+}
+
+func authRequest(router *gin.Engine, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/telemetry", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthAPIKeys(t *testing.T) {
+	router, auth := setupAuthRouter(t, AuthConfig{APIKeys: []APIKey{
+		{ID: "station-a", Role: "ingest", Key: "secret-a"},
+		{ID: "dashboard", Key: "secret-b"},
+	}})
+
+	w := authRequest(router, map[string]string{"X-API-Key": "secret-a"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var caller map[string]string
+	json.Unmarshal(w.Body.Bytes(), &caller)
+	if caller["key_id"] != "station-a" || caller["role"] != "ingest" {
+		t.Errorf("expected station-a with role ingest, got %v", caller)
+	}
+	if w := authRequest(router, map[string]string{"Authorization": "Bearer secret-b"}); w.Code != http.StatusOK {
+		t.Errorf("expected a bearer API key to be accepted, got %d", w.Code)
+	}
+
+	if w := authRequest(router, nil); w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("expected 401 with WWW-Authenticate without credentials, got %d", w.Code)
+	}
+	if w := authRequest(router, map[string]string{"X-API-Key": "secret-c"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an unknown key, got %d", w.Code)
+	}
+
+	stats := auth.Stats()
+	if stats.Callers["station-a"].Requests != 1 || stats.Callers["dashboard"].Requests != 1 {
+		t.Errorf("expected one request per key, got %+v", stats.Callers)
+	}
+	if stats.Failures[authFailureMissing] != 1 || stats.Failures[authFailureKey] != 1 {
+		t.Errorf("expected one failure per reason, got %v", stats.Failures)
+	}
+	if len(stats.Keys) != 2 || stats.Keys[0] != "dashboard" {
+		t.Errorf("expected the sorted key IDs, got %v", stats.Keys)
+	}
+}
+
+func TestAuthJWT(t *testing.T) {
+	const secret = "jwt-secret"
+	router, auth := setupAuthRouter(t, AuthConfig{JWTSecret: secret, JWTIssuer: "mission-control", JWTAudience: "orbitstream"})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	auth.now = func() time.Time { return now }
+	valid := map[string]interface{}{
+		"sub": "team-leo", "role": "analyst", "iss": "mission-control",
+		"aud": []string{"orbitstream"}, "exp": now.Add(time.Hour).Unix(),
+	}
+
+	w := authRequest(router, map[string]string{"Authorization": "Bearer " + signTestJWT(t, secret, valid)})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var caller map[string]string
+	json.Unmarshal(w.Body.Bytes(), &caller)
+	if caller["key_id"] != "team-leo" || caller["role"] != "analyst" {
+		t.Errorf("expected team-leo with role analyst, got %v", caller)
+	}
+
+	cases := []struct {
+		name   string
+		token  string
+		reason string
+	}{
+		{"wrong secret", signTestJWT(t, "other", valid), authFailureToken},
+		{"expired", signTestJWT(t, secret, withClaim(valid, "exp", now.Add(-time.Minute).Unix())), authFailureExpired},
+		{"not yet valid", signTestJWT(t, secret, withClaim(valid, "nbf", now.Add(time.Minute).Unix())), authFailureToken},
+		{"wrong issuer", signTestJWT(t, secret, withClaim(valid, "iss", "elsewhere")), authFailureToken},
+		{"wrong audience", signTestJWT(t, secret, withClaim(valid, "aud", "grafana")), authFailureToken},
+		{"no subject", signTestJWT(t, secret, withClaim(valid, "sub", "")), authFailureToken},
+	}
+	for _, tc := range cases {
+		w := authRequest(router, map[string]string{"Authorization": "Bearer " + tc.token})
+		var body map[string]string
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusUnauthorized || body["reason"] != tc.reason {
+			t.Errorf("%s: expected 401 %s, got %d %v", tc.name, tc.reason, w.Code, body)
+		}
+	}
+}
+
+func withClaim(claims map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(claims))
+	for k, v := range claims {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+func TestAuthDisabledWithoutCredentials(t *testing.T) {
+	router, auth := setupAuthRouter(t, AuthConfig{})
+	if auth.Enabled() {
+		t.Error("expected authentication to be disabled")
+	}
+	if w := authRequest(router, nil); w.Code != http.StatusOK {
+		t.Errorf("expected open access, got %d", w.Code)
+	}
+}
+
+func TestNewAuthenticatorRejectsDuplicates(t *testing.T) {
+	if _, err := NewAuthenticator(AuthConfig{APIKeys: []APIKey{{ID: "a", Key: "x"}, {ID: "a", Key: "y"}}}); err == nil {
+		t.Error("expected an error for a duplicate key ID")
+	}
+	if _, err := NewAuthenticator(AuthConfig{APIKeys: []APIKey{{ID: "a", Key: "x"}, {ID: "b", Key: "x"}}}); err == nil {
+		t.Error("expected an error for a reused key")
+	}
+}
+
+func TestAuthRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth, err := NewAuthenticator(AuthConfig{
+		APIKeys: []APIKey{
+			{ID: "oncall", Role: "admin", Key: "secret-admin"},
+			{ID: "station-a", Role: "ingest", Key: "secret-a"},
+			{ID: "dashboard", Key: "secret-b"},
+		},
+		AdminRoles: []string{"admin"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	router := gin.New()
+	admin := router.Group("/admin", auth.Middleware(), auth.RequireAdmin())
+	admin.POST("/wal/clear", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"cleared": true}) })
+	clear := func(key string) int {
+		req, _ := http.NewRequest("POST", "/admin/wal/clear", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := clear("secret-admin"); code != http.StatusOK {
+		t.Errorf("expected an admin key to be allowed, got %d", code)
+	}
+	for _, key := range []string{"secret-a", "secret-b"} {
+		if code := clear(key); code != http.StatusForbidden {
+			t.Errorf("expected 403 for a non-admin key, got %d", code)
+		}
+	}
+	if code := clear(""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without credentials, got %d", code)
+	}
+	if failures := auth.Stats().Failures[authFailureRole]; failures != 2 {
+		t.Errorf("expected 2 role failures, got %d", failures)
+	}
+}
+
+func TestAuthClientCerts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth, err := NewAuthenticator(AuthConfig{ClientCerts: []ClientCert{
+		{ID: "mission-ops", Role: "admin", Subject: "CN=mission-ops,O=Ground"},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	resolver, err := NewIdentityResolver(testIdentityConfig())
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+	router := gin.New()
+	router.Use(resolver.Middleware())
+	router.POST("/telemetry", auth.Middleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"key_id": c.GetString(ContextKeyAPIKeyID), "role": c.GetString(ContextKeyRole)})
+	})
+	request := func(remoteAddr, subject string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/telemetry", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Client-Cert-Subject", subject)
+		req.Header.Set("X-Client-Cert-Verify", "SUCCESS")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("10.1.2.3:443", "CN=mission-ops,O=Ground")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected a verified subject to authenticate, got %d", w.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if body["key_id"] != "mission-ops" || body["role"] != "admin" {
+		t.Errorf("expected the certificate's ID and role, got %v", body)
+	}
+	if w := request("10.1.2.3:443", "CN=intruder"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an unknown subject, got %d", w.Code)
+	}
+	// Headers from an untrusted peer are not a verified certificate
+	if w := request("203.0.113.7:5555", "CN=mission-ops,O=Ground"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a spoofed subject, got %d", w.Code)
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeySatelliteScope holds the SatelliteScope of the authenticated caller
+const ContextKeySatelliteScope = "orbitstream.satellite_scope"
+
+// SatelliteScope is the set of satellite ID patterns a caller may write
+// telemetry for. Patterns use path.Match syntax ("LEO-*", "SAT-00??"); a
+// nil scope allows every satellite.
+type SatelliteScope struct {
+	caller   string
+	patterns []string
+}
+
+// NewSatelliteScope creates the scope of caller; invalid patterns are an
+// error so a typo can't silently lock a mission team out (or in)
+func NewSatelliteScope(caller string, patterns []string) (*SatelliteScope, error) {
+	scope := &SatelliteScope{caller: caller}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid satellite pattern %q for %s: %w", pattern, caller, err)
+		}
+		scope.patterns = append(scope.patterns, pattern)
+	}
+	if len(scope.patterns) == 0 {
+		return nil, nil
+	}
+	return scope, nil
+}
+
+// Allows reports whether the scope covers satelliteID
+func (s *SatelliteScope) Allows(satelliteID string) bool {
+	if s == nil {
+		return true
+	}
+	for _, pattern := range s.patterns {
+		if matched, _ := path.Match(pattern, satelliteID); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Patterns returns the satellite ID patterns of the scope
+func (s *SatelliteScope) Patterns() []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s.patterns...)
+}
+
+// denial describes why satelliteID is outside the scope, for the response
+func (s *SatelliteScope) denial(satelliteID string) string {
+	return fmt.Sprintf("%s is not authorized to write telemetry for satellite %q (allowed: %s)",
+		s.caller, satelliteID, strings.Join(s.patterns, ", "))
+}
+
+// SatelliteScopeFrom returns the scope stored by the authentication
+// middleware; nil (every satellite) when the caller is unrestricted or
+// authentication is off
+func SatelliteScopeFrom(c *gin.Context) *SatelliteScope {
+	if value, ok := c.Get(ContextKeySatelliteScope); ok {
+		if scope, ok := value.(*SatelliteScope); ok {
+			return scope
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/models"
+	"orbitstream/test"
+)
+
+func TestSatelliteScope(t *testing.T) {
+	scope, err := NewSatelliteScope("API key leo-team", []string{"LEO-*", " SAT-00?? "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for id, want := range map[string]bool{"LEO-0042": true, "SAT-0017": true, "SAT-0170": false, "GEO-0001": false} {
+		if got := scope.Allows(id); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", id, got, want)
+		}
+	}
+
+	if unrestricted, err := NewSatelliteScope("API key ops", nil); err != nil || unrestricted != nil || !unrestricted.Allows("GEO-0001") {
+		t.Errorf("expected no patterns to allow every satellite, got %v (%v)", unrestricted, err)
+	}
+	if _, err := NewSatelliteScope("API key typo", []string{"LEO-["}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+// setupScopedRouter authenticates with one API key restricted to LEO
+// satellites in front of the ingest routes
+func setupScopedRouter(t *testing.T, mockBP *test.MockBatchProcessor) *gin.Engine {
+	t.Helper()
+	auth, err := NewAuthenticator(AuthConfig{APIKeys: []APIKey{
+		{ID: "leo-team", Key: "leo-secret", Satellites: []string{"LEO-*"}},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	handler := NewTelemetryHandler(mockBP)
+	router := gin.New()
+	router.POST("/telemetry", auth.Middleware(), handler.HandleTelemetry)
+	router.POST("/telemetry/batch", auth.Middleware(), handler.HandleTelemetryBatch)
+	return router
+}
+
+func postScoped(router *gin.Engine, path string, body interface{}) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "leo-secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSatelliteScopeRejectsForeignPoint(t *testing.T) {
+	mockBP := test.NewMockBatchProcessor()
+	router := setupScopedRouter(t, mockBP)
+
+	if w := postScoped(router, "/telemetry", test.NewTestTelemetryPointWithSatelliteID("LEO-0001")); w.Code != http.StatusAccepted {
+		t.Errorf("expected 202 for an own satellite, got %d", w.Code)
+	}
+	if w := postScoped(router, "/telemetry", test.NewTestTelemetryPointWithSatelliteID("GEO-0001")); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a foreign satellite, got %d", w.Code)
+	}
+	if mockBP.GetAddCallCount() != 1 {
+		t.Errorf("expected only the own point to be added, got %d", mockBP.GetAddCallCount())
+	}
+}
+
+func TestSatelliteScopeRejectsBatchPerPoint(t *testing.T) {
+	mockBP := test.NewMockBatchProcessor()
+	router := setupScopedRouter(t, mockBP)
+
+	w := postScoped(router, "/telemetry/batch", []models.TelemetryPoint{
+		test.NewTestTelemetryPointWithSatelliteID("LEO-0001"),
+		test.NewTestTelemetryPointWithSatelliteID("GEO-0001"),
+		test.NewTestTelemetryPointWithSatelliteID("LEO-0002"),
+	})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.TelemetryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response.Count != 2 || mockBP.GetAddCallCount() != 2 {
+		t.Errorf("expected the 2 own points to be accepted, got count %d and %d adds", response.Count, mockBP.GetAddCallCount())
+	}
+	if len(response.Rejected) != 1 || response.Rejected[0].Index != 1 || response.Rejected[0].SatelliteID != "GEO-0001" {
+		t.Errorf("expected point 1 to be rejected, got %+v", response.Rejected)
+	}
+
+	w = postScoped(router, "/telemetry/batch", []models.TelemetryPoint{test.NewTestTelemetryPointWithSatelliteID("GEO-0002")})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 when every point is foreign, got %d", w.Code)
+	}
+}
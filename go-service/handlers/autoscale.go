@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+)
+
+// autoscaleMetrics are the signals selectable with ?metric=
+var autoscaleMetrics = map[string]func(s db.AutoscaleSignals) float64{
+	"buffer_occupancy_ratio":      func(s db.AutoscaleSignals) float64 { return s.BufferOccupancyRatio },
+	"flush_backlog_seconds":       func(s db.AutoscaleSignals) float64 { return s.FlushBacklogSeconds },
+	"wal_growth_bytes_per_second": func(s db.AutoscaleSignals) float64 { return s.WALGrowthBytesPerSecond },
+}
+
+// AutoscaleHandler serves ingestion pressure signals to external autoscalers
+type AutoscaleHandler struct {
+	monitor *db.AutoscaleMonitor
+}
+
+// NewAutoscaleHandler creates a handler reporting the signals of monitor
+func NewAutoscaleHandler(monitor *db.AutoscaleMonitor) *AutoscaleHandler {
+	return &AutoscaleHandler{monitor: monitor}
+}
+
+// HandleAutoscaling returns every autoscaling signal, or a single one as
+// {"metric": ..., "value": ...} with ?metric=, for KEDA's metrics-api scaler
+// GET /metrics/autoscaling?metric=
+func (h *AutoscaleHandler) HandleAutoscaling(c *gin.Context) {
+	signals := h.monitor.Signals()
+
+	metric := c.Query("metric")
+	if metric == "" {
+		c.JSON(http.StatusOK, signals)
+		return
+	}
+	value, ok := autoscaleMetrics[metric]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "metric must be buffer_occupancy_ratio, flush_backlog_seconds or wal_growth_bytes_per_second",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"metric": metric, "value": value(signals)})
+}
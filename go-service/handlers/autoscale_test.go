@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/test"
+)
+
+func TestHandleAutoscaling(t *testing.T) {
+	bp := newTestBatchProcessor()
+	bp.SetMaxBufferSize(100)
+	for i := 0; i < 25; i++ {
+		if err := bp.Add(test.NewTestTelemetryPoint()); err != nil {
+			t.Fatalf("failed to add point: %v", err)
+		}
+	}
+	router := gin.New()
+	router.GET("/metrics/autoscaling", NewAutoscaleHandler(db.NewAutoscaleMonitor(bp, time.Minute)).HandleAutoscaling)
+
+	req, _ := http.NewRequest("GET", "/metrics/autoscaling", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var signals db.AutoscaleSignals
+	if err := json.Unmarshal(w.Body.Bytes(), &signals); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if signals.BufferOccupancyRatio != 0.25 || signals.MaxBufferSize != 100 {
+		t.Errorf("unexpected signals %+v", signals)
+	}
+
+	req, _ = http.NewRequest("GET", "/metrics/autoscaling?metric=buffer_occupancy_ratio", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var single struct {
+		Metric string  `json:"metric"`
+		Value  float64 `json:"value"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &single); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if w.Code != http.StatusOK || single.Metric != "buffer_occupancy_ratio" || single.Value != 0.25 {
+		t.Errorf("unexpected single metric response %d %s", w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/metrics/autoscaling?metric=cpu", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown metric, got %d", w.Code)
+	}
+}
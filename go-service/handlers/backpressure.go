@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+)
+
+// backpressurePollInterval is how often a queued request rechecks the buffer
+const backpressurePollInterval = 20 * time.Millisecond
+
+// BackpressureReporter is implemented by batch processors that can ask
+// clients to slow down before their buffer is full
+type BackpressureReporter interface {
+	Backpressure() db.BackpressureState
+}
+
+// SetBackpressureQueue holds ingest requests for up to timeout while the
+// buffer is above its high-water mark, before answering 429 (0 answers
+// immediately). Short pass-window bursts then drain without a retry.
+func (h *TelemetryHandler) SetBackpressureQueue(timeout time.Duration) {
+	h.backpressureQueue = timeout
+}
+
+// admit waits out backpressure for up to the queue timeout and answers 429
+// with Retry-After when it persists. It reports whether to go on.
+func (h *TelemetryHandler) admit(c *gin.Context) bool {
+	reporter, ok := h.batchProcessor.(BackpressureReporter)
+	if !ok {
+		return true
+	}
+	state := reporter.Backpressure()
+	if !state.Active {
+		return true
+	}
+
+	if h.backpressureQueue > 0 {
+		timeout := time.NewTimer(h.backpressureQueue)
+		defer timeout.Stop()
+		poll := time.NewTicker(backpressurePollInterval)
+		defer poll.Stop()
+	wait:
+		for state.Active {
+			select {
+			case <-poll.C:
+				state = reporter.Backpressure()
+			case <-timeout.C:
+				break wait
+			case <-c.Request.Context().Done():
+				break wait
+			}
+		}
+		if !state.Active {
+			return true
+		}
+	}
+
+	seconds := int(state.RetryAfter / time.Second)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":               "ingest buffer above its high-water mark, retry later",
+		"retry_after_seconds": seconds,
+	})
+	return false
+}
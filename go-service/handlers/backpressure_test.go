@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+	"orbitstream/test"
+)
+
+func postJSON(router *gin.Engine, path string, body interface{}) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleTelemetryBackpressure(t *testing.T) {
+	mockBP := test.NewMockBatchProcessor()
+	mockBP.SetBackpressure(db.BackpressureState{Active: true, RetryAfter: 3 * time.Second})
+	router := setupTestRouter(NewTelemetryHandler(mockBP))
+
+	w := postJSON(router, "/telemetry", test.NewTestTelemetryPoint())
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "3" {
+		t.Errorf("expected Retry-After 3, got %q", got)
+	}
+	if mockBP.GetAddCallCount() != 0 {
+		t.Errorf("expected no points added, got %d", mockBP.GetAddCallCount())
+	}
+}
+
+func TestHandleTelemetryBatchBackpressure(t *testing.T) {
+	mockBP := test.NewMockBatchProcessor()
+	mockBP.SetBackpressure(db.BackpressureState{Active: true, RetryAfter: time.Second})
+	router := setupTestRouter(NewTelemetryHandler(mockBP))
+
+	points := []models.TelemetryPoint{test.NewTestTelemetryPoint(), test.NewTestTelemetryPoint()}
+	w := postJSON(router, "/telemetry/batch", points)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", w.Code)
+	}
+	if mockBP.GetAddCallCount() != 0 {
+		t.Errorf("expected the whole batch to be held back, got %d points added", mockBP.GetAddCallCount())
+	}
+}
+
+func TestHandleTelemetryBackpressureQueued(t *testing.T) {
+	mockBP := test.NewMockBatchProcessor()
+	mockBP.SetBackpressure(db.BackpressureState{Active: true, RetryAfter: time.Second})
+	handler := NewTelemetryHandler(mockBP)
+	handler.SetBackpressureQueue(2 * time.Second)
+	router := setupTestRouter(handler)
+
+	time.AfterFunc(50*time.Millisecond, func() { mockBP.SetBackpressure(db.BackpressureState{}) })
+	w := postJSON(router, "/telemetry", test.NewTestTelemetryPoint())
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected the queued request to be accepted, got %d", w.Code)
+	}
+	if mockBP.GetAddCallCount() != 1 {
+		t.Errorf("expected 1 point added, got %d", mockBP.GetAddCallCount())
+	}
+}
+
+func TestHandleTelemetryBackpressureQueueTimeout(t *testing.T) {
+	mockBP := test.NewMockBatchProcessor()
+	mockBP.SetBackpressure(db.BackpressureState{Active: true, RetryAfter: time.Second})
+	handler := NewTelemetryHandler(mockBP)
+	handler.SetBackpressureQueue(50 * time.Millisecond)
+	router := setupTestRouter(handler)
+
+	w := postJSON(router, "/telemetry", test.NewTestTelemetryPoint())
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 after the queue timeout, got %d", w.Code)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig configures cross-origin access for browser dashboards
+type CORSConfig struct {
+	// AllowedOrigins are exact origins (https://dash.example.com) or "*";
+	// none disables CORS
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// CORS answers preflight requests and adds CORS headers on the routes it is
+// applied to. It is scoped per route so admin and ingest endpoints are never
+// reachable from a browser on another origin.
+type CORS struct {
+	origins map[string]bool
+	any     bool
+	methods string
+	headers string
+	maxAge  string
+}
+
+// NewCORS creates a CORS policy; it is disabled when no origins are allowed
+func NewCORS(cfg CORSConfig) *CORS {
+	cors := &CORS{
+		origins: make(map[string]bool, len(cfg.AllowedOrigins)),
+		methods: strings.Join(cfg.AllowedMethods, ", "),
+		headers: strings.Join(cfg.AllowedHeaders, ", "),
+		maxAge:  strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			cors.any = true
+			continue
+		}
+		cors.origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	return cors
+}
+
+// Enabled reports whether any origin is allowed
+func (cors *CORS) Enabled() bool {
+	return cors != nil && (cors.any || len(cors.origins) > 0)
+}
+
+// Handle registers a GET route with CORS and answers its preflight
+func (cors *CORS) Handle(router gin.IRoutes, path string, handlers ...gin.HandlerFunc) {
+	if !cors.Enabled() {
+		router.GET(path, handlers...)
+		return
+	}
+	router.GET(path, append([]gin.HandlerFunc{cors.Middleware()}, handlers...)...)
+	router.OPTIONS(path, cors.Middleware())
+}
+
+// Middleware adds CORS headers for allowed origins and answers preflight
+// (OPTIONS) requests with 204. Requests from other origins get no CORS
+// headers, so the browser blocks the response.
+func (cors *CORS) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || !cors.Enabled() {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		if !cors.any && !cors.origins[origin] {
+			if c.Request.Method == http.MethodOptions {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if cors.any {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Access-Control-Allow-Methods", cors.methods)
+			if cors.headers != "" {
+				c.Header("Access-Control-Allow-Headers", cors.headers)
+			}
+			c.Header("Access-Control-Max-Age", cors.maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// SecurityHeadersConfig configures the security headers on every response
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge sets Strict-Transport-Security when positive; only enable
+	// it when the service is reached over HTTPS
+	HSTSMaxAge time.Duration
+}
+
+// SecurityHeaders sets the standard hardening headers for a JSON API: no
+// MIME sniffing, no framing, no referrer and a deny-all content policy
+func SecurityHeaders(cfg SecurityHeadersConfig) gin.HandlerFunc {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+	}
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "no-referrer")
+		header.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+		if hsts != "" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestCORSRouter(cors *CORS) *gin.Engine {
+	router := gin.New()
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) }
+	cors.Handle(router, "/stats", ok)
+	router.GET("/admin/pending", ok)
+	return router
+}
+
+func doCORS(router *gin.Engine, method, path, origin string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Origin", origin)
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func newTestCORS(origins ...string) *CORS {
+	return NewCORS(CORSConfig{
+		AllowedOrigins: origins,
+		AllowedMethods: []string{"GET", "OPTIONS"},
+		AllowedHeaders: []string{"Authorization"},
+		MaxAge:         10 * time.Minute,
+	})
+}
+
+func TestCORSAllowedOrigin(t *testing.T) {
+	router := newTestCORSRouter(newTestCORS("https://dash.example.com"))
+
+	w := doCORS(router, http.MethodGet, "/stats", "https://dash.example.com")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://dash.example.com" {
+		t.Errorf("expected the origin to be allowed, got %q", got)
+	}
+	if got := w.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("expected Vary: Origin, got %q", got)
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	router := newTestCORSRouter(newTestCORS("https://dash.example.com"))
+
+	w := doCORS(router, http.MethodOptions, "/stats", "https://dash.example.com")
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, OPTIONS" {
+		t.Errorf("unexpected allowed methods %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Authorization" {
+		t.Errorf("unexpected allowed headers %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("expected max age 600, got %q", got)
+	}
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	router := newTestCORSRouter(newTestCORS("https://dash.example.com"))
+
+	w := doCORS(router, http.MethodGet, "/stats", "https://evil.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no CORS headers, got %q", got)
+	}
+
+	w = doCORS(router, http.MethodOptions, "/stats", "https://evil.example.com")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected preflight status 403, got %d", w.Code)
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	router := newTestCORSRouter(newTestCORS("*"))
+
+	w := doCORS(router, http.MethodGet, "/stats", "https://anywhere.example.com")
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected a wildcard origin, got %q", got)
+	}
+}
+
+func TestCORSNotOnAdminRoutes(t *testing.T) {
+	router := newTestCORSRouter(newTestCORS("*"))
+
+	w := doCORS(router, http.MethodGet, "/admin/pending", "https://dash.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no CORS headers on admin routes, got %q", got)
+	}
+
+	w = doCORS(router, http.MethodOptions, "/admin/pending", "https://dash.example.com")
+	if w.Code == http.StatusNoContent {
+		t.Error("expected no preflight on admin routes")
+	}
+}
+
+func TestCORSDisabled(t *testing.T) {
+	router := newTestCORSRouter(newTestCORS())
+
+	w := doCORS(router, http.MethodGet, "/stats", "https://dash.example.com")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no CORS headers when disabled, got %q", got)
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	router := gin.New()
+	router.Use(SecurityHeaders(SecurityHeadersConfig{HSTSMaxAge: 24 * time.Hour}))
+	router.GET("/stats", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+
+	w := doGet(router, "/stats")
+
+	expected := map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "no-referrer",
+		"Content-Security-Policy":   "default-src 'none'; frame-ancestors 'none'",
+		"Strict-Transport-Security": "max-age=86400",
+	}
+	for header, value := range expected {
+		if got := w.Header().Get(header); got != value {
+			t.Errorf("expected %s %q, got %q", header, value, got)
+		}
+	}
+}
+
+func TestSecurityHeadersWithoutHSTS(t *testing.T) {
+	router := gin.New()
+	router.Use(SecurityHeaders(SecurityHeadersConfig{}))
+	router.GET("/stats", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+
+	w := doGet(router, "/stats")
+
+	if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("expected no HSTS header, got %q", got)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+)
+
+// EpisodeHandler serves anomaly episode history for MTTR reporting
+type EpisodeHandler struct {
+	tracker *db.EpisodeTracker
+}
+
+// NewEpisodeHandler creates a handler reporting the episodes of tracker
+func NewEpisodeHandler(tracker *db.EpisodeTracker) *EpisodeHandler {
+	return &EpisodeHandler{tracker: tracker}
+}
+
+// HandleEpisodes lists open episodes and the most recently closed ones, with
+// open/closed counts and the mean time to recovery
+// GET /anomalies/episodes?satellite_id=&state=open|closed&limit=100
+func (h *EpisodeHandler) HandleEpisodes(c *gin.Context) {
+	satelliteID := c.Query("satellite_id")
+	state := c.Query("state")
+	if state != "" && state != "open" && state != "closed" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "state must be open or closed"})
+		return
+	}
+	limit := 100
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+
+	response := gin.H{"stats": h.tracker.Stats(satelliteID)}
+	if state != "closed" {
+		response["open"] = h.tracker.Open(satelliteID)
+	}
+	if state != "open" {
+		response["closed"] = h.tracker.History(satelliteID, limit)
+	}
+	c.JSON(http.StatusOK, response)
+}
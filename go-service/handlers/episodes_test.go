@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/test"
+)
+
+func TestHandleEpisodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tracker := db.NewEpisodeTracker(10)
+	bp := newTestBatchProcessor()
+	bp.SetEpisodeTracker(tracker)
+
+	low := test.NewTestTelemetryPointWithBattery(5)
+	if err := bp.Add(low); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/anomalies/episodes", NewEpisodeHandler(tracker).HandleEpisodes)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/anomalies/episodes?satellite_id="+low.SatelliteID, nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Open   []json.RawMessage `json:"open"`
+		Closed []json.RawMessage `json:"closed"`
+		Stats  db.EpisodeStats   `json:"stats"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(response.Open) != 1 || len(response.Closed) != 0 || response.Stats.Open != 1 {
+		t.Errorf("expected one open episode, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/anomalies/episodes?state=resolved", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown state, got %d", w.Code)
+	}
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// TelemetryExporter streams raw telemetry for bulk exports
+// This allows for mocking in tests
+type TelemetryExporter interface {
+	ExportTelemetry(ctx context.Context, q db.TelemetryQuery, cursor db.ExportCursor, fn func(models.TelemetryPoint) error) error
+}
+
+// Resume token errors
+var (
+	ErrResumeTokenInvalid = errors.New("resume token is invalid")
+	ErrResumeTokenExpired = errors.New("resume token has expired")
+)
+
+// ExportToken is the state carried by a resume token
+type ExportToken struct {
+	SatelliteID string    `json:"sat"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	After       time.Time `json:"after,omitempty"`
+	Skip        int       `json:"skip,omitempty"`
+	ExpiresAt   time.Time `json:"exp"`
+}
+
+// ResumeTokenSigner issues and verifies HMAC-signed export resume tokens
+type ResumeTokenSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewResumeTokenSigner creates a signer; tokens are valid for ttl after issue.
+// With an empty secret a random one is generated, so tokens do not survive a
+// restart or work across instances.
+func NewResumeTokenSigner(secret string, ttl time.Duration) *ResumeTokenSigner {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatalf("failed to generate export token secret: %v", err)
+		}
+		log.Printf("WARNING: EXPORT_TOKEN_SECRET not set, resume tokens are only valid on this instance until restart")
+	}
+	return &ResumeTokenSigner{secret: key, ttl: ttl}
+}
+
+// Issue encodes the token, stamping a fresh expiry
+func (s *ResumeTokenSigner) Issue(token ExportToken) string {
+	token.ExpiresAt = time.Now().UTC().Add(s.ttl)
+	payload, _ := json.Marshal(token)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded))
+}
+
+// Verify decodes a token, checking its signature and expiry
+func (s *ResumeTokenSigner) Verify(raw string) (ExportToken, error) {
+	encoded, signature, ok := strings.Cut(raw, ".")
+	if !ok {
+		return ExportToken{}, ErrResumeTokenInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.sign(encoded)) {
+		return ExportToken{}, ErrResumeTokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ExportToken{}, ErrResumeTokenInvalid
+	}
+
+	var token ExportToken
+	if err := json.Unmarshal(payload, &token); err != nil {
+		return ExportToken{}, ErrResumeTokenInvalid
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return ExportToken{}, ErrResumeTokenExpired
+	}
+	return token, nil
+}
+
+func (s *ResumeTokenSigner) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// exportWriteTimeout bounds how long writing one checkpoint interval may take
+const exportWriteTimeout = 60 * time.Second
+
+// exportCheckpoint is a stream line carrying a token to resume after it
+type exportCheckpoint struct {
+	ResumeToken string `json:"resume_token"`
+	Rows        int    `json:"rows"`
+	Complete    bool   `json:"complete,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ExportHandler streams raw telemetry as NDJSON with resume checkpoints
+type ExportHandler struct {
+	exporter        TelemetryExporter
+	guardrails      *QueryGuardrails
+	signer          *ResumeTokenSigner
+	checkpointEvery int
+}
+
+// NewExportHandler creates an export handler emitting a checkpoint every
+// checkpointEvery rows
+func NewExportHandler(exporter TelemetryExporter, guardrails *QueryGuardrails, signer *ResumeTokenSigner, checkpointEvery int) *ExportHandler {
+	if checkpointEvery <= 0 {
+		checkpointEvery = 1000
+	}
+	return &ExportHandler{
+		exporter:        exporter,
+		guardrails:      guardrails,
+		signer:          signer,
+		checkpointEvery: checkpointEvery,
+	}
+}
+
+// HandleExport streams raw telemetry for one satellite in time order
+// GET /export?satellite_id=&from=&to=
+// GET /export?resume_token=
+//
+// The response is NDJSON: one telemetry point per line, interleaved every
+// N rows with {"resume_token": ..., "rows": ...} checkpoint lines. After a
+// dropped connection, clients resume from the last checkpoint they received;
+// rows after that checkpoint are sent again. The stream ends with a
+// checkpoint line carrying "complete": true.
+func (h *ExportHandler) HandleExport(c *gin.Context) {
+	var token ExportToken
+	if raw := c.Query("resume_token"); raw != "" {
+		verified, err := h.signer.Verify(raw)
+		if errors.Is(err, ErrResumeTokenExpired) {
+			c.JSON(http.StatusGone, gin.H{"error": err.Error(), "guidance": "Restart the export from 'from'."})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		token = verified
+	} else {
+		satelliteID := c.Query("satellite_id")
+		if satelliteID == "" || c.Query("from") == "" || c.Query("to") == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "satellite_id, from and to are required"})
+			return
+		}
+		window, ok := h.guardrails.Window(c, EndpointClassExport)
+		if !ok {
+			return
+		}
+		token = ExportToken{SatelliteID: satelliteID, From: window.From, To: window.To}
+	}
+
+	cursor := db.ExportCursor{After: token.After, Skip: token.Skip}
+	checkpoint := func() string {
+		next := token
+		next.After, next.Skip = cursor.After, cursor.Skip
+		return h.signer.Issue(next)
+	}
+
+	// Exports outlive the server's WriteTimeout; extend the deadline per checkpoint
+	controller := http.NewResponseController(c.Writer)
+	extendDeadline := func() {
+		_ = controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	}
+	extendDeadline()
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("X-Resume-Token", checkpoint())
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	rows := 0
+	err := h.exporter.ExportTelemetry(c.Request.Context(), db.TelemetryQuery{
+		SatelliteID: token.SatelliteID,
+		From:        token.From,
+		To:          token.To,
+	}, cursor, func(point models.TelemetryPoint) error {
+		if err := encoder.Encode(point); err != nil {
+			return err
+		}
+		cursor = cursor.Advance(point)
+		rows++
+		if rows%h.checkpointEvery == 0 {
+			if err := encoder.Encode(exportCheckpoint{ResumeToken: checkpoint(), Rows: rows}); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			extendDeadline()
+		}
+		return nil
+	})
+
+	final := exportCheckpoint{ResumeToken: checkpoint(), Rows: rows, Complete: err == nil}
+	if err != nil {
+		if errors.Is(c.Request.Context().Err(), context.Canceled) {
+			// Client went away; it resumes from its last checkpoint
+			return
+		}
+		if errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+			// Route timeout; the final token resumes where the stream stopped
+			final.Error = "export timed out, resume with resume_token"
+		} else {
+			final.Error = fmt.Sprintf("export interrupted: %v", err)
+		}
+	}
+	_ = encoder.Encode(final)
+	c.Writer.Flush()
+}
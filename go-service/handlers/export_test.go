@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/models"
+	"orbitstream/test"
+)
+
+func setupExportRouter(querier *test.MockTelemetryQuerier, signer *ResumeTokenSigner, checkpointEvery int) *gin.Engine {
+	guardrails := newTestGuardrails()
+	guardrails.SetDefault(EndpointClassExport, QueryLimits{MaxRange: 366 * 24 * time.Hour})
+
+	router := gin.New()
+	router.GET("/export", NewExportHandler(querier, guardrails, signer, checkpointEvery).HandleExport)
+	return router
+}
+
+// exportPoints returns n points one minute apart; points 2 and 3 share a timestamp
+func exportPoints(n int) []models.TelemetryPoint {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := make([]models.TelemetryPoint, n)
+	for i := range points {
+		points[i] = test.NewTestTelemetryPoint()
+		points[i].Timestamp = base.Add(time.Duration(i) * time.Minute)
+		points[i].BatteryChargePercent = float64(i)
+	}
+	if n > 3 {
+		points[3].Timestamp = points[2].Timestamp
+	}
+	return points
+}
+
+// parseExport splits an NDJSON export into points and checkpoint lines
+func parseExport(t *testing.T, body string) ([]models.TelemetryPoint, []exportCheckpoint) {
+	var points []models.TelemetryPoint
+	var checkpoints []exportCheckpoint
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if strings.Contains(string(line), `"resume_token"`) {
+			var cp exportCheckpoint
+			if err := json.Unmarshal(line, &cp); err != nil {
+				t.Fatalf("bad checkpoint line %s: %v", line, err)
+			}
+			checkpoints = append(checkpoints, cp)
+			continue
+		}
+		var p models.TelemetryPoint
+		if err := json.Unmarshal(line, &p); err != nil {
+			t.Fatalf("bad point line %s: %v", line, err)
+		}
+		points = append(points, p)
+	}
+	return points, checkpoints
+}
+
+func TestHandleExportStreamsWithCheckpoints(t *testing.T) {
+	querier := &test.MockTelemetryQuerier{Points: exportPoints(5)}
+	router := setupExportRouter(querier, NewResumeTokenSigner("secret", time.Hour), 2)
+
+	w := doGet(router, "/export?satellite_id=SAT-0001&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Resume-Token") == "" {
+		t.Error("expected initial X-Resume-Token header")
+	}
+
+	points, checkpoints := parseExport(t, w.Body.String())
+	if len(points) != 5 {
+		t.Errorf("expected 5 points, got %d", len(points))
+	}
+	// Checkpoints after rows 2 and 4, plus the final one
+	if len(checkpoints) != 3 {
+		t.Fatalf("expected 3 checkpoints, got %d", len(checkpoints))
+	}
+	if !checkpoints[2].Complete || checkpoints[2].Rows != 5 {
+		t.Errorf("expected final complete checkpoint with 5 rows, got %+v", checkpoints[2])
+	}
+}
+
+func TestHandleExportResumeFromCheckpoint(t *testing.T) {
+	all := exportPoints(5)
+	querier := &test.MockTelemetryQuerier{Points: all}
+	router := setupExportRouter(querier, NewResumeTokenSigner("secret", time.Hour), 3)
+
+	w := doGet(router, "/export?satellite_id=SAT-0001&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z")
+	_, checkpoints := parseExport(t, w.Body.String())
+
+	// Resume from the checkpoint after row 3; rows 2 and 3 share a
+	// timestamp, so the cursor must skip exactly the one already sent
+	resumed := doGet(router, "/export?resume_token="+url.QueryEscape(checkpoints[0].ResumeToken))
+	if resumed.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", resumed.Code, resumed.Body.String())
+	}
+
+	points, _ := parseExport(t, resumed.Body.String())
+	if len(points) != 2 {
+		t.Fatalf("expected 2 remaining points, got %d", len(points))
+	}
+	if points[0].BatteryChargePercent != 3 || points[1].BatteryChargePercent != 4 {
+		t.Errorf("expected rows 3 and 4, got %v and %v", points[0].BatteryChargePercent, points[1].BatteryChargePercent)
+	}
+	if querier.LastQuery.SatelliteID != "SAT-0001" {
+		t.Errorf("expected resumed export for SAT-0001, got %s", querier.LastQuery.SatelliteID)
+	}
+}
+
+func TestHandleExportInvalidAndExpiredTokens(t *testing.T) {
+	querier := &test.MockTelemetryQuerier{Points: exportPoints(2)}
+
+	w := doGet(setupExportRouter(querier, NewResumeTokenSigner("secret", time.Hour), 10), "/export?resume_token=forged.token")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for forged token, got %d", w.Code)
+	}
+
+	other := NewResumeTokenSigner("other-secret", time.Hour)
+	token := other.Issue(ExportToken{SatelliteID: "SAT-0001"})
+	w = doGet(setupExportRouter(querier, NewResumeTokenSigner("secret", time.Hour), 10), "/export?resume_token="+url.QueryEscape(token))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for token signed with another secret, got %d", w.Code)
+	}
+
+	expiring := NewResumeTokenSigner("secret", -time.Second)
+	token = expiring.Issue(ExportToken{SatelliteID: "SAT-0001"})
+	w = doGet(setupExportRouter(querier, expiring, 10), "/export?resume_token="+url.QueryEscape(token))
+	if w.Code != http.StatusGone {
+		t.Errorf("expected status 410 for expired token, got %d", w.Code)
+	}
+}
+
+func TestHandleExportRequiresRange(t *testing.T) {
+	router := setupExportRouter(&test.MockTelemetryQuerier{}, NewResumeTokenSigner("secret", time.Hour), 10)
+
+	w := doGet(router, "/export?satellite_id=SAT-0001")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without from/to, got %d", w.Code)
+	}
+
+	w = doGet(router, "/export?satellite_id=SAT-0001&from=2020-01-01T00:00:00Z&to=2024-01-01T00:00:00Z")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 beyond export max range, got %d", w.Code)
+	}
+}
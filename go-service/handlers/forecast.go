@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+)
+
+// ForecastHandler serves a satellite's projected time until a resource
+// runs out
+type ForecastHandler struct {
+	resource   string
+	forecaster *db.Forecaster
+}
+
+// NewForecastHandler creates a forecast handler for the resource;
+// forecaster is nil when forecasting it is disabled
+func NewForecastHandler(resource string, forecaster *db.Forecaster) *ForecastHandler {
+	return &ForecastHandler{resource: resource, forecaster: forecaster}
+}
+
+// HandleForecast returns the satellite's recent trend and when it reaches
+// its limit (hours_to_limit and limit_at are null when it moves away from
+// it)
+// GET /satellites/:id/battery/forecast, GET /satellites/:id/storage/forecast
+func (h *ForecastHandler) HandleForecast(c *gin.Context) {
+	if h.forecaster == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": h.resource + " forecasting is disabled"})
+		return
+	}
+	satelliteID := c.Param("id")
+
+	forecast, err := h.forecaster.Forecast(c.Request.Context(), satelliteID)
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+	if forecast == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("not enough recent %s telemetry to forecast %s", h.resource, satelliteID)})
+		return
+	}
+	c.JSON(http.StatusOK, forecast)
+}
+
+// HandleFleetForecast returns every satellite's forecast, the soonest to
+// reach its limit first, e.g. to prioritize downlinks of satellites about to
+// fill their storage; within keeps those reaching it within that duration
+// GET /fleet/storage/forecast?within=24h
+func (h *ForecastHandler) HandleFleetForecast(c *gin.Context) {
+	if h.forecaster == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": h.resource + " forecasting is disabled"})
+		return
+	}
+	var within time.Duration
+	if value := c.Query("within"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "within must be a positive duration like 24h"})
+			return
+		}
+		within = parsed
+	}
+
+	forecasts, err := h.forecaster.ForecastFleet(c.Request.Context())
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+	if within > 0 {
+		kept := forecasts[:0]
+		for _, forecast := range forecasts {
+			if forecast.HoursToLimit != nil && *forecast.HoursToLimit <= within.Hours() {
+				kept = append(kept, forecast)
+			}
+		}
+		forecasts = kept
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"forecasts": forecasts,
+		"count":     len(forecasts),
+	})
+}
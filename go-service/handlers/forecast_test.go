@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+)
+
+// fakeResourceTrendQuerier returns canned trends for the satellite asked for,
+// or all of them for the fleet
+type fakeResourceTrendQuerier struct {
+	trends []db.ResourceTrend
+}
+
+func (f *fakeResourceTrendQuerier) ResourceTrends(ctx context.Context, resource string, since time.Time, minSamples int, satelliteID string) ([]db.ResourceTrend, error) {
+	var trends []db.ResourceTrend
+	for _, trend := range f.trends {
+		if satelliteID == "" || trend.SatelliteID == satelliteID {
+			trends = append(trends, trend)
+		}
+	}
+	return trends, nil
+}
+
+func TestHandleForecast(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	querier := &fakeResourceTrendQuerier{trends: []db.ResourceTrend{
+		{SatelliteID: "SAT-0001", Samples: 72, To: time.Now().UTC(), SlopePerHour: -4, FittedValue: 60, LatestValue: 61, R2: 0.8},
+	}}
+	forecaster := db.NewBatteryForecaster(querier, nil, db.ForecastConfig{Limit: 20})
+	get := func(handler *ForecastHandler, satelliteID string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/satellites/:id/battery/forecast", handler.HandleForecast)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/satellites/"+satelliteID+"/battery/forecast", nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(NewForecastHandler(db.ResourceBattery, forecaster), "SAT-0001")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var forecast struct {
+		SatelliteID  string   `json:"satellite_id"`
+		Resource     string   `json:"resource"`
+		Limit        float64  `json:"limit"`
+		HoursToLimit *float64 `json:"hours_to_limit"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &forecast); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if forecast.SatelliteID != "SAT-0001" || forecast.Resource != "battery" || forecast.Limit != 20 {
+		t.Errorf("unexpected forecast %s", w.Body.String())
+	}
+	if forecast.HoursToLimit == nil || *forecast.HoursToLimit < 9.9 || *forecast.HoursToLimit > 10 {
+		t.Errorf("expected about 10h to the limit, got %v", forecast.HoursToLimit)
+	}
+
+	if w := get(NewForecastHandler(db.ResourceBattery, forecaster), "SAT-0002"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without enough telemetry, got %d", w.Code)
+	}
+	if w := get(NewForecastHandler(db.ResourceBattery, nil), "SAT-0001"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when disabled, got %d", w.Code)
+	}
+}
+
+func TestHandleFleetForecast(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	to := time.Now().UTC()
+	querier := &fakeResourceTrendQuerier{trends: []db.ResourceTrend{
+		{SatelliteID: "SAT-0001", To: to, SlopePerHour: 100, FittedValue: 90000},
+		{SatelliteID: "SAT-0002", To: to, SlopePerHour: 1000, FittedValue: 90000},
+		{SatelliteID: "SAT-0003", To: to, SlopePerHour: -100, FittedValue: 90000},
+	}}
+	handler := NewForecastHandler(db.ResourceStorage, db.NewStorageForecaster(querier, nil, db.ForecastConfig{Limit: 95000}))
+	router := gin.New()
+	router.GET("/fleet/storage/forecast", handler.HandleFleetForecast)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/fleet/storage/forecast"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) []string {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var body struct {
+			Forecasts []struct {
+				SatelliteID string `json:"satellite_id"`
+			}
+			Count int
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		var ids []string
+		for _, forecast := range body.Forecasts {
+			ids = append(ids, forecast.SatelliteID)
+		}
+		if body.Count != len(ids) {
+			t.Errorf("count %d doesn't match %d forecasts", body.Count, len(ids))
+		}
+		return ids
+	}
+
+	if ids := decode(get("")); len(ids) != 3 || ids[0] != "SAT-0002" || ids[1] != "SAT-0001" || ids[2] != "SAT-0003" {
+		t.Errorf("expected the soonest to fill first, got %v", ids)
+	}
+	if ids := decode(get("?within=24h")); len(ids) != 1 || ids[0] != "SAT-0002" {
+		t.Errorf("expected only SAT-0002 within 24h, got %v", ids)
+	}
+	if w := get("?within=soon"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid duration, got %d", w.Code)
+	}
+
+	router = gin.New()
+	router.GET("/fleet/storage/forecast", NewForecastHandler(db.ResourceStorage, nil).HandleFleetForecast)
+	if w := get(""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when disabled, got %d", w.Code)
+	}
+}
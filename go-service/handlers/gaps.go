@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// GapQuerier reads the telemetry gaps recorded during ingest and replay
+// This allows for mocking in tests
+type GapQuerier interface {
+	QueryGaps(ctx context.Context, q db.GapQuery) ([]models.TelemetryGap, error)
+}
+
+// GapHandler serves historical telemetry gaps for pass coverage analysis
+type GapHandler struct {
+	querier    GapQuerier
+	guardrails *QueryGuardrails
+}
+
+// NewGapHandler creates a gap handler; querier is nil when gap detection is
+// disabled
+func NewGapHandler(querier GapQuerier, guardrails *QueryGuardrails) *GapHandler {
+	return &GapHandler{querier: querier, guardrails: guardrails}
+}
+
+// HandleGaps lists the gaps overlapping a time range, oldest first, with the
+// time without telemetry inside it and, for one satellite, its coverage
+// GET /telemetry/gaps?satellite_id=&from=&to=&min_duration=&limit=
+func (h *GapHandler) HandleGaps(c *gin.Context) {
+	if h.querier == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "gap detection is disabled"})
+		return
+	}
+	satelliteID := c.Query("satellite_id")
+	var minDuration time.Duration
+	if value := c.Query("min_duration"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_duration must be a duration like 10m"})
+			return
+		}
+		minDuration = parsed
+	}
+
+	window, ok := h.guardrails.Window(c, EndpointClassAggregate)
+	if !ok {
+		return
+	}
+
+	gaps, err := h.querier.QueryGaps(c.Request.Context(), db.GapQuery{
+		SatelliteID: satelliteID,
+		From:        window.From,
+		To:          window.To,
+		MinDuration: minDuration,
+		Limit:       window.Limit,
+	})
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+
+	response := models.TelemetryGapsResponse{
+		SatelliteID: satelliteID,
+		From:        window.From,
+		To:          window.To,
+		Count:       len(gaps),
+		Gaps:        gaps,
+		GapSeconds:  gapSecondsWithin(gaps, window.From, window.To),
+	}
+	if satelliteID != "" {
+		coverage := 100 * (1 - response.GapSeconds/window.To.Sub(window.From).Seconds())
+		response.CoveragePercent = &coverage
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// gapSecondsWithin sums the part of each gap inside [from, to)
+func gapSecondsWithin(gaps []models.TelemetryGap, from, to time.Time) float64 {
+	total := 0.0
+	for _, gap := range gaps {
+		start, end := gap.Start, gap.End
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			total += end.Sub(start).Seconds()
+		}
+	}
+	return total
+}
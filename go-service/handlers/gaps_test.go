@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// fakeGapQuerier returns canned gaps and records the last query
+type fakeGapQuerier struct {
+	gaps  []models.TelemetryGap
+	query db.GapQuery
+}
+
+func (f *fakeGapQuerier) QueryGaps(ctx context.Context, q db.GapQuery) ([]models.TelemetryGap, error) {
+	f.query = q
+	return f.gaps, nil
+}
+
+func TestHandleGaps(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	querier := &fakeGapQuerier{gaps: []models.TelemetryGap{
+		// Starts before the range: only the last 30 minutes count
+		{SatelliteID: "SAT-0001", Start: from.Add(-30 * time.Minute), End: from.Add(30 * time.Minute), DurationSeconds: 3600},
+		{SatelliteID: "SAT-0001", Start: from.Add(2 * time.Hour), End: from.Add(3 * time.Hour), DurationSeconds: 3600},
+	}}
+	router := gin.New()
+	router.GET("/telemetry/gaps", NewGapHandler(querier, newTestGuardrails()).HandleGaps)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/telemetry/gaps"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("?satellite_id=SAT-0001&from=2026-03-01T00:00:00Z&to=2026-03-01T10:00:00Z&min_duration=10m")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.TelemetryGapsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if response.Count != 2 || response.GapSeconds != 5400 {
+		t.Errorf("expected 2 gaps and 5400s without telemetry, got %+v", response)
+	}
+	if response.CoveragePercent == nil || *response.CoveragePercent != 85 {
+		t.Errorf("expected 85%% coverage, got %v", response.CoveragePercent)
+	}
+	if querier.query.SatelliteID != "SAT-0001" || querier.query.MinDuration != 10*time.Minute || querier.query.Limit != 500 {
+		t.Errorf("unexpected query %+v", querier.query)
+	}
+
+	// Fleet-wide queries have no single coverage figure
+	w = get("?from=2026-03-01T00:00:00Z&to=2026-03-01T10:00:00Z")
+	response = models.TelemetryGapsResponse{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.CoveragePercent != nil {
+		t.Errorf("expected no coverage for the fleet, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := get("?min_duration=soon"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad min_duration, got %d", w.Code)
+	}
+
+	router = gin.New()
+	router.GET("/telemetry/gaps", NewGapHandler(nil, newTestGuardrails()).HandleGaps)
+	if w := get(""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when disabled, got %d", w.Code)
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+)
+
+// GeofenceHandler serves the configured zones and who is inside them
+type GeofenceHandler struct {
+	monitor *db.GeofenceMonitor
+}
+
+// NewGeofenceHandler creates a handler reporting the zones of monitor
+func NewGeofenceHandler(monitor *db.GeofenceMonitor) *GeofenceHandler {
+	return &GeofenceHandler{monitor: monitor}
+}
+
+// HandleGeofences lists every zone with the satellites whose newest
+// position is inside it
+// GET /fleet/geofences
+func (h *GeofenceHandler) HandleGeofences(c *gin.Context) {
+	if h.monitor == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no geofences configured"})
+		return
+	}
+	zones := h.monitor.Occupancy()
+	c.JSON(http.StatusOK, gin.H{"zones": zones, "count": len(zones)})
+}
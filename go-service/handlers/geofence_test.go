@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/test"
+)
+
+func TestHandleGeofences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	monitor := db.NewGeofenceMonitor([]db.Geofence{
+		{Name: "svalbard_gs", BBox: &db.GeofenceBox{MinLat: 70, MaxLat: 82, MinLon: 0, MaxLon: 35}},
+	})
+	bp := newTestBatchProcessor()
+	bp.SetGeofenceMonitor(monitor)
+	point := test.NewTestTelemetryPointWithSatelliteID("SAT-0001")
+	lat, lon, alt := 78.2, 15.4, 550.0
+	point.Latitude, point.Longitude, point.AltitudeKM = &lat, &lon, &alt
+	if err := bp.Add(point); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/fleet/geofences", NewGeofenceHandler(monitor).HandleGeofences)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/fleet/geofences", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"satellites_inside":["SAT-0001"]`) {
+		t.Errorf("expected SAT-0001 inside svalbard_gs, got %d: %s", w.Code, w.Body.String())
+	}
+
+	router = gin.New()
+	router.GET("/fleet/geofences", NewGeofenceHandler(nil).HandleGeofences)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without geofences, got %d", w.Code)
+	}
+}
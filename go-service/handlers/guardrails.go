@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Gin context keys used to identify the caller for per-principal limits
+const (
+	// ContextKeyRole holds the caller's role (set by authentication middleware)
+	ContextKeyRole = "orbitstream.role"
+	// ContextKeyAPIKeyID holds the identifier of the caller's API key
+	ContextKeyAPIKeyID = "orbitstream.api_key_id"
+)
+
+// EndpointClass groups query endpoints that share the same cost profile
+type EndpointClass string
+
+const (
+	// EndpointClassRaw covers endpoints reading the raw telemetry hypertable
+	EndpointClassRaw EndpointClass = "raw"
+	// EndpointClassAggregate covers endpoints reading continuous aggregates
+	EndpointClassAggregate EndpointClass = "aggregate"
+	// EndpointClassExport covers streaming bulk exports of raw telemetry
+	EndpointClassExport EndpointClass = "export"
+)
+
+// QueryLimits bounds the time range and result size of a single query
+type QueryLimits struct {
+	MaxRange time.Duration
+	MaxRows  int
+}
+
+// QueryWindow is a validated query window ready to be passed to the db layer
+type QueryWindow struct {
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Limits QueryLimits
+}
+
+// QueryGuardrails enforces maximum query range and result size per endpoint
+// class, with optional overrides per role or API key. Every query endpoint
+// must go through Window so that a year-long raw query is rejected with
+// guidance instead of reaching the database.
+type QueryGuardrails struct {
+	mu        sync.RWMutex
+	defaults  map[EndpointClass]QueryLimits
+	overrides map[string]map[EndpointClass]QueryLimits
+}
+
+// NewQueryGuardrails creates guardrails with default limits for raw and aggregate endpoints
+func NewQueryGuardrails(raw, aggregate QueryLimits) *QueryGuardrails {
+	return &QueryGuardrails{
+		defaults: map[EndpointClass]QueryLimits{
+			EndpointClassRaw:       raw,
+			EndpointClassAggregate: aggregate,
+		},
+		overrides: make(map[string]map[EndpointClass]QueryLimits),
+	}
+}
+
+// SetDefault sets the default limits for an endpoint class
+func (g *QueryGuardrails) SetDefault(class EndpointClass, limits QueryLimits) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.defaults[class] = limits
+}
+
+// SetOverride sets limits for a role or API key ID on one endpoint class
+func (g *QueryGuardrails) SetOverride(principal string, class EndpointClass, limits QueryLimits) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.overrides[principal] == nil {
+		g.overrides[principal] = make(map[EndpointClass]QueryLimits)
+	}
+	g.overrides[principal][class] = limits
+}
+
+// LimitsFor resolves limits for the caller: API key override first, then role
+// override, then the class default
+func (g *QueryGuardrails) LimitsFor(c *gin.Context, class EndpointClass) QueryLimits {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	for _, key := range []string{ContextKeyAPIKeyID, ContextKeyRole} {
+		principal := c.GetString(key)
+		if principal == "" {
+			continue
+		}
+		if limits, ok := g.overrides[principal][class]; ok {
+			return limits
+		}
+	}
+	return g.defaults[class]
+}
+
+// Window parses from/to/limit query parameters and checks them against the
+// caller's limits. On violation it writes a 422 (or 400 for malformed input)
+// and returns false; handlers should return immediately in that case.
+//
+// Defaults: to = now, from = to - min(1h, max range), limit = max rows.
+func (g *QueryGuardrails) Window(c *gin.Context, class EndpointClass) (QueryWindow, bool) {
+	limits := g.LimitsFor(c, class)
+
+	to := time.Now().UTC()
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid 'to' timestamp: %v", err)})
+			return QueryWindow{}, false
+		}
+		to = parsed
+	}
+
+	defaultRange := time.Hour
+	if limits.MaxRange > 0 && limits.MaxRange < defaultRange {
+		defaultRange = limits.MaxRange
+	}
+	from := to.Add(-defaultRange)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid 'from' timestamp: %v", err)})
+			return QueryWindow{}, false
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' must be before 'to'"})
+		return QueryWindow{}, false
+	}
+
+	limit := limits.MaxRows
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'limit' must be a positive integer"})
+			return QueryWindow{}, false
+		}
+		limit = parsed
+	}
+
+	if limits.MaxRange > 0 && to.Sub(from) > limits.MaxRange {
+		g.reject(c, class, limits, fmt.Sprintf("requested range %v exceeds maximum %v for %s queries",
+			to.Sub(from), limits.MaxRange, class))
+		return QueryWindow{}, false
+	}
+	if limits.MaxRows > 0 && limit > limits.MaxRows {
+		g.reject(c, class, limits, fmt.Sprintf("requested limit %d exceeds maximum %d rows for %s queries",
+			limit, limits.MaxRows, class))
+		return QueryWindow{}, false
+	}
+
+	return QueryWindow{From: from, To: to, Limit: limit, Limits: limits}, true
+}
+
+// reject writes a 422 response explaining the limit and how to stay within it
+func (g *QueryGuardrails) reject(c *gin.Context, class EndpointClass, limits QueryLimits, reason string) {
+	guidance := "Narrow the time range or lower 'limit'."
+	if class == EndpointClassRaw {
+		guidance = "Narrow the time range, lower 'limit', or use /stats for long ranges."
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":          reason,
+		"endpoint_class": class,
+		"max_range":      limits.MaxRange.String(),
+		"max_rows":       limits.MaxRows,
+		"guidance":       guidance,
+	})
+}
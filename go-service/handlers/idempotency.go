@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader names the request header carrying the client's key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayHeader marks a response served from the cache
+	idempotentReplayHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength bounds the keys clients may send
+	maxIdempotencyKeyLength = 255
+	// idempotencySweepInterval is how often expired results are dropped
+	idempotencySweepInterval = time.Minute
+)
+
+// IdempotencyCache remembers the response to each request carrying an
+// Idempotency-Key header for a TTL, so a client retrying a batch after a
+// network timeout gets the original result back instead of ingesting the
+// points twice. Keys are scoped to the client (API key ID or IP) and route.
+type IdempotencyCache struct {
+	ttl     time.Duration
+	maxKeys int
+	now     func() time.Time
+
+	mu         sync.Mutex
+	results    map[string]*idempotentResult
+	lastSweep  time.Time
+	stored     int64
+	replayed   int64
+	inProgress int64
+	mismatched int64
+	evicted    int64
+	full       int64
+}
+
+// idempotentResult is the response to a key, or a placeholder while the
+// first request with it is still running; full marks a key begin couldn't
+// reserve
+type idempotentResult struct {
+	fingerprint [sha256.Size]byte
+	pending     bool
+	full        bool
+	status      int
+	contentType string
+	body        []byte
+	stored      time.Time
+}
+
+// IdempotencyStats reports the cached results and the retries they served
+type IdempotencyStats struct {
+	Enabled    bool   `json:"enabled"`
+	TTL        string `json:"ttl"`
+	MaxKeys    int    `json:"max_keys"`
+	Keys       int    `json:"keys"`
+	Stored     int64  `json:"stored"`
+	Replayed   int64  `json:"replayed"`
+	InProgress int64  `json:"in_progress"`
+	Mismatched int64  `json:"mismatched"`
+	Evicted    int64  `json:"evicted"`
+	Full       int64  `json:"full"`
+}
+
+// NewIdempotencyCache keeps results for ttl, at most maxKeys at a time;
+// ttl <= 0 disables the cache. Once every key held is still in progress,
+// new keys are refused with 503 rather than going over maxKeys.
+func NewIdempotencyCache(ttl time.Duration, maxKeys int) *IdempotencyCache {
+	if maxKeys < 1 {
+		maxKeys = 1
+	}
+	return &IdempotencyCache{
+		ttl:     ttl,
+		maxKeys: maxKeys,
+		now:     time.Now,
+		results: make(map[string]*idempotentResult),
+	}
+}
+
+// Enabled reports whether Idempotency-Key headers are honored
+func (ic *IdempotencyCache) Enabled() bool {
+	return ic != nil && ic.ttl > 0
+}
+
+// Middleware replays the stored response when a key is reused with the same
+// body, answers 409 while the first request with the key is still running
+// and 422 when the key is reused with a different body. Server errors and
+// 429s are not stored, nor are requests whose handler panicked, so the
+// client can retry them with the same key. It must run after
+// authentication to scope keys by API key.
+func (ic *IdempotencyCache) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if !ic.Enabled() || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Idempotency-Key must not exceed 255 characters",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if bodyTooLarge(err) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scoped := rateLimitClient(c) + " " + c.FullPath() + " " + key
+		fingerprint := sha256.Sum256(body)
+		result, ok := ic.begin(scoped, fingerprint)
+		if !ok {
+			switch {
+			case result.full:
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error": "too many requests with an Idempotency-Key in progress",
+				})
+			case result.fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+					"error": "Idempotency-Key was already used with a different request body",
+				})
+			case result.pending:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+					"error": "a request with this Idempotency-Key is still in progress",
+				})
+			default:
+				c.Header(idempotentReplayHeader, "true")
+				c.Data(result.status, result.contentType, result.body)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		// A panicking handler never reaches finish, so release the key
+		// on the way out or it stays in progress until it is evicted
+		finished := false
+		defer func() {
+			if !finished {
+				ic.release(scoped)
+			}
+		}()
+		c.Next()
+
+		ic.finish(scoped, fingerprint, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		finished = true
+	}
+}
+
+// begin reserves key for a new request; when the key is already known it
+// returns a copy of its result instead
+func (ic *IdempotencyCache) begin(key string, fingerprint [sha256.Size]byte) (idempotentResult, bool) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	now := ic.now()
+	ic.sweep(now)
+
+	if result, ok := ic.results[key]; ok && (result.pending || now.Sub(result.stored) <= ic.ttl) {
+		switch {
+		case result.fingerprint != fingerprint:
+			ic.mismatched++
+		case result.pending:
+			ic.inProgress++
+		default:
+			ic.replayed++
+		}
+		return *result, false
+	}
+
+	if len(ic.results) >= ic.maxKeys && !ic.evictOldest() {
+		ic.full++
+		return idempotentResult{full: true}, false
+	}
+	ic.results[key] = &idempotentResult{fingerprint: fingerprint, pending: true, stored: now}
+	return idempotentResult{}, true
+}
+
+// finish stores the response to key, or forgets the key when the response
+// is worth retrying
+func (ic *IdempotencyCache) finish(key string, fingerprint [sha256.Size]byte, status int, contentType string, body []byte) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+		delete(ic.results, key)
+		return
+	}
+	ic.results[key] = &idempotentResult{
+		fingerprint: fingerprint,
+		status:      status,
+		contentType: contentType,
+		body:        append([]byte(nil), body...),
+		stored:      ic.now(),
+	}
+	ic.stored++
+}
+
+// release forgets a key still reserved by a request that didn't finish
+func (ic *IdempotencyCache) release(key string) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	if result, ok := ic.results[key]; ok && result.pending {
+		delete(ic.results, key)
+	}
+}
+
+// sweep drops the results older than the TTL
+func (ic *IdempotencyCache) sweep(now time.Time) {
+	if now.Sub(ic.lastSweep) < idempotencySweepInterval {
+		return
+	}
+	ic.lastSweep = now
+	for key, result := range ic.results {
+		if !result.pending && now.Sub(result.stored) > ic.ttl {
+			delete(ic.results, key)
+		}
+	}
+}
+
+// evictOldest drops the oldest stored result to make room for a new key; it
+// reports false when every key held is still in progress
+func (ic *IdempotencyCache) evictOldest() bool {
+	var oldestKey string
+	var oldest time.Time
+	for key, result := range ic.results {
+		if !result.pending && (oldestKey == "" || result.stored.Before(oldest)) {
+			oldestKey, oldest = key, result.stored
+		}
+	}
+	if oldestKey == "" {
+		return false
+	}
+	if ic.evicted == 0 {
+		log.Printf("WARNING: Idempotency cache full at %d keys; evicting results before their TTL", ic.maxKeys)
+	}
+	delete(ic.results, oldestKey)
+	ic.evicted++
+	return true
+}
+
+// Stats returns the settings, the keys held and how often they were reused
+func (ic *IdempotencyCache) Stats() IdempotencyStats {
+	stats := IdempotencyStats{Enabled: ic.Enabled()}
+	if ic == nil {
+		return stats
+	}
+	stats.TTL = ic.ttl.String()
+	stats.MaxKeys = ic.maxKeys
+
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	stats.Keys = len(ic.results)
+	stats.Stored = ic.stored
+	stats.Replayed = ic.replayed
+	stats.InProgress = ic.inProgress
+	stats.Mismatched = ic.mismatched
+	stats.Evicted = ic.evicted
+	stats.Full = ic.full
+	return stats
+}
+
+// HandleStats returns idempotency cache settings and counts
+// GET /admin/idempotency
+func (ic *IdempotencyCache) HandleStats(c *gin.Context) {
+	c.JSON(http.StatusOK, ic.Stats())
+}
+
+// bodyRecorder keeps a copy of the response body written through it
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupIdempotentRouter counts the requests reaching the handler, which
+// answers with the status in X-Test-Status (202 by default)
+func setupIdempotentRouter(cache *IdempotencyCache, calls *int) *gin.Engine {
+	router := gin.New()
+	router.POST("/telemetry/batch", cache.Middleware(), func(c *gin.Context) {
+		*calls++
+		status := http.StatusAccepted
+		if c.GetHeader("X-Test-Status") == "503" {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"call": *calls})
+	})
+	return router
+}
+
+func idempotentRequest(router *gin.Engine, key, body string, header ...string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/telemetry/batch", bytes.NewBufferString(body))
+	req.RemoteAddr = "10.1.1.1:5000"
+	req.Header.Set(IdempotencyKeyHeader, key)
+	if len(header) == 2 {
+		req.Header.Set(header[0], header[1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyCacheReplaysRetries(t *testing.T) {
+	cache := NewIdempotencyCache(time.Hour, 10)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	calls := 0
+	router := setupIdempotentRouter(cache, &calls)
+
+	first := idempotentRequest(router, "batch-1", `[{"satellite_id": "SAT-0001"}]`)
+	retry := idempotentRequest(router, "batch-1", `[{"satellite_id": "SAT-0001"}]`)
+	if calls != 1 || retry.Code != http.StatusAccepted || retry.Body.String() != first.Body.String() {
+		t.Errorf("expected the retry to replay %q, got %d %q after %d calls", first.Body.String(), retry.Code, retry.Body.String(), calls)
+	}
+	if retry.Header().Get(idempotentReplayHeader) != "true" {
+		t.Error("expected the replay to be marked")
+	}
+
+	if w := idempotentRequest(router, "batch-1", `[{"satellite_id": "SAT-0002"}]`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for a reused key with another body, got %d", w.Code)
+	}
+	if w := idempotentRequest(router, "batch-2", `[]`); w.Code != http.StatusAccepted || calls != 2 {
+		t.Errorf("expected a new key to reach the handler, got %d after %d calls", w.Code, calls)
+	}
+
+	// Expired results are forgotten
+	now = now.Add(2 * time.Hour)
+	idempotentRequest(router, "batch-1", `[{"satellite_id": "SAT-0001"}]`)
+	if calls != 3 {
+		t.Errorf("expected an expired key to reach the handler, got %d calls", calls)
+	}
+
+	stats := cache.Stats()
+	if stats.Replayed != 1 || stats.Mismatched != 1 || stats.Stored != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestIdempotencyCacheRetriesServerErrors(t *testing.T) {
+	calls := 0
+	router := setupIdempotentRouter(NewIdempotencyCache(time.Hour, 10), &calls)
+
+	if w := idempotentRequest(router, "batch-1", `[]`, "X-Test-Status", "503"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	if w := idempotentRequest(router, "batch-1", `[]`); w.Code != http.StatusAccepted || calls != 2 {
+		t.Errorf("expected a retry after a 503 to reach the handler, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotencyCacheReleasesKeyOnPanic(t *testing.T) {
+	cache := NewIdempotencyCache(time.Hour, 10)
+	calls := 0
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.POST("/telemetry/batch", cache.Middleware(), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("handler failed")
+		}
+		c.JSON(http.StatusAccepted, gin.H{"call": calls})
+	})
+
+	if w := idempotentRequest(router, "batch-1", `[]`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 from the panic, got %d", w.Code)
+	}
+	if stats := cache.Stats(); stats.Keys != 0 || stats.InProgress != 0 {
+		t.Errorf("expected the key to be released, got %+v", stats)
+	}
+	if w := idempotentRequest(router, "batch-1", `[]`); w.Code != http.StatusAccepted || calls != 2 {
+		t.Errorf("expected a retry after a panic to reach the handler, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotencyCacheInProgress(t *testing.T) {
+	cache := NewIdempotencyCache(time.Hour, 10)
+	if _, ok := cache.begin("key:a /telemetry/batch k", [32]byte{1}); !ok {
+		t.Fatal("expected a new key to be reserved")
+	}
+	result, ok := cache.begin("key:a /telemetry/batch k", [32]byte{1})
+	if ok || !result.pending || cache.Stats().InProgress != 1 {
+		t.Errorf("expected the key to be in progress, got %+v", result)
+	}
+}
+
+func TestIdempotencyCacheFullOfPendingKeys(t *testing.T) {
+	cache := NewIdempotencyCache(time.Hour, 2)
+	for _, key := range []string{"a", "b"} {
+		if _, ok := cache.begin("key:a /telemetry/batch "+key, [32]byte{1}); !ok {
+			t.Fatalf("expected key %s to be reserved", key)
+		}
+	}
+
+	calls := 0
+	router := setupIdempotentRouter(cache, &calls)
+	w := idempotentRequest(router, "c", `[]`)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" || calls != 0 {
+		t.Errorf("expected 503 with Retry-After while full of requests in progress, got %d after %d calls", w.Code, calls)
+	}
+	if stats := cache.Stats(); stats.Keys != 2 || stats.Full != 1 {
+		t.Errorf("expected the cache to stay at 2 keys, got %+v", stats)
+	}
+
+	// Once a request finishes its result can be evicted for a new key
+	cache.finish("key:a /telemetry/batch a", [32]byte{1}, http.StatusAccepted, "application/json", nil)
+	if w := idempotentRequest(router, "c", `[]`); w.Code != http.StatusAccepted || calls != 1 {
+		t.Errorf("expected the new key to reach the handler, got %d after %d calls", w.Code, calls)
+	}
+	if stats := cache.Stats(); stats.Keys != 2 || stats.Evicted != 1 {
+		t.Errorf("expected one eviction, got %+v", stats)
+	}
+}
+
+func TestIdempotencyCacheDisabled(t *testing.T) {
+	calls := 0
+	router := setupIdempotentRouter(NewIdempotencyCache(0, 10), &calls)
+	idempotentRequest(router, "batch-1", `[]`)
+	idempotentRequest(router, "batch-1", `[]`)
+	if calls != 2 {
+		t.Errorf("expected the header to be ignored, got %d calls", calls)
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyClientIdentity holds the ClientIdentity resolved for the request
+const ContextKeyClientIdentity = "orbitstream.client_identity"
+
+// maxWarnedPeers bounds the untrusted peers remembered as already warned
+// about; past it the set starts over
+const maxWarnedPeers = 1024
+
+// ClientIdentity describes who is calling, as far as the network layer can tell.
+// Authentication (of verified client certificates), rate limiting and audit
+// logging all key off this value so they agree on the caller regardless of
+// whether a load balancer sits in front.
+type ClientIdentity struct {
+	// IP is the client address (X-Forwarded-For resolved only via trusted proxies)
+	IP string `json:"ip"`
+	// CertSubject is the mTLS client certificate subject verified by the proxy,
+	// or by the server itself when it terminates TLS
+	CertSubject string `json:"cert_subject,omitempty"`
+	// ViaProxy is true when the request arrived through a trusted proxy
+	ViaProxy bool `json:"via_proxy"`
+}
+
+// IdentityConfig configures how client identity is derived from proxy headers
+type IdentityConfig struct {
+	// TrustedProxies lists CIDRs (or bare IPs) allowed to set forwarding headers
+	TrustedProxies []string
+	// CertSubjectHeader carries the verified client certificate subject
+	CertSubjectHeader string
+	// CertVerifyHeader carries the proxy's verification result ("SUCCESS")
+	CertVerifyHeader string
+}
+
+// IdentityResolver extracts ClientIdentity from requests, honouring
+// X-Forwarded-* and mTLS headers only from trusted proxies
+type IdentityResolver struct {
+	trusted           []*net.IPNet
+	certSubjectHeader string
+	certVerifyHeader  string
+
+	mu      sync.Mutex
+	ignored int64
+	warned  map[string]bool
+}
+
+// IdentityStats reports the trusted proxies and the forwarding headers
+// ignored from peers that aren't among them
+type IdentityStats struct {
+	TrustedProxies int   `json:"trusted_proxies"`
+	IgnoredHeaders int64 `json:"ignored_headers"`
+	IgnoredPeers   int   `json:"ignored_peers"`
+}
+
+// NewIdentityResolver parses the trusted proxy list
+func NewIdentityResolver(cfg IdentityConfig) (*IdentityResolver, error) {
+	r := &IdentityResolver{
+		certSubjectHeader: cfg.CertSubjectHeader,
+		certVerifyHeader:  cfg.CertVerifyHeader,
+		warned:            make(map[string]bool),
+	}
+	for _, entry := range cfg.TrustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		r.trusted = append(r.trusted, network)
+	}
+	return r, nil
+}
+
+// isTrusted reports whether the direct peer is a trusted proxy
+func (r *IdentityResolver) isTrusted(ip net.IP) bool {
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve derives the identity of the request's caller
+func (r *IdentityResolver) Resolve(c *gin.Context) ClientIdentity {
+	peer := c.RemoteIP()
+	peerIP := net.ParseIP(peer)
+
+	if peerIP == nil || !r.isTrusted(peerIP) {
+		// Not behind a trusted proxy: forwarding headers are client-controlled
+		if c.GetHeader("X-Forwarded-For") != "" || (r.certSubjectHeader != "" && c.GetHeader(r.certSubjectHeader) != "") {
+			r.ignoreHeaders(peer)
+		}
+		return ClientIdentity{IP: peer, CertSubject: verifiedCertSubject(c)}
+	}
+
+	identity := ClientIdentity{IP: peer, ViaProxy: true}
+
+	// Walk X-Forwarded-For from the right, skipping trusted hops; the first
+	// untrusted address is the real client
+	if forwarded := c.GetHeader("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			hopIP := net.ParseIP(hop)
+			if hopIP == nil {
+				break
+			}
+			identity.IP = hop
+			if !r.isTrusted(hopIP) {
+				break
+			}
+		}
+	}
+
+	if r.certSubjectHeader != "" {
+		subject := c.GetHeader(r.certSubjectHeader)
+		verified := r.certVerifyHeader == "" || strings.EqualFold(c.GetHeader(r.certVerifyHeader), "SUCCESS")
+		if subject != "" && verified {
+			identity.CertSubject = subject
+		}
+	}
+
+	return identity
+}
+
+// ignoreHeaders counts identity headers ignored from an untrusted peer,
+// warning only the first time the peer sends them
+func (r *IdentityResolver) ignoreHeaders(peer string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ignored++
+	if r.warned[peer] {
+		return
+	}
+	if len(r.warned) >= maxWarnedPeers {
+		r.warned = make(map[string]bool)
+	}
+	r.warned[peer] = true
+	log.Printf("WARNING: Ignoring identity headers from untrusted peer %s (further headers from it are only counted)", peer)
+}
+
+// Stats returns the trusted proxies and how many identity headers were ignored
+func (r *IdentityResolver) Stats() IdentityStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return IdentityStats{TrustedProxies: len(r.trusted), IgnoredHeaders: r.ignored, IgnoredPeers: len(r.warned)}
+}
+
+// HandleStats returns identity header counts
+// GET /admin/identity
+func (r *IdentityResolver) HandleStats(c *gin.Context) {
+	c.JSON(http.StatusOK, r.Stats())
+}
+
+// verifiedCertSubject returns the subject of the client certificate the
+// server verified during its own TLS handshake
+func verifiedCertSubject(c *gin.Context) string {
+	if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 || len(c.Request.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return c.Request.TLS.VerifiedChains[0][0].Subject.String()
+}
+
+// Middleware stores the resolved identity in the gin context
+func (r *IdentityResolver) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ContextKeyClientIdentity, r.Resolve(c))
+		c.Next()
+	}
+}
+
+// ClientIdentityFrom returns the identity stored by the middleware, falling
+// back to the direct peer address when the middleware is not installed
+func ClientIdentityFrom(c *gin.Context) ClientIdentity {
+	if value, ok := c.Get(ContextKeyClientIdentity); ok {
+		if identity, ok := value.(ClientIdentity); ok {
+			return identity
+		}
+	}
+	return ClientIdentity{IP: c.RemoteIP()}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupIdentityRouter(t *testing.T, cfg IdentityConfig) *gin.Engine {
+	t.Helper()
+	resolver, err := NewIdentityResolver(cfg)
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(resolver.Middleware())
+	router.GET("/whoami", func(c *gin.Context) {
+		c.JSON(http.StatusOK, ClientIdentityFrom(c))
+	})
+	return router
+}
+
+func resolveIdentity(t *testing.T, router *gin.Engine, remoteAddr string, headers map[string]string) ClientIdentity {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/whoami", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var identity ClientIdentity
+	if err := json.Unmarshal(w.Body.Bytes(), &identity); err != nil {
+		t.Fatalf("failed to unmarshal identity: %v", err)
+	}
+	return identity
+}
+
+func testIdentityConfig() IdentityConfig {
+	return IdentityConfig{
+		TrustedProxies:    []string{"10.0.0.0/8", "192.168.1.5"},
+		CertSubjectHeader: "X-Client-Cert-Subject",
+		CertVerifyHeader:  "X-Client-Cert-Verify",
+	}
+}
+
+func TestIdentityUntrustedPeerIgnoresHeaders(t *testing.T) {
+	router := setupIdentityRouter(t, testIdentityConfig())
+
+	identity := resolveIdentity(t, router, "203.0.113.7:5555", map[string]string{
+		"X-Forwarded-For":       "1.2.3.4",
+		"X-Client-Cert-Subject": "CN=mission-ops",
+		"X-Client-Cert-Verify":  "SUCCESS",
+	})
+
+	if identity.IP != "203.0.113.7" {
+		t.Errorf("expected direct peer IP, got %s", identity.IP)
+	}
+	if identity.CertSubject != "" {
+		t.Errorf("expected spoofed cert subject to be ignored, got %s", identity.CertSubject)
+	}
+	if identity.ViaProxy {
+		t.Error("expected ViaProxy false for untrusted peer")
+	}
+}
+
+func TestIdentityUntrustedPeerWarnsOnce(t *testing.T) {
+	resolver, err := NewIdentityResolver(testIdentityConfig())
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+	router := gin.New()
+	router.Use(resolver.Middleware())
+	router.GET("/whoami", func(c *gin.Context) {
+		c.JSON(http.StatusOK, ClientIdentityFrom(c))
+	})
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	spoofed := map[string]string{"X-Forwarded-For": "1.2.3.4"}
+	for i := 0; i < 3; i++ {
+		resolveIdentity(t, router, "203.0.113.7:5555", spoofed)
+	}
+	resolveIdentity(t, router, "203.0.113.8:5555", spoofed)
+	resolveIdentity(t, router, "203.0.113.9:5555", nil)
+	resolveIdentity(t, router, "10.1.2.3:443", spoofed)
+
+	if warnings := strings.Count(logs.String(), "WARNING"); warnings != 2 {
+		t.Errorf("expected one warning per untrusted peer, got %d:\n%s", warnings, logs.String())
+	}
+	stats := resolver.Stats()
+	if stats.IgnoredHeaders != 4 || stats.IgnoredPeers != 2 || stats.TrustedProxies != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestIdentityTrustedProxyForwardedFor(t *testing.T) {
+	router := setupIdentityRouter(t, testIdentityConfig())
+
+	// Client spoofs a leading entry; the real client is the last untrusted hop
+	identity := resolveIdentity(t, router, "10.1.2.3:443", map[string]string{
+		"X-Forwarded-For": "6.6.6.6, 198.51.100.20, 10.4.4.4",
+	})
+
+	if identity.IP != "198.51.100.20" {
+		t.Errorf("expected first untrusted hop from the right, got %s", identity.IP)
+	}
+	if !identity.ViaProxy {
+		t.Error("expected ViaProxy true")
+	}
+}
+
+func TestIdentityTrustedProxyCertSubject(t *testing.T) {
+	router := setupIdentityRouter(t, testIdentityConfig())
+
+	identity := resolveIdentity(t, router, "192.168.1.5:443", map[string]string{
+		"X-Forwarded-For":       "198.51.100.20",
+		"X-Client-Cert-Subject": "CN=mission-ops",
+		"X-Client-Cert-Verify":  "SUCCESS",
+	})
+	if identity.CertSubject != "CN=mission-ops" {
+		t.Errorf("expected verified cert subject, got %q", identity.CertSubject)
+	}
+
+	identity = resolveIdentity(t, router, "192.168.1.5:443", map[string]string{
+		"X-Client-Cert-Subject": "CN=mission-ops",
+		"X-Client-Cert-Verify":  "FAILED:certificate expired",
+	})
+	if identity.CertSubject != "" {
+		t.Errorf("expected unverified cert subject to be ignored, got %q", identity.CertSubject)
+	}
+}
+
+func TestIdentityNoTrustedProxies(t *testing.T) {
+	router := setupIdentityRouter(t, IdentityConfig{})
+
+	identity := resolveIdentity(t, router, "10.1.2.3:443", map[string]string{
+		"X-Forwarded-For": "198.51.100.20",
+	})
+	if identity.IP != "10.1.2.3" {
+		t.Errorf("expected forwarding headers ignored without trusted proxies, got %s", identity.IP)
+	}
+}
+
+func TestNewIdentityResolverInvalidCIDR(t *testing.T) {
+	if _, err := NewIdentityResolver(IdentityConfig{TrustedProxies: []string{"not-an-ip"}}); err == nil {
+		t.Error("expected error for invalid trusted proxy entry")
+	}
+}
+
+func TestClientIdentityFromWithoutMiddleware(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.Request.RemoteAddr = "203.0.113.9:1234"
+
+	if identity := ClientIdentityFrom(c); identity.IP != "203.0.113.9" {
+		t.Errorf("expected fallback to remote IP, got %s", identity.IP)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IngestLimits bounds the size of ingest requests, so one giant request is
+// refused before it is read into memory rather than after it was decoded
+// and the buffer check ran
+type IngestLimits struct {
+	// MaxBodyBytes is the largest request body accepted (0 = unlimited)
+	MaxBodyBytes int64
+	// MaxBatchPoints is the most points accepted per batch (0 = unlimited)
+	MaxBatchPoints int
+}
+
+// Middleware answers 413 when the declared body is over the limit and caps
+// reading the body at it, so undeclared bodies fail while being decoded. It
+// must run before anything reading the body.
+func (l IngestLimits) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.MaxBodyBytes <= 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > l.MaxBodyBytes {
+			l.abortBodyTooLarge(c)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, l.MaxBodyBytes)
+		c.Next()
+	}
+}
+
+// bodyTooLarge reports whether reading the request body failed on the limit
+func bodyTooLarge(err error) bool {
+	var maxBytes *http.MaxBytesError
+	return errors.As(err, &maxBytes)
+}
+
+func (l IngestLimits) abortBodyTooLarge(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":          fmt.Sprintf("request body exceeds %d bytes", l.MaxBodyBytes),
+		"max_body_bytes": l.MaxBodyBytes,
+		"guidance":       l.guidance(),
+	})
+}
+
+// checkBatch answers 413 and returns false when a batch has too many points
+func (l IngestLimits) checkBatch(c *gin.Context, points int) bool {
+	if l.MaxBatchPoints <= 0 || points <= l.MaxBatchPoints {
+		return true
+	}
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":            fmt.Sprintf("batch of %d points exceeds the limit of %d", points, l.MaxBatchPoints),
+		"max_batch_points": l.MaxBatchPoints,
+		"guidance":         l.guidance(),
+	})
+	return false
+}
+
+func (l IngestLimits) guidance() string {
+	switch {
+	case l.MaxBatchPoints > 0 && l.MaxBodyBytes > 0:
+		return fmt.Sprintf("Split the data into batches of at most %d points and %d bytes.", l.MaxBatchPoints, l.MaxBodyBytes)
+	case l.MaxBatchPoints > 0:
+		return fmt.Sprintf("Split the data into batches of at most %d points.", l.MaxBatchPoints)
+	default:
+		return fmt.Sprintf("Split the data into batches of at most %d bytes.", l.MaxBodyBytes)
+	}
+}
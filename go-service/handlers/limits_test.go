@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/models"
+	"orbitstream/test"
+)
+
+func setupLimitedRouter(limits IngestLimits, mockBP *test.MockBatchProcessor) *gin.Engine {
+	handler := NewTelemetryHandler(mockBP)
+	handler.SetIngestLimits(limits)
+	router := gin.New()
+	router.POST("/telemetry", limits.Middleware(), handler.HandleTelemetry)
+	router.POST("/telemetry/batch", limits.Middleware(), handler.HandleTelemetryBatch)
+	return router
+}
+
+func TestIngestLimitsBatchPoints(t *testing.T) {
+	mockBP := test.NewMockBatchProcessor()
+	router := setupLimitedRouter(IngestLimits{MaxBatchPoints: 2}, mockBP)
+
+	jsonData, _ := json.Marshal([]models.TelemetryPoint{
+		test.NewTestTelemetryPointWithSatelliteID("SAT-0001"),
+		test.NewTestTelemetryPointWithSatelliteID("SAT-0002"),
+		test.NewTestTelemetryPointWithSatelliteID("SAT-0003"),
+	})
+	req, _ := http.NewRequest("POST", "/telemetry/batch", bytes.NewBuffer(jsonData))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "at most 2 points") {
+		t.Errorf("expected 413 with guidance, got %d: %s", w.Code, w.Body.String())
+	}
+	if mockBP.GetAddCallCount() != 0 {
+		t.Errorf("expected no point to be buffered, got %d adds", mockBP.GetAddCallCount())
+	}
+}
+
+func TestIngestLimitsBodySize(t *testing.T) {
+	mockBP := test.NewMockBatchProcessor()
+	router := setupLimitedRouter(IngestLimits{MaxBodyBytes: 64}, mockBP)
+	body := `[{"satellite_id": "SAT-0001", "battery_charge_percent": 80, "storage_usage_mb": 100}]`
+
+	// Declared length over the limit
+	req, _ := http.NewRequest("POST", "/telemetry/batch", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "guidance") {
+		t.Errorf("expected 413 for a declared length, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Undeclared (chunked) length fails while decoding
+	req, _ = http.NewRequest("POST", "/telemetry", io.NopCloser(strings.NewReader(body)))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a chunked body, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Small requests still pass
+	req, _ = http.NewRequest("POST", "/telemetry", strings.NewReader(`{"satellite_id": "SAT-0001"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected 202 under the limit, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// maxNearRadiusKM is half the Earth's circumference; any larger radius
+// covers the whole globe
+const maxNearRadiusKM = 20015.0
+
+// NearQuerier finds telemetry points near a ground point
+// This allows for mocking in tests
+type NearQuerier interface {
+	QueryNear(ctx context.Context, q db.NearQuery) ([]models.NearbyPoint, error)
+}
+
+// NearHandler serves radius searches around ground points, e.g. to find
+// which satellites passed over a ground station
+type NearHandler struct {
+	querier    NearQuerier
+	guardrails *QueryGuardrails
+}
+
+// NewNearHandler creates a radius search handler
+func NewNearHandler(querier NearQuerier, guardrails *QueryGuardrails) *NearHandler {
+	return &NearHandler{querier: querier, guardrails: guardrails}
+}
+
+// HandleNear lists the points whose position lies within radius_km of the
+// ground point (great-circle distance of the ground track), newest first
+// GET /telemetry/near?lat=&lon=&radius_km=&from=&to=&satellite_id=&limit=
+func (h *NearHandler) HandleNear(c *gin.Context) {
+	lat, ok := floatParam(c, "lat", -90, 90)
+	if !ok {
+		return
+	}
+	lon, ok := floatParam(c, "lon", -180, 180)
+	if !ok {
+		return
+	}
+	radius, ok := floatParam(c, "radius_km", 0, maxNearRadiusKM)
+	if !ok {
+		return
+	}
+	if radius == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "radius_km must be positive"})
+		return
+	}
+
+	window, ok := h.guardrails.Window(c, EndpointClassRaw)
+	if !ok {
+		return
+	}
+
+	satelliteID := c.Query("satellite_id")
+	points, err := h.querier.QueryNear(c.Request.Context(), db.NearQuery{
+		Latitude:    lat,
+		Longitude:   lon,
+		RadiusKM:    radius,
+		SatelliteID: satelliteID,
+		From:        window.From,
+		To:          window.To,
+		Limit:       window.Limit,
+	})
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TelemetryNearResponse{
+		Latitude:    lat,
+		Longitude:   lon,
+		RadiusKM:    radius,
+		SatelliteID: satelliteID,
+		From:        window.From,
+		To:          window.To,
+		Count:       len(points),
+		Points:      points,
+	})
+}
+
+// floatParam parses a required numeric query parameter within [min, max],
+// writing a 400 and returning false when it is missing or out of range
+func floatParam(c *gin.Context, name string, min, max float64) (float64, bool) {
+	raw := c.Query(name)
+	value, err := strconv.ParseFloat(raw, 64)
+	if raw == "" || err != nil || math.IsNaN(value) || value < min || value > max {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be a number between %g and %g", name, min, max)})
+		return 0, false
+	}
+	return value, true
+}
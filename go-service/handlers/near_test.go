@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// fakeNearQuerier returns canned points and records the last query
+type fakeNearQuerier struct {
+	points []models.NearbyPoint
+	query  db.NearQuery
+}
+
+func (f *fakeNearQuerier) QueryNear(ctx context.Context, q db.NearQuery) ([]models.NearbyPoint, error) {
+	f.query = q
+	return f.points, nil
+}
+
+func TestHandleNear(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	querier := &fakeNearQuerier{points: []models.NearbyPoint{
+		{TelemetryPoint: models.TelemetryPoint{SatelliteID: "SAT-0001"}, DistanceKM: 120.5},
+	}}
+	router := gin.New()
+	router.GET("/telemetry/near", NewNearHandler(querier, newTestGuardrails()).HandleNear)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/telemetry/near"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("?lat=78.2&lon=15.4&radius_km=250&satellite_id=SAT-0001&from=2026-03-01T00:00:00Z&to=2026-03-01T00:30:00Z")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.TelemetryNearResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if response.Count != 1 || response.Points[0].DistanceKM != 120.5 || response.RadiusKM != 250 {
+		t.Errorf("unexpected response %+v", response)
+	}
+	q := querier.query
+	if q.Latitude != 78.2 || q.Longitude != 15.4 || q.RadiusKM != 250 || q.SatelliteID != "SAT-0001" {
+		t.Errorf("unexpected query %+v", q)
+	}
+
+	for _, query := range []string{
+		"?lon=15.4&radius_km=250",
+		"?lat=91&lon=15.4&radius_km=250",
+		"?lat=78.2&lon=-181&radius_km=250",
+		"?lat=78.2&lon=15.4&radius_km=0",
+		"?lat=78.2&lon=15.4&radius_km=abc",
+		"?lat=NaN&lon=15.4&radius_km=250",
+	} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// ReadinessConfig sets the conditions /readyz checks. The zero value checks
+// everything, with the buffer limit at the backpressure high-water mark.
+type ReadinessConfig struct {
+	// MaxBuffered is the buffered point count at which the instance stops
+	// being ready (0 = the high-water mark)
+	MaxBuffered int
+	// SkipDatabase and SkipCircuitBreaker turn off the database
+	// reachability and insert breaker checks
+	SkipDatabase       bool
+	SkipCircuitBreaker bool
+}
+
+// SetReadiness sets the conditions /readyz checks
+func (h *TelemetryHandler) SetReadiness(cfg ReadinessConfig) {
+	h.readiness = cfg
+}
+
+// Livez reports that the process is up and serving; it checks nothing else,
+// so an orchestrator only restarts instances that stopped answering
+// GET /livez
+func (h *TelemetryHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// Readyz reports whether the instance should get traffic: the database is
+// reachable, the buffer is below its limit, the insert circuit breaker is
+// not open and the service is not draining for shutdown. An instance that
+// fails it keeps running and flushing, so it becomes ready again on its own.
+// GET /readyz
+func (h *TelemetryHandler) Readyz(c *gin.Context) {
+	response := models.ReadinessResponse{
+		Status:    "ready",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Checks:    make(map[string]models.ReadinessCheck),
+	}
+
+	if bp, ok := h.batchProcessor.(*db.BatchProcessor); ok {
+		if !h.readiness.SkipDatabase {
+			ctx, cancel := context.WithTimeout(c, 1*time.Second)
+			response.Checks["database"] = h.databaseCheck(ctx, bp)
+			cancel()
+		}
+		response.Checks["buffer"] = h.bufferCheck(bp)
+		if cb := bp.GetCircuitBreaker(); cb != nil && !h.readiness.SkipCircuitBreaker {
+			state := cb.State()
+			response.Checks["circuit_breaker"] = models.ReadinessCheck{
+				OK:     state != db.Open,
+				Detail: state.String(),
+			}
+		}
+		response.Checks["draining"] = models.ReadinessCheck{OK: !bp.Draining()}
+	}
+
+	httpStatus := http.StatusOK
+	for _, check := range response.Checks {
+		if !check.OK {
+			response.Status = "not_ready"
+			httpStatus = http.StatusServiceUnavailable
+		}
+	}
+	c.JSON(httpStatus, response)
+}
+
+// databaseCheck uses the health monitor's cached check when there is one
+func (h *TelemetryHandler) databaseCheck(ctx context.Context, bp *db.BatchProcessor) models.ReadinessCheck {
+	if h.healthMonitor != nil {
+		health := h.healthMonitor.Database(ctx)
+		if health.Healthy {
+			return models.ReadinessCheck{OK: true}
+		}
+		detail := "unreachable"
+		if health.Err != nil {
+			detail = health.Err.Error()
+		}
+		return models.ReadinessCheck{Detail: detail}
+	}
+
+	pool := bp.GetPool()
+	if h.healthPool != nil {
+		pool = h.healthPool
+	}
+	if pool == nil {
+		return models.ReadinessCheck{Detail: "no database configured"}
+	}
+	if err := pool.Ping(ctx); err != nil {
+		return models.ReadinessCheck{Detail: err.Error()}
+	}
+	return models.ReadinessCheck{OK: true}
+}
+
+// bufferCheck compares the buffered points with the configured limit or the
+// high-water mark; without either the buffer never fails readiness
+func (h *TelemetryHandler) bufferCheck(bp *db.BatchProcessor) models.ReadinessCheck {
+	state := bp.Backpressure()
+	limit := h.readiness.MaxBuffered
+	if limit <= 0 {
+		limit = state.HighWaterMark
+	}
+	if limit <= 0 {
+		return models.ReadinessCheck{OK: true, Detail: fmt.Sprintf("%d buffered", state.Buffered)}
+	}
+	return models.ReadinessCheck{
+		OK:     state.Buffered < limit,
+		Detail: fmt.Sprintf("%d of %d buffered", state.Buffered, limit),
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+	"orbitstream/test"
+)
+
+func setupProbeRouter(handler *TelemetryHandler) *gin.Engine {
+	router := gin.New()
+	router.GET("/livez", handler.Livez)
+	router.GET("/readyz", handler.Readyz)
+	return router
+}
+
+func readiness(t *testing.T, router *gin.Engine) (int, models.ReadinessResponse) {
+	t.Helper()
+	w := doGet(router, "/readyz")
+	var response models.ReadinessResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	return w.Code, response
+}
+
+func TestLivez(t *testing.T) {
+	router := setupProbeRouter(NewTelemetryHandler(test.NewMockBatchProcessor()))
+	if w := doGet(router, "/livez"); w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestReadyzBufferAboveLimit(t *testing.T) {
+	bp := newTestBatchProcessor()
+	handler := NewTelemetryHandlerWithDB(bp)
+	handler.SetReadiness(ReadinessConfig{MaxBuffered: 2, SkipDatabase: true})
+	router := setupProbeRouter(handler)
+
+	if code, response := readiness(t, router); code != http.StatusOK || response.Status != "ready" {
+		t.Fatalf("expected ready with an empty buffer, got %d %+v", code, response)
+	}
+	for _, id := range []string{"SAT-001", "SAT-002"} {
+		if err := bp.Add(test.NewTestTelemetryPointWithSatelliteID(id)); err != nil {
+			t.Fatalf("failed to add point: %v", err)
+		}
+	}
+	code, response := readiness(t, router)
+	if code != http.StatusServiceUnavailable || response.Status != "not_ready" {
+		t.Errorf("expected not ready at the buffer limit, got %d %+v", code, response)
+	}
+	if check := response.Checks["buffer"]; check.OK || check.Detail != "2 of 2 buffered" {
+		t.Errorf("unexpected buffer check %+v", check)
+	}
+}
+
+func TestReadyzOpenCircuitBreaker(t *testing.T) {
+	bp := newTestBatchProcessor()
+	cb := db.NewCircuitBreaker(1, time.Minute)
+	bp.SetCircuitBreaker(cb)
+	handler := NewTelemetryHandlerWithDB(bp)
+	handler.SetReadiness(ReadinessConfig{SkipDatabase: true})
+	router := setupProbeRouter(handler)
+
+	cb.RecordFailure()
+	if code, response := readiness(t, router); code != http.StatusServiceUnavailable || response.Checks["circuit_breaker"].OK {
+		t.Errorf("expected not ready with the breaker open, got %d %+v", code, response)
+	}
+
+	handler.SetReadiness(ReadinessConfig{SkipDatabase: true, SkipCircuitBreaker: true})
+	if code, response := readiness(t, router); code != http.StatusOK {
+		t.Errorf("expected ready with the breaker check off, got %d %+v", code, response)
+	}
+}
+
+func TestReadyzDraining(t *testing.T) {
+	bp := newTestBatchProcessor()
+	handler := NewTelemetryHandlerWithDB(bp)
+	handler.SetReadiness(ReadinessConfig{SkipDatabase: true})
+	router := setupProbeRouter(handler)
+
+	if err := bp.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if code, response := readiness(t, router); code != http.StatusServiceUnavailable || response.Checks["draining"].OK {
+		t.Errorf("expected not ready while draining, got %d %+v", code, response)
+	}
+}
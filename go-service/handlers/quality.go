@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// GroundStationHeader names the ground station that downlinked a request's
+// telemetry; without it the client identity is used
+const GroundStationHeader = "X-Ground-Station"
+
+// StationFrom returns the ground station a request is attributed to: the
+// X-Ground-Station header, else the verified client certificate subject,
+// else the client IP
+func StationFrom(c *gin.Context) string {
+	if station := c.GetHeader(GroundStationHeader); station != "" {
+		return station
+	}
+	identity := ClientIdentityFrom(c)
+	if identity.CertSubject != "" {
+		return identity.CertSubject
+	}
+	return identity.IP
+}
+
+// qualityRange is the plausible range of one numeric telemetry field
+type qualityRange struct {
+	field    string
+	min, max float64
+	value    func(p *models.TelemetryPoint) *float64
+}
+
+// qualityRanges are checked on every point; nil optional fields are skipped
+// (and counted as missing instead)
+var qualityRanges = []qualityRange{
+	{"battery_charge_percent", 0, 100, func(p *models.TelemetryPoint) *float64 { return &p.BatteryChargePercent }},
+	{"storage_usage_mb", 0, math.MaxFloat64, func(p *models.TelemetryPoint) *float64 { return &p.StorageUsageMB }},
+	{"signal_strength_dbm", -150, 0, func(p *models.TelemetryPoint) *float64 { return &p.SignalStrengthDBM }},
+	{"latitude", -90, 90, func(p *models.TelemetryPoint) *float64 { return p.Latitude }},
+	{"longitude", -180, 180, func(p *models.TelemetryPoint) *float64 { return p.Longitude }},
+	{"altitude_km", 0, math.MaxFloat64, func(p *models.TelemetryPoint) *float64 { return p.AltitudeKM }},
+	{"velocity_kmph", 0, math.MaxFloat64, func(p *models.TelemetryPoint) *float64 { return p.VelocityKMPH }},
+}
+
+// qualityOptionalFields are counted as missing when a point omits them
+var qualityOptionalFields = []struct {
+	field   string
+	missing func(p *models.TelemetryPoint) bool
+}{
+	{"satellite_id", func(p *models.TelemetryPoint) bool { return p.SatelliteID == "" }},
+	{"latitude", func(p *models.TelemetryPoint) bool { return p.Latitude == nil }},
+	{"longitude", func(p *models.TelemetryPoint) bool { return p.Longitude == nil }},
+	{"altitude_km", func(p *models.TelemetryPoint) bool { return p.AltitudeKM == nil }},
+	{"velocity_kmph", func(p *models.TelemetryPoint) bool { return p.VelocityKMPH == nil }},
+	{"software_version", func(p *models.TelemetryPoint) bool { return p.SoftwareVersion == nil }},
+}
+
+// timestampField is counted as missing when the server had to stamp a point
+const timestampField = "timestamp"
+
+// clockSkewBounds are the upper bounds of the skew histogram; a final
+// bucket holds everything beyond the last bound
+var clockSkewBounds = []time.Duration{
+	time.Second, 10 * time.Second, time.Minute, 10 * time.Minute, time.Hour,
+}
+
+// clockSkewFutureTolerance allows for clock jitter before a timestamp ahead
+// of the server clock is counted as "future"
+const clockSkewFutureTolerance = time.Second
+
+// stationQuality accumulates validation counts for one ground station
+type stationQuality struct {
+	points     int64
+	valid      int64
+	lastSeen   time.Time
+	outOfRange map[string]int64
+	missing    map[string]int64
+	// skew histogram: future, one per bound, then beyond the last bound
+	skewBuckets []int64
+	skewSamples int64
+	skewSum     time.Duration
+	skewMin     time.Duration
+	skewMax     time.Duration
+}
+
+// DataQualityTracker keeps per-ground-station validation statistics of
+// ingested telemetry: out-of-range and missing-field rates and the clock
+// skew between point timestamps and arrival. Points are only counted, never
+// rejected.
+type DataQualityTracker struct {
+	mu       sync.Mutex
+	stations map[string]*stationQuality
+	// Stations beyond maxStations are counted under "other" (0 = unlimited)
+	maxStations int
+}
+
+// NewDataQualityTracker creates an empty tracker
+func NewDataQualityTracker() *DataQualityTracker {
+	return &DataQualityTracker{stations: make(map[string]*stationQuality)}
+}
+
+// SetMaxStations caps the stations with their own scorecard; points from
+// further stations are counted under "other"
+func (t *DataQualityTracker) SetMaxStations(limit int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxStations = limit
+}
+
+// Observe records one point received from station at received.
+// timestamped reports whether the point carried its own timestamp.
+// A nil tracker ignores the call.
+func (t *DataQualityTracker) Observe(station string, point models.TelemetryPoint, timestamped bool, received time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.stations[station]
+	if !ok && t.maxStations > 0 && len(t.stations) >= t.maxStations {
+		if _, other := t.stations[db.CardinalityOther]; !other {
+			log.Printf("WARNING: more than %d ground stations, counting new ones as %q", t.maxStations, db.CardinalityOther)
+		}
+		station = db.CardinalityOther
+		s, ok = t.stations[station]
+	}
+	if !ok {
+		s = &stationQuality{
+			outOfRange:  make(map[string]int64),
+			missing:     make(map[string]int64),
+			skewBuckets: make([]int64, len(clockSkewBounds)+2),
+		}
+		t.stations[station] = s
+	}
+	s.points++
+	s.lastSeen = received
+
+	valid := true
+	for _, r := range qualityRanges {
+		value := r.value(&point)
+		if value != nil && (*value < r.min || *value > r.max || math.IsNaN(*value)) {
+			s.outOfRange[r.field]++
+			valid = false
+		}
+	}
+	if valid {
+		s.valid++
+	}
+	for _, f := range qualityOptionalFields {
+		if f.missing(&point) {
+			s.missing[f.field]++
+		}
+	}
+
+	if !timestamped {
+		s.missing[timestampField]++
+		return
+	}
+	skew := received.Sub(point.Timestamp)
+	if s.skewSamples == 0 || skew < s.skewMin {
+		s.skewMin = skew
+	}
+	if s.skewSamples == 0 || skew > s.skewMax {
+		s.skewMax = skew
+	}
+	s.skewSamples++
+	s.skewSum += skew
+	s.skewBuckets[skewBucket(skew)]++
+}
+
+// skewBucket returns the histogram index for skew
+func skewBucket(skew time.Duration) int {
+	if skew < -clockSkewFutureTolerance {
+		return 0
+	}
+	for i, bound := range clockSkewBounds {
+		if skew <= bound {
+			return i + 1
+		}
+	}
+	return len(clockSkewBounds) + 1
+}
+
+// Report returns the scorecard of one station
+func (t *DataQualityTracker) Report(station string) (models.DataQualityReport, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.stations[station]
+	if !ok {
+		return models.DataQualityReport{}, false
+	}
+	return s.report(station), true
+}
+
+// Reports returns the scorecards of every station, by name
+func (t *DataQualityTracker) Reports() []models.DataQualityReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	reports := make([]models.DataQualityReport, 0, len(t.stations))
+	for station, s := range t.stations {
+		reports = append(reports, s.report(station))
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Station < reports[j].Station })
+	return reports
+}
+
+func (s *stationQuality) report(station string) models.DataQualityReport {
+	rate := func(count int64) models.FieldRate {
+		return models.FieldRate{Count: count, Rate: float64(count) / float64(s.points)}
+	}
+	report := models.DataQualityReport{
+		Station:    station,
+		Points:     s.points,
+		LastSeen:   s.lastSeen,
+		ValidRate:  float64(s.valid) / float64(s.points),
+		OutOfRange: make(map[string]models.FieldRate, len(s.outOfRange)),
+		Missing:    make(map[string]models.FieldRate, len(s.missing)),
+	}
+	for field, count := range s.outOfRange {
+		report.OutOfRange[field] = rate(count)
+	}
+	for field, count := range s.missing {
+		report.Missing[field] = rate(count)
+	}
+
+	skew := models.ClockSkewStats{Samples: s.skewSamples}
+	if s.skewSamples > 0 {
+		skew.MeanSeconds = (s.skewSum / time.Duration(s.skewSamples)).Seconds()
+		skew.MinSeconds = s.skewMin.Seconds()
+		skew.MaxSeconds = s.skewMax.Seconds()
+	}
+	skew.Buckets = append(skew.Buckets, models.ClockSkewBucket{LE: "future", Count: s.skewBuckets[0]})
+	for i, bound := range clockSkewBounds {
+		skew.Buckets = append(skew.Buckets, models.ClockSkewBucket{LE: bound.String(), Count: s.skewBuckets[i+1]})
+	}
+	skew.Buckets = append(skew.Buckets, models.ClockSkewBucket{LE: "+Inf", Count: s.skewBuckets[len(clockSkewBounds)+1]})
+	report.ClockSkew = skew
+	return report
+}
+
+// HandleDataQuality returns per-ground-station data quality scorecards
+// GET /stats/data-quality?station=
+// Without station every station seen since startup is returned.
+func (t *DataQualityTracker) HandleDataQuality(c *gin.Context) {
+	if station := c.Query("station"); station != "" {
+		report, ok := t.Report(station)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no telemetry received from station " + station})
+			return
+		}
+		c.JSON(http.StatusOK, models.DataQualityResponse{Count: 1, Stations: []models.DataQualityReport{report}})
+		return
+	}
+
+	reports := t.Reports()
+	c.JSON(http.StatusOK, models.DataQualityResponse{Count: len(reports), Stations: reports})
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+	"orbitstream/test"
+)
+
+func TestDataQualityTrackerCountsProblems(t *testing.T) {
+	tracker := NewDataQualityTracker()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	good := test.NewTestTelemetryPoint()
+	good.Timestamp = now.Add(-5 * time.Second)
+	tracker.Observe("GS-SVALBARD", good, true, now)
+
+	bad := test.NewTestTelemetryPoint()
+	bad.BatteryChargePercent = 140
+	latitude := 95.0
+	bad.Latitude = &latitude
+	bad.Timestamp = now.Add(2 * time.Minute)
+	tracker.Observe("GS-SVALBARD", bad, true, now)
+
+	tracker.Observe("GS-SVALBARD", test.NewTestTelemetryPoint(), false, now)
+
+	report, ok := tracker.Report("GS-SVALBARD")
+	if !ok {
+		t.Fatal("expected a report for GS-SVALBARD")
+	}
+	if report.Points != 3 {
+		t.Errorf("expected 3 points, got %d", report.Points)
+	}
+	if got := report.OutOfRange["battery_charge_percent"]; got.Count != 1 || got.Rate != 1.0/3 {
+		t.Errorf("unexpected battery out-of-range rate %+v", got)
+	}
+	if got := report.OutOfRange["latitude"]; got.Count != 1 {
+		t.Errorf("expected one out-of-range latitude, got %+v", got)
+	}
+	if report.ValidRate != 2.0/3 {
+		t.Errorf("expected valid rate 2/3, got %v", report.ValidRate)
+	}
+	if got := report.Missing["timestamp"]; got.Count != 1 {
+		t.Errorf("expected one missing timestamp, got %+v", got)
+	}
+
+	skew := report.ClockSkew
+	if skew.Samples != 2 || skew.MinSeconds != -120 || skew.MaxSeconds != 5 {
+		t.Errorf("unexpected clock skew %+v", skew)
+	}
+	if skew.Buckets[0].LE != "future" || skew.Buckets[0].Count != 1 {
+		t.Errorf("expected one future timestamp, got %+v", skew.Buckets[0])
+	}
+	if skew.Buckets[2].LE != "10s" || skew.Buckets[2].Count != 1 {
+		t.Errorf("expected one skew within 10s, got %+v", skew.Buckets[2])
+	}
+
+	if _, ok := tracker.Report("GS-UNKNOWN"); ok {
+		t.Error("expected no report for an unseen station")
+	}
+}
+
+func TestHandleDataQuality(t *testing.T) {
+	tracker := NewDataQualityTracker()
+	handler := NewTelemetryHandler(test.NewMockBatchProcessor())
+	handler.SetDataQuality(tracker)
+
+	router := gin.New()
+	router.POST("/telemetry/batch", handler.HandleTelemetryBatch)
+	router.GET("/stats/data-quality", tracker.HandleDataQuality)
+
+	points := []models.TelemetryPoint{test.NewTestTelemetryPoint(), test.NewTestTelemetryPoint()}
+	body, _ := json.Marshal(points)
+	req, _ := http.NewRequest("POST", "/telemetry/batch", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(GroundStationHeader, "GS-KIRUNA")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := doGet(router, "/stats/data-quality?station=GS-KIRUNA")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.DataQualityResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response.Count != 1 || response.Stations[0].Station != "GS-KIRUNA" || response.Stations[0].Points != 2 {
+		t.Errorf("unexpected response %+v", response)
+	}
+
+	if w := doGet(router, "/stats/data-quality?station=GS-NOWHERE"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unseen station, got %d", w.Code)
+	}
+	if w := doGet(router, "/stats/data-quality"); w.Code != http.StatusOK {
+		t.Errorf("expected status 200 listing all stations, got %d", w.Code)
+	}
+}
+
+func TestDataQualityTrackerMaxStations(t *testing.T) {
+	tracker := NewDataQualityTracker()
+	tracker.SetMaxStations(2)
+	now := time.Now()
+
+	for _, station := range []string{"GS-1", "GS-2", "GS-3", "GS-4", "GS-1"} {
+		tracker.Observe(station, test.NewTestTelemetryPoint(), false, now)
+	}
+
+	reports := tracker.Reports()
+	if len(reports) != 3 {
+		t.Fatalf("expected GS-1, GS-2 and other, got %d reports", len(reports))
+	}
+	other, ok := tracker.Report(db.CardinalityOther)
+	if !ok || other.Points != 2 {
+		t.Errorf("expected GS-3 and GS-4 counted under other, got %+v", other)
+	}
+	if report, _ := tracker.Report("GS-1"); report.Points != 2 {
+		t.Errorf("expected 2 points for GS-1, got %d", report.Points)
+	}
+}
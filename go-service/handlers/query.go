@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/alerting"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// TelemetryQuerier defines the read operations used by the query endpoints
+// This allows for mocking in tests
+type TelemetryQuerier interface {
+	QueryTelemetry(ctx context.Context, q db.TelemetryQuery) ([]models.TelemetryPoint, error)
+	QueryTelemetryAsOf(ctx context.Context, q db.TelemetryQuery) ([]models.TelemetryPointAsOf, error)
+	QueryStats(ctx context.Context, q db.TelemetryQuery, resolution string) ([]models.StatsBucket, error)
+	QueryVersionStats(ctx context.Context, q db.TelemetryQuery) ([]models.VersionStatsBucket, error)
+	StatsDataThrough(ctx context.Context, resolution string, loc *time.Location) (*time.Time, error)
+	VersionStatsDataThrough(ctx context.Context) (*time.Time, error)
+}
+
+// QueryHandler serves read endpoints for raw telemetry and aggregates
+type QueryHandler struct {
+	querier    TelemetryQuerier
+	guardrails *QueryGuardrails
+	runbooks   *alerting.Runbooks
+	// statsLocation is the default zone of daily stats buckets (nil = UTC)
+	statsLocation *time.Location
+}
+
+// NewQueryHandler creates a query handler; all reads go through the guardrails
+func NewQueryHandler(querier TelemetryQuerier, guardrails *QueryGuardrails) *QueryHandler {
+	return &QueryHandler{
+		querier:    querier,
+		guardrails: guardrails,
+	}
+}
+
+// SetRunbooks attaches remediation runbooks to anomalies in query responses
+func (h *QueryHandler) SetRunbooks(runbooks *alerting.Runbooks) {
+	h.runbooks = runbooks
+}
+
+// SetStatsLocation sets the zone daily stats buckets start at midnight in
+// when the request has no tz parameter
+func (h *QueryHandler) SetStatsLocation(loc *time.Location) {
+	h.statsLocation = loc
+}
+
+// HandleQueryTelemetry returns raw telemetry points for one satellite
+// GET /telemetry?satellite_id=&from=&to=&limit=&min_severity=
+// min_severity (info, warning, critical) returns only anomalies at or above it;
+// registry=true attaches the registry attributes in force at each point's time
+func (h *QueryHandler) HandleQueryTelemetry(c *gin.Context) {
+	satelliteID := c.Query("satellite_id")
+	if satelliteID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "satellite_id is required"})
+		return
+	}
+
+	minSeverity := models.AnomalySeverity(c.Query("min_severity"))
+	if minSeverity != "" && !minSeverity.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_severity must be one of info, warning, critical"})
+		return
+	}
+
+	withRegistry := false
+	if raw := c.Query("registry"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "registry must be true or false"})
+			return
+		}
+		withRegistry = parsed
+	}
+
+	window, ok := h.guardrails.Window(c, EndpointClassRaw)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	q := db.TelemetryQuery{
+		SatelliteID: satelliteID,
+		From:        window.From,
+		To:          window.To,
+		Limit:       window.Limit,
+		MinSeverity: minSeverity,
+	}
+	var points []models.TelemetryPointAsOf
+	var err error
+	if withRegistry {
+		points, err = h.querier.QueryTelemetryAsOf(ctx, q)
+	} else {
+		var raw []models.TelemetryPoint
+		raw, err = h.querier.QueryTelemetry(ctx, q)
+		points = make([]models.TelemetryPointAsOf, len(raw))
+		for i, p := range raw {
+			points[i].TelemetryPoint = p
+		}
+	}
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+
+	stored := make([]models.TelemetryPoint, len(points))
+	for i, p := range points {
+		stored[i] = p.TelemetryPoint
+	}
+
+	c.JSON(http.StatusOK, models.TelemetryQueryResponse{
+		SatelliteID: satelliteID,
+		From:        window.From,
+		To:          window.To,
+		Count:       len(points),
+		Points:      points,
+		Runbooks:    h.runbooks.ForPoints(stored),
+	})
+}
+
+// HandleStats returns aggregate buckets for one satellite
+// GET /stats?satellite_id=&from=&to=&resolution=&limit=&tz=
+// resolution is one of 5m, 1h, 1d and is chosen from the range when omitted;
+// tz (IANA name, e.g. America/Chicago) aligns daily buckets to local midnight
+// and reports bucket times in that zone. data_through is the end of the
+// aggregate's newest refreshed bucket across the fleet.
+func (h *QueryHandler) HandleStats(c *gin.Context) {
+	satelliteID := c.Query("satellite_id")
+	if satelliteID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "satellite_id is required"})
+		return
+	}
+
+	window, ok := h.guardrails.Window(c, EndpointClassAggregate)
+	if !ok {
+		return
+	}
+
+	resolution := c.Query("resolution")
+	switch resolution {
+	case "":
+		resolution = db.ResolutionForRange(window.To.Sub(window.From))
+	case db.Resolution5Min, db.ResolutionHourly, db.ResolutionDaily:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resolution must be one of 5m, 1h, 1d"})
+		return
+	}
+
+	loc := h.statsLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	if tz := c.Query("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tz must be an IANA time zone name, e.g. Europe/Berlin"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	buckets, err := h.querier.QueryStats(ctx, db.TelemetryQuery{
+		SatelliteID: satelliteID,
+		From:        window.From,
+		To:          window.To,
+		Limit:       window.Limit,
+		Location:    loc,
+	}, resolution)
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+	dataThrough, err := h.querier.StatsDataThrough(ctx, resolution, loc)
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.StatsResponse{
+		SatelliteID: satelliteID,
+		From:        window.From,
+		To:          window.To,
+		Resolution:  resolution,
+		TimeZone:    loc.String(),
+		DataThrough: dataThrough,
+		Count:       len(buckets),
+		Buckets:     buckets,
+	})
+}
+
+// HandleVersionStats returns hourly stats split by flight software version
+// GET /stats/versions?satellite_id=&from=&to=&limit=
+func (h *QueryHandler) HandleVersionStats(c *gin.Context) {
+	satelliteID := c.Query("satellite_id")
+	if satelliteID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "satellite_id is required"})
+		return
+	}
+
+	window, ok := h.guardrails.Window(c, EndpointClassAggregate)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	buckets, err := h.querier.QueryVersionStats(ctx, db.TelemetryQuery{
+		SatelliteID: satelliteID,
+		From:        window.From,
+		To:          window.To,
+		Limit:       window.Limit,
+	})
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+	dataThrough, err := h.querier.VersionStatsDataThrough(ctx)
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.VersionStatsResponse{
+		SatelliteID: satelliteID,
+		From:        window.From,
+		To:          window.To,
+		DataThrough: dataThrough,
+		Count:       len(buckets),
+		Buckets:     buckets,
+	})
+}
@@ -0,0 +1,482 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/alerting"
+	"orbitstream/db"
+	"orbitstream/models"
+	"orbitstream/test"
+)
+
+func newTestGuardrails() *QueryGuardrails {
+	return NewQueryGuardrails(
+		QueryLimits{MaxRange: 24 * time.Hour, MaxRows: 1000},
+		QueryLimits{MaxRange: 30 * 24 * time.Hour, MaxRows: 500},
+	)
+}
+
+func setupQueryRouter(handler *QueryHandler, role string) *gin.Engine {
+	router := gin.New()
+	if role != "" {
+		router.Use(func(c *gin.Context) {
+			c.Set(ContextKeyRole, role)
+			c.Next()
+		})
+	}
+	router.GET("/telemetry", handler.HandleQueryTelemetry)
+	router.GET("/stats", handler.HandleStats)
+	router.GET("/stats/versions", handler.HandleVersionStats)
+	return router
+}
+
+func doGet(router *gin.Engine, url string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", url, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestQueryTelemetryWithinLimits(t *testing.T) {
+	querier := &test.MockTelemetryQuerier{
+		Points: []models.TelemetryPoint{test.NewTestTelemetryPoint()},
+	}
+	router := setupQueryRouter(NewQueryHandler(querier, newTestGuardrails()), "")
+
+	w := doGet(router, "/telemetry?satellite_id=SAT-0001&from=2024-01-01T00:00:00Z&to=2024-01-01T06:00:00Z&limit=100")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.TelemetryQueryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response.Count != 1 {
+		t.Errorf("expected count 1, got %d", response.Count)
+	}
+	if querier.LastQuery.Limit != 100 {
+		t.Errorf("expected limit 100 passed to querier, got %d", querier.LastQuery.Limit)
+	}
+	if querier.LastQuery.SatelliteID != "SAT-0001" {
+		t.Errorf("expected satellite SAT-0001, got %s", querier.LastQuery.SatelliteID)
+	}
+}
+
+func TestQueryTelemetryRangeTooLarge(t *testing.T) {
+	querier := &test.MockTelemetryQuerier{}
+	router := setupQueryRouter(NewQueryHandler(querier, newTestGuardrails()), "")
+
+	w := doGet(router, "/telemetry?satellite_id=SAT-0001&from=2023-01-01T00:00:00Z&to=2024-01-01T00:00:00Z")
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if _, ok := response["guidance"]; !ok {
+		t.Error("expected guidance field in 422 response")
+	}
+	if response["endpoint_class"] != string(EndpointClassRaw) {
+		t.Errorf("expected endpoint_class raw, got %v", response["endpoint_class"])
+	}
+	if querier.GetCallCount() != 0 {
+		t.Error("querier must not be called when guardrails reject the request")
+	}
+}
+
+func TestQueryTelemetryLimitTooLarge(t *testing.T) {
+	querier := &test.MockTelemetryQuerier{}
+	router := setupQueryRouter(NewQueryHandler(querier, newTestGuardrails()), "")
+
+	w := doGet(router, "/telemetry?satellite_id=SAT-0001&limit=5000")
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", w.Code)
+	}
+}
+
+func TestQueryTelemetryDefaults(t *testing.T) {
+	querier := &test.MockTelemetryQuerier{}
+	router := setupQueryRouter(NewQueryHandler(querier, newTestGuardrails()), "")
+
+	w := doGet(router, "/telemetry?satellite_id=SAT-0001")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if querier.LastQuery.Limit != 1000 {
+		t.Errorf("expected default limit 1000, got %d", querier.LastQuery.Limit)
+	}
+	if got := querier.LastQuery.To.Sub(querier.LastQuery.From); got != time.Hour {
+		t.Errorf("expected default window of 1h, got %v", got)
+	}
+}
+
+func TestQueryTelemetryBadInput(t *testing.T) {
+	router := setupQueryRouter(NewQueryHandler(&test.MockTelemetryQuerier{}, newTestGuardrails()), "")
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"missing satellite_id", "/telemetry"},
+		{"invalid from", "/telemetry?satellite_id=SAT-0001&from=yesterday"},
+		{"from after to", "/telemetry?satellite_id=SAT-0001&from=2024-01-02T00:00:00Z&to=2024-01-01T00:00:00Z"},
+		{"negative limit", "/telemetry?satellite_id=SAT-0001&limit=-1"},
+		{"invalid min_severity", "/telemetry?satellite_id=SAT-0001&min_severity=urgent"},
+		{"invalid registry", "/telemetry?satellite_id=SAT-0001&registry=maybe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doGet(router, tt.url)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestQueryTelemetryMinSeverity(t *testing.T) {
+	severity := models.SeverityCritical
+	dimension := models.DimensionBattery
+	point := test.NewTestTelemetryPoint()
+	point.IsAnomaly = true
+	point.AnomalySeverity = &severity
+	point.AnomalyDimension = &dimension
+
+	querier := &test.MockTelemetryQuerier{Points: []models.TelemetryPoint{point}}
+	router := setupQueryRouter(NewQueryHandler(querier, newTestGuardrails()), "")
+
+	w := doGet(router, "/telemetry?satellite_id=SAT-0001&min_severity=warning")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if querier.LastQuery.MinSeverity != models.SeverityWarning {
+		t.Errorf("expected min severity warning passed to querier, got %q", querier.LastQuery.MinSeverity)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	returned := response["points"].([]interface{})[0].(map[string]interface{})
+	if returned["anomaly_severity"] != "critical" || returned["anomaly_dimension"] != "battery" {
+		t.Errorf("expected severity and dimension in response, got %v", returned)
+	}
+}
+
+func TestQueryTelemetryWithRegistry(t *testing.T) {
+	constellation := "polar-a"
+	querier := &test.MockTelemetryQuerier{
+		Points: []models.TelemetryPoint{test.NewTestTelemetryPoint()},
+		Registry: &models.RegistryAttributes{
+			ValidFrom:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			Constellation: &constellation,
+		},
+	}
+	router := setupQueryRouter(NewQueryHandler(querier, newTestGuardrails()), "")
+
+	w := doGet(router, "/telemetry?satellite_id=SAT-0001")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if querier.AsOfCalls != 0 {
+		t.Error("expected registry join to be off by default")
+	}
+	if strings.Contains(w.Body.String(), `"registry"`) {
+		t.Errorf("expected no registry attributes, got %s", w.Body.String())
+	}
+
+	w = doGet(router, "/telemetry?satellite_id=SAT-0001&registry=true")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if querier.AsOfCalls != 1 {
+		t.Error("expected registry join to be requested")
+	}
+
+	var response models.TelemetryQueryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	point := response.Points[0]
+	if point.SatelliteID != "SAT-0001" {
+		t.Errorf("expected point fields at the top level, got %+v", point)
+	}
+	if point.Registry == nil || point.Registry.Constellation == nil || *point.Registry.Constellation != constellation {
+		t.Errorf("expected registry attributes in response, got %+v", point.Registry)
+	}
+}
+
+func TestQueryTelemetryIncludesRunbooks(t *testing.T) {
+	severity := models.SeverityCritical
+	dimension := models.DimensionBattery
+	detector := "threshold"
+	point := test.NewTestTelemetryPoint()
+	point.IsAnomaly = true
+	point.AnomalyDetector = &detector
+	point.AnomalySeverity = &severity
+	point.AnomalyDimension = &dimension
+
+	runbooks, err := alerting.ParseRunbooks("battery:critical=https://wiki.example/runbooks/power|Enter safe mode")
+	if err != nil {
+		t.Fatalf("failed to parse runbooks: %v", err)
+	}
+	handler := NewQueryHandler(&test.MockTelemetryQuerier{Points: []models.TelemetryPoint{point}}, newTestGuardrails())
+	handler.SetRunbooks(runbooks)
+	router := setupQueryRouter(handler, "")
+
+	w := doGet(router, "/telemetry?satellite_id=SAT-0001&min_severity=critical")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response models.TelemetryQueryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(response.Runbooks) != 1 {
+		t.Fatalf("expected 1 runbook, got %+v", response.Runbooks)
+	}
+	runbook := response.Runbooks[0]
+	if runbook.Detector != "threshold" || runbook.Dimension != dimension || runbook.URL != "https://wiki.example/runbooks/power" {
+		t.Errorf("unexpected runbook %+v", runbook)
+	}
+}
+
+func TestQueryGuardrailsRoleOverride(t *testing.T) {
+	guardrails := newTestGuardrails()
+	guardrails.SetOverride("analyst", EndpointClassRaw, QueryLimits{MaxRange: 400 * 24 * time.Hour, MaxRows: 1000})
+
+	querier := &test.MockTelemetryQuerier{}
+	url := "/telemetry?satellite_id=SAT-0001&from=2023-06-01T00:00:00Z&to=2024-01-01T00:00:00Z"
+
+	w := doGet(setupQueryRouter(NewQueryHandler(querier, guardrails), "analyst"), url)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected analyst override to allow long range, got %d", w.Code)
+	}
+
+	w = doGet(setupQueryRouter(NewQueryHandler(querier, guardrails), "viewer"), url)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected default limits for other roles, got %d", w.Code)
+	}
+}
+
+func TestQueryGuardrailsAPIKeyOverrideTakesPrecedence(t *testing.T) {
+	guardrails := newTestGuardrails()
+	guardrails.SetOverride("analyst", EndpointClassRaw, QueryLimits{MaxRange: time.Hour, MaxRows: 10})
+	guardrails.SetOverride("key-123", EndpointClassRaw, QueryLimits{MaxRange: 48 * time.Hour, MaxRows: 10})
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(ContextKeyRole, "analyst")
+	c.Set(ContextKeyAPIKeyID, "key-123")
+
+	limits := guardrails.LimitsFor(c, EndpointClassRaw)
+	if limits.MaxRange != 48*time.Hour {
+		t.Errorf("expected API key override 48h, got %v", limits.MaxRange)
+	}
+
+	aggregate := guardrails.LimitsFor(c, EndpointClassAggregate)
+	if aggregate.MaxRows != 500 {
+		t.Errorf("expected aggregate default for class without override, got %d", aggregate.MaxRows)
+	}
+}
+
+func TestHandleStatsResolutionSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{"short range uses 5m", "/stats?satellite_id=SAT-0001&from=2024-01-01T00:00:00Z&to=2024-01-01T12:00:00Z", db.Resolution5Min},
+		{"few days uses hourly", "/stats?satellite_id=SAT-0001&from=2024-01-01T00:00:00Z&to=2024-01-04T00:00:00Z", db.ResolutionHourly},
+		{"long range uses daily", "/stats?satellite_id=SAT-0001&from=2024-01-01T00:00:00Z&to=2024-01-20T00:00:00Z", db.ResolutionDaily},
+		{"explicit resolution", "/stats?satellite_id=SAT-0001&from=2024-01-01T00:00:00Z&to=2024-01-20T00:00:00Z&resolution=1h", db.ResolutionHourly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			querier := &test.MockTelemetryQuerier{}
+			router := setupQueryRouter(NewQueryHandler(querier, newTestGuardrails()), "")
+
+			w := doGet(router, tt.url)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if querier.Resolution != tt.expected {
+				t.Errorf("expected resolution %s, got %s", tt.expected, querier.Resolution)
+			}
+		})
+	}
+}
+
+func TestHandleStatsRangeTooLarge(t *testing.T) {
+	router := setupQueryRouter(NewQueryHandler(&test.MockTelemetryQuerier{}, newTestGuardrails()), "")
+
+	w := doGet(router, "/stats?satellite_id=SAT-0001&from=2023-01-01T00:00:00Z&to=2024-01-01T00:00:00Z")
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", w.Code)
+	}
+}
+
+func TestHandleStatsInvalidResolution(t *testing.T) {
+	router := setupQueryRouter(NewQueryHandler(&test.MockTelemetryQuerier{}, newTestGuardrails()), "")
+
+	w := doGet(router, "/stats?satellite_id=SAT-0001&resolution=1w")
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHandleStatsQueryError(t *testing.T) {
+	querier := &test.MockTelemetryQuerier{Err: errors.New("connection refused")}
+	router := setupQueryRouter(NewQueryHandler(querier, newTestGuardrails()), "")
+
+	w := doGet(router, "/stats?satellite_id=SAT-0001")
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
+
+func TestHandleStatsCircuitOpen(t *testing.T) {
+	querier := &test.MockTelemetryQuerier{Err: fmt.Errorf("query failed: %w", db.ErrCircuitOpen)}
+	router := setupQueryRouter(NewQueryHandler(querier, newTestGuardrails()), "")
+
+	w := doGet(router, "/stats?satellite_id=SAT-0001")
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+}
+
+func TestHandleStatsTimeZone(t *testing.T) {
+	querier := &test.MockTelemetryQuerier{}
+	handler := NewQueryHandler(querier, newTestGuardrails())
+	handler.SetStatsLocation(time.UTC)
+	router := setupQueryRouter(handler, "")
+
+	w := doGet(router, "/stats?satellite_id=SAT-0001&from=2024-01-01T00:00:00Z&to=2024-01-20T00:00:00Z&tz=America/Chicago")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if loc := querier.LastQuery.Location; loc == nil || loc.String() != "America/Chicago" {
+		t.Errorf("expected location America/Chicago, got %v", loc)
+	}
+
+	var resp models.StatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.TimeZone != "America/Chicago" {
+		t.Errorf("expected time_zone America/Chicago, got %q", resp.TimeZone)
+	}
+}
+
+func TestHandleStatsDefaultTimeZone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	querier := &test.MockTelemetryQuerier{}
+	handler := NewQueryHandler(querier, newTestGuardrails())
+	handler.SetStatsLocation(berlin)
+	router := setupQueryRouter(handler, "")
+
+	w := doGet(router, "/stats?satellite_id=SAT-0001&resolution=1d")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if querier.LastQuery.Location != berlin {
+		t.Errorf("expected the configured location, got %v", querier.LastQuery.Location)
+	}
+}
+
+func TestHandleStatsInvalidTimeZone(t *testing.T) {
+	router := setupQueryRouter(NewQueryHandler(&test.MockTelemetryQuerier{}, newTestGuardrails()), "")
+
+	w := doGet(router, "/stats?satellite_id=SAT-0001&tz=Mars/Olympus_Mons")
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHandleStatsDataThrough(t *testing.T) {
+	through := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	querier := &test.MockTelemetryQuerier{DataThrough: &through}
+	router := setupQueryRouter(NewQueryHandler(querier, newTestGuardrails()), "")
+
+	for _, path := range []string{"/stats?satellite_id=SAT-0001", "/stats/versions?satellite_id=SAT-0001"} {
+		w := doGet(router, path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		var resp struct {
+			DataThrough *time.Time `json:"data_through"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.DataThrough == nil || !resp.DataThrough.Equal(through) {
+			t.Errorf("%s: expected data_through %v, got %v", path, through, resp.DataThrough)
+		}
+	}
+}
+
+func TestHandleStatsEmptyAggregate(t *testing.T) {
+	router := setupQueryRouter(NewQueryHandler(&test.MockTelemetryQuerier{}, newTestGuardrails()), "")
+
+	w := doGet(router, "/stats?satellite_id=SAT-0001")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"data_through":null`) {
+		t.Errorf("expected a null data_through, got %s", w.Body.String())
+	}
+}
+
+func TestHandleVersionStats(t *testing.T) {
+	querier := &test.MockTelemetryQuerier{
+		Versions: []models.VersionStatsBucket{
+			{SatelliteID: "SAT-0001", SoftwareVersion: "2.3.0", DataPoints: 120},
+			{SatelliteID: "SAT-0001", SoftwareVersion: "2.4.0", DataPoints: 80, AnomalyCount: 12},
+		},
+	}
+	router := setupQueryRouter(NewQueryHandler(querier, newTestGuardrails()), "")
+
+	w := doGet(router, "/stats/versions?satellite_id=SAT-0001&from=2024-01-01T00:00:00Z&to=2024-01-08T00:00:00Z")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.VersionStatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response.Count != 2 {
+		t.Errorf("expected 2 buckets, got %d", response.Count)
+	}
+	if response.Buckets[1].SoftwareVersion != "2.4.0" || response.Buckets[1].AnomalyCount != 12 {
+		t.Errorf("unexpected version bucket: %+v", response.Buckets[1])
+	}
+}
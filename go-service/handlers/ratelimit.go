@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitSweepInterval is how often buckets of idle clients are dropped
+const rateLimitSweepInterval = time.Minute
+
+// RateLimiter is a token bucket per client on the ingest routes, so a
+// misconfigured simulator flooding the endpoint gets 429s instead of
+// filling the buffer every other client shares. Clients are keyed by API
+// key ID when authenticated, else by client IP.
+type RateLimiter struct {
+	rate  float64 // tokens per second
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	limited   int64
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	limited int64
+}
+
+// RateLimitStats reports the limits and the requests refused since startup
+type RateLimitStats struct {
+	Enabled        bool             `json:"enabled"`
+	RequestsPerSec float64          `json:"requests_per_second"`
+	Burst          int              `json:"burst"`
+	Clients        int              `json:"clients"`
+	Limited        int64            `json:"limited"`
+	LimitedClients map[string]int64 `json:"limited_clients"`
+}
+
+// NewRateLimiter allows each client rps requests per second on average and
+// bursts of up to burst requests; rps <= 0 disables the limiter
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:    rps,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Enabled reports whether requests are limited
+func (rl *RateLimiter) Enabled() bool {
+	return rl != nil && rl.rate > 0
+}
+
+// Middleware answers 429 with Retry-After when the client's bucket is
+// empty. It must run after authentication to key clients by API key.
+func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rl.Enabled() {
+			c.Next()
+			return
+		}
+
+		client := rateLimitClient(c)
+		wait, ok := rl.take(client)
+		if !ok {
+			seconds := int(math.Ceil(wait.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":               fmt.Sprintf("rate limit of %g requests per second exceeded for %s", rl.rate, client),
+				"retry_after_seconds": seconds,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitClient keys the caller by API key ID, falling back to its IP
+func rateLimitClient(c *gin.Context) string {
+	if id := c.GetString(ContextKeyAPIKeyID); id != "" {
+		return "key:" + id
+	}
+	return "ip:" + ClientIdentityFrom(c).IP
+}
+
+// take removes a token from client's bucket; when it is empty it returns
+// how long until the next token instead
+func (rl *RateLimiter) take(client string) (time.Duration, bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	rl.sweep(now)
+
+	bucket, ok := rl.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: rl.burst, updated: now}
+		rl.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(rl.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*rl.rate)
+	bucket.updated = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0, true
+	}
+	if bucket.limited == 0 {
+		log.Printf("WARNING: Rate limiting %s at %g requests per second", client, rl.rate)
+	}
+	bucket.limited++
+	rl.limited++
+	return time.Duration((1 - bucket.tokens) / rl.rate * float64(time.Second)), false
+}
+
+// sweep drops the buckets that have refilled completely; a fresh bucket
+// behaves the same, so only recently active clients take memory
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	rl.lastSweep = now
+	refill := time.Duration(rl.burst / rl.rate * float64(time.Second))
+	for client, bucket := range rl.buckets {
+		if now.Sub(bucket.updated) > refill {
+			delete(rl.buckets, client)
+		}
+	}
+}
+
+// Stats returns the limits, the clients being tracked and the requests
+// refused per recently active client
+func (rl *RateLimiter) Stats() RateLimitStats {
+	stats := RateLimitStats{Enabled: rl.Enabled(), LimitedClients: make(map[string]int64)}
+	if rl == nil {
+		return stats
+	}
+	stats.RequestsPerSec = rl.rate
+	stats.Burst = int(rl.burst)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	stats.Clients = len(rl.buckets)
+	stats.Limited = rl.limited
+	for client, bucket := range rl.buckets {
+		if bucket.limited > 0 {
+			stats.LimitedClients[client] = bucket.limited
+		}
+	}
+	return stats
+}
+
+// HandleStats returns rate limit settings and refusals
+// GET /admin/rate-limits
+func (rl *RateLimiter) HandleStats(c *gin.Context) {
+	c.JSON(http.StatusOK, rl.Stats())
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupRateLimitRouter(limiter *RateLimiter) *gin.Engine {
+	router := gin.New()
+	router.POST("/telemetry", func(c *gin.Context) {
+		if key := c.GetHeader("X-Test-Key"); key != "" {
+			c.Set(ContextKeyAPIKeyID, key)
+		}
+	}, limiter.Middleware(), func(c *gin.Context) {
+		c.Status(http.StatusAccepted)
+	})
+	return router
+}
+
+func rateLimitedRequest(router *gin.Engine, remoteAddr, key string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/telemetry", nil)
+	req.RemoteAddr = remoteAddr
+	if key != "" {
+		req.Header.Set("X-Test-Key", key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	limiter := NewRateLimiter(2, 3)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	router := setupRateLimitRouter(limiter)
+
+	for i := 0; i < 3; i++ {
+		if w := rateLimitedRequest(router, "10.1.1.1:5000", ""); w.Code != http.StatusAccepted {
+			t.Fatalf("request %d within the burst: expected 202, got %d", i, w.Code)
+		}
+	}
+	w := rateLimitedRequest(router, "10.1.1.1:5000", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected 429 with Retry-After 1, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Other clients have their own bucket
+	if w := rateLimitedRequest(router, "10.2.2.2:5000", ""); w.Code != http.StatusAccepted {
+		t.Errorf("expected another IP to be accepted, got %d", w.Code)
+	}
+	if w := rateLimitedRequest(router, "10.1.1.1:5000", "station-a"); w.Code != http.StatusAccepted {
+		t.Errorf("expected an API key to be limited apart from its IP, got %d", w.Code)
+	}
+
+	// Half a second refills one token at 2 rps
+	now = now.Add(500 * time.Millisecond)
+	if w := rateLimitedRequest(router, "10.1.1.1:5000", ""); w.Code != http.StatusAccepted {
+		t.Errorf("expected a refilled token, got %d", w.Code)
+	}
+
+	stats := limiter.Stats()
+	if stats.Limited != 1 || stats.LimitedClients["ip:10.1.1.1"] != 1 || stats.Clients != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// Idle clients are dropped once their bucket would be full again
+	now = now.Add(2 * time.Minute)
+	rateLimitedRequest(router, "10.3.3.3:5000", "")
+	if clients := limiter.Stats().Clients; clients != 1 {
+		t.Errorf("expected idle clients to be swept, got %d tracked", clients)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	router := setupRateLimitRouter(NewRateLimiter(0, 1))
+	for i := 0; i < 10; i++ {
+		if w := rateLimitedRequest(router, "10.1.1.1:5000", ""); w.Code != http.StatusAccepted {
+			t.Fatalf("expected no limit, got %d", w.Code)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// ReplicationHandler serves the standby side of WAL shipping
+type ReplicationHandler struct {
+	receiver *db.StandbyReceiver
+	token    string
+}
+
+// NewReplicationHandler creates a handler for a standby instance; token is
+// the secret shared with the primary
+func NewReplicationHandler(receiver *db.StandbyReceiver, token string) *ReplicationHandler {
+	return &ReplicationHandler{receiver: receiver, token: token}
+}
+
+// RequireToken rejects requests without the shared replication token, so
+// only the primary can ship records, truncate or promote; with no token
+// configured every request is rejected
+func (h *ReplicationHandler) RequireToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented := c.GetHeader(db.ReplicationTokenHeader)
+		if h.token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(h.token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid replication token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HandleReceive accepts a batch of WAL records shipped by the primary
+// POST /replication/wal
+func (h *ReplicationHandler) HandleReceive(c *gin.Context) {
+	var records []db.WALRecord
+	if err := c.ShouldBindJSON(&records); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.receiver.Receive(records); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TelemetryResponse{
+		Status: "received",
+		Count:  len(records),
+	})
+}
+
+// HandleTruncate drops held records after the primary committed them
+// POST /replication/wal/truncate
+func (h *ReplicationHandler) HandleTruncate(c *gin.Context) {
+	if err := h.receiver.Truncate(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "truncated"})
+}
+
+// HandlePromote takes over held records so they are replayed locally
+// POST /replication/promote
+func (h *ReplicationHandler) HandlePromote(c *gin.Context) {
+	count, err := h.receiver.Promote()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":             "promoted",
+		"records_taken_over": count,
+	})
+}
+
+// HandleStatus reports the standby state
+// GET /replication/status
+func (h *ReplicationHandler) HandleStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.receiver.Status())
+}
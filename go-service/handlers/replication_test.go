@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+)
+
+func newTestStandbyReceiver(t *testing.T) *db.StandbyReceiver {
+	t.Helper()
+	dir := t.TempDir()
+	standbyWAL, err := db.NewWAL(filepath.Join(dir, "standby.wal"))
+	if err != nil {
+		t.Fatalf("failed to create standby WAL: %v", err)
+	}
+	t.Cleanup(func() { standbyWAL.Close() })
+	localWAL, err := db.NewWAL(filepath.Join(dir, "local.wal"))
+	if err != nil {
+		t.Fatalf("failed to create local WAL: %v", err)
+	}
+	t.Cleanup(func() { localWAL.Close() })
+	return db.NewStandbyReceiver(standbyWAL, localWAL)
+}
+
+// TestReplicationRequiresToken tests that only the primary's token can ship,
+// truncate or promote
+func TestReplicationRequiresToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(token string) *gin.Engine {
+		handler := NewReplicationHandler(newTestStandbyReceiver(t), token)
+		router := gin.New()
+		router.POST("/replication/wal", handler.RequireToken(), handler.HandleReceive)
+		router.POST("/replication/promote", handler.RequireToken(), handler.HandlePromote)
+		return router
+	}
+	post := func(router *gin.Engine, path, token, body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set(db.ReplicationTokenHeader, token)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	router := newRouter("secret")
+	for _, token := range []string{"", "guess"} {
+		if code := post(router, "/replication/wal", token, `[]`); code != http.StatusUnauthorized {
+			t.Errorf("token %q: expected 401 for shipped records, got %d", token, code)
+		}
+		if code := post(router, "/replication/promote", token, ""); code != http.StatusUnauthorized {
+			t.Errorf("token %q: expected 401 for promotion, got %d", token, code)
+		}
+	}
+	if code := post(router, "/replication/wal", "secret", `[]`); code != http.StatusOK {
+		t.Errorf("expected 200 with the token, got %d", code)
+	}
+
+	// Without a configured token nothing is accepted
+	if code := post(newRouter(""), "/replication/promote", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a configured token, got %d", code)
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+)
+
+// HandleSchema reports the live hypertables, continuous aggregates,
+// compression settings and policies, and how they drifted from init.sql
+// GET /admin/schema
+func (h *AdminHandler) HandleSchema(c *gin.Context) {
+	if h.schema == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schema export is unavailable"})
+		return
+	}
+	live, err := h.schema.SchemaSnapshot(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	expected, err := db.ExpectedSchema()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	drift := db.DetectDrift(expected, live)
+	c.JSON(http.StatusOK, gin.H{"schema": live, "drift": drift, "drifted": len(drift) > 0})
+}
+
+// HandleSchemaExport dumps the live schema as an idempotent SQL migration
+// GET /admin/schema/export
+func (h *AdminHandler) HandleSchemaExport(c *gin.Context) {
+	if h.schema == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schema export is unavailable"})
+		return
+	}
+	live, err := h.schema.SchemaSnapshot(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	filename := fmt.Sprintf("%s_schema_export.sql", live.GeneratedAt.Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/sql; charset=utf-8", []byte(live.Migration()))
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/alerting"
+)
+
+// maxSilenceDuration bounds a single silence so a forgotten one cannot mute
+// a satellite indefinitely
+const maxSilenceDuration = 7 * 24 * time.Hour
+
+// SilenceRequest is the body of POST /admin/silences
+type SilenceRequest struct {
+	SatelliteID string `json:"satellite_id" binding:"required"`
+	Metric      string `json:"metric"`
+	Duration    string `json:"duration" binding:"required"`
+	Reason      string `json:"reason"`
+}
+
+// SilenceHandler manages alert silences (e.g. for planned maneuvers)
+type SilenceHandler struct {
+	silences *alerting.Silences
+}
+
+// NewSilenceHandler creates a handler for the given silence set
+func NewSilenceHandler(silences *alerting.Silences) *SilenceHandler {
+	return &SilenceHandler{silences: silences}
+}
+
+// HandleCreate silences alerts for a satellite (and optionally one metric)
+// POST /admin/silences
+func (h *SilenceHandler) HandleCreate(c *gin.Context) {
+	var req SilenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be a positive Go duration (e.g. 2h)"})
+		return
+	}
+	if duration > maxSilenceDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration must not exceed " + maxSilenceDuration.String()})
+		return
+	}
+
+	silence := h.silences.Add(req.SatelliteID, req.Metric, req.Reason, duration)
+	c.JSON(http.StatusCreated, silence)
+}
+
+// HandleList returns the active silences
+// GET /admin/silences
+func (h *SilenceHandler) HandleList(c *gin.Context) {
+	c.JSON(http.StatusOK, h.silences.Active())
+}
+
+// HandleDelete lifts a silence early
+// DELETE /admin/silences/:id
+func (h *SilenceHandler) HandleDelete(c *gin.Context) {
+	if !h.silences.Remove(c.Param("id")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "silence not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/alerting"
+)
+
+func setupSilenceRouter(handler *SilenceHandler) *gin.Engine {
+	router := gin.New()
+	admin := router.Group("/admin")
+	admin.POST("/silences", handler.HandleCreate)
+	admin.GET("/silences", handler.HandleList)
+	admin.DELETE("/silences/:id", handler.HandleDelete)
+	return router
+}
+
+func TestSilenceLifecycle(t *testing.T) {
+	router := setupSilenceRouter(NewSilenceHandler(alerting.NewSilences()))
+
+	body := []byte(`{"satellite_id":"SAT-042","metric":"signal","duration":"2h","reason":"orbit raise"}`)
+	req, _ := http.NewRequest("POST", "/admin/silences", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created alerting.Silence
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if created.ID == "" || created.SatelliteID != "SAT-042" || created.Metric != "signal" {
+		t.Errorf("unexpected silence: %+v", created)
+	}
+
+	w = doGet(router, "/admin/silences")
+	var active []alerting.Silence
+	if err := json.Unmarshal(w.Body.Bytes(), &active); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(active) != 1 {
+		t.Fatalf("expected 1 active silence, got %d", len(active))
+	}
+
+	req, _ = http.NewRequest("DELETE", "/admin/silences/"+created.ID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/admin/silences/"+created.ID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for removed silence, got %d", w.Code)
+	}
+}
+
+func TestSilenceCreateRejectsBadDuration(t *testing.T) {
+	router := setupSilenceRouter(NewSilenceHandler(alerting.NewSilences()))
+
+	for _, duration := range []string{"soon", "-1h", "720h"} {
+		body := []byte(`{"satellite_id":"SAT-042","duration":"` + duration + `"}`)
+		req, _ := http.NewRequest("POST", "/admin/silences", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("duration %q: expected status 400, got %d", duration, w.Code)
+		}
+	}
+}
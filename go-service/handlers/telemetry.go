@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"orbitstream/db"
 	"orbitstream/models"
 )
@@ -19,6 +22,16 @@ type BatchProcessorInterface interface {
 
 type TelemetryHandler struct {
 	batchProcessor BatchProcessorInterface
+	healthMonitor  *db.HealthMonitor
+	healthPool     *pgxpool.Pool
+	breakers       *db.CircuitBreakerRegistry
+	dataQuality    *DataQualityTracker
+	timestamps     *TimestampNormalizer
+	validator      *PointValidator
+	limits         IngestLimits
+	// How long ingest requests wait out backpressure before a 429
+	backpressureQueue time.Duration
+	readiness         ReadinessConfig
 }
 
 func NewTelemetryHandler(bp BatchProcessorInterface) *TelemetryHandler {
@@ -34,23 +47,106 @@ func NewTelemetryHandlerWithDB(bp *db.BatchProcessor) *TelemetryHandler {
 	}
 }
 
+// SetHealthMonitor serves database status from the monitor's cached checks
+// instead of pinging the database on every health request
+func (h *TelemetryHandler) SetHealthMonitor(hm *db.HealthMonitor) {
+	h.healthMonitor = hm
+}
+
+// SetCircuitBreakers reports the state of every operation's breaker on /health
+func (h *TelemetryHandler) SetCircuitBreakers(breakers *db.CircuitBreakerRegistry) {
+	h.breakers = breakers
+}
+
+// SetHealthPool pings the database through pool (the reserved connections)
+// when no health monitor is set, instead of the insert pool
+func (h *TelemetryHandler) SetHealthPool(pool *pgxpool.Pool) {
+	h.healthPool = pool
+}
+
+// SetDataQuality records validation statistics of ingested points per
+// ground station
+func (h *TelemetryHandler) SetDataQuality(tracker *DataQualityTracker) {
+	h.dataQuality = tracker
+}
+
+// SetTimestampNormalizer applies per-satellite timestamp formats from the
+// registry; without it formats are only auto-detected
+func (h *TelemetryHandler) SetTimestampNormalizer(normalizer *TimestampNormalizer) {
+	h.timestamps = normalizer
+}
+
+// SetValidator checks points for missing and out-of-range values before
+// they are buffered; without it points are accepted unchecked
+func (h *TelemetryHandler) SetValidator(validator *PointValidator) {
+	h.validator = validator
+}
+
+// SetIngestLimits bounds the number of points per batch; the body size is
+// enforced by IngestLimits.Middleware in front of the handler
+func (h *TelemetryHandler) SetIngestLimits(limits IngestLimits) {
+	h.limits = limits
+}
+
+// bindPayload decodes the request body into payload, answering 413 when the
+// body is over the size limit and 400 when it isn't valid JSON
+func (h *TelemetryHandler) bindPayload(c *gin.Context, payload interface{}) bool {
+	err := c.ShouldBindJSON(payload)
+	switch {
+	case err == nil:
+		return true
+	case bodyTooLarge(err):
+		h.limits.abortBodyTooLarge(c)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+	return false
+}
+
 // HandleTelemetry handles a single telemetry point
 func (h *TelemetryHandler) HandleTelemetry(c *gin.Context) {
-	var point models.TelemetryPoint
+	var payload telemetryPayload
 
-	if err := c.ShouldBindJSON(&point); err != nil {
+	if !h.bindPayload(c, &payload) {
+		return
+	}
+
+	point := payload.point()
+	timestamp, timestamped, err := h.timestamps.Normalize(point.SatelliteID, payload.Timestamp)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	point.Timestamp = timestamp
+
+	if scope := SatelliteScopeFrom(c); !scope.Allows(point.SatelliteID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": scope.denial(point.SatelliteID), "satellite_id": point.SatelliteID})
+		return
+	}
+	if err := h.validator.Apply(&point, payload.missingMeasurements()); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "satellite_id": point.SatelliteID})
+		return
+	}
+
+	if !h.admit(c) {
+		return
+	}
+
+	now := time.Now().UTC()
+	h.dataQuality.Observe(StationFrom(c), point, timestamped, now)
 
 	// Set timestamp if not provided
-	if point.Timestamp.IsZero() {
-		point.Timestamp = time.Now().UTC()
+	if !timestamped {
+		point.Timestamp = now
 	}
 
 	// Add to batch (async processing)
 	if err := h.batchProcessor.Add(point); err != nil {
-		// Buffer full - return 503 Service Unavailable
+		if errors.Is(err, db.ErrTimestampOutOfRange) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		// Buffer full despite backpressure - return 503 Service Unavailable
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": fmt.Sprintf("Buffer full: %v", err),
 		})
@@ -61,36 +157,193 @@ func (h *TelemetryHandler) HandleTelemetry(c *gin.Context) {
 	c.JSON(http.StatusAccepted, models.TelemetryResponse{
 		Status:      "accepted",
 		SatelliteID: point.SatelliteID,
+		Mode:        h.ingestMode(),
 	})
 }
 
 // HandleTelemetryBatch handles a batch of telemetry points
 func (h *TelemetryHandler) HandleTelemetryBatch(c *gin.Context) {
-	var points []models.TelemetryPoint
+	var payloads []telemetryPayload
 
-	if err := c.ShouldBindJSON(&points); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !h.bindPayload(c, &payloads) || !h.limits.checkBatch(c, len(payloads)) {
+		return
+	}
+
+	// Normalize every timestamp first so a bad one rejects the whole batch
+	points := make([]models.TelemetryPoint, len(payloads))
+	timestamped := make([]bool, len(payloads))
+	for i := range payloads {
+		points[i] = payloads[i].point()
+		timestamp, ok, err := h.timestamps.Normalize(points[i].SatelliteID, payloads[i].Timestamp)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("point %d: %v", i, err)})
+			return
+		}
+		points[i].Timestamp = timestamp
+		timestamped[i] = ok
+	}
+
+	// Points of satellites outside the caller's scope and, in strict mode,
+	// invalid points are rejected one by one; the rest of the batch is still
+	// accepted
+	result := newBatchResult(len(points))
+	denied := make([]bool, len(points))
+	scope := SatelliteScopeFrom(c)
+	for i := range points {
+		if !scope.Allows(points[i].SatelliteID) {
+			denied[i] = true
+			result.reject(i, points[i].SatelliteID, models.RejectReasonForbidden, scope.denial(points[i].SatelliteID))
+		} else if err := h.validator.Apply(&points[i], payloads[i].missingMeasurements()); err != nil {
+			denied[i] = true
+			result.reject(i, points[i].SatelliteID, models.RejectReasonValidation, err.Error())
+		}
+	}
+	if len(points) > 0 && result.rejectedAll() {
+		c.JSON(result.status(), result.response)
+		return
+	}
+
+	// Back off the whole batch rather than accepting part of it
+	if !h.admit(c) {
 		return
 	}
 
 	now := time.Now().UTC()
-	acceptedCount := 0
+	station := StationFrom(c)
 	for i := range points {
-		if points[i].Timestamp.IsZero() {
+		if denied[i] {
+			continue
+		}
+		h.dataQuality.Observe(station, points[i], timestamped[i], now)
+		if !timestamped[i] {
 			points[i].Timestamp = now
 		}
+	}
+
+	// Store-and-forward recorders send points out of order; the detectors,
+	// episodes and late data tracking expect each satellite's points in time
+	// order
+	for _, i := range orderBySatelliteTime(points) {
+		if denied[i] {
+			continue
+		}
+		// A point the buffer refuses doesn't stop the rest of the batch
 		if err := h.batchProcessor.Add(points[i]); err != nil {
-			// Log error but continue processing other points
-			fmt.Printf("Error adding point %d: %v\n", i, err)
+			result.reject(i, points[i].SatelliteID, addRejectReason(err), err.Error())
 		} else {
-			acceptedCount++
+			result.response.Count++
 		}
 	}
 
-	c.JSON(http.StatusAccepted, models.TelemetryResponse{
-		Status: "accepted",
-		Count:  acceptedCount,
+	result.response.Mode = h.ingestMode()
+	c.JSON(result.status(), result.response)
+}
+
+// batchResult collects the outcome of each point of a batch request
+type batchResult struct {
+	response models.TelemetryResponse
+}
+
+func newBatchResult(received int) *batchResult {
+	return &batchResult{response: models.TelemetryResponse{Received: received}}
+}
+
+// reject records a point that was not accepted
+func (r *batchResult) reject(index int, satelliteID, reason, message string) {
+	r.response.Rejected = append(r.response.Rejected, models.RejectedPoint{
+		Index:       index,
+		SatelliteID: satelliteID,
+		Reason:      reason,
+		Error:       message,
 	})
+	if r.response.RejectedCounts == nil {
+		r.response.RejectedCounts = make(map[string]int)
+	}
+	r.response.RejectedCounts[reason]++
+}
+
+func (r *batchResult) rejectedAll() bool {
+	return len(r.response.Rejected) == r.response.Received
+}
+
+// status sets the response status and returns the HTTP status: 202 when
+// any point was accepted, otherwise 503 when points may be retried later,
+// 403 when every point was forbidden and 422 for the rest
+func (r *batchResult) status() int {
+	switch {
+	case len(r.response.Rejected) == 0:
+		r.response.Status = "accepted"
+		return http.StatusAccepted
+	case !r.rejectedAll() || r.response.Received == 0:
+		r.response.Status = "partial"
+		return http.StatusAccepted
+	}
+	r.response.Status = "rejected"
+	counts := r.response.RejectedCounts
+	switch {
+	case counts[models.RejectReasonBufferFull] > 0 || counts[models.RejectReasonDraining] > 0:
+		return http.StatusServiceUnavailable
+	case counts[models.RejectReasonForbidden] == r.response.Received:
+		return http.StatusForbidden
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+// addRejectReason classifies an error of BatchProcessorInterface.Add
+func addRejectReason(err error) string {
+	switch {
+	case errors.Is(err, db.ErrBufferFull):
+		return models.RejectReasonBufferFull
+	case errors.Is(err, db.ErrDraining):
+		return models.RejectReasonDraining
+	case errors.Is(err, db.ErrTimestampOutOfRange):
+		return models.RejectReasonTimestamp
+	default:
+		return models.RejectReasonError
+	}
+}
+
+// HandlePoolMetrics returns the connection usage and acquire waits of every
+// database pool the health monitor watches
+// GET /metrics/pools
+func (h *TelemetryHandler) HandlePoolMetrics(c *gin.Context) {
+	if h.healthMonitor == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "pool metrics need the health monitor (WAL enabled)"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pools": h.healthMonitor.PoolStats()})
+}
+
+// ingestMode returns "wal_only" while the health monitor finds the database
+// unreachable, so clients know accepted points are only journaled for now
+func (h *TelemetryHandler) ingestMode() string {
+	if h.healthMonitor != nil && h.healthMonitor.Degraded() {
+		return "wal_only"
+	}
+	return ""
+}
+
+// orderBySatelliteTime returns the order to process points in: each
+// satellite's points sorted by timestamp (ties keep their request order),
+// placed in the positions that satellite's points had in the request
+func orderBySatelliteTime(points []models.TelemetryPoint) []int {
+	positions := make(map[string][]int)
+	for i, p := range points {
+		positions[p.SatelliteID] = append(positions[p.SatelliteID], i)
+	}
+
+	order := make([]int, len(points))
+	for _, slots := range positions {
+		sorted := append([]int(nil), slots...)
+		sort.SliceStable(sorted, func(a, b int) bool {
+			return points[sorted[a]].Timestamp.Before(points[sorted[b]].Timestamp)
+		})
+		for k, slot := range slots {
+			order[slot] = sorted[k]
+		}
+	}
+	return order
 }
 
 // HealthCheck returns the health status of the service
@@ -111,7 +364,23 @@ func (h *TelemetryHandler) HealthCheck(c *gin.Context) {
 		defer cancel()
 
 		pool := realBatchProcessor.GetPool()
-		if pool != nil {
+		if h.healthPool != nil {
+			pool = h.healthPool
+		}
+		if h.healthMonitor != nil {
+			health := h.healthMonitor.Database(ctx)
+			if health.Healthy {
+				status.DatabaseStatus = "up"
+			} else {
+				status.Status = "degraded"
+				status.DatabaseStatus = "down"
+				httpStatus = http.StatusServiceUnavailable
+			}
+			if !health.CheckedAt.IsZero() {
+				status.DatabaseCheckedAt = health.CheckedAt.UTC().Format(time.RFC3339Nano)
+				status.DatabaseCheckAgeMS = time.Since(health.CheckedAt).Milliseconds()
+			}
+		} else if pool != nil {
 			err := pool.Ping(ctx)
 			if err != nil {
 				status.Status = "degraded"
@@ -131,13 +400,29 @@ func (h *TelemetryHandler) HealthCheck(c *gin.Context) {
 			}
 		}
 
+		status.IngestMode = h.ingestMode()
+		if h.healthMonitor != nil {
+			status.Pools = h.healthMonitor.PoolStats()
+		}
+
 		// Get buffer size
 		status.BufferSize = realBatchProcessor.GetBufferSize()
+		pipeline := realBatchProcessor.Stats()
+		status.Pipeline = &pipeline
 
 		// Get circuit breaker state
 		cb := realBatchProcessor.GetCircuitBreaker()
 		if cb != nil {
 			status.CircuitBreaker = cb.State().String()
+			if since := cb.StateChangedAt(); !since.IsZero() {
+				status.CircuitBreakerSince = since.UTC().Format(time.RFC3339Nano)
+			}
+		}
+		if h.breakers != nil {
+			status.CircuitBreakers = make(map[string]string)
+			for operation, state := range h.breakers.States() {
+				status.CircuitBreakers[operation] = state.String()
+			}
 		}
 	}
 
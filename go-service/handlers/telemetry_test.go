@@ -365,6 +365,72 @@ func TestHandleTelemetryBatchZeroTimestamps(t *testing.T) {
 	}
 }
 
+func TestHandleTelemetryBatchOutOfOrder(t *testing.T) {
+	mockBP := test.NewMockBatchProcessor()
+	handler := NewTelemetryHandler(mockBP)
+	router := setupTestRouter(handler)
+
+	// A recorder dump interleaving old and new data of two satellites
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(satelliteID string, minutes int) models.TelemetryPoint {
+		p := test.NewTestTelemetryPointWithSatelliteID(satelliteID)
+		p.Timestamp = base.Add(time.Duration(minutes) * time.Minute)
+		return p
+	}
+	points := []models.TelemetryPoint{
+		at("SAT-0001", 30),
+		at("SAT-0002", 5),
+		at("SAT-0001", 10),
+		at("SAT-0002", 1),
+		at("SAT-0001", 20),
+	}
+	jsonData, _ := json.Marshal(points)
+
+	req, _ := http.NewRequest("POST", "/telemetry/batch", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", w.Code)
+	}
+
+	// Each satellite in time order, in the slots its points had
+	expected := []models.TelemetryPoint{
+		at("SAT-0001", 10),
+		at("SAT-0002", 1),
+		at("SAT-0001", 20),
+		at("SAT-0002", 5),
+		at("SAT-0001", 30),
+	}
+	added := mockBP.GetAddedPoints()
+	if len(added) != len(expected) {
+		t.Fatalf("expected %d points added, got %d", len(expected), len(added))
+	}
+	for i := range expected {
+		if added[i].SatelliteID != expected[i].SatelliteID || !added[i].Timestamp.Equal(expected[i].Timestamp) {
+			t.Errorf("point %d: expected %s at %v, got %s at %v", i,
+				expected[i].SatelliteID, expected[i].Timestamp, added[i].SatelliteID, added[i].Timestamp)
+		}
+	}
+}
+
+func TestOrderBySatelliteTimeKeepsTies(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	points := []models.TelemetryPoint{
+		{SatelliteID: "SAT-0001", Timestamp: ts, BatteryChargePercent: 1},
+		{SatelliteID: "SAT-0001", Timestamp: ts.Add(-time.Minute)},
+		{SatelliteID: "SAT-0001", Timestamp: ts, BatteryChargePercent: 2},
+	}
+
+	order := orderBySatelliteTime(points)
+
+	if len(order) != 3 || order[0] != 1 || order[1] != 0 || order[2] != 2 {
+		t.Errorf("expected order [1 0 2], got %v", order)
+	}
+}
+
 func TestHandleTelemetryBatchLarge(t *testing.T) {
 	mockBP := test.NewMockBatchProcessor()
 	handler := NewTelemetryHandler(mockBP)
@@ -582,3 +648,47 @@ func TestHandleTelemetryWithoutContentType(t *testing.T) {
 		t.Errorf("expected status 202, got %d", w.Code)
 	}
 }
+
+func TestHandleTelemetryBatchReportsRejectedPoints(t *testing.T) {
+	w, _ := postValidated(t, ValidationStrict, "/telemetry/batch", `[
+		{"satellite_id": "SAT-0001", "battery_charge_percent": 80, "storage_usage_mb": 100, "signal_strength_dbm": -60},
+		{"satellite_id": "SAT-0002", "battery_charge_percent": 180, "storage_usage_mb": 100, "signal_strength_dbm": -60},
+		{"satellite_id": "SAT-0003", "storage_usage_mb": 100, "signal_strength_dbm": -60}
+	]`)
+	var response models.TelemetryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if w.Code != http.StatusAccepted || response.Status != "partial" || response.Received != 3 || response.Count != 1 {
+		t.Errorf("expected a partial batch with 1 of 3 accepted, got %d %+v", w.Code, response)
+	}
+	if len(response.Rejected) != 2 || response.Rejected[0].Index != 1 || response.Rejected[1].Index != 2 ||
+		response.Rejected[0].Reason != models.RejectReasonValidation || response.RejectedCounts[models.RejectReasonValidation] != 2 {
+		t.Errorf("expected points 1 and 2 rejected by validation, got %+v", response)
+	}
+}
+
+func TestHandleTelemetryBatchBufferFull(t *testing.T) {
+	mockBP := test.NewMockBatchProcessor()
+	mockBP.SetShouldError(true)
+	router := setupTestRouter(NewTelemetryHandler(mockBP))
+
+	jsonData, _ := json.Marshal([]models.TelemetryPoint{
+		test.NewTestTelemetryPointWithSatelliteID("SAT-0001"),
+		test.NewTestTelemetryPointWithSatelliteID("SAT-0002"),
+	})
+	req, _ := http.NewRequest("POST", "/telemetry/batch", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// A full buffer used to be logged to stdout while the batch got 202
+	var response models.TelemetryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusServiceUnavailable || response.Status != "rejected" || response.Count != 0 {
+		t.Errorf("expected 503 with nothing accepted, got %d %+v", w.Code, response)
+	}
+	if response.RejectedCounts[models.RejectReasonBufferFull] != 2 || len(response.Rejected) != 2 {
+		t.Errorf("expected both points rejected as buffer_full, got %+v", response)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+)
+
+// RouteClass groups routes sharing a timeout budget
+type RouteClass string
+
+const (
+	// RouteClassIngest covers the telemetry write endpoints
+	RouteClassIngest RouteClass = "ingest"
+	// RouteClassQuery covers bounded reads of raw telemetry and aggregates
+	RouteClassQuery RouteClass = "query"
+	// RouteClassExport covers streaming bulk exports
+	RouteClassExport RouteClass = "export"
+	// RouteClassAdmin covers operational endpoints
+	RouteClassAdmin RouteClass = "admin"
+)
+
+// RouteTimeouts bounds how long a request may run, per route class. When the
+// budget runs out the request context is cancelled, so in-flight database
+// queries are cancelled with it, and the client gets a 504.
+type RouteTimeouts struct {
+	limits map[RouteClass]time.Duration
+
+	mu       sync.Mutex
+	timeouts map[string]int64 // by route
+}
+
+// RouteTimeoutStats reports the configured budgets and the requests that
+// exceeded them since startup
+type RouteTimeoutStats struct {
+	Limits   map[RouteClass]string `json:"limits"`
+	Timeouts map[string]int64      `json:"timeouts"`
+	Total    int64                 `json:"total"`
+}
+
+// NewRouteTimeouts creates per-class timeouts; classes without a positive
+// limit are not bounded
+func NewRouteTimeouts(limits map[RouteClass]time.Duration) *RouteTimeouts {
+	return &RouteTimeouts{
+		limits:   limits,
+		timeouts: make(map[string]int64),
+	}
+}
+
+// Middleware applies the timeout of class to a route or group
+func (rt *RouteTimeouts) Middleware(class RouteClass) gin.HandlerFunc {
+	timeout := rt.limits[class]
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return
+		}
+		rt.record(c.FullPath())
+		if !c.Writer.Written() {
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{
+				"error": fmt.Sprintf("request timed out after %v", timeout),
+			})
+		}
+	}
+}
+
+func (rt *RouteTimeouts) record(route string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.timeouts[route]++
+}
+
+// Stats returns the configured limits and timeout counts per route
+func (rt *RouteTimeouts) Stats() RouteTimeoutStats {
+	stats := RouteTimeoutStats{
+		Limits:   make(map[RouteClass]string, len(rt.limits)),
+		Timeouts: make(map[string]int64),
+	}
+	for class, limit := range rt.limits {
+		if limit > 0 {
+			stats.Limits[class] = limit.String()
+		}
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	for route, count := range rt.timeouts {
+		stats.Timeouts[route] = count
+		stats.Total += count
+	}
+	return stats
+}
+
+// HandleStats returns route timeout limits and counts
+// GET /admin/timeouts
+func (rt *RouteTimeouts) HandleStats(c *gin.Context) {
+	c.JSON(http.StatusOK, rt.Stats())
+}
+
+// respondQueryError reports a failed read: 504 when the request ran out of
+// time, 500 otherwise
+func respondQueryError(c *gin.Context, err error) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "query timed out"})
+		return
+	}
+	if errors.Is(err, db.ErrCircuitOpen) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "queries are failing, circuit breaker is open"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
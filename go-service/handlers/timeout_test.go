@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/test"
+)
+
+func newTestRouteTimeouts(limit time.Duration) *RouteTimeouts {
+	return NewRouteTimeouts(map[RouteClass]time.Duration{RouteClassQuery: limit})
+}
+
+func TestRouteTimeoutCancelsSlowHandler(t *testing.T) {
+	timeouts := newTestRouteTimeouts(20 * time.Millisecond)
+	router := gin.New()
+	cancelled := make(chan bool, 1)
+	router.GET("/slow", timeouts.Middleware(RouteClassQuery), func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+			cancelled <- true
+		case <-time.After(time.Second):
+			cancelled <- false
+		}
+	})
+
+	w := doGet(router, "/slow")
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504, got %d", w.Code)
+	}
+	if !<-cancelled {
+		t.Error("expected the request context to be cancelled")
+	}
+	stats := timeouts.Stats()
+	if stats.Total != 1 || stats.Timeouts["/slow"] != 1 {
+		t.Errorf("expected one timeout recorded for /slow, got %+v", stats)
+	}
+}
+
+func TestRouteTimeoutFastHandler(t *testing.T) {
+	timeouts := newTestRouteTimeouts(time.Second)
+	router := gin.New()
+	router.GET("/fast", timeouts.Middleware(RouteClassQuery), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	w := doGet(router, "/fast")
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if stats := timeouts.Stats(); stats.Total != 0 {
+		t.Errorf("expected no timeouts, got %+v", stats)
+	}
+}
+
+func TestRouteTimeoutUnboundedClass(t *testing.T) {
+	timeouts := newTestRouteTimeouts(time.Second)
+	router := gin.New()
+	router.GET("/export", timeouts.Middleware(RouteClassExport), func(c *gin.Context) {
+		if _, ok := c.Request.Context().Deadline(); ok {
+			t.Error("expected no deadline for a class without a limit")
+		}
+		c.Status(http.StatusOK)
+	})
+
+	if w := doGet(router, "/export"); w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestQueryTelemetryTimeoutReturns504(t *testing.T) {
+	timeouts := newTestRouteTimeouts(time.Second)
+	querier := &test.MockTelemetryQuerier{Err: context.DeadlineExceeded}
+	router := gin.New()
+	router.GET("/telemetry", timeouts.Middleware(RouteClassQuery), NewQueryHandler(querier, newTestGuardrails()).HandleQueryTelemetry)
+
+	w := doGet(router, "/telemetry?satellite_id=SAT-0001")
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected status 504, got %d: %s", w.Code, w.Body.String())
+	}
+	var response map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response["error"] != "query timed out" {
+		t.Errorf("unexpected error %q", response["error"])
+	}
+}
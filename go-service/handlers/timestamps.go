@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// Timestamp formats a satellite can declare in the registry
+const (
+	TimestampFormatAuto    = ""
+	TimestampFormatRFC3339 = "rfc3339"
+	TimestampFormatEpochS  = "epoch_s"
+	TimestampFormatEpochMS = "epoch_ms"
+	// Seconds or milliseconds since the satellite's registered boot epoch
+	TimestampFormatBootS  = "boot_s"
+	TimestampFormatBootMS = "boot_ms"
+)
+
+const (
+	// epochMillisThreshold separates auto-detected epoch milliseconds from
+	// seconds: 1e11 seconds is the year 5138, 1e11 milliseconds is 1973
+	epochMillisThreshold = 1e11
+	// bootOffsetThreshold: numbers below it (1e9 seconds is 2001) are taken
+	// as boot-relative offsets when a boot epoch is registered
+	bootOffsetThreshold = 1e9
+)
+
+// telemetryPayload is a telemetry point as sent on ingest; the raw
+// timestamp shadows TelemetryPoint.Timestamp so any format can be parsed,
+// and the required measurements shadow theirs so an omitted one can be told
+// from a zero
+type telemetryPayload struct {
+	models.TelemetryPoint
+	Timestamp            json.RawMessage `json:"timestamp,omitempty"`
+	BatteryChargePercent *float64        `json:"battery_charge_percent"`
+	StorageUsageMB       *float64        `json:"storage_usage_mb"`
+	SignalStrengthDBM    *float64        `json:"signal_strength_dbm"`
+}
+
+// point returns the telemetry point with the measurements sent (zero when
+// omitted) and without a timestamp
+func (p telemetryPayload) point() models.TelemetryPoint {
+	point := p.TelemetryPoint
+	if p.BatteryChargePercent != nil {
+		point.BatteryChargePercent = *p.BatteryChargePercent
+	}
+	if p.StorageUsageMB != nil {
+		point.StorageUsageMB = *p.StorageUsageMB
+	}
+	if p.SignalStrengthDBM != nil {
+		point.SignalStrengthDBM = *p.SignalStrengthDBM
+	}
+	return point
+}
+
+// TimestampNormalizer converts ingest timestamps to UTC times. Formats are
+// auto-detected (RFC3339 strings, epoch seconds or milliseconds) unless the
+// satellite declares one in the registry, which also provides the boot
+// epoch for boot-relative offsets. A nil normalizer only auto-detects.
+type TimestampNormalizer struct {
+	source   db.TimestampProfileSource
+	interval time.Duration
+
+	mu       sync.RWMutex
+	profiles map[string]db.TimestampProfile
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewTimestampNormalizer creates a normalizer loading satellite profiles
+// from source every interval once started
+func NewTimestampNormalizer(source db.TimestampProfileSource, interval time.Duration) *TimestampNormalizer {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &TimestampNormalizer{
+		source:   source,
+		interval: interval,
+		profiles: make(map[string]db.TimestampProfile),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start loads profiles immediately and then every interval until Stop
+func (n *TimestampNormalizer) Start() {
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		ticker := time.NewTicker(n.interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := n.Refresh(ctx); err != nil {
+				log.Printf("TimestampNormalizer: failed to load profiles: %v", err)
+			}
+			cancel()
+
+			select {
+			case <-ticker.C:
+			case <-n.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the refresh loop
+func (n *TimestampNormalizer) Stop() {
+	close(n.stopCh)
+	n.wg.Wait()
+}
+
+// Refresh replaces the satellite profiles from the source
+func (n *TimestampNormalizer) Refresh(ctx context.Context) error {
+	profiles, err := n.source.TimestampProfiles(ctx)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	n.profiles = profiles
+	n.mu.Unlock()
+	return nil
+}
+
+// profile returns the registered profile of a satellite
+func (n *TimestampNormalizer) profile(satelliteID string) db.TimestampProfile {
+	if n == nil {
+		return db.TimestampProfile{}
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.profiles[satelliteID]
+}
+
+// Normalize parses the raw timestamp of a point from satelliteID. ok is false
+// when the point carries no timestamp.
+func (n *TimestampNormalizer) Normalize(satelliteID string, raw json.RawMessage) (ts time.Time, ok bool, err error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return time.Time{}, false, nil
+	}
+	profile := n.profile(satelliteID)
+
+	var text string
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid timestamp %s: %w", raw, err)
+		}
+		if text == "" {
+			return time.Time{}, false, nil
+		}
+	} else {
+		text = string(raw)
+	}
+
+	number, numErr := strconv.ParseFloat(text, 64)
+	isNumber := numErr == nil && !math.IsNaN(number) && !math.IsInf(number, 0)
+
+	switch profile.Format {
+	case TimestampFormatAuto:
+		if !isNumber {
+			return parseRFC3339(text)
+		}
+		switch {
+		case math.Abs(number) >= epochMillisThreshold:
+			return fromEpoch(number, time.Millisecond), true, nil
+		case number >= 0 && number < bootOffsetThreshold:
+			if profile.BootEpoch == nil {
+				return time.Time{}, false, fmt.Errorf("timestamp %s looks like a boot-relative offset but no boot epoch is registered for %s", text, satelliteID)
+			}
+			return profile.BootEpoch.Add(scaleDuration(number, time.Second)).UTC(), true, nil
+		default:
+			return fromEpoch(number, time.Second), true, nil
+		}
+	case TimestampFormatRFC3339:
+		return parseRFC3339(text)
+	}
+
+	if !isNumber {
+		return time.Time{}, false, fmt.Errorf("timestamp %q is not a number, %s declares %s", text, satelliteID, profile.Format)
+	}
+	switch profile.Format {
+	case TimestampFormatEpochS:
+		return fromEpoch(number, time.Second), true, nil
+	case TimestampFormatEpochMS:
+		return fromEpoch(number, time.Millisecond), true, nil
+	case TimestampFormatBootS, TimestampFormatBootMS:
+		if profile.BootEpoch == nil {
+			return time.Time{}, false, fmt.Errorf("no boot epoch is registered for %s", satelliteID)
+		}
+		unit := time.Second
+		if profile.Format == TimestampFormatBootMS {
+			unit = time.Millisecond
+		}
+		return profile.BootEpoch.Add(scaleDuration(number, unit)).UTC(), true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("unknown timestamp format %q registered for %s", profile.Format, satelliteID)
+}
+
+func parseRFC3339(text string) (time.Time, bool, error) {
+	ts, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("timestamp %q is not RFC3339 or a number", text)
+	}
+	// The zero time is what clients serializing an unset time.Time send
+	return ts.UTC(), !ts.IsZero(), nil
+}
+
+// fromEpoch converts a (possibly fractional) count of units since the Unix epoch
+func fromEpoch(value float64, unit time.Duration) time.Time {
+	return time.Unix(0, 0).Add(scaleDuration(value, unit)).UTC()
+}
+
+// scaleDuration converts a count of units to a duration, exactly for
+// integers (float math would round nanoseconds of epoch milliseconds)
+func scaleDuration(value float64, unit time.Duration) time.Duration {
+	if value == math.Trunc(value) && math.Abs(value) < float64(math.MaxInt64/int64(unit)) {
+		return time.Duration(value) * unit
+	}
+	return time.Duration(math.Round(value * float64(unit)))
+}
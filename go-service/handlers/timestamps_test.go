@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"orbitstream/db"
+	"orbitstream/test"
+)
+
+type fakeTimestampProfiles map[string]db.TimestampProfile
+
+func (f fakeTimestampProfiles) TimestampProfiles(ctx context.Context) (map[string]db.TimestampProfile, error) {
+	return f, nil
+}
+
+func newTestNormalizer(t *testing.T) *TimestampNormalizer {
+	boot := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	normalizer := NewTimestampNormalizer(fakeTimestampProfiles{
+		"SAT-MS":   {Format: TimestampFormatEpochMS},
+		"SAT-BOOT": {Format: TimestampFormatBootMS, BootEpoch: &boot},
+		"SAT-AUTO": {BootEpoch: &boot},
+		"SAT-RFC":  {Format: TimestampFormatRFC3339},
+	}, time.Minute)
+	if err := normalizer.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	return normalizer
+}
+
+func TestTimestampNormalize(t *testing.T) {
+	normalizer := newTestNormalizer(t)
+	want := time.Date(2026, 3, 1, 12, 0, 1, 500_000_000, time.UTC)
+
+	cases := []struct {
+		satellite string
+		raw       string
+		want      time.Time
+	}{
+		{"SAT-ANY", `"2026-03-01T13:00:01.5+01:00"`, want},
+		{"SAT-ANY", `1772366401.5`, want},
+		{"SAT-ANY", `1772366401500`, want},
+		{"SAT-ANY", `"1772366401500"`, want},
+		{"SAT-MS", `1772366401500`, want},
+		{"SAT-BOOT", `1500`, want},
+		{"SAT-AUTO", `1.5`, want},
+	}
+	for _, tc := range cases {
+		got, ok, err := normalizer.Normalize(tc.satellite, json.RawMessage(tc.raw))
+		if err != nil || !ok || !got.Equal(tc.want) {
+			t.Errorf("Normalize(%s, %s) = %v, %v, %v; want %v", tc.satellite, tc.raw, got, ok, err, tc.want)
+		}
+	}
+
+	for _, raw := range []string{``, `null`, `""`, `"0001-01-01T00:00:00Z"`} {
+		if _, ok, err := normalizer.Normalize("SAT-ANY", json.RawMessage(raw)); ok || err != nil {
+			t.Errorf("expected %q to count as no timestamp, got ok=%v err=%v", raw, ok, err)
+		}
+	}
+
+	errorCases := []struct {
+		satellite string
+		raw       string
+	}{
+		{"SAT-ANY", `"yesterday"`},
+		{"SAT-ANY", `3600`}, // boot offset without a boot epoch
+		{"SAT-RFC", `1772366401`},
+		{"SAT-MS", `"2026-03-01T12:00:01Z"`},
+		{"SAT-ANY", `true`},
+	}
+	for _, tc := range errorCases {
+		if _, _, err := normalizer.Normalize(tc.satellite, json.RawMessage(tc.raw)); err == nil {
+			t.Errorf("expected Normalize(%s, %s) to fail", tc.satellite, tc.raw)
+		}
+	}
+}
+
+func TestTimestampNormalizeNilNormalizer(t *testing.T) {
+	var normalizer *TimestampNormalizer
+	got, ok, err := normalizer.Normalize("SAT-ANY", json.RawMessage(`1772366401`))
+	if err != nil || !ok || got.Unix() != 1772366401 {
+		t.Errorf("expected epoch seconds auto-detected, got %v, %v, %v", got, ok, err)
+	}
+}
+
+func TestHandleTelemetryTimestampFormats(t *testing.T) {
+	mockBP := test.NewMockBatchProcessor()
+	handler := NewTelemetryHandler(mockBP)
+	handler.SetTimestampNormalizer(newTestNormalizer(t))
+	router := setupTestRouter(handler)
+
+	body := `[
+		{"satellite_id": "SAT-MS", "battery_charge_percent": 80, "storage_usage_mb": 100, "signal_strength_dbm": -60, "timestamp": 1772366401500},
+		{"satellite_id": "SAT-BOOT", "battery_charge_percent": 80, "storage_usage_mb": 100, "signal_strength_dbm": -60, "timestamp": 1500}
+	]`
+	req, _ := http.NewRequest("POST", "/telemetry/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	want := time.Date(2026, 3, 1, 12, 0, 1, 500_000_000, time.UTC)
+	for i, point := range mockBP.GetAddedPoints() {
+		if !point.Timestamp.Equal(want) {
+			t.Errorf("point %d: expected timestamp %v, got %v", i, want, point.Timestamp)
+		}
+	}
+
+	body = `{"satellite_id": "SAT-RFC", "battery_charge_percent": 80, "storage_usage_mb": 100, "signal_strength_dbm": -60, "timestamp": 1772366401}`
+	req, _ = http.NewRequest("POST", "/telemetry", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a timestamp not in the declared format, got %d", w.Code)
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSConfig configures TLS termination in the server itself, for edge
+// deployments without a proxy in front of the ingest nodes
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mTLS: clients must present a certificate signed
+	// by one of its CAs
+	ClientCAFile string
+	// MinVersion is "1.2" or "1.3"
+	MinVersion string
+}
+
+// NewTLSConfig builds the server's TLS configuration. The certificate and
+// key are re-read when either file changes, so a renewed certificate is
+// served to new connections without a restart.
+func NewTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	minVersion, err := parseTLSVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	certificate := &certificateFiles{certFile: cfg.CertFile, keyFile: cfg.KeyFile}
+	if _, err := certificate.get(); err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion: minVersion,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return certificate.get()
+		},
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// parseTLSVersion maps a configured minimum version to its constant
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q (use 1.2 or 1.3)", version)
+	}
+}
+
+// certificateFiles caches a key pair until the modification time of the
+// certificate or key file changes
+type certificateFiles struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	certModTime time.Time
+	keyModTime  time.Time
+	certificate *tls.Certificate
+}
+
+// get returns the current certificate, reloading it when the files changed.
+// A renewal that fails to load keeps serving the previous certificate.
+func (f *certificateFiles) get() (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	certInfo, certErr := os.Stat(f.certFile)
+	keyInfo, keyErr := os.Stat(f.keyFile)
+	if certErr == nil && keyErr == nil && f.certificate != nil &&
+		certInfo.ModTime().Equal(f.certModTime) && keyInfo.ModTime().Equal(f.keyModTime) {
+		return f.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		if f.certificate != nil {
+			log.Printf("WARNING: Failed to reload TLS certificate, serving the previous one: %v", err)
+			// Retry on the next change instead of on every handshake
+			f.rememberModTimes(certInfo, keyInfo)
+			return f.certificate, nil
+		}
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	if f.certificate != nil {
+		log.Printf("EVENT: tls_certificate_reloaded cert=%s", f.certFile)
+	}
+	f.certificate = &certificate
+	f.rememberModTimes(certInfo, keyInfo)
+	return f.certificate, nil
+}
+
+// rememberModTimes records the modification times of the files that exist
+func (f *certificateFiles) rememberModTimes(certInfo, keyInfo os.FileInfo) {
+	if certInfo != nil {
+		f.certModTime = certInfo.ModTime()
+	}
+	if keyInfo != nil {
+		f.keyModTime = keyInfo.ModTime()
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCertificate is a generated certificate with its key
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCertificate issues a certificate for commonName, self-signed when
+// parent is nil
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate, isCA bool) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	issuer, signer := template, key
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCertificate{cert: cert, key: key, der: der}
+}
+
+// write stores the certificate and key as PEM files in dir
+func (c *testCertificate) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// TestTLSClientCertificateIdentity tests mTLS rejects clients without a
+// certificate and reports the verified subject as the client identity
+func TestTLSClientCertificateIdentity(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, "OrbitStream Test CA", nil, true)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCertificate(t, "ingest-node", ca, false).write(t, dir, "server")
+	client := newTestCertificate(t, "ground-station-7", ca, false)
+
+	tlsConfig, err := NewTLSConfig(TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, MinVersion: "1.2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	router := setupIdentityRouter(t, IdentityConfig{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := &http.Server{Handler: router, TLSConfig: tlsConfig}
+	go server.ServeTLS(listener, "", "")
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	url := "https://" + listener.Addr().String() + "/whoami"
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	if resp, err := anonymous.Get(url); err == nil {
+		resp.Body.Close()
+		t.Error("expected a client without a certificate to be rejected")
+	}
+
+	authenticated := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{client.der}, PrivateKey: client.key}},
+	}}}
+	resp, err := authenticated.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var identity ClientIdentity
+	if err := json.NewDecoder(resp.Body).Decode(&identity); err != nil {
+		t.Fatalf("failed to decode identity: %v", err)
+	}
+	if identity.CertSubject != "CN=ground-station-7" {
+		t.Errorf("expected the client certificate subject, got %q", identity.CertSubject)
+	}
+}
+
+// TestTLSCertificateReload tests a renewed certificate is served once its
+// files change, and a broken renewal keeps the previous one
+func TestTLSCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := newTestCertificate(t, "first", nil, false).write(t, dir, "server")
+
+	files := &certificateFiles{certFile: certFile, keyFile: keyFile}
+	first, err := files.get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newTestCertificate(t, "second", nil, false).write(t, dir, "server")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+	second, err := files.get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if leaf, _ := x509.ParseCertificate(second.Certificate[0]); leaf.Subject.CommonName != "second" || second == first {
+		t.Errorf("expected the renewed certificate, got %s", leaf.Subject.CommonName)
+	}
+
+	os.WriteFile(keyFile, []byte("not a key"), 0o600)
+	evenLater := later.Add(time.Minute)
+	os.Chtimes(keyFile, evenLater, evenLater)
+	if current, err := files.get(); err != nil || current != second {
+		t.Errorf("expected the previous certificate after a broken renewal, got %v", err)
+	}
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := newTestCertificate(t, "server", nil, false).write(t, dir, "server")
+
+	if _, err := NewTLSConfig(TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.1"}); err == nil {
+		t.Error("expected an error for TLS 1.1")
+	}
+	if _, err := NewTLSConfig(TLSConfig{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key")}); err == nil {
+		t.Error("expected an error for a missing key")
+	}
+	if _, err := NewTLSConfig(TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}); err == nil {
+		t.Error("expected an error for a client CA file without certificates")
+	}
+	tlsConfig, err := NewTLSConfig(TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"})
+	if err != nil || tlsConfig.MinVersion != tls.VersionTLS13 || tlsConfig.ClientAuth != tls.NoClientCert {
+		t.Errorf("expected TLS 1.3 without client certificates, got %+v (%v)", tlsConfig, err)
+	}
+}
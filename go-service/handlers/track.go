@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// Track geometries
+const (
+	TrackGeometryLineString = "linestring"
+	TrackGeometryMultiPoint = "multipoint"
+)
+
+// kmPerDegree is the length of a degree of latitude on a spherical Earth
+const kmPerDegree = 111.195
+
+// TrackQuerier reads the positions a satellite reported
+// This allows for mocking in tests
+type TrackQuerier interface {
+	QueryTrack(ctx context.Context, q db.TelemetryQuery) ([]models.TrackPoint, error)
+}
+
+// TrackHandler serves satellite ground tracks as GeoJSON for map
+// visualization
+type TrackHandler struct {
+	querier    TrackQuerier
+	guardrails *QueryGuardrails
+}
+
+// NewTrackHandler creates a track handler
+func NewTrackHandler(querier TrackQuerier, guardrails *QueryGuardrails) *TrackHandler {
+	return &TrackHandler{querier: querier, guardrails: guardrails}
+}
+
+// HandleTrack returns a satellite's positions in the range as a GeoJSON
+// Feature: a LineString (split into a MultiLineString where it crosses the
+// antimeridian) or a MultiPoint, with altitudes in meters and the time of
+// every position in the coordTimes property. tolerance_km simplifies the
+// track (Douglas-Peucker), dropping positions closer than that to the line.
+// GET /satellites/:id/track?from=&to=&format=geojson&geometry=&tolerance_km=&limit=
+func (h *TrackHandler) HandleTrack(c *gin.Context) {
+	satelliteID := c.Param("id")
+	if format := c.DefaultQuery("format", "geojson"); format != "geojson" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be geojson"})
+		return
+	}
+	geometry := c.DefaultQuery("geometry", TrackGeometryLineString)
+	if geometry != TrackGeometryLineString && geometry != TrackGeometryMultiPoint {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "geometry must be linestring or multipoint"})
+		return
+	}
+	var tolerance float64
+	if raw := c.Query("tolerance_km"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(parsed) || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tolerance_km must be a non-negative number"})
+			return
+		}
+		tolerance = parsed
+	}
+
+	window, ok := h.guardrails.Window(c, EndpointClassRaw)
+	if !ok {
+		return
+	}
+
+	track, err := h.querier.QueryTrack(c.Request.Context(), db.TelemetryQuery{
+		SatelliteID: satelliteID,
+		From:        window.From,
+		To:          window.To,
+		Limit:       window.Limit,
+	})
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+
+	simplified := simplifyTrack(track, tolerance)
+	feature := trackFeature(simplified, geometry)
+	feature.Properties["satellite_id"] = satelliteID
+	feature.Properties["from"] = window.From
+	feature.Properties["to"] = window.To
+	feature.Properties["points"] = len(simplified)
+	feature.Properties["source_points"] = len(track)
+
+	c.Header("Content-Type", "application/geo+json")
+	c.JSON(http.StatusOK, feature)
+}
+
+// trackFeature builds the feature geometry and its coordTimes property
+func trackFeature(track []models.TrackPoint, geometry string) models.GeoJSONFeature {
+	feature := models.GeoJSONFeature{Type: "Feature", Properties: make(map[string]any)}
+
+	if geometry == TrackGeometryMultiPoint {
+		positions := make([][]float64, len(track))
+		times := make([]time.Time, len(track))
+		for i, p := range track {
+			positions[i] = geoJSONPosition(p)
+			times[i] = p.Timestamp
+		}
+		feature.Properties["coordTimes"] = times
+		if len(track) > 0 {
+			feature.Geometry = &models.GeoJSONGeometry{Type: "MultiPoint", Coordinates: positions}
+		}
+		return feature
+	}
+
+	// Split where consecutive positions are more than 180 degrees of
+	// longitude apart; single positions left between crossings can't form
+	// a line and are dropped
+	var lines [][][]float64
+	var lineTimes [][]time.Time
+	var positions [][]float64
+	var times []time.Time
+	flush := func() {
+		if len(positions) >= 2 {
+			lines = append(lines, positions)
+			lineTimes = append(lineTimes, times)
+		}
+		positions, times = nil, nil
+	}
+	for i, p := range track {
+		if i > 0 && math.Abs(p.Longitude-track[i-1].Longitude) > 180 {
+			flush()
+		}
+		positions = append(positions, geoJSONPosition(p))
+		times = append(times, p.Timestamp)
+	}
+	flush()
+
+	switch len(lines) {
+	case 0:
+		feature.Properties["coordTimes"] = []time.Time{}
+	case 1:
+		feature.Geometry = &models.GeoJSONGeometry{Type: "LineString", Coordinates: lines[0]}
+		feature.Properties["coordTimes"] = lineTimes[0]
+	default:
+		feature.Geometry = &models.GeoJSONGeometry{Type: "MultiLineString", Coordinates: lines}
+		feature.Properties["coordTimes"] = lineTimes
+	}
+	return feature
+}
+
+// geoJSONPosition is [lon, lat] or [lon, lat, altitude in meters]
+func geoJSONPosition(p models.TrackPoint) []float64 {
+	if p.AltitudeKM == nil {
+		return []float64{p.Longitude, p.Latitude}
+	}
+	return []float64{p.Longitude, p.Latitude, *p.AltitudeKM * 1000}
+}
+
+// simplifyTrack drops positions less than toleranceKM from the line
+// through the positions kept around them (Douglas-Peucker on the ground
+// track, altitude ignored); the first and last positions are always kept
+func simplifyTrack(track []models.TrackPoint, toleranceKM float64) []models.TrackPoint {
+	if toleranceKM <= 0 || len(track) < 3 {
+		return track
+	}
+	keep := make([]bool, len(track))
+	keep[0], keep[len(track)-1] = true, true
+	stack := [][2]int{{0, len(track) - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		farthest, distance := -1, toleranceKM
+		for i := first + 1; i < last; i++ {
+			if d := segmentDistanceKM(track[i], track[first], track[last]); d > distance {
+				farthest, distance = i, d
+			}
+		}
+		if farthest >= 0 {
+			keep[farthest] = true
+			stack = append(stack, [2]int{first, farthest}, [2]int{farthest, last})
+		}
+	}
+
+	simplified := make([]models.TrackPoint, 0, len(track))
+	for i, p := range track {
+		if keep[i] {
+			simplified = append(simplified, p)
+		}
+	}
+	return simplified
+}
+
+// segmentDistanceKM approximates the ground distance from p to the segment
+// a-b in an equirectangular projection centered on a
+func segmentDistanceKM(p, a, b models.TrackPoint) float64 {
+	cosLat := math.Cos(a.Latitude * math.Pi / 180)
+	project := func(q models.TrackPoint) (float64, float64) {
+		dLon := math.Mod(q.Longitude-a.Longitude+540, 360) - 180
+		return dLon * cosLat * kmPerDegree, (q.Latitude - a.Latitude) * kmPerDegree
+	}
+	px, py := project(p)
+	bx, by := project(b)
+
+	length := bx*bx + by*by
+	t := 0.0
+	if length > 0 {
+		t = math.Max(0, math.Min(1, (px*bx+py*by)/length))
+	}
+	return math.Hypot(px-t*bx, py-t*by)
+}
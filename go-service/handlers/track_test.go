@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/models"
+)
+
+// fakeTrackQuerier returns a canned track and records the last query
+type fakeTrackQuerier struct {
+	track []models.TrackPoint
+	query db.TelemetryQuery
+}
+
+func (f *fakeTrackQuerier) QueryTrack(ctx context.Context, q db.TelemetryQuery) ([]models.TrackPoint, error) {
+	f.query = q
+	return f.track, nil
+}
+
+// trackPoints builds one position a minute from [lat, lon] pairs
+func trackPoints(start time.Time, positions ...[2]float64) []models.TrackPoint {
+	alt := 550.0
+	track := make([]models.TrackPoint, len(positions))
+	for i, pos := range positions {
+		track[i] = models.TrackPoint{Timestamp: start.Add(time.Duration(i) * time.Minute), Latitude: pos[0], Longitude: pos[1], AltitudeKM: &alt}
+	}
+	return track
+}
+
+func TestHandleTrack(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	// Crosses the antimeridian between the third and fourth position
+	querier := &fakeTrackQuerier{track: trackPoints(start,
+		[2]float64{10, 175}, [2]float64{11, 177}, [2]float64{12, 179}, [2]float64{13, -179}, [2]float64{14, -177})}
+	router := gin.New()
+	router.GET("/satellites/:id/track", NewTrackHandler(querier, newTestGuardrails()).HandleTrack)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/satellites/SAT-0001/track"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	type feature struct {
+		Type     string
+		Geometry *struct {
+			Type        string
+			Coordinates json.RawMessage
+		}
+		Properties map[string]any
+	}
+	decode := func(w *httptest.ResponseRecorder) feature {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var f feature
+		if err := json.Unmarshal(w.Body.Bytes(), &f); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		return f
+	}
+
+	w := get("?from=2026-03-01T00:00:00Z&to=2026-03-01T00:30:00Z&format=geojson")
+	if ct := w.Header().Get("Content-Type"); ct != "application/geo+json" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	line := decode(w)
+	if querier.query.SatelliteID != "SAT-0001" {
+		t.Errorf("unexpected query %+v", querier.query)
+	}
+	if line.Type != "Feature" || line.Geometry == nil || line.Geometry.Type != "MultiLineString" {
+		t.Fatalf("expected a MultiLineString feature, got %+v", line)
+	}
+	var lines [][][]float64
+	_ = json.Unmarshal(line.Geometry.Coordinates, &lines)
+	if len(lines) != 2 || len(lines[0]) != 3 || len(lines[1]) != 2 || lines[0][0][0] != 175 || lines[0][0][2] != 550000 {
+		t.Errorf("expected the track split at the antimeridian, got %v", lines)
+	}
+
+	points := decode(get("?from=2026-03-01T00:00:00Z&to=2026-03-01T00:30:00Z&geometry=multipoint"))
+	if points.Geometry == nil || points.Geometry.Type != "MultiPoint" || len(points.Properties["coordTimes"].([]any)) != 5 {
+		t.Errorf("expected 5 points, got %+v", points)
+	}
+
+	for _, query := range []string{"?format=kml", "?geometry=polygon", "?tolerance_km=-1"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+
+	querier.track = nil
+	if empty := decode(get("")); empty.Geometry != nil {
+		t.Errorf("expected no geometry for an empty track, got %+v", empty.Geometry)
+	}
+}
+
+func TestSimplifyTrack(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	// Along the equator with one 50 km detour; the points beside it are
+	// about 23 km from the lines through it
+	track := trackPoints(start,
+		[2]float64{0, 0}, [2]float64{0.01, 1}, [2]float64{0.45, 2}, [2]float64{0, 3}, [2]float64{0, 4})
+
+	if got := simplifyTrack(track, 0); len(got) != len(track) {
+		t.Errorf("zero tolerance should keep every point, got %d", len(got))
+	}
+	got := simplifyTrack(track, 30)
+	if len(got) != 3 || got[1].Longitude != 2 {
+		t.Errorf("expected the endpoints and the detour, got %+v", got)
+	}
+	if got := simplifyTrack(track, 100); len(got) != 2 {
+		t.Errorf("expected only the endpoints, got %d points", len(got))
+	}
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/models"
+)
+
+// ValidationMode decides what happens to a point that fails validation
+type ValidationMode string
+
+const (
+	// ValidationOff accepts every point unchecked
+	ValidationOff ValidationMode = "off"
+	// ValidationLenient accepts invalid points and tags them with their
+	// problems in validation_issues
+	ValidationLenient ValidationMode = "lenient"
+	// ValidationStrict rejects invalid points
+	ValidationStrict ValidationMode = "strict"
+)
+
+// ValidationConfig configures the payload checks
+type ValidationConfig struct {
+	Mode ValidationMode
+	// SatelliteIDPattern is the regular expression satellite IDs must match
+	SatelliteIDPattern string
+	// Plausible altitude and velocity ranges of the fleet
+	MinAltitudeKM   float64
+	MaxAltitudeKM   float64
+	MaxVelocityKMPH float64
+}
+
+// PointValidator checks ingested points for missing measurements and values
+// outside their physical range. Without it the handler stores zero values
+// for omitted fields, which look like a dead battery and an empty disk.
+type PointValidator struct {
+	mode        ValidationMode
+	satelliteID *regexp.Regexp
+	ranges      []validationRange
+
+	mu        sync.Mutex
+	validated int64
+	invalid   int64
+	rejected  int64
+	issues    map[string]int64 // by field:problem
+}
+
+// validationRange is the allowed range of one numeric field; nil optional
+// fields are not checked
+type validationRange struct {
+	field    string
+	min, max float64
+	value    func(p *models.TelemetryPoint) *float64
+}
+
+// ValidationStats reports the points checked and their problems since startup
+type ValidationStats struct {
+	Mode      ValidationMode   `json:"mode"`
+	Validated int64            `json:"validated"`
+	Invalid   int64            `json:"invalid"`
+	Rejected  int64            `json:"rejected"`
+	Issues    map[string]int64 `json:"issues"`
+}
+
+// NewPointValidator compiles the satellite ID pattern and the field ranges
+func NewPointValidator(cfg ValidationConfig) (*PointValidator, error) {
+	switch cfg.Mode {
+	case ValidationOff, ValidationLenient, ValidationStrict:
+	default:
+		return nil, fmt.Errorf("unknown validation mode %q (use off, lenient or strict)", cfg.Mode)
+	}
+	v := &PointValidator{
+		mode:   cfg.Mode,
+		issues: make(map[string]int64),
+		ranges: []validationRange{
+			{"battery_charge_percent", 0, 100, func(p *models.TelemetryPoint) *float64 { return &p.BatteryChargePercent }},
+			{"storage_usage_mb", 0, 1e12, func(p *models.TelemetryPoint) *float64 { return &p.StorageUsageMB }},
+			{"signal_strength_dbm", -200, 50, func(p *models.TelemetryPoint) *float64 { return &p.SignalStrengthDBM }},
+			{"latitude", -90, 90, func(p *models.TelemetryPoint) *float64 { return p.Latitude }},
+			{"longitude", -180, 180, func(p *models.TelemetryPoint) *float64 { return p.Longitude }},
+			{"altitude_km", cfg.MinAltitudeKM, cfg.MaxAltitudeKM, func(p *models.TelemetryPoint) *float64 { return p.AltitudeKM }},
+			{"velocity_kmph", 0, cfg.MaxVelocityKMPH, func(p *models.TelemetryPoint) *float64 { return p.VelocityKMPH }},
+		},
+	}
+	if cfg.SatelliteIDPattern != "" {
+		pattern, err := regexp.Compile(cfg.SatelliteIDPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid satellite ID pattern: %w", err)
+		}
+		v.satelliteID = pattern
+	}
+	return v, nil
+}
+
+// Mode returns the validation mode; a nil validator is off
+func (v *PointValidator) Mode() ValidationMode {
+	if v == nil {
+		return ValidationOff
+	}
+	return v.mode
+}
+
+// Check validates a point and returns its problems as field:problem pairs
+// (satellite_id:missing, latitude:out_of_range). missing lists the required
+// measurements absent from the payload.
+func (v *PointValidator) Check(point models.TelemetryPoint, missing []string) []string {
+	if v.Mode() == ValidationOff {
+		return nil
+	}
+
+	var issues []string
+	switch {
+	case point.SatelliteID == "":
+		issues = append(issues, "satellite_id:missing")
+	case v.satelliteID != nil && !v.satelliteID.MatchString(point.SatelliteID):
+		issues = append(issues, "satellite_id:invalid_format")
+	}
+	for _, field := range missing {
+		issues = append(issues, field+":missing")
+	}
+	for _, r := range v.ranges {
+		if value := r.value(&point); value != nil && (*value < r.min || *value > r.max) && !contains(missing, r.field) {
+			issues = append(issues, r.field+":out_of_range")
+		}
+	}
+	if (point.Latitude == nil) != (point.Longitude == nil) {
+		issues = append(issues, "position:incomplete")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.validated++
+	if len(issues) > 0 {
+		v.invalid++
+		if v.mode == ValidationStrict {
+			v.rejected++
+		}
+		for _, issue := range issues {
+			v.issues[issue]++
+		}
+	}
+	return issues
+}
+
+// Apply checks a point and reports whether to accept it: in lenient mode
+// the problems are stored with the point, in strict mode it is refused and
+// the returned error describes why
+func (v *PointValidator) Apply(point *models.TelemetryPoint, missing []string) error {
+	point.ValidationIssues = nil
+	issues := v.Check(*point, missing)
+	if len(issues) == 0 {
+		return nil
+	}
+	if v.mode == ValidationStrict {
+		return fmt.Errorf("invalid telemetry point: %s", strings.Join(issues, ", "))
+	}
+	tagged := strings.Join(issues, ",")
+	point.ValidationIssues = &tagged
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the points validated and the problems found, by kind
+func (v *PointValidator) Stats() ValidationStats {
+	stats := ValidationStats{Mode: v.Mode(), Issues: make(map[string]int64)}
+	if v == nil {
+		return stats
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	stats.Validated, stats.Invalid, stats.Rejected = v.validated, v.invalid, v.rejected
+	for issue, count := range v.issues {
+		stats.Issues[issue] = count
+	}
+	return stats
+}
+
+// HandleStats returns validation counts
+// GET /admin/validation
+func (v *PointValidator) HandleStats(c *gin.Context) {
+	c.JSON(http.StatusOK, v.Stats())
+}
+
+// missingMeasurements lists the required measurements the payload omitted
+func (p telemetryPayload) missingMeasurements() []string {
+	var missing []string
+	for _, m := range []struct {
+		field string
+		value *float64
+	}{
+		{"battery_charge_percent", p.BatteryChargePercent},
+		{"storage_usage_mb", p.StorageUsageMB},
+		{"signal_strength_dbm", p.SignalStrengthDBM},
+	} {
+		if m.value == nil {
+			missing = append(missing, m.field)
+		}
+	}
+	return missing
+}
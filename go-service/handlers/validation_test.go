@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"orbitstream/models"
+	"orbitstream/test"
+)
+
+func testValidator(t *testing.T, mode ValidationMode) *PointValidator {
+	t.Helper()
+	validator, err := NewPointValidator(ValidationConfig{
+		Mode:               mode,
+		SatelliteIDPattern: `^SAT-\d{4}$`,
+		MinAltitudeKM:      100,
+		MaxAltitudeKM:      50000,
+		MaxVelocityKMPH:    40000,
+	})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+	return validator
+}
+
+func TestPointValidatorCheck(t *testing.T) {
+	validator := testValidator(t, ValidationLenient)
+	lat, lon, alt := 95.0, 10.0, 20.0
+
+	valid := test.NewTestTelemetryPointWithSatelliteID("SAT-0001")
+	if issues := validator.Check(valid, nil); len(issues) != 0 {
+		t.Errorf("expected a valid point, got %v", issues)
+	}
+
+	invalid := test.NewTestTelemetryPointWithSatelliteID("sat 1")
+	invalid.BatteryChargePercent = 120
+	invalid.Latitude, invalid.Longitude, invalid.AltitudeKM = &lat, &lon, &alt
+	issues := validator.Check(invalid, []string{"signal_strength_dbm"})
+	want := []string{
+		"satellite_id:invalid_format", "signal_strength_dbm:missing",
+		"battery_charge_percent:out_of_range", "latitude:out_of_range", "altitude_km:out_of_range",
+	}
+	if strings.Join(issues, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, issues)
+	}
+
+	half := test.NewTestTelemetryPointWithSatelliteID("SAT-0002")
+	half.Longitude = &lon
+	if issues := validator.Check(half, nil); len(issues) != 1 || issues[0] != "position:incomplete" {
+		t.Errorf("expected an incomplete position, got %v", issues)
+	}
+
+	stats := validator.Stats()
+	if stats.Validated != 3 || stats.Invalid != 2 || stats.Rejected != 0 || stats.Issues["latitude:out_of_range"] != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	if _, err := NewPointValidator(ValidationConfig{Mode: "paranoid"}); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func postValidated(t *testing.T, mode ValidationMode, path, body string) (*httptest.ResponseRecorder, *test.MockBatchProcessor) {
+	t.Helper()
+	mockBP := test.NewMockBatchProcessor()
+	handler := NewTelemetryHandler(mockBP)
+	handler.SetValidator(testValidator(t, mode))
+	req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	setupTestRouter(handler).ServeHTTP(w, req)
+	return w, mockBP
+}
+
+func TestValidationStrictRejectsMissingFields(t *testing.T) {
+	// Missing measurements used to be stored as zeros
+	w, mockBP := postValidated(t, ValidationStrict, "/telemetry", `{"satellite_id": "SAT-0001", "battery_charge_percent": 80}`)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "storage_usage_mb:missing") {
+		t.Errorf("expected 422 naming the missing field, got %d: %s", w.Code, w.Body.String())
+	}
+	if mockBP.GetAddCallCount() != 0 {
+		t.Errorf("expected the point to be rejected, got %d adds", mockBP.GetAddCallCount())
+	}
+
+	w, mockBP = postValidated(t, ValidationStrict, "/telemetry/batch", `[
+		{"satellite_id": "SAT-0001", "battery_charge_percent": 80, "storage_usage_mb": 100, "signal_strength_dbm": -60},
+		{"satellite_id": "SAT-0002", "battery_charge_percent": 180, "storage_usage_mb": 100, "signal_strength_dbm": -60}
+	]`)
+	var response models.TelemetryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusAccepted || response.Count != 1 || len(response.Rejected) != 1 || response.Rejected[0].Index != 1 {
+		t.Errorf("expected point 1 to be rejected and point 0 accepted, got %d %+v", w.Code, response)
+	}
+	if mockBP.GetAddCallCount() != 1 {
+		t.Errorf("expected 1 add, got %d", mockBP.GetAddCallCount())
+	}
+}
+
+func TestValidationLenientTagsPoints(t *testing.T) {
+	w, mockBP := postValidated(t, ValidationLenient, "/telemetry", `{"satellite_id": "SAT-0001", "battery_charge_percent": -5, "storage_usage_mb": 100, "signal_strength_dbm": -60, "validation_issues": "forged"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	points := mockBP.GetAddedPoints()
+	if len(points) != 1 || points[0].ValidationIssues == nil || *points[0].ValidationIssues != "battery_charge_percent:out_of_range" {
+		t.Errorf("expected the point to be tagged, got %+v", points)
+	}
+}
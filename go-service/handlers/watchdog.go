@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+)
+
+// WatchdogHandler serves the satellites the contact watchdog finds silent
+type WatchdogHandler struct {
+	watchdog *db.ContactWatchdog
+}
+
+// NewWatchdogHandler creates a handler reporting the silences of watchdog
+func NewWatchdogHandler(watchdog *db.ContactWatchdog) *WatchdogHandler {
+	return &WatchdogHandler{watchdog: watchdog}
+}
+
+// HandleStale lists the satellites silent for longer than threshold (each
+// satellite's own silence window by default), longest silence first
+// GET /fleet/stale?threshold=15m
+func (h *WatchdogHandler) HandleStale(c *gin.Context) {
+	if h.watchdog == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "contact watchdog is disabled"})
+		return
+	}
+	var threshold time.Duration
+	if value := c.Query("threshold"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be a positive duration like 15m"})
+			return
+		}
+		threshold = parsed
+	}
+
+	stale := h.watchdog.Stale(threshold)
+	c.JSON(http.StatusOK, gin.H{
+		"stale":   stale,
+		"count":   len(stale),
+		"tracked": h.watchdog.Tracked(),
+	})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"orbitstream/db"
+	"orbitstream/test"
+)
+
+func TestHandleStale(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	watchdog := db.NewContactWatchdog(nil, nil, db.WatchdogConfig{DefaultWindow: time.Hour})
+	bp := newTestBatchProcessor()
+	bp.SetContactWatchdog(watchdog)
+	if err := bp.Add(test.NewTestTelemetryPointWithSatelliteID("SAT-0001")); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/fleet/stale", NewWatchdogHandler(watchdog).HandleStale)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/fleet/stale"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var response struct {
+		Stale   []db.SatelliteContact `json:"stale"`
+		Count   int                   `json:"count"`
+		Tracked int                   `json:"tracked"`
+	}
+	w := get("")
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.Count != 0 || response.Tracked != 1 {
+		t.Errorf("expected no stale satellite of 1 tracked, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = get("?threshold=1ns")
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.Count != 1 || response.Stale[0].SatelliteID != "SAT-0001" {
+		t.Errorf("expected SAT-0001 past a 1ns threshold, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := get("?threshold=soon"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad threshold, got %d", w.Code)
+	}
+
+	router = gin.New()
+	router.GET("/fleet/stale", NewWatchdogHandler(nil).HandleStale)
+	if w := get(""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when disabled, got %d", w.Code)
+	}
+}
@@ -2,37 +2,75 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata" // time zones for STATS_TIMEZONE in minimal images
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"orbitstream/alerting"
 	"orbitstream/config"
 	"orbitstream/db"
 	"orbitstream/handlers"
+	"orbitstream/models"
+	"orbitstream/reporting"
+	"orbitstream/retry"
 )
 
 func main() {
-	// Load configuration
-	cfg := config.LoadConfig()
+	// Load configuration: -set > environment > config file > profile > defaults
+	configPath := flag.String("config", os.Getenv("ORBITSTREAM_CONFIG"), "YAML or TOML config file (environment variables override it)")
+	var settings config.Settings
+	flag.Var(&settings, "set", "KEY=value setting overriding the environment and config file (repeatable)")
+	flag.Parse()
+	cfg, err := config.LoadConfigFromFile(*configPath, settings)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if *configPath != "" {
+		log.Printf("Configuration file: %s", *configPath)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
-	// Initialize database connection pool
-	pool, err := db.NewConnectionPool(cfg.DBUrl, cfg.MaxConnections)
+	// Initialize database connection pool; credentials from secret files are
+	// re-read whenever a connection is opened after they rotate
+	creds := db.Credentials{URL: cfg.DBUrl}
+	if cfg.DBUrlFile != "" {
+		creds.URLFile = db.NewSecretFile(cfg.DBUrlFile)
+	}
+	if cfg.DBPasswordFile != "" {
+		creds.PasswordFile = db.NewSecretFile(cfg.DBPasswordFile)
+	}
+	pool, err := db.NewConnectionPool(creds, cfg.MaxConnections-cfg.ReservedConnections)
 	if err != nil {
 		log.Fatalf("Failed to create connection pool: %v", err)
 	}
 	defer pool.Close()
 
-	// Initialize batch processor
-	anomalyConfig := db.AnomalyConfig{
-		BatteryMinPercent: cfg.AnomalyThresholdBattery,
-		StorageMaxMB:      cfg.AnomalyThresholdStorage,
-		SignalMinDBM:      cfg.AnomalyThresholdSignal,
+	// Health checks and replay use reserved connections, so a saturated insert
+	// workload can't starve them into reporting the database down
+	priorityPool := pool
+	if cfg.ReservedConnections > 0 {
+		priorityPool, err = db.NewReservedPool(creds, cfg.ReservedConnections)
+		if err != nil {
+			log.Fatalf("Failed to create reserved connection pool: %v", err)
+		}
+		defer priorityPool.Close()
 	}
 
+	// Initialize batch processor
+	anomalyConfig := buildAnomalyConfig(cfg)
+
 	batchProcessor := db.NewBatchProcessor(
 		pool,
 		cfg.BatchSize,
@@ -42,9 +80,282 @@ func main() {
 
 	// Configure retry and circuit breaker
 	batchProcessor.SetRetryConfig(cfg.MaxRetries, cfg.RetryDelay)
-	circuitBreaker := db.NewCircuitBreaker(cfg.CircuitBreakerThreshold, 30*time.Second)
-	batchProcessor.SetCircuitBreaker(circuitBreaker)
+	batchProcessor.SetRetryBudget(cfg.RetryBudget)
+	retryJitter, err := retry.ParseJitter(cfg.RetryJitter)
+	if err != nil {
+		log.Fatalf("Invalid RETRY_JITTER: %v", err)
+	}
+	batchProcessor.SetRetryJitter(retryJitter)
+	if policy := cfg.CircuitBreakerPolicy; policy != "consecutive" && policy != "failure_rate" {
+		log.Fatalf("Invalid CIRCUIT_BREAKER_POLICY %q (want consecutive or failure_rate)", cfg.CircuitBreakerPolicy)
+	}
+	// One breaker per operation, so a failing aggregate refresh or slow
+	// queries don't block telemetry inserts
+	breakers := db.NewCircuitBreakerRegistry(func(operation string) *db.CircuitBreaker {
+		var cb *db.CircuitBreaker
+		if cfg.CircuitBreakerPolicy == "failure_rate" {
+			cb = db.NewSlidingWindowCircuitBreaker(cfg.CircuitBreakerWindow,
+				cfg.CircuitBreakerFailureRate, cfg.CircuitBreakerMinCalls, 30*time.Second)
+		} else {
+			cb = db.NewCircuitBreaker(cfg.CircuitBreakerThreshold, 30*time.Second)
+		}
+		cb.SetHalfOpenPolicy(cfg.CircuitBreakerHalfOpenProbes, cfg.CircuitBreakerHalfOpenSuccesses)
+		cb.SetRampUp(cfg.CircuitBreakerRampUp)
+		cb.OnStateChange(func(from, to db.CircuitBreakerState) {
+			log.Printf("EVENT: circuit_breaker_state_change operation=%s from=%s to=%s", operation, from, to)
+		})
+		return cb
+	})
+	batchProcessor.SetCircuitBreaker(breakers.Get(db.OperationInsert))
+	querier := db.NewQueryService(pool)
+	querier.SetCircuitBreaker(breakers.Get(db.OperationQuery))
 	batchProcessor.SetMaxBufferSize(cfg.MaxBufferSize)
+	batchProcessor.SetHighWaterMark(cfg.MaxBufferSize * cfg.BufferHighWaterPercent / 100)
+	batchProcessor.SetFlushWorkers(cfg.FlushWorkers)
+	if cfg.BatchSizeAdaptive {
+		batchProcessor.SetBatchSizeController(db.NewBatchSizeController(db.BatchSizeConfig{
+			Min:           cfg.BatchSizeMin,
+			Max:           cfg.BatchSizeMax,
+			TargetLatency: cfg.BatchLatencyTarget,
+		}, cfg.BatchSize))
+	}
+	if cfg.BufferShards > 1 && cfg.WALWriteThrough {
+		log.Printf("BUFFER_SHARDS ignored: write-through mode keeps a single buffer")
+	}
+	batchProcessor.SetBufferShards(cfg.BufferShards)
+	detectors, err := buildAnomalyDetectors(cfg, anomalyConfig)
+	if err != nil {
+		log.Fatalf("Invalid anomaly detector configuration: %v", err)
+	}
+	batchProcessor.SetAnomalyDetectors(detectors...)
+	// Fold per-point flags into episodes; closed ones become recovery alerts
+	episodes := db.NewEpisodeTracker(cfg.AnomalyEpisodeHistory)
+	batchProcessor.SetEpisodeTracker(episodes)
+	// Tag positions with their geofence zones and alert on entries and exits
+	var geofences *db.GeofenceMonitor
+	if cfg.GeofenceFile != "" {
+		zones, err := db.LoadGeofences(cfg.GeofenceFile)
+		if err != nil {
+			log.Fatalf("Invalid geofence configuration: %v", err)
+		}
+		geofences = db.NewGeofenceMonitor(zones)
+		batchProcessor.SetGeofenceMonitor(geofences)
+		log.Printf("Geofencing %d zones from %s", len(zones), cfg.GeofenceFile)
+	}
+	// Store the distance to each satellite's TLE-predicted position
+	var propagator *db.OrbitPropagator
+	if cfg.PropagationEnabled {
+		propagator = db.NewOrbitPropagator(querier, db.PropagationConfig{
+			RefreshInterval: cfg.PropagationTLERefresh,
+			MaxTLEAge:       cfg.PropagationMaxTLEAge,
+		})
+		propagator.Start()
+		batchProcessor.SetOrbitPropagator(propagator)
+		log.Printf("Orbit propagation enabled (TLEs refreshed every %v)", cfg.PropagationTLERefresh)
+	}
+	// Bound per-satellite state against floods of random satellite IDs
+	batchProcessor.SetCardinalityGuard(db.NewCardinalityGuard(cfg.MaxTrackedSatellites, cfg.FleetSizeWarning))
+	// Reject or clamp timestamps from bad satellite clocks
+	if cfg.TimestampGuard != "" {
+		guard, err := db.NewTimestampGuard(db.TimestampGuardConfig{
+			Action:    cfg.TimestampGuard,
+			MaxFuture: cfg.TimestampMaxFuture,
+			MaxAge:    cfg.TimestampMaxAge,
+		})
+		if err != nil {
+			log.Fatalf("Invalid timestamp guard configuration: %v", err)
+		}
+		batchProcessor.SetTimestampGuard(guard)
+		log.Printf("Timestamp guard: %s points more than %v ahead or %v behind",
+			cfg.TimestampGuard, cfg.TimestampMaxFuture, cfg.TimestampMaxAge)
+	}
+	// Drop retransmissions from flaky ground links before detection
+	if cfg.DedupEnabled {
+		dedup, err := db.NewDeduplicator(cfg.DedupCacheSize, cfg.DedupMode)
+		if err != nil {
+			log.Fatalf("Invalid deduplication configuration: %v", err)
+		}
+		batchProcessor.SetDeduplicator(dedup)
+		log.Printf("Deduplicating by %s over the last %d points", cfg.DedupMode, cfg.DedupCacheSize)
+	}
+
+	// Detect telemetry arriving after its aggregate windows were refreshed
+	latePolicies, err := db.ParseLateDataPolicies(cfg.LateDataPolicies)
+	if err != nil {
+		log.Fatalf("Invalid late data configuration: %v", err)
+	}
+	lateData := db.NewLateDataTracker(db.LateDataConfig{
+		Threshold: cfg.LateDataThreshold,
+		Policies:  latePolicies,
+	})
+	lateData.SetCircuitBreaker(breakers.Get(db.OperationAggregateRefresh))
+	batchProcessor.SetLateDataTracker(lateData)
+	lateData.Start(pool, cfg.LateDataRefreshInterval)
+	log.Printf("Late data handling: threshold %v, policies %v", cfg.LateDataThreshold, cfg.LateDataPolicies)
+
+	// Record gaps in each satellite's telemetry as points are committed
+	var gapQuerier handlers.GapQuerier
+	if cfg.GapDetectionEnabled {
+		gaps := db.NewGapRecorder(db.GapConfig{
+			ExpectedCadence: cfg.GapExpectedCadence,
+			Tolerance:       cfg.GapTolerance,
+		})
+		batchProcessor.SetGapRecorder(gaps)
+		gapQuerier = querier
+		log.Printf("Gap detection enabled: silences over %v are stored in telemetry_gaps", gaps.MinGap())
+	}
+
+	// Elect one replica to run the cluster-wide background jobs below
+	var leader *db.LeaderElector
+	if cfg.LeaderElectionEnabled {
+		identity := cfg.LeaderElectionIdentity
+		if identity == "" {
+			identity, _ = os.Hostname()
+		}
+		leader = db.NewLeaderElector(pool, int64(cfg.LeaderElectionLockKey), identity, cfg.LeaderElectionInterval)
+		leader.Start()
+		log.Printf("Leader election enabled as %s (lock %d, leader: %v)", identity, cfg.LeaderElectionLockKey, leader.IsLeader())
+	}
+
+	// Send anomaly events to webhooks (Slack, PagerDuty, ...), grouping
+	// repeats and dropping silenced satellites
+	silences := alerting.NewSilences()
+	runbooks, err := alerting.ParseRunbooks(cfg.AlertRunbooks)
+	if err != nil {
+		log.Fatalf("Invalid runbook configuration: %v", err)
+	}
+	var alerter *alerting.WebhookAlerter
+	var alertGrouper *alerting.Grouper
+	if len(cfg.AlertWebhookURLs) > 0 {
+		alerter = alerting.NewWebhookAlerter(alerting.WebhookConfig{
+			URLs:        cfg.AlertWebhookURLs,
+			Timeout:     cfg.AlertWebhookTimeout,
+			MaxRetries:  cfg.AlertWebhookMaxRetries,
+			RetryDelay:  500 * time.Millisecond,
+			MinSeverity: models.AnomalySeverity(cfg.AlertMinSeverity),
+			Runbooks:    runbooks,
+		})
+		alerter.Start()
+		alertGrouper = alerting.NewGrouper(alerter, cfg.AlertGroupWindow, silences)
+		alertGrouper.Start()
+		batchProcessor.SetAlertSink(alertGrouper)
+		log.Printf("Anomaly webhook alerting enabled (%d URLs, min severity %s, group window %v)",
+			len(cfg.AlertWebhookURLs), cfg.AlertMinSeverity, cfg.AlertGroupWindow)
+	}
+
+	// Alert flight dynamics when a satellite's altitude trend shows faster
+	// decay than expected
+	var decayMonitor *db.DecayMonitor
+	if cfg.OrbitalDecayEnabled {
+		var decaySink db.AnomalyAlertSink
+		if alertGrouper != nil {
+			decaySink = alertGrouper
+		}
+		decayMonitor = db.NewDecayMonitor(querier, decaySink, db.DecayConfig{
+			Window:           cfg.OrbitalDecayWindow,
+			Interval:         cfg.OrbitalDecayInterval,
+			MinSamples:       cfg.OrbitalDecayMinSamples,
+			WarningKMPerDay:  cfg.OrbitalDecayWarnKMPerDay,
+			CriticalKMPerDay: cfg.OrbitalDecayCriticalKMPerDay,
+			MinR2:            cfg.OrbitalDecayMinR2,
+		})
+		if leader != nil {
+			decayMonitor.SetLeadership(leader)
+		}
+		decayMonitor.Start()
+		log.Printf("Orbital decay monitoring enabled (window %v, warn %.2f km/day, critical %.2f km/day)",
+			cfg.OrbitalDecayWindow, cfg.OrbitalDecayWarnKMPerDay, cfg.OrbitalDecayCriticalKMPerDay)
+	}
+
+	// Alert operations before a satellite's battery drains to its minimum
+	var batteryForecaster *db.Forecaster
+	if cfg.BatteryForecastEnabled {
+		var forecastSink db.AnomalyAlertSink
+		if alertGrouper != nil {
+			forecastSink = alertGrouper
+		}
+		batteryForecaster = db.NewBatteryForecaster(querier, forecastSink, db.ForecastConfig{
+			Window:          cfg.BatteryForecastWindow,
+			Interval:        cfg.BatteryForecastInterval,
+			MinSamples:      cfg.BatteryForecastMinSamples,
+			MinR2:           cfg.BatteryForecastMinR2,
+			Limit:           cfg.BatteryForecastThreshold,
+			WarningHorizon:  cfg.BatteryForecastWarnHorizon,
+			CriticalHorizon: cfg.BatteryForecastCriticalHorizon,
+		})
+		if leader != nil {
+			batteryForecaster.SetLeadership(leader)
+		}
+		batteryForecaster.Start()
+		log.Printf("Battery forecasting enabled (window %v, threshold %.0f%%, warn %v, critical %v)",
+			cfg.BatteryForecastWindow, cfg.BatteryForecastThreshold, cfg.BatteryForecastWarnHorizon, cfg.BatteryForecastCriticalHorizon)
+	}
+
+	// Alert operations before a satellite's onboard storage fills up, so
+	// downlinks can be scheduled in time
+	var storageForecaster *db.Forecaster
+	if cfg.StorageForecastEnabled {
+		var forecastSink db.AnomalyAlertSink
+		if alertGrouper != nil {
+			forecastSink = alertGrouper
+		}
+		storageForecaster = db.NewStorageForecaster(querier, forecastSink, db.ForecastConfig{
+			Window:          cfg.StorageForecastWindow,
+			Interval:        cfg.StorageForecastInterval,
+			MinSamples:      cfg.StorageForecastMinSamples,
+			MinR2:           cfg.StorageForecastMinR2,
+			Limit:           cfg.StorageForecastCapacityMB,
+			WarningHorizon:  cfg.StorageForecastWarnHorizon,
+			CriticalHorizon: cfg.StorageForecastCriticalHorizon,
+		})
+		if leader != nil {
+			storageForecaster.SetLeadership(leader)
+		}
+		storageForecaster.Start()
+		log.Printf("Storage forecasting enabled (window %v, capacity %.0f MB, warn %v, critical %v)",
+			cfg.StorageForecastWindow, cfg.StorageForecastCapacityMB, cfg.StorageForecastWarnHorizon, cfg.StorageForecastCriticalHorizon)
+	}
+
+	// Alert when a satellite goes silent for longer than expected
+	var watchdog *db.ContactWatchdog
+	if cfg.WatchdogEnabled {
+		var watchdogSink db.AnomalyAlertSink
+		if alertGrouper != nil {
+			watchdogSink = alertGrouper
+		}
+		windows := make([]db.SilenceWindow, len(cfg.WatchdogSilenceWindows))
+		for i, window := range cfg.WatchdogSilenceWindows {
+			windows[i] = db.SilenceWindow{Pattern: window.Pattern, Window: window.Window}
+		}
+		watchdog = db.NewContactWatchdog(querier, watchdogSink, db.WatchdogConfig{
+			DefaultWindow: cfg.WatchdogSilenceWindow,
+			Windows:       windows,
+			Interval:      cfg.WatchdogInterval,
+		})
+		if leader != nil {
+			watchdog.SetLeadership(leader)
+		}
+		batchProcessor.SetContactWatchdog(watchdog)
+		watchdog.Start()
+		log.Printf("Contact watchdog enabled (silence window %v, %d overrides)",
+			cfg.WatchdogSilenceWindow, len(windows))
+	}
+
+	// Recommend indexes or aggregates for slow telemetry queries
+	var indexAdvisor *db.IndexAdvisor
+	if cfg.IndexAdvisorEnabled {
+		indexAdvisor = db.NewIndexAdvisor(querier, db.IndexAdvisorConfig{
+			Interval:  cfg.IndexAdvisorInterval,
+			MinMeanMS: cfg.IndexAdvisorMinMeanMS,
+			TopN:      cfg.IndexAdvisorTopN,
+		})
+		if leader != nil {
+			indexAdvisor.SetLeadership(leader)
+		}
+		indexAdvisor.Start()
+		log.Printf("Index advisor enabled (every %v, queries slower than %.0f ms)",
+			cfg.IndexAdvisorInterval, cfg.IndexAdvisorMinMeanMS)
+	}
 
 	// Initialize WAL (Write Ahead Log)
 	wal, err := db.NewWAL(cfg.WALPath)
@@ -52,8 +363,34 @@ func main() {
 		log.Printf("WARNING: Failed to initialize WAL: %v", err)
 		log.Printf("Data may be lost if database becomes unavailable")
 	} else {
+		wal.SetRotation(cfg.WALMaxSize, cfg.WALSegmentMaxAge)
+		if err := wal.SetCompression(cfg.WALCompression); err != nil {
+			log.Fatalf("Invalid WAL configuration: %v", err)
+		}
 		batchProcessor.SetWAL(wal)
-		log.Printf("WAL initialized at: %s", cfg.WALPath)
+		log.Printf("WAL initialized at: %s (segments of %d bytes / %v, compression %q)",
+			cfg.WALPath, cfg.WALMaxSize, cfg.WALSegmentMaxAge, cfg.WALCompression)
+
+		// Recover points saved by a crash before counting what is left to replay
+		if recovered, err := db.RecoverEmergencyWAL(cfg.EmergencyWALPath, wal); err != nil {
+			log.Printf("WARNING: Failed to recover emergency WAL %s: %v", cfg.EmergencyWALPath, err)
+		} else if recovered > 0 {
+			log.Printf("Recovered %d records saved at the last crash from %s", recovered, cfg.EmergencyWALPath)
+		}
+
+		// Journal accepted points before buffering them, recovering what the
+		// last run left uncommitted
+		if cfg.WALWriteThrough {
+			journal, recovered, err := db.OpenWriteThroughJournal(cfg.WALJournalPath, wal)
+			if err != nil {
+				log.Fatalf("Failed to open write-through journal: %v", err)
+			}
+			defer journal.Close()
+			journal.SetRotation(cfg.WALMaxSize, cfg.WALSegmentMaxAge)
+			batchProcessor.SetWriteThrough(journal)
+			log.Printf("WAL write-through enabled, journal at %s (recovered %d uncommitted records)",
+				cfg.WALJournalPath, recovered)
+		}
 
 		// Check for existing WAL records on startup
 		if count, err := wal.Count(); err == nil && count > 0 {
@@ -61,21 +398,300 @@ func main() {
 		}
 	}
 
+	// Divert records the database rejects permanently instead of retrying them
+	if cfg.DeadLetterPath != "" {
+		dlq, err := db.NewDeadLetterQueue(cfg.DeadLetterPath)
+		if err != nil {
+			log.Fatalf("Failed to initialize dead letter queue: %v", err)
+		}
+		batchProcessor.SetDeadLetterQueue(dlq)
+		log.Printf("Dead letter queue at %s", cfg.DeadLetterPath)
+	}
+	if cfg.OverflowDir != "" && !cfg.WALWriteThrough {
+		overflow, err := db.NewOverflowQueue(cfg.OverflowDir, cfg.OverflowMaxPoints)
+		if err != nil {
+			log.Fatalf("Failed to initialize overflow queue: %v", err)
+		}
+		batchProcessor.SetOverflowQueue(overflow)
+		log.Printf("Overflow queue at %s (%d spilled records pending)", cfg.OverflowDir, overflow.Len())
+	}
+
+	// Burn in a new telemetry table layout alongside the live one
+	var shadowWriter *db.ShadowWriter
+	if cfg.ShadowWriteTable != "" {
+		shadowCtx, cancelShadow := context.WithTimeout(context.Background(), 10*time.Second)
+		shadowWriter, err = db.NewShadowWriter(shadowCtx, pool, cfg.ShadowWriteTable, cfg.ShadowWriteQueue)
+		cancelShadow()
+		if err != nil {
+			log.Fatalf("Failed to initialize shadow writes: %v", err)
+		}
+		shadowWriter.Start()
+		batchProcessor.SetShadowWriter(shadowWriter)
+		log.Printf("Shadow writing committed telemetry to %s (columns %v)",
+			cfg.ShadowWriteTable, shadowWriter.Stats().Columns)
+	}
+
+	// Configure warm standby WAL shipping
+	var walShipper *db.HTTPWALShipper
+	var replicationHandler *handlers.ReplicationHandler
+	switch cfg.ReplicationMode {
+	case "primary":
+		if wal == nil || cfg.ReplicationStandbyURL == "" {
+			log.Printf("WARNING: REPLICATION_MODE=primary requires a WAL and REPLICATION_STANDBY_URL, shipping disabled")
+			break
+		}
+		walShipper = db.NewHTTPWALShipper(cfg.ReplicationStandbyURL, cfg.ReplicationToken, 5*time.Second)
+		walShipper.Start()
+		wal.SetReplicator(walShipper)
+		log.Printf("WAL shipping to standby at %s", cfg.ReplicationStandbyURL)
+	case "standby":
+		standbyWAL, err := db.NewWAL(cfg.ReplicationStandbyWALPath)
+		if err != nil {
+			log.Fatalf("Failed to open standby WAL: %v", err)
+		}
+		defer standbyWAL.Close()
+		replicationHandler = handlers.NewReplicationHandler(db.NewStandbyReceiver(standbyWAL, wal), cfg.ReplicationToken)
+		log.Printf("Running as warm standby, shipped WAL at %s", cfg.ReplicationStandbyWALPath)
+	}
+
+	// Save buffered points and log a crash report if the service panics,
+	// including in the flush workers and the health monitor
+	defer recoverCrash(batchProcessor, cfg.EmergencyWALPath)
+	batchProcessor.SetCrashHandler(func(reason any) {
+		reportCrash(batchProcessor, reason, cfg.EmergencyWALPath)
+	})
+
 	// Start batch processor background worker
-	go batchProcessor.Start()
+	go func() {
+		defer recoverCrash(batchProcessor, cfg.EmergencyWALPath)
+		batchProcessor.Start()
+	}()
 
 	// Initialize and start health monitor
 	var healthMonitor *db.HealthMonitor
 	if wal != nil {
-		healthMonitor = db.NewHealthMonitor(pool, wal, batchProcessor)
+		healthMonitor = db.NewHealthMonitor(priorityPool, wal, batchProcessor)
 		healthMonitor.SetCheckInterval(5 * time.Second)
+		healthMonitor.SetCacheMaxAge(cfg.HealthCacheMaxAge)
+		healthMonitor.SetReplayRate(cfg.WALReplayRate, cfg.WALReplayBatchSize)
+		healthMonitor.WatchPool("ingest", pool)
+		if priorityPool != pool {
+			healthMonitor.WatchPool("reserved", priorityPool)
+		}
+		if cfg.WALReplayRate > 0 {
+			log.Printf("WAL replay paced to %d records/s", cfg.WALReplayRate)
+		}
+		healthMonitor.OnUnhealthy(func(err error) {
+			log.Printf("EVENT: ingest_mode_change mode=wal_only error=%q", err)
+		})
+		healthMonitor.OnHealthy(func() {
+			log.Println("EVENT: ingest_mode_change mode=normal")
+		})
 		healthMonitor.Start()
 		log.Println("Health monitor started")
-		defer healthMonitor.Stop()
+	}
+
+	// Per-ground-station data quality, "other" beyond MAX_GROUND_STATIONS
+	dataQuality := handlers.NewDataQualityTracker()
+	dataQuality.SetMaxStations(cfg.MaxGroundStations)
+
+	// Parse ingest timestamps in the format each satellite declares
+	timestamps := handlers.NewTimestampNormalizer(querier, cfg.TimestampProfileRefresh)
+	timestamps.Start()
+
+	// Sample WAL growth for the autoscaling signal endpoint
+	autoscaleMonitor := db.NewAutoscaleMonitor(batchProcessor, cfg.AutoscaleWALWindow)
+	autoscaleMonitor.Start()
+
+	// Configure query guardrails (max range / max rows per endpoint class)
+	guardrails := handlers.NewQueryGuardrails(
+		handlers.QueryLimits{MaxRange: cfg.QueryMaxRangeRaw, MaxRows: cfg.QueryMaxRowsRaw},
+		handlers.QueryLimits{MaxRange: cfg.QueryMaxRangeAggregate, MaxRows: cfg.QueryMaxRowsAggregate},
+	)
+	guardrails.SetDefault(handlers.EndpointClassExport, handlers.QueryLimits{MaxRange: cfg.ExportMaxRange})
+	for _, o := range cfg.QueryLimitOverrides {
+		guardrails.SetOverride(o.Principal, handlers.EndpointClass(o.Class),
+			handlers.QueryLimits{MaxRange: o.MaxRange, MaxRows: o.MaxRows})
+	}
+
+	// Daily stats buckets align to the operations center's midnight
+	statsLocation, err := time.LoadLocation(cfg.StatsTimeZone)
+	if err != nil {
+		log.Fatalf("Invalid STATS_TIMEZONE: %v", err)
+	}
+
+	// Weekly fleet health report for management, on the same day boundaries
+	var fleetReporter *reporting.Reporter
+	if cfg.FleetReportEnabled {
+		fleetReporter = buildFleetReporter(cfg, querier, dataQuality, statsLocation, leader)
+	}
+
+	// Resolve client identity from proxy headers only when behind trusted proxies
+	identity, err := handlers.NewIdentityResolver(handlers.IdentityConfig{
+		TrustedProxies:    cfg.TrustedProxies,
+		CertSubjectHeader: cfg.ClientCertSubjectHeader,
+		CertVerifyHeader:  cfg.ClientCertVerifyHeader,
+	})
+	if err != nil {
+		log.Fatalf("Invalid trusted proxy configuration: %v", err)
+	}
+
+	// API keys, JWT bearer tokens and verified client certificates guard the
+	// telemetry endpoints
+	apiKeys := make([]handlers.APIKey, 0, len(cfg.AuthAPIKeys))
+	for _, key := range cfg.AuthAPIKeys {
+		apiKeys = append(apiKeys, handlers.APIKey{
+			ID:         key.ID,
+			Role:       key.Role,
+			Key:        key.Key,
+			Satellites: cfg.AuthKeySatellites[key.ID],
+		})
+	}
+	clientCerts := make([]handlers.ClientCert, 0, len(cfg.AuthClientCerts))
+	for _, cert := range cfg.AuthClientCerts {
+		clientCerts = append(clientCerts, handlers.ClientCert{
+			ID:         cert.ID,
+			Role:       cert.Role,
+			Subject:    cert.Subject,
+			Satellites: cfg.AuthKeySatellites[cert.ID],
+		})
+	}
+	auth, err := handlers.NewAuthenticator(handlers.AuthConfig{
+		APIKeys:     apiKeys,
+		ClientCerts: clientCerts,
+		JWTSecret:   cfg.AuthJWTSecret,
+		JWTIssuer:   cfg.AuthJWTIssuer,
+		JWTAudience: cfg.AuthJWTAudience,
+		AdminRoles:  cfg.AuthAdminRoles,
+	})
+	if err != nil {
+		log.Fatalf("Invalid authentication configuration: %v", err)
+	}
+	if auth.Enabled() {
+		log.Printf("Authentication enabled: %d API key(s), %d client certificate(s), JWT: %v", len(apiKeys), len(clientCerts), cfg.AuthJWTSecret != "")
+	} else {
+		log.Printf("WARNING: Authentication disabled, the telemetry endpoints are open (set AUTH_API_KEYS, AUTH_CLIENT_CERTS or AUTH_JWT_SECRET)")
+	}
+
+	// Reject or tag points with missing or implausible values
+	validator, err := handlers.NewPointValidator(handlers.ValidationConfig{
+		Mode:               handlers.ValidationMode(cfg.ValidationMode),
+		SatelliteIDPattern: cfg.ValidationSatelliteIDPattern,
+		MinAltitudeKM:      cfg.ValidationMinAltitudeKM,
+		MaxAltitudeKM:      cfg.ValidationMaxAltitudeKM,
+		MaxVelocityKMPH:    cfg.ValidationMaxVelocityKMPH,
+	})
+	if err != nil {
+		log.Fatalf("Invalid validation configuration: %v", err)
+	}
+
+	// Per-client request rate on the ingest routes
+	rateLimiter := handlers.NewRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst)
+	if rateLimiter.Enabled() {
+		log.Printf("Rate limiting ingest to %g requests/s per client (burst %d)", cfg.RateLimitRPS, cfg.RateLimitBurst)
+	}
+
+	// Replayed responses for client retries carrying an Idempotency-Key
+	idempotency := handlers.NewIdempotencyCache(cfg.IdempotencyTTL, cfg.IdempotencyMaxKeys)
+
+	// Audit log of admin operations
+	var auditSink db.AuditSink
+	switch cfg.AuditLogSink {
+	case "file":
+		fileSink, err := db.NewFileAuditSink(cfg.AuditLogPath)
+		if err != nil {
+			log.Fatalf("Failed to initialize audit log: %v", err)
+		}
+		auditSink = fileSink
+		log.Printf("Auditing admin operations to %s", cfg.AuditLogPath)
+	case "db":
+		auditSink = db.NewTableAuditSink(pool)
+		log.Printf("Auditing admin operations to the admin_audit_log table")
+	}
+
+	var securityHeaders *handlers.SecurityHeadersConfig
+	if cfg.SecurityHeaders {
+		securityHeaders = &handlers.SecurityHeadersConfig{HSTSMaxAge: cfg.HSTSMaxAge}
 	}
 
 	// Setup HTTP router
-	router := setupRouter(batchProcessor)
+	// Batching, retry and anomaly threshold settings reload on SIGHUP or
+	// POST /admin/config/reload without dropping the buffer
+	reloader := &configReloader{
+		path:           *configPath,
+		settings:       settings,
+		batchProcessor: batchProcessor,
+		started:        cfg,
+		current:        cfg,
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			result, err := reloader.Reload()
+			if err != nil {
+				log.Printf("Config reload rejected, keeping the running configuration: %v", err)
+				continue
+			}
+			log.Printf("EVENT: config_reload applied=%v restart_required=%v", result.Applied, result.RestartRequired)
+		}
+	}()
+
+	router := setupRouter(routerDeps{
+		identity:          identity,
+		auth:              auth,
+		rateLimiter:       rateLimiter,
+		idempotency:       idempotency,
+		audit:             handlers.NewAuditLogger(auditSink),
+		ingestLimits:      handlers.IngestLimits{MaxBodyBytes: cfg.MaxBodyBytes, MaxBatchPoints: cfg.MaxBatchPoints},
+		validator:         validator,
+		trustedProxies:    cfg.TrustedProxies,
+		batchProcessor:    batchProcessor,
+		healthMonitor:     healthMonitor,
+		healthPool:        priorityPool,
+		breakers:          breakers,
+		querier:           querier,
+		guardrails:        guardrails,
+		statsLocation:     statsLocation,
+		exportSigner:      handlers.NewResumeTokenSigner(cfg.ExportTokenSecret, cfg.ExportResumeTokenTTL),
+		exportRows:        cfg.ExportCheckpointRows,
+		silences:          silences,
+		runbooks:          runbooks,
+		decayMonitor:      decayMonitor,
+		batteryForecast:   batteryForecaster,
+		storageForecast:   storageForecaster,
+		watchdog:          watchdog,
+		gaps:              gapQuerier,
+		geofences:         geofences,
+		indexAdvisor:      indexAdvisor,
+		fleetReporter:     fleetReporter,
+		leader:            leader,
+		autoscale:         autoscaleMonitor,
+		episodes:          episodes,
+		replication:       replicationHandler,
+		reloader:          reloader,
+		dataQuality:       dataQuality,
+		timestamps:        timestamps,
+		security:          securityHeaders,
+		backpressureQueue: cfg.BackpressureQueueTimeout,
+		readiness: handlers.ReadinessConfig{
+			MaxBuffered:        cfg.MaxBufferSize * cfg.ReadyBufferPercent / 100,
+			SkipDatabase:       !cfg.ReadyCheckDatabase,
+			SkipCircuitBreaker: !cfg.ReadyCheckBreaker,
+		},
+		cors: handlers.NewCORS(handlers.CORSConfig{
+			AllowedOrigins: cfg.CORSAllowedOrigins,
+			AllowedMethods: cfg.CORSAllowedMethods,
+			AllowedHeaders: cfg.CORSAllowedHeaders,
+			MaxAge:         cfg.CORSMaxAge,
+		}),
+		routeTimeouts: handlers.NewRouteTimeouts(map[handlers.RouteClass]time.Duration{
+			handlers.RouteClassIngest: cfg.RouteTimeoutIngest,
+			handlers.RouteClassQuery:  cfg.RouteTimeoutQuery,
+			handlers.RouteClassExport: cfg.RouteTimeoutExport,
+			handlers.RouteClassAdmin:  cfg.RouteTimeoutAdmin,
+		}),
+	})
 
 	// Configure HTTP server
 	server := &http.Server{
@@ -85,17 +701,54 @@ func main() {
 		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20, // 1 MB
 	}
+	if cfg.TLSCertFile != "" {
+		server.TLSConfig, err = handlers.NewTLSConfig(handlers.TLSConfig{
+			CertFile:     cfg.TLSCertFile,
+			KeyFile:      cfg.TLSKeyFile,
+			ClientCAFile: cfg.TLSClientCAFile,
+			MinVersion:   cfg.TLSMinVersion,
+		})
+		if err != nil {
+			log.Fatalf("Invalid TLS configuration: %v", err)
+		}
+	}
 
 	// Start server with graceful shutdown
 	go func() {
+		defer recoverCrash(batchProcessor, cfg.EmergencyWALPath)
 		log.Printf("Starting OrbitStream ingestion service on port %s", cfg.Port)
 		log.Printf("Configuration:")
-		log.Printf("  Batch Size: %d", cfg.BatchSize)
+		if cfg.Profile != "" {
+			log.Printf("  Profile: %s", cfg.Profile)
+		}
+		if cfg.BatchSizeAdaptive {
+			log.Printf("  Batch Size: adaptive %d-%d for %v inserts, starting at %d",
+				cfg.BatchSizeMin, cfg.BatchSizeMax, cfg.BatchLatencyTarget, cfg.BatchSize)
+		} else {
+			log.Printf("  Batch Size: %d", cfg.BatchSize)
+		}
 		log.Printf("  Batch Timeout: %v", cfg.BatchTimeout)
-		log.Printf("  Max Retries: %d", cfg.MaxRetries)
-		log.Printf("  Circuit Breaker Threshold: %d", cfg.CircuitBreakerThreshold)
+		log.Printf("  Max Retries: %d (budget %v)", cfg.MaxRetries, cfg.RetryBudget)
+		if cfg.CircuitBreakerPolicy == "failure_rate" {
+			log.Printf("  Circuit Breaker: >%.0f%% of last %d calls failed (min %d calls)",
+				cfg.CircuitBreakerFailureRate*100, cfg.CircuitBreakerWindow, cfg.CircuitBreakerMinCalls)
+		} else {
+			log.Printf("  Circuit Breaker Threshold: %d", cfg.CircuitBreakerThreshold)
+		}
 		log.Printf("  Max Buffer Size: %d", cfg.MaxBufferSize)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("  Buffer High-Water Mark: %d%%", cfg.BufferHighWaterPercent)
+		log.Printf("  Query Limits: raw %v/%d rows, aggregate %v/%d rows",
+			cfg.QueryMaxRangeRaw, cfg.QueryMaxRowsRaw, cfg.QueryMaxRangeAggregate, cfg.QueryMaxRowsAggregate)
+		log.Printf("  Validation: %s", cfg.ValidationMode)
+		log.Printf("  Route Timeouts: ingest %v, query %v, export %v, admin %v",
+			cfg.RouteTimeoutIngest, cfg.RouteTimeoutQuery, cfg.RouteTimeoutExport, cfg.RouteTimeoutAdmin)
+		serve := server.ListenAndServe
+		if server.TLSConfig != nil {
+			log.Printf("  TLS: min version %s, client certificates required: %v", cfg.TLSMinVersion, cfg.TLSClientCAFile != "")
+			// The certificate comes from TLSConfig.GetCertificate
+			serve = func() error { return server.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed: %v", err)
 		}
 	}()
@@ -115,9 +768,70 @@ func main() {
 		log.Println("Health monitor stopped")
 	}
 
-	// Stop batch processor (triggers final flush)
-	batchProcessor.Stop()
-	log.Println("Batch processor stopped")
+	// Stop accepting points and store the buffer before anything it
+	// depends on (WAL, shadow writer) is closed
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	if err := batchProcessor.Drain(drainCtx); err != nil {
+		log.Printf("Batch processor drain incomplete: %v", err)
+	} else {
+		log.Println("Batch processor drained")
+	}
+	cancelDrain()
+
+	// Write the shadow batches still queued
+	if shadowWriter != nil {
+		shadowWriter.Stop()
+	}
+
+	// Stop targeted aggregate refreshes for late data
+	lateData.Stop()
+
+	autoscaleMonitor.Stop()
+	timestamps.Stop()
+	if propagator != nil {
+		propagator.Stop()
+	}
+
+	if indexAdvisor != nil {
+		indexAdvisor.Stop()
+	}
+	if fleetReporter != nil {
+		fleetReporter.Stop()
+	}
+
+	// Stop orbital decay, forecast and contact checks before their alert sink
+	if decayMonitor != nil {
+		decayMonitor.Stop()
+	}
+	if batteryForecaster != nil {
+		batteryForecaster.Stop()
+	}
+	if storageForecaster != nil {
+		storageForecaster.Stop()
+	}
+	if watchdog != nil {
+		watchdog.Stop()
+	}
+
+	// Hand leadership to another replica once the jobs stopped
+	if leader != nil {
+		leader.Stop()
+	}
+
+	// Deliver grouped and queued anomaly alerts
+	if alertGrouper != nil {
+		alertGrouper.Stop()
+	}
+	if alerter != nil {
+		alerter.Stop()
+		log.Println("Webhook alerter stopped")
+	}
+
+	// Ship remaining WAL changes to the standby
+	if walShipper != nil {
+		walShipper.Stop()
+		log.Println("WAL shipper stopped")
+	}
 
 	// Close WAL
 	if wal != nil {
@@ -133,17 +847,399 @@ func main() {
 	log.Println("Server exited")
 }
 
-func setupRouter(batchProcessor *db.BatchProcessor) *gin.Engine {
+// buildAnomalyConfig builds the fixed anomaly thresholds
+func buildAnomalyConfig(cfg config.Config) db.AnomalyConfig {
+	return db.AnomalyConfig{
+		BatteryMinPercent: cfg.AnomalyThresholdBattery,
+		StorageMaxMB:      cfg.AnomalyThresholdStorage,
+		SignalMinDBM:      cfg.AnomalyThresholdSignal,
+		// Hysteresis / debounce
+		ConsecutivePoints:   cfg.AnomalyHysteresisPoints,
+		MinBreachDuration:   cfg.AnomalyHysteresisDuration,
+		BatteryClearPercent: cfg.AnomalyClearBattery,
+		StorageClearMB:      cfg.AnomalyClearStorage,
+		SignalClearDBM:      cfg.AnomalyClearSignal,
+	}
+}
+
+// reloadableSettings are the Config fields configReloader applies to the
+// running service; every other change waits for a restart
+var reloadableSettings = map[string]bool{
+	"BatchSize":                 true,
+	"BatchTimeout":              true,
+	"MaxRetries":                true,
+	"RetryDelay":                true,
+	"AnomalyThresholdBattery":   true,
+	"AnomalyThresholdStorage":   true,
+	"AnomalyThresholdSignal":    true,
+	"AnomalyHysteresisPoints":   true,
+	"AnomalyHysteresisDuration": true,
+	"AnomalyClearBattery":       true,
+	"AnomalyClearStorage":       true,
+	"AnomalyClearSignal":        true,
+}
+
+// configReloader re-reads the configuration the service started with (same
+// file and -set settings, current environment) on SIGHUP or
+// POST /admin/config/reload and retunes the batch processor
+type configReloader struct {
+	path           string
+	settings       config.Settings
+	batchProcessor *db.BatchProcessor
+
+	// The configuration the service started with, which restart-only
+	// settings still come from, and the one applied last
+	started config.Config
+	mu      sync.Mutex
+	current config.Config
+}
+
+// Reload applies the reloadable settings of a valid configuration at once;
+// an invalid one is rejected and the running configuration kept
+func (r *configReloader) Reload() (models.ConfigReload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := config.LoadConfigFromFile(r.path, r.settings)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		return models.ConfigReload{}, err
+	}
+
+	result := models.ConfigReload{ReloadedAt: time.Now().UTC(), Applied: []string{}, RestartRequired: []string{}}
+	for _, name := range config.ChangedSettings(r.current, cfg) {
+		if reloadableSettings[name] {
+			result.Applied = append(result.Applied, name)
+		}
+	}
+	for _, name := range config.ChangedSettings(r.started, cfg) {
+		if !reloadableSettings[name] {
+			result.RestartRequired = append(result.RestartRequired, name)
+		}
+	}
+	r.batchProcessor.Retune(db.Tuning{
+		BatchSize:    cfg.BatchSize,
+		BatchTimeout: cfg.BatchTimeout,
+		MaxRetries:   cfg.MaxRetries,
+		RetryDelay:   cfg.RetryDelay,
+		Anomaly:      buildAnomalyConfig(cfg),
+	})
+	r.current = cfg
+	return result, nil
+}
+
+// buildAnomalyDetectors builds the detector chain named in ANOMALY_DETECTORS
+// Custom detectors are looked up in the db anomaly detector registry
+func buildAnomalyDetectors(cfg config.Config, anomalyConfig db.AnomalyConfig) ([]db.AnomalyDetector, error) {
+	names := cfg.AnomalyDetectors
+	// ANOMALY_STATISTICAL_ENABLED predates ANOMALY_DETECTORS and still appends it
+	if cfg.AnomalyStatisticalEnabled && !containsString(names, "statistical") {
+		names = append(append([]string{}, names...), "statistical")
+	}
+	// Configured composite rules are evaluated even if not listed
+	if cfg.AnomalyRules != "" && !containsString(names, db.CompositeDetectorName) {
+		names = append(append([]string{}, names...), db.CompositeDetectorName)
+	}
+
+	detectors := make([]db.AnomalyDetector, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case "threshold":
+			detectors = append(detectors, db.NewThresholdDetector(anomalyConfig))
+		case "statistical":
+			detectors = append(detectors, db.NewStatisticalDetector(
+				cfg.AnomalyStatisticalAlpha,
+				cfg.AnomalyStatisticalSigma,
+				cfg.AnomalyStatisticalWarmup,
+			))
+			log.Printf("Statistical anomaly detection enabled (sigma=%.1f, alpha=%.2f)",
+				cfg.AnomalyStatisticalSigma, cfg.AnomalyStatisticalAlpha)
+		case db.PositionDetectorName:
+			detectors = append(detectors, db.NewPositionDetector(db.PositionCheckConfig{
+				MaxVelocityKMPH: cfg.PositionCheckMaxVelocityKMPH,
+				Tolerance:       cfg.PositionCheckTolerance,
+				SlackKM:         cfg.PositionCheckSlackKM,
+			}))
+			log.Printf("Position consistency detection enabled (tolerance %.0f%%, slack %.0f km)",
+				cfg.PositionCheckTolerance*100, cfg.PositionCheckSlackKM)
+		case db.CompositeDetectorName:
+			rules, err := db.ParseCompositeRules(cfg.AnomalyRules)
+			if err != nil {
+				return nil, err
+			}
+			if len(rules) == 0 {
+				return nil, fmt.Errorf("anomaly detector %q requires ANOMALY_RULES", name)
+			}
+			detectors = append(detectors, db.NewCompositeDetector(rules))
+			for _, rule := range rules {
+				log.Printf("Composite anomaly rule %s (%s): %s sustained %v", rule.Name, rule.Severity, rule.Expr, rule.Sustained)
+			}
+		default:
+			detector, ok := db.NewRegisteredAnomalyDetector(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown anomaly detector %q", name)
+			}
+			detectors = append(detectors, detector)
+		}
+	}
+	log.Printf("Anomaly detector chain: %v", names)
+	return detectors, nil
+}
+
+// recoverCrash persists unflushed telemetry to the emergency WAL and logs a
+// crash report when a panic unwinds to it, then re-panics so the process
+// still exits with the original stack trace
+func recoverCrash(bp *db.BatchProcessor, emergencyWALPath string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	reportCrash(bp, r, emergencyWALPath)
+	panic(r)
+}
+
+// reportCrash persists unflushed telemetry and logs the crash report
+func reportCrash(bp *db.BatchProcessor, reason any, emergencyWALPath string) {
+	report := bp.CrashSnapshot(reason, emergencyWALPath)
+	if data, err := json.Marshal(report); err == nil {
+		log.Printf("CRASH REPORT: %s", data)
+	} else {
+		log.Printf("CRASH REPORT: %+v", report)
+	}
+}
+
+func containsString(items []string, target string) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
+// buildFleetReporter creates the fleet report and starts its weekly schedule
+// when it has somewhere to deliver to; it can always be rendered on demand.
+// With a leader elector only the leader delivers.
+func buildFleetReporter(cfg config.Config, querier *db.QueryService, stations reporting.StationSource, loc *time.Location, leader *db.LeaderElector) *reporting.Reporter {
+	weekday, err := reporting.ParseWeekday(cfg.FleetReportWeekday)
+	if err != nil {
+		log.Fatalf("Invalid FLEET_REPORT_WEEKDAY: %v", err)
+	}
+
+	var deliverers []reporting.Deliverer
+	if len(cfg.FleetReportEmailTo) > 0 {
+		if cfg.FleetReportSMTPAddr == "" || cfg.FleetReportEmailFrom == "" {
+			log.Fatalf("FLEET_REPORT_EMAIL_TO requires FLEET_REPORT_SMTP_ADDR and FLEET_REPORT_EMAIL_FROM")
+		}
+		deliverers = append(deliverers, reporting.NewEmailDeliverer(reporting.EmailConfig{
+			Addr:     cfg.FleetReportSMTPAddr,
+			Username: cfg.FleetReportSMTPUsername,
+			Password: cfg.FleetReportSMTPPassword,
+			From:     cfg.FleetReportEmailFrom,
+			To:       cfg.FleetReportEmailTo,
+		}))
+	}
+	if cfg.FleetReportUploadURL != "" {
+		deliverers = append(deliverers, reporting.NewUploadDeliverer(cfg.FleetReportUploadURL, time.Minute,
+			retry.Policy{MaxAttempts: 5, BaseDelay: 5 * time.Second, Jitter: retry.JitterFull}))
+	}
+
+	reporter := reporting.NewReporter(querier, stations, reporting.Config{
+		Weekday:  weekday,
+		Hour:     cfg.FleetReportHour,
+		Location: loc,
+		TopN:     cfg.FleetReportTopN,
+	}, deliverers...)
+	if len(deliverers) == 0 {
+		log.Printf("Fleet report available at /admin/reports/fleet (no email or upload configured, not scheduled)")
+		return reporter
+	}
+	if leader != nil {
+		reporter.SetLeadership(leader)
+	}
+	reporter.Start()
+	log.Printf("Fleet report scheduled every %s at %02d:00 %s, next %s",
+		weekday, cfg.FleetReportHour, loc, reporter.NextRun(time.Now()).Format(time.RFC3339))
+	return reporter
+}
+
+// routerDeps bundles the components setupRouter registers routes for
+type routerDeps struct {
+	batchProcessor  *db.BatchProcessor
+	healthMonitor   *db.HealthMonitor // nil without a WAL; /health then pings directly
+	healthPool      *pgxpool.Pool     // reserved connections for health pings
+	breakers        *db.CircuitBreakerRegistry
+	querier         *db.QueryService
+	guardrails      *handlers.QueryGuardrails
+	statsLocation   *time.Location
+	exportSigner    *handlers.ResumeTokenSigner
+	exportRows      int
+	silences        *alerting.Silences
+	runbooks        *alerting.Runbooks
+	autoscale       *db.AutoscaleMonitor
+	episodes        *db.EpisodeTracker
+	decayMonitor    *db.DecayMonitor             // nil unless ORBITAL_DECAY_ENABLED
+	batteryForecast *db.Forecaster               // nil unless BATTERY_FORECAST_ENABLED
+	storageForecast *db.Forecaster               // nil unless STORAGE_FORECAST_ENABLED
+	watchdog        *db.ContactWatchdog          // nil unless WATCHDOG_ENABLED
+	gaps            handlers.GapQuerier          // nil unless GAP_DETECTION_ENABLED
+	geofences       *db.GeofenceMonitor          // nil unless GEOFENCE_FILE
+	indexAdvisor    *db.IndexAdvisor             // nil unless INDEX_ADVISOR_ENABLED
+	fleetReporter   *reporting.Reporter          // nil unless FLEET_REPORT_ENABLED
+	leader          *db.LeaderElector            // nil unless LEADER_ELECTION_ENABLED
+	replication     *handlers.ReplicationHandler // nil unless running as standby
+	reloader        handlers.ConfigReloader
+	identity        *handlers.IdentityResolver
+	auth            *handlers.Authenticator
+	rateLimiter     *handlers.RateLimiter
+	idempotency     *handlers.IdempotencyCache
+	audit           *handlers.AuditLogger
+	ingestLimits    handlers.IngestLimits
+	validator       *handlers.PointValidator
+	trustedProxies  []string
+	routeTimeouts   *handlers.RouteTimeouts
+	cors            *handlers.CORS
+	security        *handlers.SecurityHeadersConfig // nil disables security headers
+	dataQuality     *handlers.DataQualityTracker
+	timestamps      *handlers.TimestampNormalizer
+	// How long ingest requests wait out backpressure before a 429
+	backpressureQueue time.Duration
+	readiness         handlers.ReadinessConfig
+}
+
+func setupRouter(deps routerDeps) *gin.Engine {
 	router := gin.Default()
 
-	telemetryHandler := handlers.NewTelemetryHandlerWithDB(batchProcessor)
+	// Only trust forwarding headers from configured proxies (none by default),
+	// so gin's ClientIP and our identity middleware agree and cannot be spoofed
+	if err := router.SetTrustedProxies(deps.trustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	router.Use(deps.identity.Middleware())
+	if deps.security != nil {
+		router.Use(handlers.SecurityHeaders(*deps.security))
+	}
+
+	telemetryHandler := handlers.NewTelemetryHandlerWithDB(deps.batchProcessor)
+	telemetryHandler.SetHealthMonitor(deps.healthMonitor)
+	telemetryHandler.SetHealthPool(deps.healthPool)
+	telemetryHandler.SetCircuitBreakers(deps.breakers)
+	telemetryHandler.SetDataQuality(deps.dataQuality)
+	telemetryHandler.SetTimestampNormalizer(deps.timestamps)
+	telemetryHandler.SetValidator(deps.validator)
+	telemetryHandler.SetIngestLimits(deps.ingestLimits)
+	telemetryHandler.SetBackpressureQueue(deps.backpressureQueue)
+	telemetryHandler.SetReadiness(deps.readiness)
+	queryHandler := handlers.NewQueryHandler(deps.querier, deps.guardrails)
+	queryHandler.SetRunbooks(deps.runbooks)
+	queryHandler.SetStatsLocation(deps.statsLocation)
+	adminHandler := handlers.NewAdminHandler(deps.batchProcessor)
+	adminHandler.SetDecayMonitor(deps.decayMonitor)
+	adminHandler.SetIndexAdvisor(deps.indexAdvisor)
+	adminHandler.SetLeaderElector(deps.leader)
+	adminHandler.SetFleetReporter(deps.fleetReporter)
+	adminHandler.SetHealthMonitor(deps.healthMonitor)
+	adminHandler.SetRunbooks(deps.runbooks)
+	adminHandler.SetSchemaSource(deps.querier)
+	adminHandler.SetConfigReloader(deps.reloader)
+	adminHandler.SetCircuitBreakers(deps.breakers)
+	silenceHandler := handlers.NewSilenceHandler(deps.silences)
+	exportHandler := handlers.NewExportHandler(deps.querier, deps.guardrails, deps.exportSigner, deps.exportRows)
 
 	// Health check
-	router.GET("/health", telemetryHandler.HealthCheck)
+	deps.cors.Handle(router, "/health", telemetryHandler.HealthCheck)
+	router.GET("/livez", telemetryHandler.Livez)
+	router.GET("/readyz", telemetryHandler.Readyz)
+
+	// Per-class timeouts cancel the request context (and its DB queries)
+	ingestTimeout := deps.routeTimeouts.Middleware(handlers.RouteClassIngest)
+	queryTimeout := deps.routeTimeouts.Middleware(handlers.RouteClassQuery)
+	exportTimeout := deps.routeTimeouts.Middleware(handlers.RouteClassExport)
+	// Authentication runs after CORS, so preflight requests need no credentials
+	auth := deps.auth.Middleware()
+	// Rate limits key clients by the API key authentication resolved
+	rateLimit := deps.rateLimiter.Middleware()
+	// Idempotency keys are scoped by API key too; retries still count
+	// against the rate limit
+	idempotent := deps.idempotency.Middleware()
+	// Oversized bodies are refused before anything reads them
+	bodyLimit := deps.ingestLimits.Middleware()
 
 	// Telemetry endpoints
-	router.POST("/telemetry", telemetryHandler.HandleTelemetry)
-	router.POST("/telemetry/batch", telemetryHandler.HandleTelemetryBatch)
+	router.POST("/telemetry", auth, rateLimit, bodyLimit, idempotent, ingestTimeout, telemetryHandler.HandleTelemetry)
+	router.POST("/telemetry/batch", auth, rateLimit, bodyLimit, idempotent, ingestTimeout, telemetryHandler.HandleTelemetryBatch)
+
+	// Query endpoints (bounded by guardrails); the only routes browser
+	// dashboards may call cross-origin
+	deps.cors.Handle(router, "/telemetry", auth, queryTimeout, queryHandler.HandleQueryTelemetry)
+	deps.cors.Handle(router, "/stats", auth, queryTimeout, queryHandler.HandleStats)
+	deps.cors.Handle(router, "/stats/versions", auth, queryTimeout, queryHandler.HandleVersionStats)
+	deps.cors.Handle(router, "/export", auth, exportTimeout, exportHandler.HandleExport)
+	deps.cors.Handle(router, "/stats/data-quality", auth, deps.dataQuality.HandleDataQuality)
+	deps.cors.Handle(router, "/anomalies/episodes", auth, handlers.NewEpisodeHandler(deps.episodes).HandleEpisodes)
+	deps.cors.Handle(router, "/telemetry/gaps", auth, queryTimeout, handlers.NewGapHandler(deps.gaps, deps.guardrails).HandleGaps)
+	deps.cors.Handle(router, "/telemetry/near", auth, queryTimeout, handlers.NewNearHandler(deps.querier, deps.guardrails).HandleNear)
+	deps.cors.Handle(router, "/satellites/:id/track", auth, queryTimeout, handlers.NewTrackHandler(deps.querier, deps.guardrails).HandleTrack)
+	deps.cors.Handle(router, "/satellites/:id/battery/forecast", auth, queryTimeout, handlers.NewForecastHandler(db.ResourceBattery, deps.batteryForecast).HandleForecast)
+	deps.cors.Handle(router, "/satellites/:id/storage/forecast", auth, queryTimeout, handlers.NewForecastHandler(db.ResourceStorage, deps.storageForecast).HandleForecast)
+	deps.cors.Handle(router, "/fleet/storage/forecast", auth, queryTimeout, handlers.NewForecastHandler(db.ResourceStorage, deps.storageForecast).HandleFleetForecast)
+	deps.cors.Handle(router, "/fleet/stale", auth, handlers.NewWatchdogHandler(deps.watchdog).HandleStale)
+	deps.cors.Handle(router, "/fleet/geofences", auth, handlers.NewGeofenceHandler(deps.geofences).HandleGeofences)
+
+	// Pipeline pressure for KEDA / HPA external metrics
+	router.GET("/metrics/autoscaling", handlers.NewAutoscaleHandler(deps.autoscale).HandleAutoscaling)
+	router.GET("/metrics/pools", telemetryHandler.HandlePoolMetrics)
+	router.GET("/metrics/auth", deps.auth.HandleStats)
+
+	// Admin endpoints
+	// Callers are authenticated first, so operations changing state are
+	// audited with their API key ID and role as well as the result; callers
+	// without an admin role are refused after being audited
+	admin := router.Group("/admin", deps.routeTimeouts.Middleware(handlers.RouteClassAdmin), auth, deps.audit.Middleware(), deps.auth.RequireAdmin())
+	admin.GET("/audit", deps.audit.HandleList)
+	admin.GET("/stats", adminHandler.HandleStats)
+	admin.GET("/pending", adminHandler.HandlePending)
+	admin.GET("/late", adminHandler.HandleLateData)
+	admin.GET("/cardinality", adminHandler.HandleCardinality)
+	admin.GET("/dedup", adminHandler.HandleDedup)
+	admin.GET("/timestamp-guard", adminHandler.HandleTimestampGuard)
+	admin.GET("/batch-size", adminHandler.HandleBatchSize)
+	admin.GET("/leader", adminHandler.HandleLeader)
+	admin.GET("/shadow", adminHandler.HandleShadow)
+	admin.GET("/decay", adminHandler.HandleDecay)
+	admin.GET("/index-advisor", adminHandler.HandleIndexAdvisor)
+	admin.GET("/reports/fleet", adminHandler.HandleFleetReport)
+	admin.GET("/timeouts", deps.routeTimeouts.HandleStats)
+	admin.GET("/rate-limits", deps.rateLimiter.HandleStats)
+	admin.GET("/idempotency", deps.idempotency.HandleStats)
+	admin.GET("/identity", deps.identity.HandleStats)
+	admin.GET("/validation", deps.validator.HandleStats)
+	admin.GET("/wal", adminHandler.HandleWAL)
+	admin.GET("/wal/verify", adminHandler.HandleWALVerify)
+	admin.POST("/wal/replay", adminHandler.HandleWALReplay)
+	admin.POST("/wal/clear", adminHandler.HandleWALClear)
+	admin.GET("/dead-letters", adminHandler.HandleDeadLetters)
+	admin.POST("/config/reload", adminHandler.HandleConfigReload)
+	admin.POST("/flush", adminHandler.HandleFlush)
+	admin.GET("/circuit-breaker", adminHandler.HandleCircuitBreaker)
+	admin.POST("/circuit-breaker/reset", adminHandler.HandleCircuitBreakerReset)
+	admin.GET("/schema", adminHandler.HandleSchema)
+	admin.GET("/schema/export", adminHandler.HandleSchemaExport)
+	admin.POST("/silences", silenceHandler.HandleCreate)
+	admin.GET("/silences", silenceHandler.HandleList)
+	admin.DELETE("/silences/:id", silenceHandler.HandleDelete)
+
+	// Warm standby endpoints (WAL shipping from the primary); everything
+	// but the status requires the shared replication token
+	if deps.replication != nil {
+		requireToken := deps.replication.RequireToken()
+		router.POST("/replication/wal", requireToken, deps.replication.HandleReceive)
+		router.POST("/replication/wal/truncate", requireToken, deps.replication.HandleTruncate)
+		router.POST("/replication/promote", requireToken, deps.replication.HandlePromote)
+		router.GET("/replication/status", deps.replication.HandleStatus)
+	}
 
 	return router
 }
@@ -0,0 +1,106 @@
+package models
+
+import "time"
+
+// AnomalySeverity classifies how urgent an anomaly is
+type AnomalySeverity string
+
+const (
+	SeverityInfo     AnomalySeverity = "info"
+	SeverityWarning  AnomalySeverity = "warning"
+	SeverityCritical AnomalySeverity = "critical"
+)
+
+// Rank orders severities (info < warning < critical); unknown values rank 0
+func (s AnomalySeverity) Rank() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityWarning:
+		return 2
+	case SeverityCritical:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Valid reports whether s is a known severity
+func (s AnomalySeverity) Valid() bool {
+	return s.Rank() > 0
+}
+
+// AnomalyDimension is the telemetry aspect an anomaly relates to
+type AnomalyDimension string
+
+const (
+	DimensionBattery  AnomalyDimension = "battery"
+	DimensionStorage  AnomalyDimension = "storage"
+	DimensionSignal   AnomalyDimension = "signal"
+	DimensionPosition AnomalyDimension = "position"
+)
+
+// AnomalyResult is a single detector finding for a telemetry point
+type AnomalyResult struct {
+	Detector  string           `json:"detector"`
+	Severity  AnomalySeverity  `json:"severity"`
+	Dimension AnomalyDimension `json:"dimension"`
+	Message   string           `json:"message,omitempty"`
+	// Metric is the telemetry field that triggered the finding, with its
+	// value and the threshold (or statistical bound) it crossed
+	Metric    string  `json:"metric,omitempty"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+}
+
+// AnomalyEvent is an anomaly finding for one satellite, as sent to alert sinks
+type AnomalyEvent struct {
+	SatelliteID string    `json:"satellite_id"`
+	Timestamp   time.Time `json:"timestamp"`
+	// Count is how many occurrences a grouped alert stands for (0 or 1 = single)
+	Count int `json:"count,omitempty"`
+	// Runbook is the configured remediation for this kind of anomaly
+	Runbook *Runbook `json:"runbook,omitempty"`
+	// Recovered marks the end of an anomaly episode: Value is the metric back
+	// to normal and Episode the closed episode
+	Recovered bool            `json:"recovered,omitempty"`
+	Episode   *AnomalyEpisode `json:"episode,omitempty"`
+	AnomalyResult
+}
+
+// AnomalyEpisode is a run of consecutive anomalous points from one satellite
+// for one detector and metric, from the first flagged point until a point
+// is no longer flagged
+type AnomalyEpisode struct {
+	SatelliteID string           `json:"satellite_id"`
+	Detector    string           `json:"detector"`
+	Metric      string           `json:"metric,omitempty"`
+	Dimension   AnomalyDimension `json:"dimension"`
+	// Severity is the highest severity reached during the episode
+	Severity  AnomalySeverity `json:"severity"`
+	StartedAt time.Time       `json:"started_at"`
+	// LastSeenAt is the timestamp of the latest anomalous point
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// EndedAt is the timestamp of the first normal point (nil while open)
+	EndedAt *time.Time `json:"ended_at,omitempty"`
+	// PeakValue is the value furthest past the threshold
+	PeakValue       float64 `json:"peak_value"`
+	Threshold       float64 `json:"threshold"`
+	Points          int     `json:"points"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// Runbook points a responder at the remediation steps for an anomaly type
+type Runbook struct {
+	URL     string   `json:"url,omitempty"`
+	Actions []string `json:"actions,omitempty"`
+}
+
+// AnomalyRunbook is the runbook for stored anomalies with this detector,
+// dimension and severity (matching the point's anomaly_* fields)
+type AnomalyRunbook struct {
+	Detector  string           `json:"detector"`
+	Dimension AnomalyDimension `json:"dimension"`
+	Severity  AnomalySeverity  `json:"severity"`
+	Runbook
+}
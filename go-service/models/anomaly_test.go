@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestAnomalySeverityRank tests severity ordering and validation
+func TestAnomalySeverityRank(t *testing.T) {
+	if !(SeverityInfo.Rank() < SeverityWarning.Rank() && SeverityWarning.Rank() < SeverityCritical.Rank()) {
+		t.Error("expected info < warning < critical")
+	}
+	if AnomalySeverity("urgent").Valid() {
+		t.Error("expected unknown severity to be invalid")
+	}
+	if AnomalySeverity("").Rank() != 0 {
+		t.Error("expected empty severity to rank 0")
+	}
+}
+
+// TestTelemetryPointAnomalyClassificationJSON tests severity and dimension serialization
+func TestTelemetryPointAnomalyClassificationJSON(t *testing.T) {
+	severity := SeverityWarning
+	dimension := DimensionSignal
+	point := TelemetryPoint{
+		SatelliteID:      "SAT-0001",
+		IsAnomaly:        true,
+		AnomalySeverity:  &severity,
+		AnomalyDimension: &dimension,
+	}
+
+	data, err := json.Marshal(point)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if decoded["anomaly_severity"] != "warning" || decoded["anomaly_dimension"] != "signal" {
+		t.Errorf("unexpected classification in JSON: %s", data)
+	}
+
+	// Normal points omit the classification entirely
+	data, _ = json.Marshal(TelemetryPoint{SatelliteID: "SAT-0001"})
+	normal := map[string]interface{}{}
+	if err := json.Unmarshal(data, &normal); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if _, ok := normal["anomaly_severity"]; ok {
+		t.Errorf("expected anomaly_severity to be omitted, got %s", data)
+	}
+}
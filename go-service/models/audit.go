@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// AuditEntry records one admin operation: who called it, with which
+// parameters, and what it returned
+type AuditEntry struct {
+	Time   time.Time   `json:"time"`
+	Method string      `json:"method"`
+	Route  string      `json:"route"`
+	Path   string      `json:"path"`
+	Caller AuditCaller `json:"caller"`
+	// Params holds the path and query parameters
+	Params map[string]string `json:"params,omitempty"`
+	// Body and Result are the request and response bodies, truncated
+	Body       string  `json:"body,omitempty"`
+	Status     int     `json:"status"`
+	Result     string  `json:"result,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// AuditCaller identifies the caller of an admin operation as far as the
+// request tells
+type AuditCaller struct {
+	IP          string `json:"ip"`
+	CertSubject string `json:"cert_subject,omitempty"`
+	APIKeyID    string `json:"api_key_id,omitempty"`
+	Role        string `json:"role,omitempty"`
+	UserAgent   string `json:"user_agent,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// ConfigReload is the outcome of re-reading the configuration while running
+type ConfigReload struct {
+	ReloadedAt time.Time `json:"reloaded_at"`
+	// Changed settings (Config field names) applied to the running service,
+	// and changed settings that only take effect after a restart
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required"`
+}
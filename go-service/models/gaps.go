@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// TelemetryGap is a stretch without telemetry from a satellite, between two
+// stored points further apart than its expected cadence
+type TelemetryGap struct {
+	SatelliteID     string    `json:"satellite_id" db:"satellite_id"`
+	Start           time.Time `json:"start" db:"gap_start"`
+	End             time.Time `json:"end" db:"gap_end"`
+	DurationSeconds float64   `json:"duration_seconds" db:"duration_seconds"`
+}
+
+// TelemetryGapsResponse lists the gaps overlapping a time range
+type TelemetryGapsResponse struct {
+	SatelliteID string         `json:"satellite_id,omitempty"`
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	Count       int            `json:"count"`
+	Gaps        []TelemetryGap `json:"gaps"`
+	// GapSeconds is the time without telemetry inside the range
+	GapSeconds float64 `json:"gap_seconds"`
+	// CoveragePercent is the share of the range not in a gap; only set for
+	// a single satellite
+	CoveragePercent *float64 `json:"coverage_percent,omitempty"`
+}
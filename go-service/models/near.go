@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// NearbyPoint is a telemetry point with the great-circle distance from its
+// ground track position to the searched ground point
+type NearbyPoint struct {
+	TelemetryPoint
+	DistanceKM float64 `json:"distance_km"`
+}
+
+// TelemetryNearResponse lists the points within a radius of a ground point
+type TelemetryNearResponse struct {
+	Latitude    float64       `json:"lat"`
+	Longitude   float64       `json:"lon"`
+	RadiusKM    float64       `json:"radius_km"`
+	SatelliteID string        `json:"satellite_id,omitempty"`
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	Count       int           `json:"count"`
+	Points      []NearbyPoint `json:"points"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// PipelineStats counts the points through the ingest pipeline since start
+type PipelineStats struct {
+	Since time.Time `json:"since"`
+	// Points Add accepted into the buffer, refused (buffer full, draining,
+	// timestamp out of range, write-through journal failure) and dropped as
+	// retransmissions
+	Accepted   int64 `json:"accepted"`
+	Rejected   int64 `json:"rejected"`
+	Duplicates int64 `json:"duplicates"`
+	Anomalies  int64 `json:"anomalies"`
+	// Points committed by flushes (duplicates of stored points included) and
+	// the flushes that committed them
+	Flushed int64 `json:"flushed"`
+	Flushes int64 `json:"flushes"`
+	// Points of flushes that didn't reach the database: written to the WAL,
+	// spilled to the overflow queue, or rejected into the dead letter queue
+	WALDiverted     int64 `json:"wal_diverted"`
+	OverflowSpilled int64 `json:"overflow_spilled"`
+	DeadLettered    int64 `json:"dead_lettered"`
+	Buffered        int   `json:"buffered"`
+	// OverflowQueued is the points currently in the overflow queue, waiting
+	// to be retried
+	OverflowQueued int `json:"overflow_queued"`
+	// Throughput is the moving average of database insert throughput, points
+	// per second
+	Throughput float64    `json:"throughput_points_per_sec"`
+	LastFlush  *LastFlush `json:"last_flush,omitempty"`
+}
+
+// LastFlush describes the latest successful database insert
+type LastFlush struct {
+	Time       time.Time `json:"time"`
+	Points     int       `json:"points"`
+	DurationMS float64   `json:"duration_ms"`
+	Throughput float64   `json:"throughput_points_per_sec"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// PoolStats is the state of one database connection pool
+type PoolStats struct {
+	Name              string `json:"name"`
+	AcquiredConns     int32  `json:"acquired_conns"`
+	IdleConns         int32  `json:"idle_conns"`
+	ConstructingConns int32  `json:"constructing_conns"`
+	TotalConns        int32  `json:"total_conns"`
+	MaxConns          int32  `json:"max_conns"`
+	// Utilization is acquired over max connections; Saturated is set while
+	// every connection is acquired and new acquires have to wait
+	Utilization float64 `json:"utilization"`
+	Saturated   bool    `json:"saturated"`
+	// Acquires since start, those that found no idle connection and waited,
+	// and those canceled while waiting
+	AcquireCount         int64 `json:"acquire_count"`
+	EmptyAcquireCount    int64 `json:"empty_acquire_count"`
+	CanceledAcquireCount int64 `json:"canceled_acquire_count"`
+	// Mean acquire time and total time spent waiting for a connection since
+	// start, milliseconds
+	AcquireWaitMS      float64 `json:"acquire_wait_ms"`
+	EmptyAcquireWaitMS float64 `json:"empty_acquire_wait_ms"`
+	// The same over the last health check interval: acquires that waited
+	// and their mean wait
+	RecentEmptyAcquires int64   `json:"recent_empty_acquires"`
+	RecentAcquireWaitMS float64 `json:"recent_acquire_wait_ms"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// FieldRate counts points with a given field problem and their share of all
+// points from a station
+type FieldRate struct {
+	Count int64   `json:"count"`
+	Rate  float64 `json:"rate"`
+}
+
+// ClockSkewBucket counts points whose receive-time skew is at most LE
+// ("future" counts timestamps ahead of the server clock)
+type ClockSkewBucket struct {
+	LE    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+// ClockSkewStats summarizes how far point timestamps lag the server clock,
+// over points that carried a timestamp
+type ClockSkewStats struct {
+	Samples     int64             `json:"samples"`
+	MeanSeconds float64           `json:"mean_seconds"`
+	MinSeconds  float64           `json:"min_seconds"`
+	MaxSeconds  float64           `json:"max_seconds"`
+	Buckets     []ClockSkewBucket `json:"buckets"`
+}
+
+// DataQualityReport is the validation scorecard of one ground station
+type DataQualityReport struct {
+	Station  string    `json:"station"`
+	Points   int64     `json:"points"`
+	LastSeen time.Time `json:"last_seen"`
+	// ValidRate is the share of points with every field in range
+	ValidRate  float64              `json:"valid_rate"`
+	OutOfRange map[string]FieldRate `json:"out_of_range"`
+	Missing    map[string]FieldRate `json:"missing"`
+	ClockSkew  ClockSkewStats       `json:"clock_skew"`
+}
+
+// DataQualityResponse is returned by the data quality endpoint
+type DataQualityResponse struct {
+	Count    int                 `json:"count"`
+	Stations []DataQualityReport `json:"stations"`
+}
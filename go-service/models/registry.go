@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// RegistryAttributes are the satellite registry attributes in force at a
+// telemetry point's timestamp, from the versioned registry history
+type RegistryAttributes struct {
+	ValidFrom       time.Time  `json:"valid_from"`
+	ValidTo         *time.Time `json:"valid_to,omitempty"`
+	Constellation   *string    `json:"constellation,omitempty"`
+	SoftwareVersion *string    `json:"software_version,omitempty"`
+	// Operating thresholds configured for the period
+	BatteryMinPercent *float64 `json:"battery_min_percent,omitempty"`
+	StorageMaxMB      *float64 `json:"storage_max_mb,omitempty"`
+	SignalMinDBM      *float64 `json:"signal_min_dbm,omitempty"`
+}
+
+// TelemetryPointAsOf is a telemetry point returned by a read, with the
+// registry attributes in force at its timestamp when they were requested
+type TelemetryPointAsOf struct {
+	TelemetryPoint
+	Registry *RegistryAttributes `json:"registry,omitempty"`
+}
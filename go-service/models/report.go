@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// FleetReportDay is one day of fleet-wide ingest and anomaly volume
+type FleetReportDay struct {
+	Day        time.Time `json:"day"`
+	DataPoints int64     `json:"data_points"`
+	Anomalies  int64     `json:"anomalies"`
+	Satellites int       `json:"satellites"`
+}
+
+// FleetReportSatellite is a satellite ranked by anomalies over the period
+type FleetReportSatellite struct {
+	SatelliteID string   `json:"satellite_id"`
+	Anomalies   int64    `json:"anomalies"`
+	DataPoints  int64    `json:"data_points"`
+	MinBattery  *float64 `json:"min_battery,omitempty"`
+}
+
+// FleetReportIssue is an anomaly dimension and severity ranked by occurrences
+type FleetReportIssue struct {
+	Dimension  string `json:"dimension"`
+	Severity   string `json:"severity"`
+	Count      int64  `json:"count"`
+	Satellites int    `json:"satellites"`
+}
+
+// FleetReport is the periodic fleet health summary for management
+type FleetReport struct {
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Satellites  int       `json:"satellites"`
+	DataPoints  int64     `json:"data_points"`
+	Anomalies   int64     `json:"anomalies"`
+	// Totals of the period before, for week over week trends
+	PreviousDataPoints int64                  `json:"previous_data_points"`
+	PreviousAnomalies  int64                  `json:"previous_anomalies"`
+	Days               []FleetReportDay       `json:"days"`
+	TopSatellites      []FleetReportSatellite `json:"top_satellites"`
+	TopIssues          []FleetReportIssue     `json:"top_issues"`
+	// Stations is the data quality scorecard of every ground station since
+	// the process started
+	Stations []DataQualityReport `json:"stations"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// StatsBucket is one time bucket read from a continuous aggregate
+// Min/max and anomaly fields are only populated by the hourly and daily views
+type StatsBucket struct {
+	SatelliteID     string    `json:"satellite_id"`
+	Bucket          time.Time `json:"bucket"`
+	AvgBattery      float64   `json:"avg_battery"`
+	MinBattery      *float64  `json:"min_battery,omitempty"`
+	MaxBattery      *float64  `json:"max_battery,omitempty"`
+	AvgStorage      float64   `json:"avg_storage"`
+	AvgSignal       float64   `json:"avg_signal"`
+	DataPoints      int64     `json:"data_points"`
+	AnomalyCount    *int64    `json:"anomaly_count,omitempty"`
+	AvgAltitudeKM   *float64  `json:"avg_altitude_km,omitempty"`
+	MinAltitudeKM   *float64  `json:"min_altitude_km,omitempty"`
+	MaxAltitudeKM   *float64  `json:"max_altitude_km,omitempty"`
+	AvgVelocityKMPH *float64  `json:"avg_velocity_kmph,omitempty"`
+	MinVelocityKMPH *float64  `json:"min_velocity_kmph,omitempty"`
+	MaxVelocityKMPH *float64  `json:"max_velocity_kmph,omitempty"`
+	// Crude apogee and perigee altitudes from the bucket's altitude range
+	// and vis-viva semi-major axis
+	ApogeeKM  *float64 `json:"apogee_km,omitempty"`
+	PerigeeKM *float64 `json:"perigee_km,omitempty"`
+}
+
+// VersionStatsBucket is one hourly bucket of metrics for a single software version
+type VersionStatsBucket struct {
+	SatelliteID     string    `json:"satellite_id"`
+	SoftwareVersion string    `json:"software_version"`
+	Bucket          time.Time `json:"bucket"`
+	AvgBattery      float64   `json:"avg_battery"`
+	AvgStorage      float64   `json:"avg_storage"`
+	AvgSignal       float64   `json:"avg_signal"`
+	DataPoints      int64     `json:"data_points"`
+	AnomalyCount    int64     `json:"anomaly_count"`
+}
+
+// TelemetryQueryResponse is returned by the raw telemetry read endpoint
+type TelemetryQueryResponse struct {
+	SatelliteID string               `json:"satellite_id"`
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	Count       int                  `json:"count"`
+	Points      []TelemetryPointAsOf `json:"points"`
+	// Runbooks for the anomalies among Points, when configured
+	Runbooks []AnomalyRunbook `json:"runbooks,omitempty"`
+}
+
+// StatsResponse is returned by the aggregate stats endpoint
+// DataThrough is how far the aggregate has been refreshed (null while it is
+// empty); buckets after it are missing, not empty
+type StatsResponse struct {
+	SatelliteID string        `json:"satellite_id"`
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	Resolution  string        `json:"resolution"`
+	TimeZone    string        `json:"time_zone"`
+	DataThrough *time.Time    `json:"data_through"`
+	Count       int           `json:"count"`
+	Buckets     []StatsBucket `json:"buckets"`
+}
+
+// VersionStatsResponse is returned by the per-software-version stats endpoint
+type VersionStatsResponse struct {
+	SatelliteID string               `json:"satellite_id"`
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	DataThrough *time.Time           `json:"data_through"`
+	Count       int                  `json:"count"`
+	Buckets     []VersionStatsBucket `json:"buckets"`
+}
@@ -14,6 +14,28 @@ type TelemetryPoint struct {
 	Longitude            *float64  `json:"longitude,omitempty" db:"longitude"`
 	AltitudeKM           *float64  `json:"altitude_km,omitempty" db:"altitude_km"`
 	VelocityKMPH         *float64  `json:"velocity_kmph,omitempty" db:"velocity_kmph"`
+	// Flight software version running when the point was generated (optional)
+	SoftwareVersion      *string   `json:"software_version,omitempty" db:"software_version"`
+	// Comma-separated names of the anomaly detectors that flagged the point
+	AnomalyDetector      *string   `json:"anomaly_detector,omitempty" db:"anomaly_detector"`
+	// Classification of the most severe finding (nil when not anomalous)
+	AnomalySeverity      *AnomalySeverity  `json:"anomaly_severity,omitempty" db:"anomaly_severity"`
+	AnomalyDimension     *AnomalyDimension `json:"anomaly_dimension,omitempty" db:"anomaly_dimension"`
+	// Set when the point arrived after its aggregate windows were refreshed
+	IsLate               bool              `json:"is_late,omitempty" db:"is_late"`
+	// Set when the timestamp guard clamped an out-of-range timestamp
+	TimestampClamped     bool              `json:"timestamp_clamped,omitempty" db:"timestamp_clamped"`
+	// Comma-separated validation problems of a point accepted in lenient
+	// validation mode, e.g. "battery_charge_percent:out_of_range"
+	ValidationIssues     *string           `json:"validation_issues,omitempty" db:"validation_issues"`
+	// Set when the position detector found an impossible jump from the
+	// satellite's previous position
+	PositionAnomaly      bool              `json:"position_anomaly,omitempty" db:"position_anomaly"`
+	// Comma-separated names of the geofence zones the position was in
+	GeofenceZones        *string           `json:"geofence_zones,omitempty" db:"geofence_zones"`
+	// Distance between the reported position and the one the satellite's
+	// registered TLE predicts (nil without a usable TLE)
+	PositionResidualKM   *float64          `json:"position_residual_km,omitempty" db:"position_residual_km"`
 }
 
 type HealthResponse struct {
@@ -24,10 +46,71 @@ type HealthResponse struct {
 	WALRecordCount int    `json:"wal_record_count,omitempty"`
 	BufferSize     int    `json:"buffer_size,omitempty"`
 	CircuitBreaker string `json:"circuit_breaker,omitempty"`
+	// CircuitBreakerSince is when the circuit breaker entered its state
+	CircuitBreakerSince string `json:"circuit_breaker_since,omitempty"`
+	// CircuitBreakers is the state of each operation's breaker (insert,
+	// aggregate_refresh, query); CircuitBreaker is the insert breaker
+	CircuitBreakers map[string]string `json:"circuit_breakers,omitempty"`
+	// DatabaseCheckedAt is when the reported database status was last probed;
+	// DatabaseCheckAgeMS is its age when served from the health cache
+	DatabaseCheckedAt  string `json:"database_checked_at,omitempty"`
+	DatabaseCheckAgeMS int64  `json:"database_check_age_ms,omitempty"`
+	// Pipeline counts points accepted, flushed and diverted since start
+	Pipeline *PipelineStats `json:"pipeline,omitempty"`
+	// IngestMode is "wal_only" while the health monitor finds the database
+	// unreachable
+	IngestMode string `json:"ingest_mode,omitempty"`
+	// Pools is the connection usage of each database pool
+	Pools []PoolStats `json:"pools,omitempty"`
+}
+
+// ReadinessResponse is the /readyz result; the instance is ready only when
+// every check passes
+type ReadinessResponse struct {
+	Status    string                    `json:"status"`
+	Timestamp string                    `json:"timestamp"`
+	Checks    map[string]ReadinessCheck `json:"checks"`
+}
+
+// ReadinessCheck is the outcome of one readiness condition
+type ReadinessCheck struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
 }
 
 type TelemetryResponse struct {
+	// Status is "accepted", or for batches "partial" when some points were
+	// rejected and "rejected" when none was accepted
 	Status      string `json:"status"`
 	SatelliteID string `json:"satellite_id,omitempty"`
-	Count       int    `json:"count,omitempty"`
+	// Count is the number of points accepted
+	Count int `json:"count,omitempty"`
+	// Received is the number of points in a batch request
+	Received int `json:"received,omitempty"`
+	// Mode is "wal_only" when accepted points are journaled to disk until
+	// the database is back
+	Mode string `json:"mode,omitempty"`
+	// Rejected lists the points of a batch that were not accepted, and
+	// RejectedCounts counts them by reason
+	Rejected       []RejectedPoint `json:"rejected,omitempty"`
+	RejectedCounts map[string]int  `json:"rejected_counts,omitempty"`
+}
+
+// Reasons a point of a batch is rejected
+const (
+	RejectReasonForbidden  = "forbidden"
+	RejectReasonValidation = "validation"
+	RejectReasonTimestamp  = "timestamp_out_of_range"
+	RejectReasonBufferFull = "buffer_full"
+	RejectReasonDraining   = "draining"
+	RejectReasonError      = "error"
+)
+
+// RejectedPoint is a point of a batch request that was not accepted
+type RejectedPoint struct {
+	// Index is the point's position in the request
+	Index       int    `json:"index"`
+	SatelliteID string `json:"satellite_id"`
+	Reason      string `json:"reason"`
+	Error       string `json:"error"`
 }
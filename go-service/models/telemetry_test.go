@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -586,3 +587,26 @@ func TestTelemetryPointPositionZeroValues(t *testing.T) {
 		t.Errorf("expected VelocityKMPH 0.0, got %v", point.VelocityKMPH)
 	}
 }
+
+// TestTelemetryPointSoftwareVersion tests the optional software version field
+func TestTelemetryPointSoftwareVersion(t *testing.T) {
+	jsonData := `{"satellite_id": "SAT-0001", "battery_charge_percent": 80, "software_version": "2.4.1"}`
+
+	var point TelemetryPoint
+	if err := json.Unmarshal([]byte(jsonData), &point); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if point.SoftwareVersion == nil || *point.SoftwareVersion != "2.4.1" {
+		t.Errorf("expected software_version 2.4.1, got %v", point.SoftwareVersion)
+	}
+
+	// Omitted when not set
+	point.SoftwareVersion = nil
+	data, err := json.Marshal(point)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if strings.Contains(string(data), "software_version") {
+		t.Errorf("expected software_version to be omitted, got %s", data)
+	}
+}
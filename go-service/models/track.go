@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// TrackPoint is one reported position of a satellite
+type TrackPoint struct {
+	Timestamp  time.Time `json:"timestamp" db:"time"`
+	Latitude   float64   `json:"latitude" db:"latitude"`
+	Longitude  float64   `json:"longitude" db:"longitude"`
+	AltitudeKM *float64  `json:"altitude_km,omitempty" db:"altitude_km"`
+}
+
+// GeoJSONGeometry is a GeoJSON geometry; Coordinates holds positions
+// ([lon, lat] or [lon, lat, altitude in meters]) for MultiPoint and
+// LineString, and lists of them for MultiLineString
+type GeoJSONGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// GeoJSONFeature is a GeoJSON feature; Geometry is nil when there is
+// nothing to draw
+type GeoJSONFeature struct {
+	Type       string           `json:"type"`
+	Geometry   *GeoJSONGeometry `json:"geometry"`
+	Properties map[string]any   `json:"properties"`
+}
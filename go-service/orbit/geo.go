@@ -0,0 +1,93 @@
+package orbit
+
+import (
+	"math"
+	"time"
+)
+
+// WGS-84 ellipsoid, which reported latitudes and altitudes refer to
+const (
+	wgs84A  = 6378.137
+	wgs84F  = 1 / 298.257223563
+	wgs84E2 = wgs84F * (2 - wgs84F)
+)
+
+// Geodetic is a WGS-84 latitude and longitude in degrees and an altitude
+// above the ellipsoid in kilometers
+type Geodetic struct {
+	Latitude   float64
+	Longitude  float64
+	AltitudeKM float64
+}
+
+// ECEF returns the position in Earth-centered, Earth-fixed coordinates
+func (g Geodetic) ECEF() Vector {
+	lat := g.Latitude * deg2rad
+	lon := g.Longitude * deg2rad
+	sinLat := math.Sin(lat)
+	n := wgs84A / math.Sqrt(1-wgs84E2*sinLat*sinLat)
+	return Vector{
+		X: (n + g.AltitudeKM) * math.Cos(lat) * math.Cos(lon),
+		Y: (n + g.AltitudeKM) * math.Cos(lat) * math.Sin(lon),
+		Z: (n*(1-wgs84E2) + g.AltitudeKM) * sinLat,
+	}
+}
+
+// Distance returns the straight-line distance between two positions in
+// kilometers
+func Distance(a, b Geodetic) float64 {
+	pa, pb := a.ECEF(), b.ECEF()
+	return math.Sqrt((pa.X-pb.X)*(pa.X-pb.X) + (pa.Y-pb.Y)*(pa.Y-pb.Y) + (pa.Z-pb.Z)*(pa.Z-pb.Z))
+}
+
+// ToGeodetic converts an Earth-fixed position to latitude, longitude and
+// altitude, iterating on the latitude
+func ToGeodetic(v Vector) Geodetic {
+	lon := math.Atan2(v.Y, v.X)
+	r := math.Hypot(v.X, v.Y)
+	lat := math.Atan2(v.Z, r*(1-wgs84E2))
+	var n float64
+	for i := 0; i < 10; i++ {
+		sinLat := math.Sin(lat)
+		n = wgs84A / math.Sqrt(1-wgs84E2*sinLat*sinLat)
+		next := math.Atan2(v.Z+n*wgs84E2*sinLat, r)
+		done := math.Abs(next-lat) < 1e-12
+		lat = next
+		if done {
+			break
+		}
+	}
+	var alt float64
+	if math.Abs(math.Cos(lat)) > 1e-9 {
+		alt = r/math.Cos(lat) - n
+	} else {
+		alt = math.Abs(v.Z) - n*(1-wgs84E2)
+	}
+	return Geodetic{Latitude: lat / deg2rad, Longitude: lon / deg2rad, AltitudeKM: alt}
+}
+
+// TEMEToECEF rotates a TEME position into the Earth-fixed frame by the
+// Greenwich mean sidereal time; polar motion (< 20 m) is ignored
+func TEMEToECEF(v Vector, at time.Time) Vector {
+	gmst := GMST(at)
+	sin, cos := math.Sin(gmst), math.Cos(gmst)
+	return Vector{
+		X: cos*v.X + sin*v.Y,
+		Y: -sin*v.X + cos*v.Y,
+		Z: v.Z,
+	}
+}
+
+// GMST returns the Greenwich mean sidereal time in radians (IAU 1982),
+// treating UTC as UT1
+func GMST(at time.Time) float64 {
+	jd := float64(at.UnixNano())/float64(24*time.Hour) + 2440587.5
+	tut1 := (jd - 2451545) / 36525
+	seconds := -6.2e-6*tut1*tut1*tut1 + 0.093104*tut1*tut1 +
+		(876600*3600+8640184.812866)*tut1 + 67310.54841
+	gmst := math.Mod(seconds*deg2rad/240, twoPi)
+	if gmst < 0 {
+		gmst += twoPi
+	}
+	return gmst
+}
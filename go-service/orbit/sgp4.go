@@ -0,0 +1,300 @@
+package orbit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// WGS-72 constants, which the TLE mean elements are fitted with
+const (
+	earthRadiusKM = 6378.135
+	muKM3S2       = 398600.8
+	j2            = 0.001082616
+	j3            = -0.00000253881
+	j4            = -0.00000165597
+	j3oj2         = j3 / j2
+	twoPi         = 2 * math.Pi
+	minutesPerDay = 1440.0
+	deg2rad       = math.Pi / 180
+)
+
+// xke is sqrt(GM) in earth radii^1.5 per minute
+var xke = 60 / math.Sqrt(earthRadiusKM*earthRadiusKM*earthRadiusKM/muKM3S2)
+
+// ErrDeepSpace is returned for orbits with periods of 225 minutes or more,
+// which need the SDP4 lunar/solar perturbations this package doesn't model
+var ErrDeepSpace = errors.New("deep-space orbits (period >= 225 min) are not supported")
+
+// ErrDecayed is returned when the propagated orbit is below the Earth's
+// surface or no longer elliptic
+var ErrDecayed = errors.New("orbit has decayed")
+
+// Propagator computes positions from one TLE with the near-earth SGP4
+// model (Hoots & Roehrich, with the Vallado 2006 corrections)
+type Propagator struct {
+	tle TLE
+
+	// elements at epoch (radians, radians per minute)
+	ecco, inclo, nodeo, argpo, mo, bstar, no float64
+
+	// isimp is set for perigees below 220 km, which drop the higher order
+	// drag terms
+	isimp bool
+
+	aycof, con41, cc1, cc4, cc5, d2, d3, d4, delmo, eta, argpdot, omgcof,
+	sinmao, t2cof, t3cof, t4cof, t5cof, x1mth2, x7thm1, mdot, nodedot,
+	xlcof, xmcof, nodecf float64
+}
+
+// NewPropagator initializes SGP4 for a TLE
+func NewPropagator(tle TLE) (*Propagator, error) {
+	p := &Propagator{
+		tle:   tle,
+		ecco:  tle.Eccentricity,
+		inclo: tle.InclinationDeg * deg2rad,
+		nodeo: tle.RAANDeg * deg2rad,
+		argpo: tle.ArgPerigeeDeg * deg2rad,
+		mo:    tle.MeanAnomalyDeg * deg2rad,
+		bstar: tle.BStar,
+	}
+	if p.ecco < 0 || p.ecco >= 1 {
+		return nil, fmt.Errorf("eccentricity %g out of range", p.ecco)
+	}
+	noKozai := tle.MeanMotionRevDay * twoPi / minutesPerDay
+
+	// Recover the original mean motion and semi-major axis from the Kozai
+	// mean motion in the TLE
+	const x2o3 = 2.0 / 3.0
+	eccsq := p.ecco * p.ecco
+	omeosq := 1 - eccsq
+	rteosq := math.Sqrt(omeosq)
+	cosio := math.Cos(p.inclo)
+	cosio2 := cosio * cosio
+	ak := math.Pow(xke/noKozai, x2o3)
+	d1 := 0.75 * j2 * (3*cosio2 - 1) / (rteosq * omeosq)
+	del := d1 / (ak * ak)
+	adel := ak * (1 - del*del - del*(1.0/3.0+134*del*del/81))
+	del = d1 / (adel * adel)
+	p.no = noKozai / (1 + del)
+	if twoPi/p.no >= 225 {
+		return nil, ErrDeepSpace
+	}
+
+	ao := math.Pow(xke/p.no, x2o3)
+	sinio := math.Sin(p.inclo)
+	po := ao * omeosq
+	con42 := 1 - 5*cosio2
+	p.con41 = -con42 - cosio2 - cosio2
+	posq := po * po
+	rp := ao * (1 - p.ecco)
+	if rp < 1 {
+		return nil, ErrDecayed
+	}
+	p.isimp = rp < 220/earthRadiusKM+1
+
+	// Atmospheric density parameters, adjusted for low perigees
+	sfour := 78/earthRadiusKM + 1
+	qzms24 := math.Pow((120-78)/earthRadiusKM, 4)
+	perige := (rp - 1) * earthRadiusKM
+	if perige < 156 {
+		sfour = perige - 78
+		if perige < 98 {
+			sfour = 20
+		}
+		qzms24 = math.Pow((120-sfour)/earthRadiusKM, 4)
+		sfour = sfour/earthRadiusKM + 1
+	}
+
+	pinvsq := 1 / posq
+	tsi := 1 / (ao - sfour)
+	p.eta = ao * p.ecco * tsi
+	etasq := p.eta * p.eta
+	eeta := p.ecco * p.eta
+	psisq := math.Abs(1 - etasq)
+	coef := qzms24 * math.Pow(tsi, 4)
+	coef1 := coef / math.Pow(psisq, 3.5)
+	cc2 := coef1 * p.no * (ao*(1+1.5*etasq+eeta*(4+etasq)) +
+		0.375*j2*tsi/psisq*p.con41*(8+3*etasq*(8+etasq)))
+	p.cc1 = p.bstar * cc2
+	cc3 := 0.0
+	if p.ecco > 1e-4 {
+		cc3 = -2 * coef * tsi * j3oj2 * p.no * sinio / p.ecco
+	}
+	p.x1mth2 = 1 - cosio2
+	p.cc4 = 2 * p.no * coef1 * ao * omeosq * (p.eta*(2+0.5*etasq) + p.ecco*(0.5+2*etasq) -
+		j2*tsi/(ao*psisq)*(-3*p.con41*(1-2*eeta+etasq*(1.5-0.5*eeta))+
+			0.75*p.x1mth2*(2*etasq-eeta*(1+etasq))*math.Cos(2*p.argpo)))
+	p.cc5 = 2 * coef1 * ao * omeosq * (1 + 2.75*(etasq+eeta) + eeta*etasq)
+
+	// Secular rates of the mean anomaly, argument of perigee and node
+	cosio4 := cosio2 * cosio2
+	temp1 := 1.5 * j2 * pinvsq * p.no
+	temp2 := 0.5 * temp1 * j2 * pinvsq
+	temp3 := -0.46875 * j4 * pinvsq * pinvsq * p.no
+	p.mdot = p.no + 0.5*temp1*rteosq*p.con41 + 0.0625*temp2*rteosq*(13-78*cosio2+137*cosio4)
+	p.argpdot = -0.5*temp1*con42 + 0.0625*temp2*(7-114*cosio2+395*cosio4) +
+		temp3*(3-36*cosio2+49*cosio4)
+	xhdot1 := -temp1 * cosio
+	p.nodedot = xhdot1 + (0.5*temp2*(4-19*cosio2)+2*temp3*(3-7*cosio2))*cosio
+	p.omgcof = p.bstar * cc3 * math.Cos(p.argpo)
+	if p.ecco > 1e-4 {
+		p.xmcof = -x2o3 * coef * p.bstar / eeta
+	}
+	p.nodecf = 3.5 * omeosq * xhdot1 * p.cc1
+	p.t2cof = 1.5 * p.cc1
+	// Avoid dividing by zero for inclinations of 180 degrees
+	if math.Abs(cosio+1) > 1.5e-12 {
+		p.xlcof = -0.25 * j3oj2 * sinio * (3 + 5*cosio) / (1 + cosio)
+	} else {
+		p.xlcof = -0.25 * j3oj2 * sinio * (3 + 5*cosio) / 1.5e-12
+	}
+	p.aycof = -0.5 * j3oj2 * sinio
+	p.delmo = math.Pow(1+p.eta*math.Cos(p.mo), 3)
+	p.sinmao = math.Sin(p.mo)
+	p.x7thm1 = 7*cosio2 - 1
+
+	if !p.isimp {
+		cc1sq := p.cc1 * p.cc1
+		p.d2 = 4 * ao * tsi * cc1sq
+		temp := p.d2 * tsi * p.cc1 / 3
+		p.d3 = (17*ao + sfour) * temp
+		p.d4 = 0.5 * temp * ao * tsi * (221*ao + 31*sfour) * p.cc1
+		p.t3cof = p.d2 + 2*cc1sq
+		p.t4cof = 0.25 * (3*p.d3 + p.cc1*(12*p.d2+10*cc1sq))
+		p.t5cof = 0.2 * (3*p.d4 + 12*p.cc1*p.d3 + 6*p.d2*p.d2 + 15*cc1sq*(2*p.d2+cc1sq))
+	}
+	return p, nil
+}
+
+// TLE returns the element set the propagator was created from
+func (p *Propagator) TLE() TLE {
+	return p.tle
+}
+
+// Vector is a position in kilometers
+type Vector struct {
+	X, Y, Z float64
+}
+
+// propagate returns the position in the TEME frame (true equator, mean
+// equinox of date) a number of minutes after the TLE epoch
+func (p *Propagator) propagate(tsince float64) (Vector, error) {
+	const x2o3 = 2.0 / 3.0
+
+	// Secular gravity and drag
+	xmdf := p.mo + p.mdot*tsince
+	argpdf := p.argpo + p.argpdot*tsince
+	nodedf := p.nodeo + p.nodedot*tsince
+	argpm := argpdf
+	mm := xmdf
+	t2 := tsince * tsince
+	nodem := nodedf + p.nodecf*t2
+	tempa := 1 - p.cc1*tsince
+	tempe := p.bstar * p.cc4 * tsince
+	templ := p.t2cof * t2
+
+	if !p.isimp {
+		delomg := p.omgcof * tsince
+		delm := p.xmcof * (math.Pow(1+p.eta*math.Cos(xmdf), 3) - p.delmo)
+		temp := delomg + delm
+		mm = xmdf + temp
+		argpm = argpdf - temp
+		t3 := t2 * tsince
+		t4 := t3 * tsince
+		tempa = tempa - p.d2*t2 - p.d3*t3 - p.d4*t4
+		tempe = tempe + p.bstar*p.cc5*(math.Sin(mm)-p.sinmao)
+		templ = templ + p.t3cof*t3 + t4*(p.t4cof+tsince*p.t5cof)
+	}
+
+	am := math.Pow(xke/p.no, x2o3) * tempa * tempa
+	if am <= 0 {
+		return Vector{}, ErrDecayed
+	}
+	em := p.ecco - tempe
+	if em >= 1 || em < -0.001 {
+		return Vector{}, ErrDecayed
+	}
+	if em < 1e-6 {
+		em = 1e-6
+	}
+	mm += p.no * templ
+	xlm := mm + argpm + nodem
+	nodem = math.Mod(nodem, twoPi)
+	argpm = math.Mod(argpm, twoPi)
+	xlm = math.Mod(xlm, twoPi)
+	mm = math.Mod(xlm-argpm-nodem, twoPi)
+
+	// Long period periodics
+	sinip := math.Sin(p.inclo)
+	cosip := math.Cos(p.inclo)
+	axnl := em * math.Cos(argpm)
+	temp := 1 / (am * (1 - em*em))
+	aynl := em*math.Sin(argpm) + temp*p.aycof
+	xl := mm + argpm + nodem + temp*p.xlcof*axnl
+
+	// Solve Kepler's equation
+	u := math.Mod(xl-nodem, twoPi)
+	eo1 := u
+	var sineo1, coseo1 float64
+	for i, tem5 := 0, 1.0; math.Abs(tem5) >= 1e-12 && i < 10; i++ {
+		sineo1 = math.Sin(eo1)
+		coseo1 = math.Cos(eo1)
+		tem5 = (u - aynl*coseo1 + axnl*sineo1 - eo1) / (1 - coseo1*axnl - sineo1*aynl)
+		if math.Abs(tem5) >= 0.95 {
+			tem5 = math.Copysign(0.95, tem5)
+		}
+		eo1 += tem5
+	}
+
+	// Short period preliminary quantities
+	ecose := axnl*coseo1 + aynl*sineo1
+	esine := axnl*sineo1 - aynl*coseo1
+	el2 := axnl*axnl + aynl*aynl
+	pl := am * (1 - el2)
+	if pl < 0 {
+		return Vector{}, ErrDecayed
+	}
+	rl := am * (1 - ecose)
+	betal := math.Sqrt(1 - el2)
+	temp = esine / (1 + betal)
+	sinu := am / rl * (sineo1 - aynl - axnl*temp)
+	cosu := am / rl * (coseo1 - axnl + aynl*temp)
+	su := math.Atan2(sinu, cosu)
+	sin2u := (cosu + cosu) * sinu
+	cos2u := 1 - 2*sinu*sinu
+	temp = 1 / pl
+	temp1 := 0.5 * j2 * temp
+	temp2 := temp1 * temp
+
+	// Short period periodics
+	mrt := rl*(1-1.5*temp2*betal*p.con41) + 0.5*temp1*p.x1mth2*cos2u
+	if mrt < 1 {
+		return Vector{}, ErrDecayed
+	}
+	su -= 0.25 * temp2 * p.x7thm1 * sin2u
+	xnode := nodem + 1.5*temp2*cosip*sin2u
+	xinc := p.inclo + 1.5*temp2*cosip*sinip*cos2u
+
+	// Orientation vectors
+	sinsu, cossu := math.Sin(su), math.Cos(su)
+	snod, cnod := math.Sin(xnode), math.Cos(xnode)
+	sini, cosi := math.Sin(xinc), math.Cos(xinc)
+	xmx := -snod * cosi
+	xmy := cnod * cosi
+	return Vector{
+		X: mrt * (xmx*sinsu + cnod*cossu) * earthRadiusKM,
+		Y: mrt * (xmy*sinsu + snod*cossu) * earthRadiusKM,
+		Z: mrt * (sini * sinsu) * earthRadiusKM,
+	}, nil
+}
+
+// Position returns the predicted geodetic position at a time
+func (p *Propagator) Position(at time.Time) (Geodetic, error) {
+	teme, err := p.propagate(at.Sub(p.tle.Epoch).Minutes())
+	if err != nil {
+		return Geodetic{}, err
+	}
+	return ToGeodetic(TEMEToECEF(teme, at)), nil
+}
@@ -0,0 +1,106 @@
+package orbit
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// Vallado's SGP4 verification case 00005, a near-earth orbit with e = 0.186
+const (
+	testLine1 = "1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753"
+	testLine2 = "2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667"
+)
+
+func TestParseTLE(t *testing.T) {
+	tle, err := ParseTLE(testLine1, testLine2)
+	if err != nil {
+		t.Fatalf("ParseTLE: %v", err)
+	}
+	if tle.CatalogNumber != "00005" {
+		t.Errorf("catalog number = %q", tle.CatalogNumber)
+	}
+	wantEpoch := time.Date(2000, time.June, 27, 18, 50, 19, 733567000, time.UTC)
+	if d := tle.Epoch.Sub(wantEpoch); d > time.Millisecond || d < -time.Millisecond {
+		t.Errorf("epoch = %v, want %v", tle.Epoch, wantEpoch)
+	}
+	if math.Abs(tle.BStar-0.28098e-4) > 1e-12 {
+		t.Errorf("bstar = %g", tle.BStar)
+	}
+	if math.Abs(tle.Eccentricity-0.1859667) > 1e-12 {
+		t.Errorf("eccentricity = %g", tle.Eccentricity)
+	}
+
+	corrupted := testLine2[:10] + "9" + testLine2[11:]
+	if _, err := ParseTLE(testLine1, corrupted); err == nil {
+		t.Error("expected a checksum error")
+	}
+	if _, err := ParseTLE(testLine1, "2 00005"); err == nil {
+		t.Error("expected an error for a short line")
+	}
+}
+
+func TestPropagateMatchesReference(t *testing.T) {
+	tle, err := ParseTLE(testLine1, testLine2)
+	if err != nil {
+		t.Fatalf("ParseTLE: %v", err)
+	}
+	p, err := NewPropagator(tle)
+	if err != nil {
+		t.Fatalf("NewPropagator: %v", err)
+	}
+
+	// TEME positions from the reference implementation's output
+	cases := []struct {
+		minutes float64
+		want    Vector
+	}{
+		{0, Vector{7022.46529266, -1400.08296755, 0.03995155}},
+		{360, Vector{-7154.03120202, -3783.17682504, -3536.19412294}},
+		{720, Vector{-7134.59340119, 6531.68641334, 3260.27186483}},
+	}
+	for _, tc := range cases {
+		got, err := p.propagate(tc.minutes)
+		if err != nil {
+			t.Fatalf("t+%v min: %v", tc.minutes, err)
+		}
+		off := math.Sqrt((got.X-tc.want.X)*(got.X-tc.want.X) + (got.Y-tc.want.Y)*(got.Y-tc.want.Y) + (got.Z-tc.want.Z)*(got.Z-tc.want.Z))
+		if off > 0.001 {
+			t.Errorf("t+%v min: got %+v, want %+v (%.6f km off)", tc.minutes, got, tc.want, off)
+		}
+	}
+}
+
+func TestDeepSpaceRejected(t *testing.T) {
+	// A geostationary orbit (1 rev/day)
+	tle := TLE{InclinationDeg: 0.0165, Eccentricity: 0.0001959, MeanMotionRevDay: 1.0027234}
+	if _, err := NewPropagator(tle); !errors.Is(err, ErrDeepSpace) {
+		t.Errorf("expected ErrDeepSpace, got %v", err)
+	}
+}
+
+func TestGMST(t *testing.T) {
+	// 280.46061837 degrees at the J2000 epoch
+	got := GMST(time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC)) / deg2rad
+	if math.Abs(got-280.46061837) > 1e-6 {
+		t.Errorf("GMST = %.8f deg", got)
+	}
+}
+
+func TestGeodeticRoundTrip(t *testing.T) {
+	for _, g := range []Geodetic{
+		{Latitude: 0, Longitude: 0, AltitudeKM: 0},
+		{Latitude: 51.6, Longitude: -120.25, AltitudeKM: 420},
+		{Latitude: -89.9, Longitude: 179.9, AltitudeKM: 800},
+	} {
+		back := ToGeodetic(g.ECEF())
+		if math.Abs(back.Latitude-g.Latitude) > 1e-9 || math.Abs(back.Longitude-g.Longitude) > 1e-9 ||
+			math.Abs(back.AltitudeKM-g.AltitudeKM) > 1e-6 {
+			t.Errorf("round trip of %+v gave %+v", g, back)
+		}
+	}
+	if d := Distance(Geodetic{AltitudeKM: 400}, Geodetic{AltitudeKM: 410}); math.Abs(d-10) > 1e-9 {
+		t.Errorf("Distance = %v, want 10", d)
+	}
+}
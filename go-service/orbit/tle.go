@@ -0,0 +1,130 @@
+// Package orbit propagates satellite orbits from two-line element sets
+// (TLEs) with the SGP4 model, to compare reported positions with the
+// positions the published orbit predicts
+package orbit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLE holds the mean orbital elements of a two-line element set
+type TLE struct {
+	CatalogNumber string
+	Epoch         time.Time
+	// BStar is the drag term (1/earth radii)
+	BStar float64
+	// Angles in degrees
+	InclinationDeg   float64
+	RAANDeg          float64
+	ArgPerigeeDeg    float64
+	MeanAnomalyDeg   float64
+	Eccentricity     float64
+	MeanMotionRevDay float64
+}
+
+// ParseTLE parses the two lines of an element set, verifying their
+// checksums
+func ParseTLE(line1, line2 string) (TLE, error) {
+	line1 = strings.TrimRight(line1, " \r\n")
+	line2 = strings.TrimRight(line2, " \r\n")
+	if len(line1) < 69 || len(line2) < 69 || line1[0] != '1' || line2[0] != '2' {
+		return TLE{}, fmt.Errorf("expected two 69-character lines starting with 1 and 2")
+	}
+	for i, line := range []string{line1, line2} {
+		if !validChecksum(line) {
+			return TLE{}, fmt.Errorf("line %d: checksum mismatch", i+1)
+		}
+	}
+
+	var tle TLE
+	var err error
+	field := func(line string, from, to int) string {
+		return strings.TrimSpace(line[from-1 : to])
+	}
+	float := func(line string, from, to int, name string) float64 {
+		if err != nil {
+			return 0
+		}
+		var v float64
+		v, err = strconv.ParseFloat(field(line, from, to), 64)
+		if err != nil {
+			err = fmt.Errorf("invalid %s %q", name, field(line, from, to))
+		}
+		return v
+	}
+
+	tle.CatalogNumber = field(line1, 3, 7)
+	if field(line2, 3, 7) != tle.CatalogNumber {
+		return TLE{}, fmt.Errorf("lines are for different satellites (%s, %s)", tle.CatalogNumber, field(line2, 3, 7))
+	}
+	year := int(float(line1, 19, 20, "epoch year"))
+	day := float(line1, 21, 32, "epoch day")
+	tle.BStar = impliedDecimal(field(line1, 54, 61), &err, "bstar")
+	tle.InclinationDeg = float(line2, 9, 16, "inclination")
+	tle.RAANDeg = float(line2, 18, 25, "right ascension")
+	tle.Eccentricity = float(line2, 27, 33, "eccentricity") / 1e7
+	tle.ArgPerigeeDeg = float(line2, 35, 42, "argument of perigee")
+	tle.MeanAnomalyDeg = float(line2, 44, 51, "mean anomaly")
+	tle.MeanMotionRevDay = float(line2, 53, 63, "mean motion")
+	if err != nil {
+		return TLE{}, err
+	}
+	if tle.MeanMotionRevDay <= 0 {
+		return TLE{}, fmt.Errorf("mean motion must be positive")
+	}
+
+	// Two-digit years: 57-99 are 1957-1999, 00-56 are 2000-2056
+	if year < 57 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tle.Epoch = start.Add(time.Duration((day - 1) * 24 * float64(time.Hour)))
+	return tle, nil
+}
+
+// impliedDecimal parses fields like " 28098-4" meaning 0.28098e-4
+func impliedDecimal(text string, errp *error, name string) float64 {
+	if *errp != nil || text == "" {
+		return 0
+	}
+	sign := 1.0
+	switch text[0] {
+	case '-':
+		sign, text = -1, text[1:]
+	case '+':
+		text = text[1:]
+	}
+	cut := strings.LastIndexAny(text, "+-")
+	if cut <= 0 {
+		*errp = fmt.Errorf("invalid %s %q", name, text)
+		return 0
+	}
+	mantissa, err1 := strconv.ParseFloat("0."+strings.TrimSpace(text[:cut]), 64)
+	exponent, err2 := strconv.Atoi(text[cut:])
+	if err1 != nil || err2 != nil {
+		*errp = fmt.Errorf("invalid %s %q", name, text)
+		return 0
+	}
+	return sign * mantissa * math.Pow(10, float64(exponent))
+}
+
+// validChecksum checks the modulo-10 checksum in column 69: digits count
+// their value and minus signs count 1
+func validChecksum(line string) bool {
+	sum := 0
+	for _, c := range line[:68] {
+		switch {
+		case c >= '0' && c <= '9':
+			sum += int(c - '0')
+		case c == '-':
+			sum++
+		}
+	}
+	return int(line[68]-'0') == sum%10
+}
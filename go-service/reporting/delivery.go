@@ -0,0 +1,123 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"orbitstream/models"
+	"orbitstream/retry"
+)
+
+// subject is the email subject and upload file stem of a report
+func subject(report models.FleetReport) string {
+	return fmt.Sprintf("Fleet health report %s to %s",
+		report.PeriodStart.Format("2006-01-02"), report.PeriodEnd.Format("2006-01-02"))
+}
+
+// EmailConfig configures report delivery by email
+type EmailConfig struct {
+	// Addr is the SMTP server host:port
+	Addr string
+	// Username and Password authenticate with PLAIN auth when set
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// EmailDeliverer mails the report as an HTML message
+type EmailDeliverer struct {
+	cfg  EmailConfig
+	send func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmailDeliverer creates an email deliverer
+func NewEmailDeliverer(cfg EmailConfig) *EmailDeliverer {
+	return &EmailDeliverer{cfg: cfg, send: smtp.SendMail}
+}
+
+// Deliver sends the report to every recipient
+func (d *EmailDeliverer) Deliver(ctx context.Context, report models.FleetReport, html []byte) error {
+	var auth smtp.Auth
+	if d.cfg.Username != "" {
+		host, _, err := net.SplitHostPort(d.cfg.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address %q: %w", d.cfg.Addr, err)
+		}
+		auth = smtp.PlainAuth("", d.cfg.Username, d.cfg.Password, host)
+	}
+	if err := d.send(d.cfg.Addr, auth, d.cfg.From, d.cfg.To, d.message(report, html)); err != nil {
+		return fmt.Errorf("failed to email fleet report: %w", err)
+	}
+	log.Printf("FleetReport: emailed to %d recipients", len(d.cfg.To))
+	return nil
+}
+
+// message builds the MIME message, base64 encoding the HTML body
+func (d *EmailDeliverer) message(report models.FleetReport, html []byte) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", d.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(d.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject(report))
+	fmt.Fprintf(&msg, "Date: %s\r\n", report.GeneratedAt.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString(html)
+	for len(encoded) > 76 {
+		msg.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	msg.WriteString(encoded + "\r\n")
+	return msg.Bytes()
+}
+
+// UploadDeliverer PUTs the report to a URL, e.g. a pre-signed object store
+// URL or a WebDAV share. {period_end} in the URL is replaced by the last
+// day's date (YYYY-MM-DD) so every week gets its own file.
+type UploadDeliverer struct {
+	url    string
+	client *http.Client
+	policy retry.Policy
+}
+
+// NewUploadDeliverer creates an upload deliverer retrying with policy
+func NewUploadDeliverer(url string, timeout time.Duration, policy retry.Policy) *UploadDeliverer {
+	return &UploadDeliverer{url: url, client: &http.Client{Timeout: timeout}, policy: policy}
+}
+
+// Deliver uploads the rendered report
+func (d *UploadDeliverer) Deliver(ctx context.Context, report models.FleetReport, html []byte) error {
+	url := strings.ReplaceAll(d.url, "{period_end}", report.PeriodEnd.Format("2006-01-02"))
+	err := retry.Do(ctx, d.policy, func(ctx context.Context, attempt int) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(html))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.Header.Set("Content-Type", "text/html; charset=utf-8")
+		resp, err := d.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("upload returned %s", resp.Status)
+		}
+		log.Printf("FleetReport: upload attempt %d failed: %v", attempt, err)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload fleet report: %w", err)
+	}
+	log.Printf("FleetReport: uploaded %s", url)
+	return nil
+}
@@ -0,0 +1,56 @@
+package reporting
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"orbitstream/retry"
+)
+
+// TestUploadDelivererPutsReport tests that the report is PUT to the URL with the period end filled in
+func TestUploadDelivererPutsReport(t *testing.T) {
+	var gotMethod, gotPath, gotType, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotType, gotBody = r.Method, r.URL.Path, r.Header.Get("Content-Type"), string(body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	d := NewUploadDeliverer(server.URL+"/reports/fleet-{period_end}.html", time.Second, retry.Policy{MaxAttempts: 1})
+	report := sampleReport()
+	report.PeriodEnd = time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	if err := d.Deliver(context.Background(), report, []byte("<html></html>")); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	if gotMethod != http.MethodPut || gotPath != "/reports/fleet-2026-03-09.html" {
+		t.Errorf("Unexpected request %s %s", gotMethod, gotPath)
+	}
+	if gotType != "text/html; charset=utf-8" || gotBody != "<html></html>" {
+		t.Errorf("Unexpected upload %q: %q", gotType, gotBody)
+	}
+}
+
+// TestUploadDelivererRetries tests that failed uploads are retried and reported
+func TestUploadDelivererRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	d := NewUploadDeliverer(server.URL, time.Second, retry.Policy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	if err := d.Deliver(context.Background(), sampleReport(), []byte("x")); err == nil {
+		t.Error("Expected an error after all attempts fail")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls.Load())
+	}
+}
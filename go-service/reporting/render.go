@@ -0,0 +1,130 @@
+package reporting
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"time"
+
+	"orbitstream/models"
+)
+
+// reportTemplate is a self-contained page (inline styles, no scripts) so it
+// renders the same in a mail client, a browser and when printed to PDF
+var reportTemplate = template.Must(template.New("fleet").Funcs(template.FuncMap{
+	"change":  change,
+	"percent": func(rate float64) string { return fmt.Sprintf("%.1f%%", rate*100) },
+	"date":    func(t time.Time) string { return t.Format("Mon 2 Jan 2006") },
+	"deref":   func(v *float64) float64 { return *v },
+	"barWidth": func(value, largest int64) int {
+		if largest <= 0 {
+			return 0
+		}
+		return int(value * 100 / largest)
+	},
+	"maxPoints": func(days []models.FleetReportDay) int64 {
+		var largest int64
+		for _, day := range days {
+			largest = max(largest, day.DataPoints)
+		}
+		return largest
+	},
+	"stations": sortedStations,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Fleet health report {{date .PeriodStart}} to {{date .PeriodEnd}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #1f2933; margin: 2em; }
+h1 { font-size: 1.6em; margin-bottom: 0; }
+h2 { font-size: 1.2em; margin-top: 2em; border-bottom: 1px solid #cbd2d9; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #e4e7eb; }
+td.num, th.num { text-align: right; }
+.muted { color: #7b8794; }
+.bar { background: #3e7bfa; height: 10px; }
+.summary td { font-size: 1.1em; }
+@media print { body { margin: 0; } h2 { page-break-after: avoid; } }
+</style>
+</head>
+<body>
+<h1>Fleet health report</h1>
+<p class="muted">{{date .PeriodStart}} to {{date .PeriodEnd}} &middot; generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}</p>
+
+<h2>Summary</h2>
+<table class="summary">
+<tr><td>Active satellites</td><td class="num">{{.Satellites}}</td><td></td></tr>
+<tr><td>Telemetry points</td><td class="num">{{.DataPoints}}</td><td class="muted">{{change .DataPoints .PreviousDataPoints}} vs previous week</td></tr>
+<tr><td>Anomalous points</td><td class="num">{{.Anomalies}}</td><td class="muted">{{change .Anomalies .PreviousAnomalies}} vs previous week</td></tr>
+</table>
+
+<h2>Ingest volume and anomalies per day</h2>
+{{if .Days}}{{$max := maxPoints .Days}}
+<table>
+<tr><th>Day</th><th class="num">Points</th><th></th><th class="num">Anomalies</th><th class="num">Satellites</th></tr>
+{{range .Days}}<tr><td>{{date .Day}}</td><td class="num">{{.DataPoints}}</td><td><div class="bar" style="width: {{barWidth .DataPoints $max}}%"></div></td><td class="num">{{.Anomalies}}</td><td class="num">{{.Satellites}}</td></tr>
+{{end}}</table>
+{{else}}<p class="muted">No telemetry was aggregated for this week.</p>{{end}}
+
+<h2>Top issues</h2>
+{{if .TopIssues}}
+<table>
+<tr><th>Dimension</th><th>Severity</th><th class="num">Anomalous points</th><th class="num">Satellites</th></tr>
+{{range .TopIssues}}<tr><td>{{.Dimension}}</td><td>{{.Severity}}</td><td class="num">{{.Count}}</td><td class="num">{{.Satellites}}</td></tr>
+{{end}}</table>
+{{else}}<p class="muted">No anomalies this week.</p>{{end}}
+
+<h2>Satellites with the most anomalies</h2>
+{{if .TopSatellites}}
+<table>
+<tr><th>Satellite</th><th class="num">Anomalous points</th><th class="num">Points</th><th class="num">Lowest battery</th></tr>
+{{range .TopSatellites}}<tr><td>{{.SatelliteID}}</td><td class="num">{{.Anomalies}}</td><td class="num">{{.DataPoints}}</td><td class="num">{{if .MinBattery}}{{printf "%.1f%%" (deref .MinBattery)}}{{else}}-{{end}}</td></tr>
+{{end}}</table>
+{{else}}<p class="muted">No satellite reported anomalies this week.</p>{{end}}
+
+<h2>Data quality per ground station</h2>
+{{if .Stations}}
+<table>
+<tr><th>Station</th><th class="num">Points</th><th class="num">Valid</th><th class="num">Mean clock skew</th><th>Last seen</th></tr>
+{{range stations .Stations}}<tr><td>{{.Station}}</td><td class="num">{{.Points}}</td><td class="num">{{percent .ValidRate}}</td><td class="num">{{printf "%.1fs" .ClockSkew.MeanSeconds}}</td><td>{{.LastSeen.Format "2006-01-02 15:04 MST"}}</td></tr>
+{{end}}</table>
+<p class="muted">Station scorecards cover the time since the ingest service last started.</p>
+{{else}}<p class="muted">No ground station data.</p>{{end}}
+</body>
+</html>
+`))
+
+// RenderHTML renders the report as a standalone HTML page
+func RenderHTML(report models.FleetReport) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, report); err != nil {
+		return nil, fmt.Errorf("failed to render fleet report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// change describes the relative change from previous to current
+func change(current, previous int64) string {
+	if previous == 0 {
+		if current == 0 {
+			return "no change"
+		}
+		return "new"
+	}
+	pct := float64(current-previous) / float64(previous) * 100
+	return fmt.Sprintf("%+.1f%%", pct)
+}
+
+// sortedStations orders stations worst valid rate first
+func sortedStations(stations []models.DataQualityReport) []models.DataQualityReport {
+	sorted := append([]models.DataQualityReport(nil), stations...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ValidRate != sorted[j].ValidRate {
+			return sorted[i].ValidRate < sorted[j].ValidRate
+		}
+		return sorted[i].Station < sorted[j].Station
+	})
+	return sorted
+}
@@ -0,0 +1,203 @@
+// Package reporting renders the weekly fleet health report from the
+// aggregates and anomaly rows and delivers it by email or upload.
+package reporting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"orbitstream/models"
+)
+
+// Source reads the fleet-wide figures of a report period
+// This allows for mocking in tests
+type Source interface {
+	FleetReport(ctx context.Context, from, to time.Time, loc *time.Location, topN int) (models.FleetReport, error)
+}
+
+// StationSource provides the ground station data quality scorecards
+type StationSource interface {
+	Reports() []models.DataQualityReport
+}
+
+// Leadership tells the reporter whether this replica sends the report
+type Leadership interface {
+	IsLeader() bool
+}
+
+// Deliverer sends a rendered report somewhere (mailbox, bucket)
+type Deliverer interface {
+	Deliver(ctx context.Context, report models.FleetReport, html []byte) error
+}
+
+// Config configures the weekly fleet report
+type Config struct {
+	// The report runs every Weekday at Hour in Location and covers the
+	// seven days up to that day's midnight
+	Weekday  time.Weekday
+	Hour     int
+	Location *time.Location
+	// TopN limits the top satellites and top issues tables
+	TopN int
+	// Timeout bounds generating and delivering one report
+	Timeout time.Duration
+}
+
+// ParseWeekday parses an English weekday name (case-insensitive)
+func ParseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
+
+// Reporter generates the weekly fleet report and delivers it on schedule
+type Reporter struct {
+	source     Source
+	stations   StationSource
+	deliverers []Deliverer
+	cfg        Config
+	// Only the leader replica delivers scheduled reports (nil = always)
+	leadership Leadership
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewReporter creates a reporter; stations may be nil
+func NewReporter(source Source, stations StationSource, cfg Config, deliverers ...Deliverer) *Reporter {
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	if cfg.TopN <= 0 {
+		cfg.TopN = 10
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+	return &Reporter{
+		source:     source,
+		stations:   stations,
+		deliverers: deliverers,
+		cfg:        cfg,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// LastPeriod returns the latest complete report week before now: the seven
+// days up to the most recent report weekday's midnight
+func (r *Reporter) LastPeriod(now time.Time) (from, to time.Time) {
+	local := now.In(r.cfg.Location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, r.cfg.Location)
+	back := (int(local.Weekday()) - int(r.cfg.Weekday) + 7) % 7
+	to = midnight.AddDate(0, 0, -back)
+	return to.AddDate(0, 0, -7), to
+}
+
+// PeriodEnding returns the report week ending as date (YYYY-MM-DD) begins
+// in the report's zone
+func (r *Reporter) PeriodEnding(date string) (from, to time.Time, err error) {
+	to, err = time.ParseInLocation("2006-01-02", date, r.cfg.Location)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q, want YYYY-MM-DD", date)
+	}
+	return to.AddDate(0, 0, -7), to, nil
+}
+
+// NextRun returns the first scheduled run strictly after now
+func (r *Reporter) NextRun(now time.Time) time.Time {
+	local := now.In(r.cfg.Location)
+	ahead := (int(r.cfg.Weekday) - int(local.Weekday()) + 7) % 7
+	next := time.Date(local.Year(), local.Month(), local.Day()+ahead, r.cfg.Hour, 0, 0, 0, r.cfg.Location)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// Generate builds the report for the period from to to
+func (r *Reporter) Generate(ctx context.Context, from, to time.Time) (models.FleetReport, error) {
+	report, err := r.source.FleetReport(ctx, from, to, r.cfg.Location, r.cfg.TopN)
+	if err != nil {
+		return models.FleetReport{}, fmt.Errorf("failed to read fleet figures: %w", err)
+	}
+	report.PeriodStart = from
+	report.PeriodEnd = to
+	report.GeneratedAt = time.Now().UTC()
+	report.Stations = []models.DataQualityReport{}
+	if r.stations != nil {
+		report.Stations = r.stations.Reports()
+	}
+	return report, nil
+}
+
+// Run generates the report for the week before now and hands it to every
+// deliverer; a failing deliverer doesn't stop the others
+func (r *Reporter) Run(ctx context.Context, now time.Time) error {
+	from, to := r.LastPeriod(now)
+	report, err := r.Generate(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	html, err := RenderHTML(report)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, deliverer := range r.deliverers {
+		if err := deliverer.Deliver(ctx, report, html); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetLeadership makes scheduled runs deliver only on the leader replica, so
+// every week is mailed once; must be called before Start
+func (r *Reporter) SetLeadership(leadership Leadership) {
+	r.leadership = leadership
+}
+
+// Start runs the report every week until Stop
+func (r *Reporter) Start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			next := r.NextRun(time.Now())
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+			case <-r.stopCh:
+				timer.Stop()
+				return
+			}
+
+			if r.leadership != nil && !r.leadership.IsLeader() {
+				log.Printf("FleetReport: not the leader, skipping the report for the week to %s", next.Format("2006-01-02"))
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), r.cfg.Timeout)
+			if err := r.Run(ctx, next); err != nil {
+				log.Printf("FleetReport: report for the week to %s failed: %v", next.Format("2006-01-02"), err)
+			} else {
+				log.Printf("FleetReport: delivered the report for the week to %s", next.Format("2006-01-02"))
+			}
+			cancel()
+		}
+	}()
+}
+
+// Stop ends the schedule
+func (r *Reporter) Stop() {
+	close(r.stopCh)
+	r.wg.Wait()
+}
@@ -0,0 +1,248 @@
+package reporting
+
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"orbitstream/models"
+)
+
+type fakeSource struct {
+	report   models.FleetReport
+	err      error
+	from, to time.Time
+}
+
+func (s *fakeSource) FleetReport(ctx context.Context, from, to time.Time, loc *time.Location, topN int) (models.FleetReport, error) {
+	s.from, s.to = from, to
+	return s.report, s.err
+}
+
+type fakeStations []models.DataQualityReport
+
+func (s fakeStations) Reports() []models.DataQualityReport { return s }
+
+type recordingDeliverer struct {
+	reports []models.FleetReport
+	html    [][]byte
+	err     error
+}
+
+func (d *recordingDeliverer) Deliver(ctx context.Context, report models.FleetReport, html []byte) error {
+	d.reports = append(d.reports, report)
+	d.html = append(d.html, html)
+	return d.err
+}
+
+func sampleReport() models.FleetReport {
+	battery := 12.5
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	return models.FleetReport{
+		Satellites:         3,
+		DataPoints:         1500,
+		Anomalies:          30,
+		PreviousDataPoints: 1000,
+		PreviousAnomalies:  0,
+		Days: []models.FleetReportDay{
+			{Day: day, DataPoints: 1000, Anomalies: 20, Satellites: 3},
+			{Day: day.AddDate(0, 0, 1), DataPoints: 500, Anomalies: 10, Satellites: 2},
+		},
+		TopSatellites: []models.FleetReportSatellite{
+			{SatelliteID: "<SAT-001>", Anomalies: 25, DataPoints: 600, MinBattery: &battery},
+		},
+		TopIssues: []models.FleetReportIssue{
+			{Dimension: "battery", Severity: "critical", Count: 25, Satellites: 1},
+		},
+	}
+}
+
+// TestParseWeekday tests weekday names in any case and unknown names
+func TestParseWeekday(t *testing.T) {
+	day, err := ParseWeekday("Monday")
+	if err != nil || day != time.Monday {
+		t.Errorf("Expected Monday, got %v (%v)", day, err)
+	}
+	if day, _ := ParseWeekday("sUnDaY"); day != time.Sunday {
+		t.Errorf("Expected Sunday, got %v", day)
+	}
+	if _, err := ParseWeekday("mon"); err == nil {
+		t.Error("Expected an error for an abbreviated weekday")
+	}
+}
+
+// TestLastPeriod tests that the period is the week up to the latest report weekday's midnight
+func TestLastPeriod(t *testing.T) {
+	r := NewReporter(&fakeSource{}, nil, Config{Weekday: time.Monday})
+
+	// Wednesday 2026-03-04: the last Monday is 2026-03-02
+	from, to := r.LastPeriod(time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC))
+	if !to.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the period to end 2026-03-02, got %v", to)
+	}
+	if !from.Equal(to.AddDate(0, 0, -7)) {
+		t.Errorf("Expected a seven day period, got %v to %v", from, to)
+	}
+
+	// On the report weekday itself the period ends that midnight
+	_, to = r.LastPeriod(time.Date(2026, 3, 9, 0, 30, 0, 0, time.UTC))
+	if !to.Equal(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the period to end 2026-03-09, got %v", to)
+	}
+}
+
+// TestLastPeriodLocation tests that days start at midnight in the configured zone
+func TestLastPeriodLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+7", 7*3600)
+	r := NewReporter(&fakeSource{}, nil, Config{Weekday: time.Monday, Location: loc})
+
+	// Sunday 20:00 UTC is already Monday 03:00 in UTC+7
+	_, to := r.LastPeriod(time.Date(2026, 3, 8, 20, 0, 0, 0, time.UTC))
+	if !to.Equal(time.Date(2026, 3, 9, 0, 0, 0, 0, loc)) {
+		t.Errorf("Expected the period to end at Monday midnight in UTC+7, got %v", to)
+	}
+}
+
+// TestNextRun tests that the next run is the report weekday and hour strictly after now
+func TestNextRun(t *testing.T) {
+	r := NewReporter(&fakeSource{}, nil, Config{Weekday: time.Monday, Hour: 6})
+
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 6, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 9, 5, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 6, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 9, 6, 0, 0, 0, time.UTC), time.Date(2026, 3, 16, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := r.NextRun(tt.now); !got.Equal(tt.want) {
+			t.Errorf("NextRun(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
+
+// TestPeriodEnding tests choosing the week by its end date
+func TestPeriodEnding(t *testing.T) {
+	r := NewReporter(&fakeSource{}, nil, Config{})
+
+	from, to, err := r.PeriodEnding("2026-03-09")
+	if err != nil {
+		t.Fatalf("PeriodEnding failed: %v", err)
+	}
+	if !from.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected period %v to %v", from, to)
+	}
+	if _, _, err := r.PeriodEnding("09/03/2026"); err == nil {
+		t.Error("Expected an error for a malformed date")
+	}
+}
+
+// TestRunDeliversReport tests that Run renders the last week and hands it to every deliverer
+func TestRunDeliversReport(t *testing.T) {
+	source := &fakeSource{report: sampleReport()}
+	stations := fakeStations{{Station: "GS-SVALBARD", Points: 100, ValidRate: 0.95}}
+	failing := &recordingDeliverer{err: errors.New("smtp down")}
+	working := &recordingDeliverer{}
+	r := NewReporter(source, stations, Config{Weekday: time.Monday}, failing, working)
+
+	err := r.Run(context.Background(), time.Date(2026, 3, 9, 6, 0, 0, 0, time.UTC))
+	if err == nil || !strings.Contains(err.Error(), "smtp down") {
+		t.Errorf("Expected the failing deliverer's error, got %v", err)
+	}
+	if len(working.reports) != 1 {
+		t.Fatalf("Expected the second deliverer to still run, got %d deliveries", len(working.reports))
+	}
+
+	report := working.reports[0]
+	if !source.to.Equal(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)) || !report.PeriodEnd.Equal(source.to) {
+		t.Errorf("Expected the week to 2026-03-09, got %v", report.PeriodEnd)
+	}
+	if len(report.Stations) != 1 || report.Stations[0].Station != "GS-SVALBARD" {
+		t.Errorf("Expected the station scorecards in the report, got %+v", report.Stations)
+	}
+	if !strings.Contains(string(working.html[0]), "GS-SVALBARD") {
+		t.Error("Expected the rendered HTML to list the station")
+	}
+}
+
+// TestRunSourceError tests that nothing is delivered when the figures can't be read
+func TestRunSourceError(t *testing.T) {
+	deliverer := &recordingDeliverer{}
+	r := NewReporter(&fakeSource{err: errors.New("db down")}, nil, Config{}, deliverer)
+
+	if err := r.Run(context.Background(), time.Now()); err == nil {
+		t.Error("Expected an error when the source fails")
+	}
+	if len(deliverer.reports) != 0 {
+		t.Errorf("Expected no deliveries, got %d", len(deliverer.reports))
+	}
+}
+
+// TestRenderHTML tests the report sections, trends and escaping
+func TestRenderHTML(t *testing.T) {
+	html, err := RenderHTML(sampleReport())
+	if err != nil {
+		t.Fatalf("RenderHTML failed: %v", err)
+	}
+	page := string(html)
+
+	for _, want := range []string{"Fleet health report", "50.0% vs previous week", "new vs previous week", "critical", "12.5%", "width: 50%"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected the page to contain %q", want)
+		}
+	}
+	if strings.Contains(page, "<SAT-001>") || !strings.Contains(page, "&lt;SAT-001&gt;") {
+		t.Error("Expected satellite IDs to be escaped")
+	}
+	if !strings.Contains(page, "No ground station data.") {
+		t.Error("Expected the empty station note")
+	}
+}
+
+// TestEmailDelivererMessage tests the envelope and MIME message handed to the SMTP client
+func TestEmailDelivererMessage(t *testing.T) {
+	d := NewEmailDeliverer(EmailConfig{
+		Addr:     "smtp.example.com:587",
+		Username: "reports",
+		Password: "secret",
+		From:     "orbitstream@example.com",
+		To:       []string{"ops@example.com", "mgmt@example.com"},
+	})
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotAuth smtp.Auth
+	var gotMsg []byte
+	d.send = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotFrom, gotTo, gotMsg = addr, auth, from, to, msg
+		return nil
+	}
+
+	report := sampleReport()
+	report.PeriodStart = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	report.PeriodEnd = time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	if err := d.Deliver(context.Background(), report, []byte("<html></html>")); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	if gotAddr != "smtp.example.com:587" || gotFrom != "orbitstream@example.com" || len(gotTo) != 2 {
+		t.Errorf("Unexpected envelope: %s %s %v", gotAddr, gotFrom, gotTo)
+	}
+	if gotAuth == nil {
+		t.Error("Expected PLAIN auth when a username is set")
+	}
+	msg := string(gotMsg)
+	for _, want := range []string{
+		"To: ops@example.com, mgmt@example.com\r\n",
+		"Subject: Fleet health report 2026-03-02 to 2026-03-09\r\n",
+		"Content-Type: text/html; charset=utf-8\r\n",
+		"PGh0bWw+PC9odG1sPg==",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected the message to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package retry runs outbound calls (database flushes, webhooks, WAL
+// shipping) with one shared policy: exponential backoff with jitter, an
+// attempt limit, a wall clock budget and cancellation through the context.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Jitter strategies
+const (
+	// JitterProportional spreads delays ±20% around the exponential delay
+	JitterProportional = "proportional"
+	// JitterFull picks a delay uniformly between 0 and the exponential delay
+	JitterFull = "full"
+	// JitterEqual keeps half the exponential delay and randomizes the rest
+	JitterEqual = "equal"
+	// JitterNone uses the exponential delay as is
+	JitterNone = "none"
+)
+
+// ParseJitter validates a jitter strategy name ("" is proportional)
+func ParseJitter(name string) (string, error) {
+	switch name {
+	case "":
+		return JitterProportional, nil
+	case JitterProportional, JitterFull, JitterEqual, JitterNone:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown jitter strategy %q (want proportional, full, equal or none)", name)
+}
+
+var (
+	// ErrAttemptsExhausted is returned (wrapping the last error) when every
+	// attempt failed
+	ErrAttemptsExhausted = errors.New("retry attempts exhausted")
+	// ErrBudgetExhausted is returned (wrapping the last error) when the next
+	// backoff would overrun the budget or the context's deadline
+	ErrBudgetExhausted = errors.New("retry budget exhausted")
+)
+
+// Policy describes how a call is retried
+type Policy struct {
+	// MaxAttempts bounds the calls, the first included. With a Budget, 0
+	// retries until the budget runs out; without one, 0 calls once.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, doubled for each one
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential delay (0 = no cap)
+	MaxDelay time.Duration
+	// Jitter randomizes delays, see ParseJitter ("" = proportional)
+	Jitter string
+	// Budget caps the total wall clock time, backoff included (0 = none)
+	Budget time.Duration
+}
+
+// Delay returns the wait before retry number retry (1-based)
+func (p Policy) Delay(retry int) time.Duration {
+	return p.delay(retry, randFloat64)
+}
+
+// delay computes Delay using random, a source of floats in [0, 1)
+func (p Policy) delay(retry int, random func() float64) time.Duration {
+	delay := p.BaseDelay * time.Duration(1<<uint(min(max(retry-1, 0), 20)))
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	switch p.Jitter {
+	case JitterFull:
+		return time.Duration(float64(delay) * random())
+	case JitterEqual:
+		return delay/2 + time.Duration(float64(delay/2)*random())
+	case JitterNone:
+		return delay
+	default:
+		return delay + time.Duration(float64(delay)*0.2*(2.0*random()-1.0))
+	}
+}
+
+// randFloat64 returns a random float64 in [0, 1)
+// math/rand's global source is seeded per process and safe for concurrent
+// use, so goroutines retrying together don't pick the same delays
+func randFloat64() float64 {
+	return rand.Float64()
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying; Do returns it unwrapped
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do calls fn until it succeeds, it returns a Permanent error, the attempts
+// or the budget run out, or ctx is done. fn gets the context bounded by the
+// budget and the 1-based attempt number. The returned error wraps
+// ErrAttemptsExhausted, ErrBudgetExhausted or the context's error, and the
+// last error of fn.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context, attempt int) error) error {
+	if p.Budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Budget)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx, attempt)
+		if err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts || p.MaxAttempts <= 0 && p.Budget <= 0 {
+			return fmt.Errorf("%w after %d attempts: %w", ErrAttemptsExhausted, attempt, err)
+		}
+		delay := p.Delay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("%w after %d attempts: %w", ErrBudgetExhausted, attempt, err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("retry cancelled after %d attempts (%w): %w", attempt, ctx.Err(), err)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errFlaky = errors.New("flaky")
+
+// failing returns a call failing the first n attempts and counting calls
+func failing(n int, calls *int) func(ctx context.Context, attempt int) error {
+	return func(ctx context.Context, attempt int) error {
+		*calls++
+		if attempt <= n {
+			return errFlaky
+		}
+		return nil
+	}
+}
+
+// TestDelayStrategies tests the delay bounds of every jitter strategy
+func TestDelayStrategies(t *testing.T) {
+	base := 100 * time.Millisecond
+	tests := []struct {
+		strategy string
+		retry    int
+		random   float64
+		want     time.Duration
+	}{
+		{JitterNone, 1, 0.7, 100 * time.Millisecond},
+		{JitterNone, 3, 0.7, 400 * time.Millisecond},
+		{JitterFull, 3, 0, 0},
+		{JitterFull, 3, 0.5, 200 * time.Millisecond},
+		{JitterEqual, 3, 0, 200 * time.Millisecond},
+		{JitterEqual, 3, 0.5, 300 * time.Millisecond},
+		{JitterProportional, 3, 0, 320 * time.Millisecond},
+		{JitterProportional, 3, 0.5, 400 * time.Millisecond},
+		{"", 3, 0.5, 400 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		p := Policy{BaseDelay: base, Jitter: tt.strategy}
+		got := p.delay(tt.retry, func() float64 { return tt.random })
+		if got != tt.want {
+			t.Errorf("%q retry %d random %.1f: expected %v, got %v", tt.strategy, tt.retry, tt.random, tt.want, got)
+		}
+	}
+}
+
+// TestDelayMaxDelay tests that the exponential delay is capped
+func TestDelayMaxDelay(t *testing.T) {
+	p := Policy{BaseDelay: time.Second, MaxDelay: 5 * time.Second, Jitter: JitterNone}
+
+	if got := p.Delay(10); got != 5*time.Second {
+		t.Errorf("expected the delay capped at 5s, got %v", got)
+	}
+	if got := p.Delay(2); got != 2*time.Second {
+		t.Errorf("expected 2s below the cap, got %v", got)
+	}
+}
+
+// TestDelayDecorrelated tests that concurrent retries don't all pick the same delay
+func TestDelayDecorrelated(t *testing.T) {
+	p := Policy{BaseDelay: time.Second, Jitter: JitterFull}
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 50; i++ {
+		seen[p.Delay(2)] = true
+	}
+	if len(seen) < 40 {
+		t.Errorf("expected distinct delays, got %d distinct of 50", len(seen))
+	}
+}
+
+// TestRandFloat64 tests the random float generator (used for jitter)
+func TestRandFloat64(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		val := randFloat64()
+		if val < 0 || val > 1 {
+			t.Errorf("randFloat64 returned %f, expected range [0, 1]", val)
+		}
+	}
+}
+
+// TestParseJitter tests strategy validation
+func TestParseJitter(t *testing.T) {
+	if got, err := ParseJitter(""); err != nil || got != JitterProportional {
+		t.Errorf("expected empty to default to proportional, got %q (%v)", got, err)
+	}
+	for _, name := range []string{JitterProportional, JitterFull, JitterEqual, JitterNone} {
+		if _, err := ParseJitter(name); err != nil {
+			t.Errorf("expected %q to be valid: %v", name, err)
+		}
+	}
+	if _, err := ParseJitter("decorrelated"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
+
+// TestDoSucceedsAfterRetries tests that transient failures are retried
+func TestDoSucceedsAfterRetries(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 5, BaseDelay: time.Millisecond}, failing(2, &calls))
+
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+// TestDoAttemptsExhausted tests that MaxAttempts bounds the calls
+func TestDoAttemptsExhausted(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 3, BaseDelay: time.Millisecond}, failing(10, &calls))
+
+	if !errors.Is(err, ErrAttemptsExhausted) || !errors.Is(err, errFlaky) {
+		t.Errorf("expected attempts exhausted wrapping the last error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+// TestDoNoAttemptsNoBudget tests that a zero policy calls once
+func TestDoNoAttemptsNoBudget(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{}, failing(10, &calls))
+
+	if !errors.Is(err, ErrAttemptsExhausted) || calls != 1 {
+		t.Errorf("expected one call, got %d (%v)", calls, err)
+	}
+}
+
+// TestDoPermanent tests that a permanent error stops retrying and is
+// returned unwrapped
+func TestDoPermanent(t *testing.T) {
+	errBad := errors.New("bad request")
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 5, BaseDelay: time.Millisecond}, func(ctx context.Context, attempt int) error {
+		calls++
+		return Permanent(errBad)
+	})
+
+	if err != errBad {
+		t.Errorf("expected the permanent error itself, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+// TestDoBudget tests that a budget bounds retries without an attempt limit
+func TestDoBudget(t *testing.T) {
+	calls := 0
+	start := time.Now()
+	err := Do(context.Background(), Policy{BaseDelay: 20 * time.Millisecond, Budget: 200 * time.Millisecond}, failing(1000, &calls))
+
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("expected budget exhausted, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected retries to stop within the 200ms budget, took %v", elapsed)
+	}
+	if calls < 2 {
+		t.Errorf("expected retries within the budget, got %d calls", calls)
+	}
+}
+
+// TestDoBudgetBoundsAttempts tests that fn gets a context bounded by the budget
+func TestDoBudgetBoundsAttempts(t *testing.T) {
+	err := Do(context.Background(), Policy{MaxAttempts: 1, Budget: time.Minute}, func(ctx context.Context, attempt int) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the attempt context to carry the budget deadline")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+}
+
+// TestDoCancelled tests that cancelling the context ends a long backoff
+func TestDoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	calls := 0
+	start := time.Now()
+	err := Do(ctx, Policy{MaxAttempts: 100, BaseDelay: time.Minute}, failing(1000, &calls))
+
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errFlaky) {
+		t.Errorf("expected cancellation wrapping the last error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected cancellation to end the one minute backoff, took %v", elapsed)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
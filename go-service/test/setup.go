@@ -1,7 +1,9 @@
 package test
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"orbitstream/db"
 	"orbitstream/models"
 	"sync"
 	"time"
@@ -15,6 +17,7 @@ type MockBatchProcessor struct {
 	addCallCount  int
 	shouldError   bool
 	anomalyResult bool
+	backpressure  db.BackpressureState
 }
 
 // NewMockBatchProcessor creates a new mock batch processor
@@ -31,7 +34,7 @@ func (m *MockBatchProcessor) Add(point models.TelemetryPoint) error {
 	m.addCallCount++
 
 	if m.shouldError {
-		return errors.New("mock buffer full")
+		return fmt.Errorf("mock: %w", db.ErrBufferFull)
 	}
 
 	m.addedPoints = append(m.addedPoints, point)
@@ -66,6 +69,20 @@ func (m *MockBatchProcessor) SetShouldError(shouldError bool) {
 	m.shouldError = shouldError
 }
 
+// SetBackpressure sets the state Backpressure reports
+func (m *MockBatchProcessor) SetBackpressure(state db.BackpressureState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.backpressure = state
+}
+
+// Backpressure returns the configured backpressure state
+func (m *MockBatchProcessor) Backpressure() db.BackpressureState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.backpressure
+}
+
 // SetAnomalyResult sets the anomaly detection result
 func (m *MockBatchProcessor) SetAnomalyResult(anomaly bool) {
 	m.mu.Lock()
@@ -131,3 +148,106 @@ func NewAnomalousTelemetryPoint() models.TelemetryPoint {
 		IsAnomaly:            true,
 	}
 }
+
+// MockTelemetryQuerier is a mock implementation of the query service for testing
+type MockTelemetryQuerier struct {
+	mu          sync.Mutex
+	Points      []models.TelemetryPoint
+	Registry    *models.RegistryAttributes
+	Buckets     []models.StatsBucket
+	Versions    []models.VersionStatsBucket
+	Err         error
+	DataThrough *time.Time
+	LastQuery   db.TelemetryQuery
+	Resolution  string
+	CallCount   int
+	AsOfCalls   int
+}
+
+// QueryTelemetry records the query and returns the configured points
+func (m *MockTelemetryQuerier) QueryTelemetry(ctx context.Context, q db.TelemetryQuery) ([]models.TelemetryPoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.CallCount++
+	m.LastQuery = q
+	return m.Points, m.Err
+}
+
+// QueryTelemetryAsOf records the query and returns the configured points
+// with the configured registry attributes
+func (m *MockTelemetryQuerier) QueryTelemetryAsOf(ctx context.Context, q db.TelemetryQuery) ([]models.TelemetryPointAsOf, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.CallCount++
+	m.LastQuery = q
+	m.AsOfCalls++
+	points := make([]models.TelemetryPointAsOf, len(m.Points))
+	for i, p := range m.Points {
+		points[i] = models.TelemetryPointAsOf{TelemetryPoint: p, Registry: m.Registry}
+	}
+	return points, m.Err
+}
+
+// QueryStats records the query and returns the configured buckets
+func (m *MockTelemetryQuerier) QueryStats(ctx context.Context, q db.TelemetryQuery, resolution string) ([]models.StatsBucket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.CallCount++
+	m.LastQuery = q
+	m.Resolution = resolution
+	return m.Buckets, m.Err
+}
+
+// QueryVersionStats records the query and returns the configured version buckets
+func (m *MockTelemetryQuerier) QueryVersionStats(ctx context.Context, q db.TelemetryQuery) ([]models.VersionStatsBucket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.CallCount++
+	m.LastQuery = q
+	return m.Versions, m.Err
+}
+
+// StatsDataThrough returns the configured aggregate freshness
+func (m *MockTelemetryQuerier) StatsDataThrough(ctx context.Context, resolution string, loc *time.Location) (*time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.DataThrough, m.Err
+}
+
+// VersionStatsDataThrough returns the configured aggregate freshness
+func (m *MockTelemetryQuerier) VersionStatsDataThrough(ctx context.Context) (*time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.DataThrough, m.Err
+}
+
+// ExportTelemetry streams the configured points in order, honouring the cursor
+func (m *MockTelemetryQuerier) ExportTelemetry(ctx context.Context, q db.TelemetryQuery, cursor db.ExportCursor, fn func(models.TelemetryPoint) error) error {
+	m.mu.Lock()
+	m.CallCount++
+	m.LastQuery = q
+	points := m.Points
+	m.mu.Unlock()
+
+	skipped := 0
+	for _, p := range points {
+		if !cursor.After.IsZero() && p.Timestamp.Before(cursor.After) {
+			continue
+		}
+		if skipped < cursor.Skip && p.Timestamp.Equal(cursor.After) {
+			skipped++
+			continue
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return m.Err
+}
+
+// GetCallCount returns the number of queries executed
+func (m *MockTelemetryQuerier) GetCallCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.CallCount
+}